@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -28,7 +29,7 @@ func TestRootCommand(t *testing.T) {
 
 	// Check that subcommands are registered
 	subcommands := rootCmd.Commands()
-	expectedCommands := []string{"list", "config", "connect"}
+	expectedCommands := []string{"list", "config", "connect", "diff", "extract"}
 
 	for _, expected := range expectedCommands {
 		found := false
@@ -60,6 +61,42 @@ func TestListCommand(t *testing.T) {
 	// If it panics, the test will fail
 }
 
+func TestPrintPortsJSON(t *testing.T) {
+	ports := []serial.PortInfo{
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", Product: `FT232R "USB" UART`,
+			ByID: `/dev/serial/by-id/usb-a\b`, Driver: "ftdi_sio"},
+		{Name: "/dev/ttyS0"},
+	}
+	defer func(details bool) { listDetails = details }(listDetails)
+
+	// Quotes and backslashes in the details are escaped
+	listDetails = true
+	var buf bytes.Buffer
+	if err := printPortsJSON(&buf, ports); err != nil {
+		t.Fatalf("printPortsJSON failed: %v", err)
+	}
+	var decoded []serial.PortInfo
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(decoded) != 2 || decoded[0] != ports[0] || decoded[1] != ports[1] {
+		t.Errorf("Decoded %+v", decoded)
+	}
+	if strings.Contains(buf.String(), `"is_usb": false`) {
+		t.Errorf("Empty details written: %s", buf.String())
+	}
+
+	listDetails = false
+	buf.Reset()
+	var names []string
+	if err := printPortsJSON(&buf, ports); err != nil || json.Unmarshal(buf.Bytes(), &names) != nil {
+		t.Fatalf("Names output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(names) != 2 || names[0] != "/dev/ttyUSB0" {
+		t.Errorf("Names = %v", names)
+	}
+}
+
 // TestConfigCommand tests the config command
 func TestConfigCommand(t *testing.T) {
 	// Create a buffer to capture output
@@ -314,3 +351,33 @@ func TestCommandStructure(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLoginSteps(t *testing.T) {
+	steps := parseLoginSteps([]string{"login:", "admin", "Password:", "secret:router"}, 10)
+	if len(steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(steps))
+	}
+	if steps[0].Send != "admin" || steps[0].Secret != "" || steps[0].TimeoutSeconds != 10 {
+		t.Errorf("First step = %+v", steps[0])
+	}
+	if steps[1].Secret != "router" || steps[1].Send != "" {
+		t.Errorf("Second step = %+v", steps[1])
+	}
+	if desc := describeLoginStep(steps[1]); desc != `"Password:" -> secret "router"` {
+		t.Errorf("describeLoginStep = %q", desc)
+	}
+}
+
+func TestReadLogLinesRange(t *testing.T) {
+	path := t.TempDir() + "/session.log"
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, start, err := readLogLines(path + ":2-3")
+	if err != nil || start != 2 || strings.Join(lines, ",") != "two,three" {
+		t.Errorf("readLogLines(:2-3) = %q, %d, %v; want two,three from line 2", lines, start, err)
+	}
+	if _, start, _ := readLogLines(path); start != 1 {
+		t.Errorf("readLogLines without a range starts at line %d, want 1", start)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -21,6 +22,18 @@ var (
 	configStopBits int
 	configParity   string
 	configTimeout  int
+	configUSB      string
+
+	// Doctor command flags
+	doctorFix bool
+
+	// Auto-login command flags
+	autoLoginTimeout int
+
+	// Status bar command flags
+	statusLeft   string
+	statusCenter string
+	statusRight  string
 )
 
 // configCmd represents the config command
@@ -39,8 +52,15 @@ var saveCmd = &cobra.Command{
 	Short: "Save a serial port configuration",
 	Long: `Save the current serial port configuration with a given name.
 
-Example:
-  sterm config save mydevice -p COM3 -b 115200`,
+Use --usb to find the device by its USB IDs whenever the profile is used,
+so it still connects after /dev/ttyUSB0 becomes /dev/ttyUSB1. "auto" takes
+the IDs and serial number of the adapter currently at --port; 'sterm list -d'
+shows them for every port.
+
+Examples:
+  sterm config save mydevice -p COM3 -b 115200
+  sterm config save console -p /dev/ttyUSB0 --usb auto
+  sterm config save console --usb 0403:6001:A50285BI`,
 	Args: cobra.ExactArgs(1),
 	Run:  runSaveConfig,
 }
@@ -90,6 +110,75 @@ Example:
 	Run:  runShowConfig,
 }
 
+// doctorCmd checks configuration files for problems
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check configuration files for problems",
+	Long: `Check the saved configurations and settings files for unknown keys,
+invalid values and outdated schema versions.
+
+Use --fix to upgrade outdated files to the current schema. The original
+file is kept next to it with a .bak extension.
+
+Example:
+  sterm config doctor
+  sterm config doctor --fix`,
+	Args: cobra.NoArgs,
+	Run:  runDoctor,
+}
+
+// autoLoginCmd sets the prompts a configuration answers after connecting
+var autoLoginCmd = &cobra.Command{
+	Use:   "autologin <name> [<expect> <send>]...",
+	Short: "Set the prompts a configuration answers after connecting",
+	Long: `Set expect/send pairs that are run after connecting with a saved
+configuration. Each pair waits for the expect text in the received data,
+then sends the text followed by Enter. An empty expect sends straight away.
+
+A send of the form secret:<name> sends a stored secret instead, so passwords
+never go into configs.json. Store them with 'sterm secret set <name>'; the
+STERM_SECRET_<NAME> environment variable overrides a stored secret.
+
+Without pairs, auto-login is turned off.
+
+Example:
+  sterm secret set router
+  sterm config autologin router "login:" admin "Password:" secret:router
+  sterm config autologin router`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 || len(args)%2 != 1 {
+			return fmt.Errorf("expected a configuration name followed by expect/send pairs")
+		}
+		return nil
+	},
+	Run: runAutoLogin,
+}
+
+// statusBarCmd sets the status bar templates of a configuration
+var statusBarCmd = &cobra.Command{
+	Use:   "statusbar <name>",
+	Short: "Set the status bar layout of a configuration",
+	Long: `Replace the text of the left, center or right of the status bar with a
+template while connected through a saved configuration. Placeholders:
+
+  {port} {baud} {flow} {encoding} {profile}  line settings
+  {tx} {rx}                                  bytes sent and received
+  {time}                                     the time of day
+  {log}                                      file history is streamed or captured to
+
+Parts not given keep the status_bar.format templates of the settings file,
+or the built-in text. The center is replaced only while no message, scroll
+or filter is shown, and the show_port, show_hints and show_stats settings
+still hide their parts. Without flags the configuration's templates are
+removed.
+
+Example:
+  sterm config statusbar router --left " {profile} {port}@{baud} " --right " {tx}↑ {rx}↓ {time} "
+  sterm config statusbar router`,
+	Args: cobra.ExactArgs(1),
+	Run:  runStatusBar,
+}
+
 func init() {
 	// Add subcommands to config
 	configCmd.AddCommand(saveCmd)
@@ -97,6 +186,20 @@ func init() {
 	configCmd.AddCommand(listConfigCmd)
 	configCmd.AddCommand(deleteCmd)
 	configCmd.AddCommand(showCmd)
+	configCmd.AddCommand(doctorCmd)
+	configCmd.AddCommand(autoLoginCmd)
+	configCmd.AddCommand(statusBarCmd)
+
+	// Add flags for statusbar command
+	statusBarCmd.Flags().StringVar(&statusLeft, "left", "", "template for the left of the status bar")
+	statusBarCmd.Flags().StringVar(&statusCenter, "center", "", "template for the center of the status bar")
+	statusBarCmd.Flags().StringVar(&statusRight, "right", "", "template for the right of the status bar")
+
+	// Add flags for autologin command
+	autoLoginCmd.Flags().IntVar(&autoLoginTimeout, "timeout", 0, "seconds to wait for each prompt (default 30)")
+
+	// Add flags for doctor command
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "migrate outdated files to the current schema")
 
 	// Add flags for save command
 	saveCmd.Flags().StringVarP(&configPort, "port", "p", "", "serial port")
@@ -105,7 +208,8 @@ func init() {
 	saveCmd.Flags().IntVarP(&configStopBits, "stop", "s", 1, "stop bits")
 	saveCmd.Flags().StringVar(&configParity, "parity", "none", "parity")
 	saveCmd.Flags().IntVarP(&configTimeout, "timeout", "t", 10, "timeout in seconds")
-	_ = saveCmd.MarkFlagRequired("port")
+	addRS485Flags(saveCmd)
+	saveCmd.Flags().StringVar(&configUSB, "usb", "", "find the device by USB IDs: VID:PID[:SERIAL], or auto to take them from --port")
 }
 
 func runSaveConfig(cmd *cobra.Command, args []string) {
@@ -119,6 +223,29 @@ func runSaveConfig(cmd *cobra.Command, args []string) {
 		StopBits: configStopBits,
 		Parity:   configParity,
 		Timeout:  time.Duration(configTimeout) * time.Second,
+		RS485:    rs485Config(),
+	}
+
+	switch configUSB {
+	case "":
+	case "auto":
+		if configPort == "" {
+			fmt.Fprintf(os.Stderr, "--usb auto needs the device's current --port\n")
+			os.Exit(1)
+		}
+		match, err := serial.USBMatchFor(configPort)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading USB IDs: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.USB = match
+	default:
+		match, err := serial.ParseUSBMatch(configUSB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.USB = match
 	}
 
 	// Validate configuration
@@ -136,6 +263,12 @@ func runSaveConfig(cmd *cobra.Command, args []string) {
 
 	fmt.Printf("Configuration '%s' saved successfully.\n", name)
 	fmt.Printf("  Port: %s\n", cfg.Port)
+	if cfg.USB != nil {
+		fmt.Printf("  USB Device: %s\n", cfg.USB)
+	}
+	if cfg.RS485 != nil {
+		fmt.Printf("  RS-485: %s\n", describeRS485(*cfg.RS485))
+	}
 	fmt.Printf("  Baud Rate: %d\n", cfg.BaudRate)
 	fmt.Printf("  Data Bits: %d\n", cfg.DataBits)
 	fmt.Printf("  Stop Bits: %d\n", cfg.StopBits)
@@ -154,11 +287,21 @@ func runLoadConfig(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	autoLogin, err := configManager.LoadAutoLogin(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration '%s': %v\n", name, err)
+		os.Exit(1)
+	}
+
+	suppressEcho, _ := configManager.LoadSuppressEcho(name)
+	statusFormat, _ := configManager.LoadStatusFormat(name)
+
 	fmt.Printf("Loading configuration '%s'...\n", name)
 	fmt.Printf("Connecting to %s at %d baud...\n", cfg.Port, cfg.BaudRate)
 
 	// Launch terminal with loaded configuration
-	if err := app.RunInteractive(cfg); err != nil {
+	opts := app.AppOptions{ProfileName: name, AutoLogin: autoLogin, SuppressEcho: suppressEcho, StatusFormat: statusFormat}
+	if err := app.RunInteractiveWithOptions(cfg, opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running terminal: %v\n", err)
 		os.Exit(1)
 	}
@@ -194,9 +337,14 @@ func runListConfigs(cmd *cobra.Command, args []string) {
 
 		created := cfg.CreatedAt.Format("2006-01-02 15:04")
 
+		port := cfg.Config.Port
+		if cfg.Config.USB != nil {
+			port = "usb " + cfg.Config.USB.String()
+		}
+
 		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n",
 			cfg.Name,
-			cfg.Config.Port,
+			port,
 			cfg.Config.BaudRate,
 			lastUsed,
 			created)
@@ -250,11 +398,20 @@ func runShowConfig(cmd *cobra.Command, args []string) {
 	fmt.Printf("Configuration: %s\n", found.Name)
 	fmt.Println("=" + repeatString("=", len(found.Name)+14))
 	fmt.Printf("Port:        %s\n", found.Config.Port)
+	if found.Config.USB != nil {
+		fmt.Printf("USB Device:  %s\n", found.Config.USB)
+	}
+	if found.Config.RS485 != nil {
+		fmt.Printf("RS-485:      %s\n", describeRS485(*found.Config.RS485))
+	}
 	fmt.Printf("Baud Rate:   %d\n", found.Config.BaudRate)
 	fmt.Printf("Data Bits:   %d\n", found.Config.DataBits)
 	fmt.Printf("Stop Bits:   %d\n", found.Config.StopBits)
 	fmt.Printf("Parity:      %s\n", found.Config.Parity)
 	fmt.Printf("Timeout:     %d seconds\n", found.Config.Timeout)
+	if found.SuppressEcho {
+		fmt.Printf("Echo:        suppressed\n")
+	}
 	fmt.Println()
 	fmt.Printf("Created:     %s\n", found.CreatedAt.Format(time.RFC3339))
 
@@ -264,9 +421,161 @@ func runShowConfig(cmd *cobra.Command, args []string) {
 		fmt.Printf("Last Used:   Never\n")
 	}
 
+	if found.StatusBar != nil {
+		fmt.Println()
+		fmt.Println("Status bar:")
+		describeStatusFormat(*found.StatusBar)
+	}
+
+	if len(found.AutoLogin) > 0 {
+		fmt.Println()
+		fmt.Println("Auto-login:")
+		for _, step := range found.AutoLogin {
+			fmt.Printf("  %s\n", describeLoginStep(step))
+		}
+	}
+
 	fmt.Println("\nUse 'sterm config load " + name + "' to connect using this configuration.")
 }
 
+func runAutoLogin(cmd *cobra.Command, args []string) {
+	name := args[0]
+	steps := parseLoginSteps(args[1:], autoLoginTimeout)
+
+	configManager := config.NewFileConfigManager("")
+	if err := configManager.SetAutoLogin(name, steps); err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting auto-login: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(steps) == 0 {
+		fmt.Printf("Auto-login turned off for '%s'.\n", name)
+		return
+	}
+	fmt.Printf("Auto-login for '%s':\n", name)
+	for _, step := range steps {
+		fmt.Printf("  %s\n", describeLoginStep(step))
+	}
+}
+
+func runStatusBar(cmd *cobra.Command, args []string) {
+	name := args[0]
+	format := config.StatusFormat{Left: statusLeft, Center: statusCenter, Right: statusRight}
+
+	configManager := config.NewFileConfigManager("")
+	if err := configManager.SetStatusFormat(name, format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting status bar: %v\n", err)
+		os.Exit(1)
+	}
+
+	if format.IsZero() {
+		fmt.Printf("Status bar of '%s' reset to the settings file's.\n", name)
+		return
+	}
+	fmt.Printf("Status bar of '%s':\n", name)
+	describeStatusFormat(format)
+}
+
+// describeStatusFormat prints the templates set in a status bar format
+func describeStatusFormat(format config.StatusFormat) {
+	for _, part := range []struct{ name, template string }{
+		{"Left", format.Left},
+		{"Center", format.Center},
+		{"Right", format.Right},
+	} {
+		if part.template != "" {
+			fmt.Printf("  %-7s %q\n", part.name+":", part.template)
+		}
+	}
+}
+
+// parseLoginSteps turns expect/send argument pairs into auto-login steps. A
+// send of the form secret:<name> names a stored secret.
+func parseLoginSteps(pairs []string, timeout int) []config.LoginStep {
+	var steps []config.LoginStep
+	for i := 0; i+1 < len(pairs); i += 2 {
+		step := config.LoginStep{Expect: pairs[i], TimeoutSeconds: timeout}
+		if secret, ok := strings.CutPrefix(pairs[i+1], "secret:"); ok {
+			step.Secret = secret
+		} else {
+			step.Send = pairs[i+1]
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// describeLoginStep formats an auto-login step for display, without
+// revealing secrets
+func describeLoginStep(step config.LoginStep) string {
+	expect := "(connect)"
+	if step.Expect != "" {
+		expect = fmt.Sprintf("%q", step.Expect)
+	}
+	if step.Secret != "" {
+		return fmt.Sprintf("%s -> secret %q", expect, step.Secret)
+	}
+	return fmt.Sprintf("%s -> %q", expect, step.Send)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	configManager := config.NewFileConfigManager("")
+	reports := configManager.Doctor(doctorFix)
+
+	healthy := true
+	for _, report := range reports {
+		fmt.Printf("%s\n", report.Path)
+
+		if !report.Exists && report.Err == nil {
+			fmt.Println("  not present, defaults are used")
+			continue
+		}
+		if report.Err != nil {
+			fmt.Printf("  ✗ %v\n", report.Err)
+			healthy = false
+			continue
+		}
+
+		fmt.Printf("  schema version: %s\n", displayVersion(report.Version))
+
+		for _, m := range report.Migrations {
+			if report.Fixed {
+				fmt.Printf("  ✓ migrated %s -> %s: %s\n", displayVersion(m.From), m.To, m.Description)
+			} else {
+				fmt.Printf("  ! needs migration %s -> %s: %s\n", displayVersion(m.From), m.To, m.Description)
+			}
+		}
+		if report.Fixed {
+			fmt.Printf("  original saved to %s.bak\n", report.Path)
+		}
+
+		for _, issue := range report.Issues {
+			fmt.Printf("  ✗ %s\n", issue)
+		}
+
+		if report.OK() {
+			fmt.Println("  ✓ OK")
+		} else {
+			healthy = false
+		}
+	}
+
+	if !healthy {
+		if !doctorFix {
+			fmt.Println("\nRun 'sterm config doctor --fix' to apply migrations; other problems must be edited by hand.")
+		}
+		os.Exit(1)
+	}
+}
+
+// displayVersion shows an empty schema version as "none"
+func displayVersion(version string) string {
+	if version == "" {
+		return "none"
+	}
+	return version
+}
+
 func repeatString(s string, count int) string {
 	result := ""
 	for i := 0; i < count; i++ {
@@ -274,3 +583,21 @@ func repeatString(s string, count int) string {
 	}
 	return result
 }
+
+// describeRS485 formats RS-485 settings for display
+func describeRS485(rs485 serial.RS485Config) string {
+	parts := []string{"RTS high while sending"}
+	if rs485.RTSActiveLow {
+		parts[0] = "RTS low while sending"
+	}
+	if rs485.DelayBefore > 0 || rs485.DelayAfter > 0 {
+		parts = append(parts, fmt.Sprintf("delays %v/%v", rs485.DelayBefore, rs485.DelayAfter))
+	}
+	if rs485.RxDuringTx {
+		parts = append(parts, "echo")
+	}
+	if rs485.Software {
+		parts = append(parts, "software")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"sterm/pkg/app"
 	"sterm/pkg/config"
+	"sterm/pkg/decoder"
 	"sterm/pkg/serial"
 
 	"github.com/spf13/cobra"
@@ -19,11 +21,39 @@ var (
 	connectDataBits int
 	connectStopBits int
 	connectParity   string
+	connectFlow     string
 	connectTimeout  int
 
 	// Terminal behavior flags
 	sendWindowSize bool
+	windowSize     string
+	sttyCommand    string
 	terminalType   string
+	encodingName   string
+	monitorMode    bool
+	decoderNames   []string
+	noPlugins      bool
+	bridgeAddress  string
+	ignoreLock     bool
+	waitForDevice  bool
+	frameGap       string
+	suppressEcho   bool
+	metaEightBit   bool
+
+	// RS-485 flags, shared with config save
+	rs485Enabled  bool
+	rs485Before   time.Duration
+	rs485After    time.Duration
+	rs485Invert   bool
+	rs485Echo     bool
+	rs485Software bool
+
+	// History flags
+	historyFlushFile string
+	readTimes        bool
+	historyStream    string
+	printerFile      string
+	traceFile        string
 )
 
 // connectCmd represents the connect command
@@ -43,6 +73,50 @@ Examples:
   # Connect to /dev/ttyUSB0 with custom baud rate
   sterm connect /dev/ttyUSB0 -b 9600
 
+  # Connect to a device that outputs GBK instead of UTF-8
+  sterm connect /dev/ttyUSB0 --encoding gbk
+
+  # Run a local shell, e.g. to try the emulator without hardware
+  sterm connect pty:
+  sterm connect "pty:bash --norc"
+
+  # Talk to a virtual machine's serial port
+  sterm connect unix:/tmp/qemu-serial.sock
+  sterm connect "exec:qemu-system-arm -M virt -nographic -serial stdio -kernel zImage"
+
+  # Try sterm without hardware on a simulated device
+  sterm connect sim:echo
+  sterm connect "sim:replay=boot.log,chunk=16,latency=5ms"
+
+  # Play a saved history file back with its timing, twice as fast
+  sterm connect "replay:session.json,speed=2"
+
+  # Use a port on a networked serial server (ser2net, terminal servers)
+  sterm connect rfc2217:ser2net.lan:2000 -b 9600 --flow rtscts
+
+  # Show decoded GPS sentences or Modbus traffic next to the terminal
+  sterm connect /dev/ttyUSB0 -b 4800 --decode nmea
+  sterm connect /dev/ttyUSB0 -b 9600 --parity even --decode modbus
+
+  # Show each Modbus RTU message on its own line, split on 3.5 character times of silence
+  sterm connect /dev/ttyUSB0 -b 9600 --frame-gap 3.5c
+
+  # Watch a link another program is using without sending anything
+  sterm connect /dev/ttyUSB0 --monitor
+
+  # Share the port with a flasher or gdb on localhost:7000 while watching it
+  sterm connect /dev/ttyUSB0 --bridge localhost:7000
+  sterm connect /dev/ttyUSB0 --bridge rfc2217:localhost:7000
+
+  # Connect as soon as the board is plugged in, and again after replugging it
+  sterm connect /dev/ttyACM0 --wait
+
+  # Talk to a Modbus RTU bus through an RS-485 transceiver switched by RTS
+  sterm connect /dev/ttyUSB0 -b 19200 --parity even --rs485 --rs485-after 1ms
+
+  # Open a device whose lock file was left behind by a crashed program
+  sterm connect /dev/ttyUSB0 --ignore-lock
+
   # Connect using a saved configuration
   sterm connect mydevice`,
 	Args:    cobra.ExactArgs(1),
@@ -56,16 +130,46 @@ func init() {
 	connectCmd.Flags().IntVarP(&connectDataBits, "data", "d", 8, "data bits (5, 6, 7, or 8)")
 	connectCmd.Flags().IntVarP(&connectStopBits, "stop", "s", 1, "stop bits (1 or 2)")
 	connectCmd.Flags().StringVar(&connectParity, "parity", "none", "parity (none, odd, even, mark, space)")
+	connectCmd.Flags().StringVar(&connectFlow, "flow", serial.FlowNone, "flow control for RFC 2217 ports (none, xonxoff, rtscts)")
 	connectCmd.Flags().IntVarP(&connectTimeout, "timeout", "t", 10, "read timeout in seconds")
 
 	// Terminal behavior flags
-	connectCmd.Flags().BoolVar(&sendWindowSize, "send-window-size", false, "send terminal window size to remote device (may cause issues with some devices)")
+	connectCmd.Flags().StringVar(&windowSize, "window-size", "none", `how to tell the device the terminal size, on connecting and after resizes: none, xterm (resize sequence), stty (typed command), or a template like "resize {cols} {rows}\r"`)
+	connectCmd.Flags().BoolVar(&sendWindowSize, "send-window-size", false, "send terminal window size to remote device (same as --window-size xterm)")
+	_ = connectCmd.Flags().MarkDeprecated("send-window-size", "use --window-size xterm")
+	connectCmd.Flags().StringVar(&sttyCommand, "stty-command", "", `command typed at a shell prompt to tell it the size, for --window-size stty and the menu (default "stty rows {rows} cols {cols}\r")`)
 	connectCmd.Flags().StringVar(&terminalType, "term-type", "xterm", "terminal type to report (vt100, xterm, xterm-256color)")
+	connectCmd.Flags().BoolVar(&suppressEcho, "suppress-echo", false, "hide the device's echo of sent data and echo typed input locally, for half-duplex devices (saved profiles can turn it on from the F1 menu)")
+	connectCmd.Flags().BoolVar(&metaEightBit, "meta-8bit", false, "Alt+key sets the character's high bit instead of sending ESC before it, for legacy hosts (sent in the selected encoding)")
+	connectCmd.Flags().BoolVar(&monitorMode, "monitor", false, "open the port read-only and never send anything (not even replies to terminal queries)")
+	connectCmd.Flags().StringVar(&frameGap, "frame-gap", "", "start a new frame, on a new line, after this much silence: a duration (5ms) or character times (3.5c)")
+	connectCmd.Flags().StringSliceVar(&decoderNames, "decode", nil, "protocol decoders to show in a side panel ("+strings.Join(decoder.Names(), ", ")+", or one added by a plugin)")
+	connectCmd.Flags().BoolVar(&noPlugins, "no-plugins", false, "don't load plugins from ~/.sterm/plugins")
+	connectCmd.Flags().StringVar(&bridgeAddress, "bridge", "", "share the port with other tools on a TCP address, e.g. localhost:7000 (rfc2217:localhost:7000 lets them change line settings)")
+	addRS485Flags(connectCmd)
+	connectCmd.Flags().BoolVarP(&waitForDevice, "wait", "w", false, "wait for the device to be plugged in, and reconnect when it is unplugged and plugged back in")
+	connectCmd.Flags().BoolVar(&ignoreLock, "ignore-lock", false, "open the device even if another program holds its lock file (/var/lock/LCK..*)")
+	connectCmd.Flags().StringVar(&encodingName, "encoding", "utf-8", "character encoding of the device ("+strings.Join(app.CharsetNames(), ", ")+")")
+
+	// History flags
+	connectCmd.Flags().StringVar(&historyFlushFile, "history-flush", "", "append history evicted from memory to this file instead of discarding it (gzipped if it ends in .gz)")
+	connectCmd.Flags().StringVar(&historyStream, "history-stream", "", "append history to this file as it is recorded, for long sessions and tail -f (gzipped if it ends in .gz)")
+	connectCmd.Flags().StringVar(&printerFile, "printer-file", "", "append what the device sends to the terminal's printer (ESC[5i ... ESC[4i, print screen) to this file (default a log file, if printer.capture is set)")
+	connectCmd.Flags().StringVar(&traceFile, "trace", "", "write every escape sequence the device sends to this file, with its parameters and what the terminal did with it, for reporting emulation bugs")
+	connectCmd.Flags().BoolVar(&readTimes, "read-times", false, "keep when each read arrived in history, not just each entry (timestamped and JSON exports list every read)")
 }
 
 func runConnect(cmd *cobra.Command, args []string) {
 	target := args[0]
 	var serialConfig serial.SerialConfig
+	var autoLogin []config.LoginStep
+	var statusFormat config.StatusFormat
+	profileName := ""
+
+	if _, err := app.FindCharset(encodingName); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid encoding: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Check if target is a port or a configuration name
 	if isSerialPort(target) {
@@ -77,6 +181,8 @@ func runConnect(cmd *cobra.Command, args []string) {
 			StopBits: connectStopBits,
 			Parity:   connectParity,
 			Timeout:  time.Duration(connectTimeout) * time.Second,
+
+			FlowControl: connectFlow,
 		}
 
 		// Validate configuration
@@ -92,6 +198,7 @@ func runConnect(cmd *cobra.Command, args []string) {
 			fmt.Printf("  Data Bits: %d\n", connectDataBits)
 			fmt.Printf("  Stop Bits: %d\n", connectStopBits)
 			fmt.Printf("  Parity: %s\n", connectParity)
+			fmt.Printf("  Flow control: %s\n", connectFlow)
 		}
 	} else {
 		// Try to load as configuration
@@ -125,7 +232,24 @@ func runConnect(cmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 
+		// A profile matching a USB device finds it under its current name
+		if cfg.USB != nil && (!waitForDevice || serial.DevicePresent(cfg)) {
+			resolved, err := serial.ResolvePort(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Use 'sterm list -d' to see the connected USB devices.\n")
+				os.Exit(1)
+			}
+			cfg = resolved
+		}
+
 		serialConfig = cfg
+		profileName = target
+		autoLogin, _ = configManager.LoadAutoLogin(target)
+		statusFormat, _ = configManager.LoadStatusFormat(target)
+		if saved, _ := configManager.LoadSuppressEcho(target); saved {
+			suppressEcho = true
+		}
 
 		v, _ := cmd.InheritedFlags().GetBool("verbose")
 		if v {
@@ -138,8 +262,51 @@ func runConnect(cmd *cobra.Command, args []string) {
 		_ = configManager.UpdateLastUsed(target)
 	}
 
-	// Test connection
-	testConnection(serialConfig)
+	serialConfig.IgnoreLock = ignoreLock
+	if rs485 := rs485Config(); rs485 != nil {
+		serialConfig.RS485 = rs485
+	}
+	if err := serialConfig.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+	sizeStrategy, err := app.ParseWindowSize(windowSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if sendWindowSize && sizeStrategy.IsZero() {
+		sizeStrategy = app.WindowSize{Mode: app.WindowSizeXterm}
+	}
+	var typedSize string
+	if sttyCommand != "" {
+		if typedSize, err = app.ParseSttyCommand(sttyCommand); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var gap app.FrameGap
+	if frameGap != "" {
+		var err error
+		if gap, err = app.ParseFrameGap(frameGap); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Test connection. Other backends are checked when the session starts,
+	// since opening one twice would start a shell or subprocess twice.
+	if serial.IsDevicePort(serialConfig.Port) {
+		if waitForDevice && !serial.DevicePresent(serialConfig) {
+			device := serialConfig.Port
+			if serialConfig.USB != nil {
+				device = "USB device " + serialConfig.USB.String()
+			}
+			fmt.Printf("\n%s is not connected; waiting for it to be plugged in.\n", device)
+		} else {
+			testConnection(serialConfig, monitorMode)
+		}
+	}
 
 	// Launch terminal UI with additional options
 	fmt.Println("\nStarting terminal session...")
@@ -148,9 +315,27 @@ func runConnect(cmd *cobra.Command, args []string) {
 	// Pass terminal behavior options
 	debugFlag, _ := cmd.InheritedFlags().GetBool("debug")
 	appOpts := app.AppOptions{
-		SendWindowSize: sendWindowSize,
-		TerminalType:   terminalType,
-		DebugMode:      debugFlag,
+		WindowSize:       sizeStrategy,
+		SttyCommand:      typedSize,
+		TerminalType:     terminalType,
+		DebugMode:        debugFlag,
+		HistoryFlushFile: historyFlushFile,
+		ProfileName:      profileName,
+		Encoding:         encodingName,
+		Monitor:          monitorMode,
+		Decoders:         decoderNames,
+		NoPlugins:        noPlugins,
+		AutoLogin:        autoLogin,
+		Bridge:           bridgeAddress,
+		Wait:             waitForDevice,
+		FrameGap:         gap,
+		ReadTimes:        readTimes,
+		HistoryStream:    historyStream,
+		PrinterFile:      printerFile,
+		TraceFile:        traceFile,
+		SuppressEcho:     suppressEcho,
+		MetaEightBit:     metaEightBit,
+		StatusFormat:     statusFormat,
 	}
 
 	if err := app.RunInteractiveWithOptions(serialConfig, appOpts); err != nil {
@@ -159,6 +344,31 @@ func runConnect(cmd *cobra.Command, args []string) {
 	}
 }
 
+// addRS485Flags adds the flags setting up RS-485 direction control
+func addRS485Flags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&rs485Enabled, "rs485", false, "half-duplex RS-485: RTS switches the transceiver to send (by the driver on Linux where supported, otherwise by sterm)")
+	cmd.Flags().DurationVar(&rs485Before, "rs485-before", 0, "RS-485: assert RTS this long before sending, e.g. 1ms")
+	cmd.Flags().DurationVar(&rs485After, "rs485-after", 0, "RS-485: hold RTS this long after sending")
+	cmd.Flags().BoolVar(&rs485Invert, "rs485-invert", false, "RS-485: drive RTS low while sending")
+	cmd.Flags().BoolVar(&rs485Echo, "rs485-echo", false, "RS-485: keep receiving while sending, to see the echo (driver mode)")
+	cmd.Flags().BoolVar(&rs485Software, "rs485-software", false, "RS-485: toggle RTS from sterm even if the driver supports RS-485")
+}
+
+// rs485Config returns the RS-485 settings from the flags, or nil when no
+// RS-485 flag was given
+func rs485Config() *serial.RS485Config {
+	if !rs485Enabled && rs485Before == 0 && rs485After == 0 && !rs485Invert && !rs485Echo && !rs485Software {
+		return nil
+	}
+	return &serial.RS485Config{
+		DelayBefore:  rs485Before,
+		DelayAfter:   rs485After,
+		RTSActiveLow: rs485Invert,
+		RxDuringTx:   rs485Echo,
+		Software:     rs485Software,
+	}
+}
+
 func isSerialPort(name string) bool {
 	// Check if the name looks like a serial port
 	lower := strings.ToLower(name)
@@ -173,6 +383,11 @@ func isSerialPort(name string) bool {
 		return true
 	}
 
+	// Other backends such as "pty:"
+	if !serial.IsDevicePort(name) {
+		return true
+	}
+
 	// Check if it exists in the list of available ports
 	ports, err := serial.ListPorts()
 	if err == nil {
@@ -186,11 +401,15 @@ func isSerialPort(name string) bool {
 	return false
 }
 
-func testConnection(cfg serial.SerialConfig) {
+func testConnection(cfg serial.SerialConfig, monitor bool) {
 	fmt.Printf("\nTesting connection to %s...\n", cfg.Port)
 
-	// Try to open the port
+	// Try to open the port, read-only in monitor mode so the line settings
+	// of a port in use elsewhere aren't changed
 	sp := serial.NewSerialPort()
+	if monitor {
+		sp = serial.NewMonitorPort()
+	}
 	err := sp.Open(cfg)
 
 	if err != nil {
@@ -205,7 +424,11 @@ func testConnection(cfg serial.SerialConfig) {
 			fmt.Fprintf(os.Stderr, "  - On macOS: Check System Preferences > Security & Privacy\n")
 		}
 
-		if strings.Contains(errStr, "busy") || strings.Contains(errStr, "use") {
+		if errors.Is(err, serial.ErrPortBusy) {
+			fmt.Fprintf(os.Stderr, "  - Close the program named above, or wait for it to exit\n")
+			fmt.Fprintf(os.Stderr, "  - If its lock file is left over, run again with --ignore-lock\n")
+			fmt.Fprintf(os.Stderr, "  - To watch the traffic alongside it, use --monitor\n")
+		} else if strings.Contains(errStr, "busy") || strings.Contains(errStr, "use") {
 			fmt.Fprintf(os.Stderr, "  - The port may be in use by another application\n")
 			fmt.Fprintf(os.Stderr, "  - Close other terminal programs or serial monitors\n")
 		}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sterm/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffContext          int
+	diffIgnoreTimestamps bool
+	diffIgnoreWhitespace bool
+	diffKeepANSI         bool
+	diffColor            string
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <log-a>[:start-end] <log-b>[:start-end]",
+	Short: "Compare two saved session logs",
+	Long: `Compare two saved session logs line by line and highlight the differences.
+
+Useful for comparing boot logs between firmware versions. A line range can be
+appended to either file to compare regions of a log, including two regions of
+the same saved session.
+
+Examples:
+  sterm diff boot-v1.txt boot-v2.txt
+  sterm diff -t session.log:1-200 session.log:201-400`,
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+func init() {
+	diffCmd.Flags().IntVarP(&diffContext, "context", "C", 3, "number of context lines around changes")
+	diffCmd.Flags().BoolVarP(&diffIgnoreTimestamps, "ignore-timestamps", "t", false, "ignore leading [timestamp] prefixes")
+	diffCmd.Flags().BoolVarP(&diffIgnoreWhitespace, "ignore-whitespace", "w", false, "ignore whitespace differences")
+	diffCmd.Flags().BoolVar(&diffKeepANSI, "keep-ansi", false, "compare ANSI escape sequences instead of stripping them")
+	diffCmd.Flags().StringVar(&diffColor, "color", "auto", "colorize output (auto, always, never)")
+}
+
+// logRangeRegex matches a "path:start-end" log reference
+var logRangeRegex = regexp.MustCompile(`^(.+):(\d+)-(\d+)$`)
+
+func runDiff(cmd *cobra.Command, args []string) {
+	linesA, startA, err := readLogLines(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	linesB, startB, err := readLogLines(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := history.DiffOptions{
+		IgnoreTimestamps: diffIgnoreTimestamps,
+		IgnoreWhitespace: diffIgnoreWhitespace,
+		StripANSI:        !diffKeepANSI,
+	}
+	diff := history.DiffLines(linesA, linesB, opts)
+
+	// Number lines as in the files rather than the ranges compared
+	for i := range diff {
+		if diff[i].LineA > 0 {
+			diff[i].LineA += startA - 1
+		}
+		if diff[i].LineB > 0 {
+			diff[i].LineB += startB - 1
+		}
+	}
+
+	if !history.DiffHasChanges(diff) {
+		fmt.Println("Logs are identical.")
+		return
+	}
+
+	color := useColor(diffColor)
+	fmt.Printf("--- %s\n", args[0])
+	fmt.Printf("+++ %s\n", args[1])
+	printDiffHunks(diff, diffContext, color)
+
+	removed, added := 0, 0
+	for _, line := range diff {
+		switch line.Op {
+		case history.DiffDelete:
+			removed++
+		case history.DiffInsert:
+			added++
+		}
+	}
+	fmt.Printf("\n%d line(s) removed, %d line(s) added\n", removed, added)
+}
+
+// readLogLines reads a log file, optionally restricted to a 1-based inclusive
+// line range, and returns the lines with the file line number of the first
+func readLogLines(ref string) ([]string, int, error) {
+	path := ref
+	start, end := 0, 0
+
+	// Only treat the suffix as a range if the full reference isn't an existing file
+	if m := logRangeRegex.FindStringSubmatch(ref); m != nil {
+		if _, err := os.Stat(ref); err != nil {
+			path = m[1]
+			start, _ = strconv.Atoi(m[2])
+			end, _ = strconv.Atoi(m[3])
+			if start < 1 || end < start {
+				return nil, 0, fmt.Errorf("invalid line range %s-%s", m[2], m[3])
+			}
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if start > 0 && lineNum < start {
+			continue
+		}
+		if end > 0 && lineNum > end {
+			break
+		}
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read log: %w", err)
+	}
+
+	return lines, max(start, 1), nil
+}
+
+// useColor decides whether to colorize output based on the flag and stdout
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+}
+
+// printDiffHunks prints the diff in unified format with the given amount of context
+func printDiffHunks(diff []history.DiffLine, context int, color bool) {
+	if context < 0 {
+		context = 0
+	}
+
+	// Mark which lines are within context distance of a change
+	show := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == history.DiffEqual {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(diff)-1, i+context); j++ {
+			show[j] = true
+		}
+	}
+
+	for i := 0; i < len(diff); {
+		if !show[i] {
+			i++
+			continue
+		}
+
+		// Collect one contiguous hunk
+		hunkEnd := i
+		for hunkEnd < len(diff) && show[hunkEnd] {
+			hunkEnd++
+		}
+		printHunkHeader(diff[i:hunkEnd], color)
+		for _, line := range diff[i:hunkEnd] {
+			printDiffLine(line, color)
+		}
+		i = hunkEnd
+	}
+}
+
+// printHunkHeader prints a @@ -a,n +b,m @@ header for a hunk
+func printHunkHeader(hunk []history.DiffLine, color bool) {
+	startA, startB := 0, 0
+	countA, countB := 0, 0
+	for _, line := range hunk {
+		if line.LineA > 0 {
+			if startA == 0 {
+				startA = line.LineA
+			}
+			countA++
+		}
+		if line.LineB > 0 {
+			if startB == 0 {
+				startB = line.LineB
+			}
+			countB++
+		}
+	}
+
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", startA, countA, startB, countB)
+	if color {
+		header = "\x1b[36m" + header + "\x1b[0m"
+	}
+	fmt.Println(header)
+}
+
+// printDiffLine prints a single diff line with its +/- prefix
+func printDiffLine(line history.DiffLine, color bool) {
+	text := line.Op.String() + line.Text
+	if color {
+		switch line.Op {
+		case history.DiffDelete:
+			text = "\x1b[31m" + text + "\x1b[0m"
+		case history.DiffInsert:
+			text = "\x1b[32m" + text + "\x1b[0m"
+		}
+	}
+	fmt.Println(text)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sterm/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractFormat string
+	extractOutput string
+)
+
+// extractCmd represents the extract command
+var extractCmd = &cobra.Command{
+	Use:   "extract <log>[:start-end] <regex>",
+	Short: "Export fields matched in a saved session log as CSV or JSON",
+	Long: `Apply a regular expression with capture groups to every line of a saved
+session log and write the captured fields as CSV or JSON, one row per matching
+line, ready for a spreadsheet.
+
+Named groups such as (?P<temp>[-0-9.]+) give the column names; other groups are
+called field1, field2 and so on. Every row also has the line number, and the
+time from a leading [timestamp] when the log has one.
+
+Examples:
+  sterm extract boot.log 'temp=(?P<temp>[-0-9.]+) C'
+  sterm extract session.log:1-500 'V=(\d+)mV I=(\d+)mA' -o power.csv
+  sterm extract sensors.log '(?P<id>\w+): (?P<value>\S+)' --format json`,
+	Args: cobra.ExactArgs(2),
+	Run:  runExtract,
+}
+
+func init() {
+	extractCmd.Flags().StringVarP(&extractFormat, "format", "f", "", "output format: csv or json (default from the output file name, else csv)")
+	extractCmd.Flags().StringVarP(&extractOutput, "output", "o", "", "file to write instead of standard output")
+}
+
+func runExtract(cmd *cobra.Command, args []string) {
+	if err := extractFields(args[0], args[1], extractOutput, extractFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// extractFields writes the fields matched in a log to output, or to stdout
+// when output is empty
+func extractFields(logRef, pattern, output, format string) error {
+	extractor, err := history.NewFieldExtractor(pattern)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(output), ".json") {
+			format = "json"
+		}
+	}
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown format %q (use csv or json)", format)
+	}
+
+	lines, start, err := readLogLines(logRef)
+	if err != nil {
+		return err
+	}
+	rows := extractor.ExtractLines(lines)
+	for i := range rows {
+		rows[i].Line += start - 1
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == "json" {
+		err = extractor.WriteJSON(w, rows)
+	} else {
+		err = extractor.WriteCSV(w, rows)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "Extracted %d row(s) from %d line(s) to %s\n", len(rows), len(lines), output)
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"sterm/pkg/serial"
@@ -52,7 +54,10 @@ func runList(cmd *cobra.Command, args []string) {
 	case "csv":
 		printPortsCSV(portInfos)
 	case "json":
-		printPortsJSON(portInfos)
+		if err := printPortsJSON(os.Stdout, portInfos); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing ports: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		printPortsTable(portInfos)
 	}
@@ -79,7 +84,13 @@ func printPortsTable(portInfos []serial.PortInfo) {
 					fmt.Printf(" (SN: %s)", portInfo.SerialNumber)
 				}
 			}
+			if portInfo.Driver != "" {
+				fmt.Printf(" [%s]", portInfo.Driver)
+			}
 			fmt.Println()
+			if portInfo.ByID != "" {
+				fmt.Printf("    %s\n", portInfo.ByID)
+			}
 		}
 	} else {
 		// Simple list with indentation for table format
@@ -89,19 +100,24 @@ func printPortsTable(portInfos []serial.PortInfo) {
 	}
 
 	fmt.Println("\nUse 'sterm connect <port>' or 'sterm c <port>' to connect.")
+	if listDetails {
+		fmt.Println("The /dev/serial/by-id names and VID:PID:SN (sterm config save --usb) stay the same across replugging.")
+	}
 }
 
 func printPortsCSV(portInfos []serial.PortInfo) {
 	if listDetails {
-		fmt.Println("port,is_usb,vid,pid,product,serial_number")
+		fmt.Println("port,is_usb,vid,pid,product,serial_number,by_id,driver")
 		for _, portInfo := range portInfos {
-			fmt.Printf("%s,%t,%s,%s,%s,%s\n",
+			fmt.Printf("%s,%t,%s,%s,%s,%s,%s,%s\n",
 				portInfo.Name,
 				portInfo.IsUSB,
 				portInfo.VID,
 				portInfo.PID,
 				portInfo.Product,
-				portInfo.SerialNumber)
+				portInfo.SerialNumber,
+				portInfo.ByID,
+				portInfo.Driver)
 		}
 	} else {
 		fmt.Println("port")
@@ -111,49 +127,21 @@ func printPortsCSV(portInfos []serial.PortInfo) {
 	}
 }
 
-func printPortsJSON(portInfos []serial.PortInfo) {
-	if listDetails {
-		// Output full JSON objects with details
-		fmt.Println("[")
-		for i, portInfo := range portInfos {
-			fmt.Printf("  {\n")
-			fmt.Printf("    \"name\": \"%s\"", portInfo.Name)
-
-			if portInfo.IsUSB {
-				fmt.Printf(",\n    \"is_usb\": true")
-				if portInfo.VID != "" {
-					fmt.Printf(",\n    \"vid\": \"%s\"", portInfo.VID)
-				}
-				if portInfo.PID != "" {
-					fmt.Printf(",\n    \"pid\": \"%s\"", portInfo.PID)
-				}
-				if portInfo.Product != "" {
-					fmt.Printf(",\n    \"product\": \"%s\"", portInfo.Product)
-				}
-				if portInfo.SerialNumber != "" {
-					fmt.Printf(",\n    \"serial_number\": \"%s\"", portInfo.SerialNumber)
-				}
-			}
-
-			fmt.Printf("\n  }")
-			if i < len(portInfos)-1 {
-				fmt.Printf(",")
-			}
-			fmt.Printf("\n")
-		}
-		fmt.Println("]")
-	} else {
-		// Simple array of port names
-		fmt.Println("[")
+// printPortsJSON writes the ports as a JSON array: names only, or objects
+// with the details as tagged on PortInfo
+func printPortsJSON(w io.Writer, portInfos []serial.PortInfo) error {
+	var v any = portInfos
+	if !listDetails {
+		names := make([]string, len(portInfos))
 		for i, portInfo := range portInfos {
-			fmt.Printf("  \"%s\"", portInfo.Name)
-
-			if i < len(portInfos)-1 {
-				fmt.Printf(",\n")
-			} else {
-				fmt.Printf("\n")
-			}
+			names[i] = portInfo.Name
 		}
-		fmt.Println("]")
+		v = names
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode ports: %w", err)
 	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
 }
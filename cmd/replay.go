@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sterm/pkg/history"
+	"sterm/pkg/serial"
+
+	"github.com/spf13/cobra"
+)
+
+var replaySpeed string
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <history-file>",
+	Short: "Play back a saved session history through the terminal",
+	Long: `Play the received data in a saved history file back through the terminal
+emulator with the timing it was recorded with, so a captured session can be
+examined after the fact with scrollback, search, marks and decoders.
+
+JSON and timestamped history exports are accepted, gzipped or not. Files
+saved with --read-times replay read by read. The speed can be changed while
+playing from Replay Speed... in the F1 menu; the status bar shows the
+position in the recording.
+
+Examples:
+  sterm replay session.json
+  sterm replay capture.log.gz --speed 10
+  sterm replay boot.log --speed max`,
+	Args: cobra.ExactArgs(1),
+	Run:  runReplay,
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replaySpeed, "speed", "1", "playback speed: 2 plays twice as fast, 0.5 at half speed, max without gaps")
+}
+
+func runReplay(cmd *cobra.Command, args []string) {
+	if _, err := serial.ParseReplaySpeed(replaySpeed); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Fail here rather than in the terminal on a file that can't be replayed
+	path := args[0]
+	if strings.Contains(path, ",") {
+		fmt.Fprintf(os.Stderr, "Error: history file names can't contain commas\n")
+		os.Exit(1)
+	}
+	if _, err := history.LoadEntries(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s can't be replayed: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	runConnect(cmd, []string{serial.ReplayPrefix + path + ",speed=" + replaySpeed})
+}
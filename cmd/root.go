@@ -45,6 +45,10 @@ func init() {
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(connectCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(extractCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(secretCmd)
 }
 
 // initConfig reads in config file and ENV variables if set
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"sterm/pkg/secrets"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// secretCmd manages secrets in the OS keychain
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage passwords stored in the OS keychain",
+	Long: `Store, check and delete the passwords that auto-login sends, so they never
+go into the configuration files.
+
+Secrets live in the Secret Service (GNOME Keyring, KWallet) on Linux, the
+login keychain on macOS and the Credential Manager on Windows. Setting
+STERM_SECRET_<NAME> in the environment overrides a stored secret.`,
+}
+
+// secretSetCmd stores a secret
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret, prompting for its value",
+	Long: `Store a secret in the OS keychain. The value is read without echo when run
+in a terminal, otherwise from the first line of standard input.
+
+Example:
+  sterm secret set router
+  pass show router | sterm secret set router`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSecretSet,
+}
+
+// secretCheckCmd tells where a secret would be read from
+var secretCheckCmd = &cobra.Command{
+	Use:   "check <name>",
+	Short: "Check that a secret can be read, without showing it",
+	Long: `Check that a secret can be read and show where it comes from. The value
+itself is never printed.
+
+Example:
+  sterm secret check router`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSecretCheck,
+}
+
+// secretDeleteCmd removes a secret
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a secret from the OS keychain",
+	Long: `Delete a secret from the OS keychain.
+
+Example:
+  sterm secret delete router`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSecretDelete,
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretCheckCmd)
+	secretCmd.AddCommand(secretDeleteCmd)
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) {
+	name := args[0]
+	value, err := readSecretValue(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider := secrets.Default()
+	if err := provider.Set(name, value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Secret '%s' stored in %s.\n", name, provider.Name())
+}
+
+// readSecretValue prompts for a secret without echo, or reads a line from
+// standard input when it isn't a terminal
+func readSecretValue(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Value for '%s': ", name)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func runSecretCheck(cmd *cobra.Command, args []string) {
+	name := args[0]
+	if _, ok := os.LookupEnv(secrets.EnvName(name)); ok {
+		fmt.Printf("Secret '%s' is set by %s.\n", name, secrets.EnvName(name))
+		return
+	}
+
+	provider := secrets.Default()
+	if _, err := provider.Get(name); err != nil {
+		if errors.Is(err, secrets.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Secret '%s' is not stored in %s and %s is not set.\n", name, provider.Name(), secrets.EnvName(name))
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Secret '%s' is stored in %s.\n", name, provider.Name())
+}
+
+func runSecretDelete(cmd *cobra.Command, args []string) {
+	name := args[0]
+	provider := secrets.Default()
+	if err := provider.Delete(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Secret '%s' deleted from %s.\n", name, provider.Name())
+}
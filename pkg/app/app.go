@@ -2,17 +2,22 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"sterm/pkg/config"
 	"sterm/pkg/history"
+	"sterm/pkg/logging"
 	"sterm/pkg/menu"
+	"sterm/pkg/plugin"
 	"sterm/pkg/serial"
 	"sterm/pkg/terminal"
 
@@ -43,16 +48,21 @@ type Application struct {
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
 	mu           sync.RWMutex
-	updateNotify chan struct{} // Channel to notify UI updates
-	pauseChan    chan bool     // Channel to control pause state
+	updateNotify chan struct{}  // Channel to notify UI updates
+	frames       frameScheduler // When updates are drawn
+	pauseChan    chan bool      // Channel to control pause state
 
 	// State
 	isRunning     bool
 	isPaused      bool
-	localEcho     bool      // Whether to echo typed characters locally
-	lineWrap      bool      // Whether to wrap long lines
-	statusMessage string    // Temporary status message
-	statusTime    time.Time // When status message was set
+	localEcho     bool               // Whether to echo typed characters locally
+	lineWrap      bool               // Whether to wrap long lines
+	showControls  atomic.Bool        // Show received control characters instead of acting on them
+	wrapMarkers   atomic.Bool        // Mark rows that wrap onto the next in a gutter column
+	inputLocked   atomic.Bool        // Keyboard input is not sent to the device
+	literalNext   bool               // The next key goes to the device without sterm acting on it
+	passthrough   atomic.Bool        // All keys but the passthrough binding go to the device
+	notifications *NotificationQueue // Temporary status messages
 
 	// Cached status bar strings
 	cachedStatusLeft  string
@@ -60,46 +70,227 @@ type Application struct {
 	cachedBytesRecv   int64
 	cachedBytesSent   int64
 
+	// Display filter, status bar prompt and modal dialog
+	filter     *DisplayFilter
+	prompt     *statusPrompt
+	dialog     menu.Dialog
+	fullRedraw atomic.Bool // Force a full redraw on the next update
+
+	scrollbarDrag bool           // Scrollbar thumb is being dragged with the mouse
+	selection     selectionState // Local mouse selection
+
+	// Link detection settings and hint mode state
+	links       config.LinkSettings
+	linkHints   *linkHints
+	linkClicked bool // Ctrl+click opened an OSC 8 link; its release is dropped
+
+	// Bell handling and whether the host terminal window has focus
+	bell    bellState
+	focused atomic.Bool
+
+	// Alerts when the device goes quiet or comes back
+	watchdog activityWatchdog
+
+	// Patterns in received lines that start captures or pause the display
+	triggers triggerState
+
+	// Profile's expect/send steps run after connecting
+	autoLogin autoLoginState
+
+	// Checkpoints received data so it can be restored after a crash
+	autosave autosaveState
+
+	// Paste confirmation settings and the paste being received
+	pasteSettings config.PasteSettings
+	paste         pasteState
+
+	// Protocol decoders and the frames they found
+	decoders decoderState
+
+	// GPS fix from NMEA sentences, shown in the dashboard
+	gps gpsDashboard
+
+	// Numbers matched in received lines, shown in the plot pane
+	plot plotState
+
+	// Parser and renderer internals shown over the terminal
+	debugOverlay debugOverlayState
+
+	// Timestamps set with the mark key and the intervals between them
+	marks markState
+
+	// TCP server sharing the port with other tools
+	bridge bridgeState
+
+	// Waiting for the device to be plugged in
+	deviceWait deviceWaitState
+
+	// Data waiting to be written to the port
+	tx txQueue
+
+	// Splitting received data into frames on idle gaps
+	framing framingState
+
+	// File history is appended to as it is recorded
+	historyStream historyStreamState
+
+	// File evicted history is flushed to, opened on the first eviction
+	historyFlush historyStreamState
+
+	// File escape sequences are traced to
+	trace traceState
+
+	// Capturing what the device prints
+	printer printerState
+
+	// Sent and received data shown apart, from history
+	txrx txrxViewState
+
+	// The device's echo of sent data, dropped from the display
+	echo echoSuppressor
+
+	// The second last shown by a status bar template with {time}
+	statusSecond atomic.Int64
+
+	// Reporting the terminal size to the device
+	windowSize windowSizeState
+
+	// The second last shown in the title bar, and when the notification
+	// history was last opened (Unix nanoseconds); later alerts are shown
+	titleSecond atomic.Int64
+	alertsSeen  atomic.Int64
+
+	// Baud rate the driver set when it differs from the requested one
+	driverBaud atomic.Int64
+
+	// User extensions from ~/.sterm/plugins (nil when disabled)
+	plugins *plugin.Manager
+
+	// Set once a panic is being handled
+	crashing atomic.Bool
+
+	// Character encoding of the device, converted to and from UTF-8
+	codec atomic.Pointer[charsetCodec]
+
+	// East Asian ambiguous width reported by the host terminal at startup
+	hostAmbiguousWide  bool
+	hostAmbiguousKnown bool
+
+	// Bookmarks/annotations in the output
+	bookmarks *BookmarkList
+
+	// Command line history for recall
+	cmdHistory     *history.CommandHistory
+	typedLine      []rune // Command line currently being typed
+	typedLineValid bool   // False once cursor keys make the line untrackable
+
+	// Payloads sent from the send-hex box, loaded on first use
+	payloadHistory *history.CommandHistory
+
 	// Configuration
 	config AppConfig
 
-	// Debug
-	debugLog  *os.File
-	debugMode bool
+	// Settings that can be reloaded while running
+	theme           statusTheme
+	statusBar       config.StatusBarSettings
+	titleBar        config.TitleBarSettings
+	logging         config.LoggingSettings // Log directory and file name template
+	altKeys         map[rune]rune          // Pressed Alt+ letter -> default letter of the bound action
+	chordPrefix     string                 // Prefix key of chords, e.g. "Ctrl+A"; empty when Alt+ keys are used directly
+	settingsPath    string
+	settingsWatcher *config.SettingsWatcher
+
+	// Leveled diagnostic log, written to ~/.sterm/sterm-debug.log when enabled
+	log *logging.Logger
 }
 
 // AppConfig contains application configuration
 type AppConfig struct {
-	SerialConfig            serial.SerialConfig
-	TerminalWidth           int
-	TerminalHeight          int
-	HistorySize             int
-	EnableMouse             bool
-	EnableShortcuts         bool
-	SaveHistory             bool
-	HistoryFormat           history.FileFormat
-	SendWindowSizeOnConnect bool   // Send window size when connecting
-	SendWindowSizeOnResize  bool   // Send window size when resizing
-	TerminalType            string // Terminal type to report (vt100, xterm, etc.)
-	Version                 string // Application version
-	DebugMode               bool   // Enable debug logging
+	SerialConfig       serial.SerialConfig
+	TerminalWidth      int
+	TerminalHeight     int
+	HistorySize        int
+	EnableMouse        bool
+	EnableShortcuts    bool
+	SaveHistory        bool
+	HistoryFormat      history.FileFormat
+	TerminalType       string   // Terminal type to report (vt100, xterm, etc.)
+	Version            string   // Application version
+	DebugMode          bool     // Enable debug logging
+	HistoryWarnPercent int      // Warn when history usage reaches this percentage (0 = off)
+	HistoryFlushFile   string   // Append evicted history entries to this file (empty = discard)
+	ProfileName        string   // Saved configuration name, used to key per-profile data
+	Encoding           string   // Character encoding of the device (utf-8, latin1, cp437, gbk, shift-jis)
+	Monitor            bool     // Open the port read-only and never send anything, not even query responses
+	Decoders           []string // Protocol decoders to run from the start (nmea, modbus, slip, kiss)
+	DisablePlugins     bool     // Don't load plugins from ~/.sterm/plugins
+
+	// AutoLogin holds the profile's expect/send steps, run after connecting
+	AutoLogin []config.LoginStep
+
+	// Bridge is a TCP address to share the port on, e.g. "localhost:7000"
+	// or "rfc2217:localhost:7000" (empty = no bridge)
+	Bridge string
+
+	// WaitForDevice waits for a missing device instead of failing, and
+	// reconnects when it is unplugged and plugged back in
+	WaitForDevice bool
+
+	// FrameGap splits received data into frames on idle gaps this long,
+	// starting each on a new line (zero = off)
+	FrameGap FrameGap
+
+	// ReadTimestamps keeps when each read arrived in history entries, for
+	// timing analysis in exports and replay
+	ReadTimestamps bool
+
+	// PrinterFile gets what the device prints appended (empty = a file
+	// named like other logs, if printer.capture is on)
+	PrinterFile string
+
+	// HistoryStreamFile gets every history entry appended as it is
+	// recorded, in HistoryFormat (empty = off)
+	HistoryStreamFile string
+
+	// TraceFile gets every escape sequence the emulator parses written,
+	// with what was done with it (empty = off)
+	TraceFile string
+
+	// SuppressEcho hides the device's echo of sent data and echoes typed
+	// input locally instead, for half-duplex devices
+	SuppressEcho bool
+
+	// StatusFormat holds the profile's status bar templates, used over the
+	// settings file's
+	StatusFormat config.StatusFormat
+
+	// WindowSize is how the terminal size is reported to the device, on
+	// connecting and after resizes (zero = not reported)
+	WindowSize WindowSize
+
+	// SttyCommand is the template typed at a shell prompt to tell it the
+	// size, with {rows} and {cols} (empty = "stty rows R cols C")
+	SttyCommand string
+
+	// MetaEightBit makes Alt set the high bit of ASCII characters instead
+	// of sending ESC before them, for legacy hosts
+	MetaEightBit bool
 }
 
 // DefaultAppConfig returns default application configuration
 func DefaultAppConfig() AppConfig {
 	return AppConfig{
-		Version:                 "1.0.0",
-		SerialConfig:            serial.DefaultConfig(),
-		TerminalWidth:           80,
-		TerminalHeight:          24,
-		HistorySize:             10 * 1024 * 1024, // 10MB
-		EnableMouse:             true,
-		EnableShortcuts:         true,
-		SaveHistory:             true,
-		HistoryFormat:           history.FormatTimestamped,
-		SendWindowSizeOnConnect: false,   // Disabled by default - can cause issues with some devices
-		SendWindowSizeOnResize:  false,   // Disabled by default
-		TerminalType:            "xterm", // Default to xterm for better compatibility
+		Version:            "1.0.0",
+		SerialConfig:       serial.DefaultConfig(),
+		TerminalWidth:      80,
+		TerminalHeight:     24,
+		HistorySize:        10 * 1024 * 1024, // 10MB
+		EnableMouse:        true,
+		EnableShortcuts:    true,
+		SaveHistory:        true,
+		HistoryFormat:      history.FormatTimestamped,
+		TerminalType:       "xterm", // Default to xterm for better compatibility
+		HistoryWarnPercent: 80,
 	}
 }
 
@@ -154,49 +345,25 @@ func (s *Session) GetStats() (bytesSent, bytesRecv int64) {
 	return s.BytesSent, s.BytesRecv
 }
 
-// logDebug writes debug message to log file
+// logDebug writes a debug message from the application to the log
 func (app *Application) logDebug(format string, args ...interface{}) {
-	if app.debugLog != nil {
-		msg := fmt.Sprintf(format, args...)
-		timestamp := time.Now().Format("15:04:05.000")
-		fmt.Fprintf(app.debugLog, "[%s] %s\n", timestamp, msg)
-		_ = app.debugLog.Sync() // Ensure it's written immediately
-	}
+	app.log.Logf(logging.ModuleApp, logging.LevelDebug, format, args...)
 }
 
-// Debugf implements the terminal.Logger interface
-func (app *Application) Debugf(format string, args ...interface{}) {
-	app.logDebug(format, args...)
+// logSerial writes a debug message about port I/O to the log
+func (app *Application) logSerial(format string, args ...interface{}) {
+	app.log.Logf(logging.ModuleSerial, logging.LevelDebug, format, args...)
 }
 
-// createDebugLog creates debug log file in user's .sterm directory
-func createDebugLog() *os.File {
-	// Get user's home directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		// Fallback to current directory
-		debugLog, _ := os.Create("sterm-debug.log")
-		return debugLog
-	}
-
-	// Create .sterm directory if it doesn't exist
-	serialTerminalDir := filepath.Join(homeDir, ".sterm")
-	if err := os.MkdirAll(serialTerminalDir, 0755); err != nil {
-		// Fallback to current directory
-		debugLog, _ := os.Create("sterm-debug.log")
-		return debugLog
-	}
-
-	// Create debug log file in the directory
-	debugLogPath := filepath.Join(serialTerminalDir, "sterm-debug.log")
-	debugLog, err := os.Create(debugLogPath)
-	if err != nil {
-		// Fallback to current directory
-		debugLog, _ = os.Create("sterm-debug.log")
-		return debugLog
-	}
+// logRenderer writes a debug message about screen drawing to the log
+func (app *Application) logRenderer(format string, args ...interface{}) {
+	app.log.Logf(logging.ModuleRenderer, logging.LevelDebug, format, args...)
+}
 
-	return debugLog
+// debugEnabled reports whether application debug messages are being logged,
+// for callers that do extra work only when debugging
+func (app *Application) debugEnabled() bool {
+	return app.log.Enabled(logging.ModuleApp, logging.LevelDebug)
 }
 
 // NewApplication creates a new application instance
@@ -209,26 +376,32 @@ func NewApplication(config AppConfig) (*Application, error) {
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create debug log file only if debug mode is enabled
-	var debugLog *os.File
+	// The log file is only created if debug mode is enabled, here or in settings
+	log := logging.New()
 	if config.DebugMode {
-		debugLog = createDebugLog()
+		if err := log.Open(logging.DefaultPath()); err == nil {
+			log.SetLevel(logging.LevelDebug)
+		}
 	}
 
 	// Create components
 	app := &Application{
-		config:       config,
-		ctx:          ctx,
-		cancel:       cancel,
-		updateNotify: make(chan struct{}, 100), // Buffered channel for updates
-		pauseChan:    make(chan bool, 1),       // Channel for pause control
-		isRunning:    false,
-		isPaused:     false,
-		localEcho:    false, // Local echo off by default
-		lineWrap:     true,  // Line wrap on by default
-		debugLog:     debugLog,
-		debugMode:    config.DebugMode,
-	}
+		config:        config,
+		ctx:           ctx,
+		cancel:        cancel,
+		updateNotify:  make(chan struct{}, 100), // Buffered channel for updates
+		pauseChan:     make(chan bool, 1),       // Channel for pause control
+		isRunning:     false,
+		isPaused:      false,
+		localEcho:     false, // Local echo off by default
+		lineWrap:      true,  // Line wrap on by default
+		filter:        NewDisplayFilter(),
+		notifications: NewNotificationQueue(),
+		bookmarks:     NewBookmarkList(),
+		log:           log,
+	}
+	app.focused.Store(true) // Until the host terminal reports otherwise
+	app.autoLogin.steps = config.AutoLogin
 
 	// Initialize components
 	if err := app.initializeComponents(); err != nil {
@@ -241,15 +414,59 @@ func NewApplication(config AppConfig) (*Application, error) {
 
 // initializeComponents initializes all application components
 func (app *Application) initializeComponents() error {
-	// Create serial port
-	app.serialPort = serial.NewSerialPort()
+	// Create the port: a serial device, or another backend selected by a
+	// prefix such as "pty:". Monitor mode only ever reads from it.
+	port := app.config.SerialConfig.Port
+	switch {
+	case app.config.Monitor && serial.IsDevicePort(port):
+		app.serialPort = serial.NewMonitorPort()
+	case app.config.Monitor:
+		app.serialPort = &serial.ReadOnlyPort{SerialPort: serial.NewPortFor(port)}
+	default:
+		app.serialPort = serial.NewPortFor(port)
+	}
+
+	// Set up the device's character encoding
+	charset, err := FindCharset(app.config.Encoding)
+	if err != nil {
+		return err
+	}
+	app.setCharset(charset)
+
+	// Load plugins first, since they can add decoders
+	app.loadPlugins()
+
+	// Start the requested protocol decoders with their panel shown
+	for _, name := range app.config.Decoders {
+		if err := app.setDecoderEnabled(name, true); err != nil {
+			return err
+		}
+	}
+	app.decoders.visible = len(app.config.Decoders) > 0
+	app.windowSize.strategy = app.config.WindowSize
+	app.printer.path = app.config.PrinterFile
+	if app.config.SuppressEcho {
+		app.echo.setEnabled(true)
+		app.localEcho = true
+	}
+	app.setFramingGap(app.config.FrameGap)
 
 	// Create config manager
 	app.configMgr = config.NewFileConfigManager("")
 
 	// Create history manager
-	var err error
-	app.historyMgr = history.NewMemoryHistoryManager(app.config.HistorySize)
+	historyMgr := history.NewMemoryHistoryManager(app.config.HistorySize)
+	// Compress data past the newest megabyte (less for small histories), so
+	// the history size holds several times more of a session
+	_ = historyMgr.SetCompression(min(history.DefaultLiveWindow, app.config.HistorySize/4))
+	app.historyMgr = historyMgr
+	app.setupHistoryWatch()
+
+	// Load command history for this connection profile
+	app.setupCommandHistory()
+
+	// Probe the host terminal while we still own the tty
+	app.detectHostAmbiguousWidth()
 
 	// Create screen
 	screen, err := tcell.NewScreen()
@@ -261,6 +478,12 @@ func (app *Application) initializeComponents() error {
 		return fmt.Errorf("failed to initialize screen: %w", err)
 	}
 
+	// Focus reports tell whether bell notifications should go to the desktop
+	screen.EnableFocus()
+
+	// Pastes are collected so long or unusual ones can be confirmed
+	screen.EnablePaste()
+
 	// Use default terminal colors instead of forcing black background
 	defaultStyle := tcell.StyleDefault.
 		Background(tcell.ColorReset).
@@ -272,7 +495,8 @@ func (app *Application) initializeComponents() error {
 	// Mouse will only be enabled when terminal explicitly requests it
 	// Users can use Ctrl+PageUp/Down for scrolling instead
 
-	app.screen = screen
+	// Drawn through a wrapper that leaves room for the title bar
+	app.screen = newTitleScreen(screen)
 
 	// Get actual terminal dimensions from tcell screen
 	width, height := screen.Size()
@@ -299,7 +523,19 @@ func (app *Application) initializeComponents() error {
 	app.terminal.SetLineWrap(app.lineWrap)
 
 	// Set logger for terminal debugging
-	app.terminal.SetLogger(app)
+	app.terminal.SetLogger(app.log.Module(logging.ModuleParser))
+
+	// BEL from the device rings the configured bell
+	app.terminal.SetBellCallback(app.ringBell)
+
+	// Bookmarks follow their text when a resize rewraps the scrollback
+	app.terminal.SetRewrapCallback(app.bookmarks.Remap)
+
+	// Answer the device's terminal queries, except in monitor mode
+	app.terminal.SetResponseCallback(app.sendTerminalResponse)
+
+	// Data the device prints goes to a capture file
+	app.terminal.SetPrinterCallback(app.printData)
 
 	// Set mouse mode change callback to dynamically enable/disable mouse
 	app.terminal.SetMouseModeChangeCallback(func(mode terminal.MouseMode) {
@@ -320,6 +556,7 @@ func (app *Application) initializeComponents() error {
 
 	// Create input processor (single instance to maintain state)
 	app.inputProcessor = terminal.NewInputProcessor(app.terminal)
+	app.inputProcessor.GetKeyHandler().SetMetaEightBit(app.config.MetaEightBit)
 
 	// Create shortcut manager
 	app.shortcuts = terminal.NewShortcutManager()
@@ -330,9 +567,53 @@ func (app *Application) initializeComponents() error {
 	app.mainMenu = menu.NewMenu("Serial Terminal", app.screen)
 	app.setupMenu()
 
+	// Apply user settings (theme, keybindings, logging, status bar)
+	app.loadSettings()
+
 	return nil
 }
 
+// setupHistoryWatch warns before history starts discarding data and
+// optionally flushes evicted entries to disk
+func (app *Application) setupHistoryWatch() {
+	obs, ok := app.historyMgr.(history.ObservableHistory)
+	if !ok {
+		return
+	}
+
+	if app.config.HistoryWarnPercent > 0 && app.config.HistoryWarnPercent <= 100 {
+		_ = obs.SetWatermarks([]int{app.config.HistoryWarnPercent}, func(level, used, max int) {
+			if app.config.HistoryFlushFile != "" {
+				app.notifyWarning("History %d%% full - old data will be flushed to %s", level, app.config.HistoryFlushFile)
+			} else {
+				app.notifyWarning("History %d%% full - old data will be discarded (Alt+S to save)", level)
+			}
+		})
+	}
+
+	obs.SetWriteCallback(app.historyWritten)
+
+	evictionReported := false
+	obs.SetEvictionCallback(func(evicted []history.HistoryEntry) {
+		if app.config.HistoryFlushFile != "" {
+			if err := app.flushEvicted(evicted); err != nil {
+				app.logDebug("Failed to flush evicted history: %v", err)
+				if !evictionReported {
+					evictionReported = true
+					app.notifyError("History flush failed: %v", err)
+				}
+			}
+			return
+		}
+
+		// Only tell the user once; the watermark warning already preceded this
+		if !evictionReported {
+			evictionReported = true
+			app.notifyWarning("History full - oldest data is being discarded")
+		}
+	})
+}
+
 // setupShortcuts sets up application shortcuts
 func (app *Application) setupShortcuts() {
 	// Exit shortcut - use Ctrl+Shift+Q to avoid conflict with terminal
@@ -379,6 +660,22 @@ func (app *Application) setupShortcuts() {
 		}
 		return nil
 	})
+	if help := app.shortcuts.GetShortcut("help"); help != nil {
+		help.Description = "Open/close the menu"
+	}
+
+	// Keyboard shortcut overlay
+	app.shortcuts.CustomShortcut(
+		"keyboard-help",
+		"Show keyboard shortcuts",
+		tcell.KeyF2,
+		0,
+		0,
+		func() error {
+			app.showHelp()
+			return nil
+		},
+	)
 }
 
 // Start starts the application
@@ -390,9 +687,41 @@ func (app *Application) Start() error {
 		return fmt.Errorf("application is already running")
 	}
 
-	// Open serial port
+	// Open serial port, or wait for the device if it isn't plugged in
+	app.setAutoConnect(app.config.WaitForDevice)
 	if err := app.serialPort.Open(app.config.SerialConfig); err != nil {
-		return fmt.Errorf("failed to open serial port: %w", err)
+		if !app.canWaitForDevice() {
+			return fmt.Errorf("failed to open serial port: %w", err)
+		}
+		app.waitForDevice()
+	} else {
+		app.portOpened()
+		app.pluginsConnected()
+		app.startAutoLogin()
+	}
+
+	if app.config.Bridge != "" {
+		if err := app.startBridge(app.config.Bridge); err != nil {
+			app.serialPort.Close()
+			return err
+		}
+	}
+
+	if app.config.HistoryStreamFile != "" {
+		if err := app.startHistoryStream(app.config.HistoryStreamFile, app.config.HistoryFormat, false); err != nil {
+			app.stopBridge()
+			app.serialPort.Close()
+			return err
+		}
+	}
+
+	if app.config.TraceFile != "" {
+		if err := app.startTrace(app.config.TraceFile); err != nil {
+			_, _ = app.stopHistoryStream()
+			app.stopBridge()
+			app.serialPort.Close()
+			return err
+		}
 	}
 
 	// Create session
@@ -410,43 +739,42 @@ func (app *Application) Start() error {
 	// Set running state
 	app.isRunning = true
 
-	// Send initial terminal size to remote device if configured
-	if app.config.SendWindowSizeOnConnect {
-		width, height := app.screen.Size()
-		// Reserve 1 line for status bar
-		terminalHeight := height - 1
-		if app.serialPort != nil && app.serialPort.IsOpen() {
-			// Send terminal type response based on configuration
-			if app.config.TerminalType == "vt100" {
-				_, _ = app.serialPort.Write([]byte("\x1b[?1;2c")) // VT100 with AVO
-			} else if app.config.TerminalType == "xterm" {
-				_, _ = app.serialPort.Write([]byte("\x1b[?62;c")) // xterm
-			}
-
-			// Send window size using stty-compatible format
-			// Some systems expect: ESC[8;<height>;<width>t
-			// Others use environment variables or stty
-			sizeSeq := fmt.Sprintf("\x1b[8;%d;%dt", terminalHeight, width)
-			_, _ = app.serialPort.Write([]byte(sizeSeq))
-
-			// Also try sending as environment variable format
-			// This helps with programs that use LINES/COLUMNS
-			envSeq := fmt.Sprintf("\x1b]0;LINES=%d;COLUMNS=%d\x07", terminalHeight, width)
-			_, _ = app.serialPort.Write([]byte(envSeq))
+	// Tell a pty how big the screen is
+	if width, height := app.terminalSize(); height > 0 {
+		app.resizePort(width, height)
+	}
 
-			app.logDebug("Sent initial terminal size %dx%d to remote", width, terminalHeight)
-		}
+	// Tell the device too, if it is told the configured way. A stty
+	// command waits for the shell prompt.
+	if app.windowSizeStrategy().Mode == WindowSizeStty {
+		go app.reportSizeAtPrompt()
+	} else {
+		app.reportWindowSize(true)
 	}
 
 	// Start data flow goroutines
 	app.wg.Add(2)
 	go app.handleSerialInput()
 	go app.handleUserInput()
+	app.startTxQueue()
 
 	// Start UI update loop
 	app.wg.Add(1)
 	go app.updateUI()
 
+	// Start the idle watchdog
+	app.watchdog.start(time.Now())
+	app.wg.Add(1)
+	go app.runWatchdog()
+
+	// Start crash recovery checkpoints, offering to restore a crashed session
+	app.startAutosave()
+	app.wg.Add(1)
+	go app.runAutosave()
+
+	// Watch the settings file for live changes
+	app.startSettingsWatcher()
+
 	return nil
 }
 
@@ -470,6 +798,9 @@ func (app *Application) Stop() error {
 	// Cancel context to stop goroutines
 	app.cancel()
 
+	// Stop settings reloads before the screen goes away
+	app.stopSettingsWatcher()
+
 	// Post a special event to break out of PollEvent
 	if app.screen != nil {
 		app.logDebug("Posting interrupt event")
@@ -512,143 +843,204 @@ func (app *Application) Stop() error {
 		app.screen = nil
 	}
 
+	app.plugins.Close()
+	app.stopAutoLogin()
+	app.stopBridge()
+
+	if _, err := app.triggers.stopCapture(); err != nil {
+		app.logDebug("Failed to close capture: %v", err)
+	}
+	if _, err := app.stopHistoryStream(); err != nil {
+		app.logDebug("Failed to close history stream: %v", err)
+	}
+	if err := app.closeHistoryFlush(); err != nil {
+		app.logDebug("Failed to close history flush file: %v", err)
+	}
+	if _, err := app.stopTrace(); err != nil {
+		app.logDebug("Failed to close trace: %v", err)
+	}
+	if err := app.closePrinter(); err != nil {
+		app.logDebug("Failed to close printer capture: %v", err)
+	}
+
+	// A clean exit leaves nothing to recover
+	if err := app.autosave.close(); err != nil {
+		app.logDebug("Failed to remove spool: %v", err)
+	}
+
 	// End session
 	if app.session != nil {
 		app.session.End()
 	}
 
 	// Save history if configured and debug mode is enabled
-	if app.config.SaveHistory && app.debugMode && app.historyMgr != nil && app.session != nil {
-		filename := fmt.Sprintf("session_%s.log", app.session.ID)
-		_ = app.historyMgr.SaveToFile(filename, app.config.HistoryFormat)
+	if app.config.SaveHistory && app.debugEnabled() && app.historyMgr != nil && app.session != nil {
+		if filename, err := app.logFilePath(logKindCapture, ".log"); err == nil {
+			_ = app.historyMgr.SaveToFile(filename, app.config.HistoryFormat)
+		}
 	}
 
 	// Close debug log
-	if app.debugLog != nil {
-		app.debugLog.Close()
-		app.debugLog = nil
-	}
+	_ = app.log.Close()
 
 	return nil
 }
 
-// handleSerialInput reads data from serial port and sends to terminal
+// handleSerialInput feeds received data to the terminal in batches, as
+// readSerial puts it in the ring buffer
 func (app *Application) handleSerialInput() {
 	defer app.wg.Done()
+	defer app.recoverPanic("handleSerialInput")
 
-	// Use larger buffer for better performance with high-speed data
-	buffer := make([]byte, 65536) // 64KB buffer
+	ring := newRxRing(rxRingSize)
+	app.wg.Add(1)
+	go app.readSerial(ring)
+
+	batch := make([]byte, rxBatchSize)
+	var chunks []rxChunk
+	var lastBatch time.Time
 
-	// Track last data receive time for flush detection
-	var lastDataTime time.Time
-	flushTimer := time.NewTimer(100 * time.Millisecond) // Increased to 100ms for better reliability
-	flushTimer.Stop()
-	needsFlush := false
+	// Fires after a period of no data to flush the screen and decoders
+	idleTimer := time.NewTimer(rxIdleFlush)
+	idleTimer.Stop()
+	defer idleTimer.Stop()
 
 	for {
 		select {
 		case <-app.ctx.Done():
 			return
-		case isPaused := <-app.pauseChan:
-			// Handle pause state change
-			if isPaused {
-				// Wait for resume signal
-				for {
-					select {
-					case <-app.ctx.Done():
-						return
-					case resumed := <-app.pauseChan:
-						if !resumed {
-							break
-						}
-					}
-					// Break inner loop when resumed
-					if !app.isPaused {
-						break
-					}
-				}
-			}
-		case <-flushTimer.C:
-			// Force UI update after a period of no data
-			if needsFlush {
-				app.forceImmediateUIUpdate()
-				needsFlush = false
-			}
-		default:
-			// Check if paused without blocking
-			if app.isPaused {
-				// Wait a bit before checking again
-				select {
-				case <-app.ctx.Done():
-					return
-				case <-time.After(10 * time.Millisecond):
-					continue
-				}
-			}
+		case <-app.pauseChan:
+			// Data waiting since the pause is shown below
+		case <-idleTimer.C:
+			// Decoders also see the silence, to end frames on it
+			app.feedDecoders(nil)
+			app.forceImmediateUIUpdate()
+			continue
+		case <-ring.data:
+		}
 
-			// Read from serial port with timeout
-			app.serialPort.SetReadTimeout(100 * time.Millisecond)
-			n, err := app.serialPort.Read(buffer)
-			if err != nil {
-				// Timeout or error - check if we need to flush
-				if needsFlush && !lastDataTime.IsZero() && time.Since(lastDataTime) > 100*time.Millisecond {
-					// Force a final UI update if we haven't received data for 100ms
-					app.logDebug("Read timeout - forcing immediate UI update")
-					app.forceImmediateUIUpdate()
-					lastDataTime = time.Time{}
-					needsFlush = false
-				}
-				continue
+		// Received data waits in the ring while paused; the reader stops
+		// once it is full
+		for app.isPaused {
+			select {
+			case <-app.ctx.Done():
+				return
+			case <-app.pauseChan:
+			case <-time.After(100 * time.Millisecond):
 			}
+		}
 
-			if n > 0 {
-				data := buffer[:n]
+		received := false
+		for ring.buffered() > 0 {
+			var n int
+			n, chunks = app.nextBatch(ring, batch, chunks[:0], lastBatch)
+			if n == 0 {
+				break
+			}
+			app.processSerialData(batch[:n], chunks)
+			lastBatch = time.Now()
+			received = true
+		}
+		if received {
+			// Reset idle timer - will fire if no more data arrives
+			idleTimer.Reset(rxIdleFlush)
+		}
+	}
+}
 
-				// Process in terminal
-				err := app.terminal.ProcessOutput(data)
-				if err != nil {
-					app.logDebug("ProcessOutput error: %v", err)
-				}
+// processSerialData passes received data to the terminal, history and
+// everything else that watches the device. chunks are the reads the data
+// came in, with when each returned.
+func (app *Application) processSerialData(data []byte, chunks []rxChunk) {
+	// Bridge clients get the bytes as received
+	app.bridgeBroadcast(data)
+
+	// Decoders see when each read arrived
+	start := 0
+	for _, chunk := range chunks {
+		app.feedDecodersAt(data[start:chunk.end], chunk.at)
+		start = chunk.end
+	}
+
+	// Frames start on a new line and get their own history entries
+	starts := app.frameStarts(data, chunks)
+	parts := [][]byte{data}
+	if len(starts) > 0 {
+		parts = splitFrames(data, starts)
+	}
+	firstStarts := len(starts) > 0 && starts[0] == 0
+
+	arrived := time.Now()
+	if len(chunks) > 0 {
+		arrived = chunks[0].at
+	}
+
+	var text []byte
+	offset := 0
+	for i, part := range parts {
+		// Process in terminal, converted to UTF-8 if the device uses
+		// another encoding, without the echo of what was sent
+		decoded := app.decodeInput(app.echo.strip(part, arrived))
+		if len(parts) == 1 {
+			text = decoded
+		} else {
+			text = append(text, decoded...)
+		}
+		display := decoded
+		if app.showControls.Load() {
+			display = visibleControls(decoded)
+		}
+		if (i > 0 || firstStarts) && app.framing.midLine {
+			display = append([]byte("\r\n"), display...)
+		}
+		app.framing.midLine = !bytes.HasSuffix(display, []byte("\n"))
+		err := app.terminal.ProcessOutput(display)
+		if err != nil {
+			app.logSerial("ProcessOutput error: %v", err)
+		}
 
-				// Save to history
-				if app.historyMgr != nil {
-					_ = app.historyMgr.Write(data, history.DirectionOutput)
-				}
+		app.recordReceived(part, chunks, offset)
+		offset += len(part)
+	}
 
-				// Update session stats
-				if app.session != nil {
-					app.session.UpdateStats(0, int64(n))
-				}
+	// Match triggers against the completed lines
+	app.checkTriggers(text)
 
-				// Request UI update
-				app.requestUIUpdate()
+	// Answer login prompts
+	app.checkAutoLogin(text)
 
-				// Track when we last received data
-				lastDataTime = time.Now()
-				needsFlush = true
+	// Record values for the plot pane
+	app.checkPlot(text)
 
-				// Reset flush timer - will fire if no more data arrives
-				if !flushTimer.Stop() {
-					// Drain the channel if timer already fired
-					select {
-					case <-flushTimer.C:
-					default:
-					}
-				}
-				flushTimer.Reset(100 * time.Millisecond)
-			}
-		}
+	// Update session stats, and the data rate that spaces frames out
+	if app.session != nil {
+		app.session.UpdateStats(0, int64(len(data)))
 	}
+	app.frames.addReceived(len(data))
+
+	// Tell the watchdog the device is alive
+	app.watchdogActivity()
+
+	// Buffer for the next crash recovery checkpoint
+	app.autosaveData(data)
+
+	// Let plugins see the data
+	app.plugins.OnRxData(data)
+
+	// Request UI update
+	app.requestUIUpdate()
 }
 
 // handleUserInput handles keyboard and mouse input
 func (app *Application) handleUserInput() {
 	defer app.wg.Done()
+	defer app.recoverPanic("handleUserInput")
 
 	eventChan := make(chan tcell.Event)
 	exitChan := make(chan struct{})
 
 	go func() {
+		defer app.recoverPanic("event polling")
 		for {
 			// Check if we should exit before polling
 			select {
@@ -699,11 +1091,26 @@ func (app *Application) handleUserInput() {
 
 			switch ev := event.(type) {
 			case *tcell.EventKey:
-				app.handleKeyEvent(ev)
+				if app.paste.active {
+					app.pasteKey(ev)
+				} else {
+					app.handleKeyEvent(ev)
+				}
+			case *tcell.EventPaste:
+				app.handlePasteEvent(ev)
 			case *tcell.EventMouse:
 				app.handleMouseEvent(ev)
 			case *tcell.EventResize:
 				app.handleResize()
+			case *tcell.EventFocus:
+				app.focused.Store(ev.Focused)
+			case *tcell.EventInterrupt:
+				switch data := ev.Data().(type) {
+				case settingsReload:
+					app.handleSettingsReload(data)
+				case pasteChunk:
+					app.handlePasteChunk(data)
+				}
 			}
 		}
 	}
@@ -712,7 +1119,7 @@ func (app *Application) handleUserInput() {
 // handleKeyEvent handles keyboard events
 func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 	// Debug log key events when debug mode is enabled
-	if app.debugMode {
+	if app.debugEnabled() {
 		if ev.Key() == tcell.KeyRune {
 			app.logDebug("Key: Rune='%c'(0x%x), Mods=%v", ev.Rune(), ev.Rune(), ev.Modifiers())
 		} else {
@@ -726,6 +1133,9 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 		}
 	}
 
+	// Any key press drops the mouse selection
+	app.clearSelection()
+
 	// Check if menu is visible and handle its input first
 	if app.mainMenu != nil && app.mainMenu.IsVisible() {
 		if app.mainMenu.HandleKey(ev) {
@@ -733,6 +1143,33 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 		}
 	}
 
+	// Modal dialogs capture all keys while open
+	if app.dialog != nil {
+		app.handleDialogKey(ev)
+		return
+	}
+
+	// Status bar prompt captures all keys while open
+	if app.prompt != nil {
+		app.handlePromptKey(ev)
+		return
+	}
+
+	// A key pressed after the literal-next binding skips all of sterm's keys
+	if app.sendLiteralKey(ev) {
+		return
+	}
+
+	// Keyboard passthrough sends everything but its own binding
+	if app.passthroughKey(ev) {
+		return
+	}
+
+	// With a chord prefix, only the command key of a chord is sterm's
+	if ev = app.chordKey(ev); ev == nil {
+		return
+	}
+
 	// Check for exit combinations
 	// Key=17 is tcell.KeyCtrlQ
 	// Mods=3 means Ctrl+Shift (1+2=3)
@@ -795,12 +1232,13 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 	if !app.mainMenu.IsVisible() {
 		// Check for Alt+ combinations
 		if ev.Modifiers()&tcell.ModAlt != 0 {
-			switch ev.Rune() {
+			// Keybindings from settings map the pressed key to its action
+			switch app.resolveAltKey(ev.Rune()) {
 			case 'c', 'C':
 				// Alt+C - Clear Screen
 				app.logDebug("Alt+C Clear Screen shortcut")
 				if err := app.ClearScreen(); err != nil {
-					app.updateStatusMessage(fmt.Sprintf("Clear screen failed: %v", err))
+					app.notifyError("Clear screen failed: %v", err)
 				} else {
 					app.updateStatusMessage("Screen cleared")
 				}
@@ -809,7 +1247,7 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 				// Alt+H - Clear History
 				app.logDebug("Alt+H Clear History shortcut")
 				if err := app.ClearHistory(); err != nil {
-					app.updateStatusMessage(fmt.Sprintf("Clear history failed: %v", err))
+					app.notifyError("Clear history failed: %v", err)
 				} else {
 					app.updateStatusMessage("History cleared")
 				}
@@ -818,7 +1256,7 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 				// Alt+X - Reset Terminal
 				app.logDebug("Alt+X Reset Terminal shortcut")
 				if err := app.ResetTerminal(); err != nil {
-					app.updateStatusMessage(fmt.Sprintf("Reset terminal failed: %v", err))
+					app.notifyError("Reset terminal failed: %v", err)
 				} else {
 					app.updateStatusMessage("Terminal reset")
 				}
@@ -827,7 +1265,7 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 				// Alt+R - Reconnect
 				app.logDebug("Alt+R Reconnect shortcut")
 				if err := app.Reconnect(); err != nil {
-					app.updateStatusMessage(fmt.Sprintf("Reconnect failed: %v", err))
+					app.notifyError("Reconnect failed: %v", err)
 				} else {
 					app.updateStatusMessage("Reconnected successfully")
 				}
@@ -835,13 +1273,97 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 			case 's', 'S':
 				// Alt+S - Save Session
 				app.logDebug("Alt+S Save Session shortcut")
-				if err := app.saveSessionToFile(); err != nil {
-					app.updateStatusMessage(fmt.Sprintf("Save failed: %v", err))
+				if filename, err := app.saveSessionToFile(); err != nil {
+					app.notifyError("Save failed: %v", err)
 				} else {
-					filename := fmt.Sprintf("session_%s.txt", time.Now().Format("20060102_150405"))
 					app.updateStatusMessage(fmt.Sprintf("Session saved to %s", filename))
 				}
 				return
+			case 'f', 'F':
+				// Alt+F - Edit display filter pattern
+				app.logDebug("Alt+F Edit Filter shortcut")
+				app.editFilter()
+				return
+			case 'g', 'G':
+				// Alt+G - Toggle display filter on/off
+				app.logDebug("Alt+G Toggle Filter shortcut")
+				app.toggleFilter()
+				return
+			case 'p', 'P':
+				// Alt+P - Screenshot of the visible screen
+				app.logDebug("Alt+P Screenshot shortcut")
+				app.promptScreenshot()
+				return
+			case 'k', 'K':
+				// Alt+K - Command history recall
+				app.logDebug("Alt+K Command History shortcut")
+				app.openCommandHistory()
+				return
+			case 'b', 'B':
+				// Alt+B - Add bookmark at current line
+				app.logDebug("Alt+B Add Bookmark shortcut")
+				app.addBookmark()
+				return
+			case 'n', 'N':
+				// Alt+N - Notification history
+				app.logDebug("Alt+N Notifications shortcut")
+				app.openNotificationHistory()
+				return
+			case 'l', 'L':
+				// Alt+L - Leave scroll mode and jump back to live output
+				app.logDebug("Alt+L Jump to Live shortcut")
+				app.jumpToLive()
+				return
+			case 'u', 'U':
+				// Alt+U - Label links on screen and open one
+				app.logDebug("Alt+U Open Link shortcut")
+				app.openLinkHints()
+				return
+			case 'd', 'D':
+				// Alt+D - Show/hide the decoded protocol frames
+				app.logDebug("Alt+D Decoder Panel shortcut")
+				app.toggleDecoderPanel()
+				return
+			case 'e', 'E':
+				// Alt+E - Enter hex bytes or escaped text to send
+				app.logDebug("Alt+E Send Hex shortcut")
+				app.openSendHex()
+				return
+			case 'w', 'W':
+				// Alt+W - Enter characters by Unicode code point
+				app.logDebug("Alt+W Code Point shortcut")
+				app.openCodePoint()
+				return
+			case 'i', 'I':
+				// Alt+I - Lock/unlock keyboard input to the device
+				app.logDebug("Alt+I Input Lock shortcut")
+				app.toggleInputLock()
+				return
+			case 'a', 'A':
+				// Alt+A - ASCII and control character reference
+				app.logDebug("Alt+A ASCII Table shortcut")
+				app.showASCIITable()
+				return
+			case 'm', 'M':
+				// Alt+M - Mark the time, showing the interval since the last mark
+				app.logDebug("Alt+M Mark shortcut")
+				app.setMark()
+				return
+			case 'v', 'V':
+				// Alt+V - Send the next key to the device as is
+				app.logDebug("Alt+V Literal Next shortcut")
+				app.armLiteralNext()
+				return
+			case 't', 'T':
+				// Alt+T - Send all keys to the device until pressed again
+				app.logDebug("Alt+T Keyboard Passthrough shortcut")
+				app.togglePassthrough()
+				return
+			case 'o', 'O':
+				// Alt+O - Show/hide parser and renderer internals
+				app.logDebug("Alt+O Debug Overlay shortcut")
+				app.toggleDebugOverlay()
+				return
 			}
 		}
 	}
@@ -956,8 +1478,9 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 				handled = true
 			case 'h', 'H': // Left (not used in vertical scroll)
 				handled = true
-			case 'l', 'L': // Right (not used in vertical scroll)
-				handled = true
+			case 'l', 'L': // Back to live output
+				app.jumpToLive()
+				return
 			case 'g', 'G': // Top/Bottom (stay in scroll mode)
 				if ev.Modifiers()&tcell.ModShift != 0 { // G - go to bottom
 					app.terminal.ScrollToBottom()
@@ -981,6 +1504,12 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 				height := app.terminal.GetState().Height
 				app.terminal.ScrollUp(height)
 				handled = true
+			case ']': // Next bookmark
+				app.jumpToBookmark(true)
+				handled = true
+			case '[': // Previous bookmark
+				app.jumpToBookmark(false)
+				handled = true
 			}
 		case tcell.KeyUp:
 			app.terminal.ScrollUp(1)
@@ -1012,12 +1541,12 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 			return
 		}
 
-		// F1 key should pass through to shortcuts even in scroll mode
-		if ev.Key() != tcell.KeyF1 {
+		// F1 and F2 should pass through to shortcuts even in scroll mode
+		if ev.Key() != tcell.KeyF1 && ev.Key() != tcell.KeyF2 {
 			// Other keys don't exit scroll mode, just ignore them
 			return
 		}
-		// F1 continues to shortcut processing below
+		// F1 and F2 continue to shortcut processing below
 	}
 
 	// Check shortcuts first
@@ -1034,34 +1563,76 @@ func (app *Application) handleKeyEvent(ev *tcell.EventKey) {
 	}
 
 	// Process as terminal input using shared processor
-	data := app.inputProcessor.ProcessKeyEvent(ev)
+	app.sendKey(ev)
+}
 
+// sendKey sends a key to the device as typed, unless paused
+func (app *Application) sendKey(ev *tcell.EventKey) {
+	data := app.inputProcessor.ProcessKeyEvent(ev)
 	if len(data) > 0 && !app.isPaused {
-		// Local echo - display the input locally if enabled
-		if app.localEcho && app.terminal != nil {
-			// Process the input locally to show it on screen
-			_ = app.terminal.ProcessOutput(data)
-		}
+		app.sendUserData(data)
+	}
+}
 
-		// Send to serial port
-		if app.serialPort != nil && app.serialPort.IsOpen() {
-			n, _ := app.serialPort.Write(data)
+// sendUserData sends user input to the serial port, handling local echo,
+// history and session statistics
+func (app *Application) sendUserData(data []byte) {
+	if app.inputBlocked() {
+		return
+	}
 
-			// Save to history
-			if app.historyMgr != nil {
-				_ = app.historyMgr.Write(data[:n], history.DirectionInput)
-			}
+	// Local echo - display the input locally if enabled
+	if app.localEcho && app.terminal != nil {
+		// Process the input locally to show it on screen
+		_ = app.terminal.ProcessOutput(data)
+	}
 
-			// Update session stats
-			if app.session != nil {
-				app.session.UpdateStats(int64(n), 0)
-			}
-		}
+	// Send to serial port in the device's encoding and track typed command
+	// lines for recall
+	encoded := app.encodeOutput(data)
+	if n := app.writeToPort(encoded); n == len(encoded) {
+		app.trackTypedInput(data)
+	}
+}
+
+// writeToPort queues data for the serial port, to be recorded in history
+// and session stats once written. Returns the number of bytes queued; 0 if
+// the port is closed or the send queue is full.
+func (app *Application) writeToPort(data []byte) int {
+	if !app.queueWrite(data, true, nil) {
+		return 0
+	}
+	return len(data)
+}
+
+// sendTerminalResponse sends the emulator's answer to a device query (cursor
+// position, device attributes, terminal parameters). Nothing is answered in
+// monitor mode or while paused. Called with the terminal locked, so
+// nothing on this path may notify or redraw synchronously.
+func (app *Application) sendTerminalResponse(response []byte) {
+	if app.config.Monitor || app.isPaused {
+		return
 	}
+	app.queueWrite(response, false, nil)
 }
 
 // handleMouseEvent handles mouse events
 func (app *Application) handleMouseEvent(ev *tcell.EventMouse) {
+	// The scrollbar takes clicks on the right edge while in scroll mode
+	if app.handleScrollbarMouse(ev) {
+		return
+	}
+
+	// Ctrl+click opens the OSC 8 link under the pointer
+	if app.handleHyperlinkMouse(ev) {
+		return
+	}
+
+	// Shift+drag (or any drag in scroll mode) selects text locally
+	if app.handleSelectionMouse(ev) {
+		return
+	}
+
 	// Only process mouse events if mouse is enabled (terminal requested it)
 	mouseMode := app.terminal.GetState().MouseMode
 
@@ -1082,18 +1653,9 @@ func (app *Application) handleMouseEvent(ev *tcell.EventMouse) {
 
 	if len(data) > 0 {
 		// app.logDebug("Mouse sequence generated: %X (%d bytes)", data, len(data))
-		if !app.isPaused {
+		if !app.isPaused && !app.inputLocked.Load() {
 			// Send to serial port
-			if app.serialPort != nil && app.serialPort.IsOpen() {
-				_, err := app.serialPort.Write(data)
-				if err != nil {
-					app.logDebug("Failed to send mouse sequence: %v", err)
-				}
-				// Commented out for performance
-				// else {
-				// 	app.logDebug("Sent %d bytes of mouse sequence", n)
-				// }
-			}
+			app.queueWrite(data, false, nil)
 		}
 		// Commented out for performance
 		// else {
@@ -1106,26 +1668,27 @@ func (app *Application) handleMouseEvent(ev *tcell.EventMouse) {
 	// }
 }
 
+// resizePort passes the terminal size to backends that take it out of band
+func (app *Application) resizePort(width, height int) {
+	if resizer, ok := app.serialPort.(serial.Resizer); ok && app.serialPort.IsOpen() {
+		if err := resizer.Resize(width, height); err != nil {
+			app.logSerial("Failed to resize port: %v", err)
+		}
+	}
+}
+
 // handleResize handles terminal resize events
 func (app *Application) handleResize() {
-	width, height := app.screen.Size()
-	// Reserve 1 line for status bar
-	terminalHeight := height - 1
+	// Reserve 1 line for status bar, and the wrap marker gutter
+	width, terminalHeight := app.terminalSize()
 	_ = app.terminal.Resize(width, terminalHeight)
 
-	// Only send terminal size update if explicitly configured
-	// Most serial devices don't support this and it causes garbage output
-	if app.config.SendWindowSizeOnResize {
-		if app.serialPort != nil && app.serialPort.IsOpen() && !app.isPaused {
-			// Send the actual terminal size (without status bar)
-			sizeSeq := fmt.Sprintf("\x1b[8;%d;%dt", terminalHeight, width)
-			_, _ = app.serialPort.Write([]byte(sizeSeq))
+	// A pty learns the new size directly
+	app.resizePort(width, terminalHeight)
 
-			app.logDebug("Window resized to %dx%d, sent size update to remote", width, terminalHeight)
-		}
-	} else {
-		app.logDebug("Window resized to %dx%d (not sending to remote)", width, terminalHeight)
-	}
+	// The device is told once the window stops changing size
+	app.scheduleWindowSizeReport()
+	app.logDebug("Window resized to %dx%d", width, terminalHeight)
 
 	app.screen.Clear()
 	app.updateDisplay()
@@ -1134,71 +1697,65 @@ func (app *Application) handleResize() {
 // updateUI updates the terminal display
 func (app *Application) updateUI() {
 	defer app.wg.Done()
+	defer app.recoverPanic("updateUI")
+
+	housekeeping := time.NewTicker(housekeepingInterval)
+	defer housekeeping.Stop()
 
-	// Create a ticker for minimum refresh interval (to handle rapid updates)
-	ticker := time.NewTicker(16 * time.Millisecond) // ~60 FPS max
-	defer ticker.Stop()
+	// Fires when a waiting update's frame is due
+	frameTimer := time.NewTimer(housekeepingInterval)
+	frameTimer.Stop()
+	defer frameTimer.Stop()
+	waiting := false
 
-	lastUpdate := time.Now()
-	pendingUpdate := false
-	updateCount := 0
-	rateLimitWarning := false
-	lastPendingTime := time.Now()
+	frames := &app.frames
+	draw := func(now time.Time) {
+		app.updateDisplay()
+		frames.frameDrawn(now)
+	}
 
 	for {
 		select {
 		case <-app.ctx.Done():
 			return
 		case <-app.updateNotify:
-			// Mark that we have a pending update
-			pendingUpdate = true
-			lastPendingTime = time.Now()
-
-			// Log pending update
-			if len(app.updateNotify) > 10 {
-				app.logDebug("Update queue size: %d", len(app.updateNotify))
+			frames.stats.requested.Add(1)
+			if waiting {
+				frames.stats.coalesced.Add(1)
+				continue
+			}
+			now := time.Now()
+			if wait := frames.wait(now); wait > 0 {
+				waiting = true
+				frameTimer.Reset(wait)
+				continue
+			}
+			frames.stats.immediate.Add(1)
+			draw(now)
+		case <-frameTimer.C:
+			waiting = false
+			draw(time.Now())
+		case <-housekeeping.C:
+			// The clock in the title bar is updated on its own
+			now := time.Now()
+			app.flushTrace()
+			if app.titleClockTicked(now) {
+				app.drawTitleBar()
+				app.screen.Show()
 			}
 
-			// Drain extra notifications to prevent channel overflow
-			for len(app.updateNotify) > 50 {
-				<-app.updateNotify
-				if !rateLimitWarning {
-					app.logDebug("WARNING: UI update rate limit - dropping updates (queue size: %d)", len(app.updateNotify))
-					rateLimitWarning = true
-				}
+			// Redraw when a notification or the visual bell expires so it
+			// disappears on time, and now and then to refresh the debug
+			// overlay's counters
+			if app.notifications.Prune(now) || app.bell.flashEnded(now) || app.statusClockTicked(now) || app.debugOverlayDue(now) {
+				app.fullRedraw.Store(true)
+				app.requestUIUpdate()
 			}
-		case <-ticker.C:
-			// Force update if pending for too long (prevent data stuck in buffer)
-			if pendingUpdate && time.Since(lastPendingTime) > 20*time.Millisecond {
-				// Reduced from 30ms to 20ms for better responsiveness
-				app.logDebug("Force update - pending for %v", time.Since(lastPendingTime))
-				app.updateDisplay()
-				lastUpdate = time.Now()
-				pendingUpdate = false
-				rateLimitWarning = false
-				updateCount = 0
-			} else if pendingUpdate && time.Since(lastUpdate) >= 16*time.Millisecond {
-				// Normal update with rate limiting
-				updateCount++
-				// Safety check - if we're updating too frequently, skip some frames
-				if updateCount > 100 && time.Since(lastUpdate) < time.Second {
-					app.logDebug("Skipping frame due to high update rate: %d updates/sec", updateCount)
-					continue
-				}
-				if updateCount > 100 {
-					updateCount = 0
-				}
 
-				app.updateDisplay()
-				lastUpdate = time.Now()
-				pendingUpdate = false
-				rateLimitWarning = false
-			} else if pendingUpdate {
-				// Log if update is pending but not executed
-				if app.debugMode && time.Since(lastPendingTime) > 100*time.Millisecond {
-					app.logDebug("Update pending but not executed - waiting %v, last update %v ago",
-						time.Since(lastPendingTime), time.Since(lastUpdate))
-				}
+			// Try again to draw a frame held back for a synchronized
+			// update, which goes ahead once the update ends or times out
+			if frames.syncHeld.Swap(false) {
+				app.requestUIUpdate()
 			}
 		}
 	}
@@ -1211,6 +1768,7 @@ func (app *Application) requestUIUpdate() {
 		// Notification sent
 	default:
 		// Channel full, update already pending
+		app.frames.stats.dropped.Add(1)
 	}
 }
 
@@ -1246,6 +1804,7 @@ func (app *Application) forceImmediateUIUpdate() {
 	}
 
 	app.updateDisplay()
+	app.frames.frameDrawn(time.Now())
 }
 
 func min(a, b int) int {
@@ -1267,7 +1826,7 @@ func (app *Application) updateDisplay() {
 	// Add panic recovery for display updates
 	defer func() {
 		if r := recover(); r != nil {
-			app.logDebug("PANIC in updateDisplay: %v", r)
+			app.logRenderer("PANIC in updateDisplay: %v", r)
 			fmt.Printf("Display update error: %v\n", r)
 		}
 	}()
@@ -1279,13 +1838,20 @@ func (app *Application) updateDisplay() {
 		return
 	}
 
-	// Check if status message expired and needs redraw
-	needsRedraw := false
-	if app.statusMessage != "" && time.Since(app.statusTime) > 3*time.Second {
-		app.statusMessage = ""
-		needsRedraw = true
+	// Everything waits while the device redraws in a synchronized update
+	if app.frames.holdForSync(app.terminal, time.Now()) {
+		return
 	}
 
+	// Check if a full redraw was requested (prompt, filter or notification changes)
+	needsRedraw := app.fullRedraw.Swap(false)
+
+	// Newest notification goes in the status bar, older ones stack above it
+	notifications := app.notifications.Active(time.Now())
+
+	// Filtered views are recomputed on every update since lines shift around
+	filterActive := app.filter != nil && app.filter.IsActive()
+
 	// Get terminal screen buffer
 	screen := app.terminal.GetScreen()
 	if screen == nil {
@@ -1299,6 +1865,13 @@ func (app *Application) updateDisplay() {
 		return
 	}
 
+	// The overlay shows what this frame has to draw, so it is captured
+	// before the frame takes it
+	var debugInfo terminal.DebugInfo
+	if app.debugOverlay.visible.Load() {
+		debugInfo = app.terminal.DebugInfo()
+	}
+
 	// Get terminal state
 	state := app.terminal.GetState()
 
@@ -1316,8 +1889,16 @@ func (app *Application) updateDisplay() {
 	screenWidth, screenHeight := app.screen.Size()
 	contentHeight := screenHeight - 1 // Reserve bottom line for status bar
 
-	// Handle just cleared screen
-	if justCleared {
+	// Handle display filter - rendered from a filtered copy of the lines
+	if filterActive {
+		app.screen.Clear()
+		if justCleared {
+			screen.ClearJustClearedFlag()
+		}
+		app.renderFiltered(buffer, contentHeight)
+		screen.ClearDirty()
+	} else if justCleared {
+		// Handle just cleared screen
 		app.screen.Clear()
 		// Clear the flag
 		screen.ClearJustClearedFlag()
@@ -1341,6 +1922,14 @@ func (app *Application) updateDisplay() {
 			}
 		}
 	} else {
+		// Rows the terminal scrolled are moved on screen rather than drawn
+		// again, unless something covers them
+		if scroll, ok := screen.TakeScroll(); ok && !app.moveScrolledRows(scroll, contentHeight, len(notifications)) {
+			for y := scroll.Top; y <= scroll.Bottom; y++ {
+				screen.MarkLineDirty(y)
+			}
+		}
+
 		// Check if this is a full screen clear (all lines dirty and all spaces)
 		isFullClear := false
 		if screen.DirtyMinY == 0 && screen.DirtyMaxY >= screen.Height-1 {
@@ -1421,17 +2010,82 @@ func (app *Application) updateDisplay() {
 		}
 	}
 
+	// Underline URLs, show the selection and link hints on top of the content
+	if !filterActive {
+		app.drawWrapMarkers(buffer, contentHeight)
+		app.underlineLinks(buffer, contentHeight)
+		app.drawSelection(buffer, contentHeight)
+		app.drawLinkHints()
+	}
+
+	// Scroll position indicator on the right edge
+	if app.terminal.IsScrolling() && !filterActive {
+		app.drawScrollbar(screenWidth, contentHeight)
+	}
+
+	// Decoded protocol frames over the right side
+	app.drawDecoderPanel(screenWidth, contentHeight)
+	app.drawGPSDashboard(screenWidth, contentHeight)
+	app.drawTxrxView(screenWidth, contentHeight)
+
+	// Plotted values over the bottom
+	app.drawPlot(screenWidth, contentHeight)
+
+	// Parser and renderer internals on top of everything
+	app.drawDebugOverlay(screenWidth, contentHeight, debugInfo)
+
 	// Always show status bar at bottom
 	statusY := screenHeight - 1
 
 	// Prepare status bar content
 	var statusLeft, statusCenter, statusRight string
 
+	// A template replaces the built-in text of a part, filled in each time
+	// since the values change
+	format := app.statusFormat()
+	var values map[string]string
+	if !format.IsZero() {
+		values = app.statusValues(time.Now())
+	}
+
 	// Left: Connection info (cache if unchanged)
-	if app.cachedStatusLeft == "" || needsRedraw {
-		if app.serialPort != nil && app.serialPort.IsOpen() {
+	if app.cachedStatusLeft == "" || needsRedraw || format.Left != "" {
+		if !app.statusBar.ShowPort {
+			app.cachedStatusLeft = ""
+		} else if app.serialPort != nil && app.serialPort.IsOpen() {
 			cfg := app.config.SerialConfig
 			app.cachedStatusLeft = fmt.Sprintf(" %s %d ", cfg.Port, cfg.BaudRate)
+			if actual := app.driverBaud.Load(); actual != 0 {
+				app.cachedStatusLeft = fmt.Sprintf(" %s %d≈%d ", cfg.Port, cfg.BaudRate, actual)
+			}
+			if !serial.HasLineSettings(cfg.Port) {
+				app.cachedStatusLeft = fmt.Sprintf(" %s ", cfg.Port) // Baud rate doesn't apply
+			}
+			if charset := app.currentCharset(); !charset.IsUTF8() {
+				app.cachedStatusLeft += charset.Label + " "
+			}
+			if reporter, ok := app.serialPort.(serial.RS485Reporter); ok {
+				switch reporter.RS485Mode() {
+				case serial.RS485Driver:
+					app.cachedStatusLeft += "RS-485 "
+				case serial.RS485Software:
+					app.cachedStatusLeft += "RS-485 (RTS) "
+				}
+			}
+			if format.Left != "" {
+				app.cachedStatusLeft = expandStatus(format.Left, values)
+			}
+			// Kept with a template, so sending is never silently off
+			if app.passthrough.Load() {
+				app.cachedStatusLeft = " PASSTHROUGH" + app.cachedStatusLeft
+			}
+			if app.config.Monitor {
+				app.cachedStatusLeft = " MONITOR (read-only)" + app.cachedStatusLeft
+			} else if app.inputLocked.Load() {
+				app.cachedStatusLeft = " INPUT LOCKED" + app.cachedStatusLeft
+			}
+		} else if app.deviceWaiting() {
+			app.cachedStatusLeft = " Waiting for " + app.deviceName() + " "
 		} else {
 			app.cachedStatusLeft = " Disconnected "
 		}
@@ -1439,21 +2093,50 @@ func (app *Application) updateDisplay() {
 	statusLeft = app.cachedStatusLeft
 
 	// Center: Mode indicator or temporary status message
-	if app.statusMessage != "" && time.Since(app.statusTime) < 3*time.Second {
-		// Show temporary status message for 3 seconds
-		statusCenter = fmt.Sprintf(" %s ", app.statusMessage)
+	if app.prompt != nil {
+		// Prompt replaces the whole status bar center
+		statusCenter = app.prompt.Text()
+	} else if len(notifications) > 0 {
+		// Show the newest notification until it expires
+		statusCenter = fmt.Sprintf(" %s ", notifications[0].Message)
 	} else if app.terminal.IsScrolling() {
 		current, total := app.terminal.GetScrollPosition()
-		statusCenter = fmt.Sprintf(" SCROLL: %d/%d [j/k:↑↓ d/u:½Page f/b:Page g/G:Top/Bot ESC/Enter/q:Exit] ", current, total)
+		newLines := ""
+		if n := app.terminal.NewLinesSinceScroll(); n > 0 {
+			newLines = fmt.Sprintf(" +%d new", n)
+			if label := app.keyLabel("live"); label != "" {
+				newLines += fmt.Sprintf(" [%s: Live]", label)
+			}
+		}
+		if app.statusBar.ShowHints {
+			statusCenter = fmt.Sprintf(" SCROLL: %d/%d%s [j/k:↑↓ d/u:½Page f/b:Page g/G:Top/Bot [/]:Mark l:Live ESC/Enter/q:Exit] ", current, total, newLines)
+		} else {
+			statusCenter = fmt.Sprintf(" SCROLL: %d/%d%s ", current, total, newLines)
+		}
+	} else if filterActive {
+		if app.statusBar.ShowHints {
+			statusCenter = fmt.Sprintf(" FILTER(%s): /%s/ [%s: Edit] [%s: Off] ", app.filter.Mode(), app.filter.Pattern(),
+				app.keyLabel("filter"), app.keyLabel("toggle-filter"))
+		} else {
+			statusCenter = fmt.Sprintf(" FILTER(%s): /%s/ ", app.filter.Mode(), app.filter.Pattern())
+		}
 	} else if app.isPaused {
-		statusCenter = " [Shift+PgUp/↑: Scroll] [F1: Menu] PAUSED [F8: Resume] "
-	} else {
+		if app.statusBar.ShowHints {
+			statusCenter = " [Shift+PgUp/↑: Scroll] [F1: Menu] PAUSED [F8: Resume] "
+		} else {
+			statusCenter = " PAUSED [F8: Resume] "
+		}
+	} else if app.statusBar.ShowHints && format.Center != "" {
+		statusCenter = expandStatus(format.Center, values)
+	} else if app.statusBar.ShowHints {
 		// Show hint for scroll mode and pause
 		statusCenter = " [Shift+PgUp/↑: Scroll] [F1: Menu] [F8: Pause] "
 	}
 
 	// Right: Session info (cache and update only when changed)
-	if app.session != nil {
+	if app.statusBar.ShowStats && format.Right != "" {
+		statusRight = expandStatus(format.Right, values)
+	} else if app.session != nil && app.statusBar.ShowStats {
 		currentSent := app.session.BytesSent
 		currentRecv := app.session.BytesRecv
 		if currentSent != app.cachedBytesSent || currentRecv != app.cachedBytesRecv || needsRedraw {
@@ -1463,11 +2146,16 @@ func (app *Application) updateDisplay() {
 		}
 		statusRight = app.cachedStatusRight
 	}
+	statusRight = app.marks.status() + app.bridgeStatus() + app.autoLoginStatus() + app.captureStatus() + app.historyStreamStatus() + app.traceStatus() + app.printerStatus() + app.replayStatus() + app.pluginStatus() + app.framingStatus() + app.txStatus() + statusRight
 
 	// Draw status bar with different style
 	statusStyle := tcell.StyleDefault.
-		Background(tcell.ColorDarkBlue).
-		Foreground(tcell.ColorWhite)
+		Background(app.theme.background).
+		Foreground(app.theme.foreground)
+	if app.bell.flashing(time.Now()) {
+		// Visual bell
+		statusStyle = statusStyle.Reverse(true)
+	}
 
 	// Fill entire bottom line
 	for x := 0; x < screenWidth; x++ {
@@ -1495,14 +2183,21 @@ func (app *Application) updateDisplay() {
 	runeIndex := 0
 	for _, ch := range statusCenter {
 		if x < screenWidth {
-			if app.statusMessage != "" && time.Since(app.statusTime) < 3*time.Second {
-				// Highlight status message with green background
+			if app.prompt != nil {
+				// Prompt is shown with inverted colors to look like an input field
+				app.screen.SetContent(x, statusY, ch, nil, statusStyle.Reverse(true))
+			} else if len(notifications) > 0 {
+				// Highlight notification in its severity color
+				app.screen.SetContent(x, statusY, ch, nil,
+					statusStyle.Background(app.severityColor(notifications[0].Severity)).Bold(true))
+			} else if filterActive && !app.terminal.IsScrolling() {
+				// Highlight active filter
 				app.screen.SetContent(x, statusY, ch, nil,
-					statusStyle.Background(tcell.ColorDarkGreen).Bold(true))
+					statusStyle.Background(app.theme.filter).Bold(true))
 			} else if app.terminal.IsScrolling() {
 				// Highlight scroll mode
 				app.screen.SetContent(x, statusY, ch, nil,
-					statusStyle.Background(tcell.ColorDarkCyan).Bold(true))
+					statusStyle.Background(app.theme.scroll).Bold(true))
 			} else if app.isPaused {
 				// Check if current character is part of the pause indicator
 				pauseStart := strings.Index(statusCenter, pauseIndicator)
@@ -1512,7 +2207,7 @@ func (app *Application) updateDisplay() {
 				if pauseStart >= 0 && runeIndex >= runesBeforePause && runeIndex < runesBeforePause+pauseRuneCount {
 					// Highlight only the pause indicator with red background
 					app.screen.SetContent(x, statusY, ch, nil,
-						statusStyle.Background(tcell.ColorDarkRed).Bold(true))
+						statusStyle.Background(app.theme.paused).Bold(true))
 				} else {
 					// Normal style for other parts
 					app.screen.SetContent(x, statusY, ch, nil, statusStyle)
@@ -1539,14 +2234,25 @@ func (app *Application) updateDisplay() {
 		}
 	}
 
+	// Older notifications stay visible above the status bar
+	if len(notifications) > 1 {
+		app.drawToasts(notifications[1:], screenWidth, statusY)
+	}
+
 	// Show cursor (adjusted for status bar)
-	if !app.terminal.IsScrolling() {
+	if filterActive {
+		// Cursor position is meaningless in a filtered view
+		app.screen.HideCursor()
+	} else if !app.terminal.IsScrolling() {
 		if state.CursorX >= 0 && state.CursorX < screen.Width &&
 			state.CursorY >= 0 && state.CursorY < contentHeight {
 			app.screen.ShowCursor(state.CursorX, state.CursorY)
 		}
 	}
 
+	// The title bar goes above everything else
+	app.drawTitleBar()
+
 	// Show the screen
 	app.screen.Show()
 
@@ -1555,10 +2261,24 @@ func (app *Application) updateDisplay() {
 		app.mainMenu.Draw()
 	}
 
+	// Modal dialogs go above everything else
+	if app.dialog != nil {
+		app.dialog.Draw()
+	}
+
 	// Clear dirty flags
 	screen.ClearDirty()
 }
 
+// jumpToLive leaves scroll mode and shows the live screen
+func (app *Application) jumpToLive() {
+	if !app.terminal.IsScrolling() {
+		return
+	}
+	app.terminal.ExitScrollMode()
+	app.updateDisplay()
+}
+
 // Pause pauses data flow
 func (app *Application) Pause() error {
 	app.mu.Lock()
@@ -1624,7 +2344,10 @@ func (app *Application) SaveHistory(filename string) error {
 	}
 
 	if filename == "" {
-		filename = fmt.Sprintf("history_%s.log", time.Now().Format("20060102_150405"))
+		var err error
+		if filename, err = app.logFilePath(logKindHistory, ".log"); err != nil {
+			return err
+		}
 	}
 
 	return app.historyMgr.SaveToFile(filename, app.config.HistoryFormat)
@@ -1835,7 +2558,13 @@ func (app *Application) Reconnect() error {
 	}
 
 	// Reconnect
-	return app.serialPort.Open(app.config.SerialConfig)
+	if err := app.serialPort.Open(app.config.SerialConfig); err != nil {
+		return err
+	}
+	app.portOpened()
+	app.pluginsConnected()
+	app.startAutoLogin()
+	return nil
 }
 
 // GetSession returns the current session
@@ -1882,10 +2611,22 @@ func (app *Application) renderCell(x, y int, cell terminal.Cell) {
 	// Bounds check
 	width, height := app.screen.Size()
 	if x < 0 || x >= width || y < 0 || y >= height {
-		app.logDebug("renderCell out of bounds: x=%d, y=%d, screen=%dx%d", x, y, width, height)
+		app.logRenderer("renderCell out of bounds: x=%d, y=%d, screen=%dx%d", x, y, width, height)
 		return
 	}
 
+	// Double-width and double-height lines take two columns a character
+	if cell.LineSize.IsDouble() {
+		app.renderDoubleCell(x, y, cell)
+		return
+	}
+
+	// Set the cell
+	app.screen.SetContent(x, y, cell.Char, cell.Combining(), cellStyle(cell))
+}
+
+// cellStyle converts terminal cell attributes to a tcell style
+func cellStyle(cell terminal.Cell) tcell.Style {
 	// Convert terminal colors to tcell colors
 	style := tcell.StyleDefault
 
@@ -1903,7 +2644,10 @@ func (app *Application) renderCell(x, y int, cell terminal.Cell) {
 		style = style.Italic(true)
 	}
 	if cell.Attributes.Underline {
-		style = style.Underline(true)
+		style = style.Underline(underlineStyles[cell.Attributes.UnderlineStyle])
+		if cell.Attributes.UnderlineColor != terminal.ColorDefault {
+			style = style.Underline(convertColor(cell.Attributes.UnderlineColor))
+		}
 	}
 	if cell.Attributes.Reverse {
 		style = style.Reverse(true)
@@ -1912,12 +2656,41 @@ func (app *Application) renderCell(x, y int, cell terminal.Cell) {
 		style = style.Blink(true)
 	}
 
-	// Set the cell
-	app.screen.SetContent(x, y, cell.Char, nil, style)
+	// OSC 8 links are underlined, and passed on to host terminals that
+	// support them
+	if link := cell.Hyperlink; link != nil {
+		if !cell.Attributes.Underline {
+			style = style.Underline(true)
+		}
+		style = style.Url(link.URI)
+		if link.ID != "" {
+			style = style.UrlId(link.ID)
+		}
+	}
+
+	return style
+}
+
+// underlineStyles maps the terminal's underline styles to tcell's, which
+// draws them where the host terminal supports it and a plain underline
+// elsewhere
+var underlineStyles = map[terminal.UnderlineStyle]tcell.UnderlineStyle{
+	terminal.UnderlineSingle: tcell.UnderlineStyleSolid,
+	terminal.UnderlineDouble: tcell.UnderlineStyleDouble,
+	terminal.UnderlineCurly:  tcell.UnderlineStyleCurly,
+	terminal.UnderlineDotted: tcell.UnderlineStyleDotted,
+	terminal.UnderlineDashed: tcell.UnderlineStyleDashed,
 }
 
 // convertColor converts terminal color to tcell color
 func convertColor(color terminal.Color) tcell.Color {
+	if color.IsRGB() {
+		r, g, b := color.RGB()
+		return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+	}
+	if color >= 16 && color <= 255 {
+		return tcell.PaletteColor(int(color))
+	}
 	switch color {
 	case terminal.ColorDefault:
 		return tcell.ColorReset // Use terminal default color
@@ -1966,30 +2739,30 @@ func generateSessionID() string {
 // setupMenu initializes the main menu
 func (app *Application) setupMenu() {
 	// Session Management
-	app.mainMenu.AddItem("Clear Screen", "Alt+C", func() error {
+	app.mainMenu.AddItem("Clear Screen", app.keyLabel("clear"), func() error {
 		app.logDebug("Menu: Clear Screen")
 		if err := app.ClearScreen(); err != nil {
-			app.updateStatusMessage(fmt.Sprintf("Clear screen failed: %v", err))
+			app.notifyError("Clear screen failed: %v", err)
 			return err
 		}
 		app.updateStatusMessage("Screen cleared")
 		return nil
 	})
 
-	app.mainMenu.AddItem("Clear History", "Alt+H", func() error {
+	app.mainMenu.AddItem("Clear History", app.keyLabel("clear-history"), func() error {
 		app.logDebug("Menu: Clear History")
 		if err := app.ClearHistory(); err != nil {
-			app.updateStatusMessage(fmt.Sprintf("Clear history failed: %v", err))
+			app.notifyError("Clear history failed: %v", err)
 			return err
 		}
 		app.updateStatusMessage("History cleared")
 		return nil
 	})
 
-	app.mainMenu.AddItem("Reset Terminal", "Alt+X", func() error {
+	app.mainMenu.AddItem("Reset Terminal", app.keyLabel("reset"), func() error {
 		app.logDebug("Menu: Reset Terminal")
 		if err := app.ResetTerminal(); err != nil {
-			app.updateStatusMessage(fmt.Sprintf("Reset terminal failed: %v", err))
+			app.notifyError("Reset terminal failed: %v", err)
 			return err
 		}
 		app.updateStatusMessage("Terminal reset")
@@ -1999,47 +2772,136 @@ func (app *Application) setupMenu() {
 	app.mainMenu.AddSeparator()
 
 	// File Operations
-	app.mainMenu.AddItem("Save Session", "Alt+S", func() error {
+	app.mainMenu.AddItem("Save Session", app.keyLabel("save"), func() error {
 		app.logDebug("Menu: Save Session")
-		err := app.saveSessionToFile()
+		filename, err := app.saveSessionToFile()
 		if err != nil {
-			app.updateStatusMessage(fmt.Sprintf("Failed: %v", err))
+			app.notifyError("Failed: %v", err)
+			return err
 		}
-		return err
+		app.updateStatusMessage(fmt.Sprintf("Session saved to %s", filename))
+		return nil
+	})
+
+	app.mainMenu.AddItem("Save Session As...", "", func() error {
+		app.logDebug("Menu: Save Session As")
+		app.promptSaveSessionAs()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Export History...", "", func() error {
+		app.logDebug("Menu: Export History")
+		app.promptExportHistory()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Stream History to File...", "", func() error {
+		app.logDebug("Menu: Stream History")
+		app.promptHistoryStream()
+		return nil
+	})
+
+	if port := app.config.SerialConfig.Port; strings.HasPrefix(port, serial.ReplayPrefix) || strings.HasPrefix(port, serial.SimPrefix) {
+		app.mainMenu.AddItem("Replay Speed...", "", func() error {
+			app.logDebug("Menu: Replay Speed")
+			app.promptReplaySpeed()
+			return nil
+		})
+	}
+
+	app.mainMenu.AddItem("Printer Capture File...", "", func() error {
+		app.logDebug("Menu: Printer Capture File")
+		app.promptPrinterCapture()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Extract Fields...", "", func() error {
+		app.logDebug("Menu: Extract Fields")
+		app.promptExtractFields()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Send File...", "", func() error {
+		app.logDebug("Menu: Send File")
+		app.promptSendFile()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Screenshot...", app.keyLabel("screenshot"), func() error {
+		app.logDebug("Menu: Screenshot")
+		app.hideMainMenu()
+		app.promptScreenshot()
+		return nil
 	})
 
 	app.mainMenu.AddSeparator()
 
 	// Connection
-	app.mainMenu.AddItem("Reconnect", "Alt+R", func() error {
+	app.mainMenu.AddItem("Reconnect", app.keyLabel("reconnect"), func() error {
 		app.logDebug("Menu: Reconnect")
 		err := app.reconnect()
 		if err != nil {
-			app.updateStatusMessage(fmt.Sprintf("Reconnect failed: %v", err))
+			app.notifyError("Reconnect failed: %v", err)
 		}
 		return err
 	})
+	app.mainMenu.AddCheckItem("Auto-Connect", "", app.autoConnectEnabled(), func(checked bool) error {
+		app.logDebug("Menu: Auto-Connect %v", checked)
+		app.setAutoConnect(checked)
+		if checked {
+			app.updateStatusMessage("Waiting for " + app.deviceName() + " whenever it is unplugged")
+		} else {
+			app.updateStatusMessage("Auto-connect off")
+		}
+		return nil
+	})
+	if len(app.autoLogin.steps) > 0 && !app.config.Monitor {
+		app.mainMenu.AddItem("Run Auto-Login", "", func() error {
+			app.logDebug("Menu: Run Auto-Login")
+			app.hideMainMenu()
+			app.startAutoLogin()
+			return nil
+		})
+	}
+	app.mainMenu.AddItem("Cancel Pending Sends", "", func() error {
+		app.logDebug("Menu: Cancel Pending Sends")
+		app.hideMainMenu()
+		if dropped := app.cancelPendingSends(); dropped > 0 {
+			app.updateStatusMessage(fmt.Sprintf("Dropped %s waiting to be sent", formatByteSize(int64(dropped))))
+		} else {
+			app.updateStatusMessage("Nothing waiting to be sent")
+		}
+		return nil
+	})
+	app.mainMenu.AddItem("TCP Bridge...", "", func() error {
+		app.logDebug("Menu: TCP Bridge")
+		app.promptBridge()
+		return nil
+	})
 
 	app.mainMenu.AddSeparator()
 
-	// View Control
-	lineWrapLabel := "Line Wrap: ON"
-	if !app.lineWrap {
-		lineWrapLabel = "Line Wrap: OFF"
+	// Serial settings
+	app.mainMenu.AddSubmenu("Baud Rate", app.buildBaudRateMenu())
+	app.mainMenu.AddSubmenu("Encoding", app.buildEncodingMenu())
+	app.mainMenu.AddSubmenu("Logging", app.buildLoggingMenu())
+	app.mainMenu.AddSubmenu("Decoders", app.buildDecoderMenu())
+	app.mainMenu.AddSubmenu("Plot", app.buildPlotMenu())
+	app.mainMenu.AddSubmenu("TX/RX View", app.buildTxrxMenu())
+	app.mainMenu.AddSubmenu("Window Size", app.buildWindowSizeMenu())
+	if len(app.plugins.Plugins()) > 0 {
+		app.mainMenu.AddSubmenu("Plugins", app.buildPluginMenu())
+	}
+	if app.modemController() != nil {
+		app.mainMenu.AddSubmenu("Line Control", app.buildLineControlMenu())
 	}
-	app.mainMenu.AddItem(lineWrapLabel, "", func() error {
-		app.logDebug("Menu: Toggle Line Wrap")
-		app.lineWrap = !app.lineWrap
 
-		// Update menu label
-		newLabel := "Line Wrap: ON"
-		if !app.lineWrap {
-			newLabel = "Line Wrap: OFF"
-		}
-		idx := app.mainMenu.FindItemIndex("Line Wrap:")
-		if idx >= 0 {
-			app.mainMenu.UpdateItemLabel(idx, newLabel)
-		}
+	app.mainMenu.AddSeparator()
+
+	// View Control
+	app.mainMenu.AddCheckItem("Line Wrap", "", app.lineWrap, func(checked bool) error {
+		app.logDebug("Menu: Toggle Line Wrap")
+		app.lineWrap = checked
 
 		// Update status message
 		if app.lineWrap {
@@ -2052,29 +2914,27 @@ func (app *Application) setupMenu() {
 		if app.terminal != nil {
 			app.terminal.SetLineWrap(app.lineWrap)
 		}
+		return nil
+	})
 
-		// Redraw menu
-		app.mainMenu.Draw()
+	implicitCR, implicitLF := app.newlineHandling()
+	app.mainMenu.AddCheckItem("Implicit CR in Every LF", "", implicitCR, func(checked bool) error {
+		app.logDebug("Menu: Toggle Implicit CR in Every LF")
+		_, implicitLF := app.newlineHandling()
+		app.setNewlineHandling(checked, implicitLF)
 		return nil
 	})
 
-	localEchoLabel := "Local Echo: OFF"
-	if app.localEcho {
-		localEchoLabel = "Local Echo: ON"
-	}
-	app.mainMenu.AddItem(localEchoLabel, "", func() error {
-		app.logDebug("Menu: Toggle Local Echo")
-		app.localEcho = !app.localEcho
+	app.mainMenu.AddCheckItem("Implicit LF in Every CR", "", implicitLF, func(checked bool) error {
+		app.logDebug("Menu: Toggle Implicit LF in Every CR")
+		implicitCR, _ := app.newlineHandling()
+		app.setNewlineHandling(implicitCR, checked)
+		return nil
+	})
 
-		// Update menu label
-		newLabel := "Local Echo: ON"
-		if !app.localEcho {
-			newLabel = "Local Echo: OFF"
-		}
-		idx := app.mainMenu.FindItemIndex("Local Echo:")
-		if idx >= 0 {
-			app.mainMenu.UpdateItemLabel(idx, newLabel)
-		}
+	app.mainMenu.AddCheckItem("Local Echo", "", app.localEcho, func(checked bool) error {
+		app.logDebug("Menu: Toggle Local Echo")
+		app.localEcho = checked
 
 		// Update status message
 		if app.localEcho {
@@ -2082,15 +2942,169 @@ func (app *Application) setupMenu() {
 		} else {
 			app.updateStatusMessage("Local echo: OFF")
 		}
+		return nil
+	})
 
-		// Redraw menu
-		app.mainMenu.Draw()
+	app.mainMenu.AddCheckItem("Title Bar", "", app.titleBarVisible(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Title Bar")
+		app.setTitleBarVisible(checked)
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Suppress Device Echo", "", app.echo.isEnabled(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Suppress Device Echo")
+		// Typed input is shown locally in place of the echo
+		if checked {
+			app.localEcho = true
+			app.mainMenu.SetChecked(app.mainMenu.FindItemIndex("Local Echo"), true)
+		}
+		err := app.setSuppressEcho(checked)
+		switch {
+		case err != nil:
+			app.notifyError("%v", err)
+		case checked:
+			app.updateStatusMessage("Device echo suppressed, local echo: ON")
+		default:
+			app.updateStatusMessage("Device echo shown")
+		}
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Input Lock", app.keyLabel("input-lock"), app.inputLocked.Load(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Input Lock")
+		if checked != app.inputLocked.Load() {
+			app.toggleInputLock()
+		}
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Keyboard Passthrough", app.keyLabel("passthrough"), app.passthrough.Load(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Keyboard Passthrough")
+		if checked != app.passthrough.Load() {
+			app.togglePassthrough()
+		}
+		return nil
+	})
+
+	app.mainMenu.AddItem("Send Next Key Literally", app.keyLabel("literal-next"), func() error {
+		app.logDebug("Menu: Send Next Key Literally")
+		if !app.literalNext {
+			app.armLiteralNext()
+		}
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Show Control Characters", "", app.showControls.Load(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Show Control Characters")
+		app.setShowControls(checked)
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Wrap Markers", "", app.wrapMarkers.Load(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Wrap Markers")
+		app.setWrapMarkers(checked)
+		return nil
+	})
+
+	app.mainMenu.AddCheckItem("Debug Overlay", app.keyLabel("debug-overlay"), app.debugOverlay.visible.Load(), func(checked bool) error {
+		app.logDebug("Menu: Toggle Debug Overlay")
+		if checked != app.debugOverlay.visible.Load() {
+			app.toggleDebugOverlay()
+		}
+		return nil
+	})
+
+	app.mainMenu.AddItem("Add Bookmark...", app.keyLabel("bookmark"), func() error {
+		app.logDebug("Menu: Add Bookmark")
+		app.hideMainMenu()
+		app.addBookmark()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Mark Time", app.keyLabel("mark"), func() error {
+		app.logDebug("Menu: Mark Time")
+		app.setMark()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Reset Marks", "", func() error {
+		app.logDebug("Menu: Reset Marks")
+		app.resetMarks()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Display Filter...", app.keyLabel("filter"), func() error {
+		app.logDebug("Menu: Display Filter")
+		app.hideMainMenu()
+		app.editFilter()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Open Link...", app.keyLabel("open-link"), func() error {
+		app.logDebug("Menu: Open Link")
+		app.hideMainMenu()
+		app.openLinkHints()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Send Hex...", app.keyLabel("send-hex"), func() error {
+		app.logDebug("Menu: Send Hex")
+		app.hideMainMenu()
+		app.openSendHex()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Send Code Point...", app.keyLabel("code-point"), func() error {
+		app.logDebug("Menu: Send Code Point")
+		app.hideMainMenu()
+		app.openCodePoint()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Boot Time Analysis", "", func() error {
+		app.logDebug("Menu: Boot Time Analysis")
+		app.showBootTime()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Checksum...", "", func() error {
+		app.logDebug("Menu: Checksum")
+		app.hideMainMenu()
+		app.openChecksum()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Command History...", app.keyLabel("command-history"), func() error {
+		app.logDebug("Menu: Command History")
+		app.hideMainMenu()
+		app.openCommandHistory()
+		return nil
+	})
+
+	app.mainMenu.AddItem("Notifications...", app.keyLabel("notifications"), func() error {
+		app.logDebug("Menu: Notifications")
+		app.hideMainMenu()
+		app.openNotificationHistory()
 		return nil
 	})
 
 	app.mainMenu.AddSeparator()
 
 	// Help
+	app.mainMenu.AddItem("Keyboard Shortcuts", "F2", func() error {
+		app.logDebug("Menu: Keyboard Shortcuts")
+		app.hideMainMenu()
+		app.showHelp()
+		return nil
+	})
+
+	app.mainMenu.AddItem("ASCII Table", app.keyLabel("ascii-table"), func() error {
+		app.logDebug("Menu: ASCII Table")
+		app.hideMainMenu()
+		app.showASCIITable()
+		return nil
+	})
+
 	app.mainMenu.AddItem("About", "", func() error {
 		app.logDebug("Menu: About")
 		// Show about info in status message
@@ -2108,6 +3122,11 @@ func (app *Application) setupMenu() {
 		return nil
 	})
 
+	// Closing a submenu uncovers the screen underneath it
+	app.mainMenu.SetOnSubmenuClose(func() {
+		app.overlayMgr.RestoreScreen()
+	})
+
 	// Set close callback to restore screen and update display
 	app.mainMenu.SetOnClose(func() {
 		app.overlayMgr.RestoreScreen()
@@ -2143,11 +3162,18 @@ func (app *Application) hideMainMenu() {
 	}
 }
 
-// saveSessionToFile saves the current session to a file
-func (app *Application) saveSessionToFile() error {
-	// Generate filename with timestamp
-	filename := fmt.Sprintf("session_%s.txt", time.Now().Format("20060102_150405"))
+// saveSessionToFile saves the current session to a file named by the
+// logging settings and returns the file name
+func (app *Application) saveSessionToFile() (string, error) {
+	filename, err := app.logFilePath(logKindSession, ".txt")
+	if err != nil {
+		return "", err
+	}
+	return filename, app.saveSessionTo(filename)
+}
 
+// saveSessionTo saves the session header and terminal content to filename
+func (app *Application) saveSessionTo(filename string) error {
 	// Create file
 	file, err := os.Create(filename)
 	if err != nil {
@@ -2165,17 +3191,35 @@ func (app *Application) saveSessionToFile() error {
 		app.config.SerialConfig.DataBits,
 		app.config.SerialConfig.Parity,
 		app.config.SerialConfig.StopBits)
+	// Write bookmark summary
+	bookmarks := app.bookmarks.List()
+	if len(bookmarks) > 0 {
+		fmt.Fprintf(file, "Bookmarks:\n")
+		for _, bm := range bookmarks {
+			fmt.Fprintf(file, "  %s\n", bm)
+		}
+	}
 	fmt.Fprintf(file, "========================\n\n")
 
-	// Write terminal content (including scrollback)
-	lines := app.terminal.GetAllLines()
+	// Write terminal content (including scrollback), a line as the device
+	// sent it per line however many rows it wrapped over, or row by row in
+	// the visual layout. Padding and the empty rows below the output are left
+	// out.
+	rows := app.terminal.GetAllLines()
+	lines := terminal.LogicalLines(rows)
+	if app.logging.SessionLayout == "visual" {
+		lines = terminal.VisualLines(rows)
+	}
+	lines = terminal.TrimBlankLines(lines)
+	dropped := app.terminal.GetScrollbackDropped()
 	for _, line := range lines {
-		for _, cell := range line {
-			if cell.Char != 0 {
-				fmt.Fprintf(file, "%c", cell.Char)
+		// Annotate bookmarked lines inline
+		for i := line.Start; i < line.End(); i++ {
+			if bm, ok := app.bookmarks.At(dropped + i); ok {
+				fmt.Fprintf(file, "--- BOOKMARK %s ---\n", bm)
 			}
 		}
-		fmt.Fprintln(file)
+		fmt.Fprintln(file, line.Text())
 	}
 
 	app.logDebug("Session saved to %s", filename)
@@ -2188,7 +3232,7 @@ func (app *Application) saveSessionToFile() error {
 
 // reconnect disconnects and reconnects to the serial port
 func (app *Application) reconnect() error {
-	app.logDebug("Reconnecting...")
+	app.logSerial("Reconnecting...")
 
 	// Close current connection
 	if app.serialPort != nil && app.serialPort.IsOpen() {
@@ -2203,29 +3247,138 @@ func (app *Application) reconnect() error {
 	if err != nil {
 		return fmt.Errorf("failed to reconnect: %w", err)
 	}
+	app.portOpened()
+	app.pluginsConnected()
+	app.startAutoLogin()
 
 	// Clear terminal
 	app.terminal.Clear()
 
+	// A restarted shell needs the screen size again
+	if width, height := app.terminalSize(); height > 0 {
+		app.resizePort(width, height)
+	}
+
 	// Update status
 	app.updateStatusMessage("Reconnected successfully")
 
 	return nil
 }
 
-// updateStatusMessage shows a temporary status message
-func (app *Application) updateStatusMessage(message string) {
-	app.statusMessage = message
-	app.statusTime = time.Now()
-	// Force redraw to show the message
-	// Mark terminal as dirty to trigger redraw
-	if app.terminal != nil && app.terminal.GetScreen() != nil {
-		app.terminal.GetScreen().Dirty = true
+// commonBaudRates are offered in the baud rate menu
+var commonBaudRates = []int{9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// buildBaudRateMenu creates the baud rate submenu as a radio group
+func (app *Application) buildBaudRateMenu() *menu.Menu {
+	baudMenu := menu.NewMenu("Baud Rate", app.screen)
+
+	rates := commonBaudRates
+	current := app.config.SerialConfig.BaudRate
+	if !slices.Contains(rates, current) {
+		// Keep a custom rate selectable alongside the common ones
+		rates = append(slices.Clone(rates), current)
+		slices.Sort(rates)
 	}
-	app.updateDisplay()
-	// If menu is visible, also redraw it on top
-	if app.mainMenu != nil && app.mainMenu.IsVisible() {
-		app.mainMenu.Draw()
+
+	for _, rate := range rates {
+		baudMenu.AddRadioItem("baud", strconv.Itoa(rate), rate == current, func() error {
+			app.logDebug("Menu: Baud Rate %d", rate)
+			if err := app.setBaudRate(rate); err != nil {
+				app.notifyError("Baud rate change failed: %v", err)
+				return err
+			}
+			app.updateStatusMessage(fmt.Sprintf("Baud rate set to %d%s", rate, app.baudRateDeviation()))
+			return nil
+		})
+	}
+
+	baudMenu.AddSeparator()
+	baudMenu.AddItem("Custom...", "", func() error {
+		app.logDebug("Menu: Custom Baud Rate")
+		app.promptBaudRate()
+		return nil
+	})
+
+	return baudMenu
+}
+
+// setBaudRate reopens the serial port at a new baud rate. The previous
+// rate is restored if the port can't be opened. Remote ports that can be
+// reconfigured in place keep their connection.
+func (app *Application) setBaudRate(rate int) error {
+	cfg := app.config.SerialConfig
+	cfg.BaudRate = rate
+	return app.setLineSettings(cfg)
+}
+
+// setLineSettings applies new line settings (baud rate, framing, flow
+// control) the way setBaudRate does
+func (app *Application) setLineSettings(cfg serial.SerialConfig) error {
+	if reconfigurer, ok := app.serialPort.(serial.Reconfigurer); ok {
+		if err := reconfigurer.Reconfigure(cfg); err != nil {
+			return fmt.Errorf("failed to change line settings: %w", err)
+		}
+		app.config.SerialConfig = cfg
+		app.cachedStatusLeft = ""
+		return nil
+	}
+
+	// Reopening a pty or socket would restart the session
+	if !serial.IsDevicePort(cfg.Port) {
+		return fmt.Errorf("line settings only apply to serial devices")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if app.serialPort != nil && app.serialPort.IsOpen() {
+		app.serialPort.Close()
+	}
+
+	if err := app.serialPort.Open(cfg); err != nil {
+		_ = app.serialPort.Open(app.config.SerialConfig)
+		return fmt.Errorf("failed to reopen port: %w", err)
+	}
+
+	app.config.SerialConfig = cfg
+	app.portOpened()
+	app.cachedStatusLeft = "" // Port/baud shown in the status bar
+	return nil
+}
+
+// portOpened records what opening the port settled: which port a profile
+// matching a USB device by its IDs was opened on, and the baud rate the
+// driver rounded the requested one to, for the status bar and logs
+func (app *Application) portOpened() {
+	if app.config.SerialConfig.USB != nil {
+		app.config.SerialConfig.Port = app.serialPort.GetConfig().Port
+	}
+
+	actual := 0
+	if reporter, ok := app.serialPort.(serial.BaudRateReporter); ok {
+		actual = reporter.ActualBaudRate()
 	}
-	app.logDebug("Status: %s", message)
+	if requested := app.config.SerialConfig.BaudRate; actual > 0 && actual != requested {
+		app.driverBaud.Store(int64(actual))
+		app.logSerial("Driver set %d baud for the requested %d", actual, requested)
+	} else {
+		app.driverBaud.Store(0)
+	}
+	app.cachedStatusLeft = ""
+}
+
+// baudRateDeviation describes how far the driver's baud rate is from the
+// requested one, e.g. " (driver: 249600, -0.16%)", or "" when they match
+func (app *Application) baudRateDeviation() string {
+	actual := app.driverBaud.Load()
+	if actual == 0 {
+		return ""
+	}
+	requested := float64(app.config.SerialConfig.BaudRate)
+	return fmt.Sprintf(" (driver: %d, %+.2f%%)", actual, (float64(actual)-requested)/requested*100)
+}
+
+// updateStatusMessage shows a temporary informational status message
+func (app *Application) updateStatusMessage(message string) {
+	app.notify(SeverityInfo, message)
 }
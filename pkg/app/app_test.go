@@ -1,12 +1,30 @@
 package app
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
+	"sterm/pkg/config"
+	"sterm/pkg/decoder"
+	"sterm/pkg/history"
+	"sterm/pkg/menu"
 	"sterm/pkg/serial"
 	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 )
 
 func TestSessionManagement(t *testing.T) {
@@ -219,3 +237,2391 @@ func TestRunnerCreation(t *testing.T) {
 		t.Errorf("Runner serial port = %s, want COM1", runner.config.SerialConfig.Port)
 	}
 }
+
+// makeLine builds a terminal line from a string for tests
+func makeLine(s string) []terminal.Cell {
+	line := make([]terminal.Cell, 0, len(s))
+	for _, r := range s {
+		line = append(line, terminal.Cell{Char: r, Attributes: terminal.DefaultTextAttributes()})
+	}
+	return line
+}
+
+func TestDisplayFilter(t *testing.T) {
+	filter := NewDisplayFilter()
+
+	if filter.IsActive() {
+		t.Error("New filter should not be active")
+	}
+
+	// Invalid pattern should be rejected
+	if err := filter.SetPattern("(["); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+
+	if err := filter.SetPattern("ERR|WARN"); err != nil {
+		t.Fatalf("SetPattern failed: %v", err)
+	}
+	if !filter.IsActive() {
+		t.Error("Filter should be active after setting pattern")
+	}
+
+	lines := [][]terminal.Cell{
+		makeLine("INFO boot"),
+		makeLine("ERR disk"),
+		makeLine("INFO net"),
+		makeLine("WARN temp"),
+		makeLine("   "),
+	}
+
+	// Hide mode keeps only matching lines, in order
+	visible, _ := filter.Apply(lines, 10)
+	if len(visible) != 2 {
+		t.Fatalf("Hide mode returned %d lines, want 2", len(visible))
+	}
+	if lineToString(visible[0]) != "ERR disk" || lineToString(visible[1]) != "WARN temp" {
+		t.Errorf("Unexpected filtered lines: %q, %q", lineToString(visible[0]), lineToString(visible[1]))
+	}
+
+	// Hide mode respects height, keeping the most recent matches
+	visible, _ = filter.Apply(lines, 1)
+	if len(visible) != 1 || lineToString(visible[0]) != "WARN temp" {
+		t.Error("Hide mode should keep the most recent matching line")
+	}
+
+	// Highlight mode keeps all lines and reports matches
+	if filter.ToggleMode() != FilterModeHighlight {
+		t.Fatal("ToggleMode should switch to highlight")
+	}
+	visible, matches := filter.Apply(lines, 10)
+	if len(visible) != len(lines) {
+		t.Errorf("Highlight mode returned %d lines, want %d", len(visible), len(lines))
+	}
+	if matches[0] || !matches[1] || matches[2] || !matches[3] {
+		t.Errorf("Unexpected match flags: %v", matches)
+	}
+
+	// Toggle off and on
+	if filter.Toggle() {
+		t.Error("Toggle should disable the filter")
+	}
+	if !filter.Toggle() {
+		t.Error("Toggle should re-enable the filter")
+	}
+
+	// Empty pattern clears the filter
+	_ = filter.SetPattern("")
+	if filter.IsActive() || filter.Toggle() {
+		t.Error("Filter without pattern should stay inactive")
+	}
+}
+
+func TestBookmarkList(t *testing.T) {
+	bl := NewBookmarkList()
+
+	bl.Add(30, "third")
+	bl.Add(10, "first")
+	bl.Add(20, "")
+	bl.Add(10, "first again") // Same line replaces the note
+
+	if bl.Count() != 3 {
+		t.Fatalf("Count = %d, want 3", bl.Count())
+	}
+
+	list := bl.List()
+	if list[0].Line != 10 || list[1].Line != 20 || list[2].Line != 30 {
+		t.Errorf("Bookmarks not sorted by line: %v", list)
+	}
+	if list[0].Note != "first again" {
+		t.Errorf("Note = %q, want %q", list[0].Note, "first again")
+	}
+
+	if bm, ok := bl.Next(10, 0); !ok || bm.Line != 20 {
+		t.Errorf("Next(10) = %v, %v; want line 20", bm, ok)
+	}
+	if _, ok := bl.Next(30, 0); ok {
+		t.Error("Next(30) should find nothing")
+	}
+	if bm, ok := bl.Prev(30, 0); !ok || bm.Line != 20 {
+		t.Errorf("Prev(30) = %v, %v; want line 20", bm, ok)
+	}
+	// Bookmarks on trimmed lines are skipped
+	if _, ok := bl.Prev(20, 15); ok {
+		t.Error("Prev should skip bookmarks before minLine")
+	}
+
+	if _, ok := bl.At(20); !ok {
+		t.Error("At(20) should find bookmark")
+	}
+
+	// Rewrapping moves bookmarks with their text, merging any that meet
+	bl.Remap(func(line int) int { return line / 20 })
+	list = bl.List()
+	if len(list) != 2 || list[0].Line != 0 || list[1].Line != 1 || list[1].Note != "third" {
+		t.Errorf("Bookmarks after Remap = %v, want lines 0 and 1 (third)", list)
+	}
+
+	bl.Clear()
+	if bl.Count() != 0 {
+		t.Error("Clear should remove all bookmarks")
+	}
+}
+
+func TestAltKeyBindings(t *testing.T) {
+	// Defaults map each key to itself
+	keys, err := buildAltKeyMap(nil)
+	if err != nil {
+		t.Fatalf("buildAltKeyMap failed: %v", err)
+	}
+	if keys['p'] != 'p' || keys['k'] != 'k' {
+		t.Errorf("Expected default bindings, got %v", keys)
+	}
+
+	// Rebinding frees the default key, and actions can swap keys
+	keys, err = buildAltKeyMap(map[string]string{"screenshot": "Alt+O", "filter": "Alt+G", "toggle-filter": "Alt+F"})
+	if err != nil {
+		t.Fatalf("buildAltKeyMap failed: %v", err)
+	}
+	if keys['o'] != 'p' {
+		t.Errorf("Expected Alt+O to trigger screenshot, got %q", keys['o'])
+	}
+	if _, bound := keys['p']; bound {
+		t.Error("Expected Alt+P to be unbound")
+	}
+	if keys['g'] != 'f' || keys['f'] != 'g' {
+		t.Errorf("Expected filter keys to be swapped, got f=%q g=%q", keys['f'], keys['g'])
+	}
+
+	// Unknown actions are rejected
+	if _, err := buildAltKeyMap(map[string]string{"launch-rockets": "Alt+L"}); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestHelpLines(t *testing.T) {
+	app := &Application{
+		config:    AppConfig{EnableShortcuts: true},
+		shortcuts: terminal.NewShortcutManager(),
+	}
+	app.setupShortcuts()
+	app.shortcuts.CustomShortcut("macro", "Send login", tcell.KeyF5, 0, 0, func() error { return nil })
+
+	keys, err := buildAltKeyMap(map[string]string{"screenshot": "Alt+O"})
+	if err != nil {
+		t.Fatalf("buildAltKeyMap failed: %v", err)
+	}
+	app.altKeys = keys
+
+	help := strings.Join(app.helpLines(), "\n")
+	for _, want := range []string{"Scroll Mode", "Send login", "Alt+O", "Show keyboard shortcuts"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("Help should contain %q:\n%s", want, help)
+		}
+	}
+	// Shortcuts without handlers and rebound defaults aren't shown
+	if strings.Contains(help, "Open settings") || strings.Contains(help, "Alt+P") {
+		t.Errorf("Help lists inactive shortcuts:\n%s", help)
+	}
+}
+
+func TestNotificationQueue(t *testing.T) {
+	q := NewNotificationQueue()
+	start := time.Now()
+
+	q.Push(Notification{Message: "saved", Time: start})
+	q.Push(Notification{Message: "port lost", Severity: SeverityError, Time: start.Add(time.Second)})
+
+	// Newest first, both still on screen
+	active := q.Active(start.Add(2 * time.Second))
+	if len(active) != 2 || active[0].Message != "port lost" {
+		t.Fatalf("Expected 2 active notifications, newest first, got %v", active)
+	}
+
+	// Info expires after 3s, errors stay longer
+	if !q.Prune(start.Add(4 * time.Second)) {
+		t.Error("Expected the info notification to be pruned")
+	}
+	active = q.Active(start.Add(4 * time.Second))
+	if len(active) != 1 || active[0].Severity != SeverityError {
+		t.Errorf("Expected only the error to remain, got %v", active)
+	}
+	if q.Prune(start.Add(5 * time.Second)) {
+		t.Error("Expected nothing to prune")
+	}
+
+	// Explicit durations override the severity default
+	q.Push(Notification{Message: "short", Time: start, Duration: time.Millisecond})
+	if len(q.Active(start.Add(time.Second))) != 1 {
+		t.Error("Expected the short notification to have expired")
+	}
+
+	// Expired notifications stay in the history, which is capped
+	if history := q.History(); len(history) != 3 || history[0].Message != "short" {
+		t.Errorf("Expected 3 history entries, newest first, got %v", history)
+	}
+	for i := 0; i < maxNotificationHistory+10; i++ {
+		q.Push(Notification{Message: "spam"})
+	}
+	if len(q.History()) != maxNotificationHistory {
+		t.Errorf("Expected history capped at %d, got %d", maxNotificationHistory, len(q.History()))
+	}
+	if len(q.Active(time.Now())) != maxActiveNotifications {
+		t.Errorf("Expected at most %d stacked notifications", maxActiveNotifications)
+	}
+}
+
+func TestScrollbar(t *testing.T) {
+	// No scrollback: the thumb fills the track
+	if start, size := scrollbarThumbRange(20, 0, 0); start != 0 || size != 20 {
+		t.Errorf("Empty scrollback thumb = %d+%d, want 0+20", start, size)
+	}
+
+	// Top and bottom of a long scrollback pin the thumb to the ends
+	start, size := scrollbarThumbRange(20, 0, 1000)
+	if start != 0 || size < 1 {
+		t.Errorf("Top thumb = %d+%d", start, size)
+	}
+	if start, size := scrollbarThumbRange(20, 1000, 1000); start+size != 20 {
+		t.Errorf("Bottom thumb = %d+%d, want it to end at 20", start, size)
+	}
+	if start, _ := scrollbarThumbRange(20, 500, 1000); start == 0 {
+		t.Error("Middle thumb should not be at the top")
+	}
+
+	// Clicking the track maps rows to lines across the whole scrollback
+	if line := scrollbarLine(0, 20, 1000); line != 0 {
+		t.Errorf("scrollbarLine(top) = %d, want 0", line)
+	}
+	if line := scrollbarLine(19, 20, 1000); line != 1000 {
+		t.Errorf("scrollbarLine(bottom) = %d, want 1000", line)
+	}
+	if line := scrollbarLine(50, 20, 1000); line != 1000 {
+		t.Errorf("scrollbarLine(out of range) = %d, want 1000", line)
+	}
+}
+
+// cellLine converts text to a line of cells, following wide characters with
+// a continuation cell like the terminal does
+func cellLine(text string) []terminal.Cell {
+	var line []terminal.Cell
+	for _, r := range text {
+		line = append(line, terminal.Cell{Char: r, Attributes: terminal.DefaultTextAttributes()})
+		if runewidth.RuneWidth(r) == 2 {
+			line = append(line, terminal.Cell{Char: 0})
+		}
+	}
+	return line
+}
+
+func TestFindLinks(t *testing.T) {
+	lines := [][]terminal.Cell{
+		cellLine("See https://example.com/docs (or http://x.io/a_(b)), then /var/log/messages:12."),
+		cellLine("下载 https://例子.cn/路径, ./build.log"),
+		cellLine("ratio 1/2 and and/or are not paths"),
+	}
+
+	links := findLinks(lines, false)
+	var texts []string
+	for _, l := range links {
+		texts = append(texts, l.Text)
+	}
+	want := []string{"https://example.com/docs", "http://x.io/a_(b)", "https://例子.cn/路径"}
+	if strings.Join(texts, " ") != strings.Join(want, " ") {
+		t.Errorf("URLs = %q, want %q", texts, want)
+	}
+
+	// Columns account for wide characters before the link
+	if links[2].Row != 1 || links[2].StartCol != 5 {
+		t.Errorf("Wide char link at row %d col %d, want row 1 col 5", links[2].Row, links[2].StartCol)
+	}
+	if links[0].StartCol != 4 || links[0].EndCol != 4+len("https://example.com/docs") {
+		t.Errorf("Link columns = %d-%d", links[0].StartCol, links[0].EndCol)
+	}
+
+	// File paths only when enabled, and never inside URLs
+	texts = nil
+	for _, l := range findLinks(lines, true) {
+		if l.IsPath {
+			texts = append(texts, l.Text)
+		}
+	}
+	want = []string{"/var/log/messages:12", "./build.log"}
+	if strings.Join(texts, " ") != strings.Join(want, " ") {
+		t.Errorf("Paths = %q, want %q", texts, want)
+	}
+	if got := linkFilePath("/var/log/messages:12"); got != "/var/log/messages" {
+		t.Errorf("linkFilePath = %q", got)
+	}
+}
+
+func TestHintLabels(t *testing.T) {
+	if labels := hintLabels(3); strings.Join(labels, ",") != "a,s,d" {
+		t.Errorf("hintLabels(3) = %v", labels)
+	}
+
+	labels := hintLabels(100)
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		if len(label) != 2 {
+			t.Fatalf("Expected two-letter labels for 100 links, got %q", label)
+		}
+		if seen[label] {
+			t.Fatalf("Duplicate label %q", label)
+		}
+		seen[label] = true
+	}
+}
+
+func TestResolveAmbiguousWide(t *testing.T) {
+	t.Setenv("RUNEWIDTH_EASTASIAN", "")
+
+	if !resolveAmbiguousWide("wide", false, true) {
+		t.Error("wide setting should override the host terminal")
+	}
+	if resolveAmbiguousWide("narrow", true, true) {
+		t.Error("narrow setting should override the host terminal")
+	}
+	if !resolveAmbiguousWide("auto", true, true) {
+		t.Error("auto should follow the host terminal when detected")
+	}
+
+	t.Setenv("RUNEWIDTH_EASTASIAN", "0")
+	if resolveAmbiguousWide("", true, true) {
+		t.Error("RUNEWIDTH_EASTASIAN should take precedence in auto mode")
+	}
+}
+
+func TestSelectionText(t *testing.T) {
+	lines := [][]terminal.Cell{
+		cellLine("PORT   STATE  RSSI"),
+		cellLine("eth0   up     -41 "),
+		cellLine("wlan0  down   -87 /dev/ttyUSB0"),
+	}
+
+	tests := []struct {
+		name string
+		sel  Selection
+		want string
+	}{
+		{"char across lines", Selection{Mode: SelectChar, AnchorX: 7, AnchorY: 0, HeadX: 3, HeadY: 1}, "STATE  RSSI\neth0"},
+		{"char reversed", Selection{Mode: SelectChar, AnchorX: 3, AnchorY: 1, HeadX: 7, HeadY: 0}, "STATE  RSSI\neth0"},
+		{"word", Selection{Mode: SelectWord, AnchorX: 22, AnchorY: 2, HeadX: 22, HeadY: 2}, "/dev/ttyUSB0"},
+		{"word drag", Selection{Mode: SelectWord, AnchorX: 1, AnchorY: 1, HeadX: 8, HeadY: 1}, "eth0   up"},
+		{"line", Selection{Mode: SelectLine, AnchorX: 5, AnchorY: 1, HeadX: 5, HeadY: 1}, "eth0   up     -41"},
+		{"block", Selection{Mode: SelectBlock, AnchorX: 17, AnchorY: 2, HeadX: 14, HeadY: 0}, "RSSI\n-41\n-87"},
+	}
+	for _, tt := range tests {
+		if got := tt.sel.Text(lines); got != tt.want {
+			t.Errorf("%s: Text() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWrappedLines(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(11, 5)
+
+	app := &Application{
+		config:        DefaultAppConfig(),
+		screen:        sim,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 11, 4),
+		notifications: NewNotificationQueue(),
+		updateNotify:  make(chan struct{}, 100),
+		filter:        NewDisplayFilter(),
+		isRunning:     true,
+	}
+	_ = app.terminal.Start()
+
+	// The gutter takes a column from the terminal
+	app.setWrapMarkers(true)
+	if width := app.terminal.GetScreen().Width; width != 10 {
+		t.Fatalf("Terminal %d wide with wrap markers, want 10", width)
+	}
+	_ = app.terminal.ProcessOutput([]byte("0123456789abcde\r\nshort"))
+	app.updateDisplay()
+	gutter := func(y int) rune {
+		ch, _, _, _ := sim.GetContent(10, y)
+		return ch
+	}
+	if gutter(0) != wrapMarker || gutter(1) != ' ' || gutter(2) != ' ' {
+		t.Errorf("Gutter = %q %q %q, want a marker on the first row only", gutter(0), gutter(1), gutter(2))
+	}
+
+	// Copying joins the rows of a wrapped line, except in block selections
+	lines := app.terminal.GetScreen().Buffer
+	selections := []struct {
+		sel  Selection
+		want string
+	}{
+		{Selection{Mode: SelectChar, AnchorX: 8, AnchorY: 0, HeadX: 2, HeadY: 2}, "89abcde\nsho"},
+		{Selection{Mode: SelectLine, AnchorX: 2, AnchorY: 1, HeadX: 2, HeadY: 1}, "0123456789abcde"},
+		{Selection{Mode: SelectBlock, AnchorX: 0, AnchorY: 0, HeadX: 1, HeadY: 1}, "01\nab"},
+	}
+	for _, tt := range selections {
+		if got := tt.sel.Text(lines); got != tt.want {
+			t.Errorf("Text() of %+v = %q, want %q", tt.sel, got, tt.want)
+		}
+	}
+
+	// The filter matches text across the wrap and shows all its rows
+	_ = app.filter.SetPattern("9ab")
+	visible, _ := app.filter.Apply(lines, 4)
+	if len(visible) != 2 || lineToString(visible[1]) != "abcde" {
+		t.Errorf("Filtered rows = %d, want both rows of the wrapped line", len(visible))
+	}
+	app.filter.SetMode(FilterModeHighlight)
+	if _, matches := app.filter.Apply(lines, 4); len(matches) < 3 || !matches[0] || !matches[1] || matches[2] {
+		t.Errorf("Highlighted rows = %v, want both rows of the wrapped line", matches)
+	}
+
+	app.setWrapMarkers(false)
+	if width := app.terminal.GetScreen().Width; width != 11 {
+		t.Errorf("Terminal %d wide without wrap markers, want 11", width)
+	}
+}
+
+func TestSaveSessionLayout(t *testing.T) {
+	app := &Application{
+		config:        DefaultAppConfig(),
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 10, 4),
+		notifications: NewNotificationQueue(),
+		bookmarks:     NewBookmarkList(),
+	}
+	_ = app.terminal.Start()
+	_ = app.terminal.ProcessOutput([]byte("0123456789abc\r\nxy"))
+
+	// Padding and the empty rows under the output are left out
+	layouts := []struct {
+		layout string
+		want   string
+	}{
+		{"", "0123456789abc\nxy\n"},
+		{"visual", "0123456789\nabc\nxy\n"},
+	}
+	for _, tt := range layouts {
+		app.logging.SessionLayout = tt.layout
+		path := filepath.Join(t.TempDir(), "session.txt")
+		if err := app.saveSessionTo(path); err != nil {
+			t.Fatalf("saveSessionTo failed: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, text, _ := strings.Cut(string(data), "========================\n\n")
+		if text != tt.want {
+			t.Errorf("Session text in %q layout = %q, want %q", tt.layout, text, tt.want)
+		}
+	}
+}
+
+func TestSelectionMouse(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 6)
+
+	app := &Application{
+		screen:        screen,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 40, 5),
+		notifications: NewNotificationQueue(),
+	}
+	_ = app.terminal.Start()
+	_ = app.terminal.ProcessOutput([]byte("hello world"))
+
+	click := func(mods tcell.ModMask) {
+		app.handleSelectionMouse(tcell.NewEventMouse(7, 0, tcell.Button1, mods))
+		app.handleSelectionMouse(tcell.NewEventMouse(7, 0, tcell.ButtonNone, mods))
+	}
+
+	// Without Shift the click belongs to the remote application
+	if app.handleSelectionMouse(tcell.NewEventMouse(7, 0, tcell.Button1, tcell.ModNone)) {
+		t.Fatal("Plain click outside scroll mode should not select")
+	}
+
+	click(tcell.ModShift)
+	if app.selection.current != nil {
+		t.Error("A single click should not leave a selection")
+	}
+	click(tcell.ModShift)
+	if sel := app.selection.current; sel == nil || sel.Mode != SelectWord {
+		t.Fatalf("Double click should select a word, got %+v", sel)
+	}
+	if got := app.selection.current.Text(app.visibleLines()); got != "world" {
+		t.Errorf("Double click selected %q, want %q", got, "world")
+	}
+	click(tcell.ModShift)
+	if sel := app.selection.current; sel == nil || sel.Mode != SelectLine {
+		t.Errorf("Triple click should select the line, got %+v", sel)
+	}
+
+	app.clearSelection()
+	if app.selection.current != nil {
+		t.Error("clearSelection should drop the selection")
+	}
+}
+
+func TestScrollRender(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(20, 6)
+
+	app := &Application{
+		config:        DefaultAppConfig(),
+		screen:        sim,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 20, 5),
+		notifications: NewNotificationQueue(),
+		isRunning:     true,
+	}
+	_ = app.terminal.Start()
+	_ = app.terminal.ProcessOutput([]byte("line1\r\nline2\r\nline3\r\nline4\r\nline5"))
+	app.updateDisplay()
+
+	rowText := func(y int) string {
+		var sb strings.Builder
+		for x := 0; x < 20; x++ {
+			ch, _, _, _ := sim.GetContent(x, y)
+			sb.WriteRune(ch)
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+	scrollTwo := func(first, second string) {
+		t.Helper()
+		_ = app.terminal.ProcessOutput([]byte("\r\n" + first + "\r\n" + second))
+		if scroll := app.terminal.GetScreen().Scrolled; scroll.Lines != 2 || scroll.Top != 0 || scroll.Bottom != 4 {
+			t.Fatalf("Scrolled = %+v, want two rows of the whole screen", scroll)
+		}
+		// A mark only the renderer can't know about shows whether rows moved
+		sim.SetContent(19, 4, '#', nil, tcell.StyleDefault)
+		app.updateDisplay()
+	}
+
+	scrollTwo("line6", "line7")
+	for y, want := range []string{"line3", "line4", "line5", "line6", "line7"} {
+		if got := rowText(y); got != want && got != want+strings.Repeat(" ", 14)+"#" {
+			t.Errorf("Row %d = %q, want %q", y, got, want)
+		}
+	}
+	if ch, _, _, _ := sim.GetContent(19, 2); ch != '#' {
+		t.Error("Rows were drawn again instead of moved")
+	}
+	if _, ok := app.terminal.GetScreen().TakeScroll(); ok {
+		t.Error("Scroll still pending after the update")
+	}
+
+	// With a selection over the rows they are drawn again
+	app.selection.current = &Selection{Mode: SelectChar}
+	scrollTwo("line8", "line9")
+	app.selection.current = nil
+	for y, want := range []string{"line5", "line6", "line7", "line8", "line9"} {
+		if got := rowText(y); got != want {
+			t.Errorf("Row %d with a selection = %q, want %q", y, got, want)
+		}
+	}
+}
+
+func TestDebugOverlay(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(80, 16)
+
+	app := &Application{
+		config:        DefaultAppConfig(),
+		screen:        sim,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 80, 15),
+		notifications: NewNotificationQueue(),
+		updateNotify:  make(chan struct{}, 100),
+		isRunning:     true,
+	}
+	_ = app.terminal.Start()
+	_ = app.terminal.ProcessOutput([]byte("boot\x1b[2K\r\n"))
+
+	rowText := func(y int) string {
+		var sb strings.Builder
+		for x := 0; x < 80; x++ {
+			ch, _, _, _ := sim.GetContent(x, y)
+			sb.WriteRune(ch)
+		}
+		return sb.String()
+	}
+
+	app.toggleDebugOverlay()
+	if !app.debugOverlay.visible.Load() || app.contentUncovered(0) {
+		t.Fatal("Overlay not shown, or rows still moved under it")
+	}
+	app.updateDisplay()
+	if !strings.Contains(rowText(0), "Debug") {
+		t.Errorf("Header row = %q, want the overlay", rowText(0))
+	}
+	var text strings.Builder
+	for y := 1; y < 13; y++ {
+		text.WriteString(rowText(y) + "\n")
+	}
+	for _, want := range []string{"Parser     ground", `"\x1b[2K" (1 parsed)`, "Cursor     2,1 on the main screen", "Queue      ", "Goroutines "} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Overlay lacks %q:\n%s", want, text.String())
+		}
+	}
+
+	// Shown, it is redrawn now and then to refresh the counters
+	now := time.Now()
+	if !app.debugOverlayDue(now) || app.debugOverlayDue(now.Add(debugOverlayRefresh/2)) || !app.debugOverlayDue(now.Add(debugOverlayRefresh)) {
+		t.Error("Overlay not refreshed every debugOverlayRefresh")
+	}
+
+	app.toggleDebugOverlay()
+	app.updateDisplay()
+	if strings.Contains(rowText(0), "Debug") || app.debugOverlayDue(now.Add(time.Hour)) {
+		t.Errorf("Hidden overlay still drawn or refreshed: %q", rowText(0))
+	}
+}
+
+func TestSynchronizedOutputRender(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(20, 6)
+
+	app := &Application{
+		config:        DefaultAppConfig(),
+		screen:        sim,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 20, 5),
+		notifications: NewNotificationQueue(),
+		isRunning:     true,
+	}
+	_ = app.terminal.Start()
+	rowText := func(y int) string {
+		var sb strings.Builder
+		for x := 0; x < 20; x++ {
+			ch, _, _, _ := sim.GetContent(x, y)
+			sb.WriteRune(ch)
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+
+	_ = app.terminal.ProcessOutput([]byte("old"))
+	app.updateDisplay()
+	_ = app.terminal.ProcessOutput([]byte("\x1b[?2026h\x1b[2J\x1b[Hnew"))
+	app.updateDisplay()
+	if got := rowText(0); got != "old" {
+		t.Errorf("Row 0 = %q during the synchronized update, want the old screen", got)
+	}
+	if !app.frames.syncHeld.Load() {
+		t.Error("Held frame not recorded for a retry")
+	}
+
+	_ = app.terminal.ProcessOutput([]byte(" screen\x1b[?2026l"))
+	app.updateDisplay()
+	if got := rowText(0); got != "new screen" {
+		t.Errorf("Row 0 = %q after the update, want it drawn at once", got)
+	}
+
+	// An update that never ends stops holding frames back
+	_ = app.terminal.ProcessOutput([]byte("\x1b[?2026h"))
+	if !app.frames.holdForSync(app.terminal, time.Now()) || app.frames.holdForSync(app.terminal, time.Now().Add(terminal.SyncTimeout)) {
+		t.Error("Frames not held until terminal.SyncTimeout")
+	}
+}
+
+func TestFrameScheduler(t *testing.T) {
+	var frames frameScheduler
+	start := time.Now()
+	if wait := frames.wait(start); wait != 0 {
+		t.Errorf("First update waits %v", wait)
+	}
+
+	// Updates within a frame of the last one wait for the next frame
+	frames.frameDrawn(start)
+	if wait := frames.wait(start.Add(5 * time.Millisecond)); wait <= 0 || wait > time.Second/defaultMaxFPS {
+		t.Errorf("Update 5ms after a frame waits %v", wait)
+	}
+	if wait := frames.wait(start.Add(20 * time.Millisecond)); wait != 0 {
+		t.Errorf("Update 20ms after a frame waits %v", wait)
+	}
+	frames.configure(10)
+	if wait := frames.wait(start.Add(50 * time.Millisecond)); wait != 50*time.Millisecond {
+		t.Errorf("Update 50ms after a frame at 10 fps waits %v, want 50ms", wait)
+	}
+	frames.configure(0)
+
+	// A flood spaces frames out, and they speed up again when it ends
+	frames.addReceived(1 << 20)
+	if interval := frames.interval(start.Add(100 * time.Millisecond)); interval != floodFrameInterval {
+		t.Errorf("Interval during a flood = %v, want %v", interval, floodFrameInterval)
+	}
+	if interval := frames.interval(start.Add(2 * time.Second)); interval != time.Second/defaultMaxFPS {
+		t.Errorf("Interval after the flood = %v", interval)
+	}
+
+	if got := frames.stats.String(); !strings.HasPrefix(got, "frames 1 (0 immediate), requests 0") {
+		t.Errorf("Stats = %q", got)
+	}
+}
+
+func TestFindCharset(t *testing.T) {
+	for name, want := range map[string]string{"": "utf-8", "SJIS": "shift-jis", "iso-8859-1": "latin1", "GBK": "gbk"} {
+		cs, err := FindCharset(name)
+		if err != nil || cs.Name != want {
+			t.Errorf("FindCharset(%q) = %q, %v, want %q", name, cs.Name, err, want)
+		}
+	}
+	if _, err := FindCharset("ebcdic"); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+}
+
+func TestCharsetCodec(t *testing.T) {
+	codec := func(name string) *charsetCodec {
+		cs, err := FindCharset(name)
+		if err != nil {
+			t.Fatalf("FindCharset(%q) failed: %v", name, err)
+		}
+		return newCharsetCodec(cs)
+	}
+
+	// GBK "中文" is D6 D0 CE C4; split a character across two reads
+	gbk := codec("gbk")
+	got := string(gbk.Decode([]byte{'>', 0xD6, 0xD0, 0xCE})) + string(gbk.Decode([]byte{0xC4, '\r'}))
+	if got != ">中文\r" {
+		t.Errorf("GBK decoded %q, want %q", got, ">中文\r")
+	}
+	if got := gbk.Encode([]byte("中文")); string(got) != "\xD6\xD0\xCE\xC4" {
+		t.Errorf("GBK encoded % X", got)
+	}
+
+	// CP437 box drawing and escape sequences pass through
+	cp437 := codec("cp437")
+	if got := string(cp437.Decode([]byte("\x1b[1m\xC9\xCD\xBB"))); got != "\x1b[1m╔═╗" {
+		t.Errorf("CP437 decoded %q", got)
+	}
+
+	// Characters Latin-1 can't represent are replaced rather than dropped
+	latin1 := codec("latin1")
+	if got := latin1.Encode([]byte("é中")); len(got) != 2 || got[0] != 0xE9 {
+		t.Errorf("Latin-1 encoded % X", got)
+	}
+
+	sjis := codec("shift-jis")
+	if got := string(sjis.Decode([]byte{0x82, 0xA0})); got != "あ" {
+		t.Errorf("Shift-JIS decoded %q, want %q", got, "あ")
+	}
+
+	// UTF-8 is passed through untouched
+	utf8 := codec("utf-8")
+	if got := utf8.Decode([]byte{0xE4, 0xB8}); string(got) != "\xE4\xB8" {
+		t.Errorf("UTF-8 decode changed data: % X", got)
+	}
+}
+
+func TestBellState(t *testing.T) {
+	var b bellState
+	b.configure(config.BellSettings{Audible: true, Visual: true})
+
+	start := time.Now()
+	if _, ok := b.ring(start); !ok {
+		t.Fatal("First bell should ring")
+	}
+	if _, ok := b.ring(start.Add(50 * time.Millisecond)); ok {
+		t.Error("Bell within the throttle interval should not ring")
+	}
+	if !b.flashing(start.Add(100 * time.Millisecond)) {
+		t.Error("Visual bell should be showing")
+	}
+	if b.flashEnded(start.Add(100 * time.Millisecond)) {
+		t.Error("Visual bell ended too early")
+	}
+	if !b.flashEnded(start.Add(time.Second)) || b.flashEnded(start.Add(time.Second)) {
+		t.Error("flashEnded should report the end of the visual bell exactly once")
+	}
+	if _, ok := b.ring(start.Add(time.Second)); !ok {
+		t.Error("Bell after the throttle interval should ring")
+	}
+}
+
+func TestActivityWatchdog(t *testing.T) {
+	var w activityWatchdog
+	w.configure(config.WatchdogSettings{IdleSeconds: 60, ResumeSeconds: 300})
+
+	start := time.Now()
+	w.start(start)
+
+	if _, fire := w.check(start.Add(30 * time.Second)); fire {
+		t.Error("Idle alert fired before the limit")
+	}
+	if quiet, fire := w.check(start.Add(61 * time.Second)); !fire || quiet != 61*time.Second {
+		t.Errorf("Idle alert = %v, %v, want 61s, true", quiet, fire)
+	}
+	if _, fire := w.check(start.Add(120 * time.Second)); fire {
+		t.Error("Idle alert should fire once per silence")
+	}
+
+	// Data after a short silence only rearms the idle alert
+	if _, fire := w.data(start.Add(130 * time.Second)); fire {
+		t.Error("Resume alert fired after a short silence")
+	}
+	if _, fire := w.check(start.Add(200 * time.Second)); !fire {
+		t.Error("Idle alert should fire again after new data")
+	}
+
+	// Data after a long silence raises the resume alert
+	if quiet, fire := w.data(start.Add(500 * time.Second)); !fire || quiet != 370*time.Second {
+		t.Errorf("Resume alert = %v, %v, want 370s, true", quiet, fire)
+	}
+
+	// Zero limits turn the alerts off
+	w.configure(config.WatchdogSettings{})
+	if _, fire := w.check(start.Add(time.Hour)); fire {
+		t.Error("Idle alert fired while disabled")
+	}
+}
+
+func TestExpandLogName(t *testing.T) {
+	values := map[string]string{"kind": "session", "port": "ttyUSB0", "baud": "115200", "profile": "lab/router", "date": "20260101", "time": "120000"}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{kind}_{date}_{time}", "session_20260101_120000"},
+		{"{port}-{baud}", "ttyUSB0-115200"},
+		{"{profile}", "lab_router"}, // Separators can't escape the log directory
+		{"{unknown}_{kind}", "{unknown}_session"},
+		{"plain {", "plain {"},
+	}
+	for _, tt := range tests {
+		if got := expandLogName(tt.template, values); got != tt.want {
+			t.Errorf("expandLogName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestLogFilePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	app := &Application{
+		config:  AppConfig{SerialConfig: serial.SerialConfig{Port: "/dev/ttyUSB0", BaudRate: 9600}},
+		logging: config.LoggingSettings{Directory: dir, NameTemplate: "{profile}_{baud}_{kind}"},
+	}
+
+	path, err := app.logFilePath(logKindHistory, ".log")
+	if err != nil {
+		t.Fatalf("logFilePath failed: %v", err)
+	}
+	if want := filepath.Join(dir, "ttyUSB0_9600_history.log"); path != want {
+		t.Errorf("logFilePath = %q, want %q", path, want)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("Log directory was not created: %v", err)
+	}
+}
+
+func TestWriteCrashReport(t *testing.T) {
+	var sb strings.Builder
+	writeCrashReport(&sb, "updateUI", "index out of range", []byte("goroutine 1 [running]:\n"), "/tmp/COM3.spool")
+	report := sb.String()
+
+	for _, want := range []string{
+		"sterm crashed in updateUI: index out of range",
+		"/tmp/COM3.spool",
+		"goroutine 1 [running]:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report missing %q:\n%s", want, report)
+		}
+	}
+
+	// Without autosave there's nothing to mention
+	sb.Reset()
+	writeCrashReport(&sb, "main loop", "boom", nil, "")
+	if strings.Contains(sb.String(), "saved to") {
+		t.Errorf("Report mentions saved data without a spool:\n%s", sb.String())
+	}
+}
+
+func TestFeedDecoders(t *testing.T) {
+	app := &Application{}
+	if err := app.setDecoderEnabled("morse", true); err == nil {
+		t.Error("Unknown decoder enabled")
+	}
+	if err := app.setDecoderEnabled("nmea", true); err != nil {
+		t.Fatalf("setDecoderEnabled failed: %v", err)
+	}
+	_ = app.setDecoderEnabled("nmea", true) // Enabling twice keeps one decoder
+	if names := app.activeDecoderNames(); len(names) != 1 || names[0] != "nmea" {
+		t.Errorf("Active decoders = %v", names)
+	}
+
+	app.feedDecoders([]byte("$GPGLL,4916.45,N,12311.12,W,225444,A*31\r\n$GPGLL,"))
+	app.feedDecoders(nil)
+	frames := app.decodedFrames(10)
+	if len(frames) != 1 || frames[0].Decoder != "nmea" {
+		t.Fatalf("Frames = %+v", frames)
+	}
+
+	_ = app.setDecoderEnabled("nmea", false)
+	app.feedDecoders([]byte("4916.45,N,12311.12,W,225444,A*31\r\n"))
+	if got := len(app.decodedFrames(10)); got != 1 {
+		t.Errorf("Stopped decoder still adds frames: %d", got)
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []byte
+	}{
+		{"AA 55 01 FF", []byte{0xaa, 0x55, 0x01, 0xff}},
+		{"0xAA,0x55, 1", []byte{0xaa, 0x55, 0x01}},
+		{"aa5501ff", []byte{0xaa, 0x55, 0x01, 0xff}},
+		{`"AT\r\n"`, []byte("AT\r\n")},
+		{`02 "OK \"x\"" 03`, append(append([]byte{0x02}, `OK "x"`...), 0x03)},
+		{`"\xff\x00"`, []byte{0xff, 0x00}},
+	}
+	for _, tt := range tests {
+		data, err := parsePayload(tt.input)
+		if err != nil {
+			t.Errorf("parsePayload(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.expected) {
+			t.Errorf("parsePayload(%q) = % x, want % x", tt.input, data, tt.expected)
+		}
+	}
+
+	for _, input := range []string{"", "  ", "AAA", "GG", `"open`, `"\q"`} {
+		if _, err := parsePayload(input); err == nil {
+			t.Errorf("parsePayload(%q) should fail", input)
+		}
+	}
+}
+
+func TestCodePointInput(t *testing.T) {
+	latin1, _ := FindCharset("latin1")
+	tests := []struct {
+		input    string
+		charset  Charset
+		expected []byte
+	}{
+		{"e9", charsets[0], []byte("é")},
+		{"U+20AC, u+1F600", charsets[0], []byte("€😀")},
+		{`U+00E9 \x1b 41`, latin1, []byte{0xe9, 0x1b, 'A'}},
+	}
+	for _, tt := range tests {
+		inputs, err := parseCodeInput(tt.input)
+		if err != nil {
+			t.Errorf("parseCodeInput(%q) failed: %v", tt.input, err)
+			continue
+		}
+		data, err := encodeCodeInput(inputs, tt.charset)
+		if err != nil {
+			t.Errorf("encodeCodeInput(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.expected) {
+			t.Errorf("%q in %s = % x, want % x", tt.input, tt.charset.Label, data, tt.expected)
+		}
+	}
+
+	for _, input := range []string{"", "U+", "D800", "110000", `\x1`, "zz"} {
+		if _, err := parseCodeInput(input); err == nil {
+			t.Errorf("parseCodeInput(%q) should fail", input)
+		}
+	}
+	inputs, _ := parseCodeInput("20AC")
+	if _, err := encodeCodeInput(inputs, latin1); err == nil {
+		t.Error("A character Latin-1 can't encode should be refused")
+	}
+}
+
+func TestTriggerCapture(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 6)
+
+	dir := t.TempDir()
+	app := &Application{
+		screen:        screen,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 40, 5),
+		notifications: NewNotificationQueue(),
+		logging:       config.LoggingSettings{Directory: dir, NameTemplate: "{kind}"},
+	}
+	_ = app.terminal.Start()
+	app.triggers.configure([]config.TriggerSettings{{Pattern: `Kernel panic`, Capture: true, ContextLines: 2, Pause: true}})
+
+	receive := func(text string) {
+		_ = app.terminal.ProcessOutput([]byte(text))
+		app.checkTriggers([]byte(text))
+	}
+	receive("one\r\ntwo\r\nthree\r\n")
+	receive("\x1b[31mKernel panic\x1b[0m - not syncing\r\nafter\r\nmo")
+	if app.triggers.capturing() == "" {
+		t.Fatal("Trigger did not start a capture")
+	}
+	if !app.terminal.IsScrolling() {
+		t.Error("Trigger did not pause the display")
+	}
+	receive("re\r\n")
+
+	path, err := app.triggers.stopCapture()
+	if err != nil {
+		t.Fatalf("stopCapture failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read capture: %v", err)
+	}
+	want := "two\nthree\nKernel panic - not syncing\nafter\nmore\n"
+	if string(data) != want {
+		t.Errorf("Capture = %q, want %q", data, want)
+	}
+}
+
+func TestChecksumLines(t *testing.T) {
+	lines := checksumLines([]byte("123456789"))
+	if lines[0] != "9 bytes: 31 32 33 34 35 36 37 38 39" {
+		t.Errorf("Preview line = %q", lines[0])
+	}
+	var modbus string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "CRC-16/MODBUS") {
+			modbus = line
+		}
+	}
+	if !strings.Contains(modbus, "0x4B37") || !strings.Contains(modbus, "BE: 4B 37  LE: 37 4B") {
+		t.Errorf("CRC-16/MODBUS line = %q", modbus)
+	}
+}
+
+func TestASCIITableLines(t *testing.T) {
+	app := &Application{}
+	lines := app.asciiTableLines()
+	text := strings.Join(lines, "\n")
+
+	for _, want := range []string{
+		"  00    0  NUL  ^@    \\0   Ctrl+@, Ctrl+Space Null",
+		"  11   17  DC1  ^Q         Ctrl+Q*",
+		"  1B   27  ESC  ^[    \\e   Ctrl+[, Esc",
+		"  7F  127  DEL  ^?         Backspace",
+		" 41  65 A",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("ASCII table missing %q", want)
+		}
+	}
+	if strings.Contains(text, "Ctrl+A*") {
+		t.Error("Ctrl+A should not be marked as reserved")
+	}
+}
+
+func TestVisibleControls(t *testing.T) {
+	rev := func(s string) string { return "\x1b[7m" + s + "\x1b[27m" }
+	got := string(visibleControls([]byte("ok\r\n\x1b[1mA\x7f")))
+	want := "ok" + rev("␍") + rev("␊") + "\r\n" + rev("␛") + "[1mA" + rev("␡")
+	if got != want {
+		t.Errorf("visibleControls = %q, want %q", got, want)
+	}
+
+	// Drawn through the terminal the controls are text, not actions
+	term := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	_ = term.Start()
+	_ = term.ProcessOutput(visibleControls([]byte("a\tb\r\nc")))
+	screen := term.GetScreen()
+	if line := lineToString(screen.Buffer[0]); line != "a␉b␍␊" {
+		t.Errorf("First line = %q", line)
+	}
+	if line := lineToString(screen.Buffer[1]); line != "c" {
+		t.Errorf("Second line = %q", line)
+	}
+}
+
+func TestInputLock(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	app := &Application{serialPort: port, notifications: NewNotificationQueue()}
+	startTestTxQueue(t, app)
+	app.toggleInputLock()
+	app.sendUserData([]byte("blocked"))
+	app.toggleInputLock()
+	app.sendUserData([]byte("sent"))
+
+	buffer := make([]byte, 64)
+	n, err := port.Read(buffer)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := string(buffer[:n]); got != "sent" {
+		t.Errorf("Device received %q, want only what was typed while unlocked", got)
+	}
+}
+
+func TestLiteralNext(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	emulator := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	app := &Application{
+		serialPort:     port,
+		notifications:  NewNotificationQueue(),
+		terminal:       emulator,
+		inputProcessor: terminal.NewInputProcessor(emulator),
+	}
+	startTestTxQueue(t, app)
+
+	// Pressing the binding twice cancels it
+	app.armLiteralNext()
+	app.armLiteralNext()
+	if app.literalNext {
+		t.Fatal("Second press should cancel the literal key")
+	}
+
+	// Ctrl+Q would exit; armed, it goes to the device instead
+	app.armLiteralNext()
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 0x11, tcell.ModNone))
+	if app.literalNext {
+		t.Error("Literal key should only apply to one key")
+	}
+
+	buffer := make([]byte, 64)
+	n, err := port.Read(buffer)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := string(buffer[:n]); got != "\x11" {
+		t.Errorf("Device received %q, want Ctrl+Q", got)
+	}
+}
+
+func TestKeyboardPassthrough(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	emulator := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	app := &Application{
+		serialPort:     port,
+		notifications:  NewNotificationQueue(),
+		terminal:       emulator,
+		inputProcessor: terminal.NewInputProcessor(emulator),
+	}
+	startTestTxQueue(t, app)
+
+	app.togglePassthrough()
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 0x11, tcell.ModNone)) // Ctrl+Q
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 'c', tcell.ModAlt))
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 't', tcell.ModAlt))
+	if app.passthrough.Load() {
+		t.Fatal("The passthrough binding should turn passthrough off")
+	}
+
+	var got []byte
+	buffer := make([]byte, 64)
+	for len(got) < 3 {
+		n, err := port.Read(buffer)
+		if err != nil || n == 0 {
+			break
+		}
+		got = append(got, buffer[:n]...)
+	}
+	if string(got) != "\x11\x1bc" {
+		t.Errorf("Device received %q, want Ctrl+Q and Alt+C but not Alt+T", got)
+	}
+}
+
+func TestChordPrefix(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	emulator := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	app := &Application{
+		serialPort:     port,
+		notifications:  NewNotificationQueue(),
+		terminal:       emulator,
+		inputProcessor: terminal.NewInputProcessor(emulator),
+		shortcuts:      terminal.NewShortcutManager(),
+		mainMenu:       menu.NewMenu("test", nil),
+	}
+	startTestTxQueue(t, app)
+	prefix, err := app.applyChordPrefix(config.PrefixSettings{Key: "Ctrl+b"})
+	if err != nil {
+		t.Fatalf("applyChordPrefix failed: %v", err)
+	}
+	app.chordPrefix = prefix
+	if got := app.keyLabel("input-lock"); got != "Ctrl+B I" {
+		t.Errorf("keyLabel = %q, want the chord", got)
+	}
+
+	ctrlB := tcell.NewEventKey(tcell.KeyRune, 0x02, tcell.ModNone)
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 'i', tcell.ModAlt)) // Not sterm's without the prefix
+	app.handleKeyEvent(ctrlB)
+	app.handleKeyEvent(ctrlB) // The prefix twice sends it
+	app.handleKeyEvent(ctrlB)
+	app.handleKeyEvent(tcell.NewEventKey(tcell.KeyRune, 'i', tcell.ModNone))
+	if !app.inputLocked.Load() {
+		t.Error("Prefix and I should run the input-lock action")
+	}
+	app.toggleInputLock()
+
+	var got []byte
+	buffer := make([]byte, 64)
+	for len(got) < 3 {
+		n, err := port.Read(buffer)
+		if err != nil || n == 0 {
+			break
+		}
+		got = append(got, buffer[:n]...)
+	}
+	if string(got) != "\x1bi\x02" {
+		t.Errorf("Device received %q, want Alt+I and one Ctrl+B", got)
+	}
+}
+
+func TestPasteConfirm(t *testing.T) {
+	tests := []struct {
+		data     string
+		maxChars int
+		controls bool
+		want     bool
+	}{
+		{"ls -l\r", 256, true, false},
+		{"a\tb\r", 0, true, false},
+		{"12345", 4, false, true},
+		{"12345", 0, false, false},
+		{"rm\x1b[A\r", 256, true, true},
+		{"rm\x1b[A\r", 256, false, false},
+	}
+	for _, tt := range tests {
+		if got := pasteNeedsConfirm([]byte(tt.data), tt.maxChars, tt.controls); got != tt.want {
+			t.Errorf("pasteNeedsConfirm(%q, %d, %v) = %v, want %v", tt.data, tt.maxChars, tt.controls, got, tt.want)
+		}
+	}
+
+	lines := strings.Repeat("x\r", 12) + "\x03"
+	preview := pastePreview([]byte(lines))
+	if preview[0] != "25 characters on 13 lines, with control characters ␃" {
+		t.Errorf("Summary = %q", preview[0])
+	}
+	if len(preview) != 2+pastePreviewLines+1 || preview[len(preview)-1] != "  … 3 more lines" {
+		t.Errorf("Preview = %q", preview)
+	}
+}
+
+func TestPasteComposedInput(t *testing.T) {
+	app := &Application{}
+	app.handlePasteEvent(tcell.NewEventPaste(true))
+	high, low := utf16.EncodeRune('😀')
+	for _, r := range []rune{'中', '文', high, low, ' ', 'é'} {
+		app.pasteKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	if got := string(app.paste.data); got != "中文😀 é" {
+		t.Errorf("Paste collected %q", got)
+	}
+}
+
+func TestPasteBracketed(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	term := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	_ = term.Start()
+	_ = term.ProcessOutput([]byte("\x1b[?2004h"))
+
+	app := &Application{serialPort: port, terminal: term, notifications: NewNotificationQueue()}
+	startTestTxQueue(t, app)
+	app.pasteText([]byte("one\ntwo\r\n"))
+
+	buffer := make([]byte, 64)
+	n, err := port.Read(buffer)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got, want := string(buffer[:n]), "\x1b[200~one\rtwo\r\x1b[201~"; got != want {
+		t.Errorf("Device received %q, want %q", got, want)
+	}
+}
+
+func TestPasteChunks(t *testing.T) {
+	chunks := pasteChunks([]string{"ls", "pwd", ""}, "\r\n", false)
+	if len(chunks) != 2 || string(chunks[0]) != "ls\r\n" || string(chunks[1]) != "pwd\r\n" {
+		t.Errorf("Chunks = %q", chunks)
+	}
+
+	chunks = pasteChunks([]string{"a", "b"}, "\n", true)
+	if len(chunks) != 2 || string(chunks[0]) != "\x1b[200~a\n" || string(chunks[1]) != "b\x1b[201~" {
+		t.Errorf("Bracketed chunks = %q", chunks)
+	}
+
+	if chunks := pasteChunks([]string{""}, "\r", true); len(chunks) != 0 {
+		t.Errorf("Empty paste gave %q", chunks)
+	}
+}
+
+func TestAutoLogin(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	app := &Application{serialPort: port, notifications: NewNotificationQueue()}
+	startTestTxQueue(t, app)
+	app.autoLogin.steps = []config.LoginStep{
+		{Expect: "login:", Send: "admin"},
+		{Expect: "Password:", Secret: "router"},
+	}
+	app.autoLogin.lookup = func(name string) (string, error) {
+		if name != "router" {
+			t.Errorf("Looked up secret %q", name)
+		}
+		return "hunter2", nil
+	}
+	app.startAutoLogin()
+	defer app.stopAutoLogin()
+
+	read := func() string {
+		buffer := make([]byte, 64)
+		n, _ := port.Read(buffer)
+		return string(buffer[:n])
+	}
+
+	// Prompts may arrive split across reads and wrapped in escape sequences
+	app.checkAutoLogin([]byte("\x1b[1mrouter log"))
+	app.checkAutoLogin([]byte("in:\x1b[0m "))
+	if got := read(); got != "admin\r" {
+		t.Errorf("Sent %q for the login prompt", got)
+	}
+	if status := app.autoLoginStatus(); status != " LOGIN 2/2 │" {
+		t.Errorf("Status = %q while waiting for the password prompt", status)
+	}
+
+	app.checkAutoLogin([]byte("Password: "))
+	if got := read(); got != "hunter2\r" {
+		t.Errorf("Sent %q for the password prompt", got)
+	}
+	if status := app.autoLoginStatus(); status != "" {
+		t.Errorf("Status = %q after the last step", status)
+	}
+}
+
+func TestGPSDashboardLines(t *testing.T) {
+	lines, _ := gpsDashboardLines(decoder.GPSFix{}, time.Now())
+	if lines[0] != " GPS: Waiting for data" {
+		t.Errorf("Header without data = %q", lines[0])
+	}
+
+	var fix decoder.GPSFix
+	now := time.Now()
+	fix.Apply("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47", now)
+	lines, stale := gpsDashboardLines(fix, now.Add(time.Second))
+	if stale {
+		t.Error("Fresh fix reported as stale")
+	}
+	for _, want := range []string{"Latitude   48.11730° N", "Longitude  11.51667° E", "Altitude   545.4 m", "Satellites 8 used, 0 in view", "Speed      –"} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("Dashboard %q missing %q", lines, want)
+		}
+	}
+
+	if _, stale := gpsDashboardLines(fix, now.Add(time.Minute)); !stale {
+		t.Error("Fix a minute old not reported as stale")
+	}
+}
+
+func TestPlotValues(t *testing.T) {
+	app := &Application{}
+	app.plot.configure(config.PlotSettings{Patterns: []string{`temp=(?P<temp>[-0-9.]+)`, `rpm (?P<rpm>\d+)`}})
+
+	app.checkPlot([]byte("temp=21.5 rpm 1200\r\nboot ok\r\ntemp="))
+	app.checkPlot([]byte("\x1b[1m-3\x1b[0m\r\n"))
+
+	if names := app.plot.recorder.Names(); !slices.Equal(names, []string{"temp", "rpm"}) {
+		t.Fatalf("Series = %q, want temp and rpm", names)
+	}
+	samples := app.plot.recorder.Samples(10)
+	if len(samples) != 2 {
+		t.Fatalf("Recorded %d samples, want 2", len(samples))
+	}
+	if samples[0].Value(0) != 21.5 || samples[0].Value(1) != 1200 || samples[1].Value(0) != -3 {
+		t.Errorf("Samples = %v", samples)
+	}
+	if header := plotHeader(app.plot.recorder.Names(), samples); header != " Plot:  ■ temp -3  ■ rpm 1200" {
+		t.Errorf("Header = %q", header)
+	}
+
+	// Patterns typed from the menu stay until the settings change
+	if err := app.setPlotPattern(`(?P<load>\d+)%`); err != nil {
+		t.Fatalf("setPlotPattern failed: %v", err)
+	}
+	app.plot.configure(config.PlotSettings{Patterns: []string{`temp=(?P<temp>[-0-9.]+)`, `rpm (?P<rpm>\d+)`}})
+	app.checkPlot([]byte("load 40%\r\n"))
+	if names := app.plot.recorder.Names(); !slices.Equal(names, []string{"load"}) {
+		t.Errorf("Series after setPlotPattern = %q, want load", names)
+	}
+	if err := app.setPlotPattern(`\d+`); err == nil {
+		t.Error("Expected an error for a pattern without named groups")
+	}
+}
+
+func TestMarks(t *testing.T) {
+	var m markState
+	start := time.Now()
+	if _, ok := m.mark(start); ok {
+		t.Error("First mark reported an interval")
+	}
+	if status := m.status(); status != " MARK 1 │" {
+		t.Errorf("Status after one mark = %q", status)
+	}
+
+	m.mark(start.Add(12345 * time.Millisecond))
+	if status := m.status(); status != " Δ 12.345s │" {
+		t.Errorf("Status after two marks = %q", status)
+	}
+	delta, _ := m.mark(start.Add(12345*time.Millisecond + 62500*time.Millisecond))
+	if delta != 62500*time.Millisecond {
+		t.Errorf("Interval = %v, want 62.5s", delta)
+	}
+	if status := m.status(); status != " Δ 1m02.500s min 12.345s avg 37.423s (2) │" {
+		t.Errorf("Status after three marks = %q", status)
+	}
+
+	m.reset()
+	if status := m.status(); status != "" {
+		t.Errorf("Status after reset = %q", status)
+	}
+	if got := formatElapsed(850 * time.Millisecond); got != "850ms" {
+		t.Errorf("formatElapsed(850ms) = %q", got)
+	}
+}
+
+func TestBootTimeLines(t *testing.T) {
+	lines := []string{
+		"[    0.000000] Booting Linux on physical CPU 0x0",
+		"[    5.000000] old boot",
+		"U-Boot 2023.04",
+		"[    0.000000] Booting Linux on physical CPU 0x0",
+		"<6>[    0.120000] Memory: 512MB",
+		"[    2.620000] mmc0: new high speed SDHC card",
+		"   [    2.700000] random: crng init done",
+		"[    3.000000] Run /sbin/init as init process",
+	}
+	messages, boots := parseKernelMessages(lines)
+	if boots != 2 || len(messages) != 5 {
+		t.Fatalf("Found %d boots and %d messages, want 2 and 5", boots, len(messages))
+	}
+
+	gaps := slowestGaps(messages, 2)
+	if len(gaps) != 2 || gaps[0].next.text != "mmc0: new high speed SDHC card" || math.Abs(gaps[0].seconds-2.5) > 1e-9 {
+		t.Errorf("Slowest gap = %+v", gaps[0])
+	}
+	if gaps[1].before.text != "random: crng init done" {
+		t.Errorf("Second slowest gap = %+v", gaps[1])
+	}
+
+	report := bootTimeLines(lines)
+	for _, want := range []string{
+		"  2 boots in the scrollback, showing the last one",
+		"  5 messages from [0.000000] to [3.000000]: 3.000 s",
+		"     2.500 s  83.3%  after [    0.120000] Memory: 512MB",
+	} {
+		if !slices.Contains(report, want) {
+			t.Errorf("Report %q missing %q", report, want)
+		}
+	}
+	if report := bootTimeLines([]string{"login:"}); report[0] != "No kernel boot log found" {
+		t.Errorf("Report without a boot log = %q", report)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer port.Close()
+
+	app := &Application{serialPort: port, notifications: NewNotificationQueue()}
+	startTestTxQueue(t, app)
+	app.config.SerialConfig = cfg
+	if err := app.startBridge("127.0.0.1:0"); err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer app.stopBridge()
+
+	conn, err := net.Dial("tcp", app.currentBridge().Addr())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// A client's data goes to the device, and the device's to the client
+	if _, err := conn.Write([]byte("AT\r")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	buffer := make([]byte, 64)
+	n, err := port.Read(buffer)
+	if err != nil || string(buffer[:n]) != "AT\r" {
+		t.Fatalf("Device read %q, %v; want the client's data", buffer[:n], err)
+	}
+	app.bridgeBroadcast([]byte("OK\r\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := io.ReadAtLeast(conn, buffer, 4); err != nil || string(buffer[:n]) != "OK\r\n" {
+		t.Errorf("Client read %q, %v; want the device's data", buffer[:n], err)
+	}
+	if status := app.bridgeStatus(); !strings.HasPrefix(status, " BRIDGE 127.0.0.1:") || !strings.HasSuffix(status, "(1) │") {
+		t.Errorf("Status = %q", status)
+	}
+
+	app.stopBridge()
+	if status := app.bridgeStatus(); status != "" {
+		t.Errorf("Status after stopping = %q", status)
+	}
+	if addr, rfc2217 := parseBridgeAddress("rfc2217:localhost:7000"); addr != "localhost:7000" || !rfc2217 {
+		t.Errorf("parseBridgeAddress = %q, %v", addr, rfc2217)
+	}
+}
+
+// stalledPort is a port whose writes block until released, like a device
+// holding off flow control
+type stalledPort struct {
+	serial.SerialPort
+	release chan struct{}
+}
+
+func (p *stalledPort) Write(data []byte) (int, error) {
+	<-p.release
+	return p.SerialPort.Write(data)
+}
+
+// startTestTxQueue starts the send queue writer of an application built by
+// a test, as Start does, and stops it when the test ends
+func startTestTxQueue(t *testing.T, app *Application) {
+	t.Helper()
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+	app.startTxQueue()
+	t.Cleanup(func() {
+		app.cancel()
+		app.wg.Wait()
+	})
+}
+
+func TestTxQueue(t *testing.T) {
+	sim := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	cfg.Timeout = 200 * time.Millisecond
+	if err := sim.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer sim.Close()
+
+	port := &stalledPort{SerialPort: sim, release: make(chan struct{})}
+	app := &Application{serialPort: port, notifications: NewNotificationQueue()}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+	app.startTxQueue()
+
+	// Writing doesn't wait for a stalled device
+	if n := app.writeToPort([]byte("first")); n != 5 {
+		t.Fatalf("writeToPort = %d, want 5", n)
+	}
+	app.writeToPort([]byte("second"))
+	if n := app.writeToPort(make([]byte, txQueueLimit)); n != 0 {
+		t.Errorf("writeToPort past the limit = %d, want 0", n)
+	}
+	time.Sleep(txStallThreshold + 50*time.Millisecond)
+	if status := app.txStatus(); status != " TX 11 B queued │" {
+		t.Errorf("Status = %q", status)
+	}
+
+	// Cancelling drops what the writer hasn't started
+	if dropped := app.cancelPendingSends(); dropped != 6 {
+		t.Errorf("cancelPendingSends = %d, want 6", dropped)
+	}
+	close(port.release)
+
+	buffer := make([]byte, 64)
+	n, err := sim.Read(buffer)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := string(buffer[:n]); got != "first" {
+		t.Errorf("Device received %q, want only the write in progress", got)
+	}
+	if n := app.writeToPortWait([]byte("third")); n != 5 {
+		t.Errorf("writeToPortWait = %d, want 5", n)
+	}
+	if status := app.txStatus(); status != "" {
+		t.Errorf("Status after draining = %q", status)
+	}
+
+	// The writer is one of the goroutines stopping waits for
+	app.cancel()
+	done := make(chan struct{})
+	go func() {
+		app.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Send queue writer still running after the application stopped")
+	}
+}
+
+func TestTerminalResponseQueueFull(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(20, 5)
+
+	device := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	if err := device.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+	defer device.Close()
+	port := &stalledPort{SerialPort: device, release: make(chan struct{})}
+
+	app := &Application{
+		config:        DefaultAppConfig(),
+		screen:        sim,
+		serialPort:    port,
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 20, 4),
+		notifications: NewNotificationQueue(),
+		updateNotify:  make(chan struct{}, 100),
+		filter:        NewDisplayFilter(),
+		isRunning:     true,
+	}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+	_ = app.terminal.Start()
+	app.terminal.SetResponseCallback(app.sendTerminalResponse)
+	app.startTxQueue()
+	defer func() {
+		close(port.release)
+		app.cancel()
+		app.wg.Wait()
+	}()
+
+	// With the queue full, the answer to a cursor position query is
+	// refused without redrawing under the terminal's lock
+	app.writeToPort(make([]byte, txQueueLimit))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = app.terminal.ProcessOutput([]byte("\x1b[6n"))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Answering a device query with the send queue full deadlocked")
+	}
+}
+
+func TestRxRing(t *testing.T) {
+	ring := newRxRing(8)
+	start := time.Now()
+	fill := func(s string, at time.Duration) {
+		for s != "" {
+			space := ring.free()
+			if len(space) == 0 {
+				t.Fatalf("Ring full with %q left to add", s)
+			}
+			n := copy(space, s)
+			ring.commit(n, start.Add(at))
+			s = s[n:]
+		}
+	}
+
+	// Data wraps around the end of the buffer, and is taken in whole reads
+	// with the time each returned
+	fill("abc", 0)
+	fill("def", time.Millisecond)
+	buffer := make([]byte, 8)
+	n, chunks := ring.read(buffer[:4], nil)
+	if string(buffer[:n]) != "abc" || len(chunks) != 1 || chunks[0].end != 3 || !chunks[0].at.Equal(start) {
+		t.Errorf("read = %q %v, want the first read", buffer[:n], chunks)
+	}
+	fill("ghijk", 2*time.Millisecond)
+	if len(ring.free()) != 0 {
+		t.Errorf("Ring with %d bytes has free space", ring.buffered())
+	}
+	n, chunks = ring.read(buffer, nil)
+	if string(buffer[:n]) != "defghijk" {
+		t.Errorf("read = %q, want defghijk", buffer[:n])
+	}
+	var ends []int
+	for _, chunk := range chunks {
+		ends = append(ends, chunk.end)
+	}
+	if !slices.Equal(ends, []int{3, 5, 8}) {
+		t.Errorf("Chunk ends = %v, want [3 5 8]", ends)
+	}
+	if ring.buffered() != 0 {
+		t.Errorf("buffered = %d after reading everything", ring.buffered())
+	}
+
+	// The reader goroutine delivers what the device sends
+	port := serial.NewPortFor("sim:echo")
+	cfg := serial.DefaultConfig()
+	cfg.Port = "sim:echo"
+	if err := port.Open(cfg); err != nil {
+		t.Fatalf("Failed to open simulator: %v", err)
+	}
+
+	app := &Application{serialPort: port}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+	defer app.wg.Wait()
+	defer port.Close()
+	defer app.cancel()
+
+	ring = newRxRing(rxRingSize)
+	app.wg.Add(1)
+	go app.readSerial(ring)
+	if _, err := port.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	batch := make([]byte, rxBatchSize)
+	var got []byte
+	for deadline := time.Now().Add(5 * time.Second); len(got) < 5 && time.Now().Before(deadline); {
+		n, _ := app.nextBatch(ring, batch, nil, time.Now())
+		got = append(got, batch[:n]...)
+	}
+	if string(got) != "hello" {
+		t.Errorf("Received %q, want hello", got)
+	}
+}
+
+// BenchmarkRxRing measures moving received data through the ring buffer
+// between the reader and the consumer, in reads of a typical size
+func BenchmarkRxRing(b *testing.B) {
+	ring := newRxRing(rxRingSize)
+	chunk := bytes.Repeat([]byte("x"), 512)
+	batch := make([]byte, rxBatchSize)
+	chunks := make([]rxChunk, 0, rxRingStamps)
+	b.SetBytes(int64(len(chunk)))
+	for b.Loop() {
+		space := ring.free()
+		if len(space) < len(chunk) {
+			ring.read(batch, chunks[:0])
+			space = ring.free()
+		}
+		ring.commit(copy(space, chunk), time.Time{})
+	}
+}
+
+func TestFraming(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  FrameGap
+	}{
+		{"3.5c", FrameGap{Chars: 3.5}},
+		{"5ms", FrameGap{Duration: 5 * time.Millisecond}},
+	} {
+		gap, err := ParseFrameGap(tt.value)
+		if err != nil || gap != tt.want || gap.String() != tt.value {
+			t.Errorf("ParseFrameGap(%q) = %v, %v", tt.value, gap, err)
+		}
+	}
+	for _, value := range []string{"", "0ms", "-1c", "fast"} {
+		if _, err := ParseFrameGap(value); err == nil {
+			t.Errorf("ParseFrameGap(%q) succeeded", value)
+		}
+	}
+
+	// At 9600 8N1 a character takes 1.04ms, so 3.5c is 3.6ms
+	app := &Application{}
+	app.config.SerialConfig = serial.DefaultConfig()
+	app.config.SerialConfig.BaudRate = 9600
+	app.setFramingGap(FrameGap{Chars: 3.5})
+
+	start := time.Now()
+	data := []byte("\x01\x03\x00\x00\x00\x01\x84\x0a\x01\x03")
+	chunks := []rxChunk{
+		{end: 4, at: start},
+		{end: 8, at: start.Add(5 * time.Millisecond)},   // Back to back: 4 chars in 4.2ms
+		{end: 10, at: start.Add(12 * time.Millisecond)}, // 4.9ms idle
+	}
+	starts := app.frameStarts(data, chunks)
+	if !slices.Equal(starts, []int{0, 8}) {
+		t.Errorf("frameStarts = %v, want [0 8]", starts)
+	}
+	parts := splitFrames(data, starts)
+	if len(parts) != 2 || len(parts[0]) != 8 || len(parts[1]) != 2 {
+		t.Errorf("splitFrames = %q", parts)
+	}
+
+	// A read continuing the frame from the previous batch isn't split
+	if starts := app.frameStarts(data[:1], []rxChunk{{end: 1, at: start.Add(13 * time.Millisecond)}}); len(starts) != 0 {
+		t.Errorf("frameStarts for a continuation = %v", starts)
+	}
+	if status := app.framingStatus(); status != " FRAME 3.5c │" {
+		t.Errorf("Status = %q", status)
+	}
+}
+
+func TestReadTimestamps(t *testing.T) {
+	mgr := history.NewMemoryHistoryManager(1024)
+	app := &Application{historyMgr: mgr}
+	app.config.ReadTimestamps = true
+
+	// The second frame of a batch keeps the times of its own reads
+	start := time.Now()
+	chunks := []rxChunk{{end: 4, at: start}, {end: 6, at: start.Add(time.Millisecond)}, {end: 9, at: start.Add(2 * time.Millisecond)}}
+	app.recordReceived([]byte("defgh"), chunks, 4)
+	entries, _ := mgr.GetEntries(0, 1)
+	if len(entries) != 1 {
+		t.Fatalf("%d entries recorded", len(entries))
+	}
+	want := []history.ChunkTime{{Offset: 0, Time: chunks[1].at}, {Offset: 2, Time: chunks[2].at}}
+	if !slices.Equal(entries[0].Chunks, want) {
+		t.Errorf("Chunks = %v, want %v", entries[0].Chunks, want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	app := &Application{terminal: terminal.NewTerminalEmulator(nil, nil, 20, 5)}
+	_ = app.terminal.Start()
+
+	path := filepath.Join(t.TempDir(), "trace.log")
+	if err := app.startTrace(path); err != nil {
+		t.Fatalf("startTrace failed: %v", err)
+	}
+	if app.traceStatus() == "" {
+		t.Error("No status segment while tracing")
+	}
+	_ = app.terminal.ProcessOutput([]byte("ok\x1b[2J\x1b[?1004h\r\n"))
+
+	// Flushed by the housekeeping tick so the file can be followed
+	app.flushTrace()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+	for _, want := range []string{"CSI 2J  ED -> clear-screen(2)\n", "CSI ?1004h  DECSET -> ignored\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Trace lacks %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "ok") {
+		t.Errorf("Text traced:\n%s", data)
+	}
+
+	if stopped, err := app.stopTrace(); stopped != path || err != nil {
+		t.Errorf("stopTrace = %q, %v", stopped, err)
+	}
+	_ = app.terminal.ProcessOutput([]byte("\x1b[H"))
+	if after, _ := os.ReadFile(path); len(after) != len(data) || app.traceStatus() != "" {
+		t.Errorf("Traced after stopping:\n%s", after)
+	}
+}
+
+func TestIgnoredSequenceLines(t *testing.T) {
+	if lines := ignoredSequenceLines(nil, 0); len(lines) != 1 || !strings.Contains(lines[0], "No escape sequences") {
+		t.Errorf("Nothing ignored: %q", lines)
+	}
+
+	app := &Application{terminal: terminal.NewTerminalEmulator(nil, nil, 20, 5)}
+	_ = app.terminal.Start()
+	if got := app.ignoredSequenceSummary(); got != "none" {
+		t.Errorf("Summary = %q before anything was ignored", got)
+	}
+	_ = app.terminal.ProcessOutput([]byte("\x1b[?1004h\x1b[?1004h\x1b[9y\x1b[H"))
+	if got, want := app.ignoredSequenceSummary(), "2× CSI ?1004h ignored (+1 more)"; got != want {
+		t.Errorf("Summary = %q, want %q", got, want)
+	}
+
+	lines := ignoredSequenceLines(app.terminal.IgnoredSequences())
+	if !strings.HasPrefix(lines[0], "3 escape sequences ignored this session, 2 different") || lines[len(lines)-1] != "  1× CSI 9y ignored" {
+		t.Errorf("Lines = %q", lines)
+	}
+}
+
+func TestHistoryStream(t *testing.T) {
+	mgr := history.NewMemoryHistoryManager(1024 * 1024)
+	app := &Application{historyMgr: mgr}
+	app.setupHistoryWatch()
+	_ = mgr.Write([]byte("before\r\n"), history.DirectionOutput)
+
+	// Started from the menu, the history so far goes first
+	path := filepath.Join(t.TempDir(), "stream.jsonl")
+	if err := app.startHistoryStream(path, history.FormatJSON, true); err != nil {
+		t.Fatalf("startHistoryStream failed: %v", err)
+	}
+	if app.historyStreamStatus() == "" {
+		t.Error("No status segment while streaming")
+	}
+	_ = mgr.Write([]byte("ls\r"), history.DirectionInput)
+	_ = mgr.Write([]byte("after\r\n"), history.DirectionOutput)
+
+	// Readable while still open
+	entries, err := history.LoadEntries(path)
+	if err != nil || len(entries) != 3 || string(entries[2].Data) != "after\r\n" {
+		t.Fatalf("Streamed %d entries (%v), want 3", len(entries), err)
+	}
+
+	if stopped, err := app.stopHistoryStream(); stopped != path || err != nil {
+		t.Errorf("stopHistoryStream = %q, %v", stopped, err)
+	}
+	_ = mgr.Write([]byte("not streamed"), history.DirectionOutput)
+	if entries, _ = history.LoadEntries(path); len(entries) != 3 {
+		t.Errorf("%d entries after stopping, want 3", len(entries))
+	}
+}
+
+func TestHistoryFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flush.jsonl.gz")
+	app := &Application{config: AppConfig{HistoryFlushFile: path, HistoryFormat: history.FormatJSON}}
+	for _, data := range []string{"first\r\n", "second\r\n", "third\r\n"} {
+		entry := history.HistoryEntry{Timestamp: time.Now(), Data: []byte(data), Direction: history.DirectionOutput}
+		if err := app.flushEvicted([]history.HistoryEntry{entry}); err != nil {
+			t.Fatalf("flushEvicted failed: %v", err)
+		}
+	}
+	if err := app.closeHistoryFlush(); err != nil {
+		t.Fatalf("closeHistoryFlush failed: %v", err)
+	}
+
+	entries, err := history.LoadEntries(path)
+	if err != nil || len(entries) != 3 || string(entries[2].Data) != "third\r\n" {
+		t.Fatalf("Flushed %d entries (%v), want 3", len(entries), err)
+	}
+
+	// The evictions share one gzip stream rather than one each
+	data, _ := os.ReadFile(path)
+	r := bytes.NewReader(data)
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatalf("Not gzipped: %v", err)
+	}
+	zr.Multistream(false)
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		t.Fatalf("Failed to read first stream: %v", err)
+	}
+	if err := zr.Reset(r); err != io.EOF {
+		t.Errorf("Another gzip stream follows the first (%v)", err)
+	}
+}
+
+func TestTxrxRows(t *testing.T) {
+	at := time.Date(2024, 1, 2, 10, 20, 30, 400_000_000, time.Local)
+	entries := []history.HistoryEntry{
+		{Timestamp: at, Data: []byte("AT+"), Direction: history.DirectionInput},
+		{Timestamp: at.Add(time.Millisecond), Data: []byte("GMR\r\n"), Direction: history.DirectionInput},
+		{Timestamp: at.Add(2 * time.Millisecond), Data: []byte("v1.2\r\nOK\x07"), Direction: history.DirectionOutput},
+		{Timestamp: at.Add(3 * time.Millisecond), Data: []byte("0123456789"), Direction: history.DirectionInput},
+	}
+
+	rows := txrxRows(entries, 30, true)
+	want := []txrxRow{
+		{"10:20:30.400 TX AT+GMR", history.DirectionInput},
+		{"10:20:30.402 RX v1.2", history.DirectionOutput},
+		{"10:20:30.402 RX OK␇", history.DirectionOutput},
+		{"10:20:30.403 TX 0123456789", history.DirectionInput},
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("Interleaved rows = %v, want %v", rows, want)
+	}
+
+	// Long rows wrap under the data, without the time again
+	rows = txrxRows(entries[3:], 20, false)
+	want = []txrxRow{
+		{"10:20:30.403 0123456", history.DirectionInput},
+		{"             789", history.DirectionInput},
+	}
+	if !slices.Equal(rows, want) {
+		t.Errorf("Wrapped rows = %v, want %v", rows, want)
+	}
+}
+
+func TestEchoSuppressor(t *testing.T) {
+	var e echoSuppressor
+	now := time.Now()
+
+	// Off, nothing is dropped
+	e.expect([]byte("ls"), now)
+	if got := e.strip([]byte("ls"), now); string(got) != "ls" {
+		t.Errorf("Dropped %q while off", got)
+	}
+
+	e.setEnabled(true)
+	e.expect([]byte("ls\r"), now)
+	e.expect([]byte("pwd\r"), now)
+	data := []byte("l")
+	if got := e.strip(data, now); len(got) != 0 {
+		t.Errorf("strip(l) = %q, want the echo dropped", got)
+	}
+	// CR echoed as CR LF doesn't end the echo of what was typed after it
+	if got := e.strip([]byte("s\r\npw"), now); string(got) != "\n" {
+		t.Errorf("strip = %q, want \"\\n\"", got)
+	}
+	data = []byte("d\r\nfile\r\n")
+	if got := e.strip(data, now); string(got) != "\nfile\r\n" {
+		t.Errorf("strip = %q, want the output after the echo", got)
+	}
+	if string(data) != "d\r\nfile\r\n" {
+		t.Errorf("Received data modified: %q", data)
+	}
+
+	// Output that isn't echo ends it
+	e.expect([]byte("AT\r"), now)
+	if got := e.strip([]byte("OK\r\n"), now); string(got) != "OK\r\n" {
+		t.Errorf("strip = %q, want it all shown", got)
+	}
+	if got := e.strip([]byte("AT"), now); string(got) != "AT" {
+		t.Errorf("strip = %q after the echo ended, want it shown", got)
+	}
+
+	// Echo arriving too late is output
+	e.expect([]byte("x"), now)
+	if got := e.strip([]byte("x"), now.Add(2*echoTimeout)); string(got) != "x" {
+		t.Errorf("strip = %q after the timeout, want it shown", got)
+	}
+}
+
+func TestStatusFormat(t *testing.T) {
+	cfg := DefaultAppConfig()
+	cfg.SerialConfig.Port = "/dev/ttyUSB0"
+	cfg.SerialConfig.BaudRate = 9600
+	cfg.ProfileName = "bench"
+	cfg.StatusFormat = config.StatusFormat{Left: " {profile} {port}@{baud} "}
+	app := &Application{
+		config:    cfg,
+		session:   &Session{BytesSent: 12, BytesRecv: 3400},
+		statusBar: config.StatusBarSettings{Format: config.StatusFormat{Left: " {port} ", Right: " {tx}/{rx} {flow} {log} {time} {host} "}},
+	}
+
+	// The profile's parts are used over the settings file's
+	format := app.statusFormat()
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	values := app.statusValues(at)
+	if got := expandStatus(format.Left, values); got != " bench /dev/ttyUSB0@9600 " {
+		t.Errorf("Left = %q", got)
+	}
+	if got := expandStatus(format.Right, values); got != " 12/3400 none off 07:08:09 {host} " {
+		t.Errorf("Right = %q", got)
+	}
+
+	// {time} asks for a redraw once a second
+	if !app.statusClockTicked(at) || app.statusClockTicked(at.Add(time.Millisecond)) || !app.statusClockTicked(at.Add(time.Second)) {
+		t.Error("statusClockTicked doesn't follow the second")
+	}
+}
+
+func TestTitleBar(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(60, 10)
+
+	cfg := DefaultAppConfig()
+	cfg.ProfileName = "router"
+	app := &Application{
+		config:        cfg,
+		screen:        newTitleScreen(sim),
+		terminal:      terminal.NewTerminalEmulator(nil, nil, 60, 9),
+		notifications: NewNotificationQueue(),
+		session:       &Session{StartTime: time.Now().Add(-75 * time.Second)},
+		titleBar:      config.TitleBarSettings{Clock: true},
+	}
+
+	// Everything else is drawn a row down, on a screen a row shorter
+	app.setTitleBarVisible(true)
+	if _, height := app.screen.Size(); height != 9 {
+		t.Errorf("Height below the title bar = %d, want 9", height)
+	}
+	if rows := app.terminal.GetScreen().Height; rows != 8 {
+		t.Errorf("Terminal resized to %d rows, want 8", rows)
+	}
+	app.screen.SetContent(0, 0, 'x', nil, tcell.StyleDefault)
+	if ch, _, _, _ := sim.GetContent(0, 1); ch != 'x' {
+		t.Errorf("Row 0 drawn at %q on screen row 1", ch)
+	}
+
+	app.notifyWarning("Connection closed")
+	left, alerts, clock := app.titleBarText(time.Now())
+	if !strings.HasPrefix(left, " router │ Disconnected │ 1:1") || !strings.Contains(alerts, "Connection closed") || clock == "" {
+		t.Errorf("Title bar = %q %q %q", left, alerts, clock)
+	}
+
+	// Alerts stay until the notification history is looked at
+	app.alertsSeen.Store(time.Now().UnixNano())
+	if _, alerts, _ := app.titleBarText(time.Now()); alerts != "" {
+		t.Errorf("Alerts %q after the history was opened", alerts)
+	}
+
+	app.setTitleBarVisible(false)
+	if _, height := app.screen.Size(); height != 10 {
+		t.Errorf("Height without the title bar = %d, want 10", height)
+	}
+}
+
+func TestWindowSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"none", ""},
+		{"xterm", "\x1b[8;24;80t"},
+		{"STTY", "stty rows 24 cols 80\r"},
+		{`resize {cols} {rows}\r`, "resize 80 24\r"},
+		{`\e]777;size={rows}x{cols}\a`, "\x1b]777;size=24x80\a"},
+		{`echo "{cols}"\n`, "echo \"80\"\n"},
+	}
+	for _, tt := range tests {
+		strategy, err := ParseWindowSize(tt.value)
+		if err != nil {
+			t.Errorf("ParseWindowSize(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got := string(strategy.Report(24, 80)); got != tt.want {
+			t.Errorf("ParseWindowSize(%q).Report(24, 80) = %q, want %q", tt.value, got, tt.want)
+		}
+		if strategy.IsZero() != (tt.want == "") {
+			t.Errorf("ParseWindowSize(%q).IsZero() = %v", tt.value, strategy.IsZero())
+		}
+	}
+
+	for _, value := range []string{"vt100", `size \q {rows}`} {
+		if _, err := ParseWindowSize(value); err == nil {
+			t.Errorf("ParseWindowSize(%q) accepted", value)
+		}
+	}
+}
+
+func TestAtShellPrompt(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"root@router:~# ", true},
+		{"user@host:~$ ", true},
+		{"host% ", true},
+		{"$ ", true},
+		{"user@host:~$ ls", false},            // Half-typed command
+		{"Password: ", false},                 // Not a shell
+		{"> ", false},                         // Continuation prompt
+		{"=> ", false},                        // U-Boot
+		{"Progress 100%", false},              // Output
+		{"user@host:~$ \r\n", false},          // Prompt scrolled away
+		{"\x1b[?1049hroot@router:~# ", false}, // Full-screen program
+		{"root@router:~# cat\x1b[16G", false}, // Text after the cursor
+		{"boot log\r\nroot@router:~# ", true}, // Prompt after other output
+	}
+	for _, tt := range tests {
+		term := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+		_ = term.Start()
+		if err := term.ProcessOutput([]byte(tt.output)); err != nil {
+			t.Fatalf("ProcessOutput(%q): %v", tt.output, err)
+		}
+		app := &Application{config: DefaultAppConfig(), terminal: term}
+		if got := app.atShellPrompt(); got != tt.want {
+			t.Errorf("atShellPrompt() after %q = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+
+	app := &Application{config: DefaultAppConfig()}
+	if got := string(app.sttyCommand(24, 80)); got != "stty rows 24 cols 80\r" {
+		t.Errorf("Default stty command = %q", got)
+	}
+	command, err := ParseSttyCommand(`export LINES={rows} COLUMNS={cols}\n`)
+	if err != nil {
+		t.Fatalf("ParseSttyCommand: %v", err)
+	}
+	app.config.SttyCommand = command
+	if got := string(app.sttyCommand(24, 80)); got != "export LINES=24 COLUMNS=80\n" {
+		t.Errorf("Templated stty command = %q", got)
+	}
+	if _, err := ParseSttyCommand("stty sane"); err == nil {
+		t.Error("ParseSttyCommand accepted a command without the size")
+	}
+}
+
+func TestPrinterCapture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "print.txt")
+	term := terminal.NewTerminalEmulator(nil, nil, 40, 5)
+	_ = term.Start()
+	app := &Application{config: DefaultAppConfig(), terminal: term, notifications: NewNotificationQueue()}
+	app.printer.path = path
+	term.SetPrinterCallback(app.printData)
+
+	_ = term.ProcessOutput([]byte("menu\x1b[5iREPORT\r\n"))
+	if status := app.printerStatus(); status != " PRINTER 8 B │" {
+		t.Errorf("Status while printing = %q", status)
+	}
+	_ = term.ProcessOutput([]byte("PAGE 2\x1b[4i"))
+	if status := app.printerStatus(); status != "" {
+		t.Errorf("Status after printing = %q", status)
+	}
+	if err := app.closePrinter(); err != nil {
+		t.Fatalf("closePrinter: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading capture: %v", err)
+	}
+	if string(data) != "REPORT\r\nPAGE 2" {
+		t.Errorf("Captured %q", data)
+	}
+
+	// Without a chosen file, printing writes nothing unless capture is on,
+	// and then to the log directory
+	dir := t.TempDir()
+	app.logging.Directory = dir
+	if err := app.setPrinterCapture(""); err != nil {
+		t.Fatalf("setPrinterCapture: %v", err)
+	}
+	_ = term.ProcessOutput([]byte("\x1b[5iDROPPED\x1b[4i"))
+	if files, _ := os.ReadDir(dir); len(files) != 0 || app.printerCapture() != "" {
+		t.Errorf("Printing with capture off created %v", files)
+	}
+
+	// The file stops growing at the limit
+	app.printer.configure(config.PrinterSettings{Capture: true, MaxBytes: 10})
+	_ = term.ProcessOutput([]byte("\x1b[5i0123456789ABC\x1b[4i"))
+	capture := app.printerCapture()
+	if filepath.Dir(capture) != dir || !strings.HasPrefix(filepath.Base(capture), "print_") {
+		t.Errorf("Captured to %q, want a print log in %s", capture, dir)
+	}
+	_ = term.ProcessOutput([]byte("\x1b[5iMORE\x1b[4i"))
+	if err := app.closePrinter(); err != nil {
+		t.Fatalf("closePrinter: %v", err)
+	}
+	if data, _ := os.ReadFile(capture); string(data) != "0123456789" {
+		t.Errorf("Captured %q, want the first 10 bytes", data)
+	}
+}
+
+func TestRenderDoubleCell(t *testing.T) {
+	sim := tcell.NewSimulationScreen("UTF-8")
+	if err := sim.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	defer sim.Fini()
+	sim.SetSize(6, 2)
+	app := &Application{screen: sim}
+
+	for x, r := range "Hi!?" {
+		app.renderCell(x, 0, terminal.Cell{Char: r, LineSize: terminal.LineDoubleWidth})
+	}
+	var row []rune
+	for x := 0; x < 6; x++ {
+		r, _, _, _ := sim.GetContent(x, 0)
+		row = append(row, r)
+	}
+	// The fourth character has no room; wide characters read back with a
+	// blank second column
+	if got := string(row); got != "Ｈ ｉ ！ " {
+		t.Errorf("Double-width row = %q", got)
+	}
+	if doubleWidthRune('A') != 'Ａ' || doubleWidthRune(' ') != ' ' || doubleWidthRune('é') != 'é' {
+		t.Error("doubleWidthRune maps the wrong characters")
+	}
+}
+
+func TestCellStyleUnderline(t *testing.T) {
+	attrs := terminal.DefaultTextAttributes()
+	attrs.Underline = true
+	attrs.UnderlineStyle = terminal.UnderlineCurly
+	attrs.UnderlineColor = terminal.RGBColor(255, 0, 0)
+
+	want := tcell.StyleDefault.Foreground(tcell.ColorReset).Background(tcell.ColorReset).
+		Underline(tcell.UnderlineStyleCurly).Underline(tcell.NewRGBColor(255, 0, 0))
+	if got := cellStyle(terminal.Cell{Char: 'x', Attributes: attrs}); got != want {
+		t.Errorf("Curly red underline style = %+v, want %+v", got, want)
+	}
+
+	attrs.UnderlineColor = terminal.Color(196)
+	attrs.UnderlineStyle = terminal.UnderlineSingle
+	want = tcell.StyleDefault.Foreground(tcell.ColorReset).Background(tcell.ColorReset).
+		Underline(tcell.UnderlineStyleSolid).Underline(tcell.PaletteColor(196))
+	if got := cellStyle(terminal.Cell{Char: 'x', Attributes: attrs}); got != want {
+		t.Errorf("Palette underline style = %+v, want %+v", got, want)
+	}
+}
+
+func TestHyperlinks(t *testing.T) {
+	term := terminal.NewTerminalEmulator(nil, nil, 60, 3)
+	_ = term.Start()
+	_ = term.ProcessOutput([]byte("\x1b]8;;https://a.example/\x07中文 docs\x1b]8;;\x07 see https://b.example/ \x1b]8;;https://b.example/\x07https://b.example/\x1b]8;;\x07"))
+	lines := term.GetScreen().Buffer
+
+	links := findLinks(lines, false)
+	var texts []string
+	for _, l := range links {
+		texts = append(texts, fmt.Sprintf("%s@%d-%d", l.Text, l.StartCol, l.EndCol))
+	}
+	// Text matched inside an OSC 8 link isn't a second link
+	want := []string{"https://a.example/@0-9", "https://b.example/@33-51", "https://b.example/@14-32"}
+	if strings.Join(texts, " ") != strings.Join(want, " ") {
+		t.Errorf("Links = %q, want %q", texts, want)
+	}
+
+	style := cellStyle(lines[0][0])
+	if _, _, attrs := style.Decompose(); attrs&tcell.AttrUnderline == 0 {
+		t.Error("Hyperlink cell isn't underlined")
+	}
+
+	app := &Application{terminal: term}
+	if link, ok := app.hyperlinkAt(1, 0); !ok || link.URI != "https://a.example/" {
+		t.Errorf("Link on a wide character continuation = %+v, %v", link, ok)
+	}
+	if _, ok := app.hyperlinkAt(10, 0); ok {
+		t.Error("Plain text has a link")
+	}
+}
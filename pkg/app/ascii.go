@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"sterm/pkg/menu"
+)
+
+// controlChar describes an ASCII control character
+type controlChar struct {
+	name        string
+	escape      string // C escape, if there is one
+	description string
+}
+
+// controlChars describes 0x00-0x1F
+var controlChars = [32]controlChar{
+	{"NUL", `\0`, "Null"},
+	{"SOH", "", "Start of heading"},
+	{"STX", "", "Start of text"},
+	{"ETX", "", "End of text (interrupt)"},
+	{"EOT", "", "End of transmission (EOF)"},
+	{"ENQ", "", "Enquiry"},
+	{"ACK", "", "Acknowledge"},
+	{"BEL", `\a`, "Bell"},
+	{"BS", `\b`, "Backspace"},
+	{"HT", `\t`, "Horizontal tab"},
+	{"LF", `\n`, "Line feed"},
+	{"VT", `\v`, "Vertical tab"},
+	{"FF", `\f`, "Form feed"},
+	{"CR", `\r`, "Carriage return"},
+	{"SO", "", "Shift out"},
+	{"SI", "", "Shift in"},
+	{"DLE", "", "Data link escape"},
+	{"DC1", "", "Device control 1 (XON)"},
+	{"DC2", "", "Device control 2"},
+	{"DC3", "", "Device control 3 (XOFF)"},
+	{"DC4", "", "Device control 4"},
+	{"NAK", "", "Negative acknowledge"},
+	{"SYN", "", "Synchronous idle"},
+	{"ETB", "", "End of transmission block"},
+	{"CAN", "", "Cancel"},
+	{"EM", "", "End of medium"},
+	{"SUB", "", "Substitute (suspend)"},
+	{"ESC", `\e`, "Escape"},
+	{"FS", "", "File separator (quit)"},
+	{"GS", "", "Group separator"},
+	{"RS", "", "Record separator"},
+	{"US", "", "Unit separator"},
+}
+
+// controlKeys lists the keys besides Ctrl+<caret letter> that send a
+// control character
+var controlKeys = map[byte]string{
+	0x00: "Ctrl+Space",
+	0x09: "Tab",
+	0x0D: "Enter",
+	0x1B: "Esc",
+	0x7F: "Backspace",
+}
+
+// ctrlKeyByte returns the control character a key label such as "Ctrl+Q"
+// sends, for finding keys that sterm keeps for itself
+func ctrlKeyByte(keys string) (byte, bool) {
+	letter, found := strings.CutPrefix(keys, "Ctrl+")
+	if !found || len(letter) != 1 {
+		return 0, false
+	}
+	c := letter[0]
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 1, true
+	case c >= '@' && c <= '_':
+		return c - '@', true
+	}
+	return 0, false
+}
+
+// reservedCtrlKeys maps the control characters whose Ctrl keys run sterm
+// actions instead of being sent to what those actions are
+func (app *Application) reservedCtrlKeys() map[byte]string {
+	reserved := make(map[byte]string)
+	for _, k := range generalHelpKeys {
+		if b, ok := ctrlKeyByte(k.keys); ok {
+			reserved[b] = k.description
+		}
+	}
+	if app.shortcuts != nil && app.config.EnableShortcuts && app.shortcuts.IsEnabled() {
+		for _, entry := range app.shortcuts.HelpEntries() {
+			if b, ok := ctrlKeyByte(entry.Keys); ok && entry.Bound {
+				reserved[b] = entry.Description
+			}
+		}
+	}
+	return reserved
+}
+
+// asciiTableLines builds the ASCII reference: control characters with the
+// keys that send them in this session, then the printable characters
+func (app *Application) asciiTableLines() []string {
+	reserved := app.reservedCtrlKeys()
+	lines := []string{"Control Characters", "  Hex Dec  Name Caret Esc  Key             Description"}
+
+	control := func(b byte, c controlChar) {
+		caret := "^" + string(rune(b^0x40))
+		keys := []string{}
+		if b < 0x7F {
+			keys = append(keys, "Ctrl+"+string(rune(b^0x40)))
+		}
+		if key, ok := controlKeys[b]; ok {
+			keys = append(keys, key)
+		}
+		description := c.description
+		if action, ok := reserved[b]; ok {
+			keys[0] += "*"
+			description += " [Ctrl key: " + action + "]"
+		}
+		lines = append(lines, fmt.Sprintf("  %02X  %3d  %-4s %-5s %-4s %-15s %s",
+			b, b, c.name, caret, c.escape, strings.Join(keys, ", "), description))
+	}
+	for b, c := range controlChars {
+		control(byte(b), c)
+	}
+	control(0x7F, controlChar{"DEL", "", "Delete"})
+
+	lines = append(lines, "",
+		"  Alt+key sends Esc followed by the key. Keys marked * run a sterm action",
+		fmt.Sprintf("  instead; send those bytes with Send Hex (%s).", app.keyLabel("send-hex")),
+		"", "Printable Characters")
+
+	// Six columns, read down, like ascii(7)
+	const columns = 6
+	rows := (0x7F - 0x20 + columns - 1) / columns
+	for row := 0; row < rows; row++ {
+		var sb strings.Builder
+		sb.WriteString(" ")
+		for col := 0; col < columns; col++ {
+			b := 0x20 + col*rows + row
+			if b >= 0x7F {
+				break
+			}
+			char := string(rune(b))
+			if b == ' ' {
+				char = "SP"
+			}
+			fmt.Fprintf(&sb, " %02X %3d %-3s ", b, b, char)
+		}
+		lines = append(lines, strings.TrimRight(sb.String(), " "))
+	}
+	return lines
+}
+
+// showASCIITable opens the ASCII reference overlay
+func (app *Application) showASCIITable() {
+	app.openDialog(menu.NewTextDialog(app.screen, "ASCII Table", app.asciiTableLines()))
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/config"
+	"sterm/pkg/plugin"
+	"sterm/pkg/secrets"
+)
+
+// Auto-login limits
+const (
+	defaultLoginTimeout = 30 * time.Second
+	maxLoginSeen        = 4096 // Received text kept for matching the next step
+)
+
+// autoLoginState runs a profile's expect/send steps after connecting
+type autoLoginState struct {
+	steps      []config.LoginStep
+	lookup     func(name string) (string, error) // Reads secrets; secrets.Lookup when nil
+	next       int                               // Step waiting for its text
+	seen       string                            // Text received since the last step, without escapes
+	running    bool
+	generation int // Tells a stale timeout from the current one
+	timer      *time.Timer
+	mu         sync.Mutex
+}
+
+// startAutoLogin starts the profile's auto-login from the first step. It
+// may be called with the application lock held, so the first steps are
+// checked on their own goroutine.
+func (app *Application) startAutoLogin() {
+	a := &app.autoLogin
+	a.mu.Lock()
+	if len(a.steps) == 0 || app.config.Monitor {
+		a.mu.Unlock()
+		return
+	}
+	a.next = 0
+	a.seen = ""
+	a.running = true
+	app.armLoginTimeout()
+	a.mu.Unlock()
+
+	app.logDebug("Auto-login started with %d steps", len(a.steps))
+	go app.checkAutoLogin(nil)
+}
+
+// armLoginTimeout starts the timeout of the step being waited for. Called
+// with the auto-login lock held.
+func (app *Application) armLoginTimeout() {
+	a := &app.autoLogin
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.generation++
+	if !a.running {
+		return
+	}
+
+	timeout := defaultLoginTimeout
+	if seconds := a.steps[a.next].TimeoutSeconds; seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	generation := a.generation
+	a.timer = time.AfterFunc(timeout, func() { app.autoLoginTimedOut(generation) })
+}
+
+// autoLoginTimedOut gives up on auto-login if the step being waited for
+// hasn't matched yet
+func (app *Application) autoLoginTimedOut(generation int) {
+	a := &app.autoLogin
+	a.mu.Lock()
+	if !a.running || a.generation != generation {
+		a.mu.Unlock()
+		return
+	}
+	a.running = false
+	expect := a.steps[a.next].Expect
+	a.mu.Unlock()
+
+	app.notifyWarning("Auto-login gave up waiting for %q", expect)
+	app.forceRedraw()
+}
+
+// stopAutoLogin cancels a running auto-login
+func (app *Application) stopAutoLogin() {
+	a := &app.autoLogin
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = false
+	app.armLoginTimeout()
+}
+
+// checkAutoLogin adds received text to what the waiting step looks for and
+// sends the steps whose text has appeared
+func (app *Application) checkAutoLogin(text []byte) {
+	a := &app.autoLogin
+	a.mu.Lock()
+	if !a.running {
+		a.mu.Unlock()
+		return
+	}
+
+	a.seen += plugin.StripEscapes(string(text))
+	if len(a.seen) > maxLoginSeen {
+		a.seen = a.seen[len(a.seen)-maxLoginSeen:]
+	}
+
+	var ready []config.LoginStep
+	for a.next < len(a.steps) {
+		step := a.steps[a.next]
+		if step.Expect != "" {
+			i := strings.Index(a.seen, step.Expect)
+			if i < 0 {
+				break
+			}
+			// The next step waits for text after this match
+			a.seen = a.seen[i+len(step.Expect):]
+		}
+		ready = append(ready, step)
+		a.next++
+	}
+	if len(ready) > 0 {
+		a.running = a.next < len(a.steps)
+		app.armLoginTimeout()
+	}
+	done := !a.running
+	lookup := a.lookup
+	a.mu.Unlock()
+
+	for _, step := range ready {
+		if err := app.sendLoginStep(step, lookup); err != nil {
+			app.stopAutoLogin()
+			app.notifyError("Auto-login stopped: %v", err)
+			return
+		}
+	}
+	if len(ready) > 0 && done {
+		app.updateStatusMessage("Auto-login complete")
+	}
+}
+
+// sendLoginStep sends a step's text or secret followed by Enter. Secrets
+// are kept out of the history file.
+func (app *Application) sendLoginStep(step config.LoginStep, lookup func(string) (string, error)) error {
+	if step.Secret == "" {
+		app.logDebug("Auto-login: sending %q after %q", step.Send, step.Expect)
+		app.writeToPort(app.encodeOutput([]byte(step.Send + "\r")))
+		return nil
+	}
+
+	if lookup == nil {
+		lookup = secrets.Lookup
+	}
+	value, err := lookup(step.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to read secret %q: %w", step.Secret, err)
+	}
+	app.logDebug("Auto-login: sending secret %q after %q", step.Secret, step.Expect)
+
+	if app.serialPort == nil || !app.serialPort.IsOpen() {
+		return fmt.Errorf("serial port is not open")
+	}
+	if !app.queueWrite(app.encodeOutput([]byte(value+"\r")), false, nil) {
+		return fmt.Errorf("send queue is full")
+	}
+	return nil
+}
+
+// autoLoginStatus returns the status bar segment shown while auto-login is
+// waiting for a prompt
+func (app *Application) autoLoginStatus() string {
+	a := &app.autoLogin
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.running {
+		return ""
+	}
+	return fmt.Sprintf(" LOGIN %d/%d │", a.next+1, len(a.steps))
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"sterm/pkg/config"
+	"sterm/pkg/history"
+	"sterm/pkg/menu"
+)
+
+// autosaveCheckInterval is how often the autosave loop looks for a due checkpoint
+const autosaveCheckInterval = time.Second
+
+// defaultAutosaveInterval is used when the settings don't give an interval
+const defaultAutosaveInterval = 10 * time.Second
+
+// autosaveState checkpoints received data to a spool file for crash recovery
+type autosaveState struct {
+	settings config.AutosaveSettings
+	path     string             // Spool file for this profile; empty until the session starts
+	lock     *history.SpoolLock // Held for the session so no other one takes the spool
+	spool    *history.Spool     // Nil while autosave is off
+	maxSize  int64
+	mu       sync.Mutex
+}
+
+// configure applies new autosave settings, opening or removing the spool if
+// autosave was turned on or off while running
+func (a *autosaveState) configure(settings config.AutosaveSettings) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.settings = settings
+	return a.sync()
+}
+
+// sync opens or closes the spool to match the settings. Called with mu held.
+func (a *autosaveState) sync() error {
+	switch {
+	case a.settings.Enabled && a.spool == nil && a.path != "":
+		spool, err := history.OpenSpool(a.path, a.maxSize)
+		if err != nil {
+			return err
+		}
+		a.spool = spool
+	case !a.settings.Enabled && a.spool != nil:
+		err := a.spool.Close()
+		a.spool = nil
+		return err
+	}
+	return nil
+}
+
+// write buffers received data, checkpointing once enough is buffered
+func (a *autosaveState) write(data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.spool == nil {
+		return nil
+	}
+	a.spool.Write(data)
+	if a.settings.Bytes > 0 && a.spool.Pending() >= a.settings.Bytes {
+		return a.spool.Checkpoint()
+	}
+	return nil
+}
+
+// checkpoint writes buffered data if the interval has passed
+func (a *autosaveState) checkpoint(now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.spool == nil {
+		return nil
+	}
+	interval := time.Duration(a.settings.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultAutosaveInterval
+	}
+	if a.spool.SinceCheckpoint(now) < interval {
+		return nil
+	}
+	return a.spool.Checkpoint()
+}
+
+// checkpointNow writes buffered data right away and returns the spool path,
+// or "" if autosave is off. Used when crashing, so the lock is only tried:
+// the panicking goroutine may hold it.
+func (a *autosaveState) checkpointNow() (string, error) {
+	if !a.mu.TryLock() {
+		return "", fmt.Errorf("autosave is busy")
+	}
+	defer a.mu.Unlock()
+
+	if a.spool == nil {
+		return "", nil
+	}
+	return a.spool.Path(), a.spool.Checkpoint()
+}
+
+// close removes the spool on a clean exit and releases its lock
+func (a *autosaveState) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	if a.spool != nil {
+		err = a.spool.Close()
+		a.spool = nil
+	}
+	if releaseErr := a.lock.Release(); err == nil {
+		err = releaseErr
+	}
+	a.lock = nil
+	return err
+}
+
+// startAutosave sets up the spool for this session and offers to restore
+// data left behind by a session that crashed
+func (app *Application) startAutosave() {
+	profile := app.config.ProfileName
+	if profile == "" {
+		profile = app.config.SerialConfig.Port
+	}
+	path, err := history.SpoolPath("", profile)
+	if err != nil {
+		app.logDebug("Autosave disabled: %v", err)
+		return
+	}
+
+	// Another session of the same profile has the spool; its data isn't
+	// left over from a crash
+	lock, err := history.LockSpool(path)
+	if err != nil {
+		app.notifyWarning("Autosave disabled: %v", err)
+		return
+	}
+
+	recovered, err := history.RecoverSpool(path)
+	if err != nil {
+		app.logDebug("Failed to recover spool: %v", err)
+	}
+
+	app.autosave.mu.Lock()
+	app.autosave.lock = lock
+	app.autosave.path = path
+	app.autosave.maxSize = int64(app.config.HistorySize)
+	err = app.autosave.sync()
+	app.autosave.mu.Unlock()
+	if err != nil {
+		app.notifyError("Autosave disabled: %v", err)
+	}
+
+	if recovered != "" {
+		app.offerRestore(recovered)
+	}
+}
+
+// runAutosave checkpoints the spool on the configured interval
+func (app *Application) runAutosave() {
+	defer app.wg.Done()
+	defer app.recoverPanic("runAutosave")
+
+	ticker := time.NewTicker(autosaveCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := app.autosave.checkpoint(now); err != nil {
+				app.logDebug("Autosave checkpoint failed: %v", err)
+			}
+		}
+	}
+}
+
+// autosaveData buffers received data for the next checkpoint
+func (app *Application) autosaveData(data []byte) {
+	if err := app.autosave.write(data); err != nil {
+		app.logDebug("Autosave checkpoint failed: %v", err)
+	}
+}
+
+// offerRestore asks whether to restore the output of a crashed session
+func (app *Application) offerRestore(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	options := []string{
+		fmt.Sprintf("Restore %s of output from %s", formatByteSize(info.Size()), info.ModTime().Format("2006-01-02 15:04:05")),
+		"Discard",
+	}
+	app.openDialog(menu.NewSelectDialog(app.screen, "Previous Session Did Not Exit Cleanly", options, 0, func(index int, _ string) error {
+		if index == 0 {
+			if err := app.restoreSpool(path); err != nil {
+				app.notifyError("Restore failed: %v", err)
+				return err
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			app.logDebug("Failed to remove recovered spool: %v", err)
+		}
+		return nil
+	}))
+}
+
+// restoreSpool replays recovered data into the terminal and history
+func (app *Application) restoreSpool(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read recovered data: %w", err)
+	}
+
+	// A separate codec so a character split across reads isn't mixed up
+	// with live data
+	text := newCharsetCodec(app.currentCharset()).Decode(data)
+	if err := app.terminal.ProcessOutput(text); err != nil {
+		return err
+	}
+	if app.historyMgr != nil {
+		_ = app.historyMgr.Write(data, history.DirectionOutput)
+	}
+	// Keep the restored data if this session crashes too
+	app.autosaveData(data)
+
+	app.updateStatusMessage(fmt.Sprintf("Restored %s from the previous session", formatByteSize(int64(len(data)))))
+	return nil
+}
+
+// formatByteSize formats a size as B, KB or MB
+func formatByteSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/config"
+)
+
+const (
+	bellThrottle      = 200 * time.Millisecond // A burst of BELs rings once
+	bellFlashDuration = 150 * time.Millisecond // How long the visual bell shows
+)
+
+// bellState tracks bell settings and the visual bell. It has its own lock
+// because bells arrive from the reader goroutine with the terminal locked.
+type bellState struct {
+	settings   config.BellSettings
+	last       time.Time // Last bell that was acted on
+	flashUntil time.Time // End of the current visual bell
+	mu         sync.Mutex
+}
+
+// configure applies new bell settings
+func (b *bellState) configure(settings config.BellSettings) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.settings = settings
+}
+
+// ring records a bell at now. Returns the settings to act on, or false if
+// the bell is part of a burst that already rang.
+func (b *bellState) ring(now time.Time) (config.BellSettings, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.last) < bellThrottle {
+		return b.settings, false
+	}
+	b.last = now
+	if b.settings.Visual {
+		b.flashUntil = now.Add(bellFlashDuration)
+	}
+	return b.settings, true
+}
+
+// flashing reports whether the visual bell is showing at now
+func (b *bellState) flashing(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.flashUntil)
+}
+
+// flashEnded reports once that the visual bell has ended, so the status bar
+// can be redrawn without it
+func (b *bellState) flashEnded(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.flashUntil.IsZero() || now.Before(b.flashUntil) {
+		return false
+	}
+	b.flashUntil = time.Time{}
+	return true
+}
+
+// ringBell handles BEL from the device. Called with the terminal locked.
+func (app *Application) ringBell() {
+	settings, ok := app.bell.ring(time.Now())
+	if !ok {
+		return
+	}
+
+	if settings.Audible && app.screen != nil {
+		_ = app.screen.Beep()
+	}
+	if settings.Visual {
+		app.forceRedraw()
+	}
+	if settings.Notify && !app.focused.Load() {
+		go app.sendDesktopNotification(settings.NotifyCommand, "sterm: "+app.config.SerialConfig.Port, "Bell")
+	}
+}
+
+// sendDesktopNotification shows a desktop notification with the configured
+// command or the system default
+func (app *Application) sendDesktopNotification(command, title, message string) {
+	cmd := desktopNotifyCommand(command, title, message)
+	if cmd == nil {
+		app.logDebug("Desktop notifications are not supported on %s", runtime.GOOS)
+		return
+	}
+	if err := cmd.Run(); err != nil {
+		app.logDebug("Desktop notification failed: %v", err)
+	}
+}
+
+// desktopNotifyCommand returns the command that shows a desktop
+// notification, or nil if there's no default for this platform
+func desktopNotifyCommand(command, title, message string) *exec.Cmd {
+	if args := strings.Fields(command); len(args) > 0 {
+		return exec.Command(args[0], append(args[1:], title, message)...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		// Pass the text as arguments so it needs no AppleScript quoting
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	case "windows":
+		return nil
+	default:
+		return exec.Command("notify-send", title, message)
+	}
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bookmark marks a line in the terminal output with an optional note
+type Bookmark struct {
+	Line      int       // Absolute line number (stable across scrollback trimming)
+	Timestamp time.Time // When the bookmark was created
+	Note      string    // Optional annotation text
+}
+
+// String returns a one-line description of the bookmark
+func (b Bookmark) String() string {
+	if b.Note == "" {
+		return fmt.Sprintf("[%s] line %d", b.Timestamp.Format("15:04:05"), b.Line)
+	}
+	return fmt.Sprintf("[%s] line %d: %s", b.Timestamp.Format("15:04:05"), b.Line, b.Note)
+}
+
+// BookmarkList keeps bookmarks sorted by line
+type BookmarkList struct {
+	items []Bookmark
+	mu    sync.RWMutex
+}
+
+// NewBookmarkList creates an empty bookmark list
+func NewBookmarkList() *BookmarkList {
+	return &BookmarkList{
+		items: make([]Bookmark, 0),
+	}
+}
+
+// Add adds a bookmark, replacing the note of an existing bookmark on the same line
+func (bl *BookmarkList) Add(line int, note string) Bookmark {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	bm := Bookmark{
+		Line:      line,
+		Timestamp: time.Now(),
+		Note:      note,
+	}
+
+	idx := sort.Search(len(bl.items), func(i int) bool { return bl.items[i].Line >= line })
+	if idx < len(bl.items) && bl.items[idx].Line == line {
+		bl.items[idx] = bm
+		return bm
+	}
+
+	bl.items = append(bl.items, Bookmark{})
+	copy(bl.items[idx+1:], bl.items[idx:])
+	bl.items[idx] = bm
+	return bm
+}
+
+// List returns a copy of all bookmarks in line order
+func (bl *BookmarkList) List() []Bookmark {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	result := make([]Bookmark, len(bl.items))
+	copy(result, bl.items)
+	return result
+}
+
+// Count returns the number of bookmarks
+func (bl *BookmarkList) Count() int {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+	return len(bl.items)
+}
+
+// Next returns the first bookmark after the given line that is not before minLine
+func (bl *BookmarkList) Next(line, minLine int) (Bookmark, bool) {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	for _, bm := range bl.items {
+		if bm.Line > line && bm.Line >= minLine {
+			return bm, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// Prev returns the last bookmark before the given line that is not before minLine
+func (bl *BookmarkList) Prev(line, minLine int) (Bookmark, bool) {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	for i := len(bl.items) - 1; i >= 0; i-- {
+		bm := bl.items[i]
+		if bm.Line < line && bm.Line >= minLine {
+			return bm, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// At returns the bookmark on the given line, if any
+func (bl *BookmarkList) At(line int) (Bookmark, bool) {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	idx := sort.Search(len(bl.items), func(i int) bool { return bl.items[i].Line >= line })
+	if idx < len(bl.items) && bl.items[idx].Line == line {
+		return bl.items[idx], true
+	}
+	return Bookmark{}, false
+}
+
+// Remap moves every bookmark to the line remap gives for it, after the
+// lines were wrapped again. Bookmarks landing on the same line are merged,
+// keeping the later one.
+func (bl *BookmarkList) Remap(remap func(line int) int) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	items := bl.items[:0]
+	for _, bm := range bl.items {
+		bm.Line = remap(bm.Line)
+		if n := len(items); n > 0 && items[n-1].Line == bm.Line {
+			items[n-1] = bm
+			continue
+		}
+		items = append(items, bm)
+	}
+	bl.items = items
+}
+
+// Clear removes all bookmarks
+func (bl *BookmarkList) Clear() {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.items = bl.items[:0]
+}
+
+// currentBookmarkLine returns the absolute line a new bookmark should point at:
+// the top visible line in scroll mode, otherwise the cursor line
+func (app *Application) currentBookmarkLine() int {
+	dropped := app.terminal.GetScrollbackDropped()
+	if app.terminal.IsScrolling() {
+		return dropped + app.terminal.GetTopLineIndex()
+	}
+	return dropped + app.terminal.GetTopLineIndex() + app.terminal.GetState().CursorY
+}
+
+// addBookmark prompts for an optional note and bookmarks the current line
+func (app *Application) addBookmark() {
+	line := app.currentBookmarkLine()
+	app.openPrompt(newStatusPrompt("Bookmark note (optional): ", "", func(note string) error {
+		bm := app.bookmarks.Add(line, note)
+		app.logDebug("Bookmark added: %s", bm)
+		app.updateStatusMessage(fmt.Sprintf("Bookmark %d set at line %d", app.bookmarks.Count(), line))
+		return nil
+	}))
+}
+
+// jumpToBookmark scrolls to the next (forward) or previous bookmark
+func (app *Application) jumpToBookmark(forward bool) {
+	dropped := app.terminal.GetScrollbackDropped()
+	current := dropped + app.terminal.GetTopLineIndex()
+
+	var bm Bookmark
+	var ok bool
+	if forward {
+		bm, ok = app.bookmarks.Next(current, dropped)
+	} else {
+		bm, ok = app.bookmarks.Prev(current, dropped)
+	}
+	if !ok {
+		app.updateStatusMessage("No more bookmarks")
+		return
+	}
+
+	app.terminal.ScrollToLine(bm.Line - dropped)
+	app.updateStatusMessage(fmt.Sprintf("Bookmark %s", bm))
+}
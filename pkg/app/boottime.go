@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sterm/pkg/menu"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Boot time report limits
+const (
+	bootSlowestGaps   = 15 // Intervals listed in the report
+	bootMessageWidth  = 60 // Columns of each message shown
+	bootTimestampsMin = 2  // Messages needed for a report
+)
+
+// kernelTimestampRegex matches the printk timestamp of a kernel message,
+// e.g. "[    1.234567] usb 1-1: new high-speed USB device", optionally after
+// a "<6>" log level
+var kernelTimestampRegex = regexp.MustCompile(`^(?:<\d>)?\[\s*(\d+\.\d{3,9})\]\s?(.*)$`)
+
+// kernelMessage is a kernel log line with its timestamp in seconds
+type kernelMessage struct {
+	seconds float64
+	text    string
+}
+
+// bootGap is the time between a message and the one before it
+type bootGap struct {
+	seconds      float64
+	before, next kernelMessage
+}
+
+// parseKernelMessages returns the timestamped kernel messages of the last
+// boot in lines, and how many boots were found. A timestamp going backwards
+// starts a new boot.
+func parseKernelMessages(lines []string) ([]kernelMessage, int) {
+	var messages []kernelMessage
+	boots := 0
+	for _, line := range lines {
+		m := kernelTimestampRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		if len(messages) == 0 || seconds < messages[len(messages)-1].seconds {
+			messages = messages[:0]
+			boots++
+		}
+		messages = append(messages, kernelMessage{seconds: seconds, text: m[2]})
+	}
+	return messages, boots
+}
+
+// slowestGaps returns up to n of the longest intervals between consecutive
+// messages, longest first
+func slowestGaps(messages []kernelMessage, n int) []bootGap {
+	gaps := make([]bootGap, 0, len(messages))
+	for i := 1; i < len(messages); i++ {
+		gaps = append(gaps, bootGap{
+			seconds: messages[i].seconds - messages[i-1].seconds,
+			before:  messages[i-1],
+			next:    messages[i],
+		})
+	}
+	sort.SliceStable(gaps, func(i, j int) bool { return gaps[i].seconds > gaps[j].seconds })
+	return gaps[:min(n, len(gaps))]
+}
+
+// bootTimeLines builds the boot time report for the lines of the scrollback
+func bootTimeLines(lines []string) []string {
+	messages, boots := parseKernelMessages(lines)
+	if len(messages) < bootTimestampsMin {
+		return []string{
+			"No kernel boot log found",
+			"",
+			"  The analysis needs kernel messages with printk timestamps in the",
+			"  scrollback, such as \"[    1.234567] usb 1-1: new device\". Boot with",
+			"  printk.time=1 and keep the console output from power-on.",
+		}
+	}
+
+	first, last := messages[0], messages[len(messages)-1]
+	total := last.seconds - first.seconds
+	report := []string{"Boot summary"}
+	if boots > 1 {
+		report = append(report, fmt.Sprintf("  %d boots in the scrollback, showing the last one", boots))
+	}
+	report = append(report,
+		fmt.Sprintf("  %d messages from [%.6f] to [%.6f]: %.3f s", len(messages), first.seconds, last.seconds, total),
+		"",
+		"Slowest intervals",
+	)
+
+	clip := func(text string) string {
+		return runewidth.Truncate(text, bootMessageWidth, "…")
+	}
+	for _, gap := range slowestGaps(messages, bootSlowestGaps) {
+		share := 0.0
+		if total > 0 {
+			share = gap.seconds / total * 100
+		}
+		report = append(report,
+			fmt.Sprintf("  %8.3f s %5.1f%%  after [%12.6f] %s", gap.seconds, share, gap.before.seconds, clip(gap.before.text)),
+			fmt.Sprintf("  %17s  until [%12.6f] %s", "", gap.next.seconds, clip(gap.next.text)),
+		)
+	}
+	return report
+}
+
+// showBootTime analyzes the kernel boot log in the scrollback
+func (app *Application) showBootTime() {
+	cells := app.terminal.GetAllLines()
+	lines := make([]string, len(cells))
+	for i, line := range cells {
+		lines[i], _ = lineTextWithColumns(line)
+	}
+	app.openDialog(menu.NewTextDialog(app.screen, "Boot Time Analysis", bootTimeLines(lines)))
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"sterm/pkg/menu"
+	"sterm/pkg/serial"
+)
+
+// defaultBridgeAddress is offered when starting a bridge from the menu
+const defaultBridgeAddress = "localhost:7000"
+
+// bridgeState holds the TCP bridge sharing the port, if one is running
+type bridgeState struct {
+	bridge *serial.Bridge
+	mu     sync.Mutex
+}
+
+// bridgeDevice lets bridge clients use the port through the application,
+// so their traffic is displayed, logged and counted like typed input
+type bridgeDevice struct {
+	app *Application
+}
+
+// Write sends a client's data to the port
+func (d bridgeDevice) Write(data []byte) (int, error) {
+	if d.app.config.Monitor {
+		return 0, fmt.Errorf("monitor mode: input is not sent")
+	}
+	if d.app.serialPort == nil || !d.app.serialPort.IsOpen() {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+	return d.app.writeToPortWait(data), nil
+}
+
+// Config returns the port's line settings
+func (d bridgeDevice) Config() serial.SerialConfig {
+	return d.app.config.SerialConfig
+}
+
+// Reconfigure applies line settings an RFC 2217 client asked for
+func (d bridgeDevice) Reconfigure(config serial.SerialConfig) error {
+	if d.app.config.Monitor {
+		return fmt.Errorf("monitor mode: line settings are not changed")
+	}
+	if err := d.app.setLineSettings(config); err != nil {
+		d.app.notifyWarning("Bridge client could not change line settings: %v", err)
+		return err
+	}
+	d.app.updateStatusMessage(fmt.Sprintf("Bridge client set %d %d-%s-%d", config.BaudRate, config.DataBits, config.Parity, config.StopBits))
+	return nil
+}
+
+// Modem returns the port's modem line control, if it has one
+func (d bridgeDevice) Modem() serial.ModemController {
+	if d.app.config.Monitor {
+		return nil
+	}
+	return d.app.modemController()
+}
+
+// parseBridgeAddress splits an "rfc2217:" prefix off a listen address
+func parseBridgeAddress(address string) (string, bool) {
+	if rest, ok := strings.CutPrefix(address, serial.RFC2217Prefix); ok {
+		return rest, true
+	}
+	return address, false
+}
+
+// startBridge serves the port on a TCP address, replacing any running
+// bridge. An "rfc2217:" prefix lets clients change the line settings.
+func (app *Application) startBridge(address string) error {
+	addr, rfc2217 := parseBridgeAddress(address)
+	app.stopBridge()
+
+	bridge, err := serial.NewBridge(addr, rfc2217, bridgeDevice{app: app})
+	if err != nil {
+		return fmt.Errorf("failed to start TCP bridge: %w", err)
+	}
+	app.bridge.mu.Lock()
+	app.bridge.bridge = bridge
+	app.bridge.mu.Unlock()
+	app.logDebug("TCP bridge listening on %s (RFC 2217: %v)", bridge.Addr(), rfc2217)
+	return nil
+}
+
+// stopBridge closes the bridge and disconnects its clients
+func (app *Application) stopBridge() {
+	app.bridge.mu.Lock()
+	bridge := app.bridge.bridge
+	app.bridge.bridge = nil
+	app.bridge.mu.Unlock()
+	if bridge != nil {
+		_ = bridge.Close()
+	}
+}
+
+// currentBridge returns the running bridge, or nil
+func (app *Application) currentBridge() *serial.Bridge {
+	app.bridge.mu.Lock()
+	defer app.bridge.mu.Unlock()
+	return app.bridge.bridge
+}
+
+// bridgeBroadcast passes data received from the port to the bridge clients
+func (app *Application) bridgeBroadcast(data []byte) {
+	if bridge := app.currentBridge(); bridge != nil {
+		bridge.Broadcast(data)
+	}
+}
+
+// bridgeStatus returns the status bar segment shown while bridging
+func (app *Application) bridgeStatus() string {
+	bridge := app.currentBridge()
+	if bridge == nil {
+		return ""
+	}
+	return fmt.Sprintf(" BRIDGE %s (%d) │", bridge.Addr(), bridge.Clients())
+}
+
+// promptBridge asks for the address to share the port on. An empty address
+// stops the bridge.
+func (app *Application) promptBridge() {
+	initial := defaultBridgeAddress
+	if bridge := app.currentBridge(); bridge != nil {
+		initial = bridge.Addr()
+		if bridge.RFC2217() {
+			initial = serial.RFC2217Prefix + initial
+		}
+	}
+
+	label := "Listen address (rfc2217: prefix for RFC 2217, empty to stop):"
+	app.openDialog(menu.NewInputDialog(app.screen, "TCP Bridge", label, initial, func(value string) error {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			app.stopBridge()
+			app.updateStatusMessage("TCP bridge stopped")
+			return nil
+		}
+		if err := app.startBridge(value); err != nil {
+			return err
+		}
+		app.updateStatusMessage("Sharing the port on " + app.currentBridge().Addr())
+		return nil
+	}))
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sterm/pkg/menu"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// Charset is a character encoding a device can use for its output and input
+type Charset struct {
+	Name    string   // Name used on the command line
+	Label   string   // Name shown in the menu and status bar
+	Aliases []string // Other accepted names
+	enc     encoding.Encoding
+}
+
+// charsets are the supported encodings. UTF-8 has no encoding since the
+// terminal emulator decodes it itself.
+var charsets = []Charset{
+	{Name: "utf-8", Label: "UTF-8", Aliases: []string{"utf8"}},
+	{Name: "latin1", Label: "Latin-1", Aliases: []string{"iso-8859-1", "iso8859-1"}, enc: charmap.ISO8859_1},
+	{Name: "cp437", Label: "CP437", Aliases: []string{"ibm437", "dos"}, enc: charmap.CodePage437},
+	{Name: "gbk", Label: "GBK", Aliases: []string{"gb2312", "cp936"}, enc: simplifiedchinese.GBK},
+	{Name: "shift-jis", Label: "Shift-JIS", Aliases: []string{"sjis", "shift_jis", "cp932"}, enc: japanese.ShiftJIS},
+}
+
+// CharsetNames returns the names of the supported encodings
+func CharsetNames() []string {
+	names := make([]string, len(charsets))
+	for i, cs := range charsets {
+		names[i] = cs.Name
+	}
+	return names
+}
+
+// FindCharset looks up an encoding by name or alias, ignoring case. An empty
+// name is UTF-8.
+func FindCharset(name string) (Charset, error) {
+	if name == "" {
+		return charsets[0], nil
+	}
+	for _, cs := range charsets {
+		if strings.EqualFold(cs.Name, name) {
+			return cs, nil
+		}
+		for _, alias := range cs.Aliases {
+			if strings.EqualFold(alias, name) {
+				return cs, nil
+			}
+		}
+	}
+	return Charset{}, fmt.Errorf("unknown encoding %q (supported: %s)", name, strings.Join(CharsetNames(), ", "))
+}
+
+// IsUTF8 reports whether the charset is UTF-8, which needs no conversion
+func (cs Charset) IsUTF8() bool {
+	return cs.enc == nil
+}
+
+// utf8MaxExpansion is extra room for a replacement character when a
+// decoder output buffer is sized from the input
+const utf8MaxExpansion = 4
+
+// charsetCodec converts between a device's encoding and the UTF-8 used by
+// the terminal emulator. Decoding is streaming: a multi-byte character split
+// across two reads is kept until the rest arrives.
+type charsetCodec struct {
+	charset Charset
+	decoder transform.Transformer
+	pending []byte // Start of a character cut off at the end of the last read
+}
+
+// newCharsetCodec creates a codec for an encoding
+func newCharsetCodec(cs Charset) *charsetCodec {
+	c := &charsetCodec{charset: cs}
+	if !cs.IsUTF8() {
+		c.decoder = cs.enc.NewDecoder()
+	}
+	return c
+}
+
+// Decode converts received bytes to UTF-8. Must only be called from the
+// reader goroutine since it keeps state between reads.
+func (c *charsetCodec) Decode(data []byte) []byte {
+	if c.charset.IsUTF8() {
+		return data
+	}
+
+	src := append(c.pending, data...)
+	c.pending = nil
+	dst := make([]byte, len(src)*3+utf8MaxExpansion)
+	var out []byte
+	for {
+		nDst, nSrc, err := c.decoder.Transform(dst, src, false)
+		out = append(out, dst[:nDst]...)
+		src = src[nSrc:]
+		switch {
+		case errors.Is(err, transform.ErrShortDst):
+			continue
+		case errors.Is(err, transform.ErrShortSrc):
+			c.pending = append([]byte(nil), src...)
+		}
+		return out
+	}
+}
+
+// Encode converts typed UTF-8 text to the device's encoding. Characters the
+// encoding can't represent are replaced.
+func (c *charsetCodec) Encode(data []byte) []byte {
+	if c.charset.IsUTF8() {
+		return data
+	}
+
+	encoded, _, err := transform.Bytes(encoding.ReplaceUnsupported(c.charset.enc.NewEncoder()), data)
+	if err != nil {
+		return data
+	}
+	return encoded
+}
+
+// currentCharset returns the encoding in use
+func (app *Application) currentCharset() Charset {
+	if codec := app.codec.Load(); codec != nil {
+		return codec.charset
+	}
+	return charsets[0]
+}
+
+// setCharset switches the encoding used for received and typed text
+func (app *Application) setCharset(cs Charset) {
+	app.codec.Store(newCharsetCodec(cs))
+	app.mu.Lock()
+	app.config.Encoding = cs.Name
+	app.cachedStatusLeft = "" // Non-UTF-8 encodings are shown in the status bar
+	app.mu.Unlock()
+	app.logDebug("Encoding set to %s", cs.Label)
+}
+
+// decodeInput converts data received from the device to UTF-8
+func (app *Application) decodeInput(data []byte) []byte {
+	if codec := app.codec.Load(); codec != nil {
+		return codec.Decode(data)
+	}
+	return data
+}
+
+// encodeOutput converts typed UTF-8 text to the device's encoding
+func (app *Application) encodeOutput(data []byte) []byte {
+	if codec := app.codec.Load(); codec != nil {
+		return codec.Encode(data)
+	}
+	return data
+}
+
+// buildEncodingMenu creates the encoding submenu as a radio group
+func (app *Application) buildEncodingMenu() *menu.Menu {
+	encodingMenu := menu.NewMenu("Encoding", app.screen)
+	current := app.currentCharset().Name
+
+	for _, cs := range charsets {
+		encodingMenu.AddRadioItem("encoding", cs.Label, cs.Name == current, func() error {
+			app.logDebug("Menu: Encoding %s", cs.Label)
+			app.setCharset(cs)
+			app.updateStatusMessage(fmt.Sprintf("Encoding set to %s", cs.Label))
+			return nil
+		})
+	}
+	return encodingMenu
+}
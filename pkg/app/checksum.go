@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"sterm/pkg/checksum"
+	"sterm/pkg/menu"
+)
+
+// checksumPreviewBytes is how much of the data the results show
+const checksumPreviewBytes = 16
+
+// openChecksum asks for data in send-hex syntax and shows its checksums.
+// Selected text is offered as the data: as hex if it reads as hex bytes,
+// otherwise as a quoted string.
+func (app *Application) openChecksum() {
+	initial := ""
+	if app.selection.current != nil {
+		text := app.selection.current.Text(app.visibleLines())
+		if _, err := parsePayload(text); err == nil {
+			initial = text
+		} else {
+			initial = strconv.Quote(text)
+		}
+	}
+
+	app.openDialog(menu.NewInputDialog(app.screen, "Checksum", `Hex bytes and/or "text" (as in Send Hex):`, initial, func(value string) error {
+		data, err := parsePayload(value)
+		if err != nil {
+			return err
+		}
+		app.openDialog(menu.NewTextDialog(app.screen, "Checksum", checksumLines(data)))
+		return nil
+	}))
+}
+
+// checksumLines formats the checksums of data, with the byte order of
+// multi-byte results spelled out since protocols differ
+func checksumLines(data []byte) []string {
+	preview := fmt.Sprintf("% X", data[:min(len(data), checksumPreviewBytes)])
+	if len(data) > checksumPreviewBytes {
+		preview += " ..."
+	}
+	lines := []string{fmt.Sprintf("%d bytes: %s", len(data), preview), ""}
+
+	for _, a := range checksum.Algorithms {
+		line := fmt.Sprintf("%-19s %-10s", a.Name, a.Format(data))
+		if a.Bytes > 1 {
+			be := make([]byte, 4)
+			binary.BigEndian.PutUint32(be, a.Compute(data))
+			be = be[4-a.Bytes:]
+			le := make([]byte, a.Bytes)
+			for i, b := range be {
+				le[a.Bytes-1-i] = b
+			}
+			line += fmt.Sprintf("  BE: % X  LE: % X", be, le)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"sterm/pkg/config"
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// applyChordPrefix sets up prefix key chords from the settings. Returns
+// the prefix label shown in key hints, empty if chords are off.
+func (app *Application) applyChordPrefix(settings config.PrefixSettings) (string, error) {
+	prefix := tcell.KeyNUL
+	label := ""
+	if settings.Key != "" {
+		key, err := config.ParsePrefixKey(settings.Key)
+		if err != nil {
+			return "", fmt.Errorf("prefix.key: %w", err)
+		}
+		prefix = key
+		label = "Ctrl+" + strings.ToUpper(settings.Key[len(settings.Key)-1:])
+	}
+	if app.shortcuts != nil {
+		app.shortcuts.SetChordPrefix(prefix, time.Duration(settings.TimeoutMS)*time.Millisecond)
+	}
+	return label, nil
+}
+
+// chordKey follows a key through a prefix key chord while a prefix is set.
+// Only the command key of a chord is sterm's: it is returned to act on,
+// with a character turned into the Alt+ key of the same letter, so the
+// prefix and a letter run the Alt+ action. Other keys go to the device and
+// nil is returned. Scroll mode keeps its keys without the prefix.
+func (app *Application) chordKey(ev *tcell.EventKey) *tcell.EventKey {
+	if app.chordPrefix == "" || app.shortcuts == nil || app.terminal.IsScrolling() {
+		return ev
+	}
+
+	switch app.shortcuts.ProcessChord(ev.Key(), ev.Modifiers()) {
+	case terminal.ChordNone:
+		app.sendKey(ev)
+		return nil
+	case terminal.ChordPending:
+		app.updateStatusMessage(app.chordPrefix + ": waiting for a command key")
+		return nil
+	case terminal.ChordRepeat:
+		app.sendKey(ev) // The prefix twice sends it once
+		return nil
+	}
+
+	if ev.Key() != tcell.KeyRune || ev.Modifiers()&tcell.ModCtrl != 0 {
+		return ev // F1, F8, Ctrl+Q, Shift+PgUp and the like
+	}
+	if app.resolveAltKey(ev.Rune()) == 0 {
+		app.updateStatusMessage(fmt.Sprintf("%s %c is not bound", app.chordPrefix, unicode.ToUpper(ev.Rune())))
+		return nil
+	}
+	return tcell.NewEventKey(tcell.KeyRune, ev.Rune(), tcell.ModAlt)
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"sterm/pkg/history"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// secretPromptRegex matches remote prompts whose answers must never be recorded
+var secretPromptRegex = regexp.MustCompile(`(?i)(pass(word|phrase)?|passcode|pin|secret|token)\s*:?\s*$`)
+
+// setupCommandHistory loads the command history for the current connection profile
+func (app *Application) setupCommandHistory() {
+	path, err := history.CommandHistoryPath("", app.historyProfile())
+	if err != nil {
+		app.logDebug("Command history disabled: %v", err)
+		path = "" // Keep history in memory only
+	}
+
+	app.cmdHistory = history.NewCommandHistory(path, history.DefaultCommandHistorySize)
+	if err := app.cmdHistory.Load(); err != nil {
+		app.logDebug("Failed to load command history: %v", err)
+	}
+	app.typedLineValid = true
+}
+
+// historyProfile names the per-profile history files: the connection
+// profile, or the port when none is used
+func (app *Application) historyProfile() string {
+	if app.config.ProfileName != "" {
+		return app.config.ProfileName
+	}
+	return app.config.SerialConfig.Port
+}
+
+// trackTypedInput follows what the user types to reconstruct command lines.
+// Lines edited with cursor keys or other escape sequences can't be tracked
+// reliably and are skipped.
+func (app *Application) trackTypedInput(data []byte) {
+	if app.cmdHistory == nil {
+		return
+	}
+
+	for len(data) > 0 {
+		b := data[0]
+		switch {
+		case b == '\r' || b == '\n':
+			app.commitTypedLine()
+		case b == 0x7f || b == 0x08: // Backspace/DEL
+			if len(app.typedLine) > 0 {
+				app.typedLine = app.typedLine[:len(app.typedLine)-1]
+			}
+		case b == 0x03 || b == 0x15: // Ctrl+C, Ctrl+U discard the line
+			app.typedLine = app.typedLine[:0]
+			app.typedLineValid = true
+		case b == 0x1b || b < 0x20: // Escape sequences and other controls
+			app.typedLineValid = false
+		default:
+			r, size := utf8.DecodeRune(data)
+			app.typedLine = append(app.typedLine, r)
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+}
+
+// commitTypedLine stores the tracked line in command history, unless the
+// remote appears to be asking for a password
+func (app *Application) commitTypedLine() {
+	line := string(app.typedLine)
+	valid := app.typedLineValid
+	app.typedLine = app.typedLine[:0]
+	app.typedLineValid = true
+
+	if !valid || line == "" || app.isAtSecretPrompt() {
+		return
+	}
+
+	if err := app.cmdHistory.Add(line); err != nil {
+		app.logDebug("Failed to save command history: %v", err)
+	}
+}
+
+// isAtSecretPrompt checks the cursor line for a password-style prompt
+func (app *Application) isAtSecretPrompt() bool {
+	if app.terminal == nil {
+		return false
+	}
+	screen := app.terminal.GetScreen()
+	state := app.terminal.GetState()
+	if screen == nil || state.CursorY < 0 || state.CursorY >= len(screen.Buffer) {
+		return false
+	}
+
+	// Only look at text left of the cursor; with echo off the answer isn't shown
+	line := screen.Buffer[state.CursorY]
+	end := state.CursorX
+	if end > len(line) {
+		end = len(line)
+	}
+	return secretPromptRegex.MatchString(lineToString(line[:end]))
+}
+
+// openCommandHistory opens a status bar prompt for recalling previous commands.
+// Up/Down walk through history, Ctrl+R searches backwards, Enter sends the line.
+func (app *Application) openCommandHistory() {
+	if app.cmdHistory == nil || app.cmdHistory.Len() == 0 {
+		app.updateStatusMessage("Command history is empty")
+		return
+	}
+
+	const label = "History (↑/↓, Ctrl+R search): "
+	index := app.cmdHistory.Len() // One past the newest entry
+	searching := false
+	query := ""
+
+	prompt := newStatusPrompt(label, "", func(value string) error {
+		if value == "" {
+			return nil
+		}
+		app.sendUserData([]byte(value + "\r"))
+		return nil
+	})
+
+	// showEntry loads the entry at index into the input line
+	showEntry := func() {
+		cmd, _ := app.cmdHistory.Get(index)
+		prompt.input = []rune(cmd)
+	}
+
+	// search finds the next match at or before the given index
+	search := func(before int) {
+		if found := app.cmdHistory.SearchBackward(query, before); found >= 0 {
+			index = found
+			showEntry()
+			prompt.label = fmt.Sprintf("(reverse-i-search)`%s': ", query)
+		} else {
+			prompt.label = fmt.Sprintf("(failed reverse-i-search)`%s': ", query)
+		}
+	}
+
+	prompt.onKey = func(ev *tcell.EventKey) bool {
+		switch ev.Key() {
+		case tcell.KeyUp:
+			searching = false
+			prompt.label = label
+			if index > 0 {
+				index--
+				showEntry()
+			}
+			return true
+		case tcell.KeyDown:
+			searching = false
+			prompt.label = label
+			if index < app.cmdHistory.Len()-1 {
+				index++
+				showEntry()
+			} else {
+				index = app.cmdHistory.Len()
+				prompt.input = prompt.input[:0]
+			}
+			return true
+		case tcell.KeyCtrlR:
+			if searching {
+				search(index) // Next older match
+			} else {
+				searching = true
+				query = ""
+				prompt.label = "(reverse-i-search)`': "
+			}
+			return true
+		}
+
+		if !searching {
+			return false
+		}
+
+		// In search mode typing edits the query instead of the line
+		switch ev.Key() {
+		case tcell.KeyRune:
+			query += string(ev.Rune())
+			search(index + 1)
+			return true
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if len(query) > 0 {
+				_, size := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-size]
+			}
+			search(app.cmdHistory.Len())
+			return true
+		}
+		return false
+	}
+
+	app.openPrompt(prompt)
+}
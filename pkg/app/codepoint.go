@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeInput is one character or raw byte typed into the code point box
+type codeInput struct {
+	char  rune
+	raw   byte
+	isRaw bool
+}
+
+// parseCodeInput converts code point box input to characters and bytes.
+// Entries are separated by spaces or commas: a code point in hex with an
+// optional U+ ("e9", "U+20AC", "1F600"), or \x and two hex digits for a
+// byte sent as is ("\x1b").
+func parseCodeInput(text string) ([]codeInput, error) {
+	var inputs []codeInput
+	for _, token := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if digits, ok := strings.CutPrefix(token, `\x`); ok {
+			b, err := strconv.ParseUint(digits, 16, 8)
+			if err != nil || len(digits) != 2 {
+				return nil, fmt.Errorf("invalid byte %q: use \\x and two hex digits", token)
+			}
+			inputs = append(inputs, codeInput{raw: byte(b), isRaw: true})
+			continue
+		}
+
+		digits := token
+		if len(digits) > 2 && strings.EqualFold(digits[:2], "U+") {
+			digits = digits[2:]
+		}
+		n, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return nil, fmt.Errorf("invalid code point %q", token)
+		}
+		inputs = append(inputs, codeInput{char: rune(n)})
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("nothing to send")
+	}
+	return inputs, nil
+}
+
+// encodeCodeInput converts characters to the device's encoding and appends
+// the raw bytes as they are. Characters the encoding has no code for are an
+// error rather than being replaced.
+func encodeCodeInput(inputs []codeInput, cs Charset) ([]byte, error) {
+	var data []byte
+	for _, input := range inputs {
+		if input.isRaw {
+			data = append(data, input.raw)
+			continue
+		}
+		if cs.IsUTF8() {
+			data = utf8.AppendRune(data, input.char)
+			continue
+		}
+		encoded, err := cs.enc.NewEncoder().Bytes(utf8.AppendRune(nil, input.char))
+		if err != nil {
+			return nil, fmt.Errorf("U+%04X has no code in %s", input.char, cs.Label)
+		}
+		data = append(data, encoded...)
+	}
+	return data, nil
+}
+
+// openCodePoint opens a status bar prompt for entering characters by code
+// point, for those the local keyboard layout can't type. They are sent in
+// the selected encoding; input that doesn't parse stays open for fixing.
+func (app *Application) openCodePoint() {
+	if app.inputBlocked() {
+		return
+	}
+
+	var prompt *statusPrompt
+	prompt = newStatusPrompt(`Send code points (U+20AC, \x1b byte): `, "", func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		inputs, err := parseCodeInput(value)
+		var data []byte
+		if err == nil {
+			data, err = encodeCodeInput(inputs, app.currentCharset())
+		}
+		if err != nil {
+			app.openPrompt(prompt) // Keep the input so it can be corrected
+			return err
+		}
+
+		n := app.writeToPort(data)
+		if n < len(data) {
+			return fmt.Errorf("sent %d of %d bytes", n, len(data))
+		}
+		app.updateStatusMessage(fmt.Sprintf("Sent %d bytes", n))
+		return nil
+	})
+	app.openPrompt(prompt)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"unicode/utf8"
+)
+
+// Control characters are shown in reverse video so they stand out from
+// received text that happens to contain the same symbols
+const (
+	controlStyleOn  = "\x1b[7m"
+	controlStyleOff = "\x1b[27m"
+)
+
+// controlPicture returns the Unicode control picture for a C0 control
+// character or DEL, such as ␍ for CR and ␛ for ESC
+func controlPicture(b byte) rune {
+	if b == 0x7F {
+		return '␡'
+	}
+	return 0x2400 + rune(b)
+}
+
+// visibleControls replaces control characters in received text with their
+// control pictures so line endings and escape sequences can be seen instead
+// of acted on. Line feeds still end the line after their picture, so output
+// keeps its shape.
+func visibleControls(text []byte) []byte {
+	out := make([]byte, 0, len(text)*2)
+	for _, b := range text {
+		if b >= 0x20 && b != 0x7F {
+			out = append(out, b)
+			continue
+		}
+		out = append(out, controlStyleOn...)
+		out = utf8.AppendRune(out, controlPicture(b))
+		out = append(out, controlStyleOff...)
+		if b == '\n' {
+			out = append(out, '\r', '\n')
+		}
+	}
+	return out
+}
+
+// setShowControls switches between interpreting control characters and
+// showing them. History keeps the data as received either way.
+func (app *Application) setShowControls(show bool) {
+	app.showControls.Store(show)
+	if show {
+		app.updateStatusMessage("Control characters: SHOWN")
+	} else {
+		app.updateStatusMessage("Control characters: INTERPRETED")
+	}
+}
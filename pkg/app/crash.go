@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"sterm/pkg/logging"
+)
+
+// crashExitCode is the exit status after a panic, the same as an unrecovered one
+const crashExitCode = 2
+
+// recoverPanic is deferred at the top of every goroutine the application
+// starts. A panic anywhere would otherwise kill the process with the host
+// terminal still in raw mode on the alternate screen, leaving the user's
+// shell unusable and the stack trace drawn over the screen. Instead the
+// screen is shut down first, then the trace is printed and the process exits.
+func (app *Application) recoverPanic(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	app.crash(where, r, debug.Stack())
+}
+
+// crash restores the host terminal, saves what can be saved and exits. Only
+// the first panic is handled; a panic in another goroutine during cleanup
+// waits here until the process exits.
+func (app *Application) crash(where string, r any, stack []byte) {
+	if !app.crashing.CompareAndSwap(false, true) {
+		select {} // Block until the first handler exits
+	}
+
+	// Restore the host terminal before anything is printed. Locks are
+	// avoided since the panicking goroutine may have been holding one.
+	if screen := app.screen; screen != nil {
+		func() {
+			defer func() { _ = recover() }() // A broken screen must not stop the cleanup
+			screen.Fini()
+		}()
+	}
+
+	app.log.Logf(logging.ModuleApp, logging.LevelError, "PANIC in %s: %v\n%s", where, r, stack)
+
+	// Leave the spool in place with everything received so far, so the next
+	// session offers to restore it
+	spoolPath, err := app.autosave.checkpointNow()
+	if err != nil {
+		app.logDebug("Failed to checkpoint spool: %v", err)
+		spoolPath = ""
+	}
+
+	if app.serialPort != nil {
+		app.serialPort.Close()
+	}
+
+	_ = app.log.Close()
+
+	writeCrashReport(os.Stderr, where, r, stack, spoolPath)
+	os.Exit(crashExitCode)
+}
+
+// writeCrashReport prints the panic and stack trace for a bug report.
+// spoolPath is where received data was saved, if autosave is on.
+func writeCrashReport(w io.Writer, where string, r any, stack []byte, spoolPath string) {
+	fmt.Fprintf(w, "\nsterm crashed in %s: %v\n\n", where, r)
+	fmt.Fprintf(w, "Time: %s\n", time.Now().Format(time.RFC3339))
+	if spoolPath != "" {
+		fmt.Fprintf(w, "Received data was saved to %s and will be offered for restore on the next start.\n", spoolPath)
+	}
+	fmt.Fprintf(w, "Please report this with the trace below.\n\n%s", stack)
+}
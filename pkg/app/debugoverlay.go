@@ -0,0 +1,153 @@
+package app
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Debug overlay layout and refresh
+const (
+	debugOverlayWidth = 52
+
+	// debugOverlayRefresh is how often the overlay is redrawn while nothing
+	// else changes, so the counters keep moving
+	debugOverlayRefresh = 500 * time.Millisecond
+
+	// debugRateWindow is how often the TX/RX rates are measured
+	debugRateWindow = time.Second
+)
+
+// debugOverlayState holds the state of the overlay showing parser and
+// renderer internals, for tracking down rendering bugs and slowdowns
+type debugOverlayState struct {
+	visible atomic.Bool
+
+	mu             sync.Mutex
+	sampled        time.Time // When the byte counts were last sampled
+	sent, received int64
+	txRate, rxRate int64 // Bytes a second over the last window
+
+	refreshed time.Time // Used by the UI goroutine only
+}
+
+// toggleDebugOverlay shows or hides the debug overlay
+func (app *Application) toggleDebugOverlay() {
+	on := !app.debugOverlay.visible.Load()
+	app.debugOverlay.visible.Store(on)
+	if app.mainMenu != nil {
+		// Keep the menu check mark in step when toggled with the shortcut
+		app.mainMenu.SetChecked(app.mainMenu.FindItemIndex("Debug Overlay"), on)
+	}
+
+	if on {
+		app.updateStatusMessage("Debug overlay on, " + app.keyLabel("debug-overlay") + " to hide")
+	} else {
+		app.updateStatusMessage("Debug overlay off")
+		app.logRenderer("Frame stats: %s", &app.frames.stats)
+	}
+	app.forceRedraw()
+}
+
+// debugOverlayDue reports whether the visible overlay should be redrawn to
+// refresh its counters
+func (app *Application) debugOverlayDue(now time.Time) bool {
+	d := &app.debugOverlay
+	if !d.visible.Load() || now.Sub(d.refreshed) < debugOverlayRefresh {
+		return false
+	}
+	d.refreshed = now
+	return true
+}
+
+// debugRates returns the TX and RX rates in bytes a second, measured from
+// the session byte counts at most once every debugRateWindow
+func (app *Application) debugRates(now time.Time) (tx, rx int64) {
+	d := &app.debugOverlay
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if app.session == nil {
+		return 0, 0
+	}
+	sent, received := app.session.GetStats()
+	if d.sampled.IsZero() {
+		d.sampled, d.sent, d.received = now, sent, received
+		return 0, 0
+	}
+	if elapsed := now.Sub(d.sampled); elapsed >= debugRateWindow {
+		d.txRate = int64(float64(sent-d.sent) / elapsed.Seconds())
+		d.rxRate = int64(float64(received-d.received) / elapsed.Seconds())
+		d.sampled, d.sent, d.received = now, sent, received
+	}
+	return d.txRate, d.rxRate
+}
+
+// debugOverlayLines formats the overlay, starting with the header
+func (app *Application) debugOverlayLines(info terminal.DebugInfo, now time.Time) []string {
+	parser := info.ParserState.String()
+	if info.Partial != "" {
+		parser += fmt.Sprintf(" %q", info.Partial)
+	}
+	scrolled := "none"
+	if info.Scrolled.Lines != 0 {
+		scrolled = fmt.Sprintf("%+d lines in %d-%d", info.Scrolled.Lines, info.Scrolled.Top, info.Scrolled.Bottom)
+	}
+	dirty := fmt.Sprintf("%d lines", info.DirtyLines)
+	if info.DirtyLines > 0 {
+		dirty += fmt.Sprintf(", rows %d-%d", info.DirtyMinY, info.DirtyMaxY)
+	}
+	screen := "main"
+	if info.AltScreen {
+		screen = "alternate"
+	}
+	stats := &app.frames.stats
+	tx, rx := app.debugRates(now)
+
+	lines := []string{" Debug"}
+	row := func(label, format string, args ...any) {
+		lines = append(lines, fmt.Sprintf("%-11s"+format, append([]any{label}, args...)...))
+	}
+	row("Parser", "%s", parser)
+	row("Last seq", "%q (%d parsed)", info.LastSequence, info.Sequences)
+	row("Cursor", "%d,%d on the %s screen", info.CursorY+1, info.CursorX+1, screen)
+	row("Ignored", "%s", app.ignoredSequenceSummary())
+	row("Scrollback", "%d lines", info.ScrollbackLines)
+	row("Dirty", "%s", dirty)
+	row("Scrolled", "%s", scrolled)
+	row("Queue", "%d/%d updates", len(app.updateNotify), cap(app.updateNotify))
+	row("Frames", "%d drawn, %d immediate, %v apart", stats.drawn.Load(), stats.immediate.Load(),
+		time.Duration(stats.interval.Load()).Round(time.Millisecond))
+	row("Requests", "%d, %d coalesced, %d dropped", stats.requested.Load(), stats.coalesced.Load(), stats.dropped.Load())
+	row("TX/RX", "%s/s / %s/s", formatByteSize(tx), formatByteSize(rx))
+	row("Goroutines", "%d", runtime.NumGoroutine())
+	return lines
+}
+
+// drawDebugOverlay draws the overlay in the top right corner of the
+// terminal, with the emulator state captured before the frame was drawn
+func (app *Application) drawDebugOverlay(screenWidth, contentHeight int, info terminal.DebugInfo) {
+	if !app.debugOverlay.visible.Load() || contentHeight < 2 {
+		return
+	}
+
+	width := min(screenWidth, debugOverlayWidth)
+	left := screenWidth - width
+	headerStyle := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground).Bold(true)
+	for i, line := range app.debugOverlayLines(info, time.Now()) {
+		if i >= contentHeight {
+			break
+		}
+		if i == 0 {
+			app.drawPanelLine(left, i, width, line, headerStyle)
+		} else {
+			app.drawPanelLine(left, i, width, " "+line, tcell.StyleDefault)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/decoder"
+	"sterm/pkg/menu"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// decoderMaxFrames is how many decoded frames are kept for the panel
+const decoderMaxFrames = 1000
+
+// decoderPanelMinWidth is the narrowest the decoder panel gets
+const decoderPanelMinWidth = 32
+
+// decoderState holds the protocol decoders that run alongside the terminal
+// and the frames they found. Decoders are fed from the reader goroutine and
+// frames are drawn by the UI, so everything is guarded by mu.
+type decoderState struct {
+	active  []decoder.Decoder
+	frames  []decoder.Frame
+	visible bool // Panel shown on the right of the terminal
+	mu      sync.Mutex
+}
+
+// setDecoderEnabled starts or stops a decoder by name
+func (app *Application) setDecoderEnabled(name string, enabled bool) error {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, dec := range d.active {
+		if dec.Name() == name {
+			if !enabled {
+				d.active = append(d.active[:i], d.active[i+1:]...)
+			}
+			return nil
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	dec, err := decoder.New(name)
+	if err != nil {
+		return err
+	}
+	d.active = append(d.active, dec)
+	return nil
+}
+
+// decoderEnabled reports whether a decoder is running
+func (app *Application) decoderEnabled(name string) bool {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, dec := range d.active {
+		if dec.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// activeDecoderNames lists the running decoders
+func (app *Application) activeDecoderNames() []string {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make([]string, len(d.active))
+	for i, dec := range d.active {
+		names[i] = dec.Name()
+	}
+	return names
+}
+
+// feedDecoders passes data received now to the running decoders. Called
+// with no data when the line goes quiet, so decoders that frame on
+// silence can finish a message.
+func (app *Application) feedDecoders(data []byte) {
+	app.feedDecodersAt(data, time.Now())
+}
+
+// feedDecodersAt passes the running decoders data read at a given time
+func (app *Application) feedDecodersAt(data []byte, now time.Time) {
+	d := &app.decoders
+	d.mu.Lock()
+	if len(d.active) == 0 {
+		d.mu.Unlock()
+		return
+	}
+
+	added := 0
+	var sentences []decoder.Frame
+	for _, dec := range d.active {
+		frames := dec.Feed(data, now)
+		for _, frame := range frames {
+			if !frame.Valid() {
+				app.logDebug("Decoder %s: invalid frame: %s", frame.Decoder, frame.Error)
+			}
+		}
+		d.frames = append(d.frames, frames...)
+		added += len(frames)
+		if dec.Name() == "nmea" {
+			sentences = append(sentences, frames...)
+		}
+	}
+	if len(d.frames) > decoderMaxFrames {
+		d.frames = append(d.frames[:0], d.frames[len(d.frames)-decoderMaxFrames:]...)
+	}
+	visible := d.visible
+	d.mu.Unlock()
+
+	if len(sentences) > 0 {
+		app.updateGPS(sentences)
+	}
+	if added > 0 && visible {
+		app.forceRedraw()
+	}
+}
+
+// showDecodersSent passes data written to the port to the running decoders
+// that read the device's replies by what was sent
+func (app *Application) showDecodersSent(data []byte, now time.Time) {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, dec := range d.active {
+		if observer, ok := dec.(decoder.TxObserver); ok {
+			observer.ObserveTx(data, now)
+		}
+	}
+}
+
+// decodedFrames returns a copy of the newest frames, up to limit
+func (app *Application) decodedFrames(limit int) []decoder.Frame {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	start := max(0, len(d.frames)-limit)
+	return append([]decoder.Frame(nil), d.frames[start:]...)
+}
+
+// clearDecodedFrames empties the panel and drops partial frames
+func (app *Application) clearDecodedFrames() {
+	d := &app.decoders
+	d.mu.Lock()
+	d.frames = nil
+	for _, dec := range d.active {
+		dec.Reset()
+	}
+	d.mu.Unlock()
+	app.forceRedraw()
+}
+
+// decoderPanelVisible reports whether the panel is shown
+func (app *Application) decoderPanelVisible() bool {
+	d := &app.decoders
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.visible
+}
+
+// setDecoderPanelVisible shows or hides the panel. The terminal underneath
+// is redrawn when it's hidden. The panel takes the place of the GPS
+// dashboard and TX/RX view.
+func (app *Application) setDecoderPanelVisible(visible bool) {
+	d := &app.decoders
+	d.mu.Lock()
+	d.visible = visible
+	d.mu.Unlock()
+
+	if visible {
+		app.gps.mu.Lock()
+		app.gps.visible = false
+		app.gps.mu.Unlock()
+		app.txrx.mu.Lock()
+		app.txrx.mode = txrxOff
+		app.txrx.mu.Unlock()
+	}
+	app.forceRedraw()
+}
+
+// toggleDecoderPanel shows or hides the decoded frames
+func (app *Application) toggleDecoderPanel() {
+	visible := !app.decoderPanelVisible()
+	if visible && len(app.activeDecoderNames()) == 0 {
+		app.updateStatusMessage("No decoders running - enable one from the Decoders menu")
+		return
+	}
+	app.setDecoderPanelVisible(visible)
+}
+
+// drawDecoderPanel draws the newest decoded frames over the right side of
+// the terminal, newest at the bottom. Invalid frames are shown in the error
+// color with the reason.
+func (app *Application) drawDecoderPanel(screenWidth, contentHeight int) {
+	if !app.decoderPanelVisible() || contentHeight < 2 {
+		return
+	}
+
+	width := min(screenWidth, max(decoderPanelMinWidth, screenWidth*2/5))
+	left := screenWidth - width
+	frames := app.decodedFrames(contentHeight - 1)
+
+	headerStyle := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground).Bold(true)
+	style := tcell.StyleDefault
+	errorStyle := tcell.StyleDefault.Foreground(app.theme.error)
+
+	header := fmt.Sprintf(" Decoded: %s (%d)", strings.Join(app.activeDecoderNames(), ", "), len(frames))
+	app.drawPanelLine(left, 0, width, header, headerStyle)
+
+	y := contentHeight - len(frames)
+	for row := 1; row < y; row++ {
+		app.drawPanelLine(left, row, width, "", style)
+	}
+	for _, frame := range frames {
+		text := fmt.Sprintf("%s %-6s %s", frame.Time.Format("15:04:05.000"), frame.Decoder, frame.Summary)
+		lineStyle := style
+		if !frame.Valid() {
+			text += " [" + frame.Error + "]"
+			lineStyle = errorStyle
+		}
+		app.drawPanelLine(left, y, width, text, lineStyle)
+		y++
+	}
+}
+
+// drawPanelLine draws a separator and text padded or truncated to width
+func (app *Application) drawPanelLine(left, y, width int, text string, style tcell.Style) {
+	app.screen.SetContent(left, y, '│', nil, tcell.StyleDefault.Foreground(app.theme.scroll))
+	text = runewidth.FillRight(runewidth.Truncate(text, width-1, "…"), width-1)
+	x := left + 1
+	for _, ch := range text {
+		app.screen.SetContent(x, y, ch, nil, style)
+		x += runewidth.RuneWidth(ch)
+	}
+}
+
+// buildDecoderMenu creates the decoders submenu: a check item per decoder
+// and the panel controls
+func (app *Application) buildDecoderMenu() *menu.Menu {
+	decoderMenu := menu.NewMenu("Decoders", app.screen)
+
+	for _, name := range decoder.Names() {
+		decoderMenu.AddCheckItem(strings.ToUpper(name), "", app.decoderEnabled(name), func(checked bool) error {
+			app.logDebug("Menu: Decoder %s enabled=%v", name, checked)
+			if err := app.setDecoderEnabled(name, checked); err != nil {
+				app.notifyError("Decoder %s: %v", name, err)
+				return err
+			}
+			// Show the panel when the first decoder starts
+			if checked {
+				app.setDecoderPanelVisible(true)
+			} else if len(app.activeDecoderNames()) == 0 {
+				app.setDecoderPanelVisible(false)
+			}
+			return nil
+		})
+	}
+
+	decoderMenu.AddSeparator()
+	decoderMenu.AddCheckItem("Split Frames on Idle Gap", "", !app.framingGap().IsZero(), func(checked bool) error {
+		app.logDebug("Menu: Frame on idle gap %v", checked)
+		if !checked {
+			app.setFramingGap(FrameGap{})
+			app.updateStatusMessage("Framing off")
+			return nil
+		}
+		gap := app.config.FrameGap
+		if gap.IsZero() {
+			gap = DefaultFrameGap
+		}
+		app.setFramingGap(gap)
+		app.updateStatusMessage(fmt.Sprintf("New frame after %s (%v) of silence", gap, gap.For(app.config.SerialConfig)))
+		return nil
+	})
+	decoderMenu.AddItem("Show/Hide Panel", app.keyLabel("decoders"), func() error {
+		app.toggleDecoderPanel()
+		return nil
+	})
+	decoderMenu.AddCheckItem("GPS Dashboard", "", app.gpsDashboardVisible(), func(checked bool) error {
+		app.logDebug("Menu: GPS dashboard visible=%v", checked)
+		if err := app.setGPSDashboardVisible(checked); err != nil {
+			app.notifyError("GPS dashboard: %v", err)
+			return err
+		}
+		return nil
+	})
+	decoderMenu.AddItem("Clear Frames", "", func() error {
+		app.clearDecodedFrames()
+		app.updateStatusMessage("Decoded frames cleared")
+		return nil
+	})
+	return decoderMenu
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sterm/pkg/history"
+	"sterm/pkg/menu"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// sendFileChunkSize is how much of a file is written to the port at once
+const sendFileChunkSize = 256
+
+// openDialog shows a modal dialog, closing the menu first
+func (app *Application) openDialog(dialog menu.Dialog) {
+	app.hideMainMenu()
+	app.mu.Lock()
+	app.dialog = dialog
+	app.mu.Unlock()
+	app.forceRedraw()
+}
+
+// handleDialogKey passes a key to the open dialog and drops the dialog once
+// it closes. Dialog callbacks may open a follow-up dialog.
+func (app *Application) handleDialogKey(ev *tcell.EventKey) {
+	dialog := app.dialog
+	dialog.HandleKey(ev)
+
+	app.mu.Lock()
+	if app.dialog == dialog && !dialog.IsOpen() {
+		app.dialog = nil
+		if app.screen != nil {
+			app.screen.HideCursor()
+		}
+	}
+	app.mu.Unlock()
+	app.forceRedraw()
+}
+
+// promptSaveSessionAs asks where to save the session text
+func (app *Application) promptSaveSessionAs() {
+	initial, err := app.logFilePath(logKindSession, ".txt")
+	if err != nil {
+		app.notifyError("%v", err)
+		return
+	}
+	app.openDialog(menu.NewFileDialog(app.screen, "Save Session As", menu.FileDialogSave, initial, func(path string) error {
+		return app.saveSessionTo(path)
+	}))
+}
+
+// historyFormats are offered when exporting history
+var historyFormats = []struct {
+	name   string
+	format history.FileFormat
+}{
+	{"Plain text", history.FormatPlainText},
+	{"Timestamped", history.FormatTimestamped},
+	{"JSON", history.FormatJSON},
+}
+
+// promptExportHistory asks for a file and then a format to export history in
+func (app *Application) promptExportHistory() {
+	initial := fmt.Sprintf("history_%s.log", time.Now().Format("20060102_150405"))
+	app.openDialog(menu.NewFileDialog(app.screen, "Export History", menu.FileDialogSave, initial, func(path string) error {
+		names := make([]string, len(historyFormats))
+		selected := 0
+		for i, f := range historyFormats {
+			names[i] = f.name
+			if f.format == app.config.HistoryFormat {
+				selected = i
+			}
+		}
+
+		app.openDialog(menu.NewSelectDialog(app.screen, "History Format", names, selected, func(index int, _ string) error {
+			if err := app.historyMgr.SaveToFile(path, historyFormats[index].format); err != nil {
+				return fmt.Errorf("export failed: %w", err)
+			}
+			app.updateStatusMessage(fmt.Sprintf("History exported to %s", path))
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// promptExtractFields asks for a pattern with capture groups and then a
+// file to write the fields it matches in the received history to
+func (app *Application) promptExtractFields() {
+	app.openDialog(menu.NewInputDialog(app.screen, "Extract Fields", "Regex with capture groups, e.g. temp=(?P<temp>[-0-9.]+):", "", func(pattern string) error {
+		extractor, err := history.NewFieldExtractor(pattern)
+		if err != nil {
+			return err
+		}
+		initial := fmt.Sprintf("fields_%s.csv", time.Now().Format("20060102_150405"))
+		app.openDialog(menu.NewFileDialog(app.screen, "Export Fields (.csv or .json)", menu.FileDialogSave, initial, func(path string) error {
+			return app.extractFieldsTo(extractor, path)
+		}))
+		return nil
+	}))
+}
+
+// extractFieldsTo writes the fields matched in the received history to a
+// file, as JSON for a .json name and CSV otherwise
+func (app *Application) extractFieldsTo(extractor *history.FieldExtractor, path string) error {
+	entries, err := app.historyMgr.GetEntries(0, app.historyMgr.GetEntryCount())
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	rows := extractor.ExtractEntries(entries)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = extractor.WriteJSON(file, rows)
+	} else {
+		err = extractor.WriteCSV(file, rows)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	app.updateStatusMessage(fmt.Sprintf("Extracted %d rows to %s", len(rows), path))
+	return nil
+}
+
+// promptSendFile asks for a file to send to the serial port
+func (app *Application) promptSendFile() {
+	cwd, _ := os.Getwd()
+	app.openDialog(menu.NewFileDialog(app.screen, "Send File", menu.FileDialogOpen, cwd, func(path string) error {
+		return app.sendFile(path)
+	}))
+}
+
+// promptBaudRate asks for a custom baud rate
+func (app *Application) promptBaudRate() {
+	current := app.config.SerialConfig.BaudRate
+	app.openDialog(menu.NewSpinnerDialog(app.screen, "Set Baud Rate", "Baud rate", current, 50, 4000000, 100, func(rate int) error {
+		if err := app.setBaudRate(rate); err != nil {
+			return err
+		}
+		// Rebuild the menu so the baud rate radio group shows the new rate
+		app.mainMenu.Clear()
+		app.setupMenu()
+		app.updateStatusMessage(fmt.Sprintf("Baud rate set to %d%s", rate, app.baudRateDeviation()))
+		return nil
+	}))
+}
+
+// sendFile writes a file to the serial port in the background
+func (app *Application) sendFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if app.serialPort == nil || !app.serialPort.IsOpen() {
+		return fmt.Errorf("serial port is not open")
+	}
+
+	app.logDebug("Sending file %s (%d bytes)", path, len(data))
+	go func() {
+		sent := 0
+		for sent < len(data) {
+			select {
+			case <-app.ctx.Done():
+				return
+			default:
+			}
+
+			end := min(sent+sendFileChunkSize, len(data))
+			n := app.writeToPortWait(data[sent:end])
+			if n == 0 {
+				app.notifyWarning("Send file stopped after %d bytes", sent)
+				return
+			}
+			sent += n
+		}
+		app.updateStatusMessage(fmt.Sprintf("Sent %s (%d bytes)", path, sent))
+	}()
+
+	return nil
+}
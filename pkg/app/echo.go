@@ -0,0 +1,132 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// echoTimeout is how long after sending data its echo is waited for;
+	// anything received later is the device's own output
+	echoTimeout = time.Second
+
+	// echoWindow is the most sent data waiting to be echoed; older bytes
+	// are forgotten
+	echoWindow = 4096
+)
+
+// echoSuppressor hides the echo of a half-duplex device that repeats every
+// byte it is sent. Sent bytes are remembered in order and received bytes
+// matching them are dropped from the display, so with local echo on typed
+// characters show once. The first received byte that doesn't match ends
+// the echo, so output from a device that stops echoing is shown whole. A
+// line feed after an echoed carriage return is shown but doesn't end it,
+// for devices that echo Enter as CR LF.
+type echoSuppressor struct {
+	enabled bool
+	pending []byte    // Sent bytes not echoed yet
+	sentAt  time.Time // When data was last sent
+	last    byte      // The last byte echoed
+	mu      sync.Mutex
+}
+
+// echoSettingStore saves a profile's echo suppression, implemented by
+// config.FileConfigManager
+type echoSettingStore interface {
+	SetSuppressEcho(name string, suppress bool) error
+}
+
+// setEnabled turns suppression on or off, forgetting what was sent
+func (e *echoSuppressor) setEnabled(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.enabled = enabled
+	e.pending = nil
+}
+
+// isEnabled reports whether echo is being suppressed
+func (e *echoSuppressor) isEnabled() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enabled
+}
+
+// expect records data about to be sent, to be dropped when it comes back
+func (e *echoSuppressor) expect(data []byte, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.enabled {
+		return
+	}
+	if now.Sub(e.sentAt) > echoTimeout {
+		e.pending = e.pending[:0]
+	}
+	e.pending = append(e.pending, data...)
+	if len(e.pending) > echoWindow {
+		e.pending = append(e.pending[:0], e.pending[len(e.pending)-echoWindow:]...)
+	}
+	e.sentAt = now
+}
+
+// strip returns received data without the echo of sent data. data is
+// never modified; a copy is returned when anything is dropped.
+func (e *echoSuppressor) strip(data []byte, received time.Time) []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.enabled || len(e.pending) == 0 {
+		return data
+	}
+	if received.Sub(e.sentAt) > echoTimeout {
+		e.pending = e.pending[:0]
+		return data
+	}
+
+	var shown []byte
+	for i, b := range data {
+		switch {
+		case len(e.pending) > 0 && b == e.pending[0]:
+			if shown == nil {
+				shown = append(make([]byte, 0, len(data)), data[:i]...)
+			}
+			e.pending = e.pending[1:]
+			e.last = b
+			continue
+		case len(e.pending) > 0 && b == '\n' && e.last == '\r':
+			e.last = b
+		default:
+			// Not echo; neither is anything sent before it
+			e.pending = e.pending[:0]
+		}
+		if shown != nil {
+			shown = append(shown, b)
+		}
+		if len(e.pending) == 0 {
+			if shown == nil {
+				return data
+			}
+			return append(shown, data[i+1:]...)
+		}
+	}
+	if shown == nil {
+		return data
+	}
+	return shown
+}
+
+// setSuppressEcho turns echo suppression on or off, saving the choice to
+// the profile when connected through one
+func (app *Application) setSuppressEcho(suppress bool) error {
+	app.echo.setEnabled(suppress)
+	if app.config.ProfileName == "" {
+		return nil
+	}
+	store, ok := app.configMgr.(echoSettingStore)
+	if !ok {
+		return nil
+	}
+	if err := store.SetSuppressEcho(app.config.ProfileName, suppress); err != nil {
+		return fmt.Errorf("failed to save echo suppression to profile %s: %w", app.config.ProfileName, err)
+	}
+	return nil
+}
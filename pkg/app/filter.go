@@ -0,0 +1,299 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"sterm/pkg/terminal"
+)
+
+// FilterMode controls how lines that don't match the display filter are shown
+type FilterMode int
+
+const (
+	// FilterModeHide hides lines that don't match the pattern
+	FilterModeHide FilterMode = iota
+	// FilterModeHighlight keeps all lines but emphasizes matching ones and dims the rest
+	FilterModeHighlight
+)
+
+// String returns the string representation of the filter mode
+func (m FilterMode) String() string {
+	switch m {
+	case FilterModeHide:
+		return "hide"
+	case FilterModeHighlight:
+		return "highlight"
+	default:
+		return "unknown"
+	}
+}
+
+// DisplayFilter is a render-layer line filter. It never touches the
+// underlying terminal buffers, so history and scrollback keep the full data.
+type DisplayFilter struct {
+	pattern string
+	regex   *regexp.Regexp
+	mode    FilterMode
+	enabled bool
+	mu      sync.RWMutex
+}
+
+// NewDisplayFilter creates a new, disabled display filter
+func NewDisplayFilter() *DisplayFilter {
+	return &DisplayFilter{
+		mode: FilterModeHide,
+	}
+}
+
+// SetPattern compiles and sets the filter pattern. An empty pattern disables the filter.
+func (f *DisplayFilter) SetPattern(pattern string) error {
+	if pattern == "" {
+		f.mu.Lock()
+		f.pattern = ""
+		f.regex = nil
+		f.enabled = false
+		f.mu.Unlock()
+		return nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid filter pattern: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pattern = pattern
+	f.regex = re
+	f.enabled = true
+	return nil
+}
+
+// Pattern returns the current filter pattern
+func (f *DisplayFilter) Pattern() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.pattern
+}
+
+// SetMode sets the filter mode
+func (f *DisplayFilter) SetMode(mode FilterMode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mode = mode
+}
+
+// Mode returns the current filter mode
+func (f *DisplayFilter) Mode() FilterMode {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.mode
+}
+
+// ToggleMode switches between hide and highlight mode
+func (f *DisplayFilter) ToggleMode() FilterMode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mode == FilterModeHide {
+		f.mode = FilterModeHighlight
+	} else {
+		f.mode = FilterModeHide
+	}
+	return f.mode
+}
+
+// Toggle enables or disables the filter. A filter without a pattern stays disabled.
+func (f *DisplayFilter) Toggle() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.regex == nil {
+		f.enabled = false
+		return false
+	}
+	f.enabled = !f.enabled
+	return f.enabled
+}
+
+// IsActive returns true if the filter is enabled and has a valid pattern
+func (f *DisplayFilter) IsActive() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.enabled && f.regex != nil
+}
+
+// MatchLine checks whether a line of terminal cells matches the pattern
+func (f *DisplayFilter) MatchLine(line []terminal.Cell) bool {
+	return f.matchText(lineToString(line))
+}
+
+// matchText checks whether the text of a line matches the pattern
+func (f *DisplayFilter) matchText(text string) bool {
+	f.mu.RLock()
+	re := f.regex
+	f.mu.RUnlock()
+
+	if re == nil {
+		return true
+	}
+	return re.MatchString(text)
+}
+
+// logicalLineAt returns the logical line row i is part of: the rows before
+// it that wrapped onto it and the rows it wrapped onto
+func logicalLineAt(lines [][]terminal.Cell, i int) terminal.LogicalLine {
+	start, end := i, i+1
+	for start > 0 && terminal.IsWrapped(lines[start-1]) {
+		start--
+	}
+	for end < len(lines) && terminal.IsWrapped(lines[end-1]) {
+		end++
+	}
+	return terminal.LogicalLine{Start: start, Rows: lines[start:end]}
+}
+
+// Apply returns the lines to display for the given height. In hide mode only
+// the last height matching lines are kept; in highlight mode the last height
+// lines are returned unchanged. The returned match flags are parallel to the
+// lines. Lines are matched as the device sent them, so a long line wrapped
+// over several rows matches as a whole and all its rows are shown.
+func (f *DisplayFilter) Apply(lines [][]terminal.Cell, height int) ([][]terminal.Cell, []bool) {
+	if height <= 0 {
+		return nil, nil
+	}
+
+	if f.Mode() == FilterModeHighlight {
+		start := 0
+		if len(lines) > height {
+			start = len(lines) - height
+		}
+		visible := lines[start:]
+		matches := make([]bool, len(visible))
+		for i := 0; i < len(visible); {
+			line := logicalLineAt(lines, start+i)
+			match := f.matchText(line.Text())
+			for ; i < line.End()-start; i++ {
+				matches[i] = match
+			}
+		}
+		return visible, matches
+	}
+
+	// Hide mode - walk backwards collecting matching lines until the view is full
+	visible := make([][]terminal.Cell, 0, height)
+	for i := len(lines) - 1; i >= 0 && len(visible) < height; {
+		line := logicalLineAt(lines, i)
+		i = line.Start - 1
+		text := line.Text()
+		if strings.TrimSpace(text) == "" || !f.matchText(text) {
+			continue
+		}
+		for row := len(line.Rows) - 1; row >= 0; row-- {
+			visible = append(visible, line.Rows[row])
+		}
+	}
+
+	// Reverse to restore top-to-bottom order, keeping the last rows of a
+	// line that doesn't fit whole
+	for i, j := 0, len(visible)-1; i < j; i, j = i+1, j-1 {
+		visible[i], visible[j] = visible[j], visible[i]
+	}
+	if len(visible) > height {
+		visible = visible[len(visible)-height:]
+	}
+
+	matches := make([]bool, len(visible))
+	for i := range matches {
+		matches[i] = true
+	}
+	return visible, matches
+}
+
+// lineToString converts a line of cells to a string, skipping wide-char continuation cells
+func lineToString(line []terminal.Cell) string {
+	var sb strings.Builder
+	for _, cell := range line {
+		if cell.Char == 0 {
+			continue
+		}
+		sb.WriteString(cell.String())
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// isBlankLine checks if a line contains only spaces
+func isBlankLine(line []terminal.Cell) bool {
+	for _, cell := range line {
+		if cell.Char != ' ' && cell.Char != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// renderFiltered draws the filtered view of the terminal content
+func (app *Application) renderFiltered(buffer [][]terminal.Cell, contentHeight int) {
+	// In scroll mode filter the visible scrollback window, otherwise the whole stream
+	lines := buffer
+	if !app.terminal.IsScrolling() {
+		lines = app.terminal.GetAllLines()
+	}
+
+	visible, matches := app.filter.Apply(lines, contentHeight)
+	highlight := app.filter.Mode() == FilterModeHighlight
+
+	for y := 0; y < contentHeight && y < len(visible); y++ {
+		for x, cell := range visible[y] {
+			style := cellStyle(cell)
+			if highlight {
+				if matches[y] {
+					style = style.Bold(true)
+				} else {
+					style = style.Dim(true)
+				}
+			}
+			app.screen.SetContent(x, y, cell.Char, cell.Combining(), style)
+		}
+	}
+}
+
+// editFilter opens a status bar prompt to edit the display filter pattern
+func (app *Application) editFilter() {
+	prompt := newStatusPrompt("Filter regex (Tab: mode): ", app.filter.Pattern(), func(value string) error {
+		if err := app.filter.SetPattern(value); err != nil {
+			return err
+		}
+		if value == "" {
+			app.updateStatusMessage("Filter cleared")
+		} else {
+			app.updateStatusMessage(fmt.Sprintf("Filter (%s): /%s/", app.filter.Mode(), value))
+		}
+		app.forceRedraw()
+		return nil
+	})
+	prompt.onTab = func() {
+		mode := app.filter.ToggleMode()
+		prompt.label = fmt.Sprintf("Filter regex [%s] (Tab: mode): ", mode)
+	}
+	prompt.label = fmt.Sprintf("Filter regex [%s] (Tab: mode): ", app.filter.Mode())
+	app.openPrompt(prompt)
+}
+
+// toggleFilter enables or disables the display filter
+func (app *Application) toggleFilter() {
+	if app.filter.Pattern() == "" {
+		// Nothing to toggle yet - ask for a pattern instead
+		app.editFilter()
+		return
+	}
+
+	if app.filter.Toggle() {
+		app.updateStatusMessage(fmt.Sprintf("Filter ON: /%s/", app.filter.Pattern()))
+	} else {
+		app.updateStatusMessage("Filter OFF")
+	}
+	app.forceRedraw()
+}
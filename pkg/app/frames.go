@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"sterm/pkg/terminal"
+)
+
+const (
+	// defaultMaxFPS is the frame rate limit unless display.max_fps sets one
+	defaultMaxFPS = 60
+
+	// floodRate is the data rate, in bytes a second, above which frames are
+	// spaced further apart as the rate grows: about 115200 baud flat out
+	floodRate = 12 << 10
+
+	// floodFrameInterval is the longest time between frames during a flood
+	floodFrameInterval = 100 * time.Millisecond
+
+	// rateWindow is how often the data rate is measured
+	rateWindow = 100 * time.Millisecond
+
+	// housekeepingInterval is how often the clocks, notifications and the
+	// visual bell are checked for a change that needs a redraw
+	housekeepingInterval = 50 * time.Millisecond
+)
+
+// frameScheduler decides when screen updates are drawn. An update is drawn
+// at once if the last frame is a frame interval old, so typing and prompts
+// show without delay; otherwise it waits for the interval and is drawn
+// together with everything else that changed meanwhile. The interval is
+// 1/max_fps, growing with the data rate during floods so the time goes to
+// processing data rather than drawing screens too fast to read.
+type frameScheduler struct {
+	maxFPS    atomic.Int32
+	received  atomic.Int64 // Bytes received since the rate was measured
+	lastFrame atomic.Int64 // When the last frame was drawn, in Unix nanoseconds
+
+	// Set when a frame was held back for a synchronized update, so it is
+	// drawn when the update ends or times out
+	syncHeld atomic.Bool
+
+	// Used by the UI goroutine only
+	rate     float64 // Smoothed data rate in bytes a second
+	measured time.Time
+
+	stats frameStats
+}
+
+// frameStats count what the frame scheduler did, for the debug overlay
+type frameStats struct {
+	requested atomic.Uint64 // Updates requested
+	dropped   atomic.Uint64 // Requests dropped with the update queue full
+	coalesced atomic.Uint64 // Requests drawn with a frame already waiting
+	immediate atomic.Uint64 // Frames drawn as soon as they were requested
+	drawn     atomic.Uint64 // Frames drawn
+	rate      atomic.Int64  // Last measured data rate in bytes a second
+	interval  atomic.Int64  // Current frame interval
+}
+
+// configure sets the frame rate limit; 0 uses the default
+func (f *frameScheduler) configure(maxFPS int) {
+	f.maxFPS.Store(int32(maxFPS))
+}
+
+// addReceived counts received bytes toward the data rate
+func (f *frameScheduler) addReceived(n int) {
+	f.received.Add(int64(n))
+}
+
+// frameDrawn records that a frame was drawn at now
+func (f *frameScheduler) frameDrawn(now time.Time) {
+	f.lastFrame.Store(now.UnixNano())
+	f.stats.drawn.Add(1)
+}
+
+// interval returns the time to keep between frames at the current data
+// rate, measuring the rate every rateWindow
+func (f *frameScheduler) interval(now time.Time) time.Duration {
+	if elapsed := now.Sub(f.measured); elapsed >= rateWindow {
+		current := float64(f.received.Swap(0)) / elapsed.Seconds()
+		if f.measured.IsZero() || elapsed > 10*rateWindow {
+			f.rate = current // No history worth keeping
+		} else {
+			f.rate = (f.rate + current) / 2
+		}
+		f.measured = now
+		f.stats.rate.Store(int64(f.rate))
+	}
+
+	fps := int(f.maxFPS.Load())
+	if fps <= 0 {
+		fps = defaultMaxFPS
+	}
+	interval := time.Second / time.Duration(fps)
+	if f.rate > floodRate {
+		stretched := time.Duration(float64(interval) * math.Sqrt(f.rate/floodRate))
+		if stretched > floodFrameInterval {
+			stretched = floodFrameInterval
+		}
+		if stretched > interval {
+			interval = stretched
+		}
+	}
+	f.stats.interval.Store(int64(interval))
+	return interval
+}
+
+// wait returns how long an update requested at now waits for its frame,
+// 0 to draw it at once
+func (f *frameScheduler) wait(now time.Time) time.Duration {
+	last := time.Unix(0, f.lastFrame.Load())
+	if wait := f.interval(now) - now.Sub(last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// holdForSync reports whether a frame should wait because the device is
+// halfway through a synchronized update (mode 2026), so its redraw shows
+// all at once. The wait ends with the update, or after
+// terminal.SyncTimeout if the update never ends.
+func (f *frameScheduler) holdForSync(emulator *terminal.TerminalEmulator, now time.Time) bool {
+	active, since := emulator.SynchronizedUpdate()
+	if !active || now.Sub(since) >= terminal.SyncTimeout {
+		return false
+	}
+	f.syncHeld.Store(true)
+	return true
+}
+
+// String summarizes the counters for the log
+func (s *frameStats) String() string {
+	return fmt.Sprintf("frames %d (%d immediate), requests %d (%d coalesced, %d dropped), %v apart at %s/s",
+		s.drawn.Load(), s.immediate.Load(), s.requested.Load(), s.coalesced.Load(), s.dropped.Load(),
+		time.Duration(s.interval.Load()).Round(time.Millisecond), formatByteSize(s.rate.Load()))
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/serial"
+)
+
+// DefaultFrameGap is the Modbus RTU gap, used when framing is turned on
+// from the menu without a gap given
+var DefaultFrameGap = FrameGap{Chars: 3.5}
+
+// FrameGap is the silence between bytes that starts a new frame, either a
+// fixed time or a number of character times at the current line settings
+type FrameGap struct {
+	Duration time.Duration
+	Chars    float64
+}
+
+// ParseFrameGap reads a gap such as "5ms" or "3.5c" (character times)
+func ParseFrameGap(value string) (FrameGap, error) {
+	value = strings.TrimSpace(value)
+	if number, ok := strings.CutSuffix(value, "c"); ok {
+		chars, err := strconv.ParseFloat(number, 64)
+		if err != nil || chars <= 0 {
+			return FrameGap{}, fmt.Errorf("invalid frame gap %q: want a positive number of characters, e.g. 3.5c", value)
+		}
+		return FrameGap{Chars: chars}, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return FrameGap{}, fmt.Errorf("invalid frame gap %q: want a duration such as 5ms or character times such as 3.5c", value)
+	}
+	return FrameGap{Duration: duration}, nil
+}
+
+// IsZero reports whether no gap is set, which turns framing off
+func (g FrameGap) IsZero() bool {
+	return g.Duration == 0 && g.Chars == 0
+}
+
+// String formats the gap as ParseFrameGap reads it
+func (g FrameGap) String() string {
+	if g.Chars > 0 {
+		return strconv.FormatFloat(g.Chars, 'f', -1, 64) + "c"
+	}
+	return g.Duration.String()
+}
+
+// For returns the gap as a time at the given line settings
+func (g FrameGap) For(config serial.SerialConfig) time.Duration {
+	if g.Chars > 0 {
+		return time.Duration(g.Chars * float64(config.CharTime()))
+	}
+	return g.Duration
+}
+
+// framingState splits received data into frames on idle gaps between
+// reads, for binary protocols that delimit messages by silence. Only the
+// goroutine feeding the terminal uses the fields after gap; mu guards the
+// gap, which the menu changes.
+type framingState struct {
+	gap FrameGap
+	mu  sync.Mutex
+
+	last    time.Time // When the previous read returned
+	size    int       // Bytes in the current frame
+	count   int       // Frames seen
+	midLine bool      // The last data shown didn't end a line
+}
+
+// framingGap returns the gap in use, zero when framing is off
+func (app *Application) framingGap() FrameGap {
+	app.framing.mu.Lock()
+	defer app.framing.mu.Unlock()
+	return app.framing.gap
+}
+
+// setFramingGap turns framing on with a gap, or off with a zero gap
+func (app *Application) setFramingGap(gap FrameGap) {
+	app.framing.mu.Lock()
+	app.framing.gap = gap
+	app.framing.mu.Unlock()
+}
+
+// frameStarts returns the offsets in data where a new frame starts. A read
+// starts one when the line was idle for at least the gap before its first
+// byte: the time since the previous read returned, less the time its own
+// bytes took to arrive.
+func (app *Application) frameStarts(data []byte, chunks []rxChunk) []int {
+	gap := app.framingGap()
+	f := &app.framing
+	if gap.IsZero() {
+		f.last = time.Time{}
+		return nil
+	}
+	minIdle := gap.For(app.config.SerialConfig)
+	charTime := app.config.SerialConfig.CharTime()
+
+	var starts []int
+	start := 0
+	for _, chunk := range chunks {
+		length := chunk.end - start
+		idle := chunk.at.Sub(f.last) - time.Duration(length)*charTime
+		if f.last.IsZero() || idle >= minIdle {
+			if f.size > 0 {
+				app.logSerial("Frame %d: %d bytes, then %v idle", f.count, f.size, idle.Round(time.Microsecond))
+			}
+			f.count++
+			f.size = 0
+			starts = append(starts, start)
+		}
+		f.size += length
+		f.last = chunk.at
+		start = chunk.end
+	}
+	return starts
+}
+
+// splitFrames cuts data at frame starts, so each part begins a frame or
+// continues the one before
+func splitFrames(data []byte, starts []int) [][]byte {
+	parts := make([][]byte, 0, len(starts)+1)
+	prev := 0
+	for _, start := range starts {
+		if start > prev {
+			parts = append(parts, data[prev:start])
+		}
+		prev = start
+	}
+	return append(parts, data[prev:])
+}
+
+// framingStatus returns the status bar segment shown while framing is on
+func (app *Application) framingStatus() string {
+	gap := app.framingGap()
+	if gap.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" FRAME %s │", gap)
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"sterm/pkg/decoder"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// GPS dashboard layout and staleness
+const (
+	gpsDashboardWidth = 38
+	gpsStaleAfter     = 5 * time.Second
+)
+
+// gpsDashboard holds the receiver state built from NMEA sentences found by
+// the NMEA decoder. Sentences still go to the terminal and history as usual.
+type gpsDashboard struct {
+	fix     decoder.GPSFix
+	visible bool
+	mu      sync.Mutex
+}
+
+// updateGPS applies decoded NMEA sentences to the dashboard
+func (app *Application) updateGPS(frames []decoder.Frame) {
+	g := &app.gps
+	g.mu.Lock()
+	changed := false
+	for _, frame := range frames {
+		if frame.Decoder == "nmea" && frame.Valid() && g.fix.Apply(string(frame.Raw), frame.Time) {
+			changed = true
+		}
+	}
+	visible := g.visible
+	g.mu.Unlock()
+
+	if changed && visible {
+		app.forceRedraw()
+	}
+}
+
+// gpsDashboardVisible reports whether the dashboard is shown
+func (app *Application) gpsDashboardVisible() bool {
+	g := &app.gps
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.visible
+}
+
+// setGPSDashboardVisible shows or hides the dashboard. Showing it starts
+// the NMEA decoder and takes the place of the decoder panel and TX/RX view.
+func (app *Application) setGPSDashboardVisible(visible bool) error {
+	if visible {
+		if err := app.setDecoderEnabled("nmea", true); err != nil {
+			return err
+		}
+		app.setDecoderPanelVisible(false)
+		app.txrx.mu.Lock()
+		app.txrx.mode = txrxOff
+		app.txrx.mu.Unlock()
+	}
+
+	g := &app.gps
+	g.mu.Lock()
+	g.visible = visible
+	g.mu.Unlock()
+	app.forceRedraw()
+	return nil
+}
+
+// gpsDashboardLines formats the fix for the dashboard, starting with the
+// header. A fix that hasn't been updated for a while is marked stale.
+func gpsDashboardLines(fix decoder.GPSFix, now time.Time) (lines []string, stale bool) {
+	lines = []string{" GPS: " + fix.FixDescription()}
+	if fix.Updated.IsZero() {
+		return append(lines, "Enable NMEA output on the device"), false
+	}
+
+	row := func(label, format string, args ...any) {
+		lines = append(lines, fmt.Sprintf("%-11s"+format, append([]any{label}, args...)...))
+	}
+	dash := "–"
+
+	when := dash
+	if fix.UTC != "" {
+		when = fix.UTC + " UTC"
+		if fix.Date != "" {
+			when += " " + fix.Date
+		}
+	}
+	row("Time", "%s", when)
+
+	if fix.HasPosition {
+		row("Latitude", "%s", gpsCoordinate(fix.Latitude, "N", "S"))
+		row("Longitude", "%s", gpsCoordinate(fix.Longitude, "E", "W"))
+	} else {
+		row("Latitude", "%s", dash)
+		row("Longitude", "%s", dash)
+	}
+	if fix.HasAltitude {
+		row("Altitude", "%.1f m", fix.Altitude)
+	} else {
+		row("Altitude", "%s", dash)
+	}
+	if fix.HasMotion {
+		row("Speed", "%.1f kn  %.1f km/h", fix.SpeedKnots, fix.SpeedKnots*1.852)
+		row("Course", "%.1f°", fix.Course)
+	} else {
+		row("Speed", "%s", dash)
+		row("Course", "%s", dash)
+	}
+	row("Satellites", "%d used, %d in view", fix.SatsUsed, fix.SatsInView)
+	if fix.HDOP > 0 {
+		row("HDOP", "%.1f", fix.HDOP)
+	} else {
+		row("HDOP", "%s", dash)
+	}
+
+	age := now.Sub(fix.Updated)
+	stale = age > gpsStaleAfter
+	if stale {
+		row("Updated", "no data for %s", age.Truncate(time.Second))
+	} else {
+		row("Updated", "%s", fix.Updated.Format("15:04:05"))
+	}
+	return lines, stale
+}
+
+// gpsCoordinate formats decimal degrees with a hemisphere letter
+func gpsCoordinate(degrees float64, positive, negative string) string {
+	hemisphere := positive
+	if degrees < 0 {
+		hemisphere = negative
+	}
+	return fmt.Sprintf("%.5f° %s", math.Abs(degrees), hemisphere)
+}
+
+// drawGPSDashboard draws the dashboard in the top right corner of the
+// terminal
+func (app *Application) drawGPSDashboard(screenWidth, contentHeight int) {
+	if !app.gpsDashboardVisible() || contentHeight < 2 {
+		return
+	}
+
+	app.gps.mu.Lock()
+	fix := app.gps.fix
+	app.gps.mu.Unlock()
+	lines, stale := gpsDashboardLines(fix, time.Now())
+
+	width := min(screenWidth, gpsDashboardWidth)
+	left := screenWidth - width
+	headerStyle := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground).Bold(true)
+	style := tcell.StyleDefault
+	if stale {
+		style = style.Foreground(app.theme.error)
+	}
+
+	for i, line := range lines {
+		if i >= contentHeight {
+			break
+		}
+		if i == 0 {
+			app.drawPanelLine(left, i, width, line, headerStyle)
+		} else {
+			app.drawPanelLine(left, i, width, " "+line, style)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"fmt"
+
+	"sterm/pkg/menu"
+)
+
+// altKeyDescriptions describe the Alt+ actions for the help overlay
+var altKeyDescriptions = map[string]string{
+	"clear":           "Clear screen",
+	"clear-history":   "Clear scrollback history",
+	"reset":           "Reset terminal",
+	"reconnect":       "Reconnect",
+	"save":            "Save session to file",
+	"filter":          "Edit display filter",
+	"toggle-filter":   "Toggle display filter",
+	"screenshot":      "Screenshot of the visible screen",
+	"command-history": "Command history",
+	"bookmark":        "Add bookmark at current line",
+	"notifications":   "Recent notifications",
+	"live":            "Leave scroll mode and jump to live output",
+	"open-link":       "Label links on screen and open one",
+	"decoders":        "Show/hide decoded protocol frames",
+	"send-hex":        "Send hex bytes or escaped text",
+	"code-point":      "Send characters by Unicode code point",
+	"ascii-table":     "ASCII table and the keys that send control characters",
+	"input-lock":      "Lock/unlock keyboard input to the device",
+	"mark":            "Mark the time and show the interval since the last mark",
+	"literal-next":    "Send the next key to the device, even one sterm uses",
+	"passthrough":     "Send all keys to the device until pressed again",
+	"debug-overlay":   "Show/hide parser and renderer internals",
+}
+
+// helpKey is a key and what it does, for the fixed help sections
+type helpKey struct {
+	keys        string
+	description string
+}
+
+// Keys handled directly by the application rather than the shortcut manager
+var (
+	generalHelpKeys = []helpKey{
+		{"F8", "Pause/resume data flow"},
+		{"Ctrl+Q", "Exit"},
+		{"Shift+PgUp/Up", "Enter scroll mode"},
+	}
+
+	scrollHelpKeys = []helpKey{
+		{"j/k, Up/Down", "Scroll one line"},
+		{"d/u", "Scroll half a page"},
+		{"f/b, PgDn/PgUp", "Scroll one page"},
+		{"g, Home", "Jump to top"},
+		{"G, End", "Jump to bottom"},
+		{"[ / ]", "Previous/next bookmark"},
+		{"l", "Jump to live output"},
+		{"Esc/Enter/q", "Exit scroll mode"},
+	}
+
+	menuHelpKeys = []helpKey{
+		{"Up/Down", "Move selection"},
+		{"Right/Enter", "Open submenu or run item"},
+		{"Left/Esc", "Close submenu or menu"},
+	}
+)
+
+// helpLines builds the help text from the shortcuts that are currently
+// active, so rebound keys and custom shortcuts show up as they are
+func (app *Application) helpLines() []string {
+	var lines []string
+	section := func(title string, keys []helpKey) {
+		if len(keys) == 0 {
+			return
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, title)
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("  %-18s %s", k.keys, k.description))
+		}
+	}
+
+	section("General", generalHelpKeys)
+
+	if app.shortcuts != nil && app.config.EnableShortcuts && app.shortcuts.IsEnabled() {
+		listed := make(map[string]bool)
+		for _, k := range generalHelpKeys {
+			listed[k.keys] = true
+		}
+
+		var keys []helpKey
+		for _, entry := range app.shortcuts.HelpEntries() {
+			// Shortcuts without a handler do nothing in this session
+			if entry.Bound && !listed[entry.Keys] {
+				keys = append(keys, helpKey{entry.Keys, entry.Description})
+			}
+		}
+		section("Shortcuts", keys)
+	}
+
+	var altKeys []helpKey
+	for _, action := range altKeyActionNames() {
+		if label := app.keyLabel(action); label != "" {
+			altKeys = append(altKeys, helpKey{label, altKeyDescriptions[action]})
+		}
+	}
+	section("Alt Shortcuts", altKeys)
+
+	section("Scroll Mode", scrollHelpKeys)
+	section("Menu", menuHelpKeys)
+	return lines
+}
+
+// showHelp opens the keyboard shortcut overlay
+func (app *Application) showHelp() {
+	app.openDialog(menu.NewTextDialog(app.screen, "Keyboard Shortcuts", app.helpLines()))
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"sterm/pkg/history"
+	"sterm/pkg/menu"
+)
+
+// historyStreamState appends history entries to a file as they are
+// recorded. Entries are written from the goroutines that record them, so
+// mu guards the stream against being stopped from the menu meanwhile.
+type historyStreamState struct {
+	stream *history.Stream // Nil while off
+	mu     sync.Mutex
+}
+
+// startHistoryStream starts appending history to a file, first writing the
+// history already recorded if withExisting is set. Replaces any stream
+// already running.
+func (app *Application) startHistoryStream(path string, format history.FileFormat, withExisting bool) error {
+	stream, err := history.OpenStream(path, format)
+	if err != nil {
+		return fmt.Errorf("failed to start history stream: %w", err)
+	}
+
+	s := &app.historyStream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if withExisting && app.historyMgr != nil {
+		// Written with the lock held, so no new entry gets in ahead of them.
+		// The history reports writes once it is unlocked, so an entry
+		// written just before can follow them a second time.
+		if iterable, ok := app.historyMgr.(history.EntryIterator); ok {
+			err = stream.WriteSeq(iterable.All())
+		} else {
+			var entries []history.HistoryEntry
+			if entries, err = app.historyMgr.GetEntries(0, app.historyMgr.GetEntryCount()); err == nil {
+				err = stream.Write(entries...)
+			}
+		}
+		if err != nil {
+			stream.Close()
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	if s.stream != nil {
+		_ = s.stream.Close()
+	}
+	s.stream = stream
+	app.logDebug("Streaming history to %s", path)
+	return nil
+}
+
+// stopHistoryStream stops streaming history, returning the file it went to
+// ("" if none)
+func (app *Application) stopHistoryStream() (string, error) {
+	s := &app.historyStream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return "", nil
+	}
+	path := s.stream.Path()
+	err := s.stream.Close()
+	s.stream = nil
+	return path, err
+}
+
+// streamHistoryEntry is the history write callback, appending each entry
+// to the stream while one is running
+func (app *Application) streamHistoryEntry(entry history.HistoryEntry) {
+	s := &app.historyStream
+	s.mu.Lock()
+	if s.stream == nil {
+		s.mu.Unlock()
+		return
+	}
+	err := s.stream.Write(entry)
+	if err == nil {
+		s.mu.Unlock()
+		return
+	}
+	// Stop rather than fail on every write, e.g. with the disk full
+	path := s.stream.Path()
+	_ = s.stream.Close()
+	s.stream = nil
+	s.mu.Unlock()
+
+	app.logDebug("History stream to %s failed: %v", path, err)
+	app.notifyError("History stream stopped: %v", err)
+}
+
+// flushEvicted appends entries dropped from the history to the flush file.
+// The file is opened on the first eviction and kept open for the session,
+// so a .gz file is one gzip stream rather than one per eviction.
+func (app *Application) flushEvicted(entries []history.HistoryEntry) error {
+	s := &app.historyFlush
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		stream, err := history.OpenStream(app.config.HistoryFlushFile, app.config.HistoryFormat)
+		if err != nil {
+			return err
+		}
+		s.stream = stream
+	}
+	return s.stream.Write(entries...)
+}
+
+// closeHistoryFlush finishes the flush file if evicted history went to it
+func (app *Application) closeHistoryFlush() error {
+	s := &app.historyFlush
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return nil
+	}
+	err := s.stream.Close()
+	s.stream = nil
+	return err
+}
+
+// historyStreaming returns the file history is streamed to, "" if none
+func (app *Application) historyStreaming() string {
+	s := &app.historyStream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return ""
+	}
+	return s.stream.Path()
+}
+
+// promptHistoryStream stops a running history stream, or asks for a file
+// and format to start one, writing the history so far before new entries
+func (app *Application) promptHistoryStream() {
+	if path, err := app.stopHistoryStream(); path != "" {
+		if err != nil {
+			app.notifyError("Failed to close %s: %v", filepath.Base(path), err)
+			return
+		}
+		app.updateStatusMessage(fmt.Sprintf("History stream to %s stopped", path))
+		return
+	}
+
+	initial := fmt.Sprintf("history_%s.log", time.Now().Format("20060102_150405"))
+	app.openDialog(menu.NewFileDialog(app.screen, "Stream History To (appends)", menu.FileDialogSave, initial, func(path string) error {
+		names := make([]string, len(historyFormats))
+		selected := 0
+		for i, f := range historyFormats {
+			names[i] = f.name
+			if f.format == app.config.HistoryFormat {
+				selected = i
+			}
+		}
+
+		app.openDialog(menu.NewSelectDialog(app.screen, "History Format", names, selected, func(index int, _ string) error {
+			if err := app.startHistoryStream(path, historyFormats[index].format, true); err != nil {
+				return err
+			}
+			app.updateStatusMessage(fmt.Sprintf("Streaming history to %s", path))
+			return nil
+		}))
+		return nil
+	}))
+}
+
+// historyStreamStatus returns the status bar segment shown while history
+// is streamed to a file
+func (app *Application) historyStreamStatus() string {
+	if app.historyStreaming() == "" {
+		return ""
+	}
+	return " STREAM │"
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"sterm/pkg/serial"
+)
+
+const (
+	// deviceRetryInterval is how often a missing device is looked for,
+	// besides whenever a serial device is plugged in
+	deviceRetryInterval = time.Second
+
+	// deviceSettleDelay gives udev time to set a new device's permissions
+	// and by-id links after the kernel announces it
+	deviceSettleDelay = 300 * time.Millisecond
+)
+
+// deviceWaitState tracks waiting for the device to be plugged in, at
+// startup or after it was unplugged, to connect the moment it appears
+type deviceWaitState struct {
+	enabled  bool   // Wait instead of failing, and reconnect after unplugging
+	waiting  bool   // The device is missing and the port closed
+	watching bool   // watchForDevice is running
+	lastErr  string // Last failure to open the device, shown once
+	mu       sync.Mutex
+}
+
+// autoConnectEnabled reports whether missing devices are waited for
+func (app *Application) autoConnectEnabled() bool {
+	app.deviceWait.mu.Lock()
+	defer app.deviceWait.mu.Unlock()
+	return app.deviceWait.enabled
+}
+
+// setAutoConnect turns waiting for the device on or off
+func (app *Application) setAutoConnect(enabled bool) {
+	app.deviceWait.mu.Lock()
+	app.deviceWait.enabled = enabled
+	app.deviceWait.mu.Unlock()
+}
+
+// deviceWaiting reports whether the device is being waited for
+func (app *Application) deviceWaiting() bool {
+	app.deviceWait.mu.Lock()
+	defer app.deviceWait.mu.Unlock()
+	return app.deviceWait.waiting
+}
+
+// deviceName names the device being connected to, by its USB IDs for a
+// profile that matches on them
+func (app *Application) deviceName() string {
+	if usb := app.config.SerialConfig.USB; usb != nil {
+		return "USB " + usb.String()
+	}
+	return app.config.SerialConfig.Port
+}
+
+// canWaitForDevice reports whether the port is a device that is missing
+// and auto-connect is on, so it should be waited for
+func (app *Application) canWaitForDevice() bool {
+	return app.autoConnectEnabled() && serial.IsDevicePort(app.config.SerialConfig.Port) && !serial.DevicePresent(app.config.SerialConfig)
+}
+
+// waitForDevice starts waiting for the device with the port closed
+func (app *Application) waitForDevice() {
+	w := &app.deviceWait
+	w.mu.Lock()
+	w.waiting = true
+	w.lastErr = ""
+	start := !w.watching
+	w.watching = true
+	w.mu.Unlock()
+
+	app.cachedStatusLeft = ""
+	app.logSerial("Waiting for %s", app.deviceName())
+	if start {
+		app.wg.Add(1)
+		go app.watchForDevice()
+	}
+}
+
+// deviceUnplugged checks, after a read error, whether the device went
+// away; if it did and auto-connect is on, the port is closed until the
+// device comes back
+func (app *Application) deviceUnplugged() bool {
+	if !app.canWaitForDevice() {
+		return false
+	}
+	if app.serialPort.IsOpen() {
+		app.serialPort.Close()
+		app.notifyWarning("%s unplugged - waiting for it to come back", app.deviceName())
+	}
+	app.waitForDevice()
+	return true
+}
+
+// watchForDevice opens the device once it appears, trying whenever a
+// serial device is plugged in and every deviceRetryInterval in case the
+// event was missed
+func (app *Application) watchForDevice() {
+	defer app.wg.Done()
+	defer app.recoverPanic("watchForDevice")
+
+	watcher := serial.WatchHotplug()
+	defer watcher.Close()
+	ticker := time.NewTicker(deviceRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case event := <-watcher.Events():
+			if !event.Added {
+				continue
+			}
+			app.logSerial("Device plugged in: %s", event.Port)
+			select {
+			case <-app.ctx.Done():
+				return
+			case <-time.After(deviceSettleDelay):
+			}
+		case <-ticker.C:
+		}
+
+		if app.connectWaitingDevice() {
+			return
+		}
+	}
+}
+
+// connectWaitingDevice tries to open the device being waited for. It
+// returns true once waiting is over, also when the port was reopened some
+// other way, such as from the menu.
+func (app *Application) connectWaitingDevice() bool {
+	w := &app.deviceWait
+	w.mu.Lock()
+	if !w.waiting || app.serialPort.IsOpen() {
+		w.waiting = false
+		w.watching = false
+		w.mu.Unlock()
+		app.cachedStatusLeft = ""
+		return true
+	}
+	if !serial.DevicePresent(app.config.SerialConfig) {
+		w.mu.Unlock()
+		return false
+	}
+
+	if err := app.serialPort.Open(app.config.SerialConfig); err != nil {
+		// Often udev hasn't given access yet; try again on the next tick
+		report := err.Error() != w.lastErr
+		w.lastErr = err.Error()
+		w.mu.Unlock()
+		app.logSerial("Opening %s failed: %v", app.deviceName(), err)
+		if report {
+			app.notifyWarning("%s found but could not be opened: %v", app.deviceName(), err)
+		}
+		return false
+	}
+	w.waiting = false
+	w.watching = false
+	w.mu.Unlock()
+
+	app.portOpened()
+	app.cachedStatusLeft = ""
+	app.logSerial("Connected to %s", app.config.SerialConfig.Port)
+	app.updateStatusMessage("Connected to " + app.config.SerialConfig.Port)
+	app.pluginsConnected()
+	app.startAutoLogin()
+	return true
+}
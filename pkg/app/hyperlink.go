@@ -0,0 +1,79 @@
+package app
+
+import (
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// lineHyperlinks returns the OSC 8 links of a line, one for each run of
+// cells that are part of the same link
+func lineHyperlinks(row int, line []terminal.Cell) []Link {
+	var links []Link
+	var current *terminal.Hyperlink
+	for x, cell := range line {
+		link := cell.Hyperlink
+		if cell.Char == 0 && current != nil {
+			link = current // Wide character continuations belong to their character
+		}
+		switch {
+		case link == nil:
+			// Not part of a link
+		case current != nil && *link == *current:
+			links[len(links)-1].EndCol = x + 1
+		default:
+			links = append(links, Link{Row: row, StartCol: x, EndCol: x + 1, Text: link.URI})
+		}
+		current = link
+	}
+	return links
+}
+
+// overlapsLinks reports whether link shares any column with links on the
+// same row
+func overlapsLinks(links []Link, link Link) bool {
+	for _, l := range links {
+		if l.Row == link.Row && link.StartCol < l.EndCol && link.EndCol > l.StartCol {
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlinkAt returns the OSC 8 link at a screen position
+func (app *Application) hyperlinkAt(x, y int) (*terminal.Hyperlink, bool) {
+	lines := app.visibleLines()
+	if y < 0 || y >= len(lines) || x < 0 || x >= len(lines[y]) {
+		return nil, false
+	}
+	// On a wide character's continuation the link is on the character
+	for x > 0 && lines[y][x].Char == 0 {
+		x--
+	}
+	link := lines[y][x].Hyperlink
+	return link, link != nil
+}
+
+// handleHyperlinkMouse opens the OSC 8 link under the pointer on
+// Ctrl+click, and keeps the release of that click from the device. Mouse
+// events only reach sterm while its mouse is enabled; otherwise the host
+// terminal, which is given the links, handles clicks. Returns true if the
+// event was used.
+func (app *Application) handleHyperlinkMouse(ev *tcell.EventMouse) bool {
+	if app.linkClicked {
+		if ev.Buttons()&tcell.Button1 == 0 {
+			app.linkClicked = false
+		}
+		return true
+	}
+	if ev.Buttons() != tcell.Button1 || ev.Modifiers()&tcell.ModCtrl == 0 || app.selection.dragging {
+		return false
+	}
+	link, ok := app.hyperlinkAt(ev.Position())
+	if !ok {
+		return false
+	}
+	app.linkClicked = true
+	app.openLink(Link{Text: link.URI})
+	return true
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+
+	"sterm/pkg/menu"
+	"sterm/pkg/terminal"
+)
+
+// ignoredSequenceLines formats the escape sequences the emulator ignored
+// for the dialog, starting with a line on what they mean
+func ignoredSequenceLines(counts []terminal.SequenceCount, total int) []string {
+	if total == 0 {
+		return []string{"No escape sequences ignored this session."}
+	}
+	lines := []string{
+		fmt.Sprintf("%d escape sequences ignored this session, %d different.", total, len(counts)),
+		"The terminal doesn't know these or doesn't support what they ask for;",
+		"if the display looks wrong, one of them is the likely cause.",
+		"",
+	}
+	for _, count := range counts {
+		lines = append(lines, "  "+count.String())
+	}
+	return lines
+}
+
+// showIgnoredSequences shows the escape sequences ignored this session
+func (app *Application) showIgnoredSequences() {
+	counts, total := app.terminal.IgnoredSequences()
+	app.openDialog(menu.NewTextDialog(app.screen, "Ignored Escape Sequences", ignoredSequenceLines(counts, total)))
+}
+
+// ignoredSequenceSummary returns the most frequent ignored sequence and
+// how many others there are, like "17× CSI ?1004h ignored (+2 more)", for
+// the debug overlay; "none" if nothing was ignored
+func (app *Application) ignoredSequenceSummary() string {
+	counts, _ := app.terminal.IgnoredSequences()
+	if len(counts) == 0 {
+		return "none"
+	}
+	summary := counts[0].String()
+	if len(counts) > 1 {
+		summary += fmt.Sprintf(" (+%d more)", len(counts)-1)
+	}
+	return summary
+}
@@ -0,0 +1,35 @@
+package app
+
+// toggleInputLock blocks or unblocks keyboard input to the device, for
+// watching a production console without the risk of a stray keystroke.
+// Replies to terminal queries and plugin output still go through.
+func (app *Application) toggleInputLock() {
+	locked := !app.inputLocked.Load()
+	app.inputLocked.Store(locked)
+	app.cachedStatusLeft = "" // The lock is shown in the status bar
+	if app.mainMenu != nil {
+		// Keep the menu check mark in step when toggled with the shortcut
+		app.mainMenu.SetChecked(app.mainMenu.FindItemIndex("Input Lock"), locked)
+	}
+
+	if locked {
+		app.updateStatusMessage("Input locked: keystrokes are not sent")
+	} else {
+		app.updateStatusMessage("Input unlocked")
+	}
+	app.forceRedraw()
+}
+
+// inputBlocked reports whether typed input must not be sent, telling the
+// user why
+func (app *Application) inputBlocked() bool {
+	switch {
+	case app.config.Monitor:
+		app.updateStatusMessage("Monitor mode: input is not sent")
+		return true
+	case app.inputLocked.Load():
+		app.updateStatusMessage("Input locked: press " + app.keyLabel("input-lock") + " to unlock")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"sterm/pkg/menu"
+	"sterm/pkg/serial"
+)
+
+// breakDuration is how long Send Break holds the line low
+const breakDuration = 250 * time.Millisecond
+
+// modemController returns the port's modem line control, or nil if the
+// port has no modem lines (ptys, sockets, monitor mode)
+func (app *Application) modemController() serial.ModemController {
+	controller, _ := app.serialPort.(serial.ModemController)
+	return controller
+}
+
+// buildLineControlMenu creates the submenu for the DTR and RTS outputs,
+// break and the modem input lines. Both outputs start raised, as the port
+// opens them.
+func (app *Application) buildLineControlMenu() *menu.Menu {
+	lineMenu := menu.NewMenu("Line Control", app.screen)
+
+	lineMenu.AddCheckItem("DTR", "", true, func(checked bool) error {
+		app.logDebug("Menu: DTR %v", checked)
+		return app.setModemLine("DTR", checked, app.modemController().SetDTR)
+	})
+	lineMenu.AddCheckItem("RTS", "", true, func(checked bool) error {
+		app.logDebug("Menu: RTS %v", checked)
+		return app.setModemLine("RTS", checked, app.modemController().SetRTS)
+	})
+
+	lineMenu.AddSeparator()
+	lineMenu.AddItem("Send Break", "", func() error {
+		app.logDebug("Menu: Send Break")
+		if err := app.modemController().SendBreak(breakDuration); err != nil {
+			app.notifyError("Send break failed: %v", err)
+			return err
+		}
+		app.updateStatusMessage("Break sent")
+		return nil
+	})
+	lineMenu.AddItem("Show Modem Status", "", func() error {
+		status, err := app.modemController().ModemStatus()
+		if err != nil {
+			app.notifyError("Failed to read modem status: %v", err)
+			return err
+		}
+		app.updateStatusMessage(fmt.Sprintf("Modem lines active: %s", status))
+		return nil
+	})
+	return lineMenu
+}
+
+// setModemLine raises or lowers an output line and reports the result
+func (app *Application) setModemLine(name string, on bool, set func(bool) error) error {
+	if err := set(on); err != nil {
+		app.notifyError("Failed to set %s: %v", name, err)
+		return err
+	}
+	state := "lowered"
+	if on {
+		state = "raised"
+	}
+	app.updateStatusMessage(fmt.Sprintf("%s %s", name, state))
+	return nil
+}
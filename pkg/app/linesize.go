@@ -0,0 +1,40 @@
+package app
+
+import (
+	"sterm/pkg/terminal"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// doubleWidthRune returns the character drawn for r on a double-width or
+// double-height line: the fullwidth form of printable ASCII, which takes
+// two columns, and r itself otherwise
+func doubleWidthRune(r rune) rune {
+	if r > ' ' && r <= '~' {
+		return r - '!' + '！'
+	}
+	return r
+}
+
+// renderDoubleCell draws a cell of a double-width or double-height line in
+// columns 2x and 2x+1. Both halves of double-height text show the
+// characters double width, the closest a character terminal gets. Cells in
+// the right half of the line have no room and aren't shown.
+func (app *Application) renderDoubleCell(x, y int, cell terminal.Cell) {
+	width, _ := app.screen.Size()
+	column := 2 * x
+	if column >= width {
+		return
+	}
+
+	style := cellStyle(cell)
+	r := doubleWidthRune(cell.Char)
+	if cell.Char == 0 {
+		// The right half of a wide character, itself drawn four columns wide
+		r = ' '
+	}
+	app.screen.SetContent(column, y, r, cell.Combining(), style)
+	if runewidth.RuneWidth(r) < 2 && column+1 < width {
+		app.screen.SetContent(column+1, y, ' ', nil, style)
+	}
+}
@@ -0,0 +1,284 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Link is a URL or file path found on screen, or an OSC 8 link
+type Link struct {
+	Row      int    // Screen row
+	StartCol int    // First screen column
+	EndCol   int    // Screen column after the last character
+	Text     string // The URL or path; the URI for OSC 8 links
+	IsPath   bool
+}
+
+var (
+	// urlRegex matches URLs with an explicit scheme
+	urlRegex = regexp.MustCompile(`\b(?:https?|ftp|file)://[^\s<>"'` + "`" + `]+`)
+
+	// pathRegex matches absolute, home and relative file paths with at least
+	// one directory separator, optionally followed by :line
+	pathRegex = regexp.MustCompile(`(?:^|[\s(\['"=])((?:~|\.{1,2})?/[\w.\-+@/]*[\w\-+@](?::\d+)?)`)
+)
+
+// linkTrailingPunct is trimmed from the end of a match since it usually
+// belongs to the surrounding sentence
+const linkTrailingPunct = ".,;:!?'\")]}>"
+
+// hintAlphabet are the keys used for link hint labels, home row first
+const hintAlphabet = "asdfghjklqwertyuiopzxcvbnm"
+
+// linkHints is the state of link hint mode
+type linkHints struct {
+	links  []Link
+	labels []string
+	prompt *statusPrompt
+}
+
+// findLinks scans lines for URLs, and file paths if paths is set
+func findLinks(lines [][]terminal.Cell, paths bool) []Link {
+	var links []Link
+	for row, line := range lines {
+		// OSC 8 links come first; text inside them isn't matched again
+		hyperlinks := lineHyperlinks(row, line)
+		links = append(links, hyperlinks...)
+
+		text, cols := lineTextWithColumns(line)
+		if !strings.Contains(text, "://") && !(paths && strings.Contains(text, "/")) {
+			continue
+		}
+
+		var spans [][2]int
+		for _, m := range urlRegex.FindAllStringIndex(text, -1) {
+			spans = append(spans, [2]int{m[0], trimLinkEnd(text, m[0], m[1])})
+		}
+		for _, span := range spans {
+			if link := newLink(row, text, cols, span[0], span[1], false); !overlapsLinks(hyperlinks, link) {
+				links = append(links, link)
+			}
+		}
+
+		if !paths {
+			continue
+		}
+		for _, m := range pathRegex.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2], trimLinkEnd(text, m[2], m[3])
+			if overlapsSpan(spans, start, end) {
+				continue // Part of a URL
+			}
+			if link := newLink(row, text, cols, start, end, true); !overlapsLinks(hyperlinks, link) {
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// lineTextWithColumns converts a line to a string and returns the screen
+// column of every byte that starts a character
+func lineTextWithColumns(line []terminal.Cell) (string, []int) {
+	var sb strings.Builder
+	var cols []int
+	for x, cell := range line {
+		if cell.Char == 0 {
+			continue // Wide character continuation
+		}
+		n, _ := sb.WriteString(cell.String())
+		for i := 0; i < n; i++ {
+			cols = append(cols, x)
+		}
+	}
+	return sb.String(), cols
+}
+
+// newLink creates a link from a byte range of a line
+func newLink(row int, text string, cols []int, start, end int, isPath bool) Link {
+	return Link{
+		Row:      row,
+		StartCol: cols[start],
+		EndCol:   cols[end-1] + 1,
+		Text:     text[start:end],
+		IsPath:   isPath,
+	}
+}
+
+// trimLinkEnd drops trailing punctuation from a match, keeping a closing
+// parenthesis if the link itself contains the opening one
+func trimLinkEnd(text string, start, end int) int {
+	for end > start && strings.ContainsRune(linkTrailingPunct, rune(text[end-1])) {
+		if text[end-1] == ')' && strings.Count(text[start:end], "(") >= strings.Count(text[start:end], ")") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// overlapsSpan reports whether [start, end) overlaps any of spans
+func overlapsSpan(spans [][2]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && end > s[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// hintLabels returns n unique labels. All labels have the same length so
+// none is a prefix of another.
+func hintLabels(n int) []string {
+	alphabet := []rune(hintAlphabet)
+	length := 1
+	for capacity := len(alphabet); capacity < n; capacity *= len(alphabet) {
+		length++
+	}
+
+	labels := make([]string, n)
+	for i := range labels {
+		label := make([]rune, length)
+		for j, v := length-1, i; j >= 0; j-- {
+			label[j] = alphabet[v%len(alphabet)]
+			v /= len(alphabet)
+		}
+		labels[i] = string(label)
+	}
+	return labels
+}
+
+// underlineLinks redraws the cells of links on screen with an underline
+func (app *Application) underlineLinks(buffer [][]terminal.Cell, contentHeight int) {
+	if !app.links.Underline || len(buffer) == 0 {
+		return
+	}
+	for _, link := range findLinks(buffer[:min(contentHeight, len(buffer))], app.links.Paths) {
+		line := buffer[link.Row]
+		for x := link.StartCol; x < link.EndCol && x < len(line); x++ {
+			if line[x].Char == 0 {
+				continue
+			}
+			app.screen.SetContent(x, link.Row, line[x].Char, line[x].Combining(), cellStyle(line[x]).Underline(true))
+		}
+	}
+}
+
+// drawLinkHints draws the hint labels over the links while hint mode is open
+func (app *Application) drawLinkHints() {
+	hints := app.linkHints
+	if hints == nil || app.prompt != hints.prompt {
+		return
+	}
+
+	typed := string(hints.prompt.input)
+	style := tcell.StyleDefault.Background(tcell.ColorYellow).Foreground(tcell.ColorBlack).Bold(true)
+	for i, link := range hints.links {
+		label := hints.labels[i]
+		if !strings.HasPrefix(label, typed) {
+			continue
+		}
+		for j, ch := range label {
+			app.screen.SetContent(link.StartCol+j, link.Row, ch, nil, style)
+		}
+	}
+}
+
+// openLinkHints labels the links on screen and opens the one whose label is typed
+func (app *Application) openLinkHints() {
+	lines := app.visibleLines()
+	_, screenHeight := app.screen.Size()
+	lines = lines[:min(len(lines), screenHeight-1)]
+
+	links := findLinks(lines, app.links.Paths)
+	if len(links) == 0 {
+		app.updateStatusMessage("No links on screen")
+		return
+	}
+
+	hints := &linkHints{links: links, labels: hintLabels(len(links))}
+	hints.prompt = newStatusPrompt("Open link (type hint): ", "", nil)
+	hints.prompt.onKey = func(ev *tcell.EventKey) bool {
+		if ev.Key() != tcell.KeyRune {
+			return false
+		}
+		typed := string(hints.prompt.input) + string(ev.Rune())
+		for i, label := range hints.labels {
+			if label == typed {
+				app.closePrompt()
+				app.openLink(hints.links[i])
+				return true
+			}
+			if strings.HasPrefix(label, typed) {
+				hints.prompt.input = []rune(typed)
+				return true
+			}
+		}
+		return true // Ignore keys that match no label
+	}
+
+	app.linkHints = hints
+	app.openPrompt(hints.prompt)
+}
+
+// openLink opens a URL in the browser or a file with its default application
+func (app *Application) openLink(link Link) {
+	target := link.Text
+	if link.IsPath {
+		target = linkFilePath(target)
+		if _, err := os.Stat(target); err != nil {
+			app.notifyError("Cannot open %s: %v", target, err)
+			return
+		}
+	}
+
+	cmd := linkOpenCommand(app.links.Opener, target)
+	if err := cmd.Start(); err != nil {
+		app.notifyError("Failed to open %s: %v", target, err)
+		return
+	}
+	// Reap the opener in the background; browsers usually detach anyway
+	go func() { _ = cmd.Wait() }()
+
+	app.logDebug("Opened link %s with %s", target, cmd.Path)
+	app.updateStatusMessage(fmt.Sprintf("Opening %s", target))
+}
+
+// linkFilePath converts a detected path to a file name, dropping any :line
+// suffix and expanding ~
+func linkFilePath(path string) string {
+	if i := strings.LastIndexByte(path, ':'); i > 0 && strings.Trim(path[i+1:], "0123456789") == "" {
+		path = path[:i]
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// linkOpenCommand returns the command that opens target with the configured
+// opener or the system default
+func linkOpenCommand(opener, target string) *exec.Cmd {
+	if args := strings.Fields(opener); len(args) > 0 {
+		return exec.Command(args[0], append(args[1:], target)...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", target)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		return exec.Command("xdg-open", target)
+	}
+}
@@ -0,0 +1,28 @@
+package app
+
+import "github.com/gdamore/tcell/v2"
+
+// armLiteralNext makes the next key go to the device as is, for keys the
+// remote program needs that sterm would otherwise act on itself, such as
+// F1 or Ctrl+Q. Pressing the binding again cancels it.
+func (app *Application) armLiteralNext() {
+	app.literalNext = !app.literalNext
+	if app.literalNext {
+		app.updateStatusMessage("Next key is sent to the device as is")
+	} else {
+		app.updateStatusMessage("Literal key cancelled")
+	}
+}
+
+// sendLiteralKey sends a key armed with armLiteralNext to the device,
+// skipping sterm's own shortcuts. Returns false if no key was armed.
+func (app *Application) sendLiteralKey(ev *tcell.EventKey) bool {
+	if !app.literalNext {
+		return false
+	}
+	app.literalNext = false
+
+	app.logDebug("Literal key %v", ev.Name())
+	app.sendKey(ev)
+	return true
+}
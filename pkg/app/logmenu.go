@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"sterm/pkg/logging"
+	"sterm/pkg/menu"
+)
+
+// buildLoggingMenu creates the logging submenu: a radio group for the level,
+// a check item per module and the output format, which last until the
+// settings file is reloaded, and the escape sequence trace and summary.
+func (app *Application) buildLoggingMenu() *menu.Menu {
+	loggingMenu := menu.NewMenu("Logging", app.screen)
+
+	current := app.log.Level()
+	if !app.log.IsOpen() {
+		current = logging.LevelOff
+	}
+	for level := logging.LevelOff; level >= logging.LevelDebug; level-- {
+		label := strings.ToUpper(level.String()[:1]) + level.String()[1:]
+		loggingMenu.AddRadioItem("log-level", label, level == current, func() error {
+			app.setLogLevel(level)
+			app.logDebug("Menu: Log level %s", level)
+			if level == logging.LevelOff {
+				app.updateStatusMessage("Logging off")
+			} else {
+				app.updateStatusMessage(fmt.Sprintf("Logging %s and above to %s", level, logging.DefaultPath()))
+			}
+			return nil
+		})
+	}
+
+	loggingMenu.AddSeparator()
+	for _, module := range logging.Modules {
+		loggingMenu.AddCheckItem("Module: "+string(module), "", app.log.ModuleEnabled(module), func(checked bool) error {
+			app.log.SetModuleEnabled(module, checked)
+			app.logDebug("Menu: Log module %s enabled=%v", module, checked)
+			return nil
+		})
+	}
+
+	loggingMenu.AddSeparator()
+	loggingMenu.AddCheckItem("JSON Output", "", app.log.JSON(), func(checked bool) error {
+		app.log.SetJSON(checked)
+		return nil
+	})
+
+	loggingMenu.AddSeparator()
+	loggingMenu.AddCheckItem("Trace Escape Sequences", "", app.tracing() != "", func(checked bool) error {
+		app.logDebug("Menu: Trace escape sequences %v", checked)
+		app.setTracing(checked)
+		return nil
+	})
+	loggingMenu.AddItem("Ignored Escape Sequences...", "", func() error {
+		app.logDebug("Menu: Ignored escape sequences")
+		app.hideMainMenu()
+		app.showIgnoredSequences()
+		return nil
+	})
+	loggingMenu.AddItem("Stop Capture", "", func() error {
+		app.stopTriggerCapture()
+		return nil
+	})
+	return loggingMenu
+}
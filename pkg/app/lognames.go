@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sterm/pkg/config"
+)
+
+// Kinds of log file, substituted for {kind} in the name template
+const (
+	logKindSession = "session" // Screen and scrollback text saved on request
+	logKindHistory = "history" // Raw history saved on request
+	logKindCapture = "capture" // History saved automatically at exit, or a trigger capture
+	logKindPrint   = "print"   // What the device sent to the terminal's printer
+)
+
+// expandLogName fills in the {placeholders} of a file name template.
+// Unknown placeholders are left as they are.
+func expandLogName(template string, values map[string]string) string {
+	return expandPlaceholders(template, values, sanitizeFileName)
+}
+
+// expandPlaceholders fills in the {placeholders} of a template with values
+// passed through escape. Unknown placeholders are left as they are.
+func expandPlaceholders(template string, values map[string]string, escape func(string) string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template[max(start, 0):], '}') + max(start, 0)
+		if start < 0 || end < start {
+			sb.WriteString(template)
+			return sb.String()
+		}
+		sb.WriteString(template[:start])
+		if value, ok := values[template[start+1:end]]; ok {
+			sb.WriteString(escape(value))
+		} else {
+			sb.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+}
+
+// sanitizeFileName replaces characters that can't appear in a file name
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// logNameValues returns the placeholder values for a log file of a kind
+func (app *Application) logNameValues(kind string, now time.Time) map[string]string {
+	cfg := app.config.SerialConfig
+	port := filepath.Base(cfg.Port) // /dev/ttyUSB0 -> ttyUSB0
+	profile := app.config.ProfileName
+	if profile == "" {
+		profile = port
+	}
+	return map[string]string{
+		"kind":    kind,
+		"port":    port,
+		"baud":    strconv.Itoa(cfg.BaudRate),
+		"profile": profile,
+		"date":    now.Format("20060102"),
+		"time":    now.Format("150405"),
+	}
+}
+
+// logFilePath returns where to save a log file of a kind, following the
+// logging directory and name template settings. The directory is created
+// if needed.
+func (app *Application) logFilePath(kind, ext string) (string, error) {
+	template := app.logging.NameTemplate
+	if template == "" {
+		template = config.DefaultSettings().Logging.NameTemplate
+	}
+	name := expandLogName(template, app.logNameValues(kind, time.Now())) + ext
+
+	dir := app.logging.Directory
+	if dir == "" {
+		return name, nil
+	}
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[2:])
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return filepath.Join(dir, name), nil
+}
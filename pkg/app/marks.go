@@ -0,0 +1,103 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// markState times the intervals between marks set with a hotkey, such as
+// from power-on to the login prompt across several boots
+type markState struct {
+	last      time.Time     // Time of the latest mark, zero before the first
+	count     int           // Marks set
+	delta     time.Duration // Between the last two marks
+	min, max  time.Duration
+	total     time.Duration // Sum of the intervals, for the average
+	intervals int
+	mu        sync.Mutex
+}
+
+// mark records a mark at t and returns the interval since the previous
+// one, or false for the first mark
+func (m *markState) mark(t time.Time) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count++
+	previous := m.last
+	m.last = t
+	if previous.IsZero() {
+		return 0, false
+	}
+
+	delta := t.Sub(previous)
+	m.delta = delta
+	if m.intervals == 0 || delta < m.min {
+		m.min = delta
+	}
+	if delta > m.max {
+		m.max = delta
+	}
+	m.total += delta
+	m.intervals++
+	return delta, true
+}
+
+// reset forgets all marks
+func (m *markState) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last, m.count, m.delta = time.Time{}, 0, 0
+	m.min, m.max, m.total, m.intervals = 0, 0, 0, 0
+}
+
+// status returns the status bar segment for the marks: the last interval
+// and, from the second interval on, the minimum and average
+func (m *markState) status() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.count == 0:
+		return ""
+	case m.intervals == 0:
+		return " MARK 1 │"
+	case m.intervals == 1:
+		return fmt.Sprintf(" Δ %s │", formatElapsed(m.delta))
+	}
+	average := m.total / time.Duration(m.intervals)
+	return fmt.Sprintf(" Δ %s min %s avg %s (%d) │", formatElapsed(m.delta), formatElapsed(m.min), formatElapsed(average), m.intervals)
+}
+
+// formatElapsed formats an interval to the millisecond, e.g. "850ms",
+// "12.345s" or "1m02.500s"
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.3fs", d.Seconds())
+	}
+	minutes := d / time.Minute
+	return fmt.Sprintf("%dm%06.3fs", minutes, (d - minutes*time.Minute).Seconds())
+}
+
+// setMark records a mark now and shows the time since the previous one
+func (app *Application) setMark() {
+	delta, ok := app.marks.mark(time.Now())
+	if ok {
+		app.updateStatusMessage(fmt.Sprintf("Mark: %s since the previous mark", formatElapsed(delta)))
+	} else {
+		app.updateStatusMessage("Mark set: press " + app.keyLabel("mark") + " again to measure")
+	}
+	app.forceRedraw()
+}
+
+// resetMarks clears the marks and their statistics
+func (app *Application) resetMarks() {
+	app.marks.reset()
+	app.updateStatusMessage("Marks reset")
+	app.forceRedraw()
+}
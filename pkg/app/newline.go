@@ -0,0 +1,34 @@
+package app
+
+// newlineHandling reports whether every LF received also returns the
+// cursor to the first column, and every CR also moves it down a line
+func (app *Application) newlineHandling() (implicitCR, implicitLF bool) {
+	if app.terminal == nil {
+		return false, false
+	}
+	state := app.terminal.GetState()
+	return state.ImplicitCR, state.ImplicitLF
+}
+
+// setNewlineHandling changes how line endings received are shown until the
+// settings are reloaded, for the menu: a device whose lines drift right
+// like a staircase ends them with a bare LF and needs implicitCR, one that
+// keeps overwriting a single line ends them with a bare CR and needs
+// implicitLF
+func (app *Application) setNewlineHandling(implicitCR, implicitLF bool) {
+	if app.terminal == nil {
+		return
+	}
+	app.terminal.SetNewlineHandling(implicitCR, implicitLF)
+
+	switch {
+	case implicitCR && implicitLF:
+		app.updateStatusMessage("Every LF and every CR starts a new line")
+	case implicitCR:
+		app.updateStatusMessage("Every LF starts a new line (implicit CR)")
+	case implicitLF:
+		app.updateStatusMessage("Every CR starts a new line (implicit LF)")
+	default:
+		app.updateStatusMessage("Line endings shown as received")
+	}
+}
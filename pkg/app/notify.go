@@ -0,0 +1,240 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/logging"
+	"sterm/pkg/menu"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Severity is the importance of a notification
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the label shown in the notification history
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// logLevel returns the log level notifications of this severity are written at
+func (s Severity) logLevel() logging.Level {
+	switch s {
+	case SeverityWarning:
+		return logging.LevelWarn
+	case SeverityError:
+		return logging.LevelError
+	default:
+		return logging.LevelInfo
+	}
+}
+
+// defaultDuration returns how long a notification of this severity stays on
+// screen. Errors stay longer so they aren't missed.
+func (s Severity) defaultDuration() time.Duration {
+	switch s {
+	case SeverityWarning:
+		return 5 * time.Second
+	case SeverityError:
+		return 8 * time.Second
+	default:
+		return 3 * time.Second
+	}
+}
+
+// Notification is a message shown briefly in the status bar
+type Notification struct {
+	Message  string
+	Severity Severity
+	Time     time.Time
+	Duration time.Duration
+}
+
+// Expired reports whether the notification should no longer be shown
+func (n Notification) Expired(now time.Time) bool {
+	return now.Sub(n.Time) >= n.Duration
+}
+
+// Limits for the notification queue
+const (
+	maxActiveNotifications = 4   // Toasts stacked above the status bar, including the status bar one
+	maxNotificationHistory = 100 // Recent notifications kept for the history view
+)
+
+// NotificationQueue holds the notifications currently on screen and a history
+// of recent ones
+type NotificationQueue struct {
+	active  []Notification // Oldest first
+	history []Notification // Oldest first
+	mu      sync.Mutex
+}
+
+// NewNotificationQueue creates an empty notification queue
+func NewNotificationQueue() *NotificationQueue {
+	return &NotificationQueue{}
+}
+
+// Push adds a notification. A zero duration uses the severity's default.
+func (q *NotificationQueue) Push(n Notification) {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	if n.Duration <= 0 {
+		n.Duration = n.Severity.defaultDuration()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.active = append(q.active, n)
+	if len(q.active) > maxActiveNotifications {
+		q.active = q.active[len(q.active)-maxActiveNotifications:]
+	}
+
+	q.history = append(q.history, n)
+	if len(q.history) > maxNotificationHistory {
+		q.history = q.history[len(q.history)-maxNotificationHistory:]
+	}
+}
+
+// Prune removes expired notifications and reports whether any were removed
+func (q *NotificationQueue) Prune(now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	kept := q.active[:0]
+	for _, n := range q.active {
+		if !n.Expired(now) {
+			kept = append(kept, n)
+		}
+	}
+	removed := len(kept) != len(q.active)
+	q.active = kept
+	return removed
+}
+
+// Active returns the notifications still on screen at now, newest first
+func (q *NotificationQueue) Active(now time.Time) []Notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var active []Notification
+	for i := len(q.active) - 1; i >= 0; i-- {
+		if !q.active[i].Expired(now) {
+			active = append(active, q.active[i])
+		}
+	}
+	return active
+}
+
+// History returns recent notifications, newest first
+func (q *NotificationQueue) History() []Notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	history := make([]Notification, len(q.history))
+	for i, n := range q.history {
+		history[len(q.history)-1-i] = n
+	}
+	return history
+}
+
+// notify shows a notification in the status bar
+func (app *Application) notify(severity Severity, message string) {
+	app.notifications.Push(Notification{Message: message, Severity: severity})
+
+	// Force redraw to show the message. The terminal's screen isn't marked
+	// dirty, as notifications come from any goroutine and it isn't locked.
+	app.fullRedraw.Store(true)
+	app.updateDisplay()
+	// If menu is visible, also redraw it on top
+	if app.mainMenu != nil && app.mainMenu.IsVisible() {
+		app.mainMenu.Draw()
+	}
+	app.log.Logf(logging.ModuleApp, severity.logLevel(), "Status: %s", message)
+}
+
+// notifyError shows an error notification
+func (app *Application) notifyError(format string, args ...interface{}) {
+	app.notify(SeverityError, fmt.Sprintf(format, args...))
+}
+
+// notifyWarning shows a warning notification
+func (app *Application) notifyWarning(format string, args ...interface{}) {
+	app.notify(SeverityWarning, fmt.Sprintf(format, args...))
+}
+
+// severityColor returns the background used for a notification
+func (app *Application) severityColor(severity Severity) tcell.Color {
+	switch severity {
+	case SeverityWarning:
+		return app.theme.warning
+	case SeverityError:
+		return app.theme.error
+	default:
+		return app.theme.message
+	}
+}
+
+// drawToasts stacks older active notifications above the status bar, newest
+// at the bottom. The newest notification itself is shown in the status bar.
+func (app *Application) drawToasts(toasts []Notification, screenWidth, statusY int) {
+	for i, n := range toasts {
+		y := statusY - 1 - i
+		if y < 0 {
+			return
+		}
+
+		text := " " + n.Message + " "
+		if runewidth.StringWidth(text) > screenWidth {
+			text = runewidth.Truncate(text, screenWidth, "…")
+		}
+		style := tcell.StyleDefault.
+			Background(app.severityColor(n.Severity)).
+			Foreground(app.theme.foreground).
+			Bold(true)
+
+		x := screenWidth - runewidth.StringWidth(text)
+		for _, ch := range text {
+			app.screen.SetContent(x, y, ch, nil, style)
+			x += runewidth.RuneWidth(ch)
+		}
+	}
+}
+
+// openNotificationHistory shows recent notifications in a dialog
+func (app *Application) openNotificationHistory() {
+	history := app.notifications.History()
+	app.alertsSeen.Store(time.Now().UnixNano()) // Clears the title bar alerts
+	if len(history) == 0 {
+		app.updateStatusMessage("No notifications yet")
+		return
+	}
+
+	entries := make([]string, len(history))
+	for i, n := range history {
+		// Multi-line messages would break the list layout
+		message := strings.ReplaceAll(n.Message, "\n", " ")
+		entries[i] = fmt.Sprintf("%s %-5s %s", n.Time.Format("15:04:05"), n.Severity, message)
+	}
+	app.openDialog(menu.NewSelectDialog(app.screen, "Notifications", entries, 0, func(int, string) error {
+		return nil
+	}))
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// togglePassthrough turns keyboard passthrough on or off for this session.
+// While it is on every key goes to the device, the F1 menu, Ctrl+Q and the
+// other shortcuts included, except the passthrough binding itself.
+func (app *Application) togglePassthrough() {
+	on := !app.passthrough.Load()
+	app.passthrough.Store(on)
+	app.literalNext = false
+	app.cachedStatusLeft = "" // Passthrough is shown in the status bar
+	if app.mainMenu != nil {
+		// Keep the menu check mark in step when toggled with the shortcut
+		app.mainMenu.SetChecked(app.mainMenu.FindItemIndex("Keyboard Passthrough"), on)
+	}
+
+	if on {
+		app.updateStatusMessage("Keyboard passthrough: all keys go to the device, " + app.keyLabel("passthrough") + " to stop")
+	} else {
+		app.updateStatusMessage("Keyboard passthrough off")
+	}
+	app.forceRedraw()
+}
+
+// passthroughKey sends a key to the device while passthrough is on, or
+// turns passthrough off if it is the passthrough binding: the prefix and
+// its letter when a chord prefix is set. Returns false if passthrough is
+// off.
+func (app *Application) passthroughKey(ev *tcell.EventKey) bool {
+	if !app.passthrough.Load() {
+		return false
+	}
+	if app.chordPrefix != "" && app.shortcuts != nil {
+		switch app.shortcuts.ProcessChord(ev.Key(), ev.Modifiers()) {
+		case terminal.ChordPending:
+			return true
+		case terminal.ChordCommand:
+			if ev.Key() == tcell.KeyRune && app.resolveAltKey(ev.Rune()) == altKeyActions["passthrough"] {
+				app.togglePassthrough()
+				return true
+			}
+		}
+		app.sendKey(ev)
+		return true
+	}
+	if ev.Modifiers()&tcell.ModAlt != 0 && ev.Key() == tcell.KeyRune &&
+		app.resolveAltKey(ev.Rune()) == altKeyActions["passthrough"] {
+		app.togglePassthrough()
+		return true
+	}
+
+	app.sendKey(ev)
+	return true
+}
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sterm/pkg/config"
+	"sterm/pkg/menu"
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Paste preview limits
+const (
+	pastePreviewLines = 10
+	pastePreviewWidth = 72
+)
+
+// pasteLineEndings are the line endings the paste editor can send, by
+// settings name
+var pasteLineEndings = []struct {
+	name, label, ending string
+}{
+	{"cr", "CR", "\r"},
+	{"lf", "LF", "\n"},
+	{"crlf", "CRLF", "\r\n"},
+}
+
+// pasteLineDelays are the pauses after each line the paste editor offers, in
+// milliseconds. A configured delay that isn't listed is added.
+var pasteLineDelays = []int{0, 10, 50, 100, 250, 500, 1000}
+
+// pasteChunk is a line of an edited paste, posted to the UI loop when pastes
+// are sent with a delay after each line
+type pasteChunk struct {
+	data  []byte
+	lines int // Set on the last chunk: number of lines pasted
+}
+
+// pasteState collects a bracketed paste from the host terminal. It is only
+// touched by the input goroutine.
+type pasteState struct {
+	active   bool
+	data     []byte
+	composer terminal.RuneComposer
+}
+
+// handlePasteEvent starts or finishes collecting a paste. Pastes into an
+// open prompt, dialog or menu go to it as typed keys.
+func (app *Application) handlePasteEvent(ev *tcell.EventPaste) {
+	if ev.Start() {
+		if app.prompt != nil || app.dialog != nil || (app.mainMenu != nil && app.mainMenu.IsVisible()) {
+			return
+		}
+		app.paste.active = true
+		app.paste.data = app.paste.data[:0]
+		app.paste.composer.Reset()
+		return
+	}
+
+	if !app.paste.active {
+		return
+	}
+	app.paste.active = false
+	app.pasteText(append([]byte(nil), app.paste.data...))
+}
+
+// pasteKey adds a key from the host terminal's paste to the collected text
+func (app *Application) pasteKey(ev *tcell.EventKey) {
+	switch key := ev.Key(); {
+	case key == tcell.KeyRune:
+		r, ok := app.paste.composer.Add(ev.Rune())
+		if !ok {
+			return // First half of a character outside the BMP
+		}
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			app.paste.data = append(app.paste.data, 0x1B) // ESC parsed as Alt
+		}
+		app.paste.data = utf8.AppendRune(app.paste.data, r)
+	case key < 0x20 || key == 0x7F:
+		// Control keys have the values of the characters that produce them
+		app.paste.data = append(app.paste.data, byte(key))
+	}
+}
+
+// pasteText sends pasted text. If it is long or contains control characters
+// it is first opened in the paste editor, or previewed for confirmation when
+// the editor is turned off. Newlines are sent as CR like a typed Enter.
+func (app *Application) pasteText(data []byte) {
+	if len(data) == 0 || app.inputBlocked() {
+		return
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\r"))
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r"))
+
+	app.mu.RLock()
+	settings := app.pasteSettings
+	app.mu.RUnlock()
+
+	if !pasteNeedsConfirm(data, settings.ConfirmChars, settings.ConfirmControls) {
+		app.sendPaste(data)
+		return
+	}
+	if settings.Editor {
+		app.openPasteEditor(data, settings)
+		return
+	}
+
+	dialog := menu.NewConfirmDialog(app.screen, "Confirm Paste", pastePreview(data), "Send", func() error {
+		app.sendPaste(data)
+		return nil
+	})
+	dialog.SetOnCancel(func() { app.updateStatusMessage("Paste cancelled") })
+	app.openDialog(dialog)
+}
+
+// sendPaste sends pasted text, wrapped in bracketed paste markers when the
+// remote application asked for them
+func (app *Application) sendPaste(data []byte) {
+	if app.bracketedPaste() {
+		wrapped := append([]byte("\x1b[200~"), data...)
+		data = append(wrapped, "\x1b[201~"...)
+	}
+	app.sendUserData(data)
+}
+
+// bracketedPaste reports whether the remote application asked for pastes to
+// be wrapped in ESC[200~ and ESC[201~
+func (app *Application) bracketedPaste() bool {
+	return app.terminal != nil && app.terminal.GetState().BracketedPaste
+}
+
+// openPasteEditor shows a paste in an editor where it can be trimmed or
+// changed before sending, with a choice of line ending and of a pause after
+// each line for devices that drop input while busy
+func (app *Application) openPasteEditor(data []byte, settings config.PasteSettings) {
+	endings := menu.EditorOption{Label: "Line ending"}
+	for i, e := range pasteLineEndings {
+		endings.Values = append(endings.Values, e.label)
+		if e.name == settings.LineEnding {
+			endings.Selected = i
+		}
+	}
+
+	delays := pasteLineDelays
+	if !slices.Contains(delays, settings.LineDelayMS) {
+		delays = append(slices.Clone(delays), settings.LineDelayMS)
+		slices.Sort(delays)
+	}
+	delay := menu.EditorOption{Label: "Line delay"}
+	for i, ms := range delays {
+		delay.Values = append(delay.Values, strconv.Itoa(ms)+" ms")
+		if ms == settings.LineDelayMS {
+			delay.Selected = i
+		}
+	}
+
+	text := strings.ReplaceAll(string(data), "\r", "\n")
+	options := []menu.EditorOption{endings, delay}
+	dialog := menu.NewEditorDialog(app.screen, "Edit Paste", pastePreview(data)[0], text, options, "Send", func(text string, selected []int) error {
+		lines := strings.Split(text, "\n")
+		ending := pasteLineEndings[selected[0]].ending
+		app.sendPasteLines(lines, ending, time.Duration(delays[selected[1]])*time.Millisecond)
+		return nil
+	})
+	dialog.SetOnCancel(func() { app.updateStatusMessage("Paste cancelled") })
+	app.openDialog(dialog)
+}
+
+// sendPasteLines sends edited paste lines, pausing after each one if delay
+// is set. The pauses happen in the background so the UI stays responsive.
+func (app *Application) sendPasteLines(lines []string, ending string, delay time.Duration) {
+	chunks := pasteChunks(lines, ending, app.bracketedPaste())
+	if len(chunks) == 0 {
+		return
+	}
+	if delay == 0 {
+		app.sendUserData(bytes.Join(chunks, nil))
+		app.updateStatusMessage(fmt.Sprintf("Pasted %d lines", len(chunks)))
+		return
+	}
+
+	go func() {
+		for i, chunk := range chunks {
+			if i > 0 {
+				select {
+				case <-app.ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+			}
+			posted := pasteChunk{data: chunk}
+			if i == len(chunks)-1 {
+				posted.lines = len(chunks)
+			}
+			_ = app.screen.PostEvent(tcell.NewEventInterrupt(posted))
+		}
+	}()
+}
+
+// handlePasteChunk sends a line of a paste on the UI loop
+func (app *Application) handlePasteChunk(chunk pasteChunk) {
+	app.sendUserData(chunk.data)
+	if chunk.lines > 0 {
+		app.updateStatusMessage(fmt.Sprintf("Pasted %d lines", chunk.lines))
+	}
+}
+
+// pasteChunks splits edited paste lines into what is sent for each line: the
+// line and its ending, except for the last line, which gets no ending and is
+// dropped if empty. Bracketed paste markers go around the whole paste.
+func pasteChunks(lines []string, ending string, bracketed bool) [][]byte {
+	var chunks [][]byte
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			chunks = append(chunks, []byte(line+ending))
+		} else if line != "" {
+			chunks = append(chunks, []byte(line))
+		}
+	}
+	if bracketed && len(chunks) > 0 {
+		chunks[0] = append([]byte("\x1b[200~"), chunks[0]...)
+		last := len(chunks) - 1
+		chunks[last] = append(chunks[last], "\x1b[201~"...)
+	}
+	return chunks
+}
+
+// pasteNeedsConfirm reports whether a paste is longer than maxChars (0 for
+// no limit) or, with controls set, contains control characters other than
+// line breaks and tabs
+func pasteNeedsConfirm(data []byte, maxChars int, controls bool) bool {
+	if maxChars > 0 && utf8.RuneCount(data) > maxChars {
+		return true
+	}
+	return controls && len(pasteControls(data)) > 0
+}
+
+// pasteControls returns the control pictures of the unusual control
+// characters in a paste, once each in order of appearance
+func pasteControls(data []byte) []string {
+	var found []string
+	seen := make(map[byte]bool)
+	for _, b := range data {
+		if (b < 0x20 || b == 0x7F) && b != '\r' && b != '\t' && !seen[b] {
+			seen[b] = true
+			found = append(found, string(controlPicture(b)))
+		}
+	}
+	return found
+}
+
+// pastePreview describes a paste and shows its first lines with control
+// characters made visible
+func pastePreview(data []byte) []string {
+	lines := strings.Split(string(data), "\r")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1] // Trailing newline
+	}
+
+	summary := fmt.Sprintf("%d characters on %d lines", utf8.RuneCount(data), len(lines))
+	if controls := pasteControls(data); len(controls) > 0 {
+		summary += ", with control characters " + strings.Join(controls, " ")
+	}
+	preview := []string{summary, ""}
+
+	for i, line := range lines {
+		if i == pastePreviewLines {
+			preview = append(preview, fmt.Sprintf("  … %d more lines", len(lines)-i))
+			break
+		}
+		shown := []rune(strings.ReplaceAll(string(visibleText(line)), "\t", "␉"))
+		if len(shown) > pastePreviewWidth {
+			shown = append(shown[:pastePreviewWidth-1], '…')
+		}
+		preview = append(preview, "  "+string(shown))
+	}
+	return preview
+}
+
+// visibleText replaces control characters with their control pictures,
+// without the styling visibleControls adds for the terminal
+func visibleText(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		if r < 0x20 || r == 0x7F {
+			runes[i] = controlPicture(byte(r))
+		}
+	}
+	return runes
+}
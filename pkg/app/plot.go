@@ -0,0 +1,314 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/config"
+	"sterm/pkg/menu"
+	"sterm/pkg/plot"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Plot pane layout
+const (
+	plotMinHeight  = 6  // Pane rows, including the header
+	plotAxisWidth  = 10 // Columns for the value labels on the left
+	defaultPlotCap = 10000
+)
+
+// plotColors are the series colors, in order of appearance
+var plotColors = []tcell.Color{
+	tcell.ColorGreen, tcell.ColorYellow, tcell.ColorAqua,
+	tcell.ColorFuchsia, tcell.ColorRed, tcell.ColorBlue,
+}
+
+// plotState extracts numbers from received lines and keeps them for the
+// plot pane. Lines are split by the reader; the pane is drawn by the UI.
+type plotState struct {
+	patterns  []string // Current patterns, from settings or typed
+	settings  []string // Patterns from the settings file, to notice changes
+	extractor *plot.Extractor
+	recorder  *plot.Recorder
+	lines     lineSplitter // Partial received line, only touched by the reader
+	visible   bool
+	ascii     bool
+	mu        sync.Mutex
+}
+
+// configure applies the plot settings. Patterns typed from the menu stay
+// until the patterns in the settings file change.
+func (p *plotState) configure(settings config.PlotSettings) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ascii = settings.ASCII
+	limit := settings.Points
+	if limit == 0 {
+		limit = defaultPlotCap
+	}
+	if p.recorder == nil {
+		p.recorder = plot.NewRecorder(limit)
+	}
+
+	if slices.Equal(p.settings, settings.Patterns) {
+		return
+	}
+	p.settings = slices.Clone(settings.Patterns)
+	// Patterns were checked when the settings were loaded
+	if extractor, err := plot.NewExtractor(settings.Patterns); err == nil {
+		p.setExtractor(settings.Patterns, extractor)
+	}
+}
+
+// setExtractor switches to new patterns, dropping the old series. Called
+// with the lock held.
+func (p *plotState) setExtractor(patterns []string, extractor *plot.Extractor) {
+	p.patterns = slices.Clone(patterns)
+	p.extractor = extractor
+	if len(patterns) == 0 {
+		p.extractor = nil
+	}
+	if p.recorder == nil {
+		p.recorder = plot.NewRecorder(defaultPlotCap)
+	}
+	p.recorder.Reset()
+}
+
+// setPlotPattern plots the named groups of a pattern instead of the current
+// patterns, and shows the pane
+func (app *Application) setPlotPattern(pattern string) error {
+	extractor, err := plot.NewExtractor([]string{pattern})
+	if err != nil {
+		return err
+	}
+
+	p := &app.plot
+	p.mu.Lock()
+	p.setExtractor([]string{pattern}, extractor)
+	p.visible = true
+	p.mu.Unlock()
+	app.forceRedraw()
+	return nil
+}
+
+// checkPlot records the values in the lines completed by received text
+func (app *Application) checkPlot(text []byte) {
+	p := &app.plot
+	p.mu.Lock()
+	if p.extractor == nil {
+		p.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	added := false
+	for _, line := range p.lines.split(text) {
+		if values := p.extractor.Extract(line); len(values) > 0 {
+			p.recorder.Add(now, values)
+			added = true
+		}
+	}
+	visible := p.visible
+	p.mu.Unlock()
+
+	if added && visible {
+		app.forceRedraw()
+	}
+}
+
+// plotVisible reports whether the plot pane is shown
+func (app *Application) plotVisible() bool {
+	app.plot.mu.Lock()
+	defer app.plot.mu.Unlock()
+	return app.plot.visible
+}
+
+// setPlotVisible shows or hides the plot pane
+func (app *Application) setPlotVisible(visible bool) error {
+	p := &app.plot
+	p.mu.Lock()
+	if visible && p.extractor == nil {
+		p.mu.Unlock()
+		return fmt.Errorf("nothing to plot - set a pattern with Plot Values first")
+	}
+	p.visible = visible
+	p.mu.Unlock()
+	app.forceRedraw()
+	return nil
+}
+
+// clearPlot drops the recorded samples
+func (app *Application) clearPlot() {
+	p := &app.plot
+	p.mu.Lock()
+	if p.recorder != nil {
+		p.recorder.Reset()
+	}
+	p.mu.Unlock()
+	app.forceRedraw()
+}
+
+// exportPlotCSV writes the recorded samples to a CSV file
+func (app *Application) exportPlotCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	p := &app.plot
+	p.mu.Lock()
+	if p.recorder == nil {
+		p.recorder = plot.NewRecorder(defaultPlotCap)
+	}
+	err = p.recorder.WriteCSV(file)
+	samples := p.recorder.Len()
+	p.mu.Unlock()
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	app.updateStatusMessage(fmt.Sprintf("Exported %d samples to %s", samples, path))
+	return nil
+}
+
+// plotHeader describes the series with their latest values
+func plotHeader(names []string, samples []plot.Sample) string {
+	var sb strings.Builder
+	sb.WriteString(" Plot:")
+	for i, name := range names {
+		latest := "–"
+		for j := len(samples) - 1; j >= 0; j-- {
+			if v := samples[j].Value(i); !math.IsNaN(v) {
+				latest = strconv.FormatFloat(v, 'g', 6, 64)
+				break
+			}
+		}
+		fmt.Fprintf(&sb, "  ■ %s %s", name, latest)
+	}
+	if len(names) == 0 {
+		sb.WriteString("  waiting for matching lines")
+	}
+	return sb.String()
+}
+
+// drawPlot draws the plot pane over the bottom of the terminal: a header
+// with the latest values, then the chart with its range on the left
+func (app *Application) drawPlot(screenWidth, contentHeight int) {
+	height := max(plotMinHeight, contentHeight/3)
+	if !app.plotVisible() || contentHeight < height+2 || screenWidth <= plotAxisWidth+4 {
+		return
+	}
+
+	p := &app.plot
+	p.mu.Lock()
+	names := p.recorder.Names()
+	width := screenWidth - plotAxisWidth
+	samples := p.recorder.Samples(width * 2)
+	ascii := p.ascii
+	p.mu.Unlock()
+
+	series := make([]int, len(names))
+	for i := range series {
+		series[i] = i
+	}
+	chart := plot.Render(samples, series, width, height-1, ascii)
+
+	top := contentHeight - height
+	headerStyle := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground)
+	header := runewidth.FillRight(runewidth.Truncate(plotHeader(names, samples), screenWidth, "…"), screenWidth)
+	x, seriesIndex := 0, -1
+	for _, ch := range header {
+		style := headerStyle
+		if ch == '■' {
+			seriesIndex++
+			style = style.Foreground(plotColors[seriesIndex%len(plotColors)])
+		}
+		app.screen.SetContent(x, top, ch, nil, style)
+		x += runewidth.RuneWidth(ch)
+	}
+
+	axisStyle := tcell.StyleDefault.Foreground(app.theme.scroll)
+	for row := 0; row < height-1; row++ {
+		y := top + 1 + row
+		label := ""
+		switch row {
+		case 0:
+			label = strconv.FormatFloat(chart.Max, 'g', 6, 64)
+		case height - 2:
+			label = strconv.FormatFloat(chart.Min, 'g', 6, 64)
+		}
+		label = runewidth.FillLeft(runewidth.Truncate(label, plotAxisWidth-2, ""), plotAxisWidth-2) + " ┤"
+		col := 0
+		for _, ch := range label {
+			app.screen.SetContent(col, y, ch, nil, axisStyle)
+			col++
+		}
+		for i, ch := range chart.Cells[row] {
+			style := tcell.StyleDefault
+			if s := chart.Series[row][i]; s >= 0 {
+				style = style.Foreground(plotColors[s%len(plotColors)])
+			}
+			app.screen.SetContent(plotAxisWidth+i, y, ch, nil, style)
+		}
+	}
+}
+
+// promptPlotPattern asks for a regex whose named groups are plotted
+func (app *Application) promptPlotPattern() {
+	app.plot.mu.Lock()
+	initial := strings.Join(app.plot.patterns, "|")
+	app.plot.mu.Unlock()
+	if initial == "" {
+		initial = `temp=(?P<temp>[-0-9.]+)`
+	}
+
+	app.openDialog(menu.NewInputDialog(app.screen, "Plot Values", "Regex with named groups, one series per group:", initial, func(value string) error {
+		if err := app.setPlotPattern(value); err != nil {
+			return err
+		}
+		app.updateStatusMessage("Plotting " + value)
+		return nil
+	}))
+}
+
+// buildPlotMenu creates the plot submenu
+func (app *Application) buildPlotMenu() *menu.Menu {
+	plotMenu := menu.NewMenu("Plot", app.screen)
+	plotMenu.AddItem("Plot Values...", "", func() error {
+		app.hideMainMenu()
+		app.promptPlotPattern()
+		return nil
+	})
+	plotMenu.AddCheckItem("Show Plot", "", app.plotVisible(), func(checked bool) error {
+		app.logDebug("Menu: Plot visible=%v", checked)
+		if err := app.setPlotVisible(checked); err != nil {
+			app.notifyWarning("%v", err)
+			return err
+		}
+		return nil
+	})
+	plotMenu.AddItem("Clear Plot", "", func() error {
+		app.clearPlot()
+		app.updateStatusMessage("Plot cleared")
+		return nil
+	})
+	plotMenu.AddItem("Export CSV...", "", func() error {
+		app.hideMainMenu()
+		initial := fmt.Sprintf("plot_%s.csv", time.Now().Format("20060102_150405"))
+		app.openDialog(menu.NewFileDialog(app.screen, "Export Plot", menu.FileDialogSave, initial, app.exportPlotCSV))
+		return nil
+	})
+	return plotMenu
+}
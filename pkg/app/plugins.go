@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+
+	"sterm/pkg/menu"
+	"sterm/pkg/plugin"
+)
+
+// pluginHost gives plugins access to the session
+type pluginHost struct {
+	app *Application
+}
+
+// Send writes to the port in the device's encoding. Unlike typed input it
+// isn't echoed or recorded as a command line, since plugins run on the
+// reader goroutine.
+func (h pluginHost) Send(data []byte) {
+	if h.app.config.Monitor {
+		h.app.logDebug("Monitor mode: plugin output not sent")
+		return
+	}
+	h.app.writeToPort(h.app.encodeOutput(data))
+}
+
+// Notify shows a status bar message
+func (h pluginHost) Notify(message string) {
+	h.app.updateStatusMessage(message)
+}
+
+// Log writes to the debug log
+func (h pluginHost) Log(message string) {
+	h.app.logDebug("Plugin %s", message)
+}
+
+// loadPlugins runs the scripts in ~/.sterm/plugins. A plugin that fails to
+// load is reported and skipped.
+func (app *Application) loadPlugins() {
+	if app.config.DisablePlugins {
+		return
+	}
+	dir, err := plugin.DefaultDir()
+	if err != nil {
+		app.logDebug("Plugins not loaded: %v", err)
+		return
+	}
+
+	manager, errs := plugin.Load(dir, pluginHost{app: app})
+	for _, err := range errs {
+		app.notifyWarning("Failed to load %v", err)
+	}
+	for _, p := range manager.Plugins() {
+		app.logDebug("Loaded plugin %s from %s", p.Name, p.Path)
+	}
+	app.plugins = manager
+}
+
+// pluginsConnected runs the plugins' on_connect callbacks. They run on
+// their own goroutine since the port is opened with the application lock
+// held and callbacks may show notifications, which need it.
+func (app *Application) pluginsConnected() {
+	if len(app.plugins.Plugins()) == 0 {
+		return
+	}
+	port := app.config.SerialConfig.Port
+	go app.plugins.OnConnect(port)
+}
+
+// pluginStatus returns the plugins' status bar segments
+func (app *Application) pluginStatus() string {
+	var status string
+	for _, segment := range app.plugins.StatusSegments() {
+		status += " " + segment + " │"
+	}
+	return status
+}
+
+// buildPluginMenu creates the plugins submenu: the loaded plugins, whose
+// items show their state, and the menu items they added
+func (app *Application) buildPluginMenu() *menu.Menu {
+	pluginMenu := menu.NewMenu("Plugins", app.screen)
+
+	for _, p := range app.plugins.Plugins() {
+		pluginMenu.AddItem(p.Name, "", func() error {
+			if err := p.Err(); err != nil {
+				app.notifyError("%v", err)
+			} else {
+				app.updateStatusMessage(fmt.Sprintf("Plugin %s running from %s", p.Name, p.Path))
+			}
+			return nil
+		})
+	}
+
+	if items := app.plugins.MenuItems(); len(items) > 0 {
+		pluginMenu.AddSeparator()
+		for _, item := range items {
+			pluginMenu.AddItem(item.Label, "", func() error {
+				app.logDebug("Menu: Plugin %s: %s", item.Plugin, item.Label)
+				return item.Run()
+			})
+		}
+	}
+	return pluginMenu
+}
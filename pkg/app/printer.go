@@ -0,0 +1,190 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"sterm/pkg/config"
+	"sterm/pkg/menu"
+)
+
+// defaultPrinterLimit is how big the capture file may grow when
+// printer.max_bytes is 0
+const defaultPrinterLimit = 10 << 20
+
+// printerState captures what the device sends to the terminal's printer
+// (media copy: printer controller mode, auto print and print screen) to a
+// file. The file is opened when something is first printed and appended to.
+// Without a chosen file nothing is captured unless printer.capture is on, so
+// a device can't write files on its own.
+type printerState struct {
+	path     string   // Capture file; one named like other logs is chosen if empty
+	file     *os.File // Nil until something is printed
+	size     int64    // Size of the file, including what it held before
+	printed  int64    // Bytes written to the file
+	capture  bool     // Capture without a chosen file (printer.capture)
+	limit    int64    // Largest size of the file (printer.max_bytes)
+	reported bool     // Printed data was lost and this has been reported
+	mu       sync.Mutex
+}
+
+// configure applies the printer settings
+func (p *printerState) configure(settings config.PrinterSettings) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.capture = settings.Capture
+	p.limit = int64(settings.MaxBytes)
+	p.reported = false
+}
+
+// printerCapture returns the file printed data goes to, "" before anything
+// is printed unless one was chosen
+func (app *Application) printerCapture() string {
+	p := &app.printer
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.path
+}
+
+// setPrinterCapture makes printed data go to path from now on, closing the
+// previous file
+func (app *Application) setPrinterCapture(path string) error {
+	p := &app.printer
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	if p.file != nil {
+		err = p.file.Close()
+		p.file = nil
+	}
+	p.path = path
+	p.size = 0
+	p.printed = 0
+	p.reported = false
+	if err != nil {
+		return fmt.Errorf("failed to close printer capture: %w", err)
+	}
+	return nil
+}
+
+// printData is the terminal's printer callback, appending printed data to
+// the capture file up to its size limit. Called with the terminal locked,
+// so notifications, which redraw, are shown from another goroutine.
+func (app *Application) printData(data []byte) {
+	p := &app.printer
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.file == nil {
+		if p.path == "" && !p.capture {
+			app.printLost(errors.New("capture is off (turn on printer.capture or choose a Printer Capture File)"))
+			return
+		}
+		path := p.path
+		if path == "" {
+			var err error
+			if path, err = app.logFilePath(logKindPrint, ".txt"); err != nil {
+				app.printLost(err)
+				return
+			}
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			app.printLost(err)
+			return
+		}
+		p.size = 0
+		if info, err := file.Stat(); err == nil {
+			p.size = info.Size()
+		}
+		p.path = path
+		p.file = file
+		go app.updateStatusMessage(fmt.Sprintf("Device is printing - captured to %s", path))
+	}
+
+	limit := p.limit
+	if limit == 0 {
+		limit = defaultPrinterLimit
+	}
+	full := p.size+int64(len(data)) > limit
+	if full {
+		data = data[:max(0, int(limit-p.size))]
+	}
+	n, err := p.file.Write(data)
+	p.size += int64(n)
+	p.printed += int64(n)
+	if err != nil {
+		app.printLost(err)
+		return
+	}
+	if full {
+		app.printLost(fmt.Errorf("%s reached its %s limit (printer.max_bytes)", filepath.Base(p.path), formatByteSize(limit)))
+	}
+}
+
+// printLost reports printed data that was dropped, once until another file
+// is chosen or the settings change. Called with the printer locked.
+func (app *Application) printLost(err error) {
+	p := &app.printer
+	if p.reported {
+		return
+	}
+	p.reported = true
+	go app.notifyError("Printer output lost: %v", err)
+}
+
+// closePrinter closes the printer capture file
+func (app *Application) closePrinter() error {
+	p := &app.printer
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.Close()
+	p.file = nil
+	return err
+}
+
+// promptPrinterCapture asks for the file printed data is appended to
+func (app *Application) promptPrinterCapture() {
+	initial := app.printerCapture()
+	if initial == "" {
+		var err error
+		if initial, err = app.logFilePath(logKindPrint, ".txt"); err != nil {
+			app.notifyError("%v", err)
+			return
+		}
+	}
+	app.openDialog(menu.NewFileDialog(app.screen, "Printer Capture File (appends)", menu.FileDialogSave, initial, func(path string) error {
+		if err := app.setPrinterCapture(path); err != nil {
+			return err
+		}
+		app.updateStatusMessage(fmt.Sprintf("Printer output goes to %s", filepath.Base(path)))
+		return nil
+	}))
+}
+
+// printerStatus returns the status bar segment shown while the device is
+// printing: PRINTER with the amount captured while received data goes to
+// the capture file instead of the screen, AUTOPRINT while each line is
+// also printed
+func (app *Application) printerStatus() string {
+	if app.terminal == nil {
+		return ""
+	}
+	state := app.terminal.GetState()
+	switch {
+	case state.PrinterController:
+		p := &app.printer
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return fmt.Sprintf(" PRINTER %s │", formatByteSize(p.printed))
+	case state.AutoPrint:
+		return " AUTOPRINT │"
+	}
+	return ""
+}
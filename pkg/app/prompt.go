@@ -0,0 +1,88 @@
+package app
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// statusPrompt is a single-line text input shown in the status bar
+type statusPrompt struct {
+	label    string
+	input    []rune
+	onSubmit func(value string) error
+	onTab    func()                        // Optional handler for Tab key
+	onKey    func(ev *tcell.EventKey) bool // Optional handler run before default editing, returns true if handled
+}
+
+// newStatusPrompt creates a new status bar prompt with an initial value
+func newStatusPrompt(label, initial string, onSubmit func(string) error) *statusPrompt {
+	return &statusPrompt{
+		label:    label,
+		input:    []rune(initial),
+		onSubmit: onSubmit,
+	}
+}
+
+// Text returns the prompt as it should be displayed in the status bar
+func (p *statusPrompt) Text() string {
+	return " " + p.label + string(p.input) + "_ "
+}
+
+// openPrompt shows a prompt in the status bar, capturing keyboard input until closed
+func (app *Application) openPrompt(prompt *statusPrompt) {
+	app.mu.Lock()
+	app.prompt = prompt
+	app.mu.Unlock()
+	app.forceRedraw()
+}
+
+// closePrompt hides the status bar prompt
+func (app *Application) closePrompt() {
+	app.mu.Lock()
+	app.prompt = nil
+	app.mu.Unlock()
+	app.forceRedraw()
+}
+
+// handlePromptKey handles a key while the status bar prompt is open
+func (app *Application) handlePromptKey(ev *tcell.EventKey) {
+	p := app.prompt
+
+	if p.onKey != nil && p.onKey(ev) {
+		app.forceRedraw()
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		app.closePrompt()
+		return
+	case tcell.KeyEnter:
+		app.closePrompt()
+		if p.onSubmit != nil {
+			if err := p.onSubmit(string(p.input)); err != nil {
+				app.notify(SeverityError, err.Error())
+			}
+		}
+		return
+	case tcell.KeyTab:
+		if p.onTab != nil {
+			p.onTab()
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case tcell.KeyCtrlU:
+		p.input = p.input[:0]
+	case tcell.KeyRune:
+		p.input = append(p.input, ev.Rune())
+	}
+
+	app.forceRedraw()
+}
+
+// forceRedraw requests a full redraw of the screen on the next UI update
+func (app *Application) forceRedraw() {
+	app.fullRedraw.Store(true)
+	app.requestUIUpdate()
+}
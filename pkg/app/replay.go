@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"sterm/pkg/menu"
+	"sterm/pkg/serial"
+)
+
+// replayProgress reports the playback of a recording, ok false unless the
+// port is playing one
+func (app *Application) replayProgress() (serial.ReplayProgress, bool) {
+	controller, ok := app.serialPort.(serial.ReplayController)
+	if !ok {
+		return serial.ReplayProgress{}, false
+	}
+	return controller.ReplayProgress()
+}
+
+// promptReplaySpeed asks for a new speed for the recording being played
+func (app *Application) promptReplaySpeed() {
+	progress, ok := app.replayProgress()
+	if !ok {
+		app.notifyWarning("Not replaying a recording - use sterm replay FILE or a replay: port")
+		return
+	}
+
+	label := "Speed (2 = twice as fast, 0.5 = half, max = no gaps):"
+	app.openDialog(menu.NewInputDialog(app.screen, "Replay Speed", label, serial.FormatReplaySpeed(progress.Speed), func(value string) error {
+		speed, err := serial.ParseReplaySpeed(value)
+		if err != nil {
+			return err
+		}
+		if err := app.serialPort.(serial.ReplayController).SetReplaySpeed(speed); err != nil {
+			return err
+		}
+		app.updateStatusMessage("Replaying at " + serial.FormatReplaySpeed(speed))
+		return nil
+	}))
+}
+
+// replayStatus returns the status bar segment shown while a recording
+// plays: the speed and the recorded time reached
+func (app *Application) replayStatus() string {
+	progress, ok := app.replayProgress()
+	if !ok {
+		return ""
+	}
+	if progress.Done {
+		return fmt.Sprintf(" REPLAY done %s │", formatReplayTime(progress.Duration))
+	}
+	return fmt.Sprintf(" REPLAY %s %s/%s │", serial.FormatReplaySpeed(progress.Speed),
+		formatReplayTime(progress.Position), formatReplayTime(progress.Duration))
+}
+
+// formatReplayTime formats a position in a recording as m:ss, or h:mm:ss
+// for long recordings
+func formatReplayTime(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -7,6 +7,7 @@ import (
 	"syscall"
 	"time"
 
+	"sterm/pkg/config"
 	"sterm/pkg/serial"
 )
 
@@ -41,6 +42,7 @@ func (r *Runner) Run() error {
 		return fmt.Errorf("failed to create application: %w", err)
 	}
 	r.app = app
+	defer app.recoverPanic("main loop")
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
@@ -100,6 +102,19 @@ func (r *Runner) printSessionSummary() {
 	fmt.Printf("Duration: %v\n", duration)
 	fmt.Printf("Bytes Sent: %d\n", bytesSent)
 	fmt.Printf("Bytes Received: %d\n", bytesRecv)
+	if r.app.terminal != nil {
+		// Tells why the display may have looked wrong
+		if counts, total := r.app.terminal.IgnoredSequences(); total > 0 {
+			fmt.Printf("Ignored Escape Sequences: %d\n", total)
+			for i, count := range counts {
+				if i == 5 {
+					fmt.Printf("  ... and %d more\n", len(counts)-i)
+					break
+				}
+				fmt.Printf("  %s\n", count)
+			}
+		}
+	}
 	fmt.Printf("=====================\n")
 }
 
@@ -113,9 +128,30 @@ func (r *Runner) Stop() error {
 
 // AppOptions contains runtime options for the application
 type AppOptions struct {
-	SendWindowSize bool
-	TerminalType   string
-	DebugMode      bool
+	WindowSize       WindowSize // How the terminal size is reported to the device
+	SttyCommand      string     // Template typed at a shell prompt to tell it the size
+	TerminalType     string
+	DebugMode        bool
+	HistoryFlushFile string   // Append history evicted from memory to this file
+	ProfileName      string   // Saved configuration name, if connected through one
+	Encoding         string   // Character encoding of the device (empty = UTF-8)
+	Monitor          bool     // Read-only attach: never write to the port
+	Decoders         []string // Protocol decoders to run alongside the terminal
+	NoPlugins        bool     // Don't load plugins from ~/.sterm/plugins
+
+	AutoLogin []config.LoginStep // Profile's expect/send steps, run after connecting
+	Bridge    string             // TCP address to share the port on ("rfc2217:" prefix for RFC 2217)
+	Wait      bool               // Wait for the device to be plugged in, and reconnect after unplugging
+	FrameGap  FrameGap           // Split received data into frames on idle gaps (zero = off)
+	ReadTimes bool               // Keep when each read arrived in history
+
+	HistoryStream string // Append history to this file as it is recorded
+	PrinterFile   string // Append what the device prints to this file
+	TraceFile     string // Write every parsed escape sequence to this file
+	SuppressEcho  bool   // Hide the device's echo of sent data
+	MetaEightBit  bool   // Alt sets the high bit instead of sending ESC
+
+	StatusFormat config.StatusFormat // Profile's status bar templates
 }
 
 // RunInteractive runs the application in interactive mode with a UI
@@ -135,12 +171,29 @@ func RunInteractiveWithOptions(serialConfig serial.SerialConfig, opts AppOptions
 	appConfig.SerialConfig = serialConfig
 
 	// Apply options
-	appConfig.SendWindowSizeOnConnect = opts.SendWindowSize
-	appConfig.SendWindowSizeOnResize = opts.SendWindowSize
+	appConfig.WindowSize = opts.WindowSize
+	appConfig.SttyCommand = opts.SttyCommand
 	appConfig.DebugMode = opts.DebugMode
 	if opts.TerminalType != "" {
 		appConfig.TerminalType = opts.TerminalType
 	}
+	appConfig.HistoryFlushFile = opts.HistoryFlushFile
+	appConfig.ProfileName = opts.ProfileName
+	appConfig.Encoding = opts.Encoding
+	appConfig.Monitor = opts.Monitor
+	appConfig.Decoders = opts.Decoders
+	appConfig.DisablePlugins = opts.NoPlugins
+	appConfig.AutoLogin = opts.AutoLogin
+	appConfig.Bridge = opts.Bridge
+	appConfig.WaitForDevice = opts.Wait
+	appConfig.FrameGap = opts.FrameGap
+	appConfig.ReadTimestamps = opts.ReadTimes
+	appConfig.HistoryStreamFile = opts.HistoryStream
+	appConfig.PrinterFile = opts.PrinterFile
+	appConfig.TraceFile = opts.TraceFile
+	appConfig.SuppressEcho = opts.SuppressEcho
+	appConfig.MetaEightBit = opts.MetaEightBit
+	appConfig.StatusFormat = opts.StatusFormat
 
 	// Don't set fixed size - let the app detect from actual terminal
 	appConfig.TerminalWidth = 0
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"sterm/pkg/history"
+)
+
+const (
+	// rxRingSize is how much received data can wait for the screen, e.g.
+	// while paused, before reading stops and the device is held off by
+	// the driver's buffer and flow control. Must be a power of two.
+	rxRingSize = 1 << 20
+
+	// rxReadSize is the most a single read asks the port for
+	rxReadSize = 64 * 1024
+
+	// rxReadTimeout bounds each blocking read, only so the reader notices
+	// the port being closed or reopened; data is returned as it arrives
+	rxReadTimeout = time.Second
+
+	// rxBatchSize is how much data is handed to the terminal at once
+	rxBatchSize = 64 * 1024
+
+	// rxBatchMin and rxBatchDelay batch a stream of small reads: less
+	// than rxBatchMin waits up to rxBatchDelay for more before it is shown.
+	// Data after rxStreamGap of silence, such as an echoed keystroke, is
+	// shown at once.
+	rxBatchMin   = 4096
+	rxBatchDelay = 2 * time.Millisecond
+	rxStreamGap  = 10 * time.Millisecond
+
+	// rxIdleFlush is the silence after which the screen is brought up to
+	// date and decoders get to finish frames that end on a gap
+	rxIdleFlush = 100 * time.Millisecond
+)
+
+// rxRingStamps is how many reads the ring remembers the time of. Reading
+// pauses when that many are waiting, like when the bytes fill up.
+const rxRingStamps = 4096
+
+// rxRing is a lock-free ring buffer between the goroutine reading the port
+// (the only writer) and the one feeding the terminal (the only reader).
+// head and tail count bytes ever written and read, so their difference is
+// the data waiting and neither needs a lock. Each read is stamped with the
+// time it returned, so framing and history see when data arrived rather
+// than when the screen got to it.
+type rxRing struct {
+	buf  []byte
+	mask uint64
+	head atomic.Uint64 // Advanced by the reader goroutine
+	tail atomic.Uint64 // Advanced by the consumer
+
+	stamps    []rxStamp
+	stampHead atomic.Uint64 // Reads stamped, advanced after head
+	stampTail atomic.Uint64 // Reads taken
+
+	data  chan struct{} // Signalled when data is added
+	space chan struct{} // Signalled when data is taken
+}
+
+// rxStamp marks the end of a read in the byte stream and when it returned
+type rxStamp struct {
+	end uint64 // head after the read
+	at  time.Time
+}
+
+// rxChunk is one read's data within a batch: the bytes up to end, after
+// the previous chunk's end
+type rxChunk struct {
+	end int
+	at  time.Time
+}
+
+// newRxRing creates a ring holding size bytes, a power of two
+func newRxRing(size int) *rxRing {
+	return &rxRing{
+		buf:    make([]byte, size),
+		mask:   uint64(size - 1),
+		stamps: make([]rxStamp, rxRingStamps),
+		data:   make(chan struct{}, 1),
+		space:  make(chan struct{}, 1),
+	}
+}
+
+// free returns the contiguous free space to read the port into, up to the
+// end of the buffer; empty when the ring is full
+func (r *rxRing) free() []byte {
+	if r.stampHead.Load()-r.stampTail.Load() == uint64(len(r.stamps)) {
+		return nil
+	}
+	head, tail := r.head.Load(), r.tail.Load()
+	used := head - tail
+	if used == uint64(len(r.buf)) {
+		return nil
+	}
+	start := head & r.mask
+	end := start + uint64(len(r.buf)) - used
+	if end > uint64(len(r.buf)) {
+		end = uint64(len(r.buf))
+	}
+	return r.buf[start:end]
+}
+
+// commit adds n bytes written into free space, read at the given time
+func (r *rxRing) commit(n int, at time.Time) {
+	head := r.head.Add(uint64(n))
+	index := r.stampHead.Load()
+	r.stamps[index%uint64(len(r.stamps))] = rxStamp{end: head, at: at}
+	r.stampHead.Store(index + 1)
+	wakeUp(r.data)
+}
+
+// buffered returns how many bytes are waiting
+func (r *rxRing) buffered() int {
+	return int(r.head.Load() - r.tail.Load())
+}
+
+// read takes whole reads that fit in p, appending where each ends and when
+// it arrived to chunks. Reads are never longer than rxReadSize, so a p of
+// that size always gets at least one.
+func (r *rxRing) read(p []byte, chunks []rxChunk) (int, []rxChunk) {
+	tail := r.tail.Load()
+	end := tail
+	for index := r.stampTail.Load(); index < r.stampHead.Load(); index++ {
+		stamp := r.stamps[index%uint64(len(r.stamps))]
+		if stamp.end-tail > uint64(len(p)) {
+			break
+		}
+		end = stamp.end
+		chunks = append(chunks, rxChunk{end: int(end - tail), at: stamp.at})
+		r.stampTail.Store(index + 1)
+	}
+
+	n := int(end - tail)
+	if n == 0 {
+		return 0, chunks
+	}
+	start := tail & r.mask
+	copied := copy(p[:n], r.buf[start:])
+	copy(p[copied:n], r.buf)
+	r.tail.Store(end)
+	wakeUp(r.space)
+	return n, chunks
+}
+
+// wakeUp wakes whoever waits on a channel with a buffer of one, without
+// blocking when a wakeup is already pending
+func wakeUp(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// readSerial reads the port into the ring until the application stops.
+// Reads block until data arrives, so it is shown without waiting for a
+// poll interval, and the goroutine sleeps while the line is quiet.
+func (app *Application) readSerial(ring *rxRing) {
+	defer app.wg.Done()
+	defer app.recoverPanic("readSerial")
+
+	closedNotified := false
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		default:
+		}
+
+		// Nothing to read until the device is plugged in
+		if app.deviceWaiting() && !app.serialPort.IsOpen() {
+			if !app.sleepUnlessStopped(100 * time.Millisecond) {
+				return
+			}
+			continue
+		}
+
+		// Hold the device off while the screen catches up
+		space := ring.free()
+		if len(space) == 0 {
+			select {
+			case <-app.ctx.Done():
+				return
+			case <-ring.space:
+			}
+			continue
+		}
+		if len(space) > rxReadSize {
+			space = space[:rxReadSize]
+		}
+
+		app.serialPort.SetReadTimeout(rxReadTimeout)
+		n, err := app.serialPort.Read(space)
+		at := time.Now()
+		if err != nil && app.ctx.Err() != nil {
+			return // Closed on the way out
+		}
+		if errors.Is(err, io.EOF) {
+			// The other end went away for good (the shell exited); wait
+			// for a reconnect instead of spinning on the error
+			if !closedNotified {
+				closedNotified = true
+				app.logSerial("Connection closed: %v", err)
+				app.notifyWarning("Connection closed - reconnect from the F1 menu or press Ctrl+Q to exit")
+				app.forceImmediateUIUpdate()
+			}
+			if !app.sleepUnlessStopped(100 * time.Millisecond) {
+				return
+			}
+			continue
+		}
+		closedNotified = false
+		if err != nil && app.deviceUnplugged() {
+			app.forceImmediateUIUpdate()
+			continue
+		}
+		if err != nil {
+			// Closed for a reconnect or a settings change; don't spin
+			if !app.sleepUnlessStopped(10 * time.Millisecond) {
+				return
+			}
+			continue
+		}
+		if n > 0 {
+			ring.commit(n, at)
+		}
+	}
+}
+
+// sleepUnlessStopped waits for d, returning false if the application
+// stopped meanwhile
+func (app *Application) sleepUnlessStopped(d time.Duration) bool {
+	select {
+	case <-app.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// nextBatch takes up to len(batch) bytes from the ring, with the reads
+// they came in. In a stream, where the previous batch was taken at last,
+// it first waits briefly for small reads to build up. Returns no data if
+// the application stopped.
+func (app *Application) nextBatch(ring *rxRing, batch []byte, chunks []rxChunk, last time.Time) (int, []rxChunk) {
+	if ring.buffered() < rxBatchMin && time.Since(last) < rxStreamGap {
+		deadline := time.NewTimer(rxBatchDelay)
+	wait:
+		for ring.buffered() < rxBatchMin {
+			select {
+			case <-app.ctx.Done():
+				deadline.Stop()
+				return 0, chunks
+			case <-ring.data:
+			case <-deadline.C:
+				break wait
+			}
+		}
+		deadline.Stop()
+	}
+	return ring.read(batch, chunks)
+}
+
+// recordReceived saves received data starting at offset in a batch to
+// history, with the arrival time of each read in it when ReadTimestamps
+// is on
+func (app *Application) recordReceived(data []byte, chunks []rxChunk, offset int) {
+	if app.historyMgr == nil {
+		return
+	}
+	timed, ok := app.historyMgr.(history.TimedWriter)
+	if !app.config.ReadTimestamps || !ok {
+		_ = app.historyMgr.Write(data, history.DirectionOutput)
+		return
+	}
+
+	var times []history.ChunkTime
+	start := 0
+	for _, chunk := range chunks {
+		if chunk.end > offset && start < offset+len(data) {
+			times = append(times, history.ChunkTime{Offset: max(start-offset, 0), Time: chunk.at})
+		}
+		start = chunk.end
+	}
+	_ = timed.WriteTimed(data, history.DirectionOutput, times)
+}
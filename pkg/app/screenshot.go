@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sterm/pkg/terminal"
+)
+
+// visibleLines returns a copy of the lines currently shown on screen
+func (app *Application) visibleLines() [][]terminal.Cell {
+	var buffer [][]terminal.Cell
+	if app.terminal.IsScrolling() {
+		buffer = app.terminal.GetScrollbackView()
+	} else {
+		buffer = app.terminal.GetScreen().Buffer
+	}
+
+	lines := make([][]terminal.Cell, len(buffer))
+	for i, line := range buffer {
+		lines[i] = make([]terminal.Cell, len(line))
+		copy(lines[i], line)
+	}
+	return lines
+}
+
+// SaveScreenshot dumps the visible screen to a file. Files ending in .png are
+// rendered as images, anything else is written as ANSI text.
+func (app *Application) SaveScreenshot(filename string) error {
+	if app.terminal == nil {
+		return fmt.Errorf("terminal not initialized")
+	}
+
+	if filename == "" {
+		filename = fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
+	}
+
+	return app.writeScreenshot(filename, app.visibleLines())
+}
+
+// writeScreenshot writes captured lines to a file, choosing the format from the extension
+func (app *Application) writeScreenshot(filename string, lines [][]terminal.Cell) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(filename), ".png") {
+		err = terminal.WritePNGSnapshot(file, lines)
+	} else {
+		err = terminal.WriteANSISnapshot(file, lines)
+	}
+	if err != nil {
+		return err
+	}
+
+	app.logDebug("Screenshot saved to %s", filename)
+	return nil
+}
+
+// promptScreenshot asks for a screenshot filename, defaulting to a timestamped PNG
+func (app *Application) promptScreenshot() {
+	// Freeze the frame now; the prompt itself shouldn't end up in the image
+	lines := app.visibleLines()
+	defaultName := fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
+
+	app.openPrompt(newStatusPrompt("Screenshot (.png or .ans): ", defaultName, func(filename string) error {
+		if filename == "" {
+			return fmt.Errorf("screenshot cancelled: no filename")
+		}
+
+		if err := app.writeScreenshot(filename, lines); err != nil {
+			return err
+		}
+
+		app.updateStatusMessage(fmt.Sprintf("Screenshot saved to %s", filename))
+		return nil
+	}))
+}
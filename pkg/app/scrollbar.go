@@ -0,0 +1,92 @@
+package app
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Scrollbar glyphs, drawn in the rightmost column while in scroll mode
+const (
+	scrollbarTrack = '│'
+	scrollbarThumb = '█'
+)
+
+// scrollbarThumbRange returns the first row and height of the scrollbar thumb for
+// a track of the given height. position is the top visible line and lines is
+// the number of scrollback lines above the live screen, so position == lines
+// means the view is at the bottom.
+func scrollbarThumbRange(height, position, lines int) (start, size int) {
+	if height <= 0 {
+		return 0, 0
+	}
+	if lines <= 0 {
+		return 0, height
+	}
+
+	// The thumb covers the visible share of scrollback plus the live screen
+	size = max(1, height*height/(lines+height))
+	size = min(size, height)
+	start = (height - size) * position / lines
+	return max(0, min(height-size, start)), size
+}
+
+// scrollbarLine converts a row on the scrollbar track to a scrollback line
+// so that clicking the track jumps there
+func scrollbarLine(row, height, lines int) int {
+	if height <= 1 || lines <= 0 {
+		return 0
+	}
+	row = max(0, min(height-1, row))
+	return row * lines / (height - 1)
+}
+
+// drawScrollbar draws the scroll position indicator on the right edge
+func (app *Application) drawScrollbar(screenWidth, contentHeight int) {
+	if screenWidth <= 0 || contentHeight <= 0 {
+		return
+	}
+
+	_, lines := app.terminal.GetScrollPosition()
+	start, size := scrollbarThumbRange(contentHeight, app.terminal.GetTopLineIndex(), lines)
+
+	x := screenWidth - 1
+	trackStyle := tcell.StyleDefault.Foreground(app.theme.scroll)
+	thumbStyle := tcell.StyleDefault.Foreground(app.theme.scroll).Bold(true)
+	for y := 0; y < contentHeight; y++ {
+		if y >= start && y < start+size {
+			app.screen.SetContent(x, y, scrollbarThumb, nil, thumbStyle)
+		} else {
+			app.screen.SetContent(x, y, scrollbarTrack, nil, trackStyle)
+		}
+	}
+}
+
+// handleScrollbarMouse scrolls when the scrollbar is clicked or dragged.
+// Returns true if the event was used.
+func (app *Application) handleScrollbarMouse(ev *tcell.EventMouse) bool {
+	if !app.terminal.IsScrolling() {
+		app.scrollbarDrag = false
+		return false
+	}
+
+	x, y := ev.Position()
+	screenWidth, screenHeight := app.screen.Size()
+	contentHeight := screenHeight - 1
+
+	if ev.Buttons()&tcell.Button1 == 0 {
+		// Button released ends a drag
+		dragging := app.scrollbarDrag
+		app.scrollbarDrag = false
+		return dragging
+	}
+	if !app.scrollbarDrag {
+		if x != screenWidth-1 || y >= contentHeight {
+			return false
+		}
+		app.scrollbarDrag = true
+	}
+
+	_, lines := app.terminal.GetScrollPosition()
+	app.terminal.ScrollToLine(scrollbarLine(y, contentHeight, lines))
+	app.updateDisplay()
+	return true
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"sterm/pkg/terminal"
+)
+
+// moveScrolledRows shows a scroll of the terminal's rows by moving the rows
+// already in the screen buffer, like a hardware terminal's scroll region,
+// so only the rows that scrolled in are drawn. During continuous log output
+// that is one row a line instead of the whole screen. Returns false if the
+// rows have to be drawn again instead, because something else is drawn
+// over them.
+func (app *Application) moveScrolledRows(scroll terminal.RowScroll, contentHeight, notifications int) bool {
+	if scroll.Bottom >= contentHeight || !app.contentUncovered(notifications) {
+		return false
+	}
+
+	height := scroll.Bottom - scroll.Top + 1
+	if scroll.Lines >= height || -scroll.Lines >= height {
+		return true // Every row scrolled in and is dirty
+	}
+
+	width, _ := app.screen.Size()
+	moveRow := func(from, to int) {
+		for x := 0; x < width; x++ {
+			mainc, combc, style, _ := app.screen.GetContent(x, from)
+			app.screen.SetContent(x, to, mainc, combc, style)
+		}
+	}
+	if scroll.Lines > 0 {
+		for y := scroll.Top; y+scroll.Lines <= scroll.Bottom; y++ {
+			moveRow(y+scroll.Lines, y)
+		}
+	} else {
+		for y := scroll.Bottom; y+scroll.Lines >= scroll.Top; y-- {
+			moveRow(y+scroll.Lines, y)
+		}
+	}
+	return true
+}
+
+// contentUncovered reports whether the terminal's rows on screen show only
+// the terminal. Panels, toasts, menus and the selection are drawn at fixed
+// screen positions, so they would move with the rows.
+func (app *Application) contentUncovered(notifications int) bool {
+	hints := app.linkHints != nil && app.prompt == app.linkHints.prompt
+	menuVisible := app.mainMenu != nil && app.mainMenu.IsVisible()
+	return notifications <= 1 && !hints && !menuVisible && app.dialog == nil && app.selection.current == nil &&
+		!app.decoderPanelVisible() && !app.gpsDashboardVisible() && app.txrxMode() == txrxOff && !app.plotVisible() &&
+		!app.debugOverlay.visible.Load()
+}
@@ -0,0 +1,278 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// SelectionMode controls how a mouse selection grows
+type SelectionMode int
+
+const (
+	SelectChar  SelectionMode = iota // Character by character, wrapping across lines
+	SelectWord                       // Whole words (double-click)
+	SelectLine                       // Whole lines as sent, with the rows they wrapped onto (triple-click)
+	SelectBlock                      // Rectangle of columns (Alt+drag)
+)
+
+// multiClickInterval is the longest gap between clicks of a double/triple click
+const multiClickInterval = 400 * time.Millisecond
+
+// wordPunct are non-alphanumeric characters that count as part of a word so
+// double-clicking selects paths, URLs and hex values in one go
+const wordPunct = "_-./:~@%+#"
+
+// Selection is a mouse selection on the visible screen. Positions are screen
+// cells; the anchor is where the selection started and the head follows the mouse.
+type Selection struct {
+	Mode             SelectionMode
+	AnchorX, AnchorY int
+	HeadX, HeadY     int
+}
+
+// selectionRegion is a selection resolved against the screen contents
+type selectionRegion struct {
+	startX, startY int
+	endX, endY     int // Inclusive
+	block          bool
+}
+
+// isWordChar reports whether r is part of a word for double-click selection
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(wordPunct, r)
+}
+
+// cellRune returns the character in a cell, treating empty cells as spaces
+func cellRune(line []terminal.Cell, x int) rune {
+	if x < 0 || x >= len(line) || line[x].Char == 0 {
+		return ' '
+	}
+	return line[x].Char
+}
+
+// wordBounds returns the first and last column of the word at x. Clicking
+// on a space selects just that cell.
+func wordBounds(line []terminal.Cell, x int) (int, int) {
+	if !isWordChar(cellRune(line, x)) {
+		return x, x
+	}
+	// Wide character continuation cells belong to the word they're in
+	inWord := func(i int) bool {
+		return line[i].Char == 0 || isWordChar(line[i].Char)
+	}
+	start, end := x, x
+	for start > 0 && inWord(start-1) {
+		start--
+	}
+	for end+1 < len(line) && inWord(end+1) {
+		end++
+	}
+	return start, end
+}
+
+// resolve normalizes the selection and expands it to words or lines
+func (s *Selection) resolve(lines [][]terminal.Cell) selectionRegion {
+	r := selectionRegion{
+		startX: s.AnchorX, startY: s.AnchorY,
+		endX: s.HeadX, endY: s.HeadY,
+	}
+
+	if s.Mode == SelectBlock {
+		r.block = true
+		r.startX, r.endX = min(s.AnchorX, s.HeadX), max(s.AnchorX, s.HeadX)
+		r.startY, r.endY = min(s.AnchorY, s.HeadY), max(s.AnchorY, s.HeadY)
+		return r
+	}
+
+	if r.endY < r.startY || (r.endY == r.startY && r.endX < r.startX) {
+		r.startX, r.startY, r.endX, r.endY = r.endX, r.endY, r.startX, r.startY
+	}
+
+	line := func(y int) []terminal.Cell {
+		if y >= 0 && y < len(lines) {
+			return lines[y]
+		}
+		return nil
+	}
+
+	switch s.Mode {
+	case SelectWord:
+		r.startX, _ = wordBounds(line(r.startY), r.startX)
+		_, r.endX = wordBounds(line(r.endY), r.endX)
+	case SelectLine:
+		for r.startY > 0 && terminal.IsWrapped(line(r.startY-1)) {
+			r.startY--
+		}
+		for r.endY+1 < len(lines) && terminal.IsWrapped(line(r.endY)) {
+			r.endY++
+		}
+		r.startX = 0
+		r.endX = max(len(line(r.endY))-1, 0)
+	}
+	return r
+}
+
+// contains reports whether the cell at x, y is inside the region
+func (r selectionRegion) contains(x, y int) bool {
+	if y < r.startY || y > r.endY {
+		return false
+	}
+	if r.block {
+		return x >= r.startX && x <= r.endX
+	}
+	if y == r.startY && x < r.startX {
+		return false
+	}
+	if y == r.endY && x > r.endX {
+		return false
+	}
+	return true
+}
+
+// Text returns the selected text. Trailing spaces are trimmed from each line
+// and lines are joined with newlines. A row whose text wrapped onto the next
+// is joined to it without one, so a long line copies as the device sent it,
+// except in block selections, which copy the rows as shown.
+func (s *Selection) Text(lines [][]terminal.Cell) string {
+	r := s.resolve(lines)
+
+	var out []string
+	var sb strings.Builder
+	for y := r.startY; y <= r.endY && y < len(lines); y++ {
+		if y < 0 {
+			continue
+		}
+		for x, cell := range lines[y] {
+			if cell.Char == 0 || !r.contains(x, y) {
+				continue
+			}
+			sb.WriteString(cell.String())
+		}
+		if !r.block && y < r.endY && terminal.IsWrapped(lines[y]) {
+			continue
+		}
+		out = append(out, strings.TrimRight(sb.String(), " "))
+		sb.Reset()
+	}
+	if sb.Len() > 0 {
+		out = append(out, strings.TrimRight(sb.String(), " "))
+	}
+	return strings.Join(out, "\n")
+}
+
+// IsEmpty reports whether the selection covers only the cell that was clicked
+func (s *Selection) IsEmpty() bool {
+	return s.Mode == SelectChar && s.AnchorX == s.HeadX && s.AnchorY == s.HeadY
+}
+
+// selectionState tracks the mouse gesture that builds the selection
+type selectionState struct {
+	current    *Selection
+	dragging   bool
+	lastClick  time.Time
+	lastX      int
+	lastY      int
+	clickCount int
+}
+
+// handleSelectionMouse builds a selection from clicks and drags. Selection is
+// local when scrolling or when Shift is held, since otherwise the remote
+// application asked for mouse events. Returns true if the event was used.
+func (app *Application) handleSelectionMouse(ev *tcell.EventMouse) bool {
+	sel := &app.selection
+	local := app.terminal.IsScrolling() || ev.Modifiers()&tcell.ModShift != 0
+	if !local && !sel.dragging {
+		return false
+	}
+
+	x, y := ev.Position()
+	_, screenHeight := app.screen.Size()
+	y = max(0, min(screenHeight-2, y)) // Stay above the status bar
+
+	switch {
+	case ev.Buttons()&tcell.Button1 != 0 && !sel.dragging:
+		// Press: count clicks at the same spot for word and line selection
+		now := time.Now()
+		if now.Sub(sel.lastClick) < multiClickInterval && x == sel.lastX && y == sel.lastY {
+			sel.clickCount = sel.clickCount%3 + 1
+		} else {
+			sel.clickCount = 1
+		}
+		sel.lastClick, sel.lastX, sel.lastY = now, x, y
+
+		mode := SelectChar
+		switch {
+		case ev.Modifiers()&tcell.ModAlt != 0:
+			mode = SelectBlock
+		case sel.clickCount == 2:
+			mode = SelectWord
+		case sel.clickCount == 3:
+			mode = SelectLine
+		}
+		sel.current = &Selection{Mode: mode, AnchorX: x, AnchorY: y, HeadX: x, HeadY: y}
+		sel.dragging = true
+	case ev.Buttons()&tcell.Button1 != 0:
+		// Drag extends the selection
+		sel.current.HeadX, sel.current.HeadY = x, y
+	case sel.dragging:
+		// Release copies the selection
+		sel.dragging = false
+		if sel.current.IsEmpty() {
+			sel.current = nil
+		} else {
+			app.copySelection()
+		}
+	default:
+		return false
+	}
+
+	app.forceRedraw()
+	return true
+}
+
+// copySelection puts the selected text on the host clipboard (OSC 52)
+func (app *Application) copySelection() {
+	if app.selection.current == nil {
+		return
+	}
+	text := app.selection.current.Text(app.visibleLines())
+	if text == "" {
+		return
+	}
+	app.screen.SetClipboard([]byte(text))
+	app.updateStatusMessage(fmt.Sprintf("Copied %d characters", len([]rune(text))))
+}
+
+// clearSelection removes the selection highlight
+func (app *Application) clearSelection() {
+	if app.selection.current == nil {
+		return
+	}
+	app.selection.current = nil
+	app.selection.dragging = false
+	app.forceRedraw()
+}
+
+// drawSelection highlights the selected cells
+func (app *Application) drawSelection(buffer [][]terminal.Cell, contentHeight int) {
+	if app.selection.current == nil {
+		return
+	}
+	r := app.selection.current.resolve(buffer)
+	for y := max(r.startY, 0); y <= r.endY && y < contentHeight && y < len(buffer); y++ {
+		for x, cell := range buffer[y] {
+			if cell.Char == 0 || !r.contains(x, y) {
+				continue
+			}
+			style := cellStyle(cell)
+			_, _, attrs := style.Decompose()
+			app.screen.SetContent(x, y, cell.Char, cell.Combining(), style.Reverse(attrs&tcell.AttrReverse == 0))
+		}
+	}
+}
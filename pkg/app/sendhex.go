@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sterm/pkg/history"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// maxPayloadHistory is the number of send-hex payloads kept per profile
+const maxPayloadHistory = 200
+
+// parsePayload converts send-hex input to bytes. Hex bytes are separated by
+// spaces or commas and may have a 0x prefix ("AA 55 01 FF", "0xAA,0x55",
+// "AA5501FF"); double-quoted strings use Go escapes ("AT\r\n", "\x02OK\x03").
+// Both can be mixed. Strings are sent as typed, without charset conversion.
+func parsePayload(text string) ([]byte, error) {
+	var data []byte
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == ',':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("unterminated string %s", text[i:])
+			}
+			s, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s: %w", text[i:end+1], err)
+			}
+			data = append(data, s...)
+			i = end + 1
+		default:
+			end := i
+			for end < len(text) && !strings.ContainsRune(" \t,\"", rune(text[end])) {
+				end++
+			}
+			b, err := parseHexToken(text[i:end])
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+			i = end
+		}
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("nothing to send")
+	}
+	return data, nil
+}
+
+// parseHexToken decodes a run of hex digits with an optional 0x prefix. A
+// single digit is one byte, so "1" is 0x01.
+func parseHexToken(token string) ([]byte, error) {
+	digits := token
+	if len(digits) > 2 && (digits[:2] == "0x" || digits[:2] == "0X") {
+		digits = digits[2:]
+	}
+	if len(digits) == 1 {
+		digits = "0" + digits
+	}
+	if len(digits)%2 != 0 {
+		return nil, fmt.Errorf("invalid hex %q: odd number of digits", token)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q", token)
+	}
+	return b, nil
+}
+
+// setupPayloadHistory loads the send-hex history for the current profile
+func (app *Application) setupPayloadHistory() {
+	path, err := history.PayloadHistoryPath("", app.historyProfile())
+	if err != nil {
+		app.logDebug("Payload history disabled: %v", err)
+		path = "" // Keep history in memory only
+	}
+
+	app.payloadHistory = history.NewCommandHistory(path, maxPayloadHistory)
+	if err := app.payloadHistory.Load(); err != nil {
+		app.logDebug("Failed to load payload history: %v", err)
+	}
+}
+
+// openSendHex opens a status bar prompt for typing bytes to send. Up/Down
+// recall recent payloads; input that doesn't parse stays open for fixing.
+func (app *Application) openSendHex() {
+	if app.inputBlocked() {
+		return
+	}
+	if app.payloadHistory == nil {
+		app.setupPayloadHistory()
+	}
+
+	payloads := app.payloadHistory
+	index := payloads.Len() // One past the newest entry
+
+	var prompt *statusPrompt
+	prompt = newStatusPrompt("Send hex/\"text\" (↑/↓ history): ", "", func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		data, err := parsePayload(value)
+		if err != nil {
+			app.openPrompt(prompt) // Keep the input so it can be corrected
+			return err
+		}
+
+		n := app.writeToPort(data)
+		if n < len(data) {
+			return fmt.Errorf("sent %d of %d bytes", n, len(data))
+		}
+		if err := payloads.Add(value); err != nil {
+			app.logDebug("Failed to save payload history: %v", err)
+		}
+		app.updateStatusMessage(fmt.Sprintf("Sent %d bytes", n))
+		return nil
+	})
+
+	prompt.onKey = func(ev *tcell.EventKey) bool {
+		switch ev.Key() {
+		case tcell.KeyUp:
+			if index > 0 {
+				index--
+				value, _ := payloads.Get(index)
+				prompt.input = []rune(value)
+			}
+			return true
+		case tcell.KeyDown:
+			if index < payloads.Len()-1 {
+				index++
+				value, _ := payloads.Get(index)
+				prompt.input = []rune(value)
+			} else {
+				index = payloads.Len()
+				prompt.input = prompt.input[:0]
+			}
+			return true
+		}
+		return false
+	}
+
+	app.openPrompt(prompt)
+}
@@ -0,0 +1,319 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"sterm/pkg/config"
+	"sterm/pkg/history"
+	"sterm/pkg/logging"
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// altKeyActions maps rebindable action names to their default Alt+ key
+var altKeyActions = map[string]rune{
+	"clear":           'c',
+	"clear-history":   'h',
+	"reset":           'x',
+	"reconnect":       'r',
+	"save":            's',
+	"filter":          'f',
+	"toggle-filter":   'g',
+	"screenshot":      'p',
+	"command-history": 'k',
+	"bookmark":        'b',
+	"notifications":   'n',
+	"live":            'l',
+	"open-link":       'u',
+	"decoders":        'd',
+	"send-hex":        'e',
+	"code-point":      'w',
+	"ascii-table":     'a',
+	"input-lock":      'i',
+	"mark":            'm',
+	"literal-next":    'v',
+	"passthrough":     't',
+	"debug-overlay":   'o',
+}
+
+// statusTheme holds the resolved status bar colors
+type statusTheme struct {
+	foreground tcell.Color
+	background tcell.Color
+	message    tcell.Color
+	scroll     tcell.Color
+	filter     tcell.Color
+	paused     tcell.Color
+	warning    tcell.Color
+	error      tcell.Color
+}
+
+// settingsReload is posted to the UI loop when the settings file changes
+type settingsReload struct {
+	settings config.Settings
+	err      error
+}
+
+// buildAltKeyMap maps pressed Alt+ letters to the default letter of the action
+// they trigger. Rebinding an action frees its default key.
+func buildAltKeyMap(bindings map[string]string) (map[rune]rune, error) {
+	keys := make(map[rune]rune, len(altKeyActions))
+	for _, def := range altKeyActions {
+		keys[def] = def
+	}
+
+	// Release rebound defaults first so actions can swap keys
+	parsed := make(map[string]rune, len(bindings))
+	for action, key := range bindings {
+		def, ok := altKeyActions[action]
+		if !ok {
+			return nil, fmt.Errorf("keybindings: unknown action %q (valid: %s)", action, strings.Join(altKeyActionNames(), ", "))
+		}
+		r, err := config.ParseAltKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("keybindings.%s: %w", action, err)
+		}
+		parsed[action] = r
+		if keys[def] == def {
+			delete(keys, def)
+		}
+	}
+
+	for action, r := range parsed {
+		keys[r] = altKeyActions[action]
+	}
+	return keys, nil
+}
+
+// altKeyActionNames returns the sorted rebindable action names
+func altKeyActionNames() []string {
+	names := make([]string, 0, len(altKeyActions))
+	for name := range altKeyActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveAltKey translates a pressed Alt+ letter to the default letter of the
+// bound action, or 0 if the key is unbound
+func (app *Application) resolveAltKey(r rune) rune {
+	r = unicode.ToLower(r)
+	if app.altKeys == nil {
+		return r
+	}
+	return app.altKeys[r]
+}
+
+// keyLabel returns the shortcut shown in the menu for an action: Alt+ and
+// the letter, or the chord prefix and the letter when one is set
+func (app *Application) keyLabel(action string) string {
+	modifier := "Alt+"
+	if app.chordPrefix != "" {
+		modifier = app.chordPrefix + " "
+	}
+	def := altKeyActions[action]
+	for pressed, target := range app.altKeys {
+		if target == def {
+			return modifier + string(unicode.ToUpper(pressed))
+		}
+	}
+	if app.altKeys == nil {
+		return modifier + string(unicode.ToUpper(def))
+	}
+	return ""
+}
+
+// parseHistoryFormat converts a settings format name to a history file format
+func parseHistoryFormat(name string) history.FileFormat {
+	switch name {
+	case "plain_text":
+		return history.FormatPlainText
+	case "json":
+		return history.FormatJSON
+	default:
+		return history.FormatTimestamped
+	}
+}
+
+// resolveTheme converts theme color names to tcell colors
+func resolveTheme(theme config.ThemeSettings) statusTheme {
+	defaults := config.DefaultSettings().Theme
+	color := func(value, fallback string) tcell.Color {
+		if value == "" {
+			value = fallback
+		}
+		return tcell.GetColor(value)
+	}
+
+	return statusTheme{
+		foreground: color(theme.StatusForeground, defaults.StatusForeground),
+		background: color(theme.StatusBackground, defaults.StatusBackground),
+		message:    color(theme.MessageBackground, defaults.MessageBackground),
+		scroll:     color(theme.ScrollBackground, defaults.ScrollBackground),
+		filter:     color(theme.FilterBackground, defaults.FilterBackground),
+		paused:     color(theme.PausedBackground, defaults.PausedBackground),
+		warning:    color(theme.WarningBackground, defaults.WarningBackground),
+		error:      color(theme.ErrorBackground, defaults.ErrorBackground),
+	}
+}
+
+// loadSettings reads the settings file at startup. Errors leave the defaults
+// in place and are reported in the status bar.
+func (app *Application) loadSettings() {
+	app.settingsPath = config.SettingsPath("")
+
+	settings, err := config.LoadSettings(app.settingsPath)
+	if err == nil {
+		err = app.applySettings(settings)
+	}
+	if err != nil {
+		_ = app.applySettings(config.DefaultSettings())
+		app.logDebug("Failed to load settings: %v", err)
+		app.notifyError("Settings error: %v", err)
+	}
+}
+
+// applySettings applies the runtime-changeable settings. Nothing is changed
+// if the settings are invalid.
+func (app *Application) applySettings(settings config.Settings) error {
+	altKeys, err := buildAltKeyMap(settings.Keybindings)
+	if err != nil {
+		return err
+	}
+	chordPrefix, err := app.applyChordPrefix(settings.Prefix)
+	if err != nil {
+		return err
+	}
+
+	app.mu.Lock()
+	app.theme = resolveTheme(settings.Theme)
+	app.statusBar = settings.StatusBar
+	app.titleBar = settings.TitleBar
+	app.links = settings.Links
+	app.logging = settings.Logging
+	app.altKeys = altKeys
+	app.chordPrefix = chordPrefix
+	app.pasteSettings = settings.Paste
+	if settings.Logging.Format != "" {
+		app.config.HistoryFormat = parseHistoryFormat(settings.Logging.Format)
+	}
+	app.cachedStatusLeft = ""
+	app.mu.Unlock()
+
+	app.bell.configure(settings.Bell)
+	app.watchdog.configure(settings.Watchdog)
+	app.triggers.configure(settings.Triggers)
+	app.plot.configure(settings.Plot)
+	app.printer.configure(settings.Printer)
+	if err := app.autosave.configure(settings.Autosave); err != nil {
+		app.logDebug("Failed to apply autosave settings: %v", err)
+	}
+	app.applyAmbiguousWidth(settings.Display.AmbiguousWidth)
+	app.frames.configure(settings.Display.MaxFPS)
+	if policy, err := terminal.ParseInvalidUTF8Policy(settings.Display.InvalidUTF8); err == nil && app.terminal != nil {
+		app.terminal.SetInvalidUTF8Policy(policy)
+	}
+	if policy, err := terminal.ParseClearPolicy(settings.Display.ClearScrollback); err == nil && app.terminal != nil {
+		app.terminal.SetClearPolicy(policy)
+	}
+	if app.terminal != nil {
+		app.terminal.SetNewlineHandling(settings.Display.ImplicitCR, settings.Display.ImplicitLF)
+	}
+
+	app.configureLogging(settings.Logging)
+	app.setTitleBarVisible(settings.TitleBar.Show)
+	app.setWrapMarkers(settings.Display.WrapMarkers)
+
+	// Rebuild the menu so shortcut labels follow the keybindings
+	if app.mainMenu != nil {
+		app.mainMenu.Clear()
+		app.setupMenu()
+	}
+
+	app.forceRedraw()
+	return nil
+}
+
+// configureLogging applies the log level, muted modules and output format
+func (app *Application) configureLogging(settings config.LoggingSettings) {
+	level := logging.LevelOff
+	if settings.Debug {
+		level = logging.LevelDebug
+	}
+	if settings.Level != "" {
+		if parsed, err := logging.ParseLevel(settings.Level); err == nil {
+			level = parsed
+		}
+	}
+	// The command line flag keeps debug logging on regardless of the file
+	if app.config.DebugMode {
+		level = logging.LevelDebug
+	}
+
+	app.log.SetJSON(settings.JSON)
+	for _, module := range logging.Modules {
+		enabled, set := settings.Modules[string(module)]
+		app.log.SetModuleEnabled(module, enabled || !set)
+	}
+	app.setLogLevel(level)
+}
+
+// setLogLevel changes the log level, creating the log file when logging is
+// turned on and closing it when turned off
+func (app *Application) setLogLevel(level logging.Level) {
+	if level == logging.LevelOff {
+		app.log.SetLevel(level)
+		_ = app.log.Close()
+		return
+	}
+	if !app.log.IsOpen() {
+		if err := app.log.Open(logging.DefaultPath()); err != nil {
+			app.notifyError("Logging disabled: %v", err)
+			return
+		}
+	}
+	app.log.SetLevel(level)
+}
+
+// startSettingsWatcher reloads the settings file when it is edited
+func (app *Application) startSettingsWatcher() {
+	watcher, err := config.NewSettingsWatcher(app.settingsPath, func(settings config.Settings, err error) {
+		// Apply on the UI loop so key handling never sees half-applied settings
+		if app.screen != nil {
+			_ = app.screen.PostEvent(tcell.NewEventInterrupt(settingsReload{settings: settings, err: err}))
+		}
+	})
+	if err != nil {
+		// The settings directory may not exist yet; live reload is optional
+		app.logDebug("Settings live reload disabled: %v", err)
+		return
+	}
+	app.settingsWatcher = watcher
+}
+
+// stopSettingsWatcher stops watching the settings file
+func (app *Application) stopSettingsWatcher() {
+	if app.settingsWatcher != nil {
+		_ = app.settingsWatcher.Close()
+		app.settingsWatcher = nil
+	}
+}
+
+// handleSettingsReload applies settings loaded by the watcher
+func (app *Application) handleSettingsReload(reload settingsReload) {
+	err := reload.err
+	if err == nil {
+		err = app.applySettings(reload.settings)
+	}
+	if err != nil {
+		app.logDebug("Settings reload failed: %v", err)
+		app.notifyError("Settings not applied: %v", err)
+		return
+	}
+	app.updateStatusMessage("Settings reloaded")
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"sterm/pkg/config"
+)
+
+// statusFormat returns the status bar templates in use: the profile's,
+// with the parts it leaves empty from the settings file
+func (app *Application) statusFormat() config.StatusFormat {
+	return app.config.StatusFormat.Over(app.statusBar.Format)
+}
+
+// statusValues returns the placeholder values for status bar templates
+func (app *Application) statusValues(now time.Time) map[string]string {
+	cfg := app.config.SerialConfig
+	baud := strconv.Itoa(cfg.BaudRate)
+	if actual := app.driverBaud.Load(); actual != 0 {
+		baud = fmt.Sprintf("%d≈%d", cfg.BaudRate, actual)
+	}
+	flow := cfg.FlowControl
+	if flow == "" {
+		flow = "none"
+	}
+	var sent, received int64
+	if app.session != nil {
+		sent, received = app.session.BytesSent, app.session.BytesRecv
+	}
+
+	return map[string]string{
+		"port":     cfg.Port,
+		"baud":     baud,
+		"tx":       strconv.FormatInt(sent, 10),
+		"rx":       strconv.FormatInt(received, 10),
+		"time":     now.Format("15:04:05"),
+		"flow":     flow,
+		"log":      app.statusLogName(),
+		"profile":  app.config.ProfileName,
+		"encoding": app.currentCharset().Label,
+	}
+}
+
+// statusLogName returns the file data is being written to for {log}: the
+// history stream, else a trigger capture, else "off"
+func (app *Application) statusLogName() string {
+	if path := app.historyStreaming(); path != "" {
+		return filepath.Base(path)
+	}
+	if path := app.triggers.capturing(); path != "" {
+		return filepath.Base(path)
+	}
+	return "off"
+}
+
+// expandStatus fills in the {placeholders} of a status bar template.
+// Unknown placeholders are left as they are.
+func expandStatus(template string, values map[string]string) string {
+	return expandPlaceholders(template, values, func(value string) string { return value })
+}
+
+// statusClockTicked reports whether a template shows {time} and the second
+// has changed since it was last drawn, so the status bar needs a redraw
+func (app *Application) statusClockTicked(now time.Time) bool {
+	if !app.statusFormat().Uses("time") {
+		return false
+	}
+	return app.statusSecond.Swap(now.Unix()) != now.Unix()
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sttyPromptWait is how long after connecting a shell prompt is waited
+	// for before the size is typed at it
+	sttyPromptWait = 10 * time.Second
+
+	// sttyPromptPoll is how often the screen is checked for the prompt
+	sttyPromptPoll = 200 * time.Millisecond
+)
+
+// shellPromptRegex matches the text left of the cursor at a shell prompt:
+// ending in $, # or %, but not a percentage. Continuation prompts (>) and
+// bootloader or router prompts are left out on purpose.
+var shellPromptRegex = regexp.MustCompile(`(^|[^0-9$#%])[$#%]$`)
+
+// ParseSttyCommand reads the command typed to tell a shell the size, such
+// as "stty rows {rows} cols {cols}\r", with backslash escapes like those of
+// window size templates
+func ParseSttyCommand(value string) (string, error) {
+	if !strings.Contains(value, "{rows}") && !strings.Contains(value, "{cols}") {
+		return "", fmt.Errorf("invalid stty command %q: want a template with {rows} and {cols}", value)
+	}
+	command, err := unescapeTemplate(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid stty command %q: %w", value, err)
+	}
+	return command, nil
+}
+
+// sttyCommand returns the command that tells a shell the size: the
+// configured template, or stty
+func (app *Application) sttyCommand(rows, cols int) []byte {
+	if app.config.SttyCommand == "" {
+		return WindowSize{Mode: WindowSizeStty}.Report(rows, cols)
+	}
+	r := strings.NewReplacer("{rows}", strconv.Itoa(rows), "{cols}", strconv.Itoa(cols))
+	return []byte(r.Replace(app.config.SttyCommand))
+}
+
+// atShellPrompt reports whether the cursor sits at an empty shell prompt,
+// where typing a command is safe. Nothing is typed into full-screen
+// programs, half-typed commands, password prompts or anything else.
+func (app *Application) atShellPrompt() bool {
+	if app.terminal == nil || app.terminal.IsAltScreen() {
+		return false
+	}
+	screen := app.terminal.GetScreen()
+	state := app.terminal.GetState()
+	if screen == nil || state.CursorY < 0 || state.CursorY >= len(screen.Buffer) {
+		return false
+	}
+
+	line := screen.Buffer[state.CursorY]
+	end := min(state.CursorX, len(line))
+	return shellPromptRegex.MatchString(lineToString(line[:end])) && isBlankLine(line[end:])
+}
+
+// typeSttyCommand types the size command at the shell prompt, if the cursor
+// is at one
+func (app *Application) typeSttyCommand() error {
+	if app.config.Monitor {
+		return fmt.Errorf("nothing is sent in monitor mode")
+	}
+	if !app.atShellPrompt() {
+		return fmt.Errorf("no shell prompt at the cursor")
+	}
+	screen := app.terminal.GetScreen()
+	if !app.queueWrite(app.sttyCommand(screen.Height, screen.Width), false, nil) {
+		return fmt.Errorf("port is not open")
+	}
+	app.logDebug("Typed the terminal size %dx%d at the shell prompt", screen.Width, screen.Height)
+	return nil
+}
+
+// reportSizeAtPrompt reports the size once a shell prompt shows up after
+// connecting, for the stty strategy. Gives up after sttyPromptWait.
+func (app *Application) reportSizeAtPrompt() {
+	defer app.recoverPanic("reportSizeAtPrompt")
+	ticker := time.NewTicker(sttyPromptPoll)
+	defer ticker.Stop()
+	deadline := time.After(sttyPromptWait)
+
+	for {
+		if app.reportWindowSize(true) {
+			return
+		}
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-deadline:
+			app.logDebug("No shell prompt within %v of connecting; terminal size not typed", sttyPromptWait)
+			return
+		case <-ticker.C:
+		}
+	}
+}
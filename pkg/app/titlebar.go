@@ -0,0 +1,195 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// titleScreen is the screen the application draws on, moved down a row
+// while the title bar is shown. Everything but the title bar sees a screen
+// a row shorter, so the terminal, status bar, panels and menus lay out as
+// usual and mouse rows line up with what was drawn.
+type titleScreen struct {
+	tcell.Screen
+	top atomic.Int32 // 1 while the title bar is shown
+}
+
+// newTitleScreen wraps a screen, with the title bar hidden
+func newTitleScreen(screen tcell.Screen) *titleScreen {
+	return &titleScreen{Screen: screen}
+}
+
+// Size returns the size below the title bar
+func (s *titleScreen) Size() (int, int) {
+	width, height := s.Screen.Size()
+	return width, max(0, height-int(s.top.Load()))
+}
+
+// SetContent draws below the title bar
+func (s *titleScreen) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	s.Screen.SetContent(x, y+int(s.top.Load()), primary, combining, style)
+}
+
+// SetCell draws below the title bar
+func (s *titleScreen) SetCell(x, y int, style tcell.Style, ch ...rune) {
+	s.Screen.SetCell(x, y+int(s.top.Load()), style, ch...)
+}
+
+// GetContent reads below the title bar
+func (s *titleScreen) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	return s.Screen.GetContent(x, y+int(s.top.Load()))
+}
+
+// ShowCursor places the cursor below the title bar
+func (s *titleScreen) ShowCursor(x, y int) {
+	s.Screen.ShowCursor(x, y+int(s.top.Load()))
+}
+
+// LockRegion locks cells below the title bar
+func (s *titleScreen) LockRegion(x, y, width, height int, lock bool) {
+	s.Screen.LockRegion(x, y+int(s.top.Load()), width, height, lock)
+}
+
+// PollEvent returns the next event with mouse rows counted from below the
+// title bar. Mouse events on the title bar itself are dropped.
+func (s *titleScreen) PollEvent() tcell.Event {
+	for {
+		ev := s.Screen.PollEvent()
+		mouse, ok := ev.(*tcell.EventMouse)
+		top := int(s.top.Load())
+		if !ok || top == 0 {
+			return ev
+		}
+		x, y := mouse.Position()
+		if y < top {
+			continue
+		}
+		return tcell.NewEventMouse(x, y-top, mouse.Buttons(), mouse.Modifiers())
+	}
+}
+
+// titleBarVisible reports whether the title bar is shown
+func (app *Application) titleBarVisible() bool {
+	s, ok := app.screen.(*titleScreen)
+	return ok && s.top.Load() > 0
+}
+
+// setTitleBarVisible shows or hides the title bar, giving the terminal a
+// row less or more
+func (app *Application) setTitleBarVisible(visible bool) {
+	s, ok := app.screen.(*titleScreen)
+	if !ok || visible == app.titleBarVisible() {
+		return
+	}
+	if visible {
+		s.top.Store(1)
+	} else {
+		s.top.Store(0)
+	}
+
+	if app.terminal != nil {
+		width, height := app.terminalSize()
+		_ = app.terminal.Resize(width, height)
+		app.resizePort(width, height)
+		app.scheduleWindowSizeReport()
+	}
+	s.Screen.Clear()
+	app.forceRedraw()
+}
+
+// titleBarText returns the parts of the title bar: the session name,
+// connection state and time connected on the left, then on the right the
+// warnings and errors not yet seen in the notification history, and the
+// clock
+func (app *Application) titleBarText(now time.Time) (left, alerts, clock string) {
+	name := app.config.ProfileName
+	if name == "" {
+		name = app.config.SerialConfig.Port
+	}
+
+	state := "Disconnected"
+	switch {
+	case app.serialPort != nil && app.serialPort.IsOpen() && app.config.Monitor:
+		state = "Monitoring"
+	case app.serialPort != nil && app.serialPort.IsOpen():
+		state = "Connected"
+	case app.deviceWaiting():
+		state = "Waiting for device"
+	}
+	left = fmt.Sprintf(" %s │ %s", name, state)
+	if app.session != nil {
+		left += " │ " + formatReplayTime(now.Sub(app.session.StartTime))
+	}
+	left += " "
+
+	var unseen []Notification
+	seen := app.alertsSeen.Load()
+	for _, n := range app.notifications.History() {
+		if n.Severity >= SeverityWarning && n.Time.UnixNano() > seen {
+			unseen = append(unseen, n)
+		}
+	}
+	switch len(unseen) {
+	case 0:
+	case 1:
+		alerts = fmt.Sprintf(" ⚠ %s ", unseen[0].Message)
+	default:
+		alerts = fmt.Sprintf(" ⚠ %d alerts, last: %s ", len(unseen), unseen[0].Message)
+	}
+	alerts = strings.ReplaceAll(alerts, "\n", " ")
+
+	if app.titleBar.Clock {
+		clock = now.Format(" 15:04:05 ")
+	}
+	return left, alerts, clock
+}
+
+// drawTitleBar draws the title bar on the top row, if shown. Alerts are
+// cut short to leave room for the rest.
+func (app *Application) drawTitleBar() {
+	s, ok := app.screen.(*titleScreen)
+	if !ok || s.top.Load() == 0 {
+		return
+	}
+	width, _ := s.Screen.Size()
+	left, alerts, clock := app.titleBarText(time.Now())
+	room := width - runewidth.StringWidth(left) - runewidth.StringWidth(clock)
+	if alerts != "" && runewidth.StringWidth(alerts) > room {
+		alerts = runewidth.Truncate(alerts, max(0, room-1), "…") + " "
+		if room < 4 {
+			alerts = ""
+		}
+	}
+
+	style := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground)
+	for x := 0; x < width; x++ {
+		s.Screen.SetContent(x, 0, ' ', nil, style)
+	}
+	x := 0
+	draw := func(text string, style tcell.Style) {
+		for _, ch := range text {
+			if x < width {
+				s.Screen.SetContent(x, 0, ch, nil, style)
+			}
+			x += runewidth.RuneWidth(ch)
+		}
+	}
+	draw(left, style.Bold(true))
+	x = max(x, width-runewidth.StringWidth(alerts)-runewidth.StringWidth(clock))
+	draw(alerts, style.Background(app.theme.warning).Bold(true))
+	draw(clock, style)
+}
+
+// titleClockTicked reports whether the title bar is shown and the second
+// has changed since it was last drawn, for the clock and time connected
+func (app *Application) titleClockTicked(now time.Time) bool {
+	if !app.titleBarVisible() {
+		return false
+	}
+	return app.titleSecond.Swap(now.Unix()) != now.Unix()
+}
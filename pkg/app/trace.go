@@ -0,0 +1,163 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"sterm/pkg/logging"
+	"sterm/pkg/terminal"
+)
+
+// traceFileName is the trace file started from the menu, next to the debug
+// log
+const traceFileName = "sterm-trace.log"
+
+// traceState writes every escape sequence the emulator parses to a file,
+// with its parameters and what was done with it, for reporting emulation
+// bugs. Sequences are written from the goroutine processing device output
+// with the emulator locked, so a failed write only records the error; the
+// UI goroutine flushes the file and stops the trace if writing failed.
+type traceState struct {
+	mu     sync.Mutex
+	file   *os.File // Nil while off
+	out    *bufio.Writer
+	count  int // Sequences traced
+	failed error
+}
+
+// defaultTracePath returns the trace file started from the menu, in the
+// directory of the debug log
+func defaultTracePath() string {
+	return filepath.Join(filepath.Dir(logging.DefaultPath()), traceFileName)
+}
+
+// startTrace starts tracing escape sequences to a new file at path,
+// replacing any trace already running
+func (app *Application) startTrace(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %w", err)
+	}
+	if _, err := app.stopTrace(); err != nil {
+		app.logDebug("Failed to close trace: %v", err)
+	}
+
+	t := &app.trace
+	t.mu.Lock()
+	t.file, t.out, t.count, t.failed = file, bufio.NewWriter(file), 0, nil
+	fmt.Fprintf(t.out, "# sterm escape sequence trace, started %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(t.out, "# time  sequence  name -> actions (\"ignored\" if none; \"unknown\" if not recognized)\n")
+	t.mu.Unlock()
+
+	app.terminal.SetSequenceTracer(app.traceSequence)
+	app.logDebug("Tracing escape sequences to %s", path)
+	return nil
+}
+
+// stopTrace stops tracing, returning the file the trace went to ("" if
+// none)
+func (app *Application) stopTrace() (string, error) {
+	if app.terminal != nil {
+		app.terminal.SetSequenceTracer(nil)
+	}
+
+	t := &app.trace
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return "", nil
+	}
+	path := t.file.Name()
+	err := t.out.Flush()
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	t.file, t.out = nil, nil
+	return path, err
+}
+
+// traceSequence is the emulator's sequence tracer, writing one line per
+// sequence
+func (app *Application) traceSequence(sequence terminal.SequenceTrace) {
+	t := &app.trace
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.out == nil || t.failed != nil {
+		return
+	}
+	t.count++
+	if _, err := fmt.Fprintf(t.out, "%s  %s\n", time.Now().Format("15:04:05.000000"), sequence); err != nil {
+		t.failed = err
+	}
+}
+
+// flushTrace writes out traced sequences so the file can be followed while
+// tracing, and stops the trace if writing to it failed
+func (app *Application) flushTrace() {
+	t := &app.trace
+	t.mu.Lock()
+	if t.out == nil {
+		t.mu.Unlock()
+		return
+	}
+	err := t.failed
+	if err == nil {
+		err = t.out.Flush()
+	}
+	t.mu.Unlock()
+	if err == nil {
+		return
+	}
+
+	path, _ := app.stopTrace()
+	app.logDebug("Trace to %s failed: %v", path, err)
+	app.notifyError("Escape sequence trace stopped: %v", err)
+}
+
+// tracing returns the file escape sequences are traced to, "" if none
+func (app *Application) tracing() string {
+	t := &app.trace
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return ""
+	}
+	return t.file.Name()
+}
+
+// setTracing starts or stops the trace to the default trace file, for the
+// menu
+func (app *Application) setTracing(on bool) {
+	if !on {
+		path, err := app.stopTrace()
+		if err != nil {
+			app.notifyError("Failed to close %s: %v", filepath.Base(path), err)
+			return
+		}
+		app.trace.mu.Lock()
+		count := app.trace.count
+		app.trace.mu.Unlock()
+		app.updateStatusMessage(fmt.Sprintf("Traced %d escape sequences to %s", count, path))
+		return
+	}
+
+	path := defaultTracePath()
+	if err := app.startTrace(path); err != nil {
+		app.notifyError("%v", err)
+		return
+	}
+	app.updateStatusMessage("Tracing escape sequences to " + path)
+}
+
+// traceStatus returns the status bar segment shown while escape sequences
+// are traced
+func (app *Application) traceStatus() string {
+	if app.tracing() == "" {
+		return ""
+	}
+	return " TRACE │"
+}
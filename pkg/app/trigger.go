@@ -0,0 +1,221 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"sterm/pkg/config"
+	"sterm/pkg/plugin"
+)
+
+// maxTriggerLine bounds the partial line kept for matching triggers
+const maxTriggerLine = 4096
+
+// lineSplitter collects received text into complete lines for matching
+type lineSplitter struct {
+	line []byte
+}
+
+// split adds received text to the partial line and returns the lines it
+// completes, without line endings and escape sequences
+func (s *lineSplitter) split(text []byte) []string {
+	var lines []string
+	for _, b := range text {
+		switch b {
+		case '\n':
+			lines = append(lines, plugin.StripEscapes(string(s.line)))
+			s.line = s.line[:0]
+		case '\r':
+		default:
+			if len(s.line) < maxTriggerLine {
+				s.line = append(s.line, b)
+			}
+		}
+	}
+	return lines
+}
+
+// trigger is a pattern from settings and what to do when it matches
+type trigger struct {
+	settings config.TriggerSettings
+	regex    *regexp.Regexp
+}
+
+// triggerState matches received lines against the triggers and writes the
+// capture file they start
+type triggerState struct {
+	triggers    []trigger
+	lines       lineSplitter // Partial received line, only touched by the reader
+	capture     *os.File     // Open capture file, nil when not capturing
+	capturePath string
+	mu          sync.Mutex
+}
+
+// configure compiles the triggers from settings. Patterns were checked when
+// the settings were loaded; any that don't compile are skipped.
+func (t *triggerState) configure(settings []config.TriggerSettings) {
+	var triggers []trigger
+	for _, s := range settings {
+		if regex, err := regexp.Compile(s.Pattern); err == nil {
+			triggers = append(triggers, trigger{settings: s, regex: regex})
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.triggers = triggers
+}
+
+// capturing returns the capture file path, or "" when not capturing
+func (t *triggerState) capturing() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.capturePath
+}
+
+// writeCapture appends lines to the capture file
+func (t *triggerState) writeCapture(lines []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.capture == nil || len(lines) == 0 {
+		return nil
+	}
+	if _, err := t.capture.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to write capture: %w", err)
+	}
+	return nil
+}
+
+// stopCapture closes the capture file. Returns its path, or "" if nothing
+// was being captured.
+func (t *triggerState) stopCapture() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.capture == nil {
+		return "", nil
+	}
+	path := t.capturePath
+	err := t.capture.Close()
+	t.capture = nil
+	t.capturePath = ""
+	if err != nil {
+		return path, fmt.Errorf("failed to close capture: %w", err)
+	}
+	return path, nil
+}
+
+// checkTriggers matches the lines completed by received text, already
+// processed by the terminal, and records them in a running capture
+func (app *Application) checkTriggers(text []byte) {
+	app.triggers.mu.Lock()
+	triggers := app.triggers.triggers
+	capturing := app.triggers.capture != nil
+	app.triggers.mu.Unlock()
+
+	if len(triggers) == 0 && !capturing {
+		return
+	}
+
+	lines := app.triggers.lines.split(text)
+	if capturing {
+		if err := app.triggers.writeCapture(lines); err != nil {
+			app.logDebug("Trigger capture: %v", err)
+		}
+	}
+	for i, line := range lines {
+		for _, t := range triggers {
+			if t.regex.MatchString(line) {
+				app.fireTrigger(t.settings, len(lines)-1-i)
+			}
+		}
+	}
+}
+
+// fireTrigger starts a capture and pauses the display as the trigger asks.
+// after is the number of lines that arrived after the match in the same
+// read; they are already in the scrollback and go into the capture with the
+// context. Matches that change nothing, such as while a capture is already
+// running, are silent so a chatty pattern doesn't flood notifications.
+func (app *Application) fireTrigger(settings config.TriggerSettings, after int) {
+	var actions []string
+	if settings.Capture && app.triggers.capturing() == "" {
+		path, err := app.startCapture(settings.ContextLines, after)
+		if err != nil {
+			app.notifyError("Trigger /%s/: %v", settings.Pattern, err)
+		} else {
+			actions = append(actions, "capturing to "+path)
+		}
+	}
+	if settings.Pause && app.terminal != nil && !app.terminal.IsScrolling() {
+		app.terminal.EnterScrollMode()
+		actions = append(actions, fmt.Sprintf("display paused (%s for live output)", app.keyLabel("live")))
+		app.forceRedraw()
+	}
+
+	if len(actions) > 0 {
+		app.logDebug("Trigger /%s/ matched: %s", settings.Pattern, strings.Join(actions, ", "))
+		app.notifyWarning("Trigger /%s/ matched: %s", settings.Pattern, strings.Join(actions, ", "))
+	}
+}
+
+// startCapture creates a capture file holding the context lines before the
+// match, the match and the lines after it from the same read. Received lines
+// are appended until the capture is stopped.
+func (app *Application) startCapture(context, after int) (string, error) {
+	path, err := app.logFilePath(logKindCapture, ".log")
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	var lines []string
+	if app.terminal != nil {
+		// Lines above the cursor are complete; the cursor line is still
+		// arriving and is captured when it ends
+		all := app.terminal.GetAllLines()
+		end := len(all) - len(app.terminal.GetScreen().Buffer) + app.terminal.GetState().CursorY
+		end = max(0, min(end, len(all)))
+		for _, line := range all[max(0, end-context-1-after):end] {
+			lines = append(lines, lineToString(line))
+		}
+	}
+
+	app.triggers.mu.Lock()
+	app.triggers.capture = file
+	app.triggers.capturePath = path
+	app.triggers.mu.Unlock()
+
+	if err := app.triggers.writeCapture(lines); err != nil {
+		app.logDebug("Trigger capture: %v", err)
+	}
+	return path, nil
+}
+
+// stopTriggerCapture ends the capture started by a trigger
+func (app *Application) stopTriggerCapture() {
+	path, err := app.triggers.stopCapture()
+	switch {
+	case err != nil:
+		app.notifyError("%v", err)
+	case path == "":
+		app.updateStatusMessage("No capture running")
+	default:
+		app.updateStatusMessage("Capture saved to " + path)
+	}
+}
+
+// captureStatus returns the status bar segment shown while capturing
+func (app *Application) captureStatus() string {
+	if app.triggers.capturing() == "" {
+		return ""
+	}
+	return " CAPTURE │"
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"sterm/pkg/history"
+)
+
+const (
+	// txQueueLimit is how many bytes may wait to be sent before typed input
+	// is refused instead of queued
+	txQueueLimit = 1 << 20
+
+	// txStallThreshold is how long data has to wait before the status bar
+	// shows the queue, so ordinary typing doesn't flash it
+	txStallThreshold = 200 * time.Millisecond
+)
+
+// txItem is data waiting to be written to the port
+type txItem struct {
+	data   []byte
+	record bool     // Saved in history; secrets and mouse reports aren't
+	done   chan int // Receives the bytes written, for senders that wait
+}
+
+// txQueue holds data for the port, written in order by one goroutine so
+// a device holding off flow control blocks the writer instead of the UI
+type txQueue struct {
+	items  []txItem
+	queued int           // Bytes waiting, including the item being written
+	since  time.Time     // When the queue last became non-empty
+	wake   chan struct{} // Signals the writer; nil until it is started
+	mu     sync.Mutex
+}
+
+// startTxQueue starts the goroutine writing the send queue, which runs until
+// the application stops. Data queued before then waits for it.
+func (app *Application) startTxQueue() {
+	q := &app.tx
+	q.mu.Lock()
+	q.wake = make(chan struct{}, 1)
+	wake := q.wake
+	q.mu.Unlock()
+
+	app.wg.Add(1)
+	go app.runTxQueue(wake)
+}
+
+// queueWrite adds data to the send queue. Senders passing done receive the
+// bytes written once the data has gone, and are never refused; others are
+// refused when the queue is full. Returns false if the data was refused.
+func (app *Application) queueWrite(data []byte, record bool, done chan int) bool {
+	if app.serialPort == nil || !app.serialPort.IsOpen() {
+		return false
+	}
+
+	q := &app.tx
+	q.mu.Lock()
+	if done == nil && q.queued+len(data) > txQueueLimit {
+		waiting := q.queued
+		q.mu.Unlock()
+		// Notified from another goroutine, since answers to device queries
+		// are queued with the terminal locked and a redraw would wait on it
+		go app.notifyWarning("Send queue full (%s waiting) - input dropped", formatByteSize(int64(waiting)))
+		return false
+	}
+	if q.queued == 0 {
+		q.since = time.Now()
+		// Show the queue in the status bar if it is still waiting then
+		time.AfterFunc(txStallThreshold+time.Millisecond, app.requestUIUpdate)
+	}
+	q.items = append(q.items, txItem{data: data, record: record, done: done})
+	q.queued += len(data)
+	wake := q.wake
+	q.mu.Unlock()
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// writeToPortWait queues data and waits until it has been written, for
+// senders outside the UI goroutine that should go no faster than the port.
+// Returns the number of bytes written; 0 if the port is closed or the
+// send was cancelled.
+func (app *Application) writeToPortWait(data []byte) int {
+	done := make(chan int, 1)
+	if !app.queueWrite(data, true, done) {
+		return 0
+	}
+	select {
+	case n := <-done:
+		return n
+	case <-app.doneChannel():
+		return 0
+	}
+}
+
+// doneChannel returns the channel closed when the application stops
+func (app *Application) doneChannel() <-chan struct{} {
+	if app.ctx == nil {
+		return nil
+	}
+	return app.ctx.Done()
+}
+
+// runTxQueue writes queued data to the port in order
+func (app *Application) runTxQueue(wake <-chan struct{}) {
+	defer app.wg.Done()
+	defer app.recoverPanic("runTxQueue")
+
+	q := &app.tx
+	for {
+		q.mu.Lock()
+		if len(q.items) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-app.doneChannel():
+				return
+			case <-wake:
+			}
+			continue
+		}
+		item := q.items[0]
+		q.mu.Unlock()
+
+		n := 0
+		if app.serialPort != nil && app.serialPort.IsOpen() {
+			// Secrets and mouse reports aren't echoed
+			if item.record {
+				app.echo.expect(item.data, time.Now())
+			}
+			var err error
+			n, err = app.serialPort.Write(item.data)
+			if err != nil {
+				app.logSerial("Write failed after %d of %d bytes: %v", n, len(item.data), err)
+			}
+			app.showDecodersSent(item.data[:n], time.Now())
+		}
+		if item.record && app.historyMgr != nil {
+			_ = app.historyMgr.Write(item.data[:n], history.DirectionInput)
+		}
+		if app.session != nil {
+			app.session.UpdateStats(int64(n), 0)
+		}
+
+		q.mu.Lock()
+		// cancelPendingSends leaves the item being written at the front
+		q.items = q.items[1:]
+		q.queued -= len(item.data)
+		shown := time.Since(q.since) > txStallThreshold
+		q.mu.Unlock()
+
+		if item.done != nil {
+			item.done <- n
+		}
+		if shown {
+			app.requestUIUpdate() // Update or clear the TX status segment
+		}
+	}
+}
+
+// cancelPendingSends drops everything waiting in the send queue. Data the
+// writer has already handed to the port still goes out. Returns the
+// number of bytes dropped.
+func (app *Application) cancelPendingSends() int {
+	q := &app.tx
+	q.mu.Lock()
+	var dropped []txItem
+	if len(q.items) > 1 {
+		// The first item is being written
+		dropped = q.items[1:]
+		q.items = q.items[:1]
+	}
+	bytes := 0
+	for _, item := range dropped {
+		bytes += len(item.data)
+	}
+	q.queued -= bytes
+	q.mu.Unlock()
+
+	for _, item := range dropped {
+		if item.done != nil {
+			item.done <- 0
+		}
+	}
+	return bytes
+}
+
+// txStatus returns the status bar segment shown while data waits to be
+// sent, such as to a device holding off flow control
+func (app *Application) txStatus() string {
+	q := &app.tx
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued == 0 || time.Since(q.since) < txStallThreshold {
+		return ""
+	}
+	return fmt.Sprintf(" TX %s queued │", formatByteSize(int64(q.queued)))
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"sterm/pkg/history"
+	"sterm/pkg/menu"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// txrxMaxEntries is how many of the newest history entries the TX/RX view
+// looks through
+const txrxMaxEntries = 2000
+
+// txrxPanelMinWidth is the narrowest the TX/RX view gets
+const txrxPanelMinWidth = 40
+
+// Colors telling sent data from received data in the TX/RX view
+var (
+	txColor = tcell.ColorOrange
+	rxColor = tcell.ColorAqua
+)
+
+// TX/RX view layouts
+const (
+	txrxOff         = iota
+	txrxSplit       // Sent data in the top pane, received in the bottom one
+	txrxInterleaved // One list in time order, colored by direction
+)
+
+// txrxViewState is the layout of the TX/RX view, set from the menu and
+// read when drawing and when history is written
+type txrxViewState struct {
+	mode int
+	mu   sync.Mutex
+}
+
+// txrxRow is a line of the TX/RX view
+type txrxRow struct {
+	text      string
+	direction history.Direction
+}
+
+// txrxMode returns the TX/RX view layout
+func (app *Application) txrxMode() int {
+	app.txrx.mu.Lock()
+	defer app.txrx.mu.Unlock()
+	return app.txrx.mode
+}
+
+// setTxrxMode shows the TX/RX view in a layout, or hides it with txrxOff.
+// The view takes the place of the decoder panel and GPS dashboard.
+func (app *Application) setTxrxMode(mode int) {
+	app.txrx.mu.Lock()
+	app.txrx.mode = mode
+	app.txrx.mu.Unlock()
+
+	if mode != txrxOff {
+		app.decoders.mu.Lock()
+		app.decoders.visible = false
+		app.decoders.mu.Unlock()
+		app.gps.mu.Lock()
+		app.gps.visible = false
+		app.gps.mu.Unlock()
+	}
+	app.forceRedraw()
+}
+
+// historyWritten is the history write callback: the entry goes to the
+// history stream and the TX/RX view
+func (app *Application) historyWritten(entry history.HistoryEntry) {
+	app.streamHistoryEntry(entry)
+	if app.txrxMode() != txrxOff {
+		app.requestUIUpdate()
+	}
+}
+
+// txrxRows lays out history entries as rows of at most width cells. Data
+// runs on across entries in the same direction, like on the terminal, and
+// a row starts at each line feed and change of direction, with the time
+// of the entry that started it. Control characters other than line
+// endings are shown as control pictures.
+func txrxRows(entries []history.HistoryEntry, width int, labels bool) []txrxRow {
+	var rows []txrxRow
+	var line strings.Builder
+	lineWidth := 0
+	open := false // The last row can be continued
+	direction := history.Direction(-1)
+
+	flush := func() {
+		if open {
+			rows = append(rows, txrxRow{text: line.String(), direction: direction})
+		}
+		line.Reset()
+		lineWidth = 0
+		open = false
+	}
+	start := func(entry history.HistoryEntry, continued bool) {
+		prefix := entry.Timestamp.Format("15:04:05.000") + " "
+		if continued {
+			prefix = strings.Repeat(" ", len(prefix))
+		}
+		if labels {
+			label := "TX "
+			if entry.Direction == history.DirectionOutput {
+				label = "RX "
+			}
+			prefix += label
+		}
+		line.WriteString(prefix)
+		lineWidth = len(prefix)
+		open = true
+	}
+
+	for _, entry := range entries {
+		if entry.Direction != direction {
+			flush()
+			direction = entry.Direction
+		}
+		data := entry.Data
+		for len(data) > 0 {
+			r, size := utf8.DecodeRune(data)
+			data = data[size:]
+			switch {
+			case r == '\n':
+				if !open {
+					start(entry, false)
+				}
+				flush()
+				continue
+			case r == '\r':
+				continue
+			case r < 0x20 || r == 0x7F:
+				r = controlPicture(byte(r))
+			}
+
+			if !open {
+				start(entry, false)
+			}
+			w := runewidth.RuneWidth(r)
+			if lineWidth+w > width {
+				// Wrap, continuing under the data
+				flush()
+				start(entry, true)
+			}
+			line.WriteRune(r)
+			lineWidth += w
+		}
+	}
+	flush()
+	return rows
+}
+
+// txrxEntries returns the newest history entries for the TX/RX view
+func (app *Application) txrxEntries() []history.HistoryEntry {
+	if app.historyMgr == nil {
+		return nil
+	}
+	count := app.historyMgr.GetEntryCount()
+	start := max(0, count-txrxMaxEntries)
+	entries, err := app.historyMgr.GetEntries(start, count-start)
+	if err != nil {
+		return nil
+	}
+	return entries
+}
+
+// drawTxrxView draws sent and received data over the right side of the
+// terminal, newest at the bottom: in two panes, or one list with each
+// direction in its own color
+func (app *Application) drawTxrxView(screenWidth, contentHeight int) {
+	mode := app.txrxMode()
+	if mode == txrxOff || contentHeight < 4 {
+		return
+	}
+
+	width := min(screenWidth, max(txrxPanelMinWidth, screenWidth*2/5))
+	left := screenWidth - width
+	entries := app.txrxEntries()
+	headerStyle := tcell.StyleDefault.Background(app.theme.background).Foreground(app.theme.foreground).Bold(true)
+
+	if mode == txrxInterleaved {
+		app.drawTxrxPane(left, 0, width, contentHeight, " TX/RX", headerStyle, txrxRows(entries, width-1, true))
+		return
+	}
+
+	var sent, received []history.HistoryEntry
+	for _, entry := range entries {
+		if entry.Direction == history.DirectionInput {
+			sent = append(sent, entry)
+		} else {
+			received = append(received, entry)
+		}
+	}
+	txHeight := contentHeight / 2
+	app.drawTxrxPane(left, 0, width, txHeight, " TX (sent)", headerStyle, txrxRows(sent, width-1, false))
+	app.drawTxrxPane(left, txHeight, width, contentHeight-txHeight, " RX (received)", headerStyle, txrxRows(received, width-1, false))
+}
+
+// drawTxrxPane draws a header and the last rows that fit below it
+func (app *Application) drawTxrxPane(left, top, width, height int, title string, headerStyle tcell.Style, rows []txrxRow) {
+	app.drawPanelLine(left, top, width, title, headerStyle)
+	rows = rows[max(0, len(rows)-(height-1)):]
+
+	y := top + height - len(rows)
+	for row := top + 1; row < y; row++ {
+		app.drawPanelLine(left, row, width, "", tcell.StyleDefault)
+	}
+	for _, row := range rows {
+		color := rxColor
+		if row.direction == history.DirectionInput {
+			color = txColor
+		}
+		app.drawPanelLine(left, y, width, row.text, tcell.StyleDefault.Foreground(color))
+		y++
+	}
+}
+
+// buildTxrxMenu creates the TX/RX view submenu, a choice of layouts
+func (app *Application) buildTxrxMenu() *menu.Menu {
+	txrxMenu := menu.NewMenu("TX/RX View", app.screen)
+	layouts := []struct {
+		label   string
+		mode    int
+		message string
+	}{
+		{"Off", txrxOff, "TX/RX view off"},
+		{"Split Panes", txrxSplit, "Sent data above, received data below"},
+		{"Interleaved", txrxInterleaved, "Sent and received data in time order, by color"},
+	}
+	current := app.txrxMode()
+	for _, layout := range layouts {
+		txrxMenu.AddRadioItem("txrx", layout.label, layout.mode == current, func() error {
+			app.logDebug("Menu: TX/RX view %s", layout.label)
+			app.setTxrxMode(layout.mode)
+			app.updateStatusMessage(layout.message)
+			return nil
+		})
+	}
+	return txrxMenu
+}
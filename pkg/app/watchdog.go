@@ -0,0 +1,143 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/config"
+)
+
+// watchdogInterval is how often the idle watchdog checks for silence
+const watchdogInterval = time.Second
+
+// Watchdog events, passed to the alert command in STERM_EVENT
+const (
+	watchdogIdle   = "idle"
+	watchdogResume = "resume"
+)
+
+// activityWatchdog tracks when data was last received to detect a device
+// going quiet and coming back
+type activityWatchdog struct {
+	settings  config.WatchdogSettings
+	lastData  time.Time // Last data received, or when watching started
+	idleFired bool      // The idle alert has fired for the current silence
+	mu        sync.Mutex
+}
+
+// configure applies new watchdog settings
+func (w *activityWatchdog) configure(settings config.WatchdogSettings) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.settings = settings
+}
+
+// start begins watching at now, as if data had just been received
+func (w *activityWatchdog) start(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastData = now
+	w.idleFired = false
+}
+
+// data records data received at now. Returns how long the device was quiet
+// and true if that was long enough to raise the resume alert.
+func (w *activityWatchdog) data(now time.Time) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	quiet := now.Sub(w.lastData)
+	w.lastData = now
+	w.idleFired = false
+
+	limit := time.Duration(w.settings.ResumeSeconds) * time.Second
+	return quiet, limit > 0 && quiet >= limit
+}
+
+// check returns how long the device has been quiet at now and true the
+// first time that exceeds the idle limit
+func (w *activityWatchdog) check(now time.Time) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	quiet := now.Sub(w.lastData)
+	limit := time.Duration(w.settings.IdleSeconds) * time.Second
+	if limit <= 0 || w.idleFired || quiet < limit {
+		return quiet, false
+	}
+	w.idleFired = true
+	return quiet, true
+}
+
+// runWatchdog raises the idle alert when no data arrives for too long
+func (app *Application) runWatchdog() {
+	defer app.wg.Done()
+	defer app.recoverPanic("runWatchdog")
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if app.isPaused {
+				// Nothing is read while paused, so silence means nothing
+				app.watchdog.start(now)
+				continue
+			}
+			if quiet, fire := app.watchdog.check(now); fire {
+				app.watchdogAlert(watchdogIdle, quiet)
+			}
+		}
+	}
+}
+
+// watchdogActivity records received data and raises the resume alert if
+// the device had been quiet for long enough
+func (app *Application) watchdogActivity() {
+	if quiet, fire := app.watchdog.data(time.Now()); fire {
+		app.watchdogAlert(watchdogResume, quiet)
+	}
+}
+
+// watchdogAlert shows a watchdog alert and runs the configured bell and command
+func (app *Application) watchdogAlert(event string, quiet time.Duration) {
+	app.watchdog.mu.Lock()
+	settings := app.watchdog.settings
+	app.watchdog.mu.Unlock()
+
+	quiet = quiet.Round(time.Second)
+	if event == watchdogIdle {
+		app.notifyWarning("No data received for %s", quiet)
+	} else {
+		app.notifyWarning("Data received after %s of silence", quiet)
+	}
+	app.logDebug("Watchdog %s alert after %s", event, quiet)
+
+	if settings.Bell {
+		app.ringBell()
+	}
+	if args := strings.Fields(settings.Command); len(args) > 0 {
+		go app.runWatchdogCommand(args, event, quiet)
+	}
+}
+
+// runWatchdogCommand runs the alert command with details in its environment
+func (app *Application) runWatchdogCommand(args []string, event string, quiet time.Duration) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"STERM_EVENT="+event,
+		"STERM_PORT="+app.config.SerialConfig.Port,
+		fmt.Sprintf("STERM_QUIET_SECONDS=%d", int(quiet.Seconds())),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		app.logDebug("Watchdog command output: %s", output)
+		app.notifyError("Watchdog command failed: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"os"
+	"time"
+
+	"sterm/pkg/terminal"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ambiguousProbeTimeout bounds how long startup waits for the host terminal
+// to answer the ambiguous width probe
+const ambiguousProbeTimeout = 300 * time.Millisecond
+
+// detectHostAmbiguousWidth asks the host terminal how wide it draws East
+// Asian ambiguous characters. Must run before the screen takes over the tty.
+func (app *Application) detectHostAmbiguousWidth() {
+	// An explicit RUNEWIDTH_EASTASIAN overrides detection, as in other tools
+	if os.Getenv("RUNEWIDTH_EASTASIAN") != "" {
+		return
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		app.logDebug("Ambiguous width detection skipped: %v", err)
+		return
+	}
+	defer tty.Close()
+
+	wide, err := terminal.DetectAmbiguousWidth(tty, ambiguousProbeTimeout)
+	if err != nil {
+		app.logDebug("Ambiguous width detection failed: %v", err)
+		return
+	}
+	app.hostAmbiguousWide, app.hostAmbiguousKnown = wide, true
+	app.logDebug("Host terminal draws ambiguous characters %s", widthName(wide))
+}
+
+// resolveAmbiguousWide decides the ambiguous character width for a
+// display.ambiguous_width setting. "auto" follows RUNEWIDTH_EASTASIAN, then
+// the host terminal, then the locale.
+func resolveAmbiguousWide(mode string, hostWide, hostKnown bool) bool {
+	switch mode {
+	case "wide":
+		return true
+	case "narrow":
+		return false
+	}
+
+	if env := os.Getenv("RUNEWIDTH_EASTASIAN"); env != "" {
+		return env == "1"
+	}
+	if hostKnown {
+		return hostWide
+	}
+	return runewidth.IsEastAsian()
+}
+
+// applyAmbiguousWidth sets the ambiguous character width used by the
+// emulator and the renderer. Text already on screen keeps its layout.
+func (app *Application) applyAmbiguousWidth(mode string) {
+	wide := resolveAmbiguousWide(mode, app.hostAmbiguousWide, app.hostAmbiguousKnown)
+	if wide == terminal.AmbiguousWide() {
+		return
+	}
+	terminal.SetAmbiguousWide(wide)
+	app.logDebug("Ambiguous characters are now %s", widthName(wide))
+}
+
+// widthName describes an ambiguous width for logs
+func widthName(wide bool) string {
+	if wide {
+		return "wide"
+	}
+	return "narrow"
+}
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/menu"
+)
+
+// windowSizeDebounce is how long the window has to stay the same size
+// before it is reported, so dragging a window edge sends one report
+const windowSizeDebounce = 300 * time.Millisecond
+
+// Ways of reporting the terminal size to the device
+const (
+	WindowSizeNone   = "none"   // Not reported; the usual for serial consoles
+	WindowSizeXterm  = "xterm"  // The xterm resize sequence, CSI 8 ; rows ; cols t
+	WindowSizeStty   = "stty"   // Typed as a stty command, for a shell prompt
+	WindowSizeCustom = "custom" // Text from a template
+)
+
+// WindowSize is how the terminal size is reported to the device. Ptys and
+// telnet learn it out of band regardless.
+type WindowSize struct {
+	Mode     string
+	Template string // Text sent by WindowSizeCustom, with {rows} and {cols}
+}
+
+// ParseWindowSize reads a strategy: none, xterm, stty, or a template such
+// as "resize {cols} {rows}\r" with backslash escapes (\r, \n, \e, \x1b).
+// An empty value is none.
+func ParseWindowSize(value string) (WindowSize, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", WindowSizeNone:
+		return WindowSize{Mode: WindowSizeNone}, nil
+	case WindowSizeXterm:
+		return WindowSize{Mode: WindowSizeXterm}, nil
+	case WindowSizeStty:
+		return WindowSize{Mode: WindowSizeStty}, nil
+	}
+
+	if !strings.Contains(value, "{rows}") && !strings.Contains(value, "{cols}") {
+		return WindowSize{}, fmt.Errorf("invalid window size strategy %q: want none, xterm, stty or a template with {rows} and {cols}", value)
+	}
+	template, err := unescapeTemplate(value)
+	if err != nil {
+		return WindowSize{}, fmt.Errorf("invalid window size template %q: %w", value, err)
+	}
+	return WindowSize{Mode: WindowSizeCustom, Template: template}, nil
+}
+
+// unescapeTemplate interprets Go backslash escapes in a template, plus \e
+// for ESC
+func unescapeTemplate(template string) (string, error) {
+	template = strings.ReplaceAll(template, `\e`, `\x1b`)
+	template = strings.ReplaceAll(template, `"`, `\"`)
+	return strconv.Unquote(`"` + template + `"`)
+}
+
+// IsZero reports whether the size isn't reported
+func (w WindowSize) IsZero() bool {
+	return w.Mode == "" || w.Mode == WindowSizeNone
+}
+
+// String describes the strategy for the menu and status messages
+func (w WindowSize) String() string {
+	if w.Mode == "" {
+		return WindowSizeNone
+	}
+	if w.Mode == WindowSizeCustom {
+		return strconv.Quote(w.Template)
+	}
+	return w.Mode
+}
+
+// Report returns what to send for a size, nil if nothing is
+func (w WindowSize) Report(rows, cols int) []byte {
+	switch w.Mode {
+	case WindowSizeXterm:
+		return fmt.Appendf(nil, "\x1b[8;%d;%dt", rows, cols)
+	case WindowSizeStty:
+		return fmt.Appendf(nil, "stty rows %d cols %d\r", rows, cols)
+	case WindowSizeCustom:
+		r := strings.NewReplacer("{rows}", strconv.Itoa(rows), "{cols}", strconv.Itoa(cols))
+		return []byte(r.Replace(w.Template))
+	}
+	return nil
+}
+
+// windowSizeState reports the terminal size, waiting for resizes to settle
+type windowSizeState struct {
+	strategy   WindowSize
+	timer      *time.Timer // Pending report after a resize
+	rows, cols int         // Last size reported
+	mu         sync.Mutex
+}
+
+// windowSizeStrategy returns how the size is reported
+func (app *Application) windowSizeStrategy() WindowSize {
+	w := &app.windowSize
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.strategy
+}
+
+// setWindowSizeStrategy changes how the size is reported, reporting it
+// straight away the new way
+func (app *Application) setWindowSizeStrategy(strategy WindowSize) {
+	w := &app.windowSize
+	w.mu.Lock()
+	w.strategy = strategy
+	w.rows, w.cols = 0, 0
+	w.mu.Unlock()
+	app.reportWindowSize(true)
+}
+
+// reportWindowSize sends the terminal size to the device the configured
+// way. Unless forced, a size already reported isn't sent again. A stty
+// command is only typed at a shell prompt. Returns false if nothing was
+// sent.
+func (app *Application) reportWindowSize(force bool) bool {
+	if app.config.Monitor || app.isPaused || app.terminal == nil {
+		return false
+	}
+	w := &app.windowSize
+	w.mu.Lock()
+	screen := app.terminal.GetScreen()
+	rows, cols := screen.Height, screen.Width
+	report := w.strategy.Report(rows, cols)
+	if report == nil || (!force && rows == w.rows && cols == w.cols) {
+		w.mu.Unlock()
+		return false
+	}
+	if w.strategy.Mode == WindowSizeStty {
+		if !app.atShellPrompt() {
+			w.mu.Unlock()
+			app.logDebug("Terminal size not typed: no shell prompt at the cursor")
+			return false
+		}
+		report = app.sttyCommand(rows, cols)
+	}
+	w.rows, w.cols = rows, cols
+	strategy := w.strategy
+	w.mu.Unlock()
+
+	if !app.queueWrite(report, false, nil) {
+		return false
+	}
+	app.logDebug("Reported terminal size %dx%d to the device (%s)", cols, rows, strategy)
+	return true
+}
+
+// scheduleWindowSizeReport reports the size once resizing has stopped for
+// windowSizeDebounce
+func (app *Application) scheduleWindowSizeReport() {
+	w := &app.windowSize
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.strategy.IsZero() {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(windowSizeDebounce, func() {
+		app.reportWindowSize(false)
+	})
+}
+
+// buildWindowSizeMenu creates the window size submenu: the ways of
+// reporting the size and a way to send it now
+func (app *Application) buildWindowSizeMenu() *menu.Menu {
+	sizeMenu := menu.NewMenu("Window Size", app.screen)
+	current := app.windowSizeStrategy()
+
+	strategies := []struct {
+		label    string
+		strategy WindowSize
+	}{
+		{"Don't Report", WindowSize{Mode: WindowSizeNone}},
+		{"xterm Sequence", WindowSize{Mode: WindowSizeXterm}},
+		{"Type stty Command", WindowSize{Mode: WindowSizeStty}},
+	}
+	// A template can only be given on the command line
+	if configured := app.config.WindowSize; configured.Mode == WindowSizeCustom {
+		strategies = append(strategies, struct {
+			label    string
+			strategy WindowSize
+		}{"Template " + configured.String(), configured})
+	}
+	for _, s := range strategies {
+		checked := s.strategy.Mode == current.Mode || s.strategy.Mode == WindowSizeNone && current.IsZero()
+		sizeMenu.AddRadioItem("window-size", s.label, checked, func() error {
+			app.logDebug("Menu: Window size strategy %s", s.strategy)
+			app.setWindowSizeStrategy(s.strategy)
+			app.updateStatusMessage("Window size reporting: " + s.strategy.String())
+			return nil
+		})
+	}
+
+	sizeMenu.AddSeparator()
+	sizeMenu.AddItem("Report Size Now", "", func() error {
+		if !app.reportWindowSize(true) {
+			if app.windowSizeStrategy().Mode == WindowSizeStty {
+				app.notifyWarning("Window size not sent - no shell prompt at the cursor")
+			} else {
+				app.notifyWarning("Window size not sent - choose a way to report it first")
+			}
+			return nil
+		}
+		screen := app.terminal.GetScreen()
+		app.updateStatusMessage(fmt.Sprintf("Reported %dx%d", screen.Width, screen.Height))
+		return nil
+	})
+	sizeMenu.AddItem("Type stty Command at Prompt", "", func() error {
+		app.logDebug("Menu: Type stty command")
+		if err := app.typeSttyCommand(); err != nil {
+			app.notifyWarning("stty command not typed - %v", err)
+			return nil
+		}
+		screen := app.terminal.GetScreen()
+		app.updateStatusMessage(fmt.Sprintf("Typed the size %dx%d at the prompt", screen.Width, screen.Height))
+		return nil
+	})
+	return sizeMenu
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"sterm/pkg/terminal"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// wrapMarker is drawn in the gutter next to a row whose text continues on
+// the next row because it was too long for the screen
+const wrapMarker = '↩'
+
+// gutterWidth returns the columns kept free right of the terminal for wrap
+// markers: one while they are shown
+func (app *Application) gutterWidth() int {
+	if app.wrapMarkers.Load() {
+		return 1
+	}
+	return 0
+}
+
+// terminalSize returns the size the terminal gets on the screen: all of it
+// but the status bar and the wrap marker gutter
+func (app *Application) terminalSize() (width, height int) {
+	width, height = app.screen.Size()
+	return max(1, width-app.gutterWidth()), height - 1
+}
+
+// setWrapMarkers shows or hides the wrap marker gutter, giving the terminal
+// a column less or more
+func (app *Application) setWrapMarkers(show bool) {
+	if app.wrapMarkers.Swap(show) == show {
+		return
+	}
+	if app.terminal != nil && app.screen != nil {
+		if width, height := app.terminalSize(); height > 0 {
+			_ = app.terminal.Resize(width, height)
+			app.resizePort(width, height)
+			app.scheduleWindowSizeReport()
+		}
+		app.screen.Clear()
+	}
+	app.forceRedraw()
+}
+
+// drawWrapMarkers marks the rows whose text wraps onto the next row in the
+// gutter right of the terminal
+func (app *Application) drawWrapMarkers(buffer [][]terminal.Cell, contentHeight int) {
+	if !app.wrapMarkers.Load() {
+		return
+	}
+	screenWidth, _ := app.screen.Size()
+	x := screenWidth - 1
+	style := tcell.StyleDefault.Dim(true)
+	for y := 0; y < contentHeight; y++ {
+		marker := ' '
+		if y < len(buffer) && terminal.IsWrapped(buffer[y]) {
+			marker = wrapMarker
+		}
+		app.screen.SetContent(x, y, marker, nil, style)
+	}
+}
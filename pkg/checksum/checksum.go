@@ -0,0 +1,101 @@
+// Package checksum computes the CRCs and simple checksums common in serial
+// protocols
+package checksum
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// Algorithm is a named checksum
+type Algorithm struct {
+	Name    string
+	Bytes   int // Size of the result in bytes
+	Compute func(data []byte) uint32
+}
+
+// Format returns the checksum of data in hex, e.g. "0x4B37"
+func (a Algorithm) Format(data []byte) string {
+	return fmt.Sprintf("0x%0*X", a.Bytes*2, a.Compute(data))
+}
+
+// Algorithms lists the supported checksums. The CRC parameters follow the
+// catalogue at reveng.sourceforge.io, so results match other tools.
+var Algorithms = []Algorithm{
+	{"CRC-16/MODBUS", 2, func(data []byte) uint32 { return uint32(CRC16Modbus(data)) }},
+	{"CRC-16/CCITT-FALSE", 2, func(data []byte) uint32 { return uint32(CRC16CCITT(data)) }},
+	{"CRC-16/XMODEM", 2, func(data []byte) uint32 { return uint32(CRC16XModem(data)) }},
+	{"CRC-32", 4, func(data []byte) uint32 { return crc32.ChecksumIEEE(data) }},
+	{"CRC-32C", 4, func(data []byte) uint32 { return crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) }},
+	{"XOR-8", 1, func(data []byte) uint32 { return uint32(XOR8(data)) }},
+	{"SUM-8", 1, func(data []byte) uint32 { return uint32(Sum8(data)) }},
+	{"LRC-8", 1, func(data []byte) uint32 { return uint32(LRC8(data)) }},
+}
+
+// CRC16Modbus computes the CRC-16/MODBUS of data: reflected polynomial
+// 0x8005, initial value 0xFFFF. Modbus RTU sends it low byte first.
+func CRC16Modbus(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRC16CCITT computes the CRC-16/CCITT-FALSE of data: polynomial 0x1021,
+// initial value 0xFFFF, not reflected
+func CRC16CCITT(data []byte) uint16 {
+	return crc16Normal(data, 0xFFFF)
+}
+
+// CRC16XModem computes the CRC-16/XMODEM of data: polynomial 0x1021,
+// initial value 0
+func CRC16XModem(data []byte) uint16 {
+	return crc16Normal(data, 0)
+}
+
+// crc16Normal computes an unreflected CRC-16 with polynomial 0x1021
+func crc16Normal(data []byte, crc uint16) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// XOR8 XORs all bytes together, as in NMEA sentences
+func XOR8(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum ^= b
+	}
+	return sum
+}
+
+// Sum8 adds all bytes, modulo 256
+func Sum8(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// LRC8 is the two's complement of Sum8, as in Modbus ASCII: adding it to
+// the sum of the data gives zero
+func LRC8(data []byte) byte {
+	return -Sum8(data)
+}
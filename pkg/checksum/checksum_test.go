@@ -0,0 +1,34 @@
+package checksum
+
+import "testing"
+
+func TestAlgorithms(t *testing.T) {
+	// Check values for "123456789" from the CRC catalogue
+	check := []byte("123456789")
+	expected := map[string]string{
+		"CRC-16/MODBUS":      "0x4B37",
+		"CRC-16/CCITT-FALSE": "0x29B1",
+		"CRC-16/XMODEM":      "0x31C3",
+		"CRC-32":             "0xCBF43926",
+		"CRC-32C":            "0xE3069283",
+		"XOR-8":              "0x31",
+		"SUM-8":              "0xDD",
+		"LRC-8":              "0x23",
+	}
+
+	if len(Algorithms) != len(expected) {
+		t.Errorf("Expected %d algorithms, got %d", len(expected), len(Algorithms))
+	}
+	for _, a := range Algorithms {
+		if got := a.Format(check); got != expected[a.Name] {
+			t.Errorf("%s = %s, want %s", a.Name, got, expected[a.Name])
+		}
+	}
+}
+
+func TestLRC8(t *testing.T) {
+	data := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A}
+	if sum := Sum8(data) + LRC8(data); sum != 0 {
+		t.Errorf("Sum plus LRC = %02X, want 0", sum)
+	}
+}
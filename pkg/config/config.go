@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sterm/pkg/serial"
 	"strings"
 	"time"
@@ -29,6 +30,24 @@ type ConfigInfo struct {
 	CreatedAt   time.Time           `json:"created_at"`
 	LastUsedAt  time.Time           `json:"last_used_at"`
 	Description string              `json:"description,omitempty"`
+	AutoLogin   []LoginStep         `json:"auto_login,omitempty"`
+
+	// SuppressEcho hides the device's echo of sent data, for half-duplex
+	// devices that repeat every byte back
+	SuppressEcho bool `json:"suppress_echo,omitempty"`
+
+	// StatusBar holds status bar templates used over the settings file's
+	StatusBar *StatusFormat `json:"status_bar,omitempty"`
+}
+
+// LoginStep is one expect/send pair of a profile's auto-login: wait for
+// Expect in the received text, then send Send, or the stored secret named
+// Secret, followed by Enter. An empty Expect sends straight away.
+type LoginStep struct {
+	Expect         string `json:"expect,omitempty"`
+	Send           string `json:"send,omitempty"`
+	Secret         string `json:"secret,omitempty"`          // Looked up with the secrets package, never stored here
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"` // How long to wait for Expect (0 = 30 seconds)
 }
 
 // Validate checks if the configuration info is valid
@@ -54,6 +73,63 @@ type ConfigStorage struct {
 	Version string                `json:"version"`
 }
 
+// Validate checks every saved configuration and reports all problems as a *ValidationError
+func (s ConfigStorage) Validate() error {
+	var issues []ValidationIssue
+
+	names := make([]string, 0, len(s.Configs))
+	for name := range s.Configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := s.Configs[name]
+		path := "configs." + name
+		if info.Name != name {
+			issues = append(issues, ValidationIssue{
+				Path:    path + ".name",
+				Message: fmt.Sprintf("name %q doesn't match its key %q", info.Name, name),
+			})
+		}
+		if err := info.Config.Validate(); err != nil {
+			issues = append(issues, ValidationIssue{Path: path + ".config", Message: err.Error()})
+		}
+		if info.CreatedAt.IsZero() {
+			issues = append(issues, ValidationIssue{Path: path + ".created_at", Message: "missing creation time"})
+		}
+		for i, step := range info.AutoLogin {
+			stepPath := fmt.Sprintf("%s.auto_login.%d", path, i)
+			if step.Send != "" && step.Secret != "" {
+				issues = append(issues, ValidationIssue{Path: stepPath, Message: "set either send or secret, not both"})
+			}
+			if step.TimeoutSeconds < 0 {
+				issues = append(issues, ValidationIssue{Path: stepPath + ".timeout_seconds", Message: "must not be negative"})
+			}
+		}
+		if info.StatusBar != nil {
+			issues = append(issues, info.StatusBar.validate(path+".status_bar")...)
+		}
+	}
+
+	return errorFromIssues(issues)
+}
+
+// parseStorage migrates configuration storage data to the current version
+// and decodes it, returning the migrations that were applied
+func parseStorage(data []byte) (ConfigStorage, []Migration, error) {
+	migrated, _, applied, err := migrateJSON(data, CurrentStorageVersion, storageMigrations)
+	if err != nil {
+		return ConfigStorage{}, nil, err
+	}
+
+	var storage ConfigStorage
+	if err := json.Unmarshal(migrated, &storage); err != nil {
+		return ConfigStorage{}, nil, err
+	}
+	return storage, applied, nil
+}
+
 // FileConfigManager implements ConfigManager using file storage
 type FileConfigManager struct {
 	configDir  string
@@ -91,7 +167,7 @@ func (fcm *FileConfigManager) Initialize() error {
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		storage := ConfigStorage{
 			Configs: make(map[string]ConfigInfo),
-			Version: "1.0",
+			Version: CurrentStorageVersion,
 		}
 
 		if err := fcm.saveStorage(storage); err != nil {
@@ -133,6 +209,9 @@ func (fcm *FileConfigManager) SaveConfig(name string, config serial.SerialConfig
 	if existing, exists := storage.Configs[name]; exists {
 		configInfo.CreatedAt = existing.CreatedAt
 		configInfo.Description = existing.Description
+		configInfo.AutoLogin = existing.AutoLogin
+		configInfo.SuppressEcho = existing.SuppressEcho
+		configInfo.StatusBar = existing.StatusBar
 	}
 
 	storage.Configs[name] = configInfo
@@ -313,6 +392,142 @@ func (fcm *FileConfigManager) SetConfigDescription(name, description string) err
 	return nil
 }
 
+// LoadAutoLogin returns the auto-login steps of a configuration
+func (fcm *FileConfigManager) LoadAutoLogin(name string) ([]LoginStep, error) {
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return nil, fmt.Errorf("configuration '%s' not found", name)
+	}
+	return configInfo.AutoLogin, nil
+}
+
+// SetAutoLogin replaces the auto-login steps of a configuration. No steps
+// turns auto-login off.
+func (fcm *FileConfigManager) SetAutoLogin(name string, steps []LoginStep) error {
+	if name == "" {
+		return fmt.Errorf("configuration name cannot be empty")
+	}
+
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return fmt.Errorf("configuration '%s' not found", name)
+	}
+
+	configInfo.AutoLogin = steps
+	if err := (ConfigStorage{Configs: map[string]ConfigInfo{name: configInfo}}).Validate(); err != nil {
+		return err
+	}
+	storage.Configs[name] = configInfo
+
+	if err := fcm.saveStorage(storage); err != nil {
+		return fmt.Errorf("failed to save auto-login: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSuppressEcho reports whether a configuration hides the device's echo
+func (fcm *FileConfigManager) LoadSuppressEcho(name string) (bool, error) {
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return false, fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return false, fmt.Errorf("configuration '%s' not found", name)
+	}
+	return configInfo.SuppressEcho, nil
+}
+
+// SetSuppressEcho turns hiding the device's echo on or off for a
+// configuration
+func (fcm *FileConfigManager) SetSuppressEcho(name string, suppress bool) error {
+	if name == "" {
+		return fmt.Errorf("configuration name cannot be empty")
+	}
+
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return fmt.Errorf("configuration '%s' not found", name)
+	}
+
+	configInfo.SuppressEcho = suppress
+	storage.Configs[name] = configInfo
+
+	if err := fcm.saveStorage(storage); err != nil {
+		return fmt.Errorf("failed to save echo suppression: %w", err)
+	}
+
+	return nil
+}
+
+// LoadStatusFormat returns the status bar templates of a configuration,
+// empty if it has none
+func (fcm *FileConfigManager) LoadStatusFormat(name string) (StatusFormat, error) {
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return StatusFormat{}, fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return StatusFormat{}, fmt.Errorf("configuration '%s' not found", name)
+	}
+	if configInfo.StatusBar == nil {
+		return StatusFormat{}, nil
+	}
+	return *configInfo.StatusBar, nil
+}
+
+// SetStatusFormat replaces the status bar templates of a configuration. An
+// empty format goes back to the settings file's.
+func (fcm *FileConfigManager) SetStatusFormat(name string, format StatusFormat) error {
+	if name == "" {
+		return fmt.Errorf("configuration name cannot be empty")
+	}
+
+	storage, err := fcm.loadStorage()
+	if err != nil {
+		return fmt.Errorf("failed to load configurations: %w", err)
+	}
+
+	configInfo, exists := storage.Configs[name]
+	if !exists {
+		return fmt.Errorf("configuration '%s' not found", name)
+	}
+
+	configInfo.StatusBar = nil
+	if !format.IsZero() {
+		configInfo.StatusBar = &format
+	}
+	if err := (ConfigStorage{Configs: map[string]ConfigInfo{name: configInfo}}).Validate(); err != nil {
+		return err
+	}
+	storage.Configs[name] = configInfo
+
+	if err := fcm.saveStorage(storage); err != nil {
+		return fmt.Errorf("failed to save status bar format: %w", err)
+	}
+
+	return nil
+}
+
 // ExportConfig exports a configuration to a JSON file
 func (fcm *FileConfigManager) ExportConfig(name, filePath string) error {
 	if name == "" {
@@ -432,10 +647,16 @@ func (fcm *FileConfigManager) RestoreConfigs(backupPath string) error {
 		return fmt.Errorf("failed to read backup file: %w", err)
 	}
 
-	var storage ConfigStorage
-	if err := json.Unmarshal(data, &storage); err != nil {
+	// Backups from older versions are upgraded before being restored
+	storage, applied, err := parseStorage(data)
+	if err != nil {
 		return fmt.Errorf("invalid backup file format: %w", err)
 	}
+	if len(applied) > 0 {
+		if data, err = json.MarshalIndent(storage, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode migrated backup: %w", err)
+		}
+	}
 
 	// Validate all configurations in the backup
 	for name, configInfo := range storage.Configs {
@@ -470,14 +691,14 @@ func (fcm *FileConfigManager) loadStorage() (ConfigStorage, error) {
 			// Return empty storage if file doesn't exist
 			return ConfigStorage{
 				Configs: make(map[string]ConfigInfo),
-				Version: "1.0",
+				Version: CurrentStorageVersion,
 			}, nil
 		}
 		return ConfigStorage{}, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var storage ConfigStorage
-	if err := json.Unmarshal(data, &storage); err != nil {
+	storage, _, err := parseStorage(data)
+	if err != nil {
 		return ConfigStorage{}, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
@@ -492,6 +713,7 @@ func (fcm *FileConfigManager) loadStorage() (ConfigStorage, error) {
 // saveStorage saves the configuration storage to file
 func (fcm *FileConfigManager) saveStorage(storage ConfigStorage) error {
 	configPath := fcm.getConfigPath()
+	storage.Version = CurrentStorageVersion
 
 	data, err := json.MarshalIndent(storage, "", "  ")
 	if err != nil {
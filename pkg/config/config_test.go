@@ -1,10 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sterm/pkg/serial"
+	"strings"
 	"testing"
 	"time"
 )
@@ -396,6 +398,93 @@ func TestFileConfigManager_ConfigExists(t *testing.T) {
 	}
 }
 
+func TestFileConfigManager_AutoLogin(t *testing.T) {
+	manager := NewFileConfigManager(t.TempDir())
+	if err := manager.SaveConfig("router", serial.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	steps := []LoginStep{
+		{Expect: "login:", Send: "admin"},
+		{Expect: "Password:", Secret: "router"},
+	}
+	if err := manager.SetAutoLogin("router", steps); err != nil {
+		t.Fatalf("SetAutoLogin() failed: %v", err)
+	}
+
+	// Saving the port settings again keeps the steps
+	if err := manager.SaveConfig("router", serial.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+	loaded, err := manager.LoadAutoLogin("router")
+	if err != nil {
+		t.Fatalf("LoadAutoLogin() failed: %v", err)
+	}
+	if len(loaded) != 2 || loaded[1] != steps[1] {
+		t.Errorf("LoadAutoLogin() = %+v, want %+v", loaded, steps)
+	}
+
+	if err := manager.SetAutoLogin("router", []LoginStep{{Expect: "x", Send: "a", Secret: "b"}}); err == nil {
+		t.Error("Expected an error for a step with both send and secret")
+	}
+	if err := manager.SetAutoLogin("missing", steps); err == nil {
+		t.Error("Expected an error for an unknown configuration")
+	}
+}
+
+func TestFileConfigManager_SuppressEcho(t *testing.T) {
+	manager := NewFileConfigManager(t.TempDir())
+	if err := manager.SaveConfig("modem", serial.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+	if err := manager.SetSuppressEcho("modem", true); err != nil {
+		t.Fatalf("SetSuppressEcho() failed: %v", err)
+	}
+
+	// Saving the port settings again keeps the toggle
+	if err := manager.SaveConfig("modem", serial.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+	if suppress, err := manager.LoadSuppressEcho("modem"); err != nil || !suppress {
+		t.Errorf("LoadSuppressEcho() = %v, %v, want true", suppress, err)
+	}
+
+	if err := manager.SetSuppressEcho("missing", true); err == nil {
+		t.Error("Expected an error for an unknown configuration")
+	}
+}
+
+func TestFileConfigManager_StatusFormat(t *testing.T) {
+	manager := NewFileConfigManager(t.TempDir())
+	if err := manager.SaveConfig("bench", serial.DefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig() failed: %v", err)
+	}
+
+	format := StatusFormat{Left: " {profile} {port} ", Right: " {tx}/{rx} {time} "}
+	if err := manager.SetStatusFormat("bench", format); err != nil {
+		t.Fatalf("SetStatusFormat() failed: %v", err)
+	}
+	if loaded, err := manager.LoadStatusFormat("bench"); err != nil || loaded != format {
+		t.Errorf("LoadStatusFormat() = %+v, %v, want %+v", loaded, err, format)
+	}
+
+	// Parts left empty come from the settings file
+	merged := format.Over(StatusFormat{Left: " x ", Center: " {log} "})
+	if merged.Left != format.Left || merged.Center != " {log} " || !merged.Uses("time") {
+		t.Errorf("Over() = %+v", merged)
+	}
+
+	if err := manager.SetStatusFormat("bench", StatusFormat{Left: "{host}"}); err == nil {
+		t.Error("Expected an error for an unknown placeholder")
+	}
+	if err := manager.SetStatusFormat("bench", StatusFormat{}); err != nil {
+		t.Fatalf("SetStatusFormat() failed: %v", err)
+	}
+	if loaded, _ := manager.LoadStatusFormat("bench"); !loaded.IsZero() {
+		t.Errorf("LoadStatusFormat() = %+v after clearing", loaded)
+	}
+}
+
 func TestFileConfigManager_SetConfigDescription(t *testing.T) {
 	tempDir := t.TempDir()
 	manager := NewFileConfigManager(tempDir)
@@ -723,3 +812,219 @@ func TestFileConfigManager_SequentialOperations(t *testing.T) {
 		t.Errorf("Should have 7 configs after deletions, got %d", len(finalConfigs))
 	}
 }
+
+func TestLoadSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, SettingsFileName)
+
+	// Missing file gives defaults
+	settings, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings on missing file failed: %v", err)
+	}
+	if !settings.StatusBar.ShowPort || settings.Theme.StatusBackground != "darkblue" {
+		t.Errorf("Expected default settings, got %+v", settings)
+	}
+
+	// Partial file keeps defaults for missing fields
+	content := `{"theme": {"status_background": "#202040"}, "status_bar": {"show_port": true, "show_hints": false, "show_stats": true}, "keybindings": {"screenshot": "Alt+O"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	settings, err = LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if settings.Theme.StatusBackground != "#202040" || settings.Theme.StatusForeground != "white" {
+		t.Errorf("Unexpected theme: %+v", settings.Theme)
+	}
+	if settings.StatusBar.ShowHints {
+		t.Error("Expected hints to be hidden")
+	}
+	if settings.Keybindings["screenshot"] != "Alt+O" {
+		t.Errorf("Expected screenshot keybinding, got %v", settings.Keybindings)
+	}
+
+	// Invalid values are reported rather than silently ignored
+	invalid := []string{
+		`{"theme": {"status_background": "notacolor"}}`,
+		`{"logging": {"format": "xml"}}`,
+		`{"keybindings": {"screenshot": "Ctrl+P"}}`,
+		`{"keybindings": {"screenshot": "Alt+O", "bookmark": "alt+o"}}`,
+		`{not json`,
+	}
+	for _, content := range invalid {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write settings: %v", err)
+		}
+		if _, err := LoadSettings(path); err == nil {
+			t.Errorf("Expected error for settings %s", content)
+		}
+	}
+}
+
+func TestSettingsWatcher(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, SettingsFileName)
+
+	type result struct {
+		settings Settings
+		err      error
+	}
+	results := make(chan result, 10)
+	watcher, err := NewSettingsWatcher(path, func(settings Settings, err error) {
+		results <- result{settings, err}
+	})
+	if err != nil {
+		t.Fatalf("NewSettingsWatcher failed: %v", err)
+	}
+	defer watcher.Close()
+
+	// Creating the file triggers a reload
+	if err := os.WriteFile(path, []byte(`{"logging": {"debug": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	select {
+	case r := <-results:
+		if r.err != nil {
+			t.Fatalf("Unexpected reload error: %v", r.err)
+		}
+		if !r.settings.Logging.Debug {
+			t.Error("Expected reloaded settings to enable debug logging")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for settings reload")
+	}
+
+	// Other files in the directory are ignored, invalid edits report errors
+	_ = os.WriteFile(filepath.Join(tempDir, "configs.json"), []byte("{}"), 0644)
+	if err := os.WriteFile(path, []byte(`{"logging": {"format": "xml"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+	select {
+	case r := <-results:
+		if r.err == nil {
+			t.Error("Expected error for invalid settings")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for settings reload")
+	}
+}
+
+func TestStorageMigration(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewFileConfigManager(tempDir)
+
+	// Legacy file without a version, with hand-written parity and timeout
+	legacy := `{"configs": {"dev": {"name": "dev", "config": {"port": "COM1", "baud_rate": 9600,
+		"data_bits": 8, "stop_bits": 1, "parity": "E", "timeout": "5s"}, "created_at": "2024-01-01T00:00:00Z"}}}`
+	if err := os.WriteFile(manager.GetConfigPath(), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := manager.LoadConfig("dev")
+	if err != nil {
+		t.Fatalf("LoadConfig failed on legacy file: %v", err)
+	}
+	if cfg.Parity != "even" || cfg.Timeout != 5*time.Second {
+		t.Errorf("Expected migrated parity/timeout, got %q/%v", cfg.Parity, cfg.Timeout)
+	}
+
+	// Saving writes the current version
+	storage, err := manager.loadStorage()
+	if err != nil {
+		t.Fatalf("loadStorage failed: %v", err)
+	}
+	if storage.Version != CurrentStorageVersion {
+		t.Errorf("Expected version %s after save, got %s", CurrentStorageVersion, storage.Version)
+	}
+
+	// Files from a newer sterm are rejected instead of misread
+	if err := os.WriteFile(manager.GetConfigPath(), []byte(`{"version": "99", "configs": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := manager.ListConfigs(); err == nil {
+		t.Error("Expected error for unsupported config version")
+	}
+}
+
+func TestSettingsValidationIssues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SettingsFileName)
+	content := `{"theme": {"status_backgrond": "blue"}, "logging": {"format": "xml", "level": "verbose", "modules": {"parsr": false}, "name_template": "{port}_{host}", "session_layout": "rows"}, "display": {"ambiguous_width": "double", "invalid_utf8": "skip", "clear_scrollback": "keep", "max_fps": 1000}, "autosave": {"interval_seconds": -5}, "paste": {"line_ending": "nl"}, "plot": {"patterns": ["temp=([0-9]+)"]}, "printer": {"max_bytes": -1}, "triggers": [{"pattern": "panic", "context_line": 5}, {"pattern": "(", "context_lines": -1}], "prefix": {"key": "Alt+A"}, "colour": 1}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+
+	_, err := LoadSettings(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	// All problems are reported, with suggestions for typos
+	expected := []string{
+		`colour: unknown key`,
+		`theme.status_backgrond: unknown key (did you mean "status_background"?)`,
+		`triggers.0.context_line: unknown key (did you mean "context_lines"?)`,
+		`logging.format: invalid format "xml"`,
+		`logging.session_layout: invalid layout "rows"`,
+		`logging.level: invalid level "verbose"`,
+		`logging.modules.parsr: unknown module`,
+		`logging.name_template: unknown placeholder "{host}"`,
+		`display.ambiguous_width: invalid width "double"`,
+		`display.invalid_utf8: invalid policy "skip"`,
+		`display.clear_scrollback: invalid policy "keep"`,
+		`display.max_fps: must be between 1 and 240`,
+		`autosave.interval_seconds: must not be negative`,
+		`paste.line_ending: invalid line ending "nl"`,
+		`plot.patterns.0: needs a named group`,
+		`printer.max_bytes: must not be negative`,
+		`triggers.1.pattern: invalid regular expression`,
+		`triggers.1.context_lines: must not be negative`,
+		`prefix.key: key "Alt+A" must be of the form Ctrl+<letter>`,
+	}
+	if len(verr.Issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %v", len(expected), verr.Issues)
+	}
+	for i, want := range expected {
+		if got := verr.Issues[i].String(); !strings.HasPrefix(got, want) {
+			t.Errorf("Issue %d = %q, want prefix %q", i, got, want)
+		}
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := NewFileConfigManager(tempDir)
+
+	legacy := `{"configs": {"dev": {"name": "dev", "config": {"port": "COM1", "baud_rate": 9600,
+		"data_bits": 8, "stop_bits": 1, "parity": "N", "timeout": 0}, "created_at": "2024-01-01T00:00:00Z"}}}`
+	if err := os.WriteFile(manager.GetConfigPath(), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	reports := manager.Doctor(false)
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].OK() || len(reports[0].Migrations) != 2 {
+		t.Errorf("Expected pending migrations, got %+v", reports[0])
+	}
+	if reports[1].Exists || !reports[1].OK() {
+		t.Errorf("Missing settings file should be OK, got %+v", reports[1])
+	}
+
+	// Fix writes the migrated file and keeps a backup
+	reports = manager.Doctor(true)
+	if !reports[0].OK() || !reports[0].Fixed {
+		t.Errorf("Expected fixed report, got %+v", reports[0])
+	}
+	if _, err := os.Stat(manager.GetConfigPath() + ".bak"); err != nil {
+		t.Errorf("Expected backup file: %v", err)
+	}
+
+	reports = manager.Doctor(false)
+	if !reports[0].OK() || reports[0].Version != CurrentStorageVersion {
+		t.Errorf("Expected clean report after fix, got %+v", reports[0])
+	}
+}
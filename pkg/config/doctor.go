@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// FileReport describes the health of one config file
+type FileReport struct {
+	Path       string
+	Exists     bool
+	Version    string            // Version found in the file
+	Migrations []Migration       // Migrations needed to reach the current version
+	Issues     []ValidationIssue // Unknown keys and invalid values
+	Err        error             // Set if the file couldn't be read or parsed
+	Fixed      bool              // Migrations were written back to the file
+}
+
+// OK reports whether the file has no problems left
+func (r FileReport) OK() bool {
+	return r.Err == nil && len(r.Issues) == 0 && (len(r.Migrations) == 0 || r.Fixed)
+}
+
+// Doctor checks the configuration and settings files. With fix set, pending
+// migrations are written back after saving a .bak copy of the original.
+func (fcm *FileConfigManager) Doctor(fix bool) []FileReport {
+	return []FileReport{
+		checkFile(fcm.getConfigPath(), CurrentStorageVersion, storageMigrations, validateStorageDoc, fix),
+		checkFile(fcm.GetSettingsPath(), CurrentSettingsVersion, settingsMigrations, validateSettingsDoc, fix),
+	}
+}
+
+// validateStorageDoc checks a migrated configs.json document
+func validateStorageDoc(doc map[string]interface{}, data []byte) []ValidationIssue {
+	issues := unknownKeys(doc, reflect.TypeOf(ConfigStorage{}), "")
+
+	var storage ConfigStorage
+	if err := json.Unmarshal(data, &storage); err != nil {
+		return append(issues, ValidationIssue{Message: err.Error()})
+	}
+	return append(issues, validationIssues(storage.Validate())...)
+}
+
+// validateSettingsDoc checks a migrated settings.json document
+func validateSettingsDoc(doc map[string]interface{}, data []byte) []ValidationIssue {
+	issues := unknownKeys(doc, reflect.TypeOf(Settings{}), "")
+
+	settings := DefaultSettings()
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return append(issues, ValidationIssue{Message: err.Error()})
+	}
+	return append(issues, validationIssues(settings.Validate())...)
+}
+
+// validationIssues unpacks the issues of a validation error
+func validationIssues(err error) []ValidationIssue {
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Issues
+	}
+	return []ValidationIssue{{Message: err.Error()}}
+}
+
+// checkFile migrates and validates a single config file
+func checkFile(path, target string, migrations []Migration,
+	validate func(doc map[string]interface{}, data []byte) []ValidationIssue, fix bool) FileReport {
+	report := FileReport{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Err = fmt.Errorf("failed to read file: %w", err)
+		}
+		return report
+	}
+	report.Exists = true
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		report.Err = fmt.Errorf("invalid JSON: %w", err)
+		return report
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	report.Version = documentVersion(doc)
+
+	applied, err := migrateDocument(doc, target, migrations)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+	report.Migrations = applied
+
+	migrated, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		report.Err = fmt.Errorf("failed to encode migrated file: %w", err)
+		return report
+	}
+	report.Issues = validate(doc, migrated)
+
+	if fix && len(applied) > 0 {
+		if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+			report.Err = fmt.Errorf("failed to write backup: %w", err)
+			return report
+		}
+		if err := os.WriteFile(path, migrated, 0644); err != nil {
+			report.Err = fmt.Errorf("failed to write migrated file: %w", err)
+			return report
+		}
+		report.Fixed = true
+	}
+
+	return report
+}
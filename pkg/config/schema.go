@@ -0,0 +1,343 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Current schema versions written by this version of sterm
+const (
+	CurrentStorageVersion  = "2"
+	CurrentSettingsVersion = "1"
+)
+
+// Migration upgrades a config document from one schema version to the next.
+// Migrations work on the raw JSON document so they can handle fields that
+// no longer exist in the Go structs.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	Apply       func(doc map[string]interface{}) error
+}
+
+// storageMigrations upgrade configs.json, in order
+var storageMigrations = []Migration{
+	{
+		From:        "",
+		To:          "1.0",
+		Description: "add version and configs fields",
+		Apply: func(doc map[string]interface{}) error {
+			if _, ok := doc["configs"]; !ok {
+				doc["configs"] = map[string]interface{}{}
+			}
+			return nil
+		},
+	},
+	{
+		From:        "1.0",
+		To:          "2",
+		Description: "normalize parity names and duration strings in timeouts",
+		Apply:       normalizeStorageV2,
+	},
+}
+
+// settingsMigrations upgrade settings.json, in order
+var settingsMigrations = []Migration{
+	{
+		From:        "",
+		To:          "1",
+		Description: "add schema version",
+		Apply:       func(doc map[string]interface{}) error { return nil },
+	},
+}
+
+// parityAliases maps hand-written parity names to their canonical form
+var parityAliases = map[string]string{
+	"n": "none", "none": "none",
+	"o": "odd", "odd": "odd",
+	"e": "even", "even": "even",
+	"m": "mark", "mark": "mark",
+	"s": "space", "space": "space",
+}
+
+// normalizeStorageV2 rewrites parity values like "N" or "Even" to lowercase
+// names and converts timeouts written as "10s" to the nanosecond form
+func normalizeStorageV2(doc map[string]interface{}) error {
+	configs, _ := doc["configs"].(map[string]interface{})
+	for name, raw := range configs {
+		info, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cfg, ok := info["config"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if parity, ok := cfg["parity"].(string); ok {
+			if canonical, known := parityAliases[strings.ToLower(parity)]; known {
+				cfg["parity"] = canonical
+			}
+		}
+
+		if timeout, ok := cfg["timeout"].(string); ok {
+			d, err := time.ParseDuration(timeout)
+			if err != nil {
+				return fmt.Errorf("configs.%s.config.timeout: invalid duration %q", name, timeout)
+			}
+			cfg["timeout"] = float64(d)
+		}
+	}
+	return nil
+}
+
+// documentVersion returns the schema version stored in a document
+func documentVersion(doc map[string]interface{}) string {
+	switch v := doc["version"].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%g", v)
+	default:
+		return ""
+	}
+}
+
+// pendingMigrations returns the migrations needed to bring a document from
+// version to target, or an error if the version is unknown
+func pendingMigrations(version, target string, migrations []Migration) ([]Migration, error) {
+	var pending []Migration
+	for version != target {
+		found := false
+		for _, m := range migrations {
+			if m.From == version {
+				pending = append(pending, m)
+				version = m.To
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported config version %q (this sterm supports up to %q); upgrade sterm to read this file", version, target)
+		}
+	}
+	return pending, nil
+}
+
+// migrateDocument applies all pending migrations to doc in place and
+// returns the migrations that were applied
+func migrateDocument(doc map[string]interface{}, target string, migrations []Migration) ([]Migration, error) {
+	pending, err := pendingMigrations(documentVersion(doc), target, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range pending {
+		if err := m.Apply(doc); err != nil {
+			return nil, fmt.Errorf("migration %q -> %q failed: %w", m.From, m.To, err)
+		}
+		doc["version"] = m.To
+	}
+	return pending, nil
+}
+
+// migrateJSON parses data, migrates it and returns the upgraded JSON
+func migrateJSON(data []byte, target string, migrations []Migration) ([]byte, map[string]interface{}, []Migration, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, nil, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	applied, err := migrateDocument(doc, target, migrations)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(applied) == 0 {
+		return data, doc, nil, nil
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	return migrated, doc, applied, nil
+}
+
+// ValidationIssue is a single problem found in a config file
+type ValidationIssue struct {
+	Path    string // Dotted path to the offending key, e.g. "configs.dev.config.parity"
+	Message string
+}
+
+// String formats the issue for display
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+// ValidationError collects all problems found in a config file
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// Error joins the issues into one message
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = issue.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// errorFromIssues returns a ValidationError, or nil if there are no issues
+func errorFromIssues(issues []ValidationIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: issues}
+}
+
+// unknownKeys reports keys in doc that don't correspond to a field of t,
+// recursing into nested objects, maps and lists
+func unknownKeys(doc interface{}, t reflect.Type, path string) []ValidationIssue {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var issues []ValidationIssue
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if t == reflect.TypeOf(time.Time{}) {
+			return nil
+		}
+
+		fields := jsonFields(t)
+		keys := sortedKeys(obj)
+		for _, key := range keys {
+			field, known := fields[key]
+			if !known {
+				names := make([]string, 0, len(fields))
+				for name := range fields {
+					names = append(names, name)
+				}
+				msg := "unknown key"
+				if suggestion := closestName(key, names); suggestion != "" {
+					msg = fmt.Sprintf("unknown key (did you mean %q?)", suggestion)
+				}
+				issues = append(issues, ValidationIssue{Path: joinPath(path, key), Message: msg})
+				continue
+			}
+			issues = append(issues, unknownKeys(obj[key], field.Type, joinPath(path, key))...)
+		}
+	case reflect.Map:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(obj) {
+			issues = append(issues, unknownKeys(obj[key], t.Elem(), joinPath(path, key))...)
+		}
+	case reflect.Slice:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range list {
+			issues = append(issues, unknownKeys(item, t.Elem(), joinPath(path, strconv.Itoa(i)))...)
+		}
+	}
+	return issues
+}
+
+// jsonFields returns the struct fields of t keyed by their JSON name
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// sortedKeys returns the keys of obj in sorted order for stable messages
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinPath appends a key to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestName returns the candidate closest to name, if it is close enough
+// to be a likely typo
+func closestName(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/2 + 1
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,620 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// SettingsFileName is the name of the application settings file in the config directory
+const SettingsFileName = "settings.json"
+
+// Settings contains user preferences that can be changed while the app is running
+type Settings struct {
+	Version     string            `json:"version,omitempty"`
+	Theme       ThemeSettings     `json:"theme"`
+	Keybindings map[string]string `json:"keybindings,omitempty"` // Action name -> key, e.g. "screenshot": "Alt+O"
+	Prefix      PrefixSettings    `json:"prefix"`
+	Logging     LoggingSettings   `json:"logging"`
+	StatusBar   StatusBarSettings `json:"status_bar"`
+	TitleBar    TitleBarSettings  `json:"title_bar"`
+	Links       LinkSettings      `json:"links"`
+	Display     DisplaySettings   `json:"display"`
+	Bell        BellSettings      `json:"bell"`
+	Watchdog    WatchdogSettings  `json:"watchdog"`
+	Autosave    AutosaveSettings  `json:"autosave"`
+	Triggers    []TriggerSettings `json:"triggers,omitempty"`
+	Paste       PasteSettings     `json:"paste"`
+	Plot        PlotSettings      `json:"plot"`
+	Printer     PrinterSettings   `json:"printer"`
+}
+
+// ThemeSettings contains status bar colors. Colors are tcell names
+// ("darkblue", "white") or hex values ("#1e1e2e"); empty uses the default.
+type ThemeSettings struct {
+	StatusForeground  string `json:"status_foreground,omitempty"`
+	StatusBackground  string `json:"status_background,omitempty"`
+	MessageBackground string `json:"message_background,omitempty"`
+	ScrollBackground  string `json:"scroll_background,omitempty"`
+	FilterBackground  string `json:"filter_background,omitempty"`
+	PausedBackground  string `json:"paused_background,omitempty"`
+	WarningBackground string `json:"warning_background,omitempty"`
+	ErrorBackground   string `json:"error_background,omitempty"`
+}
+
+// PrefixSettings turn sterm's keys into chords of a prefix key and a
+// command key, like screen and tmux, so none of them is kept from the device
+type PrefixSettings struct {
+	Key       string `json:"key,omitempty"`        // e.g. "Ctrl+A"; empty uses the keys directly
+	TimeoutMS int    `json:"timeout_ms,omitempty"` // How long the command key is waited for (0 = 2000)
+}
+
+// LoggingSettings contains logging options
+type LoggingSettings struct {
+	Debug        bool            `json:"debug"`                   // Write the debug log to ~/.sterm/sterm-debug.log
+	Level        string          `json:"level,omitempty"`         // Log level: debug, info, warn, error or off; empty is debug if Debug is set, else off
+	Modules      map[string]bool `json:"modules,omitempty"`       // Set a module (app, parser, renderer, serial) to false to mute it
+	JSON         bool            `json:"json,omitempty"`          // Write the log as JSON lines
+	Format       string          `json:"format,omitempty"`        // Session save format: plain_text, timestamped or json
+	Directory    string          `json:"directory,omitempty"`     // Where saved sessions and history go; empty is the current directory
+	NameTemplate string          `json:"name_template,omitempty"` // File name without extension, e.g. "{profile}_{date}_{time}"
+
+	// SessionLayout is how saved sessions break the text into lines:
+	// "logical" writes a line as the device sent it however many rows it
+	// wrapped over, "visual" writes the rows as they are on screen
+	SessionLayout string `json:"session_layout,omitempty"`
+}
+
+// MaxFPSLimit is the highest accepted display.max_fps
+const MaxFPSLimit = 240
+
+// LogLevels are the accepted values of logging.level
+var LogLevels = []string{"debug", "info", "warn", "error", "off"}
+
+// LogModules are the modules that can be muted in logging.modules
+var LogModules = []string{"app", "parser", "renderer", "serial"}
+
+// LogNamePlaceholders are the placeholders allowed in logging.name_template
+var LogNamePlaceholders = []string{"kind", "port", "baud", "profile", "date", "time"}
+
+// logPlaceholderRegex matches a {placeholder} in a name template
+var logPlaceholderRegex = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// StatusBarSettings controls which parts of the status bar are shown
+type StatusBarSettings struct {
+	ShowPort  bool `json:"show_port"`
+	ShowHints bool `json:"show_hints"`
+	ShowStats bool `json:"show_stats"`
+
+	// Format replaces the built-in text of the parts with templates. A
+	// saved configuration can have its own, used over this one.
+	Format StatusFormat `json:"format"`
+}
+
+// TitleBarSettings controls the line above the terminal showing the session
+// name, connection state, time connected and alerts
+type TitleBarSettings struct {
+	Show  bool `json:"show"`
+	Clock bool `json:"clock"` // Show the time of day on the right
+}
+
+// StatusFormat holds templates for the left, center and right of the status
+// bar, such as " {port} {baud} {flow} " or " TX:{tx} RX:{rx} {time} ". An
+// empty template keeps the built-in text.
+type StatusFormat struct {
+	Left   string `json:"left,omitempty"`
+	Center string `json:"center,omitempty"`
+	Right  string `json:"right,omitempty"`
+}
+
+// StatusPlaceholders are the placeholders allowed in status bar templates
+var StatusPlaceholders = []string{"port", "baud", "tx", "rx", "time", "flow", "log", "profile", "encoding"}
+
+// IsZero reports whether no part has a template
+func (f StatusFormat) IsZero() bool {
+	return f == StatusFormat{}
+}
+
+// Over returns f with the parts it leaves empty taken from base
+func (f StatusFormat) Over(base StatusFormat) StatusFormat {
+	if f.Left == "" {
+		f.Left = base.Left
+	}
+	if f.Center == "" {
+		f.Center = base.Center
+	}
+	if f.Right == "" {
+		f.Right = base.Right
+	}
+	return f
+}
+
+// Uses reports whether any part has a placeholder
+func (f StatusFormat) Uses(placeholder string) bool {
+	p := "{" + placeholder + "}"
+	return strings.Contains(f.Left, p) || strings.Contains(f.Center, p) || strings.Contains(f.Right, p)
+}
+
+// validate reports unknown placeholders in the templates under path
+func (f StatusFormat) validate(path string) []ValidationIssue {
+	var issues []ValidationIssue
+	parts := []struct {
+		name     string
+		template string
+	}{
+		{"left", f.Left},
+		{"center", f.Center},
+		{"right", f.Right},
+	}
+	for _, part := range parts {
+		for _, m := range logPlaceholderRegex.FindAllStringSubmatch(part.template, -1) {
+			if !slices.Contains(StatusPlaceholders, m[1]) {
+				issues = append(issues, ValidationIssue{
+					Path:    path + "." + part.name,
+					Message: fmt.Sprintf("unknown placeholder %q (use one of {%s})", m[0], strings.Join(StatusPlaceholders, "}, {")),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// LinkSettings controls detection of URLs and file paths in terminal output
+type LinkSettings struct {
+	Underline bool   `json:"underline"`        // Underline detected links on screen
+	Paths     bool   `json:"paths"`            // Also detect file paths like /var/log/messages or ./build.log:12
+	Opener    string `json:"opener,omitempty"` // Command used to open links; empty uses the system default
+}
+
+// DisplaySettings controls how characters are laid out on screen
+type DisplaySettings struct {
+	// AmbiguousWidth is the width of East Asian ambiguous characters such as
+	// ○, → and Cyrillic/Greek letters: "narrow" (1 cell), "wide" (2 cells) or
+	// "auto" to ask the host terminal, falling back to the locale
+	AmbiguousWidth string `json:"ambiguous_width,omitempty"`
+
+	// InvalidUTF8 is how bytes that aren't valid UTF-8 are shown: "replace"
+	// with U+FFFD, "drop" them, or "latin1" to show them as Latin-1
+	InvalidUTF8 string `json:"invalid_utf8,omitempty"`
+
+	// MaxFPS limits how often the screen is redrawn a second (0 = 60).
+	// Floods of data are drawn less often still.
+	MaxFPS int `json:"max_fps,omitempty"`
+
+	// ImplicitCR returns the cursor to the first column on every LF, for
+	// devices ending lines with a bare LF whose output otherwise drifts
+	// right like a staircase; ImplicitLF moves it down a line on every CR,
+	// for devices ending lines with a bare CR
+	ImplicitCR bool `json:"implicit_cr,omitempty"`
+	ImplicitLF bool `json:"implicit_lf,omitempty"`
+
+	// WrapMarkers keeps a column free right of the terminal and marks the
+	// rows whose text is too long for the screen and continues on the next
+	WrapMarkers bool `json:"wrap_markers,omitempty"`
+
+	// ClearScrollback is what clearing the screen does to the scrollback:
+	// "push" the lines on screen into it, "discard" them, or "ed3" to
+	// discard them and let the device erase the scrollback with ED 3
+	ClearScrollback string `json:"clear_scrollback,omitempty"`
+}
+
+// BellSettings controls what happens when the device sends BEL, e.g. when a
+// long flash or boot operation finishes
+type BellSettings struct {
+	Audible       bool   `json:"audible"`                  // Beep through the host terminal
+	Visual        bool   `json:"visual"`                   // Flash the status bar
+	Notify        bool   `json:"notify"`                   // Desktop notification while the window is unfocused
+	NotifyCommand string `json:"notify_command,omitempty"` // Command run with title and message; empty uses notify-send or osascript
+}
+
+// WatchdogSettings raise an alert when the device goes quiet or starts
+// talking again, e.g. to catch crashes and reboots overnight
+type WatchdogSettings struct {
+	IdleSeconds   int    `json:"idle_seconds,omitempty"`   // Alert when nothing has been received for this long (0 = off)
+	ResumeSeconds int    `json:"resume_seconds,omitempty"` // Alert when data arrives after being quiet this long (0 = off)
+	Bell          bool   `json:"bell"`                     // Ring the bell with each alert
+	Command       string `json:"command,omitempty"`        // Command run on each alert, with STERM_EVENT, STERM_PORT and STERM_QUIET_SECONDS set
+}
+
+// AutosaveSettings control crash recovery: received data is checkpointed to
+// a spool file under ~/.sterm/spool and offered for restore after a crash
+type AutosaveSettings struct {
+	Enabled         bool `json:"enabled"`
+	IntervalSeconds int  `json:"interval_seconds,omitempty"` // Checkpoint at least this often
+	Bytes           int  `json:"bytes,omitempty"`            // Also checkpoint once this much data is buffered (0 = time only)
+}
+
+// TriggerSettings react to a received line matching a pattern, e.g. to keep
+// the output around a kernel panic that happens overnight
+type TriggerSettings struct {
+	Pattern      string `json:"pattern"`                 // Regular expression matched against each received line
+	Capture      bool   `json:"capture"`                 // Start a capture file with the output from the match on
+	ContextLines int    `json:"context_lines,omitempty"` // Lines of scrollback up to the match copied into the capture
+	Pause        bool   `json:"pause"`                   // Freeze the display at the match (scroll mode)
+}
+
+// PasteSettings guard against pasting the wrong thing into a device, such
+// as a whole clipboard into a bootloader prompt
+type PasteSettings struct {
+	ConfirmChars    int  `json:"confirm_chars"`    // Ask before sending pastes longer than this (0 = never by length)
+	ConfirmControls bool `json:"confirm_controls"` // Ask before sending pastes with control characters other than newlines and tabs
+
+	// Pastes that need confirming open in an editor instead of a preview
+	Editor      bool   `json:"editor"`
+	LineEnding  string `json:"line_ending,omitempty"` // Editor's initial line ending: "cr" (default), "lf" or "crlf"
+	LineDelayMS int    `json:"line_delay_ms"`         // Editor's initial pause after each line, for devices without flow control
+}
+
+// PlotSettings pull numbers out of received lines for the plot pane. Each
+// named capture group of a pattern is a series, e.g. `temp=(?P<temp>[-0-9.]+)`.
+type PlotSettings struct {
+	Patterns []string `json:"patterns,omitempty"`
+	Points   int      `json:"points"` // Samples kept for the chart and CSV export
+	ASCII    bool     `json:"ascii"`  // Draw with '*' instead of braille, for fonts without braille
+}
+
+// PrinterSettings control what is done with output a device sends to the
+// terminal's printer (ESC[5i ... ESC[4i, ESC[i). Unless a capture file is
+// chosen, it is dropped when Capture is off.
+type PrinterSettings struct {
+	Capture  bool `json:"capture"`   // Write it to a file named like other logs, with kind print
+	MaxBytes int  `json:"max_bytes"` // Stop capturing once the file is this big (0 = 10 MB)
+}
+
+// DefaultSettings returns the settings used when no settings file exists
+func DefaultSettings() Settings {
+	return Settings{
+		Version: CurrentSettingsVersion,
+		Theme: ThemeSettings{
+			StatusForeground:  "white",
+			StatusBackground:  "darkblue",
+			MessageBackground: "darkgreen",
+			ScrollBackground:  "darkcyan",
+			FilterBackground:  "darkmagenta",
+			PausedBackground:  "darkred",
+			WarningBackground: "olive",
+			ErrorBackground:   "maroon",
+		},
+		Keybindings: map[string]string{},
+		Logging: LoggingSettings{
+			Format:       "timestamped",
+			NameTemplate: "{kind}_{date}_{time}",
+		},
+		StatusBar: StatusBarSettings{
+			ShowPort:  true,
+			ShowHints: true,
+			ShowStats: true,
+		},
+		TitleBar: TitleBarSettings{
+			Clock: true,
+		},
+		Links: LinkSettings{
+			Underline: true,
+		},
+		Display: DisplaySettings{
+			AmbiguousWidth:  "auto",
+			InvalidUTF8:     "replace",
+			ClearScrollback: "push",
+		},
+		Bell: BellSettings{
+			Audible: true,
+		},
+		Autosave: AutosaveSettings{
+			Enabled:         true,
+			IntervalSeconds: 10,
+			Bytes:           64 * 1024,
+		},
+		Paste: PasteSettings{
+			ConfirmChars:    256,
+			ConfirmControls: true,
+			Editor:          true,
+		},
+		Plot: PlotSettings{
+			Points: 10000,
+		},
+		Printer: PrinterSettings{
+			MaxBytes: 10 << 20,
+		},
+	}
+}
+
+// Validate checks the settings and reports every problem found as a *ValidationError
+func (s Settings) Validate() error {
+	var issues []ValidationIssue
+
+	colors := []struct {
+		name  string
+		value string
+	}{
+		{"theme.status_foreground", s.Theme.StatusForeground},
+		{"theme.status_background", s.Theme.StatusBackground},
+		{"theme.message_background", s.Theme.MessageBackground},
+		{"theme.scroll_background", s.Theme.ScrollBackground},
+		{"theme.filter_background", s.Theme.FilterBackground},
+		{"theme.paused_background", s.Theme.PausedBackground},
+		{"theme.warning_background", s.Theme.WarningBackground},
+		{"theme.error_background", s.Theme.ErrorBackground},
+	}
+	for _, c := range colors {
+		if c.value != "" && tcell.GetColor(c.value) == tcell.ColorDefault {
+			issues = append(issues, ValidationIssue{
+				Path:    c.name,
+				Message: fmt.Sprintf("unknown color %q (use a name like \"darkblue\" or a hex value like \"#1e1e2e\")", c.value),
+			})
+		}
+	}
+
+	switch s.Logging.Format {
+	case "", "plain_text", "timestamped", "json":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "logging.format",
+			Message: fmt.Sprintf("invalid format %q (must be one of plain_text, timestamped, json)", s.Logging.Format),
+		})
+	}
+
+	switch s.Logging.SessionLayout {
+	case "", "logical", "visual":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "logging.session_layout",
+			Message: fmt.Sprintf("invalid layout %q (must be one of logical, visual)", s.Logging.SessionLayout),
+		})
+	}
+
+	if s.Logging.Level != "" && !slices.Contains(LogLevels, strings.ToLower(s.Logging.Level)) {
+		issues = append(issues, ValidationIssue{
+			Path:    "logging.level",
+			Message: fmt.Sprintf("invalid level %q (must be one of %s)", s.Logging.Level, strings.Join(LogLevels, ", ")),
+		})
+	}
+	for _, module := range slices.Sorted(maps.Keys(s.Logging.Modules)) {
+		if !slices.Contains(LogModules, module) {
+			issues = append(issues, ValidationIssue{
+				Path:    "logging.modules." + module,
+				Message: fmt.Sprintf("unknown module (must be one of %s)", strings.Join(LogModules, ", ")),
+			})
+		}
+	}
+	for _, m := range logPlaceholderRegex.FindAllStringSubmatch(s.Logging.NameTemplate, -1) {
+		if !slices.Contains(LogNamePlaceholders, m[1]) {
+			issues = append(issues, ValidationIssue{
+				Path:    "logging.name_template",
+				Message: fmt.Sprintf("unknown placeholder %q (use one of {%s})", m[0], strings.Join(LogNamePlaceholders, "}, {")),
+			})
+		}
+	}
+
+	issues = append(issues, s.StatusBar.Format.validate("status_bar.format")...)
+
+	switch s.Display.AmbiguousWidth {
+	case "", "auto", "narrow", "wide":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "display.ambiguous_width",
+			Message: fmt.Sprintf("invalid width %q (must be one of auto, narrow, wide)", s.Display.AmbiguousWidth),
+		})
+	}
+
+	switch s.Display.InvalidUTF8 {
+	case "", "replace", "drop", "latin1":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "display.invalid_utf8",
+			Message: fmt.Sprintf("invalid policy %q (must be one of replace, drop, latin1)", s.Display.InvalidUTF8),
+		})
+	}
+
+	switch s.Display.ClearScrollback {
+	case "", "push", "discard", "ed3":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "display.clear_scrollback",
+			Message: fmt.Sprintf("invalid policy %q (must be one of push, discard, ed3)", s.Display.ClearScrollback),
+		})
+	}
+
+	if s.Display.MaxFPS < 0 || s.Display.MaxFPS > MaxFPSLimit {
+		issues = append(issues, ValidationIssue{
+			Path:    "display.max_fps",
+			Message: fmt.Sprintf("must be between 1 and %d, or 0 for the default", MaxFPSLimit),
+		})
+	}
+
+	if s.Watchdog.IdleSeconds < 0 {
+		issues = append(issues, ValidationIssue{Path: "watchdog.idle_seconds", Message: "must not be negative"})
+	}
+	if s.Watchdog.ResumeSeconds < 0 {
+		issues = append(issues, ValidationIssue{Path: "watchdog.resume_seconds", Message: "must not be negative"})
+	}
+
+	if s.Autosave.IntervalSeconds < 0 {
+		issues = append(issues, ValidationIssue{Path: "autosave.interval_seconds", Message: "must not be negative"})
+	}
+	if s.Autosave.Bytes < 0 {
+		issues = append(issues, ValidationIssue{Path: "autosave.bytes", Message: "must not be negative"})
+	}
+
+	if s.Paste.ConfirmChars < 0 {
+		issues = append(issues, ValidationIssue{Path: "paste.confirm_chars", Message: "must not be negative"})
+	}
+	switch s.Paste.LineEnding {
+	case "", "cr", "lf", "crlf":
+	default:
+		issues = append(issues, ValidationIssue{
+			Path:    "paste.line_ending",
+			Message: fmt.Sprintf("invalid line ending %q (must be one of cr, lf, crlf)", s.Paste.LineEnding),
+		})
+	}
+	if s.Paste.LineDelayMS < 0 {
+		issues = append(issues, ValidationIssue{Path: "paste.line_delay_ms", Message: "must not be negative"})
+	}
+
+	for i, pattern := range s.Plot.Patterns {
+		path := fmt.Sprintf("plot.patterns.%d", i)
+		re, err := regexp.Compile(pattern)
+		switch {
+		case err != nil:
+			issues = append(issues, ValidationIssue{Path: path, Message: fmt.Sprintf("invalid regular expression: %v", err)})
+		case !slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" }):
+			issues = append(issues, ValidationIssue{Path: path, Message: "needs a named group such as (?P<temp>[-0-9.]+)"})
+		}
+	}
+	if s.Plot.Points < 0 {
+		issues = append(issues, ValidationIssue{Path: "plot.points", Message: "must not be negative"})
+	}
+
+	if s.Printer.MaxBytes < 0 {
+		issues = append(issues, ValidationIssue{Path: "printer.max_bytes", Message: "must not be negative"})
+	}
+
+	for i, trigger := range s.Triggers {
+		path := fmt.Sprintf("triggers.%d", i)
+		if trigger.Pattern == "" {
+			issues = append(issues, ValidationIssue{Path: path + ".pattern", Message: "must not be empty"})
+		} else if _, err := regexp.Compile(trigger.Pattern); err != nil {
+			issues = append(issues, ValidationIssue{Path: path + ".pattern", Message: fmt.Sprintf("invalid regular expression: %v", err)})
+		}
+		if trigger.ContextLines < 0 {
+			issues = append(issues, ValidationIssue{Path: path + ".context_lines", Message: "must not be negative"})
+		}
+	}
+
+	if s.Prefix.Key != "" {
+		if _, err := ParsePrefixKey(s.Prefix.Key); err != nil {
+			issues = append(issues, ValidationIssue{Path: "prefix.key", Message: err.Error()})
+		}
+	}
+	if s.Prefix.TimeoutMS < 0 {
+		issues = append(issues, ValidationIssue{Path: "prefix.timeout_ms", Message: "must not be negative"})
+	}
+
+	// Sort actions so duplicate reports are stable
+	actions := make([]string, 0, len(s.Keybindings))
+	for action := range s.Keybindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	usedKeys := make(map[rune]string)
+	for _, action := range actions {
+		key := s.Keybindings[action]
+		r, err := ParseAltKey(key)
+		if err != nil {
+			issues = append(issues, ValidationIssue{Path: "keybindings." + action, Message: err.Error()})
+			continue
+		}
+		if other, exists := usedKeys[r]; exists {
+			issues = append(issues, ValidationIssue{
+				Path:    "keybindings." + action,
+				Message: fmt.Sprintf("%s is already bound to %s", key, other),
+			})
+			continue
+		}
+		usedKeys[r] = action
+	}
+
+	return errorFromIssues(issues)
+}
+
+// ParseAltKey parses a key binding of the form "Alt+<letter>" and returns
+// the lowercase letter
+func ParseAltKey(key string) (rune, error) {
+	prefix, letter, found := strings.Cut(key, "+")
+	if !found || !strings.EqualFold(prefix, "alt") {
+		return 0, fmt.Errorf("key %q must be of the form Alt+<letter>", key)
+	}
+
+	runes := []rune(letter)
+	if len(runes) != 1 || !unicode.IsLetter(runes[0]) || runes[0] > unicode.MaxASCII {
+		return 0, fmt.Errorf("key %q must be of the form Alt+<letter>", key)
+	}
+	return unicode.ToLower(runes[0]), nil
+}
+
+// ParsePrefixKey parses a chord prefix of the form "Ctrl+<letter>", or Ctrl
+// with one of [ \ ] ^ _, and returns the control key it sends
+func ParsePrefixKey(key string) (tcell.Key, error) {
+	prefix, char, found := strings.Cut(key, "+")
+	if !found || !strings.EqualFold(prefix, "ctrl") || len(char) != 1 {
+		return 0, fmt.Errorf("key %q must be of the form Ctrl+<letter>", key)
+	}
+
+	c := unicode.ToUpper(rune(char[0]))
+	if c < 'A' || c > '_' {
+		return 0, fmt.Errorf("key %q must be of the form Ctrl+<letter>", key)
+	}
+	return tcell.Key(c - '@'), nil
+}
+
+// SettingsPath returns the settings file location for a config directory
+// (~/.sterm when configDir is empty)
+func SettingsPath(configDir string) string {
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			configDir = ".sterm"
+		} else {
+			configDir = filepath.Join(homeDir, ".sterm")
+		}
+	}
+	return filepath.Join(configDir, SettingsFileName)
+}
+
+// GetSettingsPath returns the full path to the settings file
+func (fcm *FileConfigManager) GetSettingsPath() string {
+	return filepath.Join(fcm.configDir, SettingsFileName)
+}
+
+// LoadSettings reads, migrates and validates a settings file. Fields missing
+// from the file keep their default values, and a missing file yields the
+// defaults. Unknown keys are errors so typos don't go unnoticed.
+func LoadSettings(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultSettings(), nil
+		}
+		return DefaultSettings(), fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	settings, _, err := parseSettings(data)
+	if err != nil {
+		return DefaultSettings(), err
+	}
+	return settings, nil
+}
+
+// parseSettings migrates and validates settings data, returning the
+// migrations that were applied
+func parseSettings(data []byte) (Settings, []Migration, error) {
+	settings := DefaultSettings()
+
+	migrated, doc, applied, err := migrateJSON(data, CurrentSettingsVersion, settingsMigrations)
+	if err != nil {
+		return settings, nil, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+
+	issues := unknownKeys(doc, reflect.TypeOf(Settings{}), "")
+
+	if err := json.Unmarshal(migrated, &settings); err != nil {
+		issues = append(issues, ValidationIssue{Message: err.Error()})
+	} else {
+		issues = append(issues, validationIssues(settings.Validate())...)
+	}
+
+	if err := errorFromIssues(issues); err != nil {
+		return DefaultSettings(), applied, fmt.Errorf("invalid settings: %w", err)
+	}
+	return settings, applied, nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settingsReloadDelay groups the burst of events editors produce when saving
+const settingsReloadDelay = 200 * time.Millisecond
+
+// SettingsCallback receives freshly loaded settings, or the error that
+// prevented loading them
+type SettingsCallback func(settings Settings, err error)
+
+// SettingsWatcher reloads the settings file whenever it changes on disk
+type SettingsWatcher struct {
+	path     string
+	watcher  *fsnotify.Watcher
+	callback SettingsCallback
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSettingsWatcher starts watching the settings file at path. The directory
+// is watched rather than the file, since many editors save by replacing the
+// file and the file may not exist yet.
+func NewSettingsWatcher(path string, callback SettingsCallback) (*SettingsWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch settings directory: %w", err)
+	}
+
+	sw := &SettingsWatcher{
+		path:     filepath.Clean(path),
+		watcher:  watcher,
+		callback: callback,
+		done:     make(chan struct{}),
+	}
+
+	sw.wg.Add(1)
+	go sw.run()
+
+	return sw, nil
+}
+
+// run processes file events until the watcher is closed
+func (sw *SettingsWatcher) run() {
+	defer sw.wg.Done()
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-sw.done:
+			return
+		case event, ok := <-sw.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != sw.path {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				reload = time.After(settingsReloadDelay)
+			}
+		case err, ok := <-sw.watcher.Errors:
+			if !ok {
+				return
+			}
+			sw.callback(Settings{}, fmt.Errorf("settings watcher error: %w", err))
+		case <-reload:
+			reload = nil
+			settings, err := LoadSettings(sw.path)
+			sw.callback(settings, err)
+		}
+	}
+}
+
+// Close stops watching the settings file
+func (sw *SettingsWatcher) Close() error {
+	close(sw.done)
+	err := sw.watcher.Close()
+	sw.wg.Wait()
+	return err
+}
@@ -0,0 +1,116 @@
+// Package decoder finds protocol frames in the raw byte stream from a
+// device, such as NMEA sentences or Modbus RTU messages, so they can be
+// shown decoded next to the terminal view.
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Frame is one decoded message
+type Frame struct {
+	Time    time.Time // When the last byte of the frame arrived
+	Decoder string    // Name of the decoder that found it
+	Summary string    // One-line human readable description
+	Error   string    // Why the frame is invalid, e.g. a checksum mismatch; empty if valid
+	Raw     []byte    // Bytes of the frame as received
+}
+
+// Valid reports whether the frame passed its checks
+func (f Frame) Valid() bool {
+	return f.Error == ""
+}
+
+// Decoder finds frames in a byte stream. Feed is called with every chunk
+// received, and with no data when the line has been idle for a while so
+// decoders that frame on silence can finish a message. Decoders keep
+// partial frames between calls and are not safe for concurrent use.
+type Decoder interface {
+	Name() string
+	Feed(data []byte, now time.Time) []Frame
+	Reset()
+}
+
+// TxObserver is implemented by decoders that are also shown the data sent
+// to the device, as some protocols can't be read from one side alone: a
+// Modbus read response can have the length of a request.
+type TxObserver interface {
+	ObserveTx(data []byte, now time.Time)
+}
+
+// factories holds the registered decoders by name. Plugins add and remove
+// theirs while the session runs, so factoriesMu guards it.
+var (
+	factories   = map[string]func() Decoder{}
+	factoriesMu sync.RWMutex
+)
+
+// Register makes a decoder available by name. Built-in decoders register
+// themselves; plugins can add more.
+func Register(name string, factory func() Decoder) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// Registered reports whether a decoder of that name exists
+func Registered(name string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, ok := factories[name]
+	return ok
+}
+
+// Unregister removes a decoder, such as one a plugin added when the plugin
+// is unloaded
+func Unregister(name string) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	delete(factories, name)
+}
+
+// Names returns the registered decoder names, sorted
+func Names() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a decoder by name
+func New(name string) (Decoder, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown decoder %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(), nil
+}
+
+// maxPreview is how many bytes of a frame hexPreview shows
+const maxPreview = 16
+
+// hexPreview formats the start of data as hex bytes
+func hexPreview(data []byte) string {
+	var sb strings.Builder
+	for i, b := range data {
+		if i == maxPreview {
+			sb.WriteString(" ...")
+			break
+		}
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02X", b)
+	}
+	return sb.String()
+}
@@ -0,0 +1,201 @@
+package decoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"kiss", "modbus", "nmea", "slip"} {
+		d, err := New(name)
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", name, err)
+		}
+		if d.Name() != name {
+			t.Errorf("New(%q).Name() = %q", name, d.Name())
+		}
+	}
+	if _, err := New("morse"); err == nil {
+		t.Error("New(morse) succeeded")
+	}
+}
+
+func TestNMEA(t *testing.T) {
+	d := &NMEA{}
+	now := time.Now()
+
+	// Split across reads, surrounded by a non-NMEA line
+	frames := d.Feed([]byte("booting...\r\n$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*4"), now)
+	if len(frames) != 0 {
+		t.Fatalf("Frames before the line ended: %+v", frames)
+	}
+	frames = d.Feed([]byte("7\r\n$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6B\r\n"), now)
+	if len(frames) != 2 {
+		t.Fatalf("Got %d frames, want 2", len(frames))
+	}
+
+	gga := frames[0]
+	if !gga.Valid() {
+		t.Errorf("GGA invalid: %s", gga.Error)
+	}
+	for _, want := range []string{"GPGGA", "12:35:19", "48.11730°N", "11.51667°E", "sats=08", "alt=545.4m"} {
+		if !strings.Contains(gga.Summary, want) {
+			t.Errorf("GGA summary %q missing %q", gga.Summary, want)
+		}
+	}
+	if frames[1].Valid() {
+		t.Error("RMC with a wrong checksum is valid")
+	}
+	if !gga.Time.Equal(now) {
+		t.Errorf("Time = %v, want %v", gga.Time, now)
+	}
+
+	if frame := ParseNMEA("$PXYZ,1,2"); !frame.Valid() || !strings.Contains(frame.Summary, "no checksum") {
+		t.Errorf("Sentence without checksum: %+v", frame)
+	}
+}
+
+func TestGPSFix(t *testing.T) {
+	var fix GPSFix
+	now := time.Now()
+	if desc := fix.FixDescription(); desc != "Waiting for data" {
+		t.Errorf("Initial description = %q", desc)
+	}
+
+	sentences := []string{
+		"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+		"$GPRMC,123519,A,4807.038,S,01131.000,W,022.4,084.4,230394,003.1,W*65",
+		"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75",
+		"$GLGSV,1,1,03,65,40,083,46,66,17,308,41,67,07,344,39*5F",
+		"$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39",
+	}
+	for _, s := range sentences {
+		if !fix.Apply(s, now) {
+			t.Errorf("Apply(%q) = false", s)
+		}
+	}
+	if fix.Apply("$GPRMC,000000,V,,,,,,,010100,,*00", now) {
+		t.Error("Sentence with a bad checksum was applied")
+	}
+
+	if !fix.Valid || !fix.HasPosition || fix.Latitude > -48.1172 || fix.Latitude < -48.1174 || fix.Longitude > -11.5166 {
+		t.Errorf("Position = %v, %v (valid %v)", fix.Latitude, fix.Longitude, fix.Valid)
+	}
+	if fix.Altitude != 545.4 || fix.SpeedKnots != 22.4 || fix.Course != 84.4 {
+		t.Errorf("Altitude %v, speed %v, course %v", fix.Altitude, fix.SpeedKnots, fix.Course)
+	}
+	if fix.SatsUsed != 8 || fix.SatsInView != 11 || fix.HDOP != 1.3 {
+		t.Errorf("Satellites %d/%d, HDOP %v", fix.SatsUsed, fix.SatsInView, fix.HDOP)
+	}
+	if fix.UTC != "12:35:19" || fix.Date != "1994-03-23" {
+		t.Errorf("Time %q, date %q", fix.UTC, fix.Date)
+	}
+	if desc := fix.FixDescription(); desc != "3D fix (GPS)" {
+		t.Errorf("Description = %q", desc)
+	}
+}
+
+func TestModbusCRC(t *testing.T) {
+	// Read 10 holding registers from unit 1 at address 0
+	if crc := ModbusCRC([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A}); crc != 0xCDC5 {
+		t.Errorf("ModbusCRC = %04X, want CDC5", crc)
+	}
+}
+
+func TestModbus(t *testing.T) {
+	d := &Modbus{}
+	now := time.Now()
+
+	request := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x02, 0xC4, 0x0B}
+	response := []byte{0x01, 0x03, 0x04, 0x00, 0x0A, 0x01, 0x02}
+	crc := ModbusCRC(response)
+	response = append(response, byte(crc), byte(crc>>8))
+
+	// A request and response glued together in one read, the response split
+	frames := d.Feed(append(append([]byte(nil), request...), response[:3]...), now)
+	frames = append(frames, d.Feed(response[3:], now)...)
+	if len(frames) != 2 {
+		t.Fatalf("Got %d frames, want 2: %+v", len(frames), frames)
+	}
+	if !frames[0].Valid() || !strings.Contains(frames[0].Summary, "unit 1 Read Holding Registers: request addr=0 count=2") {
+		t.Errorf("Request frame: %+v", frames[0])
+	}
+	if !frames[1].Valid() || !strings.Contains(frames[1].Summary, "response [10 258]") {
+		t.Errorf("Response frame: %+v", frames[1])
+	}
+
+	// A corrupted frame is emitted after the gap with a CRC error
+	bad := append([]byte(nil), request...)
+	bad[7] ^= 0xFF
+	if frames := d.Feed(bad, now); len(frames) != 0 {
+		t.Fatalf("Corrupted frame emitted early: %+v", frames)
+	}
+	frames = d.Feed(nil, now.Add(modbusFrameGap))
+	if len(frames) != 1 || frames[0].Valid() || !strings.Contains(frames[0].Error, "CRC") {
+		t.Errorf("Corrupted frame: %+v", frames)
+	}
+
+	exception := []byte{0x11, 0x83, 0x02}
+	crc = ModbusCRC(exception)
+	frame := ParseModbus(append(exception, byte(crc), byte(crc>>8)))
+	if !strings.Contains(frame.Summary, "exception 2 (illegal data address)") {
+		t.Errorf("Exception frame: %+v", frame)
+	}
+}
+
+func TestModbusDirection(t *testing.T) {
+	withCRC := func(body ...byte) []byte {
+		crc := ModbusCRC(body)
+		return append(body, byte(crc), byte(crc>>8))
+	}
+	// Reading 20 coils returns 3 bytes, the length of a read request
+	request := withCRC(0x01, 0x01, 0x00, 0x00, 0x00, 0x14)
+	response := withCRC(0x01, 0x01, 0x03, 0xAA, 0x55, 0x01)
+	now := time.Now()
+
+	// Both seen on the line, as when monitoring a bus
+	d := &Modbus{}
+	frames := d.Feed(append(append([]byte(nil), request...), response...), now)
+	if len(frames) != 2 || !strings.Contains(frames[0].Summary, "request addr=0 count=20") {
+		t.Fatalf("Frames = %+v", frames)
+	}
+	if !frames[1].Valid() || !strings.Contains(frames[1].Summary, "response 3 bytes: AA 55 01") {
+		t.Errorf("Response frame: %+v", frames[1])
+	}
+
+	// The request sent by sterm, only the response received
+	d = &Modbus{}
+	d.ObserveTx(request, now)
+	frames = d.Feed(response, now)
+	if len(frames) != 1 || !strings.Contains(frames[0].Summary, "response 3 bytes") {
+		t.Errorf("Frames after sending the request = %+v", frames)
+	}
+
+	// Another request follows the response
+	if frames := d.Feed(request, now); len(frames) != 1 || !strings.Contains(frames[0].Summary, "request") {
+		t.Errorf("Next request = %+v", frames)
+	}
+}
+
+func TestSLIP(t *testing.T) {
+	d := &SLIP{}
+	frames := d.Feed([]byte{slipEnd, slipEnd, 0x01, slipEsc, slipEscEnd, 0x02, slipEsc, slipEscEsc, slipEnd}, time.Now())
+	if len(frames) != 1 {
+		t.Fatalf("Got %d frames, want 1", len(frames))
+	}
+	if !frames[0].Valid() || frames[0].Summary != "4 bytes: 01 C0 02 DB" {
+		t.Errorf("Frame: %+v", frames[0])
+	}
+
+	frames = d.Feed([]byte{0x01, slipEsc, 0x42, slipEnd}, time.Now())
+	if len(frames) != 1 || frames[0].Valid() {
+		t.Errorf("Bad escape: %+v", frames)
+	}
+
+	kiss := &SLIP{kiss: true}
+	frames = kiss.Feed([]byte{slipEnd, 0x10, 'h', 'i', slipEnd}, time.Now())
+	if len(frames) != 1 || frames[0].Summary != "port 1 Data, 2 bytes: 68 69" {
+		t.Errorf("KISS frame: %+v", frames)
+	}
+}
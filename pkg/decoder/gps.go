@@ -0,0 +1,148 @@
+package decoder
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GPSFix is the receiver state put together from NMEA sentences, for the
+// GPS dashboard. Fields a receiver hasn't reported yet keep their zero
+// values; the Has flags tell those from real zeros.
+type GPSFix struct {
+	Updated time.Time // When the last sentence was applied
+
+	Valid   bool   // RMC status is A (active)
+	Quality int    // GGA fix quality: 0 none, 1 GPS, 2 DGPS, 4 RTK fixed, 5 RTK float, 6 estimated
+	Mode    int    // GSA fix type: 1 none, 2 2D, 3 3D
+	UTC     string // hh:mm:ss.ss
+	Date    string // yyyy-mm-dd
+
+	Latitude, Longitude float64 // Decimal degrees, negative for S and W
+	HasPosition         bool
+	Altitude            float64 // Meters above mean sea level
+	HasAltitude         bool
+	SpeedKnots          float64
+	Course              float64 // Degrees true
+	HasMotion           bool
+
+	SatsUsed   int
+	SatsInView int     // Summed over the constellations reporting GSV
+	HDOP       float64 // 0 until reported
+	inView     map[string]int
+}
+
+// Apply updates the fix from a sentence without its line ending. Sentences
+// with a bad checksum and types that don't describe the fix are ignored; the
+// return value tells whether anything changed.
+func (g *GPSFix) Apply(sentence string, now time.Time) bool {
+	if len(sentence) < 7 || sentence[0] != '$' || !ParseNMEA(sentence).Valid() {
+		return false
+	}
+	body, _, _ := strings.Cut(sentence[1:], "*")
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 {
+		return false
+	}
+	talker, kind := fields[0][:2], fields[0][2:]
+	f := func(i int) string { return nmeaField(fields[1:], i) }
+
+	switch kind {
+	case "GGA":
+		g.UTC = nmeaTime(f(0))
+		g.setPosition(f(1), f(2), f(3), f(4))
+		g.Quality, _ = strconv.Atoi(f(5))
+		if sats, err := strconv.Atoi(f(6)); err == nil {
+			g.SatsUsed = sats
+		}
+		if hdop, err := strconv.ParseFloat(f(7), 64); err == nil {
+			g.HDOP = hdop
+		}
+		g.Altitude, g.HasAltitude = parseFloat(f(8))
+	case "RMC":
+		g.UTC = nmeaTime(f(0))
+		g.Valid = f(1) == "A"
+		g.setPosition(f(2), f(3), f(4), f(5))
+		speed, hasSpeed := parseFloat(f(6))
+		course, _ := parseFloat(f(7))
+		if hasSpeed {
+			g.SpeedKnots, g.Course, g.HasMotion = speed, course, true
+		}
+		if date := f(8); len(date) == 6 {
+			century := "20"
+			if date[4:6] >= "80" {
+				century = "19" // Two-digit years, as receivers from before 2000 sent them
+			}
+			g.Date = century + date[4:6] + "-" + date[2:4] + "-" + date[0:2]
+		}
+	case "GSA":
+		g.Mode, _ = strconv.Atoi(f(1))
+		if hdop, err := strconv.ParseFloat(f(15), 64); err == nil {
+			g.HDOP = hdop
+		}
+	case "GSV":
+		sats, err := strconv.Atoi(f(2))
+		if err != nil {
+			return false
+		}
+		if g.inView == nil {
+			g.inView = make(map[string]int)
+		}
+		g.inView[talker] = sats
+		g.SatsInView = 0
+		for _, n := range g.inView {
+			g.SatsInView += n
+		}
+	default:
+		return false
+	}
+
+	g.Updated = now
+	return true
+}
+
+// setPosition stores a position if both coordinates parse
+func (g *GPSFix) setPosition(lat, latHemisphere, lon, lonHemisphere string) {
+	latitude, ok1 := nmeaDegrees(lat)
+	longitude, ok2 := nmeaDegrees(lon)
+	if !ok1 || !ok2 {
+		return
+	}
+	if latHemisphere == "S" {
+		latitude = -latitude
+	}
+	if lonHemisphere == "W" {
+		longitude = -longitude
+	}
+	g.Latitude, g.Longitude, g.HasPosition = latitude, longitude, true
+}
+
+// FixDescription names the fix, e.g. "3D fix (DGPS)" or "No fix"
+func (g *GPSFix) FixDescription() string {
+	if g.Updated.IsZero() {
+		return "Waiting for data"
+	}
+
+	qualities := map[int]string{1: "GPS", 2: "DGPS", 3: "PPS", 4: "RTK fixed", 5: "RTK float", 6: "estimated", 8: "simulated"}
+	switch {
+	case g.Mode == 3 || (g.Mode == 0 && g.HasAltitude && g.Quality > 0):
+		if q, ok := qualities[g.Quality]; ok {
+			return "3D fix (" + q + ")"
+		}
+		return "3D fix"
+	case g.Mode == 2:
+		return "2D fix"
+	case g.Quality > 0 || (g.Mode == 0 && g.Valid):
+		if q, ok := qualities[g.Quality]; ok {
+			return "Fix (" + q + ")"
+		}
+		return "Fix"
+	}
+	return "No fix"
+}
+
+// parseFloat parses an optional numeric field
+func parseFloat(value string) (float64, bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil
+}
@@ -0,0 +1,275 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"sterm/pkg/checksum"
+)
+
+// modbusFrameGap is the silence that ends a Modbus RTU frame. The standard
+// 3.5 character times can't be seen through OS read buffering, so complete
+// frames are found by length and CRC instead and the gap only finishes
+// frames that never became valid.
+const modbusFrameGap = 20 * time.Millisecond
+
+// modbusMaxFrame is the longest RTU frame the standard allows
+const modbusMaxFrame = 256
+
+// modbusFunctions names the common function codes
+var modbusFunctions = map[byte]string{
+	1:  "Read Coils",
+	2:  "Read Discrete Inputs",
+	3:  "Read Holding Registers",
+	4:  "Read Input Registers",
+	5:  "Write Single Coil",
+	6:  "Write Single Register",
+	7:  "Read Exception Status",
+	8:  "Diagnostics",
+	15: "Write Multiple Coils",
+	16: "Write Multiple Registers",
+	17: "Report Server ID",
+	23: "Read/Write Multiple Registers",
+}
+
+// modbusExceptions names the exception codes
+var modbusExceptions = map[byte]string{
+	1:  "illegal function",
+	2:  "illegal data address",
+	3:  "illegal data value",
+	4:  "server device failure",
+	5:  "acknowledge",
+	6:  "server device busy",
+	8:  "memory parity error",
+	10: "gateway path unavailable",
+	11: "gateway target failed to respond",
+}
+
+// Modbus decodes Modbus RTU requests and responses and validates their CRC.
+// A frame for the unit and function of the last request, whether sterm
+// sent it or it was seen on the line, is read as its response.
+type Modbus struct {
+	buf     []byte
+	last    time.Time     // When the last byte arrived
+	tx      []byte        // Sent data not yet found to hold a request
+	txLast  time.Time     // When data was last sent
+	request modbusRequest // The request awaiting a response
+	waiting bool          // Whether request is set
+}
+
+// modbusRequest identifies a request by the unit and function it went to
+type modbusRequest struct {
+	unit, fc byte
+}
+
+func init() {
+	Register("modbus", func() Decoder { return &Modbus{} })
+}
+
+// Name returns "modbus"
+func (d *Modbus) Name() string {
+	return "modbus"
+}
+
+// Reset drops a partial frame and the request awaiting a response
+func (d *Modbus) Reset() {
+	d.buf = d.buf[:0]
+	d.tx = d.tx[:0]
+	d.waiting = false
+}
+
+// ObserveTx finds the requests in data sent to the device, so the frames
+// that come back are read as their responses
+func (d *Modbus) ObserveTx(data []byte, now time.Time) {
+	if len(d.tx) > 0 && now.Sub(d.txLast) >= modbusFrameGap {
+		d.tx = d.tx[:0]
+	}
+	d.txLast = now
+	d.tx = append(d.tx, data...)
+
+	for len(d.tx) >= 4 {
+		n := modbusFrameLength(d.tx, false)
+		if n == 0 {
+			if len(d.tx) >= modbusMaxFrame {
+				d.tx = d.tx[:0]
+			}
+			break
+		}
+		d.request, d.waiting = modbusRequest{unit: d.tx[0], fc: d.tx[1]}, true
+		d.tx = append(d.tx[:0], d.tx[n:]...)
+	}
+}
+
+// awaited reports whether buf starts with the response to the request
+// last seen
+func (d *Modbus) awaited(buf []byte) bool {
+	return d.waiting && len(buf) >= 2 && buf[0] == d.request.unit && buf[1]&0x7F == d.request.fc
+}
+
+// Feed splits the stream into frames: as soon as the buffer starts with a
+// frame of a plausible length and a valid CRC, or after a gap in the data
+func (d *Modbus) Feed(data []byte, now time.Time) []Frame {
+	var frames []Frame
+	if len(d.buf) > 0 && now.Sub(d.last) >= modbusFrameGap {
+		frames = append(frames, d.frame(d.buf, now))
+		d.buf = d.buf[:0]
+	}
+	if len(data) == 0 {
+		return frames
+	}
+	d.last = now
+	d.buf = append(d.buf, data...)
+
+	for len(d.buf) >= 4 {
+		n := modbusFrameLength(d.buf, d.awaited(d.buf))
+		if n == 0 {
+			if len(d.buf) >= modbusMaxFrame {
+				frames = append(frames, d.frame(d.buf, now))
+				d.buf = d.buf[:0]
+			}
+			break
+		}
+		frames = append(frames, d.frame(d.buf[:n], now))
+		d.buf = append(d.buf[:0], d.buf[n:]...)
+	}
+	return frames
+}
+
+// frame decodes a complete frame, copying its bytes, and keeps track of
+// the request awaiting a response
+func (d *Modbus) frame(data []byte, now time.Time) Frame {
+	response := d.awaited(data)
+	if !response && len(data) >= 4 {
+		response = modbusGuessResponse(data[1], data[2:len(data)-2])
+	}
+	frame := parseModbus(data, response)
+	frame.Time = now
+	switch {
+	case response:
+		d.waiting = false
+	case frame.Valid() && len(data) >= 4:
+		d.request, d.waiting = modbusRequest{unit: data[0], fc: data[1]}, true
+	}
+	return frame
+}
+
+// modbusFrameLength returns the length of the frame at the start of buf if
+// one of the lengths its function code allows ends in a valid CRC, or 0.
+// The lengths of a response are tried first if one is expected.
+func modbusFrameLength(buf []byte, response bool) int {
+	fc := buf[1]
+	var requests, responses []int
+	switch {
+	case fc&0x80 != 0:
+		responses = []int{5}
+	case fc >= 1 && fc <= 4:
+		requests, responses = []int{8}, []int{5 + int(buf[2])}
+	case fc == 5 || fc == 6:
+		requests = []int{8} // The response echoes the request
+	case fc == 15 || fc == 16:
+		responses = []int{8}
+		if len(buf) > 6 {
+			requests = []int{9 + int(buf[6])}
+		}
+	default:
+		return 0 // Unknown length; wait for the gap
+	}
+	lengths := append(requests, responses...)
+	if response {
+		lengths = append(responses, requests...)
+	}
+
+	for _, n := range lengths {
+		if n <= len(buf) && ModbusCRC(buf[:n-2]) == binary.LittleEndian.Uint16(buf[n-2:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// ModbusCRC computes the CRC-16/MODBUS of data
+func ModbusCRC(data []byte) uint16 {
+	return checksum.CRC16Modbus(data)
+}
+
+// ParseModbus decodes one RTU frame seen on its own, telling requests
+// from responses by their shape
+func ParseModbus(data []byte) Frame {
+	response := len(data) >= 4 && modbusGuessResponse(data[1], data[2:len(data)-2])
+	return parseModbus(data, response)
+}
+
+// modbusGuessResponse tells a request from a response by its payload
+// alone. A read response carrying 3 bytes has the length of a read
+// request and is taken for one.
+func modbusGuessResponse(fc byte, payload []byte) bool {
+	switch {
+	case fc&0x80 != 0:
+		return true
+	case fc >= 1 && fc <= 4:
+		return len(payload) != 4
+	case fc == 15 || fc == 16:
+		return len(payload) == 4
+	default:
+		return false
+	}
+}
+
+// parseModbus decodes one RTU frame known to be a request or a response
+func parseModbus(data []byte, response bool) Frame {
+	frame := Frame{Decoder: "modbus", Raw: append([]byte(nil), data...)}
+	if len(data) < 4 {
+		frame.Summary = hexPreview(data)
+		frame.Error = fmt.Sprintf("too short (%d bytes)", len(data))
+		return frame
+	}
+
+	body := data[:len(data)-2]
+	if got, want := binary.LittleEndian.Uint16(data[len(data)-2:]), ModbusCRC(body); got != want {
+		frame.Error = fmt.Sprintf("CRC %04X, expected %04X", got, want)
+	}
+
+	unit, fc, payload := body[0], body[1], body[2:]
+	name := modbusFunctions[fc&0x7F]
+	if name == "" {
+		name = fmt.Sprintf("function %d", fc&0x7F)
+	}
+	frame.Summary = fmt.Sprintf("unit %d %s: %s", unit, name, modbusDetail(fc, payload, response))
+	return frame
+}
+
+// modbusDetail describes the payload of a request or response
+func modbusDetail(fc byte, payload []byte, response bool) string {
+	u16 := func(i int) uint16 { return binary.BigEndian.Uint16(payload[i:]) }
+
+	if fc&0x80 != 0 && len(payload) == 1 {
+		reason := modbusExceptions[payload[0]]
+		if reason == "" {
+			reason = "unknown"
+		}
+		return fmt.Sprintf("exception %d (%s)", payload[0], reason)
+	}
+
+	switch {
+	case fc >= 1 && fc <= 4 && !response && len(payload) == 4:
+		return fmt.Sprintf("request addr=%d count=%d", u16(0), u16(2))
+	case fc >= 1 && fc <= 4 && response && len(payload) >= 1 && int(payload[0]) == len(payload)-1:
+		if fc <= 2 {
+			return fmt.Sprintf("response %d bytes: %s", payload[0], hexPreview(payload[1:]))
+		}
+		var values []uint16
+		for i := 1; i+1 < len(payload); i += 2 {
+			values = append(values, u16(i))
+		}
+		return fmt.Sprintf("response %v", values)
+	case (fc == 5 || fc == 6) && len(payload) == 4:
+		return fmt.Sprintf("addr=%d value=%d", u16(0), u16(2))
+	case (fc == 15 || fc == 16) && response && len(payload) == 4:
+		return fmt.Sprintf("response addr=%d count=%d", u16(0), u16(2))
+	case (fc == 15 || fc == 16) && !response && len(payload) >= 5:
+		return fmt.Sprintf("request addr=%d count=%d data: %s", u16(0), u16(2), hexPreview(payload[5:]))
+	default:
+		return hexPreview(payload)
+	}
+}
@@ -0,0 +1,153 @@
+package decoder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmeaMaxLine bounds a sentence; the standard allows 82 characters but some
+// receivers send longer proprietary ones
+const nmeaMaxLine = 256
+
+// NMEA decodes NMEA 0183 sentences ($GPGGA, !AIVDM, ...) and checks their
+// checksums. Lines that aren't sentences, such as boot messages, are skipped.
+type NMEA struct {
+	line []byte
+}
+
+func init() {
+	Register("nmea", func() Decoder { return &NMEA{} })
+}
+
+// Name returns "nmea"
+func (d *NMEA) Name() string {
+	return "nmea"
+}
+
+// Reset drops a partial sentence
+func (d *NMEA) Reset() {
+	d.line = d.line[:0]
+}
+
+// Feed collects lines and decodes the ones that are sentences
+func (d *NMEA) Feed(data []byte, now time.Time) []Frame {
+	var frames []Frame
+	for _, b := range data {
+		if b != '\n' {
+			if len(d.line) < nmeaMaxLine {
+				d.line = append(d.line, b)
+			}
+			continue
+		}
+
+		line := strings.TrimRight(string(d.line), "\r")
+		d.line = d.line[:0]
+		if len(line) > 1 && (line[0] == '$' || line[0] == '!') {
+			frame := ParseNMEA(line)
+			frame.Time = now
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// ParseNMEA decodes one sentence without its line ending
+func ParseNMEA(sentence string) Frame {
+	frame := Frame{Decoder: "nmea", Raw: []byte(sentence)}
+
+	body, checksum, hasChecksum := strings.Cut(sentence[1:], "*")
+	if hasChecksum {
+		want, err := strconv.ParseUint(checksum, 16, 8)
+		var got byte
+		for i := 0; i < len(body); i++ {
+			got ^= body[i]
+		}
+		switch {
+		case err != nil || len(checksum) != 2:
+			frame.Error = fmt.Sprintf("malformed checksum %q", checksum)
+		case byte(want) != got:
+			frame.Error = fmt.Sprintf("checksum %02X, expected %02X", want, got)
+		}
+	}
+
+	fields := strings.Split(body, ",")
+	kind := fields[0]
+	if len(kind) == 5 {
+		kind = kind[2:] // Drop the talker, e.g. GP or GN
+	}
+	frame.Summary = fields[0] + " " + nmeaSummary(kind, fields[1:])
+	if !hasChecksum {
+		frame.Summary += " (no checksum)"
+	}
+	return frame
+}
+
+// nmeaField returns field i or "" if the sentence is short
+func nmeaField(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// nmeaSummary describes the common sentence types, and the field count of others
+func nmeaSummary(kind string, fields []string) string {
+	f := func(i int) string { return nmeaField(fields, i) }
+	switch kind {
+	case "GGA":
+		return fmt.Sprintf("fix time=%s pos=%s %s quality=%s sats=%s alt=%s%s",
+			nmeaTime(f(0)), nmeaCoord(f(1), f(2)), nmeaCoord(f(3), f(4)), f(5), f(6), f(8), strings.ToLower(f(9)))
+	case "RMC":
+		status := "valid"
+		if f(1) != "A" {
+			status = "no fix"
+		}
+		return fmt.Sprintf("%s time=%s pos=%s %s speed=%skn course=%s date=%s",
+			status, nmeaTime(f(0)), nmeaCoord(f(2), f(3)), nmeaCoord(f(4), f(5)), f(6), f(7), f(8))
+	case "GLL":
+		return fmt.Sprintf("pos=%s %s time=%s status=%s", nmeaCoord(f(0), f(1)), nmeaCoord(f(2), f(3)), nmeaTime(f(4)), f(5))
+	case "VTG":
+		return fmt.Sprintf("course=%s speed=%skn %skm/h", f(0), f(4), f(6))
+	case "GSV":
+		return fmt.Sprintf("satellites in view=%s (message %s/%s)", f(2), f(1), f(0))
+	case "GSA":
+		return fmt.Sprintf("mode=%s fix=%sD pdop=%s hdop=%s vdop=%s", f(0), f(1), f(14), f(15), f(16))
+	case "ZDA":
+		return fmt.Sprintf("time=%s date=%s-%s-%s", nmeaTime(f(0)), f(3), f(2), f(1))
+	default:
+		return fmt.Sprintf("%d fields", len(fields))
+	}
+}
+
+// nmeaTime formats hhmmss.ss as hh:mm:ss.ss
+func nmeaTime(value string) string {
+	if len(value) < 6 {
+		return value
+	}
+	return value[0:2] + ":" + value[2:4] + ":" + value[4:]
+}
+
+// nmeaCoord converts (d)ddmm.mmmm and a hemisphere to decimal degrees
+func nmeaCoord(value, hemisphere string) string {
+	degrees, ok := nmeaDegrees(value)
+	if !ok {
+		return value + hemisphere
+	}
+	return fmt.Sprintf("%.5f°%s", degrees, hemisphere)
+}
+
+// nmeaDegrees parses (d)ddmm.mmmm as decimal degrees
+func nmeaDegrees(value string) (float64, bool) {
+	dot := strings.IndexByte(value, '.')
+	if dot < 2 {
+		return 0, false
+	}
+	degrees, err1 := strconv.ParseFloat(value[:dot-2], 64)
+	minutes, err2 := strconv.ParseFloat(value[dot-2:], 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	return degrees + minutes/60, true
+}
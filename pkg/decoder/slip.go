@@ -0,0 +1,125 @@
+package decoder
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLIP framing bytes (RFC 1055), also used by KISS TNCs
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+// slipMaxFrame bounds a frame so a stream without END bytes can't grow it
+const slipMaxFrame = 4096
+
+// kissCommands names the KISS command nibbles
+var kissCommands = map[byte]string{
+	0: "Data",
+	1: "TXDELAY",
+	2: "Persistence",
+	3: "SlotTime",
+	4: "TXtail",
+	5: "FullDuplex",
+	6: "SetHardware",
+}
+
+// SLIP finds frames delimited by SLIP END bytes and undoes the escaping.
+// With kiss set the first byte of each frame is decoded as a KISS command.
+type SLIP struct {
+	kiss    bool
+	frame   []byte
+	raw     []byte
+	escaped bool
+	badEsc  bool
+}
+
+func init() {
+	Register("slip", func() Decoder { return &SLIP{} })
+	Register("kiss", func() Decoder { return &SLIP{kiss: true} })
+}
+
+// Name returns "slip" or "kiss"
+func (d *SLIP) Name() string {
+	if d.kiss {
+		return "kiss"
+	}
+	return "slip"
+}
+
+// Reset drops a partial frame
+func (d *SLIP) Reset() {
+	d.frame, d.raw = d.frame[:0], d.raw[:0]
+	d.escaped, d.badEsc = false, false
+}
+
+// Feed unescapes bytes and returns a frame at every END that closes one
+func (d *SLIP) Feed(data []byte, now time.Time) []Frame {
+	var frames []Frame
+	for _, b := range data {
+		if b == slipEnd {
+			// Back-to-back ENDs are idle fill, not empty frames
+			if len(d.frame) > 0 || d.badEsc {
+				frames = append(frames, d.finish(now))
+			}
+			d.Reset()
+			continue
+		}
+
+		d.raw = append(d.raw, b)
+		switch {
+		case d.escaped:
+			d.escaped = false
+			switch b {
+			case slipEscEnd:
+				b = slipEnd
+			case slipEscEsc:
+				b = slipEsc
+			default:
+				d.badEsc = true
+			}
+		case b == slipEsc:
+			d.escaped = true
+			continue
+		}
+		d.frame = append(d.frame, b)
+
+		if len(d.raw) >= slipMaxFrame {
+			frame := d.finish(now)
+			frame.Error = "no END within 4096 bytes"
+			frames = append(frames, frame)
+			d.Reset()
+		}
+	}
+	return frames
+}
+
+// finish decodes the collected frame
+func (d *SLIP) finish(now time.Time) Frame {
+	frame := Frame{
+		Time:    now,
+		Decoder: d.Name(),
+		Raw:     append([]byte(nil), d.raw...),
+	}
+	if d.badEsc {
+		frame.Error = "invalid escape sequence"
+	}
+
+	payload := d.frame
+	if d.kiss && len(payload) > 0 {
+		port, cmd := payload[0]>>4, payload[0]&0x0F
+		name := kissCommands[cmd]
+		if payload[0] == 0xFF {
+			name, port = "Return", 0
+		} else if name == "" {
+			name = fmt.Sprintf("command %d", cmd)
+		}
+		frame.Summary = fmt.Sprintf("port %d %s, ", port, name)
+		payload = payload[1:]
+	}
+	frame.Summary += fmt.Sprintf("%d bytes: %s", len(payload), hexPreview(payload))
+	return frame
+}
@@ -0,0 +1,160 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ChunkTime marks where a read starts within an entry's data and when it
+// arrived. An entry covering a 64KB buffer may hold hundreds of reads;
+// these keep the timing between them for analysis and replay.
+type ChunkTime struct {
+	Offset int       `json:"offset"`
+	Time   time.Time `json:"time"`
+}
+
+// TimedWriter is implemented by history managers that can keep the
+// arrival time of each read within an entry
+type TimedWriter interface {
+	// WriteTimed adds an entry like Write, with the offsets and times of
+	// the reads it was made of
+	WriteTimed(data []byte, direction Direction, chunks []ChunkTime) error
+}
+
+// TimedData is a piece of an entry's data with the time it arrived
+type TimedData struct {
+	Time time.Time
+	Data []byte
+}
+
+// validateChunks checks that chunks start at the beginning of data and
+// their offsets increase within it
+func validateChunks(data []byte, chunks []ChunkTime) error {
+	for i, chunk := range chunks {
+		switch {
+		case i == 0 && chunk.Offset != 0:
+			return fmt.Errorf("first chunk must start at offset 0, got %d", chunk.Offset)
+		case i > 0 && chunk.Offset <= chunks[i-1].Offset:
+			return fmt.Errorf("chunk offsets must increase: %d after %d", chunk.Offset, chunks[i-1].Offset)
+		case chunk.Offset >= len(data):
+			return fmt.Errorf("chunk offset %d is past the %d bytes of data", chunk.Offset, len(data))
+		}
+	}
+	return nil
+}
+
+// newTimedEntry creates an entry keeping a copy of the read times
+func newTimedEntry(data []byte, direction Direction, chunks []ChunkTime) (HistoryEntry, error) {
+	if err := validateChunks(data, chunks); err != nil {
+		return HistoryEntry{}, err
+	}
+	entry := NewHistoryEntry(data, direction)
+	if len(chunks) > 0 {
+		entry.Chunks = append([]ChunkTime(nil), chunks...)
+	}
+	return entry, nil
+}
+
+// Pieces splits the entry into its reads with their arrival times, or
+// returns the whole entry at its timestamp if read times weren't kept
+func (h HistoryEntry) Pieces() []TimedData {
+	if len(h.Chunks) == 0 {
+		return []TimedData{{Time: h.Timestamp, Data: h.Data}}
+	}
+	pieces := make([]TimedData, len(h.Chunks))
+	for i, chunk := range h.Chunks {
+		end := len(h.Data)
+		if i+1 < len(h.Chunks) {
+			end = h.Chunks[i+1].Offset
+		}
+		pieces[i] = TimedData{Time: chunk.Time, Data: h.Data[chunk.Offset:end]}
+	}
+	return pieces
+}
+
+// LoadEntries reads saved history: JSON, either a whole export
+// (SaveToFile) or one entry per line (AppendEntriesToFile), or the
+// timestamped format; gzipped or not
+func LoadEntries(filename string) ([]HistoryEntry, error) {
+	data, err := ReadHistoryFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return ParseEntries(data)
+}
+
+// ParseEntries parses history in any format LoadEntries reads
+func ParseEntries(data []byte) ([]HistoryEntry, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return ParseTimestamped(data)
+	}
+
+	var export struct {
+		Entries []HistoryEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &export); err == nil && export.Entries != nil {
+		return export.Entries, nil
+	}
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry on line %d: %w", lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if entries == nil {
+		return nil, fmt.Errorf("no history entries found")
+	}
+	return entries, nil
+}
+
+// timestampedLine matches a line of the timestamped format
+var timestampedLine = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?)\] (<<|>>) `)
+
+// ParseTimestamped parses history saved in the timestamped format, an
+// entry per line (a read per line where read times were kept). Times are
+// in the local time zone, as they were written. Line feeds in the data
+// were saved as \n and are restored; a literal backslash-n in the data
+// can't be told apart and comes back as a line feed too.
+func ParseTimestamped(data []byte) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	for lineNum, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		match := timestampedLine.FindSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d is not a timestamped history entry", lineNum+1)
+		}
+		at, err := time.ParseInLocation("2006-01-02 15:04:05", string(match[1]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time on line %d: %w", lineNum+1, err)
+		}
+		direction := DirectionInput
+		if string(match[2]) == ">>" {
+			direction = DirectionOutput
+		}
+		entry := NewHistoryEntry(bytes.ReplaceAll(line[len(match[0]):], []byte(`\n`), []byte("\n")), direction)
+		entry.Timestamp = at
+		entries = append(entries, entry)
+	}
+	if entries == nil {
+		return nil, fmt.Errorf("no history entries found")
+	}
+	return entries, nil
+}
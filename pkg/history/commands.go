@@ -0,0 +1,228 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultCommandHistorySize is the number of commands kept per profile
+const DefaultCommandHistorySize = 1000
+
+// CommandHistory is a readline-style history of typed command lines,
+// persisted to one file per connection profile
+type CommandHistory struct {
+	path       string
+	commands   []string
+	maxEntries int
+	fileLines  int // Commands in the file, kept ones and trimmed ones not yet rewritten
+	mu         sync.RWMutex
+}
+
+// unsafeNameChars matches characters that can't appear in history file names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// CommandHistoryPath returns the history file for a profile under baseDir
+// (~/.sterm/history when baseDir is empty)
+func CommandHistoryPath(baseDir, profile string) (string, error) {
+	return profileHistoryPath(baseDir, profile, ".history")
+}
+
+// PayloadHistoryPath returns the file of payloads sent from the send-hex
+// box for a profile, next to its command history
+func PayloadHistoryPath(baseDir, profile string) (string, error) {
+	return profileHistoryPath(baseDir, profile, ".payloads")
+}
+
+// profileHistoryPath names a per-profile file with the given extension
+func profileHistoryPath(baseDir, profile, ext string) (string, error) {
+	if baseDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		baseDir = filepath.Join(homeDir, ".sterm", "history")
+	}
+
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(profile, "_"), "_")
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(baseDir, name+ext), nil
+}
+
+// NewCommandHistory creates a command history backed by the given file.
+// An empty path keeps the history in memory only.
+func NewCommandHistory(path string, maxEntries int) *CommandHistory {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCommandHistorySize
+	}
+	return &CommandHistory{
+		path:       path,
+		commands:   make([]string, 0),
+		maxEntries: maxEntries,
+	}
+}
+
+// Load reads the history file. A missing file is not an error.
+func (ch *CommandHistory) Load() error {
+	if ch.path == "" {
+		return nil
+	}
+
+	file, err := os.Open(ch.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open command history: %w", err)
+	}
+	defer file.Close()
+
+	var commands []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read command history: %w", err)
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.fileLines = len(commands)
+	if len(commands) > ch.maxEntries {
+		commands = commands[len(commands)-ch.maxEntries:]
+	}
+	ch.commands = commands
+	return nil
+}
+
+// Add records a command. Empty commands and repeats of the last command are ignored.
+func (ch *CommandHistory) Add(command string) error {
+	command = strings.TrimRight(command, "\r\n")
+	if strings.TrimSpace(command) == "" || strings.ContainsAny(command, "\r\n") {
+		return nil
+	}
+
+	ch.mu.Lock()
+	if n := len(ch.commands); n > 0 && ch.commands[n-1] == command {
+		ch.mu.Unlock()
+		return nil
+	}
+	ch.commands = append(ch.commands, command)
+	if len(ch.commands) > ch.maxEntries {
+		ch.commands = ch.commands[len(ch.commands)-ch.maxEntries:]
+	}
+	ch.fileLines++
+	compact := ch.fileLines > 2*ch.maxEntries
+	ch.mu.Unlock()
+
+	if ch.path == "" {
+		return nil
+	}
+
+	// Commands are appended, and the file rewritten with only the kept ones
+	// once it holds twice as many, so it doesn't grow without bound
+	if compact {
+		return ch.Save()
+	}
+	return ch.appendLine(command)
+}
+
+// appendLine appends a single command to the history file
+func (ch *CommandHistory) appendLine(command string) error {
+	if err := os.MkdirAll(filepath.Dir(ch.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(ch.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open command history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(command + "\n"); err != nil {
+		return fmt.Errorf("failed to write command history: %w", err)
+	}
+	return nil
+}
+
+// Save rewrites the whole history file
+func (ch *CommandHistory) Save() error {
+	if ch.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ch.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	ch.mu.Lock()
+	content := strings.Join(ch.commands, "\n")
+	ch.fileLines = len(ch.commands)
+	ch.mu.Unlock()
+	if content != "" {
+		content += "\n"
+	}
+
+	tempFile := ch.path + ".tmp"
+	if err := os.WriteFile(tempFile, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write command history: %w", err)
+	}
+	if err := os.Rename(tempFile, ch.path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to save command history: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of stored commands
+func (ch *CommandHistory) Len() int {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return len(ch.commands)
+}
+
+// Get returns the command at index (0 = oldest)
+func (ch *CommandHistory) Get(index int) (string, bool) {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	if index < 0 || index >= len(ch.commands) {
+		return "", false
+	}
+	return ch.commands[index], true
+}
+
+// Commands returns a copy of all commands, oldest first
+func (ch *CommandHistory) Commands() []string {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	result := make([]string, len(ch.commands))
+	copy(result, ch.commands)
+	return result
+}
+
+// SearchBackward finds the most recent command containing query, starting
+// at index before and moving towards older entries. Returns -1 if not found.
+func (ch *CommandHistory) SearchBackward(query string, before int) int {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	if before > len(ch.commands) {
+		before = len(ch.commands)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(ch.commands[i], query) {
+			return i
+		}
+	}
+	return -1
+}
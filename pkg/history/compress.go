@@ -0,0 +1,304 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultLiveWindow is how much recent data a compressing history keeps
+// uncompressed, so the data being added and looked at most is cheap to read
+const DefaultLiveWindow = 1024 * 1024
+
+// compressSegmentSize is how much entry data is compressed together. Each
+// segment is a separate gzip stream, so reading one entry decompresses at
+// most this much.
+const compressSegmentSize = 64 * 1024
+
+// CompressibleHistory is implemented by history managers that can compress
+// data once it is older than a live window, fitting more into their size
+type CompressibleHistory interface {
+	// SetCompression keeps the newest liveWindow bytes uncompressed and
+	// compresses older data; 0 stops compressing new data
+	SetCompression(liveWindow int) error
+	CompressionStats() CompressionStats
+}
+
+// CompressionStats describes the compressed part of a history
+type CompressionStats struct {
+	Segments        int `json:"segments"`
+	Entries         int `json:"entries"`          // Entries whose data is compressed
+	RawBytes        int `json:"raw_bytes"`        // Their data uncompressed
+	CompressedBytes int `json:"compressed_bytes"` // What it takes compressed
+}
+
+// Ratio returns the uncompressed size over the compressed size, 0 when
+// nothing is compressed
+func (s CompressionStats) Ratio() float64 {
+	if s.CompressedBytes == 0 {
+		return 0
+	}
+	return float64(s.RawBytes) / float64(s.CompressedBytes)
+}
+
+// segment is the gzip-compressed data of a run of consecutive entries
+type segment struct {
+	data []byte
+	raw  int // Uncompressed length
+}
+
+// packedRef locates a compressed entry's data within its segment. cost is
+// the entry's share of the compressed size, counted against the history's
+// maximum size instead of the data's length.
+type packedRef struct {
+	seg    *segment
+	offset int
+	length int
+	cost   int
+}
+
+// segmentCache holds the last segment decompressed, as reads and searches
+// mostly walk entries in order
+type segmentCache struct {
+	seg  *segment
+	data []byte
+	mu   sync.Mutex
+}
+
+// get returns the decompressed data of seg. Segments are only written by
+// compressEntries, but a corrupt one fails the entries in it rather than
+// the whole history.
+func (c *segmentCache) get(seg *segment) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seg == seg {
+		return c.data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(seg.data))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt compressed history: %w", err)
+	}
+	var buf bytes.Buffer
+	buf.Grow(seg.raw)
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, fmt.Errorf("corrupt compressed history: %w", err)
+	}
+	if buf.Len() != seg.raw {
+		return nil, fmt.Errorf("corrupt compressed history: %d bytes, want %d", buf.Len(), seg.raw)
+	}
+	c.seg, c.data = seg, buf.Bytes()
+	return c.data, nil
+}
+
+// compressEntries compresses the data of entries into one segment, returning
+// where each entry's data is in it
+func compressEntries(entries []HistoryEntry) ([]packedRef, error) {
+	raw := 0
+	for _, entry := range entries {
+		raw += len(entry.Data)
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	for _, entry := range entries {
+		if _, err := writer.Write(entry.Data); err != nil {
+			return nil, fmt.Errorf("failed to compress history: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress history: %w", err)
+	}
+	seg := &segment{data: bytes.Clone(buf.Bytes()), raw: raw}
+
+	// Share the compressed size out by length, the rounding going to the
+	// last entry so the costs add up to the segment
+	refs := make([]packedRef, len(entries))
+	offset, charged := 0, 0
+	for i, entry := range entries {
+		cost := 0
+		if raw > 0 {
+			cost = len(entry.Data) * len(seg.data) / raw
+		}
+		if i == len(entries)-1 {
+			cost = len(seg.data) - charged
+		}
+		refs[i] = packedRef{seg: seg, offset: offset, length: len(entry.Data), cost: cost}
+		offset += len(entry.Data)
+		charged += cost
+	}
+	return refs, nil
+}
+
+// SetCompression keeps the newest liveWindow bytes uncompressed and
+// compresses older data in segments, so the maximum size holds several
+// times more. 0 stops compressing new data; what is already compressed
+// stays so.
+func (mhm *MemoryHistoryManager) SetCompression(liveWindow int) error {
+	if liveWindow < 0 {
+		return fmt.Errorf("live window cannot be negative")
+	}
+	mhm.mu.Lock()
+	defer mhm.unlock()
+	mhm.liveWindow = liveWindow
+	mhm.compress = liveWindow > 0
+	mhm.compressOld()
+	return nil
+}
+
+// CompressionStats describes the compressed part of the history
+func (mhm *MemoryHistoryManager) CompressionStats() CompressionStats {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+	stats := CompressionStats{Entries: mhm.packedCount, CompressedBytes: mhm.packedCost}
+	var last *segment
+	for _, ref := range mhm.refs[:mhm.packedCount] {
+		stats.RawBytes += ref.length
+		if ref.seg != last {
+			stats.Segments++
+			last = ref.seg
+		}
+	}
+	return stats
+}
+
+// compressOld compresses the oldest uncompressed entries in segments while
+// more than the live window is uncompressed. A segment is only made once
+// there is a whole one's worth of data past the window.
+func (mhm *MemoryHistoryManager) compressOld() {
+	if !mhm.compress {
+		return
+	}
+	for mhm.liveSize-compressSegmentSize > mhm.liveWindow {
+		end, raw := mhm.packedCount, 0
+		for end < len(mhm.entries) && raw < compressSegmentSize && mhm.liveSize-raw-len(mhm.entries[end].Data) >= mhm.liveWindow {
+			raw += len(mhm.entries[end].Data)
+			end++
+		}
+		if raw < compressSegmentSize {
+			return
+		}
+
+		refs, err := compressEntries(mhm.entries[mhm.packedCount:end])
+		if err != nil {
+			return // Stays uncompressed
+		}
+		for i, ref := range refs {
+			mhm.entries[mhm.packedCount+i].Data = nil
+			mhm.refs[mhm.packedCount+i] = ref
+			mhm.packedCost += ref.cost
+		}
+		mhm.packedCount = end
+		mhm.liveSize -= raw
+	}
+}
+
+// entrySize returns what entry i counts against the maximum size
+func (mhm *MemoryHistoryManager) entrySize(i int) int {
+	if i < mhm.packedCount {
+		return mhm.refs[i].cost
+	}
+	return len(mhm.entries[i].Data)
+}
+
+// dataLen returns the length of entry i's data, compressed or not
+func (mhm *MemoryHistoryManager) dataLen(i int) int {
+	if i < mhm.packedCount {
+		return mhm.refs[i].length
+	}
+	return len(mhm.entries[i].Data)
+}
+
+// materialize returns copies of entries [start, end) with their data,
+// decompressing where needed. Entries whose data can't be read are left
+// out, and the first such error is returned with the rest.
+func (mhm *MemoryHistoryManager) materialize(start, end int) ([]HistoryEntry, error) {
+	result := make([]HistoryEntry, 0, end-start)
+	var firstErr error
+	for i := start; i < end; i++ {
+		entry, err := mhm.entryAt(i)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result, firstErr
+}
+
+// evict drops the oldest n entries, reporting them to the eviction callback
+func (mhm *MemoryHistoryManager) evict(n int) {
+	if n <= 0 {
+		return
+	}
+	if mhm.onEvict != nil {
+		// Data that can't be read is dropped anyway; pass on the rest
+		entries, _ := mhm.materialize(0, n)
+		mhm.notifyEvicted(entries)
+	}
+	packed := min(n, mhm.packedCount)
+	for _, ref := range mhm.refs[:packed] {
+		mhm.packedCost -= ref.cost
+	}
+	for _, entry := range mhm.entries[packed:n] {
+		mhm.liveSize -= len(entry.Data)
+	}
+	mhm.packedCount -= packed
+	mhm.entries = mhm.entries[n:]
+	mhm.masks = mhm.masks[n:]
+	mhm.refs = mhm.refs[n:]
+}
+
+// gzipSuffix marks history files that are written compressed
+const gzipSuffix = ".gz"
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsCompressedFile reports whether history saved to filename is gzipped
+func IsCompressedFile(filename string) bool {
+	return strings.HasSuffix(filename, gzipSuffix)
+}
+
+// ReadHistoryFile reads a saved history file, decompressing it if it is
+// gzipped. Files appended to in several sessions hold several gzip
+// streams, which are read one after another.
+func ReadHistoryFile(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if magic, _ := reader.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return io.ReadAll(reader)
+	}
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", filename, err)
+	}
+	defer gz.Close()
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", filename, err)
+	}
+	return data, nil
+}
+
+// compressedWriter wraps file in a gzip stream when filename asks for one.
+// The returned close flushes the stream; it must be called before the file
+// is closed.
+func compressedWriter(file *os.File, filename string) (io.Writer, func() error) {
+	if !IsCompressedFile(filename) {
+		return file, func() error { return nil }
+	}
+	gz := gzip.NewWriter(file)
+	return gz, gz.Close
+}
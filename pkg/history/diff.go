@@ -0,0 +1,251 @@
+package history
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DiffOp represents the kind of change for a diffed line
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // Line present in both
+	DiffDelete               // Line only in the first log
+	DiffInsert               // Line only in the second log
+)
+
+// String returns the unified-diff prefix for the operation
+func (op DiffOp) String() string {
+	switch op {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// DiffLine is a single line of diff output
+type DiffLine struct {
+	Op    DiffOp
+	Text  string // Original (un-normalized) text
+	LineA int    // 1-based line number in the first log, 0 if not present
+	LineB int    // 1-based line number in the second log, 0 if not present
+}
+
+// DiffOptions controls how lines are normalized before comparison
+type DiffOptions struct {
+	IgnoreTimestamps bool // Strip leading [timestamp] prefixes
+	IgnoreWhitespace bool // Collapse runs of whitespace and trim
+	StripANSI        bool // Remove ANSI escape sequences
+}
+
+var (
+	timestampPrefixRegex = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2}[ T])?\d{2}:\d{2}:\d{2}(\.\d+)?\]\s*`)
+	ansiEscapeRegex      = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|[@-Z\\-_])`)
+	whitespaceRegex      = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeLine applies the diff options to a line
+func (o DiffOptions) NormalizeLine(line string) string {
+	if o.StripANSI {
+		line = ansiEscapeRegex.ReplaceAllString(line, "")
+	}
+	if o.IgnoreTimestamps {
+		line = timestampPrefixRegex.ReplaceAllString(line, "")
+	}
+	if o.IgnoreWhitespace {
+		line = strings.TrimSpace(whitespaceRegex.ReplaceAllString(line, " "))
+	}
+	return line
+}
+
+// DiffLines computes a line diff between two logs using the Myers algorithm
+func DiffLines(a, b []string, opts DiffOptions) []DiffLine {
+	// Normalize once up front
+	na := make([]string, len(a))
+	for i, line := range a {
+		na[i] = opts.NormalizeLine(line)
+	}
+	nb := make([]string, len(b))
+	for i, line := range b {
+		nb[i] = opts.NormalizeLine(line)
+	}
+
+	// Trim common prefix and suffix to keep the search space small
+	prefix := 0
+	for prefix < len(na) && prefix < len(nb) && na[prefix] == nb[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(na)-prefix && suffix < len(nb)-prefix &&
+		na[len(na)-1-suffix] == nb[len(nb)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]DiffLine, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		result = append(result, DiffLine{Op: DiffEqual, Text: a[i], LineA: i + 1, LineB: i + 1})
+	}
+
+	for _, op := range myersDiff(na[prefix:len(na)-suffix], nb[prefix:len(nb)-suffix]) {
+		switch op.op {
+		case DiffEqual:
+			result = append(result, DiffLine{Op: DiffEqual, Text: a[prefix+op.ai], LineA: prefix + op.ai + 1, LineB: prefix + op.bi + 1})
+		case DiffDelete:
+			result = append(result, DiffLine{Op: DiffDelete, Text: a[prefix+op.ai], LineA: prefix + op.ai + 1})
+		case DiffInsert:
+			result = append(result, DiffLine{Op: DiffInsert, Text: b[prefix+op.bi], LineB: prefix + op.bi + 1})
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		ai := len(a) - suffix + i
+		bi := len(b) - suffix + i
+		result = append(result, DiffLine{Op: DiffEqual, Text: a[ai], LineA: ai + 1, LineB: bi + 1})
+	}
+
+	return result
+}
+
+// diffOp is an edit step produced by myersDiff with indices into the inputs
+type diffOp struct {
+	op     DiffOp
+	ai, bi int
+}
+
+// myersDiff returns the shortest edit script between a and b. It uses the
+// linear space refinement of the Myers algorithm: the middle snake of the
+// edit path splits the inputs in two, and each half is diffed in turn, so
+// memory stays proportional to the input even for logs that differ on
+// every line.
+func myersDiff(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	return diffRange(a, b, 0, 0, ops)
+}
+
+// diffRange appends the edit script of a against b to ops, with ai and bi
+// the positions of a and b in the full inputs
+func diffRange(a, b []string, ai, bi int, ops []diffOp) []diffOp {
+	// Lines the ranges start or end with in common are equal
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{op: DiffEqual, ai: ai + prefix, bi: bi + prefix})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	ai, bi = ai+prefix, bi+prefix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+	n, m := len(a), len(b)
+
+	switch {
+	case n == 0:
+		for y := range b {
+			ops = append(ops, diffOp{op: DiffInsert, ai: ai, bi: bi + y})
+		}
+	case m == 0:
+		for x := range a {
+			ops = append(ops, diffOp{op: DiffDelete, ai: ai + x, bi: bi})
+		}
+	default:
+		d, x, y, u, v := middleSnake(a, b)
+		if d > 1 {
+			ops = diffRange(a[:x], b[:y], ai, bi, ops)
+			for i := 0; i < u-x; i++ {
+				ops = append(ops, diffOp{op: DiffEqual, ai: ai + x + i, bi: bi + y + i})
+			}
+			ops = diffRange(a[u:], b[v:], ai+u, bi+v, ops)
+			break
+		}
+		// One edit with the first lines differing: it is the first line of
+		// the longer range, and the rest are equal
+		if n > m {
+			ops = append(ops, diffOp{op: DiffDelete, ai: ai, bi: bi})
+			ai++
+		} else {
+			ops = append(ops, diffOp{op: DiffInsert, ai: ai, bi: bi})
+			bi++
+		}
+		for i := 0; i < min(n, m); i++ {
+			ops = append(ops, diffOp{op: DiffEqual, ai: ai + i, bi: bi + i})
+		}
+		ai, bi = ai+min(n, m), bi+min(n, m)
+		n, m = 0, 0
+	}
+
+	ai, bi = ai+n, bi+m
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, diffOp{op: DiffEqual, ai: ai + i, bi: bi + i})
+	}
+	return ops
+}
+
+// middleSnake finds the snake in the middle of a shortest edit path between
+// a and b, searching from both ends at once. It returns the length of the
+// path and the snake's start (x, y) and end (u, v).
+func middleSnake(a, b []string) (d, x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	// Furthest x reached on each diagonal, from the start and from the end
+	forward := make([]int, 2*maxD+3)
+	backward := make([]int, 2*maxD+3)
+
+	for step := 0; step <= maxD; step++ {
+		for k := -step; k <= step; k += 2 {
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			// Diagonal k from the start is delta-k from the end
+			if kb := delta - k; odd && kb >= -(step-1) && kb <= step-1 && u+backward[offset+kb] >= n {
+				return 2*step - 1, x, y, u, v
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var bx int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				bx = backward[offset+k+1]
+			} else {
+				bx = backward[offset+k-1] + 1
+			}
+			by := bx - k
+			sx, sy := bx, by
+			for bx < n && by < m && a[n-1-bx] == b[m-1-by] {
+				bx++
+				by++
+			}
+			backward[offset+k] = bx
+			if kf := delta - k; !odd && kf >= -step && kf <= step && bx+forward[offset+kf] >= n {
+				return 2 * step, n - bx, m - by, n - sx, m - sy
+			}
+		}
+	}
+	return n + m, 0, 0, 0, 0 // Not reached: the searches always meet
+}
+
+// DiffHasChanges reports whether the diff contains any insertions or deletions
+func DiffHasChanges(diff []DiffLine) bool {
+	for _, line := range diff {
+		if line.Op != DiffEqual {
+			return true
+		}
+	}
+	return false
+}
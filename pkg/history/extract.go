@@ -0,0 +1,191 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// extractTimeFormat is how row times are written
+const extractTimeFormat = "2006-01-02 15:04:05.000"
+
+// FieldExtractor pulls the capture groups of a regular expression out of
+// received lines, for exporting measurement logs to spreadsheets
+type FieldExtractor struct {
+	regex   *regexp.Regexp
+	columns []string
+}
+
+// ExtractedRow holds the fields of one matching line
+type ExtractedRow struct {
+	Time   time.Time // When the line was received; zero if unknown
+	Line   int       // 1-based line number
+	Fields []string  // One per column, empty for groups that didn't take part
+}
+
+// NewFieldExtractor compiles a pattern with at least one capture group.
+// Named groups give their column names; others are called field1, field2
+// and so on by position.
+func NewFieldExtractor(pattern string) (*FieldExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("pattern needs a capture group such as (\\d+)")
+	}
+
+	columns := make([]string, re.NumSubexp())
+	for i, name := range re.SubexpNames()[1:] {
+		if name == "" {
+			name = "field" + strconv.Itoa(i+1)
+		}
+		columns[i] = name
+	}
+	return &FieldExtractor{regex: re, columns: columns}, nil
+}
+
+// Columns returns the field names, in group order
+func (e *FieldExtractor) Columns() []string {
+	return e.columns
+}
+
+// match returns the row for a line, or false if it doesn't match
+func (e *FieldExtractor) match(line string, number int, t time.Time) (ExtractedRow, bool) {
+	m := e.regex.FindStringSubmatch(line)
+	if m == nil {
+		return ExtractedRow{}, false
+	}
+	return ExtractedRow{Time: t, Line: number, Fields: m[1:]}, true
+}
+
+// ExtractLines matches lines read from a saved log. A leading [timestamp]
+// such as the one timestamped logs add gives the row's time and isn't
+// matched against.
+func (e *FieldExtractor) ExtractLines(lines []string) []ExtractedRow {
+	var rows []ExtractedRow
+	for i, line := range lines {
+		line = ansiEscapeRegex.ReplaceAllString(line, "")
+		var t time.Time
+		if prefix := timestampPrefixRegex.FindString(line); prefix != "" {
+			t = parseLogTimestamp(strings.TrimSpace(prefix))
+			line = line[len(prefix):]
+		}
+		if row, ok := e.match(line, i+1, t); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// ExtractEntries matches the lines of the received data in history
+// entries. Each line gets the time of the entry that completed it; sent
+// data is skipped.
+func (e *FieldExtractor) ExtractEntries(entries []HistoryEntry) []ExtractedRow {
+	var rows []ExtractedRow
+	var line strings.Builder
+	number := 0
+	flush := func(t time.Time) {
+		number++
+		text := ansiEscapeRegex.ReplaceAllString(line.String(), "")
+		if row, ok := e.match(text, number, t); ok {
+			rows = append(rows, row)
+		}
+		line.Reset()
+	}
+
+	var last time.Time
+	for _, entry := range entries {
+		if entry.Direction != DirectionOutput {
+			continue
+		}
+		last = entry.Timestamp
+		for _, b := range entry.Data {
+			switch b {
+			case '\n':
+				flush(entry.Timestamp)
+			case '\r':
+			default:
+				line.WriteByte(b)
+			}
+		}
+	}
+	if line.Len() > 0 {
+		flush(last)
+	}
+	return rows
+}
+
+// WriteCSV writes the rows with a header line: time, line and the columns
+func (e *FieldExtractor) WriteCSV(w io.Writer, rows []ExtractedRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"time", "line"}, e.columns...)); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, row := range rows {
+		record := append([]string{formatRowTime(row.Time), strconv.Itoa(row.Line)}, row.Fields...)
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the rows as an array of objects keyed by column, with
+// "time" and "line" added. Fields that look like finite numbers are written
+// as numbers; "nan" and "inf", which JSON has no numbers for, stay strings.
+func (e *FieldExtractor) WriteJSON(w io.Writer, rows []ExtractedRow) error {
+	objects := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		object := map[string]any{"line": row.Line}
+		if !row.Time.IsZero() {
+			object["time"] = formatRowTime(row.Time)
+		}
+		for j, field := range row.Fields {
+			if number, err := strconv.ParseFloat(field, 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+				object[e.columns[j]] = number
+			} else {
+				object[e.columns[j]] = field
+			}
+		}
+		objects[i] = object
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(objects); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
+
+// formatRowTime formats a row time, empty when unknown and without a date
+// for logs that only had the time of day
+func formatRowTime(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case t.Year() == 0:
+		return t.Format(extractTimeFormat[len("2006-01-02 "):])
+	}
+	return t.Format(extractTimeFormat)
+}
+
+// parseLogTimestamp reads a "[2006-01-02 15:04:05.000]" or "[15:04:05]"
+// prefix, returning the zero time for other forms
+func parseLogTimestamp(prefix string) time.Time {
+	value := strings.Trim(prefix, "[]")
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
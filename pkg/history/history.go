@@ -2,10 +2,14 @@
 package history
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"iter"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -64,6 +68,8 @@ type HistoryManager interface {
 	SetMaxSize(size int) error
 	GetMaxSize() int
 	GetEntries(start, count int) ([]HistoryEntry, error)
+	SearchEntries(pattern string, direction Direction, timeRange TimeRange) ([]SearchResult, error)
+	GetEntriesInRange(timeRange TimeRange) ([]HistoryEntry, error)
 }
 
 // HistoryEntry represents a single entry in the communication history
@@ -72,6 +78,9 @@ type HistoryEntry struct {
 	Direction Direction `json:"direction"`
 	Data      []byte    `json:"data"`
 	Length    int       `json:"length"`
+
+	// Chunks are the reads the data arrived in, when kept (see TimedWriter)
+	Chunks []ChunkTime `json:"chunks,omitempty"`
 }
 
 // Validate checks if the history entry is valid
@@ -123,12 +132,14 @@ type HistoryStats struct {
 
 // RingBufferHistoryManager implements HistoryManager using a ring buffer
 type RingBufferHistoryManager struct {
+	historyObserver
 	buffer     []byte
 	maxSize    int
 	writePos   int
 	readPos    int
 	size       int
 	entries    []HistoryEntry
+	masks      []uint64 // Search index, parallel to entries
 	maxEntries int
 	entryCount int
 	entryStart int
@@ -150,6 +161,7 @@ func NewRingBufferHistoryManager(maxSize int) *RingBufferHistoryManager {
 		maxSize:    maxSize,
 		maxEntries: maxEntries,
 		entries:    make([]HistoryEntry, maxEntries),
+		masks:      make([]uint64, maxEntries),
 		writePos:   0,
 		readPos:    0,
 		size:       0,
@@ -160,6 +172,14 @@ func NewRingBufferHistoryManager(maxSize int) *RingBufferHistoryManager {
 
 // Write adds data to the history buffer
 func (rbhm *RingBufferHistoryManager) Write(data []byte, direction Direction) error {
+	return rbhm.WriteTimed(data, direction, nil)
+}
+
+// WriteTimed adds data to the history buffer with the times of its reads
+func (rbhm *RingBufferHistoryManager) WriteTimed(data []byte, direction Direction, chunks []ChunkTime) error {
+	rbhm.mu.Lock()
+	defer rbhm.unlock()
+
 	if data == nil {
 		return fmt.Errorf("data cannot be nil")
 	}
@@ -169,10 +189,19 @@ func (rbhm *RingBufferHistoryManager) Write(data []byte, direction Direction) er
 	}
 
 	// Create history entry
-	entry := NewHistoryEntry(data, direction)
+	entry, err := newTimedEntry(data, direction, chunks)
+	if err != nil {
+		return err
+	}
+
+	// Report the oldest entry before it is overwritten
+	if rbhm.entryCount == rbhm.maxEntries {
+		rbhm.notifyEvicted([]HistoryEntry{rbhm.entries[rbhm.entryStart]})
+	}
 
 	// Add entry to entries ring buffer
 	rbhm.entries[rbhm.entryStart] = entry
+	rbhm.masks[rbhm.entryStart] = bigramMask(entry.Data)
 	rbhm.entryStart = (rbhm.entryStart + 1) % rbhm.maxEntries
 
 	if rbhm.entryCount < rbhm.maxEntries {
@@ -214,11 +243,16 @@ func (rbhm *RingBufferHistoryManager) Write(data []byte, direction Direction) er
 		}
 	}
 
+	rbhm.notifyWritten(entry)
+	rbhm.checkWatermarks(rbhm.size, rbhm.maxSize)
 	return nil
 }
 
 // Read reads data from the history buffer
 func (rbhm *RingBufferHistoryManager) Read(offset, length int) ([]byte, error) {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+
 	if offset < 0 {
 		return nil, fmt.Errorf("offset cannot be negative")
 	}
@@ -248,11 +282,15 @@ func (rbhm *RingBufferHistoryManager) Read(offset, length int) ([]byte, error) {
 
 // GetSize returns the current size of data in the buffer
 func (rbhm *RingBufferHistoryManager) GetSize() int {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
 	return rbhm.size
 }
 
 // GetEntryCount returns the number of entries in the history
 func (rbhm *RingBufferHistoryManager) GetEntryCount() int {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
 	return rbhm.entryCount
 }
 
@@ -262,16 +300,16 @@ func (rbhm *RingBufferHistoryManager) SaveToFile(filename string, format FileFor
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	entries, err := rbhm.GetEntries(0, rbhm.entryCount)
-	if err != nil {
-		return fmt.Errorf("failed to get entries: %w", err)
-	}
-
-	return saveEntriesToFile(entries, filename, format)
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+	return saveSeqToFile(allEntries(rbhm, nil), filename, format)
 }
 
 // Clear clears all data from the history buffer
 func (rbhm *RingBufferHistoryManager) Clear() error {
+	rbhm.mu.Lock()
+	defer rbhm.unlock()
+
 	rbhm.writePos = 0
 	rbhm.readPos = 0
 	rbhm.size = 0
@@ -285,6 +323,7 @@ func (rbhm *RingBufferHistoryManager) Clear() error {
 
 	for i := range rbhm.entries {
 		rbhm.entries[i] = HistoryEntry{}
+		rbhm.masks[i] = 0
 	}
 
 	return nil
@@ -296,6 +335,9 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 		return fmt.Errorf("size must be positive")
 	}
 
+	rbhm.mu.Lock()
+	defer rbhm.unlock()
+
 	if size == rbhm.maxSize {
 		return nil // No change needed
 	}
@@ -307,6 +349,7 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 		newMaxEntries = 1000
 	}
 	newEntries := make([]HistoryEntry, newMaxEntries)
+	newMasks := make([]uint64, newMaxEntries)
 
 	// Copy existing data if new buffer is larger
 	if size > rbhm.maxSize {
@@ -325,6 +368,7 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 		for i := 0; i < copyCount; i++ {
 			entryPos := (rbhm.entryStart - rbhm.entryCount + i + rbhm.maxEntries) % rbhm.maxEntries
 			newEntries[i] = rbhm.entries[entryPos]
+			newMasks[i] = rbhm.masks[entryPos]
 		}
 
 		rbhm.readPos = 0
@@ -352,9 +396,14 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 		}
 
 		startEntry := rbhm.entryCount - copyCount
+		if startEntry > 0 {
+			dropped, _ := rbhm.getEntries(0, startEntry)
+			rbhm.notifyEvicted(dropped)
+		}
 		for i := 0; i < copyCount; i++ {
 			entryPos := (rbhm.entryStart - rbhm.entryCount + startEntry + i + rbhm.maxEntries) % rbhm.maxEntries
 			newEntries[i] = rbhm.entries[entryPos]
+			newMasks[i] = rbhm.masks[entryPos]
 		}
 
 		rbhm.readPos = 0
@@ -367,6 +416,7 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 	rbhm.buffer = newBuffer
 	rbhm.maxSize = size
 	rbhm.entries = newEntries
+	rbhm.masks = newMasks
 	rbhm.maxEntries = newMaxEntries
 
 	return nil
@@ -374,11 +424,20 @@ func (rbhm *RingBufferHistoryManager) SetMaxSize(size int) error {
 
 // GetMaxSize returns the maximum size of the buffer
 func (rbhm *RingBufferHistoryManager) GetMaxSize() int {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
 	return rbhm.maxSize
 }
 
 // GetEntries returns a slice of history entries
 func (rbhm *RingBufferHistoryManager) GetEntries(start, count int) ([]HistoryEntry, error) {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+	return rbhm.getEntries(start, count)
+}
+
+// getEntries is GetEntries with mu held
+func (rbhm *RingBufferHistoryManager) getEntries(start, count int) ([]HistoryEntry, error) {
 	if start < 0 {
 		return nil, fmt.Errorf("start cannot be negative")
 	}
@@ -406,8 +465,47 @@ func (rbhm *RingBufferHistoryManager) GetEntries(start, count int) ([]HistoryEnt
 	return result, nil
 }
 
+// SearchEntries finds entries whose data matches the regex pattern, optionally
+// restricted by direction (DirectionAny for both) and time range. Matches that
+// span two entries are not found.
+func (rbhm *RingBufferHistoryManager) SearchEntries(pattern string, direction Direction, timeRange TimeRange) ([]SearchResult, error) {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+	return searchEntries(rbhm, pattern, direction, timeRange)
+}
+
+// GetEntriesInRange returns all entries inside the time range
+func (rbhm *RingBufferHistoryManager) GetEntriesInRange(timeRange TimeRange) ([]HistoryEntry, error) {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+	return entriesInRange(rbhm, timeRange)
+}
+
+// entryLen implements entrySource
+func (rbhm *RingBufferHistoryManager) entryLen() int {
+	return rbhm.entryCount
+}
+
+// entryAt implements entrySource, i is the logical index (0 = oldest)
+func (rbhm *RingBufferHistoryManager) entryAt(i int) (HistoryEntry, error) {
+	return rbhm.metaAt(i), nil
+}
+
+// metaAt implements entrySource
+func (rbhm *RingBufferHistoryManager) metaAt(i int) HistoryEntry {
+	return rbhm.entries[(rbhm.entryStart-rbhm.entryCount+i+rbhm.maxEntries)%rbhm.maxEntries]
+}
+
+// maskAt implements entrySource
+func (rbhm *RingBufferHistoryManager) maskAt(i int) uint64 {
+	return rbhm.masks[(rbhm.entryStart-rbhm.entryCount+i+rbhm.maxEntries)%rbhm.maxEntries]
+}
+
 // GetStats returns statistics about the history buffer
 func (rbhm *RingBufferHistoryManager) GetStats() HistoryStats {
+	rbhm.mu.RLock()
+	defer rbhm.mu.RUnlock()
+
 	stats := HistoryStats{
 		TotalEntries: rbhm.entryCount,
 		TotalBytes:   rbhm.size,
@@ -441,82 +539,171 @@ func (rbhm *RingBufferHistoryManager) GetStats() HistoryStats {
 	return stats
 }
 
-// saveEntriesToFile saves history entries to a file in the specified format
+// saveEntriesToFile saves history entries to a file in the specified format,
+// gzipped if the filename ends in .gz
 func saveEntriesToFile(entries []HistoryEntry, filename string, format FileFormat) error {
+	return saveSeqToFile(slices.Values(entries), filename, format)
+}
+
+// saveSeqToFile saves entries to a file as they are produced, so a history
+// that compresses its data never has all of it decompressed at once
+func saveSeqToFile(entries iter.Seq[HistoryEntry], filename string, format FileFormat) error {
+	if format != FormatPlainText && format != FormatTimestamped && format != FormatJSON {
+		return fmt.Errorf("unsupported format: %v", format)
+	}
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
+	w, closeWriter := compressedWriter(file, filename)
+	buffered := bufio.NewWriterSize(w, 64*1024)
+	if format == FormatJSON {
+		err = saveAsJSON(buffered, entries)
+	} else {
+		err = writeEntries(buffered, entries, format)
+	}
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := closeWriter(); err != nil {
+		return fmt.Errorf("failed to compress file: %w", err)
+	}
+	return nil
+}
+
+// AppendEntriesToFile appends history entries to a file, creating it if needed.
+// JSON entries are written one object per line so the file stays appendable.
+// A filename ending in .gz gets each append as another gzip stream, which
+// ReadHistoryFile and gunzip read as one.
+func AppendEntriesToFile(entries []HistoryEntry, filename string, format FileFormat) error {
+	stream, err := OpenStream(filename, format)
+	if err != nil {
+		return err
+	}
+	if err := stream.Write(entries...); err != nil {
+		stream.Close()
+		return err
+	}
+	return stream.Close()
+}
+
+// writeEntries writes entries in an appendable format: plain text,
+// timestamped lines, or JSON with one entry per line
+func writeEntries(w io.Writer, entries iter.Seq[HistoryEntry], format FileFormat) error {
 	switch format {
 	case FormatPlainText:
-		return saveAsPlainText(file, entries)
+		return saveAsPlainText(w, entries)
 	case FormatTimestamped:
-		return saveAsTimestamped(file, entries)
+		return saveAsTimestamped(w, entries)
 	case FormatJSON:
-		return saveAsJSON(file, entries)
+		encoder := json.NewEncoder(w)
+		for entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("failed to encode entry: %w", err)
+			}
+		}
+		return nil
 	default:
 		return fmt.Errorf("unsupported format: %v", format)
 	}
 }
 
 // saveAsPlainText saves entries as plain text
-func saveAsPlainText(file *os.File, entries []HistoryEntry) error {
-	for _, entry := range entries {
-		if _, err := file.Write(entry.Data); err != nil {
+func saveAsPlainText(w io.Writer, entries iter.Seq[HistoryEntry]) error {
+	for entry := range entries {
+		if _, err := w.Write(entry.Data); err != nil {
 			return fmt.Errorf("failed to write data: %w", err)
 		}
 	}
 	return nil
 }
 
-// saveAsTimestamped saves entries with timestamps
-func saveAsTimestamped(file *os.File, entries []HistoryEntry) error {
-	for _, entry := range entries {
+// saveAsTimestamped saves entries with timestamps, a line per read where
+// read times were kept
+func saveAsTimestamped(w io.Writer, entries iter.Seq[HistoryEntry]) error {
+	for entry := range entries {
 		direction := "<<"
 		if entry.Direction == DirectionOutput {
 			direction = ">>"
 		}
 
-		line := fmt.Sprintf("[%s] %s %s\n",
-			entry.Timestamp.Format("2006-01-02 15:04:05.000"),
-			direction,
-			strings.ReplaceAll(string(entry.Data), "\n", "\\n"))
-
-		if _, err := file.WriteString(line); err != nil {
-			return fmt.Errorf("failed to write timestamped data: %w", err)
+		// Read times need more precision than entries to be of use
+		layout := "2006-01-02 15:04:05.000"
+		if len(entry.Chunks) > 0 {
+			layout = "2006-01-02 15:04:05.000000"
+		}
+		for _, piece := range entry.Pieces() {
+			line := fmt.Sprintf("[%s] %s %s\n",
+				piece.Time.Format(layout),
+				direction,
+				strings.ReplaceAll(string(piece.Data), "\n", "\\n"))
+
+			if _, err := io.WriteString(w, line); err != nil {
+				return fmt.Errorf("failed to write timestamped data: %w", err)
+			}
 		}
 	}
 	return nil
 }
 
-// saveAsJSON saves entries as JSON
-func saveAsJSON(file *os.File, entries []HistoryEntry) error {
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-
-	data := struct {
-		Entries []HistoryEntry `json:"entries"`
-		Count   int            `json:"count"`
-	}{
-		Entries: entries,
-		Count:   len(entries),
+// saveAsJSON saves entries as a JSON document, written an entry at a time
+// rather than built in memory
+func saveAsJSON(w io.Writer, entries iter.Seq[HistoryEntry]) error {
+	count := 0
+	if _, err := io.WriteString(w, "{\n  \"entries\": ["); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	for entry := range entries {
+		data, err := json.MarshalIndent(entry, "    ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		separator := "\n    "
+		if count > 0 {
+			separator = ",\n    "
+		}
+		if _, err := io.WriteString(w, separator); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		count++
 	}
 
-	if err := encoder.Encode(data); err != nil {
+	closing := "]"
+	if count > 0 {
+		closing = "\n  ]"
+	}
+	if _, err := fmt.Fprintf(w, "%s,\n  \"count\": %d\n}\n", closing, count); err != nil {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
-
 	return nil
 }
 
 // MemoryHistoryManager implements HistoryManager using simple in-memory storage
 // This is a simpler alternative to RingBufferHistoryManager for smaller datasets
 type MemoryHistoryManager struct {
+	historyObserver
 	entries    []HistoryEntry
+	masks      []uint64 // Search index, parallel to entries
 	maxSize    int
 	maxEntries int
+
+	// The oldest packedCount entries have their data compressed, with Data
+	// nil and refs saying where it is; the rest are live
+	refs        []packedRef // Parallel to entries
+	packedCount int
+	packedCost  int // Compressed bytes of the packed entries
+	liveSize    int // Data bytes of the live entries
+	compress    bool
+	liveWindow  int
+	cache       segmentCache
 }
 
 // NewMemoryHistoryManager creates a new memory-based history manager
@@ -527,6 +714,7 @@ func NewMemoryHistoryManager(maxSize int) *MemoryHistoryManager {
 
 	return &MemoryHistoryManager{
 		entries:    make([]HistoryEntry, 0),
+		masks:      make([]uint64, 0),
 		maxSize:    maxSize,
 		maxEntries: maxSize / 10, // Estimate 10 bytes per entry
 	}
@@ -534,6 +722,14 @@ func NewMemoryHistoryManager(maxSize int) *MemoryHistoryManager {
 
 // Write adds data to the memory history
 func (mhm *MemoryHistoryManager) Write(data []byte, direction Direction) error {
+	return mhm.WriteTimed(data, direction, nil)
+}
+
+// WriteTimed adds data to the memory history with the times of its reads
+func (mhm *MemoryHistoryManager) WriteTimed(data []byte, direction Direction, chunks []ChunkTime) error {
+	mhm.mu.Lock()
+	defer mhm.unlock()
+
 	if data == nil {
 		return fmt.Errorf("data cannot be nil")
 	}
@@ -542,30 +738,51 @@ func (mhm *MemoryHistoryManager) Write(data []byte, direction Direction) error {
 		return fmt.Errorf("invalid direction: %d", direction)
 	}
 
-	entry := NewHistoryEntry(data, direction)
+	entry, err := newTimedEntry(data, direction, chunks)
+	if err != nil {
+		return err
+	}
 
 	// Check if we need to remove old entries
 	currentSize := mhm.calculateTotalSize()
-	for currentSize+len(data) > mhm.maxSize && len(mhm.entries) > 0 {
+	removeCount := 0
+	for currentSize+len(data) > mhm.maxSize && removeCount < len(mhm.entries) {
 		// Remove oldest entry
-		removed := mhm.entries[0]
-		mhm.entries = mhm.entries[1:]
-		currentSize -= len(removed.Data)
+		currentSize -= mhm.entrySize(removeCount)
+		removeCount++
 	}
 
 	// Check entry count limit
-	if len(mhm.entries) >= mhm.maxEntries {
+	if len(mhm.entries)-removeCount >= mhm.maxEntries {
 		// Remove oldest entries to make room
-		removeCount := len(mhm.entries) - mhm.maxEntries + 1
-		mhm.entries = mhm.entries[removeCount:]
+		removeCount = len(mhm.entries) - mhm.maxEntries + 1
+		if removeCount > len(mhm.entries) {
+			removeCount = len(mhm.entries)
+		}
+		currentSize = 0
+		for i := removeCount; i < len(mhm.entries); i++ {
+			currentSize += mhm.entrySize(i)
+		}
 	}
 
+	mhm.evict(removeCount)
+
 	mhm.entries = append(mhm.entries, entry)
+	mhm.masks = append(mhm.masks, bigramMask(entry.Data))
+	mhm.refs = append(mhm.refs, packedRef{})
+	mhm.liveSize += len(data)
+	mhm.notifyWritten(entry)
+	mhm.compressOld()
+
+	mhm.checkWatermarks(mhm.calculateTotalSize(), mhm.maxSize)
 	return nil
 }
 
 // Read reads data from the memory history
 func (mhm *MemoryHistoryManager) Read(offset, length int) ([]byte, error) {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+
 	if offset < 0 {
 		return nil, fmt.Errorf("offset cannot be negative")
 	}
@@ -574,31 +791,39 @@ func (mhm *MemoryHistoryManager) Read(offset, length int) ([]byte, error) {
 		return nil, fmt.Errorf("length cannot be negative")
 	}
 
-	// Concatenate all data
-	var allData []byte
-	for _, entry := range mhm.entries {
-		allData = append(allData, entry.Data...)
-	}
-
-	if offset >= len(allData) {
-		return []byte{}, nil
-	}
-
-	end := offset + length
-	if end > len(allData) {
-		end = len(allData)
+	// Concatenate the data of the entries in range, skipping those before
+	// it without decompressing them
+	result := []byte{}
+	start := 0
+	for i := range mhm.entries {
+		if len(result) >= length {
+			break
+		}
+		size := mhm.dataLen(i)
+		if start+size > offset {
+			entry, err := mhm.entryAt(i)
+			if err != nil {
+				return nil, err
+			}
+			data := entry.Data[max(offset-start, 0):]
+			result = append(result, data[:min(len(data), length-len(result))]...)
+		}
+		start += size
 	}
-
-	return allData[offset:end], nil
+	return result, nil
 }
 
 // GetSize returns the total size of data in memory
 func (mhm *MemoryHistoryManager) GetSize() int {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
 	return mhm.calculateTotalSize()
 }
 
 // GetEntryCount returns the number of entries
 func (mhm *MemoryHistoryManager) GetEntryCount() int {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
 	return len(mhm.entries)
 }
 
@@ -608,12 +833,24 @@ func (mhm *MemoryHistoryManager) SaveToFile(filename string, format FileFormat)
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	return saveEntriesToFile(mhm.entries, filename, format)
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+	var readErr error
+	if err := saveSeqToFile(allEntries(mhm, &readErr), filename, format); err != nil {
+		return err
+	}
+	// Saved without the entries that couldn't be read
+	return readErr
 }
 
 // Clear clears all entries
 func (mhm *MemoryHistoryManager) Clear() error {
+	mhm.mu.Lock()
+	defer mhm.unlock()
 	mhm.entries = mhm.entries[:0]
+	mhm.masks = mhm.masks[:0]
+	mhm.refs = mhm.refs[:0]
+	mhm.packedCount, mhm.packedCost, mhm.liveSize = 0, 0, 0
 	return nil
 }
 
@@ -623,22 +860,29 @@ func (mhm *MemoryHistoryManager) SetMaxSize(size int) error {
 		return fmt.Errorf("size must be positive")
 	}
 
+	mhm.mu.Lock()
+	defer mhm.unlock()
+
 	mhm.maxSize = size
 	mhm.maxEntries = size / 10
 
 	// Remove entries if current size exceeds new limit
 	currentSize := mhm.calculateTotalSize()
-	for currentSize > size && len(mhm.entries) > 0 {
-		removed := mhm.entries[0]
-		mhm.entries = mhm.entries[1:]
-		currentSize -= len(removed.Data)
+	removeCount := 0
+	for currentSize > size && removeCount < len(mhm.entries) {
+		currentSize -= mhm.entrySize(removeCount)
+		removeCount++
 	}
+	mhm.evict(removeCount)
 
+	mhm.checkWatermarks(currentSize, mhm.maxSize)
 	return nil
 }
 
 // GetMaxSize returns the maximum size
 func (mhm *MemoryHistoryManager) GetMaxSize() int {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
 	return mhm.maxSize
 }
 
@@ -652,6 +896,9 @@ func (mhm *MemoryHistoryManager) GetEntries(start, count int) ([]HistoryEntry, e
 		return nil, fmt.Errorf("count cannot be negative")
 	}
 
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+
 	if start >= len(mhm.entries) {
 		return []HistoryEntry{}, nil
 	}
@@ -662,19 +909,63 @@ func (mhm *MemoryHistoryManager) GetEntries(start, count int) ([]HistoryEntry, e
 	}
 
 	// Return a copy of the entries
-	result := make([]HistoryEntry, end-start)
-	copy(result, mhm.entries[start:end])
+	entries, err := mhm.materialize(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
 
-	return result, nil
+// SearchEntries finds entries whose data matches the regex pattern, optionally
+// restricted by direction (DirectionAny for both) and time range. Matches that
+// span two entries are not found.
+func (mhm *MemoryHistoryManager) SearchEntries(pattern string, direction Direction, timeRange TimeRange) ([]SearchResult, error) {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+	return searchEntries(mhm, pattern, direction, timeRange)
 }
 
-// calculateTotalSize calculates the total size of all data
-func (mhm *MemoryHistoryManager) calculateTotalSize() int {
-	total := 0
-	for _, entry := range mhm.entries {
-		total += len(entry.Data)
+// GetEntriesInRange returns all entries inside the time range
+func (mhm *MemoryHistoryManager) GetEntriesInRange(timeRange TimeRange) ([]HistoryEntry, error) {
+	mhm.mu.RLock()
+	defer mhm.mu.RUnlock()
+	return entriesInRange(mhm, timeRange)
+}
+
+// entryLen implements entrySource
+func (mhm *MemoryHistoryManager) entryLen() int {
+	return len(mhm.entries)
+}
+
+// entryAt implements entrySource, decompressing the entry's data if needed
+func (mhm *MemoryHistoryManager) entryAt(i int) (HistoryEntry, error) {
+	entry := mhm.entries[i]
+	if i < mhm.packedCount {
+		ref := mhm.refs[i]
+		data, err := mhm.cache.get(ref.seg)
+		if err != nil {
+			return entry, err
+		}
+		end := ref.offset + ref.length
+		entry.Data = data[ref.offset:end:end]
 	}
-	return total
+	return entry, nil
+}
+
+// metaAt implements entrySource; the data of compressed entries is left out
+func (mhm *MemoryHistoryManager) metaAt(i int) HistoryEntry {
+	return mhm.entries[i]
+}
+
+// maskAt implements entrySource
+func (mhm *MemoryHistoryManager) maskAt(i int) uint64 {
+	return mhm.masks[i]
+}
+
+// calculateTotalSize calculates the total size of all data, compressed
+// data counting at its compressed size
+func (mhm *MemoryHistoryManager) calculateTotalSize() int {
+	return mhm.packedCost + mhm.liveSize
 }
 
 // PersistentHistoryManager extends HistoryManager with automatic persistence features
@@ -710,7 +1001,18 @@ func (phm *PersistentHistoryManager) SetAutoBackup(enabled bool, interval time.D
 
 // Write wraps the base Write method and triggers backup if needed
 func (phm *PersistentHistoryManager) Write(data []byte, direction Direction) error {
-	err := phm.HistoryManager.Write(data, direction)
+	return phm.WriteTimed(data, direction, nil)
+}
+
+// WriteTimed keeps the read times if the base manager can, and triggers
+// backup if needed
+func (phm *PersistentHistoryManager) WriteTimed(data []byte, direction Direction, chunks []ChunkTime) error {
+	var err error
+	if timed, ok := phm.HistoryManager.(TimedWriter); ok && len(chunks) > 0 {
+		err = timed.WriteTimed(data, direction, chunks)
+	} else {
+		err = phm.HistoryManager.Write(data, direction)
+	}
 	if err != nil {
 		return err
 	}
@@ -815,7 +1117,7 @@ func (phm *PersistentHistoryManager) SaveToTempFile(format FileFormat) (string,
 
 // LoadFromFile loads history from a file (for restoration)
 func (phm *PersistentHistoryManager) LoadFromFile(filename string) error {
-	data, err := os.ReadFile(filename)
+	data, err := ReadHistoryFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
@@ -1,11 +1,16 @@
 package history
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -998,3 +1003,1011 @@ func TestPersistentHistoryManager_CreateTempFile(t *testing.T) {
 		t.Errorf("Temp file name should have prefix 'history_temp_', got: %s", filename)
 	}
 }
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		opts DiffOptions
+		want string // Concatenated op prefixes
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, DiffOptions{}, "  "},
+		{"empty", nil, nil, DiffOptions{}, ""},
+		{"all inserted", nil, []string{"a", "b"}, DiffOptions{}, "++"},
+		{"all deleted", []string{"a", "b"}, nil, DiffOptions{}, "--"},
+		{"changed middle", []string{"a", "b", "c"}, []string{"a", "x", "c"}, DiffOptions{}, " -+ "},
+		{"inserted line", []string{"a", "c"}, []string{"a", "b", "c"}, DiffOptions{}, " + "},
+		{
+			"timestamps ignored",
+			[]string{"[2024-01-01 10:00:00.000] boot", "[10:00:01.5] ready"},
+			[]string{"[2024-02-01 11:00:00.000] boot", "[11:00:09.1] ready"},
+			DiffOptions{IgnoreTimestamps: true},
+			"  ",
+		},
+		{"ansi stripped", []string{"\x1b[32mOK\x1b[0m"}, []string{"OK"}, DiffOptions{StripANSI: true}, " "},
+		{"whitespace ignored", []string{"a  b "}, []string{"a b"}, DiffOptions{IgnoreWhitespace: true}, " "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffLines(tt.a, tt.b, tt.opts)
+			var ops strings.Builder
+			for _, line := range diff {
+				ops.WriteString(line.Op.String())
+			}
+			if ops.String() != tt.want {
+				t.Errorf("DiffLines() ops = %q, want %q", ops.String(), tt.want)
+			}
+			if DiffHasChanges(diff) != strings.ContainsAny(tt.want, "+-") {
+				t.Errorf("DiffHasChanges() = %v", DiffHasChanges(diff))
+			}
+		})
+	}
+}
+
+func TestDiffLines_LineNumbers(t *testing.T) {
+	diff := DiffLines([]string{"a", "b", "c"}, []string{"a", "c", "d"}, DiffOptions{})
+
+	// Expect: " a", "-b", " c", "+d"
+	if len(diff) != 4 {
+		t.Fatalf("len(diff) = %d, want 4", len(diff))
+	}
+	if diff[1].Op != DiffDelete || diff[1].LineA != 2 || diff[1].LineB != 0 {
+		t.Errorf("Unexpected delete line: %+v", diff[1])
+	}
+	if diff[2].Op != DiffEqual || diff[2].LineA != 3 || diff[2].LineB != 2 {
+		t.Errorf("Unexpected equal line: %+v", diff[2])
+	}
+	if diff[3].Op != DiffInsert || diff[3].Text != "d" || diff[3].LineB != 3 {
+		t.Errorf("Unexpected insert line: %+v", diff[3])
+	}
+}
+
+func TestDiffLines_Shortest(t *testing.T) {
+	// The script is a shortest one and rebuilds both inputs
+	lcs := func(a, b []string) int {
+		dp := make([][]int, len(a)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					dp[i][j] = dp[i+1][j+1] + 1
+				} else {
+					dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+				}
+			}
+		}
+		return dp[0][0]
+	}
+	seed := uint32(1)
+	random := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			seed = seed*1664525 + 1013904223
+			lines[i] = string(rune('a' + seed>>29))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(int(seed>>27)%12), random(int(seed>>26)%12)
+		var gotA, gotB []string
+		edits := 0
+		for _, line := range DiffLines(a, b, DiffOptions{}) {
+			if line.LineA > 0 {
+				gotA = append(gotA, a[line.LineA-1])
+			}
+			if line.LineB > 0 {
+				gotB = append(gotB, b[line.LineB-1])
+			}
+			if line.Op != DiffEqual {
+				edits++
+			}
+		}
+		if fmt.Sprint(gotA) != fmt.Sprint(a) || fmt.Sprint(gotB) != fmt.Sprint(b) {
+			t.Fatalf("Diff of %q and %q doesn't rebuild them", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Diff of %q and %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+
+	// Logs differing on every line take linear memory
+	a, b := make([]string, 8000), make([]string, 8000)
+	for i := range a {
+		a[i], b[i] = fmt.Sprint("a", i), fmt.Sprint("b", i)
+	}
+	if diff := DiffLines(a, b, DiffOptions{}); len(diff) != 16000 {
+		t.Errorf("len(diff) = %d, want 16000", len(diff))
+	}
+}
+
+func TestSearchEntries(t *testing.T) {
+	managers := map[string]HistoryManager{
+		"ring":   NewRingBufferHistoryManager(1024),
+		"memory": NewMemoryHistoryManager(1024),
+	}
+
+	for name, hm := range managers {
+		t.Run(name, func(t *testing.T) {
+			_ = hm.Write([]byte("boot: starting kernel"), DirectionOutput)
+			_ = hm.Write([]byte("help"), DirectionInput)
+			_ = hm.Write([]byte("error: disk not found"), DirectionOutput)
+			_ = hm.Write([]byte("error in input"), DirectionInput)
+
+			results, err := hm.SearchEntries("error", DirectionAny, TimeRange{})
+			if err != nil {
+				t.Fatalf("SearchEntries failed: %v", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("Expected 2 results, got %d", len(results))
+			}
+			if results[0].Index != 2 || results[1].Index != 3 {
+				t.Errorf("Unexpected result indices: %d, %d", results[0].Index, results[1].Index)
+			}
+			if results[0].MatchStart != 0 || results[0].MatchEnd != 5 {
+				t.Errorf("Unexpected match range: %d-%d", results[0].MatchStart, results[0].MatchEnd)
+			}
+
+			// Direction filter
+			results, _ = hm.SearchEntries("error", DirectionOutput, TimeRange{})
+			if len(results) != 1 || string(results[0].Entry.Data) != "error: disk not found" {
+				t.Errorf("Direction filter returned %v", results)
+			}
+
+			// Regex without a literal prefix still works
+			results, _ = hm.SearchEntries(`(?i)KERNEL|DISK`, DirectionAny, TimeRange{})
+			if len(results) != 2 {
+				t.Errorf("Expected 2 case-insensitive results, got %d", len(results))
+			}
+
+			// Time window excluding everything
+			future := TimeRange{Start: time.Now().Add(time.Hour)}
+			results, _ = hm.SearchEntries("error", DirectionAny, future)
+			if len(results) != 0 {
+				t.Errorf("Expected no results in future window, got %d", len(results))
+			}
+
+			// Invalid input
+			if _, err := hm.SearchEntries("", DirectionAny, TimeRange{}); err == nil {
+				t.Error("Expected error for empty pattern")
+			}
+			if _, err := hm.SearchEntries("([", DirectionAny, TimeRange{}); err == nil {
+				t.Error("Expected error for invalid pattern")
+			}
+			if _, err := hm.SearchEntries("x", Direction(5), TimeRange{}); err == nil {
+				t.Error("Expected error for invalid direction")
+			}
+		})
+	}
+}
+
+func TestGetEntriesInRange(t *testing.T) {
+	hm := NewMemoryHistoryManager(1024)
+
+	_ = hm.Write([]byte("old"), DirectionOutput)
+	time.Sleep(5 * time.Millisecond)
+	mid := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	_ = hm.Write([]byte("new1"), DirectionOutput)
+	_ = hm.Write([]byte("new2"), DirectionOutput)
+
+	entries, err := hm.GetEntriesInRange(TimeRange{Start: mid})
+	if err != nil {
+		t.Fatalf("GetEntriesInRange failed: %v", err)
+	}
+	if len(entries) != 2 || string(entries[0].Data) != "new1" {
+		t.Errorf("Unexpected entries after mid: %v", entries)
+	}
+
+	entries, _ = hm.GetEntriesInRange(TimeRange{End: mid})
+	if len(entries) != 1 || string(entries[0].Data) != "old" {
+		t.Errorf("Unexpected entries before mid: %v", entries)
+	}
+
+	entries, _ = hm.GetEntriesInRange(TimeRange{})
+	if len(entries) != 3 {
+		t.Errorf("Open range should return all entries, got %d", len(entries))
+	}
+}
+
+func TestRingBufferSearchAfterWrap(t *testing.T) {
+	hm := NewRingBufferHistoryManager(100) // 1000 entry minimum
+
+	for i := 0; i < 1500; i++ {
+		_ = hm.Write([]byte(fmt.Sprintf("line %d", i)), DirectionOutput)
+	}
+
+	// Oldest entries were overwritten, newest are still searchable
+	results, err := hm.SearchEntries(`^line 1499$`, DirectionAny, TimeRange{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d (err=%v)", len(results), err)
+	}
+	if results[0].Index != hm.GetEntryCount()-1 {
+		t.Errorf("Result index = %d, want %d", results[0].Index, hm.GetEntryCount()-1)
+	}
+
+	results, _ = hm.SearchEntries(`^line 10$`, DirectionAny, TimeRange{})
+	if len(results) != 0 {
+		t.Error("Overwritten entry should not be found")
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	hm := NewMemoryHistoryManager(100)
+
+	var fired []int
+	if err := hm.SetWatermarks([]int{80, 50}, func(level, used, max int) {
+		fired = append(fired, level)
+		if max != 100 {
+			t.Errorf("max = %d, want 100", max)
+		}
+	}); err != nil {
+		t.Fatalf("SetWatermarks failed: %v", err)
+	}
+
+	_ = hm.Write(make([]byte, 40), DirectionOutput) // 40%
+	if len(fired) != 0 {
+		t.Errorf("No watermark should fire at 40%%, got %v", fired)
+	}
+
+	_ = hm.Write(make([]byte, 45), DirectionOutput) // 85%
+	if len(fired) != 2 || fired[0] != 50 || fired[1] != 80 {
+		t.Errorf("Expected watermarks [50 80], got %v", fired)
+	}
+
+	// Staying above doesn't fire again
+	_ = hm.Write(make([]byte, 5), DirectionOutput) // 90%
+	if len(fired) != 2 {
+		t.Errorf("Watermarks should not re-fire while above, got %v", fired)
+	}
+
+	// Invalid levels are rejected
+	if err := hm.SetWatermarks([]int{0}, nil); err == nil {
+		t.Error("Expected error for watermark 0")
+	}
+	if err := hm.SetWatermarks([]int{101}, nil); err == nil {
+		t.Error("Expected error for watermark 101")
+	}
+}
+
+func TestEvictionCallback(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		hm := NewMemoryHistoryManager(100)
+
+		var evicted []HistoryEntry
+		hm.SetEvictionCallback(func(entries []HistoryEntry) {
+			evicted = append(evicted, entries...)
+		})
+
+		_ = hm.Write([]byte(strings.Repeat("a", 60)), DirectionOutput)
+		_ = hm.Write([]byte(strings.Repeat("b", 30)), DirectionOutput)
+		if len(evicted) != 0 {
+			t.Fatalf("Nothing should be evicted yet, got %d", len(evicted))
+		}
+
+		_ = hm.Write([]byte(strings.Repeat("c", 30)), DirectionOutput)
+		if len(evicted) != 1 || evicted[0].Data[0] != 'a' {
+			t.Fatalf("Expected oldest entry to be evicted, got %v", evicted)
+		}
+
+		// Shrinking also reports evicted entries
+		_ = hm.SetMaxSize(30)
+		if len(evicted) != 2 || evicted[1].Data[0] != 'b' {
+			t.Errorf("Expected 'b' entry evicted on shrink, got %d entries", len(evicted))
+		}
+	})
+
+	t.Run("ring", func(t *testing.T) {
+		hm := NewRingBufferHistoryManager(100) // 1000 entry minimum
+
+		count := 0
+		var first HistoryEntry
+		hm.SetEvictionCallback(func(entries []HistoryEntry) {
+			if count == 0 {
+				first = entries[0]
+			}
+			count += len(entries)
+		})
+
+		for i := 0; i < 1005; i++ {
+			_ = hm.Write([]byte(fmt.Sprintf("%d", i)), DirectionOutput)
+		}
+
+		if count != 5 {
+			t.Errorf("Expected 5 evictions, got %d", count)
+		}
+		if string(first.Data) != "0" {
+			t.Errorf("First evicted entry = %q, want %q", first.Data, "0")
+		}
+	})
+}
+
+func TestAppendEntriesToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "flush.log")
+
+	entries := []HistoryEntry{NewHistoryEntry([]byte("one"), DirectionOutput)}
+	if err := AppendEntriesToFile(entries, filename, FormatPlainText); err != nil {
+		t.Fatalf("AppendEntriesToFile failed: %v", err)
+	}
+	entries = []HistoryEntry{NewHistoryEntry([]byte("two"), DirectionOutput)}
+	if err := AppendEntriesToFile(entries, filename, FormatPlainText); err != nil {
+		t.Fatalf("AppendEntriesToFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "onetwo" {
+		t.Errorf("File content = %q, want %q", data, "onetwo")
+	}
+
+	// JSON is written as one object per line
+	jsonFile := filepath.Join(t.TempDir(), "flush.jsonl")
+	_ = AppendEntriesToFile(entries, jsonFile, FormatJSON)
+	_ = AppendEntriesToFile(entries, jsonFile, FormatJSON)
+	data, _ = os.ReadFile(jsonFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, got %d", len(lines))
+	}
+	var entry HistoryEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil || string(entry.Data) != "two" {
+		t.Errorf("Failed to decode JSON line: %v", err)
+	}
+}
+
+func TestCommandHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "dev.history")
+
+	ch := NewCommandHistory(path, 3)
+	for _, cmd := range []string{"ls", "ls", "", "uname -a", "dmesg"} {
+		if err := ch.Add(cmd); err != nil {
+			t.Fatalf("Add(%q) failed: %v", cmd, err)
+		}
+	}
+
+	// Consecutive duplicates and empty commands are skipped
+	if ch.Len() != 3 {
+		t.Fatalf("Expected 3 commands, got %d: %v", ch.Len(), ch.Commands())
+	}
+
+	// Exceeding the limit drops the oldest command, also on disk
+	if err := ch.Add("reboot"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	loaded := NewCommandHistory(path, 3)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	expected := []string{"uname -a", "dmesg", "reboot"}
+	if got := loaded.Commands(); strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v after reload, got %v", expected, got)
+	}
+
+	// The file is appended to until it holds twice the limit, then rewritten
+	fileLines := func() int {
+		data, _ := os.ReadFile(path)
+		return strings.Count(string(data), "\n")
+	}
+	if n := fileLines(); n != 4 {
+		t.Errorf("History file has %d lines, want 4 appended", n)
+	}
+	for _, cmd := range []string{"a", "b", "c"} {
+		_ = ch.Add(cmd)
+	}
+	if n := fileLines(); n != 3 {
+		t.Errorf("History file has %d lines past twice the limit, want the 3 kept", n)
+	}
+
+	// Reverse search walks from newest to oldest
+	if idx := loaded.SearchBackward("e", loaded.Len()); idx != 2 {
+		t.Errorf("Expected newest match at 2, got %d", idx)
+	}
+	if idx := loaded.SearchBackward("e", 2); idx != 1 {
+		t.Errorf("Expected next match at 1, got %d", idx)
+	}
+	if idx := loaded.SearchBackward("zzz", loaded.Len()); idx != -1 {
+		t.Errorf("Expected no match, got %d", idx)
+	}
+
+	// A missing file is not an error
+	if err := NewCommandHistory(filepath.Join(t.TempDir(), "none"), 0).Load(); err != nil {
+		t.Errorf("Load of missing file failed: %v", err)
+	}
+}
+
+func TestCommandHistoryPath(t *testing.T) {
+	tests := []struct {
+		profile  string
+		expected string
+	}{
+		{"mydevice", "mydevice.history"},
+		{"/dev/ttyUSB0", "dev_ttyUSB0.history"},
+		{"COM3", "COM3.history"},
+		{"", "default.history"},
+	}
+
+	for _, tt := range tests {
+		path, err := CommandHistoryPath("/base", tt.profile)
+		if err != nil {
+			t.Fatalf("CommandHistoryPath(%q) failed: %v", tt.profile, err)
+		}
+		if want := filepath.Join("/base", tt.expected); path != want {
+			t.Errorf("CommandHistoryPath(%q) = %q, want %q", tt.profile, path, want)
+		}
+	}
+
+	path, err := PayloadHistoryPath("/base", "/dev/ttyUSB0")
+	if err != nil {
+		t.Fatalf("PayloadHistoryPath failed: %v", err)
+	}
+	if want := filepath.Join("/base", "dev_ttyUSB0.payloads"); path != want {
+		t.Errorf("PayloadHistoryPath = %q, want %q", path, want)
+	}
+}
+
+func TestSpool(t *testing.T) {
+	path, err := SpoolPath(t.TempDir(), "/dev/ttyUSB0")
+	if err != nil {
+		t.Fatalf("SpoolPath failed: %v", err)
+	}
+	if filepath.Base(path) != "dev_ttyUSB0.spool" {
+		t.Errorf("SpoolPath = %q, want dev_ttyUSB0.spool", path)
+	}
+
+	spool, err := OpenSpool(path, 64)
+	if err != nil {
+		t.Fatalf("OpenSpool failed: %v", err)
+	}
+
+	// Nothing reaches the file before a checkpoint
+	spool.Write([]byte("line 1\nline 2\n"))
+	if spool.Pending() != 14 {
+		t.Errorf("Pending = %d, want 14", spool.Pending())
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("File has %q before checkpoint", data)
+	}
+	if err := spool.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "line 1\nline 2\n" {
+		t.Errorf("File = %q after checkpoint", data)
+	}
+	if spool.Pending() != 0 {
+		t.Errorf("Pending = %d after checkpoint, want 0", spool.Pending())
+	}
+
+	// Growing past maxSize keeps the newest half from a line boundary
+	for i := 3; i <= 12; i++ {
+		spool.Write([]byte(fmt.Sprintf("line %d\n", i)))
+	}
+	if err := spool.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if len(data) > 32 || !strings.HasSuffix(string(data), "line 12\n") || !strings.HasPrefix(string(data), "line ") {
+		t.Errorf("File = %q after compaction", data)
+	}
+
+	// Appends continue after compaction
+	spool.Write([]byte("tail\n"))
+	if err := spool.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasSuffix(string(data), "line 12\ntail\n") {
+		t.Errorf("File = %q after append", data)
+	}
+
+	// A clean exit removes the file
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Spool file still exists after Close")
+	}
+}
+
+func TestRecoverSpool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.spool")
+
+	// No spool: nothing to recover
+	if recovered, err := RecoverSpool(path); err != nil || recovered != "" {
+		t.Errorf("RecoverSpool = %q, %v with no spool", recovered, err)
+	}
+
+	// An empty spool is just removed
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if recovered, err := RecoverSpool(path); err != nil || recovered != "" {
+		t.Errorf("RecoverSpool = %q, %v with empty spool", recovered, err)
+	}
+
+	// A spool left by a crash is moved aside so a new one can start
+	if err := os.WriteFile(path, []byte("boot log\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := RecoverSpool(path)
+	if err != nil {
+		t.Fatalf("RecoverSpool failed: %v", err)
+	}
+	if recovered != path+".recovered" {
+		t.Errorf("RecoverSpool = %q, want %q", recovered, path+".recovered")
+	}
+	if data, _ := os.ReadFile(recovered); string(data) != "boot log\n" {
+		t.Errorf("Recovered data = %q", data)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Spool file still exists after recovery")
+	}
+
+	// Data that wasn't restored or discarded is offered again
+	if again, _ := RecoverSpool(path); again != recovered {
+		t.Errorf("RecoverSpool = %q on second start, want %q", again, recovered)
+	}
+}
+
+func TestSpoolLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool", "device.spool")
+	lock, err := LockSpool(path)
+	if err != nil {
+		t.Fatalf("LockSpool failed: %v", err)
+	}
+
+	// A second session of the profile keeps off the live spool
+	if _, err := LockSpool(path); !errors.Is(err, ErrSpoolBusy) {
+		t.Errorf("LockSpool of a held spool = %v, want ErrSpoolBusy", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	again, err := LockSpool(path)
+	if err != nil {
+		t.Fatalf("LockSpool after Release failed: %v", err)
+	}
+	_ = again.Release()
+}
+
+func TestFieldExtractor(t *testing.T) {
+	extractor, err := NewFieldExtractor(`V=(?P<mv>\d+)mV (\w+)`)
+	if err != nil {
+		t.Fatalf("NewFieldExtractor failed: %v", err)
+	}
+	if got := strings.Join(extractor.Columns(), ","); got != "mv,field2" {
+		t.Errorf("Columns = %q, want mv,field2", got)
+	}
+	if _, err := NewFieldExtractor(`V=\d+`); err == nil {
+		t.Error("Expected an error for a pattern without groups")
+	}
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	entries := []HistoryEntry{
+		{Timestamp: start, Direction: DirectionOutput, Data: []byte("boot\r\nV=33")},
+		{Timestamp: start.Add(time.Second), Direction: DirectionInput, Data: []byte("V=1mV typed\r")},
+		{Timestamp: start.Add(2 * time.Second), Direction: DirectionOutput, Data: []byte("00mV \x1b[32mok\x1b[0m\r\nV=5mV low")},
+	}
+	rows := extractor.ExtractEntries(entries)
+
+	var csvOut strings.Builder
+	if err := extractor.WriteCSV(&csvOut, rows); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	want := "time,line,mv,field2\n" +
+		"2024-03-01 10:00:02.000,2,3300,ok\n" +
+		"2024-03-01 10:00:02.000,3,5,low\n"
+	if csvOut.String() != want {
+		t.Errorf("CSV = %q, want %q", csvOut.String(), want)
+	}
+
+	var jsonOut strings.Builder
+	if err := extractor.WriteJSON(&jsonOut, rows[:1]); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var objects []map[string]any
+	if err := json.Unmarshal([]byte(jsonOut.String()), &objects); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", jsonOut.String(), err)
+	}
+	if len(objects) != 1 || objects[0]["mv"] != 3300.0 || objects[0]["field2"] != "ok" || objects[0]["line"] != 2.0 {
+		t.Errorf("JSON = %v", objects)
+	}
+
+	// Values JSON has no numbers for are kept as text
+	special, err := NewFieldExtractor(`temp=(?P<temp>\S+)`)
+	if err != nil {
+		t.Fatalf("NewFieldExtractor failed: %v", err)
+	}
+	jsonOut.Reset()
+	if err := special.WriteJSON(&jsonOut, special.ExtractLines([]string{"temp=nan", "temp=-inf", "temp=21.5"})); err != nil {
+		t.Fatalf("WriteJSON failed with non-finite values: %v", err)
+	}
+	objects = nil
+	if err := json.Unmarshal([]byte(jsonOut.String()), &objects); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", jsonOut.String(), err)
+	}
+	if len(objects) != 3 || objects[0]["temp"] != "nan" || objects[1]["temp"] != "-inf" || objects[2]["temp"] != 21.5 {
+		t.Errorf("JSON with non-finite values = %v", objects)
+	}
+
+	// Saved logs: the timestamp prefix gives the time
+	rows = extractor.ExtractLines([]string{"[2024-03-01 10:00:05.250] V=12mV high", "V=none", "V=7mV plain"})
+	if len(rows) != 2 {
+		t.Fatalf("ExtractLines found %d rows, want 2", len(rows))
+	}
+	if got := formatRowTime(rows[0].Time); got != "2024-03-01 10:00:05.250" {
+		t.Errorf("Time from prefix = %q", got)
+	}
+	if !rows[1].Time.IsZero() || rows[1].Line != 3 || rows[1].Fields[0] != "7" {
+		t.Errorf("Row without timestamp = %+v", rows[1])
+	}
+}
+
+func TestReadTimes(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	chunks := []ChunkTime{
+		{Offset: 0, Time: start},
+		{Offset: 3, Time: start.Add(1500 * time.Microsecond)},
+	}
+	for _, mgr := range []HistoryManager{NewMemoryHistoryManager(1024), NewRingBufferHistoryManager(1024)} {
+		if err := mgr.(TimedWriter).WriteTimed([]byte("ab\ncd"), DirectionOutput, chunks); err != nil {
+			t.Fatalf("WriteTimed failed: %v", err)
+		}
+		if err := mgr.(TimedWriter).WriteTimed([]byte("x"), DirectionOutput, []ChunkTime{{Offset: 1}}); err == nil {
+			t.Error("WriteTimed accepted a chunk past the data")
+		}
+		entries, err := mgr.GetEntries(0, 10)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("GetEntries = %v, %v", entries, err)
+		}
+		pieces := entries[0].Pieces()
+		if len(pieces) != 2 || string(pieces[0].Data) != "ab\n" || string(pieces[1].Data) != "cd" || !pieces[1].Time.Equal(chunks[1].Time) {
+			t.Errorf("Pieces = %+v", pieces)
+		}
+	}
+
+	// Timestamped exports get a line per read, JSON keeps the offsets
+	mgr := NewMemoryHistoryManager(1024)
+	_ = mgr.WriteTimed([]byte("ab\ncd"), DirectionOutput, chunks)
+	dir := t.TempDir()
+	text := filepath.Join(dir, "session.log")
+	if err := mgr.SaveToFile(text, FormatTimestamped); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(text)
+	want := "[2024-05-01 12:00:00.000000] >> ab\\n\n[2024-05-01 12:00:00.001500] >> cd\n"
+	if string(data) != want {
+		t.Errorf("Timestamped export = %q, want %q", data, want)
+	}
+
+	for _, save := range []func(string) error{
+		func(path string) error { return mgr.SaveToFile(path, FormatJSON) },
+		func(path string) error {
+			entries, _ := mgr.GetEntries(0, 10)
+			return AppendEntriesToFile(entries, path, FormatJSON)
+		},
+	} {
+		path := filepath.Join(dir, "session.json")
+		_ = os.Remove(path)
+		if err := save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		entries, err := LoadEntries(path)
+		if err != nil || len(entries) != 1 || len(entries[0].Chunks) != 2 || entries[0].Chunks[1].Offset != 3 {
+			t.Errorf("LoadEntries = %+v, %v", entries, err)
+		}
+	}
+}
+
+func TestHistoryCompression(t *testing.T) {
+	mhm := NewMemoryHistoryManager(1024 * 1024)
+	if err := mhm.SetCompression(64 * 1024); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	var evicted []HistoryEntry
+	mhm.SetEvictionCallback(func(entries []HistoryEntry) {
+		evicted = append(evicted, entries...)
+	})
+
+	// Repetitive log lines, several times the history's size uncompressed
+	var all []byte
+	for i := 0; i < 40000; i++ {
+		line := []byte(fmt.Sprintf("sensor %d: temperature=%d.%d status=OK\r\n", i%16, 20+i%7, i%10))
+		if err := mhm.Write(line, DirectionOutput); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		all = append(all, line...)
+	}
+
+	stats := mhm.CompressionStats()
+	if stats.Segments == 0 || stats.Ratio() < 4 {
+		t.Fatalf("Expected compressed segments at a good ratio, got %+v", stats)
+	}
+	if mhm.GetSize() > mhm.GetMaxSize() {
+		t.Errorf("Size %d over maximum %d", mhm.GetSize(), mhm.GetMaxSize())
+	}
+	if len(evicted) != 0 {
+		t.Errorf("%d entries evicted; %d bytes should fit compressed", len(evicted), len(all))
+	}
+
+	// Reads across compressed segments and the live window see the data as written
+	read, err := mhm.Read(100000, 300000)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !bytes.Equal(read, all[100000:400000]) {
+		t.Error("Read returned different data from what was written")
+	}
+	read, _ = mhm.Read(len(all)-10, 100)
+	if !bytes.Equal(read, all[len(all)-10:]) {
+		t.Errorf("Read at the end = %q", read)
+	}
+
+	entries, _ := mhm.GetEntries(0, 2)
+	if string(entries[1].Data) != "sensor 1: temperature=21.1 status=OK\r\n" {
+		t.Errorf("Compressed entry data = %q", entries[1].Data)
+	}
+	results, err := mhm.SearchEntries(`sensor 3: temperature=23\.3`, DirectionOutput, TimeRange{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Index != 3 {
+		t.Errorf("Search found %d results, first %+v", len(results), results)
+	}
+
+	// Saved files are gzipped by name and read back transparently
+	path := filepath.Join(t.TempDir(), "history.json.gz")
+	if err := mhm.SaveToFile(path, FormatJSON); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(raw, gzipMagic) || len(raw) > len(all)/4 {
+		t.Errorf("Saved file isn't gzipped: %d bytes", len(raw))
+	}
+	loaded, err := LoadEntries(path)
+	if err != nil {
+		t.Fatalf("LoadEntries failed: %v", err)
+	}
+	if len(loaded) != mhm.GetEntryCount() || !bytes.Equal(loaded[0].Data, entries[0].Data) {
+		t.Errorf("Loaded %d entries, want %d", len(loaded), mhm.GetEntryCount())
+	}
+
+	// Appends add gzip streams that read back as one file
+	path = filepath.Join(t.TempDir(), "flush.jsonl.gz")
+	for i := 0; i < 3; i++ {
+		if err := AppendEntriesToFile(entries, path, FormatJSON); err != nil {
+			t.Fatalf("AppendEntriesToFile failed: %v", err)
+		}
+	}
+	if loaded, err = LoadEntries(path); err != nil || len(loaded) != 6 {
+		t.Errorf("Loaded %d appended entries: %v", len(loaded), err)
+	}
+
+	// Shrinking evicts compressed entries with their data
+	if err := mhm.SetMaxSize(64 * 1024); err != nil {
+		t.Fatalf("SetMaxSize failed: %v", err)
+	}
+	if len(evicted) == 0 || !bytes.Equal(evicted[0].Data, entries[0].Data) {
+		t.Errorf("Evicted entries lost their data")
+	}
+	if mhm.GetSize() > 64*1024 {
+		t.Errorf("Size %d after shrinking", mhm.GetSize())
+	}
+
+	if err := mhm.Clear(); err != nil || mhm.GetSize() != 0 || mhm.CompressionStats().Entries != 0 {
+		t.Errorf("Clear left %d bytes, %+v", mhm.GetSize(), mhm.CompressionStats())
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	compressed := NewMemoryHistoryManager(256 * 1024)
+	_ = compressed.SetCompression(16 * 1024)
+	managers := map[string]HistoryManager{
+		"memory": compressed,
+		"ring":   NewRingBufferHistoryManager(64 * 1024),
+	}
+	for name, mgr := range managers {
+		t.Run(name, func(t *testing.T) {
+			// Callbacks may call back into the manager
+			obs := mgr.(ObservableHistory)
+			_ = obs.SetWatermarks([]int{50}, func(level, used, max int) { mgr.GetEntryCount() })
+			obs.SetEvictionCallback(func(evicted []HistoryEntry) { mgr.GetSize() })
+			obs.SetWriteCallback(func(entry HistoryEntry) { mgr.GetEntryCount() })
+
+			// Received and sent data written from their own goroutines
+			// while the UI reads
+			var wg sync.WaitGroup
+			for w, direction := range []Direction{DirectionOutput, DirectionInput} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 3000; i++ {
+						_ = mgr.Write([]byte(fmt.Sprintf("writer %d line %d\r\n", w, i)), direction)
+						if i%100 == 0 {
+							time.Sleep(time.Millisecond)
+						}
+					}
+				}()
+			}
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			for reading := true; reading; {
+				select {
+				case <-done:
+					reading = false
+				default:
+				}
+				count := mgr.GetEntryCount()
+				entries, err := mgr.GetEntries(max(count-50, 0), 50)
+				if err != nil {
+					t.Fatalf("GetEntries failed: %v", err)
+				}
+				for _, entry := range entries {
+					if len(entry.Data) == 0 {
+						t.Fatalf("Entry without data: %+v", entry)
+					}
+				}
+				_, _ = mgr.Read(0, 100)
+				_, _ = mgr.SearchEntries("line 1", DirectionAny, TimeRange{})
+				time.Sleep(time.Millisecond)
+			}
+			if mgr.GetSize() > mgr.GetMaxSize() {
+				t.Errorf("Size %d over maximum %d", mgr.GetSize(), mgr.GetMaxSize())
+			}
+		})
+	}
+}
+
+func TestCorruptCompressedSegment(t *testing.T) {
+	mhm := NewMemoryHistoryManager(1024 * 1024)
+	if err := mhm.SetCompression(64 * 1024); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	for i := 0; i < 30000; i++ {
+		_ = mhm.Write([]byte(fmt.Sprintf("line %d\r\n", i)), DirectionOutput)
+	}
+	if mhm.CompressionStats().Segments == 0 {
+		t.Fatal("Nothing was compressed")
+	}
+
+	// Damage the first segment; reading it reports an error instead of panicking
+	seg := mhm.refs[0].seg
+	seg.data = seg.data[:len(seg.data)/2]
+	mhm.cache = segmentCache{}
+
+	if _, err := mhm.GetEntries(0, 1); err == nil {
+		t.Error("GetEntries of a corrupt entry succeeded")
+	}
+	if _, err := mhm.Read(0, 10); err == nil {
+		t.Error("Read of corrupt data succeeded")
+	}
+	if _, err := mhm.SearchEntries("line", DirectionAny, TimeRange{}); err == nil {
+		t.Error("Search through corrupt data succeeded")
+	}
+
+	// Saving keeps the entries that can be read and reports the rest
+	path := filepath.Join(t.TempDir(), "history.log")
+	if err := mhm.SaveToFile(path, FormatJSON); err == nil {
+		t.Error("SaveToFile of corrupt data succeeded")
+	}
+	loaded, err := LoadEntries(path)
+	if err != nil || len(loaded) == 0 || len(loaded) >= mhm.GetEntryCount() {
+		t.Errorf("Saved %d of %d entries (%v)", len(loaded), mhm.GetEntryCount(), err)
+	}
+	if string(loaded[len(loaded)-1].Data) != "line 29999\r\n" {
+		t.Errorf("Last saved entry = %q", loaded[len(loaded)-1].Data)
+	}
+}
+
+func TestStream(t *testing.T) {
+	entries := []HistoryEntry{
+		NewHistoryEntry([]byte("AT\r"), DirectionInput),
+		NewHistoryEntry([]byte("OK\r\n"), DirectionOutput),
+	}
+
+	// A JSON export is streamed out in the same layout it always had
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.json")
+	if err := saveEntriesToFile(entries, path, FormatJSON); err != nil {
+		t.Fatalf("saveEntriesToFile failed: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+	want, _ := json.MarshalIndent(struct {
+		Entries []HistoryEntry `json:"entries"`
+		Count   int            `json:"count"`
+	}{entries, len(entries)}, "", "  ")
+	if string(saved) != string(want)+"\n" {
+		t.Errorf("Streamed JSON export differs:\n%s\nwant:\n%s", saved, want)
+	}
+	path = filepath.Join(dir, "empty.json")
+	if err := saveEntriesToFile(nil, path, FormatJSON); err != nil {
+		t.Fatalf("saveEntriesToFile failed: %v", err)
+	}
+	if saved, _ = os.ReadFile(path); !json.Valid(saved) || !strings.Contains(string(saved), `"entries": []`) {
+		t.Errorf("Empty export = %s", saved)
+	}
+
+	// A gzipped stream can be read while it is open, and appends to what
+	// an earlier stream left
+	path = filepath.Join(dir, "live.jsonl.gz")
+	if err := AppendEntriesToFile(entries[:1], path, FormatJSON); err != nil {
+		t.Fatalf("AppendEntriesToFile failed: %v", err)
+	}
+	stream, err := OpenStream(path, FormatJSON)
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	mhm := NewMemoryHistoryManager(1024)
+	mhm.SetWriteCallback(func(entry HistoryEntry) {
+		if err := stream.Write(entry); err != nil {
+			t.Errorf("Stream write failed: %v", err)
+		}
+	})
+	_ = mhm.Write([]byte("OK\r\n"), DirectionOutput)
+	file, _ := os.Open(path)
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Open stream isn't readable: %v", err)
+	}
+	partial, _ := io.ReadAll(gz) // Ends early, the last stream being unfinished
+	file.Close()
+	if strings.Count(string(partial), "\n") != 2 {
+		t.Errorf("Read %q from the open stream, want 2 entries", partial)
+	}
+	if stream.Entries() != 1 {
+		t.Errorf("Entries() = %d, want 1", stream.Entries())
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := stream.Write(entries...); err == nil {
+		t.Error("Write after Close should fail")
+	}
+	loaded, err := LoadEntries(path)
+	if err != nil || len(loaded) != 2 {
+		t.Errorf("Loaded %d entries: %v", len(loaded), err)
+	}
+
+	if _, err := OpenStream(path, FileFormat(999)); err == nil {
+		t.Error("OpenStream with an unsupported format should fail")
+	}
+}
+
+func TestParseTimestamped(t *testing.T) {
+	// A timestamped export reads back with its times, directions and line feeds
+	mhm := NewMemoryHistoryManager(1024)
+	_ = mhm.Write([]byte("AT\r"), DirectionInput)
+	_ = mhm.Write([]byte("OK\r\nready\r\n"), DirectionOutput)
+	path := filepath.Join(t.TempDir(), "session.log.gz")
+	if err := mhm.SaveToFile(path, FormatTimestamped); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	saved, _ := mhm.GetEntries(0, 2)
+
+	loaded, err := LoadEntries(path)
+	if err != nil {
+		t.Fatalf("LoadEntries failed: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Loaded %d entries, want 2", len(loaded))
+	}
+	for i, entry := range loaded {
+		if !bytes.Equal(entry.Data, saved[i].Data) || entry.Direction != saved[i].Direction {
+			t.Errorf("Entry %d = %q (%v), want %q (%v)", i, entry.Data, entry.Direction, saved[i].Data, saved[i].Direction)
+		}
+		if !entry.Timestamp.Equal(saved[i].Timestamp.Truncate(time.Millisecond)) {
+			t.Errorf("Entry %d time = %v, want %v", i, entry.Timestamp, saved[i].Timestamp)
+		}
+	}
+
+	if _, err := ParseTimestamped([]byte("[    0.000000] Linux version 6.1\n")); err == nil {
+		t.Error("A kernel log isn't timestamped history")
+	}
+}
@@ -0,0 +1,142 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DirectionAny matches entries in either direction when searching
+const DirectionAny Direction = -1
+
+// TimeRange restricts queries to a time window. A zero Start or End leaves
+// that side of the window open.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains checks whether a timestamp falls inside the range (inclusive)
+func (tr TimeRange) Contains(t time.Time) bool {
+	if !tr.Start.IsZero() && t.Before(tr.Start) {
+		return false
+	}
+	if !tr.End.IsZero() && t.After(tr.End) {
+		return false
+	}
+	return true
+}
+
+// SearchResult is a single match returned by SearchEntries
+type SearchResult struct {
+	Index      int          `json:"index"`       // Entry index usable with GetEntries
+	Entry      HistoryEntry `json:"entry"`       // The matching entry
+	MatchStart int          `json:"match_start"` // Byte offset of the first match in Entry.Data
+	MatchEnd   int          `json:"match_end"`   // Byte offset just past the first match
+}
+
+// entrySource gives the search code uniform, ordered access to a manager's entries
+type entrySource interface {
+	entryLen() int
+	entryAt(i int) (HistoryEntry, error) // Fails if the entry's data can't be read
+	metaAt(i int) HistoryEntry           // Like entryAt, but Data may be left out
+	maskAt(i int) uint64
+}
+
+// bigramMask builds a 64-bit bloom mask of the byte pairs in data. An entry can
+// only contain a literal if the literal's mask is a subset of the entry's mask,
+// which lets searches skip most entries without running the regex.
+func bigramMask(data []byte) uint64 {
+	var mask uint64
+	for i := 0; i+1 < len(data); i++ {
+		mask |= 1 << ((uint(data[i])*31 + uint(data[i+1])) % 64)
+	}
+	return mask
+}
+
+// timeBounds returns the [first, last) entry indices inside the time range.
+// Entries are appended in time order, so both ends are found by binary search.
+func timeBounds(src entrySource, tr TimeRange) (int, int) {
+	n := src.entryLen()
+	first := 0
+	if !tr.Start.IsZero() {
+		first = sort.Search(n, func(i int) bool {
+			return !src.metaAt(i).Timestamp.Before(tr.Start)
+		})
+	}
+	last := n
+	if !tr.End.IsZero() {
+		last = sort.Search(n, func(i int) bool {
+			return src.metaAt(i).Timestamp.After(tr.End)
+		})
+	}
+	if last < first {
+		last = first
+	}
+	return first, last
+}
+
+// entriesInRange returns copies of all entries inside the time range
+func entriesInRange(src entrySource, tr TimeRange) ([]HistoryEntry, error) {
+	first, last := timeBounds(src, tr)
+	result := make([]HistoryEntry, 0, last-first)
+	for i := first; i < last; i++ {
+		entry, err := src.entryAt(i)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// searchEntries runs a regex search over the entries of a source
+func searchEntries(src entrySource, pattern string, direction Direction, tr TimeRange) ([]SearchResult, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern cannot be empty")
+	}
+	if direction != DirectionAny && direction != DirectionInput && direction != DirectionOutput {
+		return nil, fmt.Errorf("invalid direction: %d", direction)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	// Use the literal prefix of the pattern to pre-filter entries by bigram mask
+	var literalMask uint64
+	if prefix, _ := re.LiteralPrefix(); len(prefix) >= 2 {
+		literalMask = bigramMask([]byte(prefix))
+	}
+
+	first, last := timeBounds(src, tr)
+	results := make([]SearchResult, 0)
+	for i := first; i < last; i++ {
+		if literalMask != 0 && src.maskAt(i)&literalMask != literalMask {
+			continue
+		}
+
+		if direction != DirectionAny && src.metaAt(i).Direction != direction {
+			continue
+		}
+
+		entry, err := src.entryAt(i)
+		if err != nil {
+			return nil, err
+		}
+		loc := re.FindIndex(entry.Data)
+		if loc == nil {
+			continue
+		}
+		results = append(results, SearchResult{
+			Index:      i,
+			Entry:      entry,
+			MatchStart: loc[0],
+			MatchEnd:   loc[1],
+		})
+	}
+
+	return results, nil
+}
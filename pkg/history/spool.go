@@ -0,0 +1,235 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recoveredSuffix marks a spool left behind by a session that didn't exit cleanly
+const recoveredSuffix = ".recovered"
+
+// lockSuffix names the lock file kept next to a spool
+const lockSuffix = ".lock"
+
+// ErrSpoolBusy is returned when another session holds the lock of a spool
+var ErrSpoolBusy = errors.New("spool is in use by another session")
+
+// SpoolLock keeps other sessions of the same profile away from a spool and
+// its recovered data while held
+type SpoolLock struct {
+	file *os.File
+}
+
+// LockSpool takes the lock of the spool at path, failing with ErrSpoolBusy
+// if another session holds it. The system drops the lock when the process
+// ends, crash or not, so a spool is only recovered once its session is gone.
+func LockSpool(path string) (*SpoolLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	file, err := os.OpenFile(path+lockSuffix, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool lock: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrSpoolBusy) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock spool: %w", err)
+	}
+	return &SpoolLock{file: file}, nil
+}
+
+// Release gives up the lock
+func (l *SpoolLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Spool is an append-only file of received data that lets a session be
+// recovered after a crash or power loss. Data is buffered in memory and
+// written out and synced to disk at checkpoints. A clean exit removes it.
+type Spool struct {
+	path           string
+	maxSize        int64 // The oldest half is dropped when the file grows past this
+	file           *os.File
+	size           int64
+	pending        []byte
+	lastCheckpoint time.Time
+	mu             sync.Mutex
+}
+
+// SpoolPath returns the spool file for a profile under baseDir
+// (~/.sterm/spool when baseDir is empty)
+func SpoolPath(baseDir, profile string) (string, error) {
+	if baseDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		baseDir = filepath.Join(homeDir, ".sterm", "spool")
+	}
+
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(profile, "_"), "_")
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(baseDir, name+".spool"), nil
+}
+
+// RecoverSpool moves a spool left behind by a crashed session out of the way
+// so a new one can start. Returns the path of recovered data, or "" if there
+// is none. Recovered data that wasn't restored or discarded last time is
+// offered again. The caller must hold the spool's lock, or it could take
+// the live spool of another session for a crashed one.
+func RecoverSpool(path string) (string, error) {
+	recovered := path + recoveredSuffix
+	if info, err := os.Stat(path); err == nil {
+		if info.Size() == 0 {
+			_ = os.Remove(path)
+		} else if err := os.Rename(path, recovered); err != nil {
+			return "", fmt.Errorf("failed to move spool file: %w", err)
+		}
+	}
+
+	if info, err := os.Stat(recovered); err == nil && info.Size() > 0 {
+		return recovered, nil
+	}
+	return "", nil
+}
+
+// OpenSpool creates an empty spool file at path
+func OpenSpool(path string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	return &Spool{
+		path:           path,
+		maxSize:        maxSize,
+		file:           file,
+		lastCheckpoint: time.Now(),
+	}, nil
+}
+
+// Path returns the spool file location
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Write buffers received data until the next checkpoint
+func (s *Spool) Write(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, data...)
+}
+
+// Pending returns the number of bytes waiting for a checkpoint
+func (s *Spool) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// SinceCheckpoint returns how long ago the last checkpoint was
+func (s *Spool) SinceCheckpoint(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastCheckpoint)
+}
+
+// Checkpoint writes buffered data to the file and syncs it to disk
+func (s *Spool) Checkpoint() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCheckpoint = time.Now()
+	if len(s.pending) == 0 || s.file == nil {
+		return nil
+	}
+
+	if s.maxSize > 0 && s.size+int64(len(s.pending)) > s.maxSize {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	} else {
+		n, err := s.file.Write(s.pending)
+		s.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write spool file: %w", err)
+		}
+	}
+	s.pending = s.pending[:0]
+
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool file: %w", err)
+	}
+	return nil
+}
+
+// compact rewrites the spool with the newest half of its data plus the
+// pending data, starting at a line boundary
+func (s *Spool) compact() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read spool file: %w", err)
+	}
+	data = append(data, s.pending...)
+
+	keep := data[int64(len(data))-min(int64(len(data)), s.maxSize/2):]
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 && i+1 < len(keep) {
+		keep = keep[i+1:]
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, keep, 0600); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	s.file.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		// Keep appending to the old file rather than writing to a closed one
+		s.file, _ = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+		return fmt.Errorf("failed to replace spool file: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		s.file = nil
+		return fmt.Errorf("failed to reopen spool file: %w", err)
+	}
+	s.file = file
+	s.size = int64(len(keep))
+	return nil
+}
+
+// Close closes and removes the spool. Called on a clean exit, when there is
+// nothing to recover.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	s.file = nil
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove spool file: %w", err)
+	}
+	return nil
+}
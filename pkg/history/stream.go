@@ -0,0 +1,166 @@
+package history
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"sync"
+)
+
+// WriteCallback is called with each entry just after it is added
+type WriteCallback func(entry HistoryEntry)
+
+// Stream appends entries to a history file as they are recorded, so a long
+// session is saved as it goes instead of all at once at the end, and other
+// tools can follow the file with tail -f. JSON is written one entry per
+// line. A .gz file is flushed after every write so what has been written
+// can be decompressed while the stream is still open.
+type Stream struct {
+	path    string
+	format  FileFormat
+	file    *os.File
+	gz      *gzip.Writer // Nil unless the file is compressed
+	w       io.Writer
+	entries int
+	mu      sync.Mutex
+}
+
+// OpenStream opens a history file for appending entries, creating it if
+// needed
+func OpenStream(filename string, format FileFormat) (*Stream, error) {
+	if filename == "" {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	if format != FormatPlainText && format != FormatTimestamped && format != FormatJSON {
+		return nil, fmt.Errorf("unsupported format: %v", format)
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	s := &Stream{path: filename, format: format, file: file, w: file}
+	if IsCompressedFile(filename) {
+		s.gz = gzip.NewWriter(file)
+		s.w = s.gz
+	}
+	return s, nil
+}
+
+// Path returns the file the stream appends to
+func (s *Stream) Path() string {
+	return s.path
+}
+
+// Entries returns how many entries have been written
+func (s *Stream) Entries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries
+}
+
+// Write appends entries to the file
+func (s *Stream) Write(entries ...HistoryEntry) error {
+	return s.WriteSeq(slices.Values(entries))
+}
+
+// WriteSeq appends entries as they are produced, such as a whole history
+// when a stream starts
+func (s *Stream) WriteSeq(entries iter.Seq[HistoryEntry]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("stream is closed")
+	}
+	counted := func(yield func(HistoryEntry) bool) {
+		for entry := range entries {
+			s.entries++
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+	if err := writeEntries(s.w, counted, s.format); err != nil {
+		return err
+	}
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return fmt.Errorf("failed to compress file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close finishes the file
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	var err error
+	if s.gz != nil {
+		if err = s.gz.Close(); err != nil {
+			err = fmt.Errorf("failed to compress file: %w", err)
+		}
+	}
+	if closeErr := s.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close file: %w", closeErr)
+	}
+	s.file = nil
+	return err
+}
+
+// EntryIterator is implemented by history managers that can hand out
+// their entries one at a time
+type EntryIterator interface {
+	All() iter.Seq[HistoryEntry]
+}
+
+// allEntries yields a source's entries oldest first, fetching each only
+// when it is reached. Entries whose data can't be read are skipped; the
+// first such error is left in *errp unless errp is nil.
+func allEntries(src entrySource, errp *error) iter.Seq[HistoryEntry] {
+	return func(yield func(HistoryEntry) bool) {
+		for i := 0; i < src.entryLen(); i++ {
+			entry, err := src.entryAt(i)
+			if err != nil {
+				if errp != nil && *errp == nil {
+					*errp = err
+				}
+				continue
+			}
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// All returns the entries oldest first, decompressing each only when it is
+// reached, for saving a long history without a copy of all of it. Entries
+// in a corrupt compressed segment are skipped. Writes wait until the loop
+// over the entries ends, so its body must not call the manager.
+func (mhm *MemoryHistoryManager) All() iter.Seq[HistoryEntry] {
+	return lockedEntries(&mhm.mu, allEntries(mhm, nil))
+}
+
+// All returns the entries oldest first. Writes wait until the loop over the
+// entries ends, so its body must not call the manager.
+func (rbhm *RingBufferHistoryManager) All() iter.Seq[HistoryEntry] {
+	return lockedEntries(&rbhm.mu, allEntries(rbhm, nil))
+}
+
+// lockedEntries holds a manager's read lock while entries are iterated
+func lockedEntries(mu *sync.RWMutex, entries iter.Seq[HistoryEntry]) iter.Seq[HistoryEntry] {
+	return func(yield func(HistoryEntry) bool) {
+		mu.RLock()
+		defer mu.RUnlock()
+		entries(yield)
+	}
+}
@@ -0,0 +1,146 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// WatermarkCallback is called when history usage rises past a watermark.
+// level is the watermark percentage, used and max are in bytes.
+type WatermarkCallback func(level int, used, max int)
+
+// EvictionCallback is called with entries dropped to make room
+type EvictionCallback func(evicted []HistoryEntry)
+
+// ObservableHistory is implemented by history managers that can report usage
+// watermarks and evictions, so callers can warn users or flush old data to disk
+type ObservableHistory interface {
+	SetWatermarks(levels []int, callback WatermarkCallback) error
+	SetEvictionCallback(callback EvictionCallback)
+	SetWriteCallback(callback WriteCallback)
+}
+
+// historyObserver tracks watermark state and callbacks for a history
+// manager. Its mutex is the manager's too: data is written from the reader
+// and send queue goroutines and read from the UI, so the manager's methods
+// hold mu around their work. Callbacks are queued while it is held and made
+// by unlock, so they can call back into the manager.
+type historyObserver struct {
+	watermarks  []int  // Sorted watermark percentages
+	reached     []bool // Whether each watermark has fired since usage last dropped below it
+	onWatermark WatermarkCallback
+	onEvict     EvictionCallback
+	onWrite     WriteCallback
+	pending     []func() // Callbacks due once the manager is unlocked
+	mu          sync.RWMutex
+}
+
+// unlock releases the manager after a change and makes the callbacks the
+// change was due
+func (o *historyObserver) unlock() {
+	calls := o.pending
+	o.pending = nil
+	o.mu.Unlock()
+	for _, call := range calls {
+		call()
+	}
+}
+
+// SetWatermarks sets the usage percentages (1-100) that trigger the callback.
+// Each watermark fires once when crossed upward and re-arms when usage drops below it.
+func (o *historyObserver) SetWatermarks(levels []int, callback WatermarkCallback) error {
+	for _, level := range levels {
+		if level < 1 || level > 100 {
+			return fmt.Errorf("watermark must be between 1 and 100, got %d", level)
+		}
+	}
+
+	sorted := make([]int, len(levels))
+	copy(sorted, levels)
+	sort.Ints(sorted)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.watermarks = sorted
+	o.reached = make([]bool, len(sorted))
+	o.onWatermark = callback
+	return nil
+}
+
+// SetEvictionCallback sets the callback for entries about to be dropped
+func (o *historyObserver) SetEvictionCallback(callback EvictionCallback) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onEvict = callback
+}
+
+// SetWriteCallback sets the callback for entries just added, such as to
+// stream them to a file
+func (o *historyObserver) SetWriteCallback(callback WriteCallback) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onWrite = callback
+}
+
+// checkWatermarks queues callbacks for newly crossed watermarks. Called
+// with mu held.
+func (o *historyObserver) checkWatermarks(used, max int) {
+	if max <= 0 || len(o.watermarks) == 0 {
+		return
+	}
+
+	percent := used * 100 / max
+	for i, level := range o.watermarks {
+		if percent >= level {
+			if !o.reached[i] {
+				o.reached[i] = true
+				if callback := o.onWatermark; callback != nil {
+					o.pending = append(o.pending, func() { callback(level, used, max) })
+				}
+			}
+		} else {
+			// Re-arm so the watermark fires again next time it is crossed
+			o.reached[i] = false
+		}
+	}
+}
+
+// notifyEvicted queues the report of entries being dropped. Called with mu
+// held.
+func (o *historyObserver) notifyEvicted(entries []HistoryEntry) {
+	if callback := o.onEvict; callback != nil && len(entries) > 0 {
+		o.pending = append(o.pending, func() { callback(entries) })
+	}
+}
+
+// notifyWritten queues the report of an entry just added. Called with mu
+// held.
+func (o *historyObserver) notifyWritten(entry HistoryEntry) {
+	if callback := o.onWrite; callback != nil {
+		o.pending = append(o.pending, func() { callback(entry) })
+	}
+}
+
+// SetWatermarks forwards to the base manager if it supports watermarks
+func (phm *PersistentHistoryManager) SetWatermarks(levels []int, callback WatermarkCallback) error {
+	obs, ok := phm.HistoryManager.(ObservableHistory)
+	if !ok {
+		return fmt.Errorf("watermarks not supported for this history manager type")
+	}
+	return obs.SetWatermarks(levels, callback)
+}
+
+// SetEvictionCallback forwards to the base manager if it supports eviction callbacks
+func (phm *PersistentHistoryManager) SetEvictionCallback(callback EvictionCallback) {
+	if obs, ok := phm.HistoryManager.(ObservableHistory); ok {
+		obs.SetEvictionCallback(callback)
+	}
+}
+
+// SetWriteCallback forwards to the base manager if it supports write callbacks
+func (phm *PersistentHistoryManager) SetWriteCallback(callback WriteCallback) {
+	if obs, ok := phm.HistoryManager.(ObservableHistory); ok {
+		obs.SetWriteCallback(callback)
+	}
+}
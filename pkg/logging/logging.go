@@ -0,0 +1,314 @@
+// Package logging provides sterm's internal diagnostic log: leveled messages
+// tagged with the subsystem that wrote them, written as text or JSON lines to
+// ~/.sterm/sterm-debug.log.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FileName is the log file created in the .sterm directory
+const FileName = "sterm-debug.log"
+
+// Level is the severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff // Nothing is logged
+)
+
+// LevelNames are the accepted level names, lowest first
+var LevelNames = []string{"debug", "info", "warn", "error", "off"}
+
+// String returns the level name used in settings and JSON output
+func (l Level) String() string {
+	if l >= LevelDebug && int(l) < len(LevelNames) {
+		return LevelNames[l]
+	}
+	return "unknown"
+}
+
+// ParseLevel parses a level name, ignoring case. "warning" is accepted for warn.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+	if name == "warning" {
+		return LevelWarn, nil
+	}
+	for i, n := range LevelNames {
+		if n == name {
+			return Level(i), nil
+		}
+	}
+	return LevelOff, fmt.Errorf("invalid level %q (must be one of %s)", name, strings.Join(LevelNames, ", "))
+}
+
+// Module is the subsystem a message comes from. Modules can be muted
+// separately to debug just one part of sterm.
+type Module string
+
+const (
+	ModuleApp      Module = "app"      // Application, menus and settings
+	ModuleParser   Module = "parser"   // Escape sequence parsing and terminal state
+	ModuleRenderer Module = "renderer" // Screen drawing
+	ModuleSerial   Module = "serial"   // Port I/O and connection handling
+)
+
+// Modules lists every module in menu order
+var Modules = []Module{ModuleApp, ModuleParser, ModuleRenderer, ModuleSerial}
+
+// Logger writes leveled messages for enabled modules. The zero state logs
+// nothing until Open is called and a level other than off is set. A Logger
+// is safe for use from multiple goroutines, and a nil Logger discards
+// everything.
+type Logger struct {
+	out   io.Writer
+	file  *os.File // Set when writing to a file Open created
+	level Level
+	muted map[Module]bool
+	json  bool
+	mu    sync.Mutex
+
+	// Fast path, read without mu: the lowest level written, LevelOff with
+	// no output, so messages below it cost no lock
+	threshold atomic.Int32
+}
+
+// New creates a logger with no output
+func New() *Logger {
+	l := &Logger{level: LevelOff, muted: make(map[Module]bool)}
+	l.threshold.Store(int32(LevelOff))
+	return l
+}
+
+// DefaultPath returns ~/.sterm/sterm-debug.log, or the file in the current
+// directory if the home directory can't be used
+func DefaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return FileName
+	}
+	dir := filepath.Join(homeDir, ".sterm")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return FileName
+	}
+	return filepath.Join(dir, FileName)
+}
+
+// Open starts writing to the log file at path, replacing any earlier
+// output. The file is appended to, so turning logging off and on again, or
+// starting another session, keeps what was logged before.
+func (l *Logger) Open(path string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closeLocked()
+	l.out, l.file = file, file
+	l.update()
+	return nil
+}
+
+// SetOutput writes to w instead of a file. The caller keeps ownership of w.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closeLocked()
+	l.out = w
+	l.update()
+}
+
+// IsOpen reports whether the logger has somewhere to write
+func (l *Logger) IsOpen() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.out != nil
+}
+
+// Close stops logging and closes the log file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closeLocked()
+}
+
+// closeLocked closes the output. Called with mu held.
+func (l *Logger) closeLocked() error {
+	var err error
+	if l.file != nil {
+		_ = l.file.Sync()
+		err = l.file.Close()
+	}
+	l.out, l.file = nil, nil
+	l.update()
+	return err
+}
+
+// update refreshes the fast path threshold. Called with mu held.
+func (l *Logger) update() {
+	threshold := l.level
+	if l.out == nil {
+		threshold = LevelOff
+	}
+	l.threshold.Store(int32(threshold))
+}
+
+// SetLevel sets the lowest level that is written
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+	l.update()
+}
+
+// Level returns the lowest level that is written
+func (l *Logger) Level() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// SetModuleEnabled mutes or unmutes a module
+func (l *Logger) SetModuleEnabled(module Module, enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if enabled {
+		delete(l.muted, module)
+	} else {
+		l.muted[module] = true
+	}
+}
+
+// ModuleEnabled reports whether a module's messages are written
+func (l *Logger) ModuleEnabled(module Module) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.muted[module]
+}
+
+// SetJSON switches between text lines and JSON lines
+func (l *Logger) SetJSON(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.json = enabled
+}
+
+// JSON reports whether the log is written as JSON lines
+func (l *Logger) JSON() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.json
+}
+
+// Enabled reports whether a message would be written, so callers can skip
+// building expensive messages
+func (l *Logger) Enabled(module Module, level Level) bool {
+	if l == nil || !l.passes(level) {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enabled(module, level)
+}
+
+// passes is the fast path check of a level against the threshold, made
+// without taking mu
+func (l *Logger) passes(level Level) bool {
+	return level >= Level(l.threshold.Load()) && level < LevelOff
+}
+
+// enabled is Enabled with mu held
+func (l *Logger) enabled(module Module, level Level) bool {
+	return l.out != nil && level >= l.level && level < LevelOff && !l.muted[module]
+}
+
+// jsonEntry is one line of JSON output
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Module  Module `json:"module"`
+	Message string `json:"msg"`
+}
+
+// Logf writes a message if the level and module are enabled
+func (l *Logger) Logf(module Module, level Level, format string, args ...interface{}) {
+	if l == nil || !l.passes(level) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.enabled(module, level) {
+		return
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	now := time.Now()
+	if l.json {
+		line, err := json.Marshal(jsonEntry{
+			Time:    now.Format(time.RFC3339Nano),
+			Level:   level.String(),
+			Module:  module,
+			Message: msg,
+		})
+		if err != nil {
+			return
+		}
+		_, _ = l.out.Write(append(line, '\n'))
+	} else {
+		fmt.Fprintf(l.out, "[%s] %-5s %-8s %s\n", now.Format("15:04:05.000"), strings.ToUpper(level.String()), module, msg)
+	}
+
+	// Written through immediately so the log survives a crash
+	if l.file != nil {
+		_ = l.file.Sync()
+	}
+}
+
+// Module returns a logger that tags messages with module
+func (l *Logger) Module(module Module) *ModuleLogger {
+	return &ModuleLogger{logger: l, module: module}
+}
+
+// ModuleLogger writes messages for one module
+type ModuleLogger struct {
+	logger *Logger
+	module Module
+}
+
+// Debugf writes a debug message
+func (m *ModuleLogger) Debugf(format string, args ...interface{}) {
+	m.logger.Logf(m.module, LevelDebug, format, args...)
+}
+
+// Infof writes an informational message
+func (m *ModuleLogger) Infof(format string, args ...interface{}) {
+	m.logger.Logf(m.module, LevelInfo, format, args...)
+}
+
+// Warnf writes a warning
+func (m *ModuleLogger) Warnf(format string, args ...interface{}) {
+	m.logger.Logf(m.module, LevelWarn, format, args...)
+}
+
+// Errorf writes an error
+func (m *ModuleLogger) Errorf(format string, args ...interface{}) {
+	m.logger.Logf(m.module, LevelError, format, args...)
+}
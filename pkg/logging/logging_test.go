@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Level
+		wantErr  bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"warn", LevelWarn, false},
+		{"warning", LevelWarn, false},
+		{"error", LevelError, false},
+		{"off", LevelOff, false},
+		{"verbose", LevelOff, true},
+	}
+
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && level != tt.expected {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, level, tt.expected)
+		}
+	}
+}
+
+func TestLoggerLevelsAndModules(t *testing.T) {
+	var sb strings.Builder
+	logger := New()
+
+	// Nothing is written until there is an output and a level
+	logger.Logf(ModuleApp, LevelError, "dropped")
+	logger.SetOutput(&sb)
+	logger.Logf(ModuleApp, LevelError, "dropped")
+	if sb.Len() != 0 {
+		t.Fatalf("Logger wrote before a level was set: %q", sb.String())
+	}
+
+	logger.SetLevel(LevelInfo)
+	logger.Module(ModuleSerial).Debugf("below level")
+	logger.Module(ModuleSerial).Infof("port opened at %d", 115200)
+	logger.Module(ModuleParser).Warnf("unknown sequence")
+
+	logger.SetModuleEnabled(ModuleParser, false)
+	logger.Module(ModuleParser).Errorf("muted")
+	if logger.Enabled(ModuleParser, LevelError) {
+		t.Error("Muted module reported as enabled")
+	}
+	if !logger.Enabled(ModuleSerial, LevelWarn) {
+		t.Error("Serial warnings should be enabled")
+	}
+
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", sb.String())
+	}
+	if !strings.Contains(lines[0], "INFO") || !strings.Contains(lines[0], "serial") || !strings.HasSuffix(lines[0], "port opened at 115200") {
+		t.Errorf("Unexpected line %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "WARN") || !strings.Contains(lines[1], "parser") {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+
+	// Turning the level off stops everything
+	sb.Reset()
+	logger.SetLevel(LevelOff)
+	logger.Module(ModuleApp).Errorf("dropped")
+	if sb.Len() != 0 {
+		t.Errorf("Logger wrote with level off: %q", sb.String())
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var sb strings.Builder
+	logger := New()
+	logger.SetOutput(&sb)
+	logger.SetLevel(LevelDebug)
+	logger.SetJSON(true)
+
+	logger.Module(ModuleRenderer).Debugf("frame took %dms", 3)
+
+	var entry map[string]string
+	if err := json.Unmarshal([]byte(sb.String()), &entry); err != nil {
+		t.Fatalf("Output is not JSON: %v (%q)", err, sb.String())
+	}
+	if entry["level"] != "debug" || entry["module"] != "renderer" || entry["msg"] != "frame took 3ms" || entry["time"] == "" {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+}
+
+func TestLoggerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	logger := New()
+	if err := logger.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	logger.SetLevel(LevelWarn)
+	logger.Module(ModuleApp).Warnf("written")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if logger.IsOpen() {
+		t.Error("Logger still open after Close")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if !strings.Contains(string(data), "written") {
+		t.Errorf("Log file = %q", data)
+	}
+
+	// Opened again, the earlier messages are kept
+	if err := logger.Open(path); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	logger.Module(ModuleApp).Errorf("appended")
+	_ = logger.Close()
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "written") || !strings.Contains(string(data), "appended") {
+		t.Errorf("Log file after reopening = %q", data)
+	}
+	if logger.Enabled(ModuleApp, LevelError) {
+		t.Error("Enabled with no output")
+	}
+}
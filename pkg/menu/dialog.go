@@ -0,0 +1,807 @@
+package menu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Dialog is a modal widget drawn on top of the screen that captures all
+// keyboard input while open
+type Dialog interface {
+	Draw()
+	HandleKey(ev *tcell.EventKey)
+	IsOpen() bool
+}
+
+// Dialog colors match the menu
+var (
+	dialogStyle   = tcell.StyleDefault.Background(tcell.ColorDarkBlue).Foreground(tcell.ColorWhite)
+	fieldStyle    = tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+	selectedStyle = tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+	errorStyle    = dialogStyle.Foreground(tcell.ColorYellow).Bold(true)
+	hintStyle     = dialogStyle.Foreground(tcell.ColorSilver)
+)
+
+// dialogBase holds what all dialogs share: the frame, open state and error line
+type dialogBase struct {
+	screen   tcell.Screen
+	title    string
+	open     bool
+	err      string // Shown at the bottom of the dialog, e.g. validation errors
+	onCancel func()
+}
+
+// IsOpen returns whether the dialog is still showing
+func (d *dialogBase) IsOpen() bool {
+	return d.open
+}
+
+// SetOnCancel sets the callback run when the dialog is dismissed with Esc
+func (d *dialogBase) SetOnCancel(callback func()) {
+	d.onCancel = callback
+}
+
+// cancel closes the dialog without submitting
+func (d *dialogBase) cancel() {
+	d.open = false
+	if d.onCancel != nil {
+		d.onCancel()
+	}
+}
+
+// submit runs the submit callback, keeping the dialog open with the error
+// shown if it fails
+func (d *dialogBase) submit(fn func() error) {
+	if err := fn(); err != nil {
+		d.err = err.Error()
+		return
+	}
+	d.open = false
+}
+
+// frame draws the dialog box centered on screen and returns the position
+// and size of its interior
+func (d *dialogBase) frame(width, height int) (x, y, innerWidth, innerHeight int) {
+	screenWidth, screenHeight := d.screen.Size()
+	if width > screenWidth {
+		width = screenWidth
+	}
+	if height > screenHeight {
+		height = screenHeight
+	}
+	left := (screenWidth - width) / 2
+	top := (screenHeight - height) / 2
+
+	for row := top; row < top+height; row++ {
+		for col := left; col < left+width; col++ {
+			ch := ' '
+			switch {
+			case row == top && col == left:
+				ch = '┌'
+			case row == top && col == left+width-1:
+				ch = '┐'
+			case row == top+height-1 && col == left:
+				ch = '└'
+			case row == top+height-1 && col == left+width-1:
+				ch = '┘'
+			case row == top || row == top+height-1:
+				ch = '─'
+			case col == left || col == left+width-1:
+				ch = '│'
+			}
+			d.screen.SetContent(col, row, ch, nil, dialogStyle)
+		}
+	}
+
+	if d.title != "" {
+		title := " " + d.title + " "
+		drawString(d.screen, left+(width-runewidth.StringWidth(title))/2, top, title, dialogStyle.Bold(true), width-2)
+	}
+
+	return left + 2, top + 1, width - 4, height - 2
+}
+
+// drawFooter draws the error message or the key hint on the last interior line
+func (d *dialogBase) drawFooter(x, y, width int, hint string) {
+	if d.err != "" {
+		drawString(d.screen, x, y, d.err, errorStyle, width)
+	} else {
+		drawString(d.screen, x, y, hint, hintStyle, width)
+	}
+}
+
+// drawString draws text clipped to maxWidth cells and returns the width used
+func drawString(screen tcell.Screen, x, y int, text string, style tcell.Style, maxWidth int) int {
+	used := 0
+	for _, ch := range text {
+		w := runewidth.RuneWidth(ch)
+		if used+w > maxWidth {
+			break
+		}
+		screen.SetContent(x+used, y, ch, nil, style)
+		used += w
+	}
+	return used
+}
+
+// textField is a single-line editable text field
+type textField struct {
+	value  []rune
+	cursor int
+}
+
+// newTextField creates a text field with the cursor at the end
+func newTextField(initial string) textField {
+	value := []rune(initial)
+	return textField{value: value, cursor: len(value)}
+}
+
+// String returns the field contents
+func (f *textField) String() string {
+	return string(f.value)
+}
+
+// set replaces the field contents
+func (f *textField) set(value string) {
+	f.value = []rune(value)
+	f.cursor = len(f.value)
+}
+
+// handleKey applies an editing key, returning false for keys it doesn't handle
+func (f *textField) handleKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		if f.cursor > 0 {
+			f.cursor--
+		}
+	case tcell.KeyRight:
+		if f.cursor < len(f.value) {
+			f.cursor++
+		}
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		f.cursor = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		f.cursor = len(f.value)
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if f.cursor > 0 {
+			f.value = append(f.value[:f.cursor-1], f.value[f.cursor:]...)
+			f.cursor--
+		}
+	case tcell.KeyDelete:
+		if f.cursor < len(f.value) {
+			f.value = append(f.value[:f.cursor], f.value[f.cursor+1:]...)
+		}
+	case tcell.KeyCtrlU:
+		f.value = f.value[:0]
+		f.cursor = 0
+	case tcell.KeyRune:
+		f.value = append(f.value[:f.cursor], append([]rune{ev.Rune()}, f.value[f.cursor:]...)...)
+		f.cursor++
+	default:
+		return false
+	}
+	return true
+}
+
+// draw renders the field, scrolling horizontally to keep the cursor visible
+func (f *textField) draw(screen tcell.Screen, x, y, width int, focused bool) {
+	for i := 0; i < width; i++ {
+		screen.SetContent(x+i, y, ' ', nil, fieldStyle)
+	}
+
+	start := 0
+	if f.cursor >= width {
+		start = f.cursor - width + 1
+	}
+	col := 0
+	for i := start; i < len(f.value) && col < width; i++ {
+		screen.SetContent(x+col, y, f.value[i], nil, fieldStyle)
+		col += runewidth.RuneWidth(f.value[i])
+	}
+
+	if focused {
+		cursorX := 0
+		for i := start; i < f.cursor; i++ {
+			cursorX += runewidth.RuneWidth(f.value[i])
+		}
+		screen.ShowCursor(x+cursorX, y)
+	}
+}
+
+// InputDialog asks for a single line of text
+type InputDialog struct {
+	dialogBase
+	label    string
+	field    textField
+	onSubmit func(value string) error
+}
+
+// NewInputDialog creates an open text input dialog. If onSubmit returns an
+// error the dialog stays open and shows it.
+func NewInputDialog(screen tcell.Screen, title, label, initial string, onSubmit func(value string) error) *InputDialog {
+	return &InputDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		label:      label,
+		field:      newTextField(initial),
+		onSubmit:   onSubmit,
+	}
+}
+
+// Value returns the current text
+func (d *InputDialog) Value() string {
+	return d.field.String()
+}
+
+// Draw renders the dialog
+func (d *InputDialog) Draw() {
+	x, y, width, _ := d.frame(60, 7)
+	drawString(d.screen, x, y+1, d.label, dialogStyle, width)
+	d.field.draw(d.screen, x, y+2, width, true)
+	d.drawFooter(x, y+4, width, "Enter: OK  Esc: Cancel")
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *InputDialog) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		d.cancel()
+	case tcell.KeyEnter:
+		d.submit(func() error { return d.onSubmit(d.field.String()) })
+	default:
+		if d.field.handleKey(ev) {
+			d.err = ""
+		}
+	}
+}
+
+// SpinnerDialog asks for a number within a range
+type SpinnerDialog struct {
+	dialogBase
+	label    string
+	field    textField
+	min, max int
+	step     int
+	onSubmit func(value int) error
+}
+
+// NewSpinnerDialog creates an open numeric input dialog. Up/Down change the
+// value by step, PageUp/PageDown by ten steps, and digits can be typed.
+func NewSpinnerDialog(screen tcell.Screen, title, label string, value, min, max, step int, onSubmit func(value int) error) *SpinnerDialog {
+	if step <= 0 {
+		step = 1
+	}
+	return &SpinnerDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		label:      label,
+		field:      newTextField(strconv.Itoa(value)),
+		min:        min,
+		max:        max,
+		step:       step,
+		onSubmit:   onSubmit,
+	}
+}
+
+// Value parses the current value, checking the allowed range
+func (d *SpinnerDialog) Value() (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(d.field.String()))
+	if err != nil {
+		return 0, fmt.Errorf("not a number: %q", d.field.String())
+	}
+	if value < d.min || value > d.max {
+		return value, fmt.Errorf("must be between %d and %d", d.min, d.max)
+	}
+	return value, nil
+}
+
+// adjust changes the value by delta, clamped to the range
+func (d *SpinnerDialog) adjust(delta int) {
+	value, _ := strconv.Atoi(strings.TrimSpace(d.field.String()))
+	value = max(d.min, min(d.max, value+delta))
+	d.field.set(strconv.Itoa(value))
+	d.err = ""
+}
+
+// Draw renders the dialog
+func (d *SpinnerDialog) Draw() {
+	x, y, width, _ := d.frame(50, 7)
+	drawString(d.screen, x, y+1, fmt.Sprintf("%s (%d-%d)", d.label, d.min, d.max), dialogStyle, width)
+	fieldWidth := min(width-4, 16)
+	d.field.draw(d.screen, x, y+2, fieldWidth, true)
+	drawString(d.screen, x+fieldWidth+1, y+2, "▲▼", dialogStyle, 2)
+	d.drawFooter(x, y+4, width, "↑/↓: Change  Enter: OK  Esc: Cancel")
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *SpinnerDialog) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		d.cancel()
+	case tcell.KeyEnter:
+		d.submit(func() error {
+			value, err := d.Value()
+			if err != nil {
+				return err
+			}
+			return d.onSubmit(value)
+		})
+	case tcell.KeyUp:
+		d.adjust(d.step)
+	case tcell.KeyDown:
+		d.adjust(-d.step)
+	case tcell.KeyPgUp:
+		d.adjust(d.step * 10)
+	case tcell.KeyPgDn:
+		d.adjust(-d.step * 10)
+	case tcell.KeyRune:
+		// Only digits and a leading minus sign make sense here
+		if unicode.IsDigit(ev.Rune()) || (ev.Rune() == '-' && d.min < 0) {
+			d.field.handleKey(ev)
+			d.err = ""
+		}
+	default:
+		if d.field.handleKey(ev) {
+			d.err = ""
+		}
+	}
+}
+
+// listView is a scrollable single-selection list
+type listView struct {
+	items    []string
+	selected int
+	offset   int
+}
+
+// handleKey moves the selection, returning false for keys it doesn't handle
+func (l *listView) handleKey(ev *tcell.EventKey, pageSize int) bool {
+	if len(l.items) == 0 {
+		return false
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		l.selected--
+	case tcell.KeyDown:
+		l.selected++
+	case tcell.KeyPgUp:
+		l.selected -= pageSize
+	case tcell.KeyPgDn:
+		l.selected += pageSize
+	case tcell.KeyHome:
+		l.selected = 0
+	case tcell.KeyEnd:
+		l.selected = len(l.items) - 1
+	case tcell.KeyRune:
+		// Jump to the next item starting with the typed letter
+		r := unicode.ToLower(ev.Rune())
+		for i := 1; i <= len(l.items); i++ {
+			idx := (l.selected + i) % len(l.items)
+			if first := []rune(l.items[idx]); len(first) > 0 && unicode.ToLower(first[0]) == r {
+				l.selected = idx
+				break
+			}
+		}
+	default:
+		return false
+	}
+	l.selected = max(0, min(len(l.items)-1, l.selected))
+	return true
+}
+
+// draw renders the visible part of the list
+func (l *listView) draw(screen tcell.Screen, x, y, width, height int) {
+	if l.selected < l.offset {
+		l.offset = l.selected
+	} else if l.selected >= l.offset+height {
+		l.offset = l.selected - height + 1
+	}
+
+	for row := 0; row < height; row++ {
+		idx := l.offset + row
+		style := dialogStyle
+		if idx == l.selected {
+			style = selectedStyle
+		}
+		for col := 0; col < width; col++ {
+			screen.SetContent(x+col, y+row, ' ', nil, style)
+		}
+		if idx < len(l.items) {
+			drawString(screen, x+1, y+row, l.items[idx], style, width-2)
+		}
+	}
+}
+
+// SelectDialog asks the user to pick one option from a list
+type SelectDialog struct {
+	dialogBase
+	list     listView
+	onSelect func(index int, option string) error
+}
+
+// NewSelectDialog creates an open dropdown-style selection dialog
+func NewSelectDialog(screen tcell.Screen, title string, options []string, selected int, onSelect func(index int, option string) error) *SelectDialog {
+	if selected < 0 || selected >= len(options) {
+		selected = 0
+	}
+	return &SelectDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		list:       listView{items: options, selected: selected},
+		onSelect:   onSelect,
+	}
+}
+
+// Selected returns the index of the highlighted option
+func (d *SelectDialog) Selected() int {
+	return d.list.selected
+}
+
+// Draw renders the dialog
+func (d *SelectDialog) Draw() {
+	width := runewidth.StringWidth(d.title) + 8
+	for _, option := range d.list.items {
+		width = max(width, runewidth.StringWidth(option)+8)
+	}
+	height := min(len(d.list.items), 12) + 4
+
+	x, y, innerWidth, innerHeight := d.frame(max(width, 40), height)
+	d.list.draw(d.screen, x, y, innerWidth, innerHeight-2)
+	d.drawFooter(x, y+innerHeight-1, innerWidth, "Enter: Select  Esc: Cancel")
+	d.screen.HideCursor()
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *SelectDialog) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		d.cancel()
+	case tcell.KeyEnter:
+		if len(d.list.items) == 0 {
+			d.cancel()
+			return
+		}
+		d.submit(func() error { return d.onSelect(d.list.selected, d.list.items[d.list.selected]) })
+	default:
+		if d.list.handleKey(ev, 10) {
+			d.err = ""
+		}
+	}
+}
+
+// FileDialogMode selects between picking an existing file and naming a new one
+type FileDialogMode int
+
+const (
+	FileDialogOpen FileDialogMode = iota // Pick an existing file
+	FileDialogSave                       // Pick a directory and type a file name
+)
+
+// FileDialog is a simple file browser
+type FileDialog struct {
+	dialogBase
+	mode      FileDialogMode
+	dir       string
+	list      listView
+	isDir     []bool
+	name      textField // File name, save mode only
+	nameFocus bool      // Whether the name field has focus instead of the list
+	onSubmit  func(path string) error
+}
+
+// NewFileDialog creates an open file browser starting at startPath. In save
+// mode the base name of startPath is used as the initial file name.
+func NewFileDialog(screen tcell.Screen, title string, mode FileDialogMode, startPath string, onSubmit func(path string) error) *FileDialog {
+	d := &FileDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		mode:       mode,
+		onSubmit:   onSubmit,
+	}
+
+	dir := startPath
+	if info, err := os.Stat(startPath); err != nil || !info.IsDir() {
+		dir = filepath.Dir(startPath)
+		if mode == FileDialogSave {
+			d.name = newTextField(filepath.Base(startPath))
+			d.nameFocus = true
+		}
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	d.chdir(dir)
+
+	return d
+}
+
+// Dir returns the directory being browsed
+func (d *FileDialog) Dir() string {
+	return d.dir
+}
+
+// chdir lists a directory, directories first. Hidden files are skipped.
+func (d *FileDialog) chdir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		d.err = fmt.Sprintf("cannot open %s: %v", dir, err)
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+
+	items := []string{"../"}
+	isDir := []bool{true}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if entry.IsDir() {
+			items = append(items, entry.Name()+"/")
+		} else {
+			items = append(items, entry.Name())
+		}
+		isDir = append(isDir, entry.IsDir())
+	}
+
+	d.dir = dir
+	d.list = listView{items: items}
+	d.isDir = isDir
+	d.err = ""
+}
+
+// activate opens the selected directory or picks the selected file
+func (d *FileDialog) activate() {
+	if len(d.list.items) == 0 {
+		return
+	}
+	item := d.list.items[d.list.selected]
+
+	if d.isDir[d.list.selected] {
+		if item == "../" {
+			d.chdir(filepath.Dir(d.dir))
+		} else {
+			d.chdir(filepath.Join(d.dir, strings.TrimSuffix(item, "/")))
+		}
+		return
+	}
+
+	if d.mode == FileDialogSave {
+		// Picking an existing file fills in the name; Enter again overwrites it
+		d.name.set(item)
+		d.nameFocus = true
+		return
+	}
+	d.submit(func() error { return d.onSubmit(filepath.Join(d.dir, item)) })
+}
+
+// submitName submits the typed file name relative to the current directory
+func (d *FileDialog) submitName() {
+	name := strings.TrimSpace(d.name.String())
+	if name == "" {
+		d.err = "enter a file name"
+		return
+	}
+	path := name
+	if !filepath.IsAbs(name) {
+		path = filepath.Join(d.dir, name)
+	}
+	d.submit(func() error { return d.onSubmit(path) })
+}
+
+// Draw renders the dialog
+func (d *FileDialog) Draw() {
+	x, y, width, height := d.frame(70, 20)
+
+	drawString(d.screen, x, y, d.dir, dialogStyle.Bold(true), width)
+
+	listHeight := height - 3
+	if d.mode == FileDialogSave {
+		listHeight -= 2
+	}
+	d.list.draw(d.screen, x, y+1, width, listHeight)
+
+	hint := "Enter: Open  Backspace: Up  Esc: Cancel"
+	if d.mode == FileDialogSave {
+		used := drawString(d.screen, x, y+listHeight+2, "Name: ", dialogStyle, width)
+		d.name.draw(d.screen, x+used, y+listHeight+2, width-used, d.nameFocus)
+		hint = "Tab: Switch  Enter: Save  Esc: Cancel"
+	}
+	if !d.nameFocus {
+		d.screen.HideCursor()
+	}
+
+	d.drawFooter(x, y+height-1, width, hint)
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *FileDialog) HandleKey(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyEscape {
+		d.cancel()
+		return
+	}
+
+	if d.mode == FileDialogSave && (ev.Key() == tcell.KeyTab || ev.Key() == tcell.KeyBacktab) {
+		d.nameFocus = !d.nameFocus
+		return
+	}
+
+	if d.nameFocus {
+		if ev.Key() == tcell.KeyEnter {
+			d.submitName()
+		} else if d.name.handleKey(ev) {
+			d.err = ""
+		}
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		d.activate()
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyLeft:
+		d.chdir(filepath.Dir(d.dir))
+	default:
+		d.list.handleKey(ev, 10)
+	}
+}
+
+// TextDialog shows read-only text that can be scrolled, such as help.
+// Lines that don't start with a space are drawn bold as section headings.
+type TextDialog struct {
+	dialogBase
+	lines  []string
+	offset int
+	height int // Visible lines at the last draw, used for paging
+}
+
+// NewTextDialog creates an open scrollable text viewer
+func NewTextDialog(screen tcell.Screen, title string, lines []string) *TextDialog {
+	return &TextDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		lines:      lines,
+		height:     1,
+	}
+}
+
+// Offset returns the index of the first visible line
+func (d *TextDialog) Offset() int {
+	return d.offset
+}
+
+// scroll moves the view by delta lines, clamped to the text
+func (d *TextDialog) scroll(delta int) {
+	d.offset = max(0, min(len(d.lines)-d.height, d.offset+delta))
+}
+
+// Draw renders the dialog
+func (d *TextDialog) Draw() {
+	width := runewidth.StringWidth(d.title) + 8
+	for _, line := range d.lines {
+		width = max(width, runewidth.StringWidth(line)+6)
+	}
+	_, screenHeight := d.screen.Size()
+
+	x, y, innerWidth, innerHeight := d.frame(max(width, 40), min(len(d.lines)+4, screenHeight-2))
+	d.height = max(1, innerHeight-2)
+	d.scroll(0)
+
+	for row := 0; row < d.height; row++ {
+		idx := d.offset + row
+		if idx >= len(d.lines) {
+			break
+		}
+		style := dialogStyle
+		if line := d.lines[idx]; line != "" && line[0] != ' ' {
+			style = style.Bold(true)
+		}
+		drawString(d.screen, x, y+row, d.lines[idx], style, innerWidth)
+	}
+
+	hint := "Esc: Close"
+	if len(d.lines) > d.height {
+		hint = fmt.Sprintf("↑/↓ PgUp/PgDn: Scroll (%d/%d)  Esc: Close", d.offset+1, len(d.lines)-d.height+1)
+	}
+	d.drawFooter(x, y+innerHeight-1, innerWidth, hint)
+	d.screen.HideCursor()
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *TextDialog) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape, tcell.KeyEnter:
+		d.cancel()
+	case tcell.KeyUp:
+		d.scroll(-1)
+	case tcell.KeyDown:
+		d.scroll(1)
+	case tcell.KeyPgUp:
+		d.scroll(-d.height)
+	case tcell.KeyPgDn:
+		d.scroll(d.height)
+	case tcell.KeyHome:
+		d.offset = 0
+	case tcell.KeyEnd:
+		d.scroll(len(d.lines))
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q', 'Q':
+			d.cancel()
+		case 'k':
+			d.scroll(-1)
+		case 'j':
+			d.scroll(1)
+		case ' ':
+			d.scroll(d.height)
+		}
+	}
+}
+
+// ConfirmDialog shows text and asks whether to go ahead, such as a preview
+// of data about to be sent
+type ConfirmDialog struct {
+	dialogBase
+	lines     []string
+	action    string // Label of the confirming key, e.g. "Send"
+	onConfirm func() error
+}
+
+// NewConfirmDialog creates an open confirmation dialog. Enter or y runs
+// onConfirm; Esc or n cancels. If onConfirm returns an error the dialog
+// stays open and shows it.
+func NewConfirmDialog(screen tcell.Screen, title string, lines []string, action string, onConfirm func() error) *ConfirmDialog {
+	return &ConfirmDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		lines:      lines,
+		action:     action,
+		onConfirm:  onConfirm,
+	}
+}
+
+// Draw renders the dialog. Lines that don't fit are cut off.
+func (d *ConfirmDialog) Draw() {
+	width := runewidth.StringWidth(d.title) + 8
+	for _, line := range d.lines {
+		width = max(width, runewidth.StringWidth(line)+6)
+	}
+	_, screenHeight := d.screen.Size()
+
+	x, y, innerWidth, innerHeight := d.frame(max(width, 40), min(len(d.lines)+4, screenHeight-2))
+	for row := 0; row < min(len(d.lines), innerHeight-2); row++ {
+		drawString(d.screen, x, y+row, d.lines[row], dialogStyle, innerWidth)
+	}
+	d.drawFooter(x, y+innerHeight-1, innerWidth, fmt.Sprintf("Enter/Y: %s  Esc/N: Cancel", d.action))
+	d.screen.HideCursor()
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *ConfirmDialog) HandleKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		d.cancel()
+	case tcell.KeyEnter:
+		d.submit(d.onConfirm)
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'y', 'Y':
+			d.submit(d.onConfirm)
+		case 'n', 'N':
+			d.cancel()
+		}
+	}
+}
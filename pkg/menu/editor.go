@@ -0,0 +1,278 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// textArea is a multi-line editable text buffer
+type textArea struct {
+	lines    [][]rune
+	row, col int // Cursor position
+	top      int // First visible line
+	left     int // First visible rune, for lines wider than the area
+}
+
+// newTextArea creates a text area holding text, with the cursor at the start
+func newTextArea(text string) textArea {
+	var lines [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, []rune(line))
+	}
+	return textArea{lines: lines}
+}
+
+// String returns the text with lines joined by "\n"
+func (a *textArea) String() string {
+	lines := make([]string, len(a.lines))
+	for i, line := range a.lines {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// moveTo places the cursor, clamped to the text
+func (a *textArea) moveTo(row, col int) {
+	a.row = max(0, min(len(a.lines)-1, row))
+	a.col = max(0, min(len(a.lines[a.row]), col))
+}
+
+// insert adds a rune at the cursor
+func (a *textArea) insert(r rune) {
+	line := a.lines[a.row]
+	a.lines[a.row] = append(line[:a.col], append([]rune{r}, line[a.col:]...)...)
+	a.col++
+}
+
+// splitLine breaks the line at the cursor
+func (a *textArea) splitLine() {
+	line := a.lines[a.row]
+	rest := append([]rune(nil), line[a.col:]...)
+	a.lines[a.row] = line[:a.col]
+	a.lines = append(a.lines[:a.row+1], append([][]rune{rest}, a.lines[a.row+1:]...)...)
+	a.row++
+	a.col = 0
+}
+
+// joinNext appends the next line to the cursor's line
+func (a *textArea) joinNext() {
+	if a.row+1 >= len(a.lines) {
+		return
+	}
+	a.lines[a.row] = append(a.lines[a.row], a.lines[a.row+1]...)
+	a.lines = append(a.lines[:a.row+1], a.lines[a.row+2:]...)
+}
+
+// deleteLine removes the cursor's line, leaving one empty line at least
+func (a *textArea) deleteLine() {
+	if len(a.lines) == 1 {
+		a.lines[0] = a.lines[0][:0]
+	} else {
+		a.lines = append(a.lines[:a.row], a.lines[a.row+1:]...)
+	}
+	a.moveTo(a.row, a.col)
+}
+
+// handleKey applies an editing key, returning false for keys it doesn't
+// handle. pageSize is the number of visible lines.
+func (a *textArea) handleKey(ev *tcell.EventKey, pageSize int) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		a.moveTo(a.row-1, a.col)
+	case tcell.KeyDown:
+		a.moveTo(a.row+1, a.col)
+	case tcell.KeyLeft:
+		if a.col > 0 {
+			a.col--
+		} else if a.row > 0 {
+			a.moveTo(a.row-1, len(a.lines[a.row-1]))
+		}
+	case tcell.KeyRight:
+		if a.col < len(a.lines[a.row]) {
+			a.col++
+		} else if a.row+1 < len(a.lines) {
+			a.moveTo(a.row+1, 0)
+		}
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		a.col = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		a.col = len(a.lines[a.row])
+	case tcell.KeyPgUp:
+		a.moveTo(a.row-pageSize, a.col)
+	case tcell.KeyPgDn:
+		a.moveTo(a.row+pageSize, a.col)
+	case tcell.KeyEnter:
+		a.splitLine()
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if a.col > 0 {
+			line := a.lines[a.row]
+			a.lines[a.row] = append(line[:a.col-1], line[a.col:]...)
+			a.col--
+		} else if a.row > 0 {
+			a.moveTo(a.row-1, len(a.lines[a.row-1]))
+			a.joinNext()
+		}
+	case tcell.KeyDelete:
+		if line := a.lines[a.row]; a.col < len(line) {
+			a.lines[a.row] = append(line[:a.col], line[a.col+1:]...)
+		} else {
+			a.joinNext()
+		}
+	case tcell.KeyCtrlK:
+		a.deleteLine()
+	case tcell.KeyTab:
+		a.insert('\t')
+	case tcell.KeyRune:
+		a.insert(ev.Rune())
+	default:
+		return false
+	}
+	return true
+}
+
+// draw renders the visible part of the text, scrolling to keep the cursor
+// in view. Control characters are shown as control pictures.
+func (a *textArea) draw(screen tcell.Screen, x, y, width, height int) {
+	if a.row < a.top {
+		a.top = a.row
+	} else if a.row >= a.top+height {
+		a.top = a.row - height + 1
+	}
+	if a.col < a.left {
+		a.left = a.col
+	}
+	for runewidth.StringWidth(string(a.lines[a.row][a.left:a.col])) >= width {
+		a.left++
+	}
+
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			screen.SetContent(x+col, y+row, ' ', nil, fieldStyle)
+		}
+		idx := a.top + row
+		if idx >= len(a.lines) {
+			continue
+		}
+		line := a.lines[idx]
+		if a.left >= len(line) {
+			continue
+		}
+		col := 0
+		for _, r := range line[a.left:] {
+			if r < 0x20 || r == 0x7F {
+				r = controlPicture(r)
+			}
+			w := runewidth.RuneWidth(r)
+			if col+w > width {
+				break
+			}
+			screen.SetContent(x+col, y+row, r, nil, fieldStyle)
+			col += w
+		}
+	}
+
+	cursorX := runewidth.StringWidth(string(a.lines[a.row][a.left:a.col]))
+	screen.ShowCursor(x+cursorX, y+a.row-a.top)
+}
+
+// controlPicture returns the Unicode control picture for a control character
+func controlPicture(r rune) rune {
+	if r == 0x7F {
+		return '␡'
+	}
+	return 0x2400 + r
+}
+
+// EditorOption is a setting shown below an EditorDialog's text, such as the
+// line ending to send. Its function key steps through the values.
+type EditorOption struct {
+	Label    string
+	Values   []string
+	Selected int
+}
+
+// EditorDialog edits multi-line text before it is used, such as a paste
+// about to be sent. Options are changed with F2, F3 and so on.
+type EditorDialog struct {
+	dialogBase
+	header   string
+	area     textArea
+	options  []EditorOption
+	action   string // Label of the submitting key, e.g. "Send"
+	height   int    // Visible text lines at the last draw, used for paging
+	onSubmit func(text string, options []int) error
+}
+
+// NewEditorDialog creates an open text editor. Ctrl+S submits the text,
+// with lines joined by "\n", and the selected value of each option. If
+// onSubmit returns an error the dialog stays open and shows it.
+func NewEditorDialog(screen tcell.Screen, title, header, text string, options []EditorOption, action string, onSubmit func(text string, options []int) error) *EditorDialog {
+	return &EditorDialog{
+		dialogBase: dialogBase{screen: screen, title: title, open: true},
+		header:     header,
+		area:       newTextArea(text),
+		options:    options,
+		action:     action,
+		height:     1,
+		onSubmit:   onSubmit,
+	}
+}
+
+// Text returns the edited text with lines joined by "\n"
+func (d *EditorDialog) Text() string {
+	return d.area.String()
+}
+
+// Selected returns the selected value index of each option
+func (d *EditorDialog) Selected() []int {
+	selected := make([]int, len(d.options))
+	for i, option := range d.options {
+		selected[i] = option.Selected
+	}
+	return selected
+}
+
+// Draw renders the dialog
+func (d *EditorDialog) Draw() {
+	screenWidth, screenHeight := d.screen.Size()
+	x, y, width, height := d.frame(min(screenWidth-2, 100), screenHeight-2)
+
+	drawString(d.screen, x, y, d.header, dialogStyle, width)
+	d.height = max(1, height-4)
+	d.area.draw(d.screen, x, y+1, width, d.height)
+
+	col := 0
+	for i, option := range d.options {
+		text := fmt.Sprintf("F%d %s: %s", i+2, option.Label, option.Values[option.Selected])
+		col += drawString(d.screen, x+col, y+height-2, text, dialogStyle, width-col) + 3
+		if col >= width {
+			break
+		}
+	}
+
+	d.drawFooter(x, y+height-1, width, fmt.Sprintf("Ctrl+S: %s  Ctrl+K: Delete line  Esc: Cancel  (line %d/%d)", d.action, d.area.row+1, len(d.area.lines)))
+	d.screen.Show()
+}
+
+// HandleKey processes keyboard input
+func (d *EditorDialog) HandleKey(ev *tcell.EventKey) {
+	if i := int(ev.Key() - tcell.KeyF2); i >= 0 && i < len(d.options) {
+		option := &d.options[i]
+		option.Selected = (option.Selected + 1) % len(option.Values)
+		return
+	}
+
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		d.cancel()
+	case tcell.KeyCtrlS:
+		d.submit(func() error { return d.onSubmit(d.Text(), d.Selected()) })
+	default:
+		if d.area.handleKey(ev, d.height) {
+			d.err = ""
+		}
+	}
+}
@@ -19,8 +19,12 @@ type Menu struct {
 	parent   *Menu
 	title    string
 
+	// Submenu currently open on top of this menu
+	openChild *Menu
+
 	// Callbacks
-	onClose func()
+	onClose        func()
+	onSubmenuClose func()
 }
 
 // MenuItem represents a single menu item
@@ -31,6 +35,9 @@ type MenuItem struct {
 	Submenu   *Menu
 	Enabled   bool
 	Separator bool
+	Checkable bool   // Item shows a check mark that toggles when activated
+	Checked   bool   // Current check state for checkable items
+	Group     string // Radio group name; checking one item unchecks the others
 }
 
 // NewMenu creates a new menu
@@ -76,9 +83,42 @@ func (m *Menu) AddSubmenu(label string, submenu *Menu) {
 	m.updateDimensions()
 }
 
+// AddCheckItem adds a checkable item. onToggle receives the new state;
+// if it returns an error the check mark is reverted.
+func (m *Menu) AddCheckItem(label, shortcut string, checked bool, onToggle func(checked bool) error) {
+	index := len(m.items)
+	m.items = append(m.items, MenuItem{
+		Label:     label,
+		Shortcut:  shortcut,
+		Enabled:   true,
+		Checkable: true,
+		Checked:   checked,
+	})
+	m.items[index].Action = func() error {
+		return onToggle(m.items[index].Checked)
+	}
+	m.updateDimensions()
+}
+
+// AddRadioItem adds an item to a radio group. Only one item per group is
+// checked; onSelect runs when the item becomes checked and the previous
+// selection is restored if it returns an error.
+func (m *Menu) AddRadioItem(group, label string, checked bool, onSelect func() error) {
+	m.items = append(m.items, MenuItem{
+		Label:     label,
+		Action:    onSelect,
+		Enabled:   true,
+		Checkable: true,
+		Checked:   checked,
+		Group:     group,
+	})
+	m.updateDimensions()
+}
+
 // Show displays the menu
 func (m *Menu) Show() {
 	m.visible = true
+	m.openChild = nil
 	// Center the menu on screen
 	screenWidth, screenHeight := m.screen.Size()
 	m.x = (screenWidth - m.width) / 2
@@ -86,12 +126,74 @@ func (m *Menu) Show() {
 	m.Draw()
 }
 
-// Hide hides the menu
+// showSubmenu opens the submenu of the item at index next to that item
+func (m *Menu) showSubmenu(index int) {
+	sub := m.items[index].Submenu
+	screenWidth, screenHeight := m.screen.Size()
+
+	sub.visible = true
+	sub.openChild = nil
+	sub.x = m.x + m.width - 1
+	if sub.x+sub.width > screenWidth {
+		// Not enough room on the right, open to the left instead
+		sub.x = m.x - sub.width + 1
+	}
+	if sub.x < 0 {
+		sub.x = 0
+	}
+	sub.y = m.itemY(index) - 1
+	if sub.y+sub.height > screenHeight {
+		sub.y = screenHeight - sub.height
+	}
+	if sub.y < 0 {
+		sub.y = 0
+	}
+
+	m.openChild = sub
+	sub.Draw()
+}
+
+// Hide hides the menu and any open submenus
 func (m *Menu) Hide() {
+	if m.openChild != nil {
+		// Detach first so the child doesn't redraw us while we close
+		child := m.openChild
+		m.openChild = nil
+		child.Hide()
+	}
+
 	m.visible = false
 	if m.onClose != nil {
 		m.onClose()
 	}
+
+	// Closing a submenu returns to its parent
+	if m.parent != nil && m.parent.openChild == m {
+		m.parent.openChild = nil
+		if root := m.root(); root.visible {
+			if root.onSubmenuClose != nil {
+				root.onSubmenuClose()
+			}
+			root.Draw()
+		}
+	}
+}
+
+// root returns the top level menu
+func (m *Menu) root() *Menu {
+	for m.parent != nil {
+		m = m.parent
+	}
+	return m
+}
+
+// itemY returns the screen row of the item at index
+func (m *Menu) itemY(index int) int {
+	y := m.y + 1
+	if m.title != "" {
+		y += 2
+	}
+	return y + index
 }
 
 // IsVisible returns whether the menu is visible
@@ -152,6 +254,9 @@ func (m *Menu) Draw() {
 			if item.Submenu != nil {
 				label = label + " >"
 			}
+			if item.Checkable {
+				label = checkMark(item) + " " + label
+			}
 			m.drawText(m.x+2, itemY, label, itemStyle)
 
 			// Draw shortcut if present
@@ -164,6 +269,25 @@ func (m *Menu) Draw() {
 	}
 
 	m.screen.Show()
+
+	// Open submenus are drawn on top of their parent
+	if m.openChild != nil {
+		m.openChild.Draw()
+	}
+}
+
+// checkMark returns the check indicator for a checkable item
+func checkMark(item MenuItem) string {
+	switch {
+	case item.Group != "" && item.Checked:
+		return "(•)"
+	case item.Group != "":
+		return "( )"
+	case item.Checked:
+		return "[x]"
+	default:
+		return "[ ]"
+	}
 }
 
 // HandleKey processes keyboard input
@@ -172,6 +296,11 @@ func (m *Menu) HandleKey(ev *tcell.EventKey) bool {
 		return false
 	}
 
+	// An open submenu gets the keys first
+	if m.openChild != nil && m.openChild.visible {
+		return m.openChild.HandleKey(ev)
+	}
+
 	switch ev.Key() {
 	case tcell.KeyEscape:
 		m.Hide()
@@ -195,7 +324,7 @@ func (m *Menu) HandleKey(ev *tcell.EventKey) bool {
 		if m.selected >= 0 && m.selected < len(m.items) {
 			item := m.items[m.selected]
 			if item.Submenu != nil && item.Enabled {
-				item.Submenu.Show()
+				m.showSubmenu(m.selected)
 				return true
 			}
 		}
@@ -268,10 +397,14 @@ func (m *Menu) activateSelected() bool {
 	}
 
 	if item.Submenu != nil {
-		item.Submenu.Show()
+		m.showSubmenu(m.selected)
 		return true
 	}
 
+	if item.Checkable {
+		return m.activateCheckable(m.selected)
+	}
+
 	if item.Action != nil {
 		err := item.Action()
 		if err != nil {
@@ -284,6 +417,61 @@ func (m *Menu) activateSelected() bool {
 	return false
 }
 
+// activateCheckable toggles a checkbox or selects a radio item, reverting
+// the change if the item's action fails
+func (m *Menu) activateCheckable(index int) bool {
+	item := m.items[index]
+	if item.Group != "" && item.Checked {
+		// Selecting the current radio item changes nothing
+		return true
+	}
+
+	previous := make([]bool, len(m.items))
+	for i := range m.items {
+		previous[i] = m.items[i].Checked
+	}
+	m.SetChecked(index, !item.Checked)
+
+	if item.Action != nil {
+		if err := item.Action(); err != nil {
+			for i := range m.items {
+				m.items[i].Checked = previous[i]
+			}
+			fmt.Printf("Menu action error: %v\n", err)
+		}
+	}
+
+	if m.visible {
+		m.Draw()
+	}
+	return true
+}
+
+// SetChecked sets the check state of a checkable item. Checking a radio item
+// unchecks the rest of its group.
+func (m *Menu) SetChecked(index int, checked bool) {
+	if index < 0 || index >= len(m.items) || !m.items[index].Checkable {
+		return
+	}
+
+	if group := m.items[index].Group; group != "" && checked {
+		for i := range m.items {
+			if m.items[i].Group == group {
+				m.items[i].Checked = false
+			}
+		}
+	}
+	m.items[index].Checked = checked
+}
+
+// IsChecked returns the check state of an item
+func (m *Menu) IsChecked(index int) bool {
+	if index < 0 || index >= len(m.items) {
+		return false
+	}
+	return m.items[index].Checked
+}
+
 // drawBorder draws the menu border
 func (m *Menu) drawBorder() {
 	style := tcell.StyleDefault.Background(tcell.ColorDarkBlue).Foreground(tcell.ColorWhite)
@@ -330,6 +518,9 @@ func (m *Menu) updateDimensions() {
 			if item.Submenu != nil {
 				width += 2 // Space for submenu indicator
 			}
+			if item.Checkable {
+				width += 4 // Space for check mark
+			}
 			if width > maxWidth {
 				maxWidth = width
 			}
@@ -348,6 +539,12 @@ func (m *Menu) SetOnClose(callback func()) {
 	m.onClose = callback
 }
 
+// SetOnSubmenuClose sets the callback run when a submenu closes, before the
+// menu is redrawn, so the caller can restore what the submenu covered
+func (m *Menu) SetOnSubmenuClose(callback func()) {
+	m.onSubmenuClose = callback
+}
+
 // EnableItem enables or disables a menu item
 func (m *Menu) EnableItem(index int, enabled bool) {
 	if index >= 0 && index < len(m.items) {
@@ -357,6 +554,7 @@ func (m *Menu) EnableItem(index int, enabled bool) {
 
 // Clear removes all menu items
 func (m *Menu) Clear() {
+	m.openChild = nil
 	m.items = []MenuItem{}
 	m.selected = 0
 	m.updateDimensions()
@@ -0,0 +1,347 @@
+package menu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newTestScreen(t *testing.T) tcell.Screen {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Failed to init simulation screen: %v", err)
+	}
+	screen.SetSize(80, 25)
+	t.Cleanup(screen.Fini)
+	return screen
+}
+
+func key(k tcell.Key) *tcell.EventKey {
+	return tcell.NewEventKey(k, 0, tcell.ModNone)
+}
+
+func TestCheckItems(t *testing.T) {
+	m := NewMenu("Test", newTestScreen(t))
+
+	var toggled []bool
+	m.AddCheckItem("Line Wrap", "", true, func(checked bool) error {
+		toggled = append(toggled, checked)
+		return nil
+	})
+	m.AddCheckItem("Failing", "", false, func(checked bool) error {
+		return fmt.Errorf("refused")
+	})
+	m.Show()
+
+	// Enter toggles the check mark and reports the new state
+	m.HandleKey(key(tcell.KeyEnter))
+	m.HandleKey(key(tcell.KeyEnter))
+	if len(toggled) != 2 || toggled[0] != false || toggled[1] != true {
+		t.Errorf("Expected toggles [false true], got %v", toggled)
+	}
+	if !m.IsChecked(0) {
+		t.Error("Expected item to be checked after two toggles")
+	}
+
+	// A failing action reverts the check mark
+	m.HandleKey(key(tcell.KeyDown))
+	m.HandleKey(key(tcell.KeyEnter))
+	if m.IsChecked(1) {
+		t.Error("Expected failed toggle to be reverted")
+	}
+}
+
+func TestRadioGroupInSubmenu(t *testing.T) {
+	screen := newTestScreen(t)
+	root := NewMenu("Main", screen)
+	sub := NewMenu("Baud Rate", screen)
+
+	selected := 0
+	for _, rate := range []int{9600, 115200, 230400} {
+		sub.AddRadioItem("baud", fmt.Sprint(rate), rate == 115200, func() error {
+			if rate == 230400 {
+				return fmt.Errorf("unsupported")
+			}
+			selected = rate
+			return nil
+		})
+	}
+	root.AddItem("Other", "", func() error { return nil })
+	root.AddSubmenu("Baud Rate", sub)
+
+	closed := 0
+	root.SetOnSubmenuClose(func() { closed++ })
+	root.Show()
+
+	// Navigate into the submenu
+	root.HandleKey(key(tcell.KeyDown))
+	root.HandleKey(key(tcell.KeyRight))
+	if !sub.IsVisible() {
+		t.Fatal("Expected submenu to open")
+	}
+
+	// Keys now go to the submenu; selecting 9600 unchecks 115200
+	root.HandleKey(key(tcell.KeyEnter))
+	if selected != 9600 || !sub.IsChecked(0) || sub.IsChecked(1) {
+		t.Errorf("Expected 9600 selected, got %d (checked %v %v)", selected, sub.IsChecked(0), sub.IsChecked(1))
+	}
+
+	// A failing selection keeps the previous choice
+	root.HandleKey(key(tcell.KeyDown))
+	root.HandleKey(key(tcell.KeyDown))
+	root.HandleKey(key(tcell.KeyEnter))
+	if !sub.IsChecked(0) || sub.IsChecked(2) {
+		t.Error("Expected failed radio selection to be reverted")
+	}
+
+	// Left returns to the parent without closing it
+	root.HandleKey(key(tcell.KeyLeft))
+	if sub.IsVisible() || !root.IsVisible() {
+		t.Error("Expected Left to close only the submenu")
+	}
+	if closed != 1 {
+		t.Errorf("Expected submenu close callback once, got %d", closed)
+	}
+
+	// Hiding the root closes open submenus too
+	root.HandleKey(key(tcell.KeyRight))
+	root.Hide()
+	if sub.IsVisible() {
+		t.Error("Expected submenu to close with its parent")
+	}
+}
+
+func typeText(d Dialog, text string) {
+	for _, r := range text {
+		d.HandleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+func TestInputDialog(t *testing.T) {
+	var submitted string
+	d := NewInputDialog(newTestScreen(t), "Save", "File name:", "log", func(value string) error {
+		if value == "bad" {
+			return fmt.Errorf("invalid name")
+		}
+		submitted = value
+		return nil
+	})
+	d.Draw()
+
+	// Errors keep the dialog open
+	d.HandleKey(key(tcell.KeyCtrlU))
+	typeText(d, "bad")
+	d.HandleKey(key(tcell.KeyEnter))
+	if !d.IsOpen() || d.err != "invalid name" {
+		t.Errorf("Expected dialog to stay open with error, open=%v err=%q", d.IsOpen(), d.err)
+	}
+
+	// Cursor editing inserts in the middle
+	d.HandleKey(key(tcell.KeyHome))
+	typeText(d, "not")
+	d.HandleKey(key(tcell.KeyEnter))
+	if d.IsOpen() || submitted != "notbad" {
+		t.Errorf("Expected submit of %q, got %q (open=%v)", "notbad", submitted, d.IsOpen())
+	}
+}
+
+func TestSpinnerDialog(t *testing.T) {
+	var submitted int
+	d := NewSpinnerDialog(newTestScreen(t), "Baud", "Rate", 100, 50, 1000, 100, func(value int) error {
+		submitted = value
+		return nil
+	})
+
+	d.HandleKey(key(tcell.KeyUp))
+	d.HandleKey(key(tcell.KeyPgUp)) // Clamped to max
+	if v, _ := d.Value(); v != 1000 {
+		t.Errorf("Expected value clamped to 1000, got %d", v)
+	}
+
+	// Non-digits are ignored, out of range values are rejected
+	d.HandleKey(key(tcell.KeyCtrlU))
+	typeText(d, "2x0")
+	d.HandleKey(key(tcell.KeyEnter))
+	if !d.IsOpen() {
+		t.Error("Expected out of range value to keep dialog open")
+	}
+
+	d.HandleKey(key(tcell.KeyCtrlU))
+	typeText(d, "300")
+	d.HandleKey(key(tcell.KeyEnter))
+	if d.IsOpen() || submitted != 300 {
+		t.Errorf("Expected submit of 300, got %d", submitted)
+	}
+}
+
+func TestSelectDialog(t *testing.T) {
+	chosen := ""
+	d := NewSelectDialog(newTestScreen(t), "Format", []string{"Plain text", "Timestamped", "JSON"}, 0, func(index int, option string) error {
+		chosen = option
+		return nil
+	})
+	d.Draw()
+
+	typeText(d, "j")
+	if d.Selected() != 2 {
+		t.Errorf("Expected letter jump to JSON, got %d", d.Selected())
+	}
+	d.HandleKey(key(tcell.KeyEnter))
+	if chosen != "JSON" || d.IsOpen() {
+		t.Errorf("Expected JSON selected and dialog closed, got %q", chosen)
+	}
+}
+
+func TestConfirmDialog(t *testing.T) {
+	confirmed := 0
+	d := NewConfirmDialog(newTestScreen(t), "Confirm Paste", []string{"  line 1", "  line 2"}, "Send", func() error {
+		confirmed++
+		return nil
+	})
+	d.Draw()
+
+	typeText(d, "x")
+	if !d.IsOpen() || confirmed != 0 {
+		t.Error("Other keys should neither confirm nor cancel")
+	}
+	typeText(d, "y")
+	if d.IsOpen() || confirmed != 1 {
+		t.Errorf("Expected y to confirm and close, confirmed %d times", confirmed)
+	}
+
+	cancelled := false
+	d = NewConfirmDialog(newTestScreen(t), "Confirm Paste", nil, "Send", func() error {
+		t.Error("Cancelled dialog should not confirm")
+		return nil
+	})
+	d.SetOnCancel(func() { cancelled = true })
+	d.HandleKey(key(tcell.KeyEscape))
+	if d.IsOpen() || !cancelled {
+		t.Error("Expected Esc to cancel")
+	}
+}
+
+func TestEditorDialog(t *testing.T) {
+	var sent string
+	var selected []int
+	options := []EditorOption{
+		{Label: "Line ending", Values: []string{"CR", "LF", "CRLF"}},
+		{Label: "Line delay", Values: []string{"0 ms", "50 ms"}},
+	}
+	d := NewEditorDialog(newTestScreen(t), "Edit Paste", "3 lines", "ls\nrm -rf /\npwd", options, "Send", func(text string, opts []int) error {
+		sent, selected = text, opts
+		return nil
+	})
+	d.Draw()
+
+	// Drop the second line, change the first and split the last
+	d.HandleKey(key(tcell.KeyDown))
+	d.HandleKey(key(tcell.KeyCtrlK))
+	d.HandleKey(key(tcell.KeyUp))
+	d.HandleKey(key(tcell.KeyEnd))
+	typeText(d, " -l")
+	d.HandleKey(key(tcell.KeyDown))
+	d.HandleKey(key(tcell.KeyEnd))
+	d.HandleKey(key(tcell.KeyEnter))
+	d.HandleKey(key(tcell.KeyBackspace2))
+	d.HandleKey(key(tcell.KeyEnter))
+	typeText(d, "date")
+	if got := d.Text(); got != "ls -l\npwd\ndate" {
+		t.Errorf("Text = %q", got)
+	}
+
+	d.HandleKey(key(tcell.KeyF2))
+	d.HandleKey(key(tcell.KeyF2))
+	d.HandleKey(key(tcell.KeyF3))
+	d.Draw()
+	d.HandleKey(key(tcell.KeyCtrlS))
+	if d.IsOpen() || sent != "ls -l\npwd\ndate" {
+		t.Errorf("Expected Ctrl+S to submit the text, got %q", sent)
+	}
+	if len(selected) != 2 || selected[0] != 2 || selected[1] != 1 {
+		t.Errorf("Selected options = %v, want [2 1]", selected)
+	}
+
+	// Backspace at the start of a line joins it to the one above
+	d = NewEditorDialog(newTestScreen(t), "Edit Paste", "", "ab\ncd", nil, "Send", nil)
+	d.HandleKey(key(tcell.KeyDown))
+	d.HandleKey(key(tcell.KeyBackspace2))
+	if got := d.Text(); got != "abcd" {
+		t.Errorf("Text after join = %q", got)
+	}
+}
+
+func TestFileDialog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logs", "boot.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open mode: navigate into a directory and pick a file
+	picked := ""
+	d := NewFileDialog(newTestScreen(t), "Send File", FileDialogOpen, dir, func(path string) error {
+		picked = path
+		return nil
+	})
+	d.Draw()
+	d.HandleKey(key(tcell.KeyDown)) // "logs/" after "../"
+	d.HandleKey(key(tcell.KeyEnter))
+	if d.Dir() != filepath.Join(dir, "logs") {
+		t.Fatalf("Expected to enter logs directory, in %s", d.Dir())
+	}
+	d.HandleKey(key(tcell.KeyDown))
+	d.HandleKey(key(tcell.KeyEnter))
+	if picked != filepath.Join(dir, "logs", "boot.txt") || d.IsOpen() {
+		t.Errorf("Expected boot.txt to be picked, got %q", picked)
+	}
+
+	// Save mode: the initial name is editable and joined with the directory
+	saved := ""
+	d = NewFileDialog(newTestScreen(t), "Save As", FileDialogSave, filepath.Join(dir, "session.txt"), func(path string) error {
+		saved = path
+		return nil
+	})
+	d.HandleKey(key(tcell.KeyBackspace2))
+	d.HandleKey(key(tcell.KeyBackspace2))
+	d.HandleKey(key(tcell.KeyBackspace2))
+	typeText(d, "log")
+	d.HandleKey(key(tcell.KeyEnter))
+	if saved != filepath.Join(dir, "session.log") {
+		t.Errorf("Expected save to session.log, got %q", saved)
+	}
+}
+
+func TestTextDialog(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("  line %d", i)
+	}
+	d := NewTextDialog(newTestScreen(t), "Help", lines)
+	d.Draw()
+
+	d.HandleKey(key(tcell.KeyPgDn))
+	if d.Offset() == 0 {
+		t.Error("Expected PgDn to scroll")
+	}
+	d.HandleKey(key(tcell.KeyEnd))
+	end := d.Offset()
+	d.HandleKey(key(tcell.KeyDown))
+	if d.Offset() != end || end+d.height != len(lines) {
+		t.Errorf("Expected scrolling to stop at the last page, offset %d", d.Offset())
+	}
+	d.HandleKey(key(tcell.KeyHome))
+	if d.Offset() != 0 {
+		t.Errorf("Expected Home to return to the top, got %d", d.Offset())
+	}
+
+	typeText(d, "q")
+	if d.IsOpen() {
+		t.Error("Expected q to close the dialog")
+	}
+}
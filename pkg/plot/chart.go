@@ -0,0 +1,97 @@
+package plot
+
+import "math"
+
+// Chart is a rendered plot: a grid of cells and, for coloring, which
+// series drew each cell (-1 for none)
+type Chart struct {
+	Cells    [][]rune
+	Series   [][]int
+	Min, Max float64 // Value range of the vertical axis
+}
+
+// braille dot bits by column and row within a cell, from U+2800
+var brailleDots = [2][4]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// Render draws series of the samples as lines in a width x height cell
+// grid, newest sample at the right. Braille characters give each cell 2x4
+// dots; ascii draws one '*' per cell for terminals without braille glyphs.
+func Render(samples []Sample, series []int, width, height int, ascii bool) Chart {
+	chart := Chart{Cells: make([][]rune, height), Series: make([][]int, height)}
+	for row := range chart.Cells {
+		chart.Cells[row] = make([]rune, width)
+		chart.Series[row] = make([]int, width)
+		for col := range chart.Cells[row] {
+			chart.Cells[row][col] = ' '
+			chart.Series[row][col] = -1
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return chart
+	}
+
+	dotsX, dotsY := 2, 4
+	if ascii {
+		dotsX, dotsY = 1, 1
+	}
+	columns, rows := width*dotsX, height*dotsY
+	if len(samples) > columns {
+		samples = samples[len(samples)-columns:]
+	}
+	offset := columns - len(samples) // Right-align so new samples enter from the right
+
+	chart.Min, chart.Max = math.Inf(1), math.Inf(-1)
+	for _, sample := range samples {
+		for _, s := range series {
+			if v := sample.Value(s); isFinite(v) {
+				chart.Min = math.Min(chart.Min, v)
+				chart.Max = math.Max(chart.Max, v)
+			}
+		}
+	}
+	if math.IsInf(chart.Min, 1) {
+		chart.Min, chart.Max = 0, 1
+	} else if chart.Min == chart.Max {
+		chart.Min, chart.Max = chart.Min-1, chart.Max+1
+	}
+
+	dot := func(x, y, s int) {
+		col, row := x/dotsX, (rows-1-y)/dotsY
+		if ascii {
+			chart.Cells[row][col] = '*'
+		} else {
+			bits := chart.Cells[row][col] - 0x2800
+			if chart.Cells[row][col] == ' ' {
+				bits = 0
+			}
+			chart.Cells[row][col] = 0x2800 + (bits | brailleDots[x%2][(rows-1-y)%4])
+		}
+		chart.Series[row][col] = s
+	}
+
+	for _, s := range series {
+		prev := -1
+		for i, sample := range samples {
+			v := sample.Value(s)
+			if !isFinite(v) {
+				continue
+			}
+			y := int(math.Round((v - chart.Min) / (chart.Max - chart.Min) * float64(rows-1)))
+			y = min(max(y, 0), rows-1)
+			x := offset + i
+			// Join to the previous point with a vertical run
+			lo, hi := y, y
+			if prev >= 0 {
+				lo, hi = min(y, prev), max(y, prev)
+			}
+			for yy := lo; yy <= hi; yy++ {
+				dot(x, yy, s)
+			}
+			prev = y
+		}
+	}
+	return chart
+}
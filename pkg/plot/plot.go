@@ -0,0 +1,179 @@
+// Package plot pulls numeric values out of received lines with regular
+// expressions, keeps them as time series and draws them as text charts
+package plot
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Value is a number found in a line, named after its capture group
+type Value struct {
+	Name  string
+	Value float64
+}
+
+// Extractor finds values in lines with regular expressions. Each named
+// capture group is a series: `temp=(?P<temp>-?[\d.]+)`.
+type Extractor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewExtractor compiles the patterns. Each needs at least one named group.
+func NewExtractor(patterns []string) (*Extractor, error) {
+	e := &Extractor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if !hasNamedGroup(re) {
+			return nil, fmt.Errorf("pattern %q has no named group such as (?P<temp>[0-9.]+)", pattern)
+		}
+		e.patterns = append(e.patterns, re)
+	}
+	return e, nil
+}
+
+// hasNamedGroup reports whether a regex names any of its groups
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract returns the values in a line, in pattern and group order. Groups
+// whose text isn't a finite number ("inf" and "nan" parse as floats) are
+// skipped.
+func (e *Extractor) Extract(line string) []Value {
+	var values []Value
+	for _, re := range e.patterns {
+		match := re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			if name == "" || match[i] == "" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(match[i], 64); err == nil && isFinite(v) {
+				values = append(values, Value{Name: name, Value: v})
+			}
+		}
+	}
+	return values
+}
+
+// isFinite reports whether v is neither NaN nor infinite
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Sample holds the values found in one line. Values are indexed like the
+// recorder's names; NaN means the line didn't have that series.
+type Sample struct {
+	Time   time.Time
+	Values []float64
+}
+
+// Value returns series i of the sample, or NaN
+func (s Sample) Value(i int) float64 {
+	if i < len(s.Values) {
+		return s.Values[i]
+	}
+	return math.NaN()
+}
+
+// Recorder keeps the newest samples, up to a limit
+type Recorder struct {
+	names   []string
+	index   map[string]int
+	samples []Sample
+	limit   int
+}
+
+// NewRecorder creates a recorder keeping up to limit samples
+func NewRecorder(limit int) *Recorder {
+	return &Recorder{index: make(map[string]int), limit: max(1, limit)}
+}
+
+// Add records the values found in a line. New names become new series.
+func (r *Recorder) Add(t time.Time, values []Value) {
+	if len(values) == 0 {
+		return
+	}
+	for _, v := range values {
+		if _, ok := r.index[v.Name]; !ok {
+			r.index[v.Name] = len(r.names)
+			r.names = append(r.names, v.Name)
+		}
+	}
+
+	sample := Sample{Time: t, Values: make([]float64, len(r.names))}
+	for i := range sample.Values {
+		sample.Values[i] = math.NaN()
+	}
+	for _, v := range values {
+		sample.Values[r.index[v.Name]] = v.Value
+	}
+
+	r.samples = append(r.samples, sample)
+	if len(r.samples) > r.limit {
+		r.samples = append(r.samples[:0], r.samples[len(r.samples)-r.limit:]...)
+	}
+}
+
+// Names returns the series names in the order they first appeared
+func (r *Recorder) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Samples returns the newest samples, up to n
+func (r *Recorder) Samples(n int) []Sample {
+	start := max(0, len(r.samples)-n)
+	return append([]Sample(nil), r.samples[start:]...)
+}
+
+// Len returns the number of samples kept
+func (r *Recorder) Len() int {
+	return len(r.samples)
+}
+
+// Reset drops all series and samples
+func (r *Recorder) Reset() {
+	r.names = nil
+	r.index = make(map[string]int)
+	r.samples = nil
+}
+
+// WriteCSV writes the samples with a time column and a column per series.
+// Series missing from a line are left empty.
+func (r *Recorder) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"time"}, r.names...)); err != nil {
+		return err
+	}
+	row := make([]string, len(r.names)+1)
+	for _, sample := range r.samples {
+		row[0] = sample.Time.Format(time.RFC3339Nano)
+		for i := range r.names {
+			row[i+1] = ""
+			if v := sample.Value(i); !math.IsNaN(v) {
+				row[i+1] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
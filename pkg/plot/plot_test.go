@@ -0,0 +1,91 @@
+package plot
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractor(t *testing.T) {
+	e, err := NewExtractor([]string{`temp=(?P<temp>-?[\d.]+)`, `hum=(?P<hum>[\d.]+)%`})
+	if err != nil {
+		t.Fatalf("NewExtractor failed: %v", err)
+	}
+	values := e.Extract("sensor: temp=-3.5 hum=41%")
+	if len(values) != 2 || values[0] != (Value{"temp", -3.5}) || values[1] != (Value{"hum", 41}) {
+		t.Errorf("Extract = %+v", values)
+	}
+	if values := e.Extract("temp=abc"); len(values) != 0 {
+		t.Errorf("Non-numeric group gave %+v", values)
+	}
+	inf, err := NewExtractor([]string{`temp=(?P<temp>\S+)`})
+	if err != nil {
+		t.Fatalf("NewExtractor failed: %v", err)
+	}
+	for _, line := range []string{"temp=inf", "temp=-Inf", "temp=nan"} {
+		if values := inf.Extract(line); len(values) != 0 {
+			t.Errorf("Extract(%q) = %+v, want nothing", line, values)
+		}
+	}
+
+	if _, err := NewExtractor([]string{`temp=([\d.]+)`}); err == nil {
+		t.Error("Expected an error for a pattern without named groups")
+	}
+	if _, err := NewExtractor([]string{`(?P<x>`}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestRecorderCSV(t *testing.T) {
+	r := NewRecorder(2)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.Add(start, []Value{{"a", 1}})
+	r.Add(start.Add(time.Second), []Value{{"a", 2}})
+	r.Add(start.Add(2*time.Second), []Value{{"b", 0.5}})
+
+	if r.Len() != 2 {
+		t.Errorf("Len = %d, want the limit of 2", r.Len())
+	}
+	var sb strings.Builder
+	if err := r.WriteCSV(&sb); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	want := "time,a,b\n2024-05-01T12:00:01Z,2,\n2024-05-01T12:00:02Z,,0.5\n"
+	if sb.String() != want {
+		t.Errorf("CSV = %q, want %q", sb.String(), want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	var samples []Sample
+	for i := 0; i < 4; i++ {
+		samples = append(samples, Sample{Values: []float64{float64(i), math.NaN()}})
+	}
+
+	chart := Render(samples, []int{0}, 4, 2, true)
+	if chart.Min != 0 || chart.Max != 3 {
+		t.Errorf("Range = %v..%v, want 0..3", chart.Min, chart.Max)
+	}
+	// Rising line: low points at the bottom left, joined up to the top right
+	if got := string(chart.Cells[0]) + "|" + string(chart.Cells[1]); got != "  **|*** " {
+		t.Errorf("ASCII chart = %q", got)
+	}
+	if chart.Series[0][3] != 0 || chart.Series[0][0] != -1 {
+		t.Errorf("Series owners = %v", chart.Series)
+	}
+
+	// Two samples in braille fill the right column of a single cell
+	chart = Render(samples[:2], []int{0}, 1, 1, false)
+	if chart.Cells[0][0] != 0x2800|0x40|0x08|0x10|0x20|0x80 {
+		t.Errorf("Braille cell = %U", chart.Cells[0][0])
+	}
+
+	// Infinite values are skipped like missing ones rather than stretching
+	// the range off the chart
+	samples = []Sample{{Values: []float64{21.5}}, {Values: []float64{math.Inf(1)}}, {Values: []float64{22}}}
+	chart = Render(samples, []int{0}, 3, 2, true)
+	if chart.Min != 21.5 || chart.Max != 22 {
+		t.Errorf("Range = %v..%v with an infinite sample, want 21.5..22", chart.Min, chart.Max)
+	}
+}
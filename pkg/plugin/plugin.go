@@ -0,0 +1,460 @@
+// Package plugin loads user extensions written in Lua from ~/.sterm/plugins.
+// Each plugin is a .lua file run in its own interpreter when sterm starts.
+// It registers callbacks through the global "sterm" table:
+//
+//	sterm.on_rx(function(data) ... end)       -- raw bytes received
+//	sterm.on_line(function(line) ... end)     -- received lines, escape sequences removed
+//	sterm.on_connect(function(port) ... end)  -- port opened or reopened
+//	sterm.add_menu_item("Label", function() ... end)
+//	sterm.add_status(function() return "text" end)
+//	sterm.add_decoder("name", function(data) return {{summary = "..."}} end)
+//
+// and acts on the session with sterm.send(text), sterm.notify(text) and
+// sterm.log(text).
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/decoder"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// callTimeout stops a plugin callback that runs away, such as an endless loop
+const callTimeout = time.Second
+
+// maxLine bounds the line collected for on_line callbacks
+const maxLine = 4096
+
+// Host is what plugins can do to the running session
+type Host interface {
+	Send(data []byte)      // Write to the port as if typed
+	Notify(message string) // Show a status bar notification
+	Log(message string)    // Write to the debug log
+}
+
+// MenuItem is a menu entry added by a plugin
+type MenuItem struct {
+	Plugin string
+	Label  string
+	run    *lua.LFunction
+	owner  *Plugin
+}
+
+// Run calls the plugin's handler for the item
+func (item MenuItem) Run() error {
+	return item.owner.call(item.run)
+}
+
+// Plugin is one loaded script
+type Plugin struct {
+	Name string
+	Path string
+
+	host      Host
+	state     *lua.LState
+	onRx      []*lua.LFunction
+	onLine    []*lua.LFunction
+	onConnect []*lua.LFunction
+	status    []*lua.LFunction
+	menu      []MenuItem
+	decoders  []string // Names registered with add_decoder, removed on Close
+	line      []byte   // Partial line, only touched by the reader
+	err       error    // Set once a callback fails; the plugin is then disabled
+
+	// Host calls made by the running script, made once it returns and the
+	// interpreter is unlocked: a notification redraws the status bar, which
+	// calls back into the plugin for its segments
+	pending []func()
+	mu      sync.Mutex
+}
+
+// Err returns the error that disabled the plugin, or nil while it works
+func (p *Plugin) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Manager holds the loaded plugins and dispatches events to them
+type Manager struct {
+	plugins []*Plugin
+	host    Host
+}
+
+// DefaultDir returns ~/.sterm/plugins
+func DefaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".sterm", "plugins"), nil
+}
+
+// Load runs every .lua file in dir, in name order. A missing directory
+// means no plugins. Plugins that fail to load are reported and skipped; the
+// rest still load.
+func Load(dir string, host Host) (*Manager, []error) {
+	m := &Manager{host: host}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return m, []error{fmt.Errorf("failed to list plugins: %w", err)}
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		p, err := loadPlugin(path, host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.plugins = append(m.plugins, p)
+	}
+	return m, errs
+}
+
+// loadPlugin runs a script, which registers its callbacks
+func loadPlugin(path string, host Host) (*Plugin, error) {
+	p := &Plugin{
+		Name:  strings.TrimSuffix(filepath.Base(path), ".lua"),
+		Path:  path,
+		host:  host,
+		state: lua.NewState(),
+	}
+	p.register()
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	p.state.SetContext(ctx)
+	if err := p.state.DoFile(path); err != nil {
+		p.state.Close()
+		p.unregisterDecoders()
+		return nil, fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	p.state.RemoveContext()
+	p.runPending(p.takePending())
+	return p, nil
+}
+
+// register installs the sterm table in the plugin's interpreter
+func (p *Plugin) register() {
+	L := p.state
+	addFunc := func(list *[]*lua.LFunction) lua.LGFunction {
+		return func(L *lua.LState) int {
+			*list = append(*list, L.CheckFunction(1))
+			return 0
+		}
+	}
+
+	L.SetGlobal("sterm", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"on_rx":      addFunc(&p.onRx),
+		"on_line":    addFunc(&p.onLine),
+		"on_connect": addFunc(&p.onConnect),
+		"add_status": addFunc(&p.status),
+		"add_menu_item": func(L *lua.LState) int {
+			p.menu = append(p.menu, MenuItem{Plugin: p.Name, Label: L.CheckString(1), run: L.CheckFunction(2), owner: p})
+			return 0
+		},
+		"add_decoder": func(L *lua.LState) int {
+			name, fn := L.CheckString(1), L.CheckFunction(2)
+			if decoder.Registered(name) {
+				L.RaiseError("decoder %q already exists", name)
+			}
+			decoder.Register(name, func() decoder.Decoder {
+				return &luaDecoder{name: name, fn: fn, plugin: p}
+			})
+			p.decoders = append(p.decoders, name)
+			return 0
+		},
+		"send": func(L *lua.LState) int {
+			data := []byte(L.CheckString(1))
+			p.pending = append(p.pending, func() { p.host.Send(data) })
+			return 0
+		},
+		"notify": func(L *lua.LState) int {
+			message := p.Name + ": " + L.CheckString(1)
+			p.pending = append(p.pending, func() { p.host.Notify(message) })
+			return 0
+		},
+		"log": func(L *lua.LState) int {
+			message := p.Name + ": " + L.CheckString(1)
+			p.pending = append(p.pending, func() { p.host.Log(message) })
+			return 0
+		},
+	}))
+}
+
+// takePending returns the host calls the script made and clears them.
+// Called with p.mu held, or before the plugin is shared.
+func (p *Plugin) takePending() []func() {
+	pending := p.pending
+	p.pending = nil
+	return pending
+}
+
+// runPending makes host calls the script made. Called without p.mu held.
+func (p *Plugin) runPending(pending []func()) {
+	for _, call := range pending {
+		call()
+	}
+}
+
+// unregisterDecoders removes the decoders the plugin added
+func (p *Plugin) unregisterDecoders() {
+	for _, name := range p.decoders {
+		decoder.Unregister(name)
+	}
+	p.decoders = nil
+}
+
+// call runs a callback with a time limit. The first failure disables the
+// plugin so a broken script can't flood the session with errors.
+func (p *Plugin) call(fn *lua.LFunction, args ...lua.LValue) error {
+	_, err := p.callResult(fn, 0, args...)
+	return err
+}
+
+// callResult runs a callback and returns its first result when want is 1.
+// Must not be called with p.mu held. The host is only called once the
+// interpreter is unlocked, so the host may call back into the plugin.
+func (p *Plugin) callResult(fn *lua.LFunction, want int, args ...lua.LValue) (lua.LValue, error) {
+	result, pending, failed, err := p.run(fn, want, args...)
+	p.runPending(pending)
+	if failed {
+		p.host.Notify(fmt.Sprintf("Plugin %s disabled after an error (see log)", p.Name))
+		p.host.Log(err.Error())
+	}
+	return result, err
+}
+
+// run is callResult with p.mu held, returning the host calls the callback
+// made and whether it just failed
+func (p *Plugin) run(fn *lua.LFunction, want int, args ...lua.LValue) (result lua.LValue, pending []func(), failed bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return lua.LNil, nil, false, p.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	p.state.SetContext(ctx)
+	defer p.state.RemoveContext()
+
+	err = p.state.CallByParam(lua.P{Fn: fn, NRet: want, Protect: true}, args...)
+	pending = p.takePending()
+	if err != nil {
+		p.err = fmt.Errorf("plugin %s: %w", p.Name, err)
+		return lua.LNil, pending, true, p.err
+	}
+	if want == 0 {
+		return lua.LNil, pending, false, nil
+	}
+	result = p.state.Get(-1)
+	p.state.Pop(1)
+	return result, pending, false, nil
+}
+
+// Plugins returns the loaded plugins
+func (m *Manager) Plugins() []*Plugin {
+	if m == nil {
+		return nil
+	}
+	return m.plugins
+}
+
+// OnRxData passes received data to on_rx callbacks and complete lines to
+// on_line callbacks
+func (m *Manager) OnRxData(data []byte) {
+	if m == nil {
+		return
+	}
+	for _, p := range m.plugins {
+		if len(p.onRx) == 0 && len(p.onLine) == 0 {
+			continue
+		}
+		for _, fn := range p.onRx {
+			_ = p.call(fn, lua.LString(data))
+		}
+		if len(p.onLine) == 0 {
+			continue
+		}
+		for _, line := range p.splitLines(data) {
+			for _, fn := range p.onLine {
+				_ = p.call(fn, lua.LString(line))
+			}
+		}
+	}
+}
+
+// splitLines adds data to the partial line and returns the lines it
+// completes, without line endings and escape sequences
+func (p *Plugin) splitLines(data []byte) []string {
+	var lines []string
+	for _, b := range data {
+		switch b {
+		case '\n':
+			lines = append(lines, StripEscapes(string(p.line)))
+			p.line = p.line[:0]
+		case '\r':
+		default:
+			if len(p.line) < maxLine {
+				p.line = append(p.line, b)
+			}
+		}
+	}
+	return lines
+}
+
+// OnConnect tells plugins the port was opened
+func (m *Manager) OnConnect(port string) {
+	if m == nil {
+		return
+	}
+	for _, p := range m.plugins {
+		for _, fn := range p.onConnect {
+			_ = p.call(fn, lua.LString(port))
+		}
+	}
+}
+
+// MenuItems returns the menu entries plugins added
+func (m *Manager) MenuItems() []MenuItem {
+	if m == nil {
+		return nil
+	}
+	var items []MenuItem
+	for _, p := range m.plugins {
+		items = append(items, p.menu...)
+	}
+	return items
+}
+
+// StatusSegments returns the text of the plugins' status bar segments.
+// Empty segments are left out.
+func (m *Manager) StatusSegments() []string {
+	if m == nil {
+		return nil
+	}
+	var segments []string
+	for _, p := range m.plugins {
+		for _, fn := range p.status {
+			value, err := p.callResult(fn, 1)
+			if err != nil {
+				continue
+			}
+			if text := lua.LVAsString(value); text != "" {
+				segments = append(segments, text)
+			}
+		}
+	}
+	return segments
+}
+
+// Close shuts down the interpreters and removes the decoders plugins added.
+// Closing again does nothing.
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	for _, p := range m.plugins {
+		p.mu.Lock()
+		if p.state != nil {
+			p.state.Close()
+			p.state = nil
+		}
+		p.err = fmt.Errorf("plugin %s: closed", p.Name)
+		p.pending = nil
+		p.unregisterDecoders()
+		p.mu.Unlock()
+	}
+}
+
+// luaDecoder is a protocol decoder written as a plugin function. The
+// function gets each chunk of received data (empty when the line is idle)
+// and returns a list of frames, each a table with summary and optionally
+// error fields.
+type luaDecoder struct {
+	name   string
+	fn     *lua.LFunction
+	plugin *Plugin
+}
+
+// Name returns the name the plugin registered
+func (d *luaDecoder) Name() string {
+	return d.name
+}
+
+// Reset does nothing; a plugin keeps its own state
+func (d *luaDecoder) Reset() {}
+
+// Feed calls the plugin function and converts its frames
+func (d *luaDecoder) Feed(data []byte, now time.Time) []decoder.Frame {
+	result, err := d.plugin.callResult(d.fn, 1, lua.LString(data))
+	if err != nil {
+		return nil
+	}
+	table, ok := result.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+
+	var frames []decoder.Frame
+	table.ForEach(func(_, value lua.LValue) {
+		frame := decoder.Frame{Time: now, Decoder: d.name}
+		switch v := value.(type) {
+		case lua.LString:
+			frame.Summary = string(v)
+		case *lua.LTable:
+			frame.Summary = lua.LVAsString(v.RawGetString("summary"))
+			frame.Error = lua.LVAsString(v.RawGetString("error"))
+			frame.Raw = []byte(lua.LVAsString(v.RawGetString("raw")))
+		default:
+			return
+		}
+		frames = append(frames, frame)
+	})
+	return frames
+}
+
+// StripEscapes removes ANSI escape sequences (CSI, OSC and two-byte
+// escapes) and other control characters except tab from text
+func StripEscapes(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == 0x1b && i+1 < len(text) && text[i+1] == '[':
+			// CSI: parameters up to a final byte in @..~
+			i += 2
+			for i < len(text) && (text[i] < 0x40 || text[i] > 0x7e) {
+				i++
+			}
+		case c == 0x1b && i+1 < len(text) && text[i+1] == ']':
+			// OSC: up to BEL or ST
+			i += 2
+			for i < len(text) && text[i] != 0x07 && !(text[i] == 0x1b && i+1 < len(text) && text[i+1] == '\\') {
+				i++
+			}
+			if i < len(text) && text[i] == 0x1b {
+				i++
+			}
+		case c == 0x1b:
+			i++ // Two-byte escape such as ESC 7
+		case c < 0x20 && c != '\t', c == 0x7f:
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
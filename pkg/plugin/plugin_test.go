@@ -0,0 +1,236 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sterm/pkg/decoder"
+)
+
+// recordingHost records what plugins did
+type recordingHost struct {
+	sent     []string
+	notified []string
+	logged   []string
+}
+
+func (h *recordingHost) Send(data []byte)      { h.sent = append(h.sent, string(data)) }
+func (h *recordingHost) Notify(message string) { h.notified = append(h.notified, message) }
+func (h *recordingHost) Log(message string)    { h.logged = append(h.logged, message) }
+
+// writePlugins creates plugin files in a temporary directory
+func writePlugins(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadAndEvents(t *testing.T) {
+	dir := writePlugins(t, map[string]string{
+		"autologin.lua": `
+local bytes = 0
+sterm.on_rx(function(data) bytes = bytes + #data end)
+sterm.on_line(function(line)
+  if line == "login:" then sterm.send("root\r") end
+end)
+sterm.on_connect(function(port) sterm.log("connected to " .. port) end)
+sterm.add_status(function() return "rx " .. bytes end)
+sterm.add_menu_item("Say hello", function() sterm.notify("hello") end)
+`,
+		"broken.lua": `this is not lua`,
+		"notes.txt":  `ignored`,
+	})
+
+	host := &recordingHost{}
+	m, errs := Load(dir, host)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Errorf("Load errors = %v, want one for broken.lua", errs)
+	}
+	if len(m.Plugins()) != 1 || m.Plugins()[0].Name != "autologin" {
+		t.Fatalf("Plugins = %+v", m.Plugins())
+	}
+	defer m.Close()
+
+	m.OnConnect("/dev/ttyUSB0")
+	m.OnRxData([]byte("\x1b[1mlog"))
+	m.OnRxData([]byte("in:\x1b[0m\r\n"))
+	if len(host.sent) != 1 || host.sent[0] != "root\r" {
+		t.Errorf("Sent %q", host.sent)
+	}
+	if len(host.logged) != 1 || host.logged[0] != "autologin: connected to /dev/ttyUSB0" {
+		t.Errorf("Logged %q", host.logged)
+	}
+	if segments := m.StatusSegments(); len(segments) != 1 || segments[0] != "rx 16" {
+		t.Errorf("StatusSegments = %q", segments)
+	}
+
+	items := m.MenuItems()
+	if len(items) != 1 || items[0].Label != "Say hello" {
+		t.Fatalf("MenuItems = %+v", items)
+	}
+	if err := items[0].Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(host.notified) != 1 || host.notified[0] != "autologin: hello" {
+		t.Errorf("Notified %q", host.notified)
+	}
+}
+
+func TestMissingDirectory(t *testing.T) {
+	m, errs := Load(filepath.Join(t.TempDir(), "none"), &recordingHost{})
+	if len(errs) != 0 || len(m.Plugins()) != 0 {
+		t.Errorf("Load of a missing directory: %v, %v", m.Plugins(), errs)
+	}
+
+	// A nil manager is safe to use when plugins are disabled
+	var none *Manager
+	none.OnRxData([]byte("x"))
+	none.OnConnect("x")
+	if none.MenuItems() != nil || none.StatusSegments() != nil {
+		t.Error("Nil manager returned items")
+	}
+}
+
+func TestCallbackErrorsDisablePlugin(t *testing.T) {
+	dir := writePlugins(t, map[string]string{
+		"loop.lua": `sterm.on_rx(function(data) while true do end end)`,
+		"fail.lua": `sterm.on_line(function(line) error("boom") end)`,
+	})
+	host := &recordingHost{}
+	m, errs := Load(dir, host)
+	if len(errs) != 0 {
+		t.Fatalf("Load errors: %v", errs)
+	}
+	defer m.Close()
+
+	start := time.Now()
+	m.OnRxData([]byte("a\n"))
+	m.OnRxData([]byte("b\n"))
+	if elapsed := time.Since(start); elapsed > 3*callTimeout {
+		t.Errorf("Runaway callback took %v", elapsed)
+	}
+	for _, p := range m.Plugins() {
+		if p.Err() == nil {
+			t.Errorf("Plugin %s still enabled", p.Name)
+		}
+	}
+	if len(host.notified) != 2 {
+		t.Errorf("Notified %q, want one message per plugin", host.notified)
+	}
+}
+
+func TestPluginDecoder(t *testing.T) {
+	dir := writePlugins(t, map[string]string{
+		"frames.lua": `
+sterm.add_decoder("stx", function(data)
+  local frames = {}
+  for body in string.gmatch(data, "\2([^\3]*)\3") do
+    table.insert(frames, {summary = "frame " .. body})
+  end
+  if data == "bad" then table.insert(frames, {summary = "?", error = "garbled"}) end
+  return frames
+end)
+`,
+	})
+	m, errs := Load(dir, &recordingHost{})
+	if len(errs) != 0 {
+		t.Fatalf("Load errors: %v", errs)
+	}
+	defer m.Close()
+
+	d, err := decoder.New("stx")
+	if err != nil {
+		t.Fatalf("Plugin decoder not registered: %v", err)
+	}
+	frames := d.Feed([]byte("\x02one\x03\x02two\x03"), time.Now())
+	if len(frames) != 2 || frames[1].Summary != "frame two" || frames[1].Decoder != "stx" {
+		t.Errorf("Frames = %+v", frames)
+	}
+	if frames := d.Feed([]byte("bad"), time.Now()); len(frames) != 1 || frames[0].Valid() {
+		t.Errorf("Invalid frame = %+v", frames)
+	}
+
+	// A second plugin can't replace the decoder
+	dup := writePlugins(t, map[string]string{"dup.lua": `sterm.add_decoder("stx", function(data) return {} end)`})
+	if other, errs := Load(dup, &recordingHost{}); len(errs) != 1 || !strings.Contains(errs[0].Error(), "already exists") {
+		t.Errorf("Load errors for a duplicate decoder = %v", errs)
+	} else {
+		other.Close()
+	}
+
+	// Unloading the plugin removes its decoder
+	m.Close()
+	if _, err := decoder.New("stx"); err == nil {
+		t.Error("Plugin decoder still registered after Close")
+	}
+}
+
+// statusHost redraws the plugins' status segments on every notification,
+// as the application does
+type statusHost struct {
+	recordingHost
+	manager *Manager
+}
+
+func (h *statusHost) Notify(message string) {
+	h.recordingHost.Notify(message)
+	h.manager.StatusSegments()
+}
+
+func TestPluginHostReentry(t *testing.T) {
+	dir := writePlugins(t, map[string]string{
+		"count.lua": `
+local n = 0
+sterm.add_status(function() return "n " .. n end)
+sterm.add_menu_item("Count", function() n = n + 1; sterm.notify("counted") end)
+sterm.add_menu_item("Fail", function() error("boom") end)
+`,
+	})
+	host := &statusHost{}
+	m, errs := Load(dir, host)
+	if len(errs) != 0 {
+		t.Fatalf("Load errors: %v", errs)
+	}
+	defer m.Close()
+	host.manager = m
+
+	// The notification calls back into the plugin, which must not deadlock
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, item := range m.MenuItems() {
+			_ = item.Run()
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Plugin callback deadlocked notifying the host")
+	}
+	if len(host.notified) != 2 || host.notified[0] != "count: counted" {
+		t.Errorf("Notified %q", host.notified)
+	}
+}
+
+func TestStripEscapes(t *testing.T) {
+	tests := map[string]string{
+		"plain":                      "plain",
+		"\x1b[1;32mgreen\x1b[0m":     "green",
+		"\x1b]0;title\x07prompt$ ":   "prompt$ ",
+		"\x1b]8;;http://x\x1b\\link": "link",
+		"a\x1b7b\x08c\td":            "abc\td",
+	}
+	for in, want := range tests {
+		if got := StripEscapes(in); got != want {
+			t.Errorf("StripEscapes(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runTool runs a credential helper command with input on stdin, returning
+// its output. A non-zero exit is returned as an *exec.ExitError, with what
+// the command wrote to stderr in its Stderr and the error message.
+func runTool(input string, name string, args ...string) (string, error) {
+	out, _, err := runToolStderr(input, name, args...)
+	return out, err
+}
+
+// runToolStderr is runTool for commands that report some failures only on
+// stderr, returning what was written there as well
+func runToolStderr(input string, name string, args ...string) (string, string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", "", fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	if exitErr != nil {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	if err != nil && stderr.Len() > 0 {
+		return "", stderr.String(), fmt.Errorf("%s: %s: %w", name, strings.TrimSpace(stderr.String()), err)
+	}
+	return string(out), stderr.String(), err
+}
+
+// secretService stores secrets in the freedesktop.org Secret Service
+// (GNOME Keyring, KWallet) with the secret-tool command from libsecret
+type secretService struct{}
+
+// Name describes the store
+func (secretService) Name() string {
+	return "Secret Service (secret-tool)"
+}
+
+// secretToolMissing reports whether secret-tool failed only because nothing
+// is stored: it exits 1 without a message then, while a missing D-Bus
+// session or a locked keyring also exit 1 but say why on stderr
+func secretToolMissing(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(exitErr.Stderr) == 0
+}
+
+// Get returns a secret
+func (secretService) Get(name string) (string, error) {
+	out, err := runTool("", "secret-tool", "lookup", "service", Service, "account", name)
+	if secretToolMissing(err) || (err == nil && out == "") {
+		return "", notFound(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up secret: %w", err)
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// Set stores a secret. The value goes through stdin, not the command line.
+func (secretService) Set(name, value string) error {
+	label := fmt.Sprintf("%s: %s", Service, name)
+	if _, err := runTool(value, "secret-tool", "store", "--label", label, "service", Service, "account", name); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a secret
+func (secretService) Delete(name string) error {
+	_, err := runTool("", "secret-tool", "clear", "service", Service, "account", name)
+	if err != nil && !secretToolMissing(err) {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
+
+// keychain stores secrets as generic passwords in the macOS login keychain
+// with the security command
+type keychain struct{}
+
+// Name describes the store
+func (keychain) Name() string {
+	return "macOS Keychain"
+}
+
+// Get returns a secret
+func (keychain) Get(name string) (string, error) {
+	out, err := runTool("", "security", "find-generic-password", "-s", Service, "-a", name, "-w")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		// errSecItemNotFound
+		return "", notFound(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// Set stores a secret, updating an existing one. add-generic-password only
+// takes the password as an argument, where any local user could read it from
+// the process list, so the command is written to an interactive security
+// session on stdin instead, with the password hex encoded to need no quoting.
+// That session exits 0 even when the command fails, so anything it writes to
+// stderr is taken as the failure.
+func (keychain) Set(name, value string) error {
+	if strings.ContainsAny(name, "\r\n") {
+		return fmt.Errorf("failed to store secret: invalid name %q", name)
+	}
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		securityQuote(Service), securityQuote(name), hex.EncodeToString([]byte(value)))
+	_, stderr, err := runToolStderr(command, "security", "-i")
+	if err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("failed to store secret: security: %s", msg)
+	}
+	return nil
+}
+
+// securityQuote quotes an argument for a command line read by security -i,
+// which splits on spaces outside double quotes and takes the character after
+// a backslash literally
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// Delete removes a secret
+func (keychain) Delete(name string) error {
+	_, err := runTool("", "security", "delete-generic-password", "-s", Service, "-a", name)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return nil
+}
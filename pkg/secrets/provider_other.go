@@ -0,0 +1,13 @@
+//go:build !windows
+
+package secrets
+
+import "runtime"
+
+// defaultProvider returns the credential store of this platform
+func defaultProvider() Provider {
+	if runtime.GOOS == "darwin" {
+		return keychain{}
+	}
+	return secretService{}
+}
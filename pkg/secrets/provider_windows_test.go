@@ -0,0 +1,37 @@
+//go:build windows
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestWinCred(t *testing.T) {
+	store := winCred{}
+	name := fmt.Sprintf("test-%d", os.Getpid())
+	t.Cleanup(func() { _ = store.Delete(name) })
+
+	if _, err := store.Get(name); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Set = %v, want ErrNotFound", err)
+	}
+	for _, value := range []string{"hunter2", "", "pässwörd"} {
+		if err := store.Set(name, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", value, err)
+		}
+		if got, err := store.Get(name); err != nil || got != value {
+			t.Errorf("Get = %q, %v; want %q", got, err, value)
+		}
+	}
+	if err := store.Delete(name); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(name); err != nil {
+		t.Errorf("Delete of a missing secret = %v", err)
+	}
+}
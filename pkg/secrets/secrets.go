@@ -0,0 +1,68 @@
+// Package secrets looks up passwords and other credentials that shouldn't be
+// written to the configuration files, such as the ones auto-login sends
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Service is the service name secrets are stored under in the OS keychain
+const Service = "sterm"
+
+// ErrNotFound is returned when a secret isn't stored anywhere
+var ErrNotFound = errors.New("secret not found")
+
+// Provider stores secrets in an OS credential store
+type Provider interface {
+	// Name describes the store, e.g. "macOS Keychain"
+	Name() string
+	// Get returns a secret, or an error wrapping ErrNotFound
+	Get(name string) (string, error)
+	// Set stores a secret, replacing any stored under the same name
+	Set(name, value string) error
+	// Delete removes a secret; deleting a missing one is not an error
+	Delete(name string) error
+}
+
+// Default returns the credential store of this platform: the Secret Service
+// (GNOME Keyring, KWallet) through secret-tool on Linux and BSD, the login
+// keychain on macOS and the Credential Manager on Windows
+func Default() Provider {
+	return defaultProvider()
+}
+
+// Lookup returns the named secret. The STERM_SECRET_<NAME> environment
+// variable takes precedence, for scripts and CI; otherwise the secret is read
+// from the default provider.
+func Lookup(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("secret name cannot be empty")
+	}
+	if value, ok := os.LookupEnv(EnvName(name)); ok {
+		return value, nil
+	}
+	return Default().Get(name)
+}
+
+// EnvName returns the environment variable that overrides a secret: the name
+// upper-cased with other characters than letters and digits replaced by "_"
+func EnvName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("STERM_SECRET_")
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+// notFound returns the error for a missing secret
+func notFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
@@ -0,0 +1,166 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"router":       "STERM_SECRET_ROUTER",
+		"lab-switch.1": "STERM_SECRET_LAB_SWITCH_1",
+	}
+	for name, want := range tests {
+		if got := EnvName(name); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLookupEnv(t *testing.T) {
+	t.Setenv("STERM_SECRET_ROUTER_PW", "hunter2")
+	value, err := Lookup("router-pw")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if value != "hunter2" {
+		t.Errorf("Lookup = %q, want the environment value", value)
+	}
+
+	if _, err := Lookup(""); err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+}
+
+func TestDefaultProvider(t *testing.T) {
+	if name := Default().Name(); name == "" {
+		t.Error("Default provider has no name")
+	}
+}
+
+// fakeTool puts a shell script named name first on PATH, as a stand-in for
+// a credential helper. The script sees the mode in STERM_FAKE and can keep
+// what it is given in the file named by STERM_FAKE_LOG.
+func fakeTool(t *testing.T, name, script string) (log string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	log = filepath.Join(dir, "log")
+	t.Setenv("STERM_FAKE_LOG", log)
+	return log
+}
+
+func TestRunTool(t *testing.T) {
+	fakeTool(t, "tool", `read -r line; printf 'got %s' "$line"; [ "$1" = fail ] && { echo "no keyring" >&2; exit 3; }; exit 0`)
+
+	out, err := runTool("input", "tool")
+	if err != nil || out != "got input" {
+		t.Errorf("runTool = %q, %v; want the output of the tool", out, err)
+	}
+	_, err = runTool("", "tool", "fail")
+	if err == nil || !strings.Contains(err.Error(), "no keyring") {
+		t.Errorf("runTool error = %v, want the tool's message", err)
+	}
+	if _, err := runTool("", "missing-tool"); err == nil || !strings.Contains(err.Error(), "failed to run") {
+		t.Errorf("runTool of a missing command = %v", err)
+	}
+}
+
+func TestSecretService(t *testing.T) {
+	log := fakeTool(t, "secret-tool", `case "$1:$STERM_FAKE" in
+lookup:stored) printf 'hunter2\n' ;;
+lookup:missing|clear:missing) exit 1 ;;
+*:locked) echo "Cannot autolaunch D-Bus without X11 \$DISPLAY" >&2; exit 1 ;;
+store:*) read -r value; printf '%s %s' "$3" "$value" > "$STERM_FAKE_LOG" ;;
+esac`)
+	store := secretService{}
+
+	t.Setenv("STERM_FAKE", "stored")
+	if value, err := store.Get("router"); err != nil || value != "hunter2" {
+		t.Errorf("Get = %q, %v; want hunter2", value, err)
+	}
+	if err := store.Set("router", "s3cret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if data, _ := os.ReadFile(log); string(data) != "sterm: router s3cret" {
+		t.Errorf("Stored %q, want the label and the value from stdin", data)
+	}
+
+	// Exit 1 alone means nothing is stored
+	t.Setenv("STERM_FAKE", "missing")
+	if _, err := store.Get("router"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing secret = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("router"); err != nil {
+		t.Errorf("Delete of a missing secret = %v", err)
+	}
+
+	// A failure with a message is reported, not taken for a missing secret
+	t.Setenv("STERM_FAKE", "locked")
+	if _, err := store.Get("router"); err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "D-Bus") {
+		t.Errorf("Get with no D-Bus session = %v, want its error", err)
+	}
+	if err := store.Delete("router"); err == nil {
+		t.Error("Delete with no D-Bus session should fail")
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	log := fakeTool(t, "security", `case "$1:$STERM_FAKE" in
+find-generic-password:stored) printf 'hunter2\n' ;;
+*:missing) echo "The specified item could not be found in the keychain." >&2; exit 44 ;;
+*:locked) echo "User interaction is not allowed." >&2; exit 36 ;;
+-i:denied) read -r line; echo "security: SecKeychainItemCreateFromContent: User interaction is not allowed." >&2 ;;
+-i:*) read -r line; printf '%s\n%s\n' "$*" "$line" > "$STERM_FAKE_LOG" ;;
+esac`)
+	store := keychain{}
+
+	t.Setenv("STERM_FAKE", "stored")
+	if value, err := store.Get("router"); err != nil || value != "hunter2" {
+		t.Errorf("Get = %q, %v; want hunter2", value, err)
+	}
+	if err := store.Set(`lab "a"`, "s3cret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	// The password is hex encoded on stdin, never on the command line
+	want := "-i\nadd-generic-password -U -s \"sterm\" -a \"lab \\\"a\\\"\" -X 733363726574\n"
+	if data, _ := os.ReadFile(log); string(data) != want {
+		t.Errorf("security called with %q, want %q", data, want)
+	}
+	if err := store.Set("bad\nname", "s3cret"); err == nil {
+		t.Error("Set should refuse a name that ends the command line")
+	}
+
+	// security -i exits 0 when the command fails
+	t.Setenv("STERM_FAKE", "denied")
+	if err := store.Set("router", "s3cret"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Set with a failing command = %v, want its error", err)
+	}
+
+	// errSecItemNotFound
+	t.Setenv("STERM_FAKE", "missing")
+	if _, err := store.Get("router"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing secret = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("router"); err != nil {
+		t.Errorf("Delete of a missing secret = %v", err)
+	}
+
+	t.Setenv("STERM_FAKE", "locked")
+	if _, err := store.Get("router"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get from a locked keychain = %v, want its error", err)
+	}
+	if err := store.Delete("router"); err == nil {
+		t.Error("Delete from a locked keychain should fail")
+	}
+}
@@ -0,0 +1,239 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Port names starting with one of these prefixes select a backend other than
+// a serial device. They can be used anywhere a port name is accepted,
+// including saved configurations.
+const (
+	PTYPrefix     = "pty:"     // Local shell or command on a pseudo-terminal, e.g. "pty:" or "pty:bash -l"
+	SocketPrefix  = "unix:"    // Unix domain socket, e.g. "unix:/tmp/qemu-serial.sock"
+	TCPPrefix     = "tcp:"     // Raw TCP connection, e.g. "tcp:192.168.1.10:4000"
+	PipePrefix    = "pipe:"    // Named pipe, e.g. "pipe:/tmp/guest" (uses guest.in/guest.out if present)
+	ExecPrefix    = "exec:"    // Standard input and output of a subprocess, e.g. "exec:qemu-system-arm -serial stdio"
+	RFC2217Prefix = "rfc2217:" // Networked serial server with remote port control, e.g. "rfc2217:ser2net.lan:2000"
+	SimPrefix     = "sim:"     // Simulated device for testing without hardware, e.g. "sim:echo,latency=20ms"
+	ReplayPrefix  = "replay:"  // Saved history played back with its timing, e.g. "replay:session.json,speed=4"
+)
+
+// backendPrefixes lists the prefixes of non-device backends
+var backendPrefixes = []string{PTYPrefix, SocketPrefix, TCPPrefix, PipePrefix, ExecPrefix, RFC2217Prefix, SimPrefix, ReplayPrefix}
+
+// NewPortFor returns a port for name: a backend selected by its prefix, or a
+// serial device
+func NewPortFor(name string) SerialPort {
+	switch {
+	case strings.HasPrefix(name, PTYPrefix):
+		return NewPTYPort()
+	case strings.HasPrefix(name, SocketPrefix):
+		return newStreamPort(SocketPrefix, dialSocket("unix"))
+	case strings.HasPrefix(name, TCPPrefix):
+		return newStreamPort(TCPPrefix, dialSocket("tcp"))
+	case strings.HasPrefix(name, PipePrefix):
+		return newStreamPort(PipePrefix, openPipe)
+	case strings.HasPrefix(name, ExecPrefix):
+		return newStreamPort(ExecPrefix, startProcess)
+	case strings.HasPrefix(name, RFC2217Prefix):
+		return NewRFC2217Port()
+	case strings.HasPrefix(name, SimPrefix):
+		return newStreamPort(SimPrefix, openSimulator)
+	case strings.HasPrefix(name, ReplayPrefix):
+		return newStreamPort(ReplayPrefix, openReplay)
+	default:
+		return NewSerialPort()
+	}
+}
+
+// IsDevicePort reports whether name is a serial device rather than one of
+// the other backends
+func IsDevicePort(name string) bool {
+	for _, prefix := range backendPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasLineSettings reports whether baud rate, framing and flow control apply
+// to the port: serial devices, local or behind an RFC 2217 server
+func HasLineSettings(name string) bool {
+	return IsDevicePort(name) || strings.HasPrefix(name, RFC2217Prefix)
+}
+
+// splitCommand splits a command line into arguments. Single and double
+// quotes group words containing spaces; there are no other escapes.
+func splitCommand(line string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// closedError marks a read error that ends the connection for good (the
+// process exited or the peer hung up) so callers can match it with io.EOF
+func closedError(what string, err error) error {
+	if !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w (%v)", io.EOF, err)
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
+
+// Reconfigurer is implemented by ports that can change line settings
+// without closing the connection
+type Reconfigurer interface {
+	Reconfigure(config SerialConfig) error
+}
+
+// ModemStatus is the state of the modem input lines
+type ModemStatus struct {
+	CTS bool // Clear to send
+	DSR bool // Data set ready
+	RI  bool // Ring indicator
+	DCD bool // Data carrier detect
+}
+
+// String lists the lines that are active, e.g. "CTS DSR"
+func (m ModemStatus) String() string {
+	var lines []string
+	for _, line := range []struct {
+		name string
+		on   bool
+	}{{"CTS", m.CTS}, {"DSR", m.DSR}, {"RI", m.RI}, {"DCD", m.DCD}} {
+		if line.on {
+			lines = append(lines, line.name)
+		}
+	}
+	if len(lines) == 0 {
+		return "none"
+	}
+	return strings.Join(lines, " ")
+}
+
+// ModemController is implemented by ports with modem control lines
+type ModemController interface {
+	SetDTR(on bool) error
+	SetRTS(on bool) error
+	ModemStatus() (ModemStatus, error)
+	SendBreak(d time.Duration) error
+}
+
+// Resizer is implemented by ports that can tell the other end the terminal
+// size out of band, such as a pseudo-terminal
+type Resizer interface {
+	Resize(cols, rows int) error
+}
+
+// streamReader adds read timeouts to a blocking reader such as a pipe or a
+// pseudo-terminal. A goroutine reads ahead into a channel; Read waits on it.
+type streamReader struct {
+	chunks  chan []byte
+	done    chan struct{}
+	pending []byte
+	err     error // Set once the reader has failed, returned after pending data
+	errMu   sync.Mutex
+	once    sync.Once
+}
+
+// newStreamReader starts reading from r
+func newStreamReader(r io.Reader) *streamReader {
+	s := &streamReader{
+		chunks: make(chan []byte, 16),
+		done:   make(chan struct{}),
+	}
+	go s.run(r)
+	return s
+}
+
+// run copies data from r to the channel until r fails or the reader is closed
+func (s *streamReader) run(r io.Reader) {
+	defer close(s.chunks)
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			select {
+			case s.chunks <- chunk:
+			case <-s.done:
+				return
+			}
+		}
+		if err != nil {
+			s.errMu.Lock()
+			s.err = err
+			s.errMu.Unlock()
+			return
+		}
+	}
+}
+
+// Read returns buffered data, waiting up to timeout for more (0 waits
+// forever). A timeout returns no data and no error, like a serial port. Once
+// the underlying reader fails its error is returned.
+func (s *streamReader) Read(p []byte, timeout time.Duration) (int, error) {
+	if len(s.pending) == 0 {
+		var expired <-chan time.Time
+		if timeout > 0 {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		select {
+		case chunk, ok := <-s.chunks:
+			if !ok {
+				s.errMu.Lock()
+				defer s.errMu.Unlock()
+				if s.err == nil {
+					return 0, io.EOF
+				}
+				return 0, s.err
+			}
+			s.pending = chunk
+		case <-expired:
+			return 0, nil
+		}
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Close stops the read-ahead goroutine. The underlying reader must be closed
+// by the caller to unblock it.
+func (s *streamReader) Close() {
+	s.once.Do(func() { close(s.done) })
+}
@@ -0,0 +1,101 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ErrUnsupportedBaudRate is returned when a device's driver refuses the
+// requested baud rate
+var ErrUnsupportedBaudRate = errors.New("baud rate not supported by the driver")
+
+// BaudRateReporter is implemented by ports that can tell the baud rate the
+// driver actually set, which may be rounded from the one requested
+type BaudRateReporter interface {
+	// ActualBaudRate returns the rate in effect, or 0 when unknown
+	ActualBaudRate() int
+}
+
+// probeBaudRate is the rate a device is reopened at to find out which
+// rates its driver takes
+const probeBaudRate = 9600
+
+// acceptedBaudRates reopens a device that couldn't be configured and tries
+// the common and special rates with the same framing. It returns the rates
+// the driver took, as the driver rounded them where that can be read back,
+// or nil when the requested rate wasn't the problem.
+func acceptedBaudRates(port string, mode serial.Mode) []int {
+	probe := mode
+	probe.BaudRate = probeBaudRate
+	device, err := serial.Open(port, &probe)
+	if err != nil {
+		return nil
+	}
+	defer device.Close()
+	if device.SetMode(&mode) == nil {
+		return nil // Refused for another reason, or not reproducible
+	}
+
+	candidates := append(GetCommonBaudRates(), GetSpecialBaudRates()...)
+	slices.Sort(candidates)
+	var accepted []int
+	for _, rate := range candidates {
+		try := mode
+		try.BaudRate = rate
+		if device.SetMode(&try) != nil {
+			continue
+		}
+		if actual := effectiveBaudRate(port); actual > 0 {
+			rate = actual
+		}
+		if !slices.Contains(accepted, rate) {
+			accepted = append(accepted, rate)
+		}
+	}
+	slices.Sort(accepted)
+	return accepted
+}
+
+// baudRateError explains a device that couldn't be configured at the
+// requested rate, listing the rates its driver accepts. It returns nil if
+// the rate wasn't the cause.
+func baudRateError(port string, mode serial.Mode) error {
+	accepted := acceptedBaudRates(port, mode)
+	if len(accepted) == 0 {
+		return nil
+	}
+	rates := make([]string, len(accepted))
+	for i, rate := range accepted {
+		rates[i] = strconv.Itoa(rate)
+	}
+	return fmt.Errorf("failed to open serial port %s: %w: %d (it accepts %s)", port, ErrUnsupportedBaudRate, mode.BaudRate, strings.Join(rates, ", "))
+}
+
+// ActualBaudRate returns the baud rate the driver set, which drivers
+// round to what the chip can generate, or 0 when it can't be read back
+func (sp *CrossPlatformSerialPort) ActualBaudRate() int {
+	if !sp.isOpen {
+		return 0
+	}
+	return effectiveBaudRate(sp.config.Port)
+}
+
+// CharTime returns how long one character takes on the line: a start bit,
+// the data bits, the parity bit if any and the stop bits. Returns 0 if the
+// baud rate is unknown.
+func (c SerialConfig) CharTime() time.Duration {
+	if c.BaudRate <= 0 {
+		return 0
+	}
+	bits := 1 + c.DataBits + c.StopBits
+	if c.Parity != "" && c.Parity != "none" {
+		bits++
+	}
+	return time.Duration(bits) * time.Second / time.Duration(c.BaudRate)
+}
@@ -0,0 +1,9 @@
+//go:build !linux || ppc64le
+
+package serial
+
+// effectiveBaudRate can't read the speed back without termios2: macOS keeps
+// IOSSIOSPEED rates out of termios and Windows reports the requested rate
+func effectiveBaudRate(device string) int {
+	return 0
+}
@@ -0,0 +1,22 @@
+//go:build linux && !ppc64le
+
+package serial
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// effectiveBaudRate reads back the speed the driver set on the device this
+// process has open, which drivers such as cp210x and pl2303 round to one
+// their chip can generate. Returns 0 when unknown.
+func effectiveBaudRate(device string) int {
+	fd, ok := deviceDescriptor(device)
+	if !ok {
+		return 0
+	}
+	// termios2 holds the speed as a number even for the Bxxx constants
+	if termios, err := unix.IoctlGetTermios(fd, unix.TCGETS2); err == nil && termios.Ospeed > 0 {
+		return int(termios.Ospeed)
+	}
+	return 0
+}
@@ -0,0 +1,343 @@
+package serial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Bridge limits
+const (
+	bridgeQueueChunks  = 256 // Device data queued per client before it is dropped
+	bridgeWriteTimeout = 5 * time.Second
+	bridgeBreak        = 250 * time.Millisecond // Length of a break a client asks for
+)
+
+// BridgeDevice is the port a Bridge shares: the serial port as seen by the
+// program that has it open, so client traffic is displayed and logged too
+type BridgeDevice interface {
+	// Write sends data from a client to the device
+	Write(data []byte) (int, error)
+	// Config returns the current line settings
+	Config() SerialConfig
+	// Reconfigure applies line settings an RFC 2217 client asked for
+	Reconfigure(config SerialConfig) error
+	// Modem returns the modem line control, or nil if the port has none
+	Modem() ModemController
+}
+
+// Bridge serves an open port on a TCP port, so other tools (flashers, gdb,
+// pppd) can use the device while it stays open here. Data from the device
+// goes to every client and data from any client goes to the device. With
+// RFC 2217 enabled, clients can also change the line settings and control
+// the modem lines through the Telnet COM Port Control Option.
+type Bridge struct {
+	listener net.Listener
+	device   BridgeDevice
+	rfc2217  bool
+	clients  map[*bridgeClient]struct{}
+	mu       sync.Mutex
+	closed   bool
+}
+
+// bridgeClient is a connection to a Bridge
+type bridgeClient struct {
+	conn   net.Conn
+	queue  chan []byte // Device data waiting to be written
+	done   chan struct{}
+	telnet telnetDecoder
+	once   sync.Once
+}
+
+// NewBridge listens on addr, e.g. "localhost:7000" or ":7000", and serves
+// device to the clients that connect
+func NewBridge(addr string, rfc2217 bool, device BridgeDevice) (*Bridge, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	b := &Bridge{
+		listener: listener,
+		device:   device,
+		rfc2217:  rfc2217,
+		clients:  make(map[*bridgeClient]struct{}),
+	}
+	go b.accept()
+	return b, nil
+}
+
+// Addr returns the address the bridge listens on
+func (b *Bridge) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// RFC2217 reports whether clients talk RFC 2217 rather than raw TCP
+func (b *Bridge) RFC2217() bool {
+	return b.rfc2217
+}
+
+// Clients returns the number of connected clients
+func (b *Bridge) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Broadcast queues data received from the device for every client. A
+// client that falls too far behind is disconnected rather than holding up
+// the port.
+func (b *Bridge) Broadcast(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if b.rfc2217 {
+		data = escapeIAC(data)
+	}
+	chunk := append([]byte(nil), data...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for client := range b.clients {
+		select {
+		case client.queue <- chunk:
+		default:
+			client.close()
+		}
+	}
+}
+
+// Close stops listening and disconnects the clients
+func (b *Bridge) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	for client := range b.clients {
+		client.close()
+	}
+	b.mu.Unlock()
+	return b.listener.Close()
+}
+
+// accept serves connections until the listener is closed
+func (b *Bridge) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		client := &bridgeClient{
+			conn:  conn,
+			queue: make(chan []byte, bridgeQueueChunks),
+			done:  make(chan struct{}),
+		}
+
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			conn.Close()
+			return
+		}
+		b.clients[client] = struct{}{}
+		b.mu.Unlock()
+
+		if b.rfc2217 {
+			// Binary mode both ways and no go-aheads; the client asks for
+			// COM port control with WILL COM-PORT-OPTION
+			client.queue <- []byte{
+				telnetIAC, telnetWILL, telnetOptBinary,
+				telnetIAC, telnetDO, telnetOptBinary,
+				telnetIAC, telnetWILL, telnetOptSGA,
+				telnetIAC, telnetDO, telnetOptSGA,
+			}
+		}
+		go b.write(client)
+		go b.read(client)
+	}
+}
+
+// close disconnects the client; safe to call more than once
+func (c *bridgeClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// send queues a reply to the client
+func (c *bridgeClient) send(data []byte) {
+	select {
+	case c.queue <- data:
+	default:
+		c.close()
+	}
+}
+
+// write sends queued data to the client until it disconnects
+func (b *Bridge) write(client *bridgeClient) {
+	for {
+		select {
+		case <-client.done:
+			return
+		case data := <-client.queue:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(bridgeWriteTimeout))
+			if _, err := client.conn.Write(data); err != nil {
+				client.close()
+				return
+			}
+		}
+	}
+}
+
+// read passes the client's data to the device until it disconnects
+func (b *Bridge) read(client *bridgeClient) {
+	defer func() {
+		client.close()
+		b.mu.Lock()
+		delete(b.clients, client)
+		b.mu.Unlock()
+	}()
+
+	buffer := make([]byte, 4096)
+	var data []byte
+	for {
+		n, err := client.conn.Read(buffer)
+		if err != nil {
+			return
+		}
+		data = buffer[:n]
+		if b.rfc2217 {
+			data = client.telnet.decode(data, nil,
+				func(verb, option byte) { b.negotiate(client, verb, option) },
+				func(sub []byte) { b.subnegotiation(client, sub) })
+		}
+		if len(data) > 0 {
+			if _, err := b.device.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// negotiate answers a client's WILL/WONT/DO/DONT, agreeing to COM port
+// control and the options sent on connect and refusing anything else
+func (b *Bridge) negotiate(client *bridgeClient, verb, option byte) {
+	switch verb {
+	case telnetWILL:
+		switch option {
+		case telnetOptComPort:
+			client.send([]byte{telnetIAC, telnetDO, telnetOptComPort})
+		case telnetOptBinary, telnetOptSGA:
+		default:
+			client.send([]byte{telnetIAC, telnetDONT, option})
+		}
+	case telnetDO:
+		switch option {
+		case telnetOptBinary, telnetOptSGA:
+		default:
+			client.send([]byte{telnetIAC, telnetWONT, option})
+		}
+	}
+}
+
+// RFC 2217 SET-PARITY values by parity name
+var bridgeParities = map[string]byte{"none": 1, "odd": 2, "even": 3, "mark": 4, "space": 5}
+
+// subnegotiation carries out a client's COM-PORT-OPTION command and replies
+// with the setting now in effect, which tells the client when a request
+// was refused
+func (b *Bridge) subnegotiation(client *bridgeClient, sub []byte) {
+	if len(sub) < 3 || sub[0] != telnetOptComPort {
+		return
+	}
+	cmd, value := sub[1], sub[2:]
+	config := b.device.Config()
+	reconfigure := func(change func(*SerialConfig)) {
+		updated := config
+		change(&updated)
+		if updated != config && b.device.Reconfigure(updated) == nil {
+			config = b.device.Config()
+		}
+	}
+
+	var reply []byte
+	switch cmd {
+	case comSetBaudRate:
+		if len(value) < 4 {
+			return
+		}
+		if rate := int(binary.BigEndian.Uint32(value)); rate > 0 {
+			reconfigure(func(c *SerialConfig) { c.BaudRate = rate })
+		}
+		reply = binary.BigEndian.AppendUint32(nil, uint32(config.BaudRate))
+	case comSetDataSize:
+		if size := int(value[0]); size >= 5 && size <= 8 {
+			reconfigure(func(c *SerialConfig) { c.DataBits = size })
+		}
+		reply = []byte{byte(config.DataBits)}
+	case comSetParity:
+		for name, code := range bridgeParities {
+			if code == value[0] {
+				reconfigure(func(c *SerialConfig) { c.Parity = name })
+			}
+		}
+		reply = []byte{bridgeParities[config.Parity]}
+	case comSetStopSize:
+		// 1.5 stop bits (3) isn't supported and is answered with the current size
+		if size := int(value[0]); size == 1 || size == 2 {
+			reconfigure(func(c *SerialConfig) { c.StopBits = size })
+		}
+		reply = []byte{byte(config.StopBits)}
+	case comSetControl:
+		reply = []byte{b.control(value[0], reconfigure, &config)}
+	case comSetModemStateMask, comSetLineStateMask, comPurgeData:
+		reply = value[:1]
+	default:
+		return
+	}
+
+	msg := []byte{telnetIAC, telnetSB, telnetOptComPort, cmd + comServerOffset}
+	msg = append(msg, escapeIAC(reply)...)
+	client.send(append(msg, telnetIAC, telnetSE))
+}
+
+// control carries out a SET-CONTROL request and returns the value to reply
+// with: flow control, break and the DTR and RTS outputs
+func (b *Bridge) control(value byte, reconfigure func(func(*SerialConfig)), config *SerialConfig) byte {
+	modem := b.device.Modem()
+	switch value {
+	case 0: // Request flow control setting
+	case controlNoFlow, controlXonXoff, controlHardware:
+		flow := map[byte]string{controlNoFlow: FlowNone, controlXonXoff: FlowXonXoff, controlHardware: FlowRTSCTS}[value]
+		reconfigure(func(c *SerialConfig) { c.FlowControl = flow })
+	case controlBreakOn:
+		if modem != nil && modem.SendBreak(bridgeBreak) == nil {
+			return controlBreakOn
+		}
+		return controlBreakOff
+	case controlBreakOff:
+		return controlBreakOff
+	case controlDTROn, controlDTROff:
+		on := value == controlDTROn
+		if modem == nil || modem.SetDTR(on) != nil {
+			return boolControl(!on, controlDTROn, controlDTROff)
+		}
+		return value
+	case controlRTSOn, controlRTSOff:
+		on := value == controlRTSOn
+		if modem == nil || modem.SetRTS(on) != nil {
+			return boolControl(!on, controlRTSOn, controlRTSOff)
+		}
+		return value
+	default:
+		return value
+	}
+
+	switch config.FlowControl {
+	case FlowXonXoff:
+		return controlXonXoff
+	case FlowRTSCTS:
+		return controlHardware
+	}
+	return controlNoFlow
+}
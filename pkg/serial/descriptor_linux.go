@@ -0,0 +1,31 @@
+package serial
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// deviceDescriptor finds the file descriptor this process has a device
+// open on. The port library doesn't expose its descriptor, and the device
+// is opened exclusively so it can't be opened again for ioctls the library
+// doesn't make; /proc/self/fd links name the device each descriptor is on.
+func deviceDescriptor(device string) (int, bool) {
+	target, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return 0, false
+	}
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+	for _, entry := range fds {
+		if link, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name())); err != nil || link != target {
+			continue
+		}
+		if fd, err := strconv.Atoi(entry.Name()); err == nil {
+			return fd, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,14 @@
+package serial
+
+import "golang.org/x/sys/unix"
+
+// lineEchoes reports whether the kernel sends what a tty receives back down
+// the line: echo is on, or newlines are echoed in canonical mode. Files and
+// other devices that aren't ttys don't echo.
+func lineEchoes(fd int) bool {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return false
+	}
+	return termios.Lflag&unix.ECHO != 0 || termios.Lflag&(unix.ICANON|unix.ECHONL) == unix.ICANON|unix.ECHONL
+}
@@ -0,0 +1,8 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package serial
+
+// lineEchoes can't read the line settings on this platform
+func lineEchoes(fd int) bool {
+	return false
+}
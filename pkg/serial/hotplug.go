@@ -0,0 +1,155 @@
+package serial
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hotplugPollInterval is how often the port list is compared where the
+// system has no hotplug notifications sterm can use
+const hotplugPollInterval = time.Second
+
+// HotplugEvent reports a serial device being plugged in or removed
+type HotplugEvent struct {
+	Port  string // Device, e.g. /dev/ttyUSB0 or COM4
+	Added bool   // False when the device went away
+}
+
+// HotplugWatcher reports serial devices as they come and go: from kernel
+// uevents on Linux, elsewhere by polling the port list. Events are hints;
+// one that arrives while the previous is unread is dropped, so check the
+// device itself (DevicePresent) before acting on one.
+type HotplugWatcher struct {
+	events    chan HotplugEvent
+	done      chan struct{}
+	closer    io.Closer // Uevent socket, when used
+	closeOnce sync.Once
+}
+
+// WatchHotplug starts watching for serial devices
+func WatchHotplug() *HotplugWatcher {
+	w := &HotplugWatcher{
+		events: make(chan HotplugEvent, 16),
+		done:   make(chan struct{}),
+	}
+	if closer, err := watchUevents(w.emit); err == nil {
+		w.closer = closer
+	} else {
+		go w.poll()
+	}
+	return w
+}
+
+// Events returns the channel events are delivered on
+func (w *HotplugWatcher) Events() <-chan HotplugEvent {
+	return w.events
+}
+
+// Close stops watching
+func (w *HotplugWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.closer != nil {
+			_ = w.closer.Close()
+		}
+	})
+}
+
+// emit delivers an event unless the channel is full or the watcher closed
+func (w *HotplugWatcher) emit(event HotplugEvent) {
+	select {
+	case <-w.done:
+	case w.events <- event:
+	default:
+	}
+}
+
+// poll compares the port list every hotplugPollInterval
+func (w *HotplugWatcher) poll() {
+	known := make(map[string]bool)
+	if ports, err := ListPorts(); err == nil {
+		for _, port := range ports {
+			known[port] = true
+		}
+	}
+
+	ticker := time.NewTicker(hotplugPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		ports, err := ListPorts()
+		if err != nil {
+			continue
+		}
+		current := make(map[string]bool, len(ports))
+		for _, port := range ports {
+			current[port] = true
+			if !known[port] {
+				w.emit(HotplugEvent{Port: port, Added: true})
+			}
+		}
+		for port := range known {
+			if !current[port] {
+				w.emit(HotplugEvent{Port: port})
+			}
+		}
+		known = current
+	}
+}
+
+// parseUevent reads a kernel uevent ("add@/devices/...\0ACTION=add\0...")
+// for a tty device being added or removed
+func parseUevent(message []byte) (HotplugEvent, bool) {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(string(message), "\x00") {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			fields[key] = value
+		}
+	}
+	if fields["SUBSYSTEM"] != "tty" || fields["DEVNAME"] == "" {
+		return HotplugEvent{}, false
+	}
+
+	port := fields["DEVNAME"]
+	if !strings.HasPrefix(port, "/") {
+		port = "/dev/" + port
+	}
+	switch fields["ACTION"] {
+	case "add":
+		return HotplugEvent{Port: port, Added: true}, true
+	case "remove":
+		return HotplugEvent{Port: port}, true
+	}
+	return HotplugEvent{}, false
+}
+
+// DevicePresent reports whether the device a configuration opens is
+// connected: the one its USB match picks, or a device with its port name
+func DevicePresent(config SerialConfig) bool {
+	if config.USB != nil {
+		_, err := FindUSBPort(*config.USB)
+		return err == nil
+	}
+	if _, err := os.Stat(config.Port); err == nil {
+		return true
+	}
+
+	// COM ports aren't files
+	ports, err := ListPorts()
+	if err != nil {
+		return false
+	}
+	for _, port := range ports {
+		if strings.EqualFold(port, config.Port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package serial
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// watchUevents listens for the kernel's device uevents on a netlink socket,
+// calling emit for every tty added or removed. The kernel sends an event
+// before udev has set the device's permissions, so opening may need a
+// moment's retry. Sandboxes without netlink fall back to polling.
+func watchUevents(emit func(HotplugEvent)) (io.Closer, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// Non-blocking so reads go through the runtime poller and Close ends them
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	socket := os.NewFile(uintptr(fd), "uevent")
+	go func() {
+		buffer := make([]byte, 16384)
+		for {
+			n, err := socket.Read(buffer)
+			if err != nil {
+				return
+			}
+			if event, ok := parseUevent(buffer[:n]); ok {
+				emit(event)
+			}
+		}
+	}()
+	return socket, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package serial
+
+import (
+	"errors"
+	"io"
+)
+
+// watchUevents is Linux only; other systems poll the port list, which the
+// enumerator reads from IOKit, the Windows registry or devfs
+func watchUevents(emit func(HotplugEvent)) (io.Closer, error) {
+	return nil, errors.New("uevents are only available on Linux")
+}
@@ -0,0 +1,40 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.bug.st/serial"
+)
+
+// ErrPortBusy is returned when another program has the port open
+// exclusively or holds its lock file
+var ErrPortBusy = errors.New("port is in use by another program")
+
+// portLock is a lock file held for an open port; the zero value holds none
+type portLock struct {
+	path string
+}
+
+// release removes the lock file, if one was created
+func (l *portLock) release() {
+	if l != nil && l.path != "" {
+		removeLockFile(l.path)
+		l.path = ""
+	}
+}
+
+// openError explains why a device couldn't be opened, naming the programs
+// that have it open when the system can tell
+func openError(port string, err error) error {
+	var portErr *serial.PortError
+	if !errors.As(err, &portErr) || portErr.Code() != serial.PortBusy {
+		return fmt.Errorf("failed to open serial port %s: %w", port, err)
+	}
+
+	if users := portUsers(port); len(users) > 0 {
+		return fmt.Errorf("failed to open serial port %s: %w (open in %s)", port, ErrPortBusy, strings.Join(users, ", "))
+	}
+	return fmt.Errorf("failed to open serial port %s: %w", port, ErrPortBusy)
+}
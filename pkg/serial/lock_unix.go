@@ -0,0 +1,145 @@
+//go:build !windows
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockDirs are where UUCP-style lock files (LCK..ttyUSB0) are looked for,
+// in the order they are tried when creating one
+var lockDirs = []string{"/var/lock", "/run/lock", "/var/spool/lock"}
+
+// lockPort takes the LCK.. lock file of a device, the convention minicom,
+// picocom and ModemManager follow, after checking none of the lock
+// directories holds a live one. Stale locks from processes that have
+// exited are removed. If no lock directory is writable the port is opened
+// without a lock, as most distributions only let the lock group write there.
+// With ignore set, existing locks are neither checked nor taken over.
+func lockPort(port string, ignore bool) (*portLock, error) {
+	if ignore {
+		return &portLock{}, nil
+	}
+
+	name := lockFileName(port)
+	for _, dir := range lockDirs {
+		path := filepath.Join(dir, name)
+		pid, ok := readLockFile(path)
+		if !ok {
+			continue
+		}
+		if pid != os.Getpid() && processAlive(pid) {
+			return nil, fmt.Errorf("failed to open serial port %s: %w (locked by %s through %s)", port, ErrPortBusy, describeProcess(pid), path)
+		}
+		// Left behind by a process that has exited
+		_ = os.Remove(path)
+	}
+
+	content := []byte(fmt.Sprintf("%10d\n", os.Getpid()))
+	for _, dir := range lockDirs {
+		path := filepath.Join(dir, name)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			continue
+		}
+		_, err = file.Write(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+			continue
+		}
+		return &portLock{path: path}, nil
+	}
+	return &portLock{}, nil
+}
+
+// lockFileName returns the lock file name of a device, following symlinks
+// such as /dev/serial/by-id so every name of a device shares one lock
+func lockFileName(port string) string {
+	if resolved, err := filepath.EvalSymlinks(port); err == nil {
+		port = resolved
+	}
+	return "LCK.." + filepath.Base(port)
+}
+
+// readLockFile returns the process ID in a lock file. Unreadable contents
+// count as a stale lock, with pid 0.
+func readLockFile(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, true
+	}
+	return pid, true
+}
+
+// removeLockFile deletes a lock file this process created
+func removeLockFile(path string) {
+	if pid, ok := readLockFile(path); ok && pid == os.Getpid() {
+		_ = os.Remove(path)
+	}
+}
+
+// processAlive reports whether a process exists, even one owned by another
+// user that can't be signalled
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// describeProcess names a process, e.g. "minicom (pid 812)", where /proc
+// tells its name
+func describeProcess(pid int) string {
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return fmt.Sprintf("%s (pid %d)", strings.TrimSpace(string(comm)), pid)
+	}
+	return fmt.Sprintf("pid %d", pid)
+}
+
+// portUsers lists the other processes with the device open, found through
+// their /proc/<pid>/fd links. Processes of other users can't be seen
+// without privileges, and systems without /proc return nothing.
+func portUsers(port string) []string {
+	device, err := filepath.EvalSymlinks(port)
+	if err != nil {
+		return nil
+	}
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var users []string
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if target, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && target == device {
+				users = append(users, describeProcess(pid))
+				break
+			}
+		}
+	}
+	return users
+}
@@ -0,0 +1,66 @@
+//go:build !windows
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockPort(t *testing.T) {
+	dir := t.TempDir()
+	saved := lockDirs
+	lockDirs = []string{dir}
+	defer func() { lockDirs = saved }()
+	path := filepath.Join(dir, "LCK..ttyTEST0")
+
+	lock, err := lockPort("/dev/ttyTEST0", false)
+	if err != nil {
+		t.Fatalf("lockPort failed: %v", err)
+	}
+	if pid, ok := readLockFile(path); !ok || pid != os.Getpid() {
+		t.Fatalf("Lock file holds pid %d, want %d", pid, os.Getpid())
+	}
+	if data, _ := os.ReadFile(path); len(data) != 11 {
+		t.Errorf("Lock file %q is not a 10-digit pid and newline", data)
+	}
+	lock.release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Lock file left after release")
+	}
+
+	// A live process holds the port
+	writeLock := func(pid int) {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("%10d\n", pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeLock(os.Getppid())
+	_, err = lockPort("/dev/ttyTEST0", false)
+	if !errors.Is(err, ErrPortBusy) || !strings.Contains(err.Error(), path) {
+		t.Errorf("lockPort = %v, want ErrPortBusy naming the lock file", err)
+	}
+	if lock, err := lockPort("/dev/ttyTEST0", true); err != nil {
+		t.Errorf("lockPort ignoring locks failed: %v", err)
+	} else {
+		lock.release()
+	}
+	if pid, _ := readLockFile(path); pid != os.Getppid() {
+		t.Error("Ignoring the lock replaced the other program's lock file")
+	}
+
+	// A lock left by a process that has exited is taken over
+	writeLock(1 << 30)
+	lock, err = lockPort("/dev/ttyTEST0", false)
+	if err != nil {
+		t.Fatalf("lockPort with a stale lock failed: %v", err)
+	}
+	defer lock.release()
+	if pid, _ := readLockFile(path); pid != os.Getpid() {
+		t.Errorf("Stale lock not replaced: pid %d", pid)
+	}
+}
@@ -0,0 +1,15 @@
+package serial
+
+// lockPort does nothing on Windows: COM ports are opened with no sharing,
+// so the system already refuses a second program with ERROR_ACCESS_DENIED
+func lockPort(port string, ignore bool) (*portLock, error) {
+	return &portLock{}, nil
+}
+
+// removeLockFile is never called on Windows, where no lock files are made
+func removeLockFile(path string) {}
+
+// portUsers can't tell which program has a COM port open
+func portUsers(port string) []string {
+	return nil
+}
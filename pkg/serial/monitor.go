@@ -0,0 +1,32 @@
+package serial
+
+import "errors"
+
+// ErrReadOnly is returned when writing to a port opened in monitor mode
+var ErrReadOnly = errors.New("port is open read-only (monitor mode)")
+
+// ReadOnlyPort wraps a port so nothing can ever be written to it. Keyboard
+// input, terminal query responses (DSR, DA) and file transfers all go
+// through Write, so refusing writes here covers every path at once.
+type ReadOnlyPort struct {
+	SerialPort
+}
+
+// Write refuses to send anything
+func (p *ReadOnlyPort) Write(data []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+// NewMonitorPort creates a port for observing a link without disturbing it.
+// Where the platform allows, the device is opened read-only and its line
+// settings are left as they are, so a program already using the port keeps
+// working.
+func NewMonitorPort() SerialPort {
+	return &ReadOnlyPort{SerialPort: newMonitorPort()}
+}
+
+// IsReadOnly reports whether a port refuses writes
+func IsReadOnly(port SerialPort) bool {
+	_, ok := port.(*ReadOnlyPort)
+	return ok
+}
@@ -0,0 +1,131 @@
+//go:build !windows
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ttyMonitor reads a serial device opened with O_RDONLY. The line settings
+// (baud rate, parity) are not touched: changing them would break the program
+// already using the port. Use stty to set them if nothing else has. A line
+// with echo on is refused, as the kernel would send everything received
+// back to the device.
+type ttyMonitor struct {
+	file    *os.File
+	config  SerialConfig
+	timeout time.Duration
+}
+
+// newMonitorPort creates a read-only tty port
+func newMonitorPort() SerialPort {
+	return &ttyMonitor{}
+}
+
+// Open opens the device read-only without making it the controlling terminal
+func (m *ttyMonitor) Open(config SerialConfig) error {
+	if m.file != nil {
+		return fmt.Errorf("serial port is already open")
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	config, err := ResolvePort(config)
+	if err != nil {
+		return err
+	}
+
+	// Non-blocking so reads go through the runtime poller and honor deadlines
+	file, err := os.OpenFile(config.Port, os.O_RDONLY|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open serial port %s: %w", config.Port, err)
+	}
+	if lineEchoes(int(file.Fd())) {
+		file.Close()
+		return fmt.Errorf("cannot monitor %s: echo is on, so received data would be sent back to the device (turn it off with stty -F %s raw -echo)", config.Port, config.Port)
+	}
+
+	m.file = file
+	m.config = config
+	m.timeout = config.Timeout
+	return nil
+}
+
+// Close closes the device
+func (m *ttyMonitor) Close() error {
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close serial port: %w", err)
+	}
+	return nil
+}
+
+// Read reads available data. A timeout returns no data and no error, the
+// same as the regular serial port.
+func (m *ttyMonitor) Read(buffer []byte) (int, error) {
+	if m.file == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+
+	deadline := time.Time{}
+	if m.timeout > 0 {
+		deadline = time.Now().Add(m.timeout)
+	}
+	// Deadlines aren't supported on plain files, which are read without one
+	if err := m.file.SetReadDeadline(deadline); err != nil && !errors.Is(err, os.ErrNoDeadline) {
+		return 0, fmt.Errorf("failed to set read timeout: %w", err)
+	}
+
+	n, err := m.file.Read(buffer)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to read from serial port: %w", err)
+	}
+	return n, nil
+}
+
+// Write is never reached through ReadOnlyPort
+func (m *ttyMonitor) Write(data []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+// IsOpen returns true if the device is open
+func (m *ttyMonitor) IsOpen() bool {
+	return m.file != nil
+}
+
+// GetConfig returns the configuration the port was opened with
+func (m *ttyMonitor) GetConfig() SerialConfig {
+	return m.config
+}
+
+// SetReadTimeout sets how long Read waits for data
+func (m *ttyMonitor) SetReadTimeout(timeout time.Duration) error {
+	if m.file == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	m.timeout = timeout
+	m.config.Timeout = timeout
+	return nil
+}
+
+// GetAvailablePorts returns a list of available serial ports
+func (m *ttyMonitor) GetAvailablePorts() ([]string, error) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available ports: %w", err)
+	}
+	return ports, nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package serial
+
+// newMonitorPort opens the port normally: Windows has no read-only access to
+// COM ports and only one program can open one at a time, so monitor mode
+// relies on ReadOnlyPort to keep anything from being sent
+func newMonitorPort() SerialPort {
+	return NewCrossPlatformSerialPort()
+}
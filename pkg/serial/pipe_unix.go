@@ -0,0 +1,57 @@
+//go:build !windows
+
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// pipePair reads from one named pipe and writes to another
+type pipePair struct {
+	in  *os.File // Written by us
+	out *os.File // Read by us
+}
+
+// openPipe opens a named pipe. Like QEMU's pipe chardev, if path.in and
+// path.out exist, data is written to path.in and read from path.out;
+// otherwise path itself is opened for reading and writing.
+func openPipe(path string) (io.ReadWriteCloser, error) {
+	if _, err := os.Stat(path + ".in"); err == nil {
+		// Opening for reading first would block until the other side opens
+		// its end, so the write end is opened read-write to not block
+		in, err := os.OpenFile(path+".in", os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open pipe %s.in: %w", path, err)
+		}
+		out, err := os.OpenFile(path+".out", os.O_RDWR, 0)
+		if err != nil {
+			in.Close()
+			return nil, fmt.Errorf("failed to open pipe %s.out: %w", path, err)
+		}
+		return &pipePair{in: in, out: out}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pipe %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Read reads from the output pipe
+func (p *pipePair) Read(b []byte) (int, error) {
+	return p.out.Read(b)
+}
+
+// Write writes to the input pipe
+func (p *pipePair) Write(b []byte) (int, error) {
+	return p.in.Write(b)
+}
+
+// Close closes both pipes
+func (p *pipePair) Close() error {
+	return errors.Join(p.in.Close(), p.out.Close())
+}
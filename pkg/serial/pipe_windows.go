@@ -0,0 +1,25 @@
+//go:build windows
+
+package serial
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// openPipe connects to a Windows named pipe such as \\.\pipe\com1, which
+// is how Hyper-V and VirtualBox expose virtual serial ports. A bare name is
+// taken to be in \\.\pipe\.
+func openPipe(name string) (io.ReadWriteCloser, error) {
+	path := name
+	if !strings.HasPrefix(path, `\\`) {
+		path = `\\.\pipe\` + path
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pipe %s: %w", path, err)
+	}
+	return f, nil
+}
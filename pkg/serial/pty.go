@@ -0,0 +1,183 @@
+//go:build !windows
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// PTYPort runs a local shell or command on a pseudo-terminal, so it gets the
+// same scrollback, logging and triggers as a serial device. Useful for
+// trying out the emulator without hardware. The command comes from the port
+// name after "pty:"; an empty command starts $SHELL.
+type PTYPort struct {
+	config  SerialConfig
+	cmd     *exec.Cmd
+	pty     *os.File
+	reader  *streamReader
+	timeout time.Duration
+	exited  chan struct{}
+	mu      sync.Mutex
+}
+
+// NewPTYPort creates a pseudo-terminal port
+func NewPTYPort() *PTYPort {
+	return &PTYPort{}
+}
+
+// ptyCommand returns the command line for a pty port name
+func ptyCommand(name string) []string {
+	args := splitCommand(strings.TrimPrefix(name, PTYPrefix))
+	if len(args) > 0 {
+		return args
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return []string{shell}
+	}
+	return []string{"/bin/sh"}
+}
+
+// Open starts the command on a new pseudo-terminal
+func (p *PTYPort) Open(config SerialConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pty != nil {
+		return fmt.Errorf("serial port is already open")
+	}
+
+	args := ptyCommand(config.Port)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	f, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: 80, Rows: 24})
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	p.config = config
+	p.cmd = cmd
+	p.pty = f
+	p.reader = newStreamReader(f)
+	p.timeout = config.Timeout
+	p.exited = exited
+	return nil
+}
+
+// Close hangs up the pseudo-terminal, which ends the shell
+func (p *PTYPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pty == nil {
+		return nil
+	}
+
+	p.reader.Close()
+	err := p.pty.Close()
+
+	// Closing the master sends SIGHUP; kill anything that ignores it
+	select {
+	case <-p.exited:
+	case <-time.After(time.Second):
+		_ = p.cmd.Process.Kill()
+	}
+
+	p.pty = nil
+	if err != nil {
+		return fmt.Errorf("failed to close pty: %w", err)
+	}
+	return nil
+}
+
+// Read reads output from the command. Once it exits, io.EOF is returned.
+func (p *PTYPort) Read(buffer []byte) (int, error) {
+	p.mu.Lock()
+	reader, timeout := p.reader, p.timeout
+	p.mu.Unlock()
+
+	if reader == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+	n, err := reader.Read(buffer, timeout)
+	if err != nil {
+		// Linux reports the slave side closing as EIO
+		return n, closedError("process exited", err)
+	}
+	return n, nil
+}
+
+// Write sends input to the command
+func (p *PTYPort) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	f := p.pty
+	p.mu.Unlock()
+
+	if f == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+	n, err := f.Write(data)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to pty: %w", err)
+	}
+	return n, nil
+}
+
+// IsOpen returns true while the pseudo-terminal is open
+func (p *PTYPort) IsOpen() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pty != nil
+}
+
+// GetConfig returns the configuration the port was opened with
+func (p *PTYPort) GetConfig() SerialConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config
+}
+
+// SetReadTimeout sets how long Read waits for output
+func (p *PTYPort) SetReadTimeout(timeout time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pty == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	p.timeout = timeout
+	p.config.Timeout = timeout
+	return nil
+}
+
+// GetAvailablePorts returns the serial devices; a pty is always available
+func (p *PTYPort) GetAvailablePorts() ([]string, error) {
+	return ListPorts()
+}
+
+// Resize sets the pseudo-terminal size, which sends SIGWINCH to the command
+func (p *PTYPort) Resize(cols, rows int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pty == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	if err := pty.Setsize(p.pty, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}); err != nil {
+		return fmt.Errorf("failed to resize pty: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package serial
+
+import (
+	"fmt"
+	"time"
+)
+
+// PTYPort is not available on Windows, which has no Unix pseudo-terminals
+type PTYPort struct{}
+
+// NewPTYPort creates a pseudo-terminal port
+func NewPTYPort() *PTYPort {
+	return &PTYPort{}
+}
+
+// Open always fails on Windows
+func (p *PTYPort) Open(config SerialConfig) error {
+	return fmt.Errorf("pty connections are not supported on Windows")
+}
+
+// Close does nothing
+func (p *PTYPort) Close() error { return nil }
+
+// Read always fails
+func (p *PTYPort) Read(buffer []byte) (int, error) {
+	return 0, fmt.Errorf("serial port is not open")
+}
+
+// Write always fails
+func (p *PTYPort) Write(data []byte) (int, error) {
+	return 0, fmt.Errorf("serial port is not open")
+}
+
+// IsOpen always returns false
+func (p *PTYPort) IsOpen() bool { return false }
+
+// GetConfig returns an empty configuration
+func (p *PTYPort) GetConfig() SerialConfig { return SerialConfig{} }
+
+// SetReadTimeout always fails
+func (p *PTYPort) SetReadTimeout(timeout time.Duration) error {
+	return fmt.Errorf("serial port is not open")
+}
+
+// GetAvailablePorts returns the serial devices
+func (p *PTYPort) GetAvailablePorts() ([]string, error) {
+	return ListPorts()
+}
+
+// Resize always fails
+func (p *PTYPort) Resize(cols, rows int) error {
+	return fmt.Errorf("serial port is not open")
+}
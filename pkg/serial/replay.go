@@ -0,0 +1,179 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayController is implemented by ports that can play back a recording
+type ReplayController interface {
+	// ReplayProgress reports how far playback has got; ok is false when
+	// the port isn't playing a recording
+	ReplayProgress() (progress ReplayProgress, ok bool)
+	// SetReplaySpeed changes the pace, taking effect within the current gap
+	SetReplaySpeed(speed float64) error
+}
+
+// ReplayProgress describes the playback of a recording
+type ReplayProgress struct {
+	Speed    float64       // 1 as recorded, +Inf without gaps
+	Position time.Duration // Recorded time of the last read played, from the first
+	Duration time.Duration // Recorded time from the first read to the last
+	Done     bool          // Everything has been played
+}
+
+// ParseReplaySpeed reads a replay speed such as "2", "0.5x" or "max" (no
+// gaps at all)
+func ParseReplaySpeed(value string) (float64, error) {
+	value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "x")
+	if value == "max" {
+		return math.Inf(1), nil
+	}
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || speed <= 0 || math.IsInf(speed, 0) || math.IsNaN(speed) {
+		return 0, fmt.Errorf("invalid replay speed %q: want a positive number such as 2 or 0.5, or max", value)
+	}
+	return speed, nil
+}
+
+// FormatReplaySpeed formats a speed as ParseReplaySpeed reads it
+func FormatReplaySpeed(speed float64) string {
+	if math.IsInf(speed, 1) {
+		return "max"
+	}
+	return strconv.FormatFloat(speed, 'f', -1, 64) + "x"
+}
+
+// openReplay plays back a history file named by target, with simulator
+// options after a comma. Typed data goes nowhere.
+func openReplay(target string) (io.ReadWriteCloser, error) {
+	path, options, _ := strings.Cut(target, ",")
+	opts, err := ParseSimOptions("replay=" + path + "," + options)
+	if err != nil {
+		return nil, err
+	}
+	opts.Echo = false
+	return newSimConn(opts), nil
+}
+
+// replayState paces a simulator's timed replay and tracks how far it got
+type replayState struct {
+	speed   float64
+	start   time.Time // Recorded time of the first read
+	total   int       // Reads to play
+	played  int
+	changed chan struct{} // Signalled when the speed changes
+	mu      sync.Mutex
+}
+
+// init sets up pacing for the recording in opts
+func (r *replayState) init(opts SimOptions) {
+	r.speed = opts.Speed
+	if r.speed == 0 {
+		r.speed = 1
+	}
+	r.total = len(opts.ReplayTimed)
+	if r.total > 0 {
+		r.start = opts.ReplayTimed[0].Time
+	}
+	r.changed = make(chan struct{}, 1)
+}
+
+// wait sleeps for a recorded gap at the replay speed, following speed
+// changes made meanwhile. Returns false if done closed first.
+func (r *replayState) wait(gap time.Duration, done <-chan struct{}) bool {
+	for gap > 0 {
+		r.mu.Lock()
+		speed := r.speed
+		r.mu.Unlock()
+		if math.IsInf(speed, 1) {
+			return true
+		}
+
+		started := time.Now()
+		timer := time.NewTimer(time.Duration(float64(gap) / speed))
+		select {
+		case <-timer.C:
+			return true
+		case <-r.changed:
+			timer.Stop()
+			gap -= time.Duration(float64(time.Since(started)) * speed)
+		case <-done:
+			timer.Stop()
+			return false
+		}
+	}
+	return true
+}
+
+// advance records that the first n reads have been played
+func (r *replayState) advance(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.played = n
+}
+
+// ReplayProgress reports how far a timed replay has got
+func (c *simConn) ReplayProgress() (ReplayProgress, bool) {
+	r := &c.replay
+	if r.total == 0 {
+		return ReplayProgress{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	progress := ReplayProgress{
+		Speed:    r.speed,
+		Duration: c.opts.ReplayTimed[r.total-1].Time.Sub(r.start),
+		Done:     r.played == r.total,
+	}
+	if r.played > 0 {
+		progress.Position = c.opts.ReplayTimed[r.played-1].Time.Sub(r.start)
+	}
+	return progress, true
+}
+
+// SetReplaySpeed changes the pace of a timed replay
+func (c *simConn) SetReplaySpeed(speed float64) error {
+	if speed <= 0 || math.IsNaN(speed) {
+		return fmt.Errorf("replay speed must be positive")
+	}
+	r := &c.replay
+	if r.total == 0 {
+		return fmt.Errorf("not replaying a recording")
+	}
+	r.mu.Lock()
+	r.speed = speed
+	r.mu.Unlock()
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// ReplayProgress reports the playback of a replay or simulator port
+func (p *StreamPort) ReplayProgress() (ReplayProgress, bool) {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if controller, ok := conn.(ReplayController); ok {
+		return controller.ReplayProgress()
+	}
+	return ReplayProgress{}, false
+}
+
+// SetReplaySpeed changes the pace of a replay or simulator port
+func (p *StreamPort) SetReplaySpeed(speed float64) error {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if controller, ok := conn.(ReplayController); ok {
+		return controller.SetReplaySpeed(speed)
+	}
+	return fmt.Errorf("not replaying a recording")
+}
@@ -0,0 +1,499 @@
+package serial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Telnet protocol bytes (RFC 854) and options used by RFC 2217
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetOptBinary  = 0
+	telnetOptSGA     = 3 // Suppress go-ahead
+	telnetOptComPort = 44
+)
+
+// RFC 2217 COM-PORT-OPTION commands sent by the client. The server answers
+// with the same command plus 100.
+const (
+	comSetBaudRate       = 1
+	comSetDataSize       = 2
+	comSetParity         = 3
+	comSetStopSize       = 4
+	comSetControl        = 5
+	comNotifyModemState  = 7
+	comSetLineStateMask  = 10
+	comSetModemStateMask = 11
+	comPurgeData         = 12
+	comServerOffset      = 100
+)
+
+// SET-CONTROL values
+const (
+	controlNoFlow   = 1
+	controlXonXoff  = 2
+	controlHardware = 3
+	controlBreakOn  = 5
+	controlBreakOff = 6
+	controlDTROn    = 8
+	controlDTROff   = 9
+	controlRTSOn    = 11
+	controlRTSOff   = 12
+)
+
+// NOTIFY-MODEMSTATE bits
+const (
+	modemStateCTS = 0x10
+	modemStateDSR = 0x20
+	modemStateRI  = 0x40
+	modemStateDCD = 0x80
+)
+
+// telnetMaxSubOption bounds a subnegotiation so a broken server can't grow it
+const telnetMaxSubOption = 64
+
+// telnetState is where the decoder is in the telnet byte stream
+type telnetState int
+
+const (
+	telnetData telnetState = iota
+	telnetCommand
+	telnetOption // After WILL, WONT, DO or DONT
+	telnetSub
+	telnetSubIAC
+)
+
+// RFC2217Port talks to a networked serial server (ser2net, many terminal
+// servers) using the Telnet COM Port Control Option, so the remote port's
+// baud rate, framing, flow control and modem lines can be set, unlike with
+// a raw TCP connection.
+type RFC2217Port struct {
+	conn    net.Conn
+	reader  *streamReader
+	timeout time.Duration
+	mu      sync.Mutex // Guards conn, reader and timeout
+	writeMu sync.Mutex // Keeps escaped data and commands from interleaving
+
+	// Telnet decoder, only touched by Read
+	telnet  telnetDecoder
+	pending []byte // Decoded data that didn't fit in the caller's buffer
+
+	// Line state, updated by the decoder and by control calls
+	config   SerialConfig
+	comPort  bool // Server agreed to COM-PORT-OPTION
+	modem    byte // Last NOTIFY-MODEMSTATE
+	dtr, rts bool
+	lineMu   sync.Mutex
+}
+
+// NewRFC2217Port creates an RFC 2217 client port
+func NewRFC2217Port() *RFC2217Port {
+	return &RFC2217Port{}
+}
+
+// Open connects to host:port from the port name and asks the server for
+// COM port control. The line settings are sent once the server agrees.
+func (p *RFC2217Port) Open(config SerialConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		return fmt.Errorf("serial port is already open")
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	address := strings.TrimPrefix(config.Port, RFC2217Prefix)
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	p.lineMu.Lock()
+	p.config = config
+	p.comPort, p.modem, p.dtr, p.rts = false, 0, true, true
+	p.lineMu.Unlock()
+
+	// Binary mode in both directions so bytes pass unchanged, no go-aheads
+	_, err = conn.Write([]byte{
+		telnetIAC, telnetWILL, telnetOptBinary,
+		telnetIAC, telnetDO, telnetOptBinary,
+		telnetIAC, telnetWILL, telnetOptSGA,
+		telnetIAC, telnetDO, telnetOptSGA,
+		telnetIAC, telnetWILL, telnetOptComPort,
+	})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to negotiate with %s: %w", address, err)
+	}
+
+	p.conn = conn
+	p.reader = newStreamReader(conn)
+	p.timeout = config.Timeout
+	p.telnet = telnetDecoder{}
+	p.pending = nil
+	return nil
+}
+
+// Close disconnects from the server
+func (p *RFC2217Port) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	p.reader.Close()
+	err := p.conn.Close()
+	p.conn = nil
+	if err != nil {
+		return fmt.Errorf("failed to close connection: %w", err)
+	}
+	return nil
+}
+
+// Read returns serial data with telnet commands removed. A read that only
+// carried commands returns no data.
+func (p *RFC2217Port) Read(buffer []byte) (int, error) {
+	p.mu.Lock()
+	reader, timeout := p.reader, p.timeout
+	p.mu.Unlock()
+
+	if reader == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+
+	if len(p.pending) == 0 {
+		raw := make([]byte, len(buffer))
+		n, err := reader.Read(raw, timeout)
+		if err != nil {
+			return 0, closedError("connection closed", err)
+		}
+		p.pending = p.decode(raw[:n], p.pending[:0])
+	}
+
+	n := copy(buffer, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// decode strips telnet commands from raw, appending data to out and
+// answering negotiations
+func (p *RFC2217Port) decode(raw, out []byte) []byte {
+	return p.telnet.decode(raw, out, p.negotiate, p.subnegotiation)
+}
+
+// telnetDecoder splits a telnet byte stream into data, option negotiations
+// and subnegotiations. It keeps its state between calls, so commands may be
+// split across reads.
+type telnetDecoder struct {
+	state telnetState
+	verb  byte
+	sub   []byte
+}
+
+// decode appends the data in raw to out, calling negotiate for each WILL,
+// WONT, DO and DONT and subnegotiation for each complete SB ... SE
+func (d *telnetDecoder) decode(raw, out []byte, negotiate func(verb, option byte), subnegotiation func(sub []byte)) []byte {
+	for _, b := range raw {
+		switch d.state {
+		case telnetData:
+			if b == telnetIAC {
+				d.state = telnetCommand
+			} else {
+				out = append(out, b)
+			}
+		case telnetCommand:
+			switch b {
+			case telnetIAC:
+				out = append(out, telnetIAC) // Escaped 0xFF
+				d.state = telnetData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				d.verb = b
+				d.state = telnetOption
+			case telnetSB:
+				d.sub = d.sub[:0]
+				d.state = telnetSub
+			default:
+				d.state = telnetData // NOP, GA and friends
+			}
+		case telnetOption:
+			negotiate(d.verb, b)
+			d.state = telnetData
+		case telnetSub:
+			if b == telnetIAC {
+				d.state = telnetSubIAC
+			} else if len(d.sub) < telnetMaxSubOption {
+				d.sub = append(d.sub, b)
+			}
+		case telnetSubIAC:
+			switch b {
+			case telnetSE:
+				subnegotiation(d.sub)
+				d.state = telnetData
+			case telnetIAC:
+				d.sub = append(d.sub, telnetIAC)
+				d.state = telnetSub
+			default:
+				d.state = telnetData // Malformed; drop it
+			}
+		}
+	}
+	return out
+}
+
+// negotiate answers the server's WILL/WONT/DO/DONT. Options we asked for are
+// acknowledged silently; anything else is refused.
+func (p *RFC2217Port) negotiate(verb, option byte) {
+	switch verb {
+	case telnetDO:
+		switch option {
+		case telnetOptComPort:
+			p.lineMu.Lock()
+			first := !p.comPort
+			p.comPort = true
+			p.lineMu.Unlock()
+			if first {
+				_ = p.sendLineSettings()
+			}
+		case telnetOptBinary, telnetOptSGA:
+		default:
+			_, _ = p.writeRaw([]byte{telnetIAC, telnetWONT, option})
+		}
+	case telnetDONT:
+		if option == telnetOptComPort {
+			p.lineMu.Lock()
+			p.comPort = false
+			p.lineMu.Unlock()
+		}
+	case telnetWILL:
+		switch option {
+		case telnetOptBinary, telnetOptSGA:
+		default:
+			_, _ = p.writeRaw([]byte{telnetIAC, telnetDONT, option})
+		}
+	}
+}
+
+// subnegotiation handles COM-PORT-OPTION notifications and replies
+func (p *RFC2217Port) subnegotiation(sub []byte) {
+	if len(sub) < 3 || sub[0] != telnetOptComPort {
+		return
+	}
+	p.lineMu.Lock()
+	defer p.lineMu.Unlock()
+
+	switch sub[1] {
+	case comServerOffset + comNotifyModemState:
+		p.modem = sub[2]
+	case comServerOffset + comSetBaudRate:
+		if len(sub) >= 6 {
+			// The server reports the rate it actually set
+			if rate := int(binary.BigEndian.Uint32(sub[2:6])); rate > 0 {
+				p.config.BaudRate = rate
+			}
+		}
+	}
+}
+
+// escapeIAC doubles 0xFF bytes so data isn't taken for telnet commands
+func escapeIAC(data []byte) []byte {
+	if bytes.IndexByte(data, telnetIAC) < 0 {
+		return data
+	}
+	escaped := make([]byte, 0, len(data)+8)
+	for _, b := range data {
+		escaped = append(escaped, b)
+		if b == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+	}
+	return escaped
+}
+
+// writeRaw writes bytes to the connection as they are
+func (p *RFC2217Port) writeRaw(data []byte) (int, error) {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	if conn == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return conn.Write(data)
+}
+
+// command sends a COM-PORT-OPTION subnegotiation
+func (p *RFC2217Port) command(cmd byte, value ...byte) error {
+	msg := []byte{telnetIAC, telnetSB, telnetOptComPort, cmd}
+	msg = append(msg, escapeIAC(value)...)
+	msg = append(msg, telnetIAC, telnetSE)
+	if _, err := p.writeRaw(msg); err != nil {
+		return fmt.Errorf("failed to send COM port command: %w", err)
+	}
+	return nil
+}
+
+// sendLineSettings sends baud rate, framing, flow control and modem lines
+func (p *RFC2217Port) sendLineSettings() error {
+	config := p.GetConfig()
+	baud := make([]byte, 4)
+	binary.BigEndian.PutUint32(baud, uint32(config.BaudRate))
+
+	parity := map[string]byte{"none": 1, "odd": 2, "even": 3, "mark": 4, "space": 5}[config.Parity]
+	flow := byte(controlNoFlow)
+	switch config.FlowControl {
+	case FlowXonXoff:
+		flow = controlXonXoff
+	case FlowRTSCTS:
+		flow = controlHardware
+	}
+
+	p.lineMu.Lock()
+	dtr, rts := p.dtr, p.rts
+	p.lineMu.Unlock()
+
+	steps := [][]byte{
+		append([]byte{comSetBaudRate}, baud...),
+		{comSetDataSize, byte(config.DataBits)},
+		{comSetParity, parity},
+		{comSetStopSize, byte(config.StopBits)},
+		{comSetControl, flow},
+		{comSetModemStateMask, modemStateCTS | modemStateDSR | modemStateRI | modemStateDCD},
+		{comSetControl, boolControl(dtr, controlDTROn, controlDTROff)},
+	}
+	// With hardware flow control the server drives RTS itself
+	if flow != controlHardware {
+		steps = append(steps, []byte{comSetControl, boolControl(rts, controlRTSOn, controlRTSOff)})
+	}
+	for _, step := range steps {
+		if err := p.command(step[0], step[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boolControl picks the SET-CONTROL value for a line state
+func boolControl(on bool, onValue, offValue byte) byte {
+	if on {
+		return onValue
+	}
+	return offValue
+}
+
+// Write sends data, escaping telnet command bytes
+func (p *RFC2217Port) Write(data []byte) (int, error) {
+	if _, err := p.writeRaw(escapeIAC(data)); err != nil {
+		return 0, fmt.Errorf("failed to write to serial server: %w", err)
+	}
+	return len(data), nil
+}
+
+// IsOpen returns true while connected
+func (p *RFC2217Port) IsOpen() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conn != nil
+}
+
+// GetConfig returns the current line settings, with the baud rate the
+// server reported if it adjusted it
+func (p *RFC2217Port) GetConfig() SerialConfig {
+	p.lineMu.Lock()
+	defer p.lineMu.Unlock()
+	return p.config
+}
+
+// SetReadTimeout sets how long Read waits for data
+func (p *RFC2217Port) SetReadTimeout(timeout time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	p.timeout = timeout
+	return nil
+}
+
+// GetAvailablePorts returns the local serial devices
+func (p *RFC2217Port) GetAvailablePorts() ([]string, error) {
+	return ListPorts()
+}
+
+// Reconfigure changes the remote line settings without reconnecting
+func (p *RFC2217Port) Reconfigure(config SerialConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	p.lineMu.Lock()
+	p.config.BaudRate = config.BaudRate
+	p.config.DataBits = config.DataBits
+	p.config.StopBits = config.StopBits
+	p.config.Parity = config.Parity
+	p.config.FlowControl = config.FlowControl
+	enabled := p.comPort
+	p.lineMu.Unlock()
+
+	if !enabled {
+		return fmt.Errorf("server has not enabled COM port control")
+	}
+	return p.sendLineSettings()
+}
+
+// SetDTR raises or lowers DTR on the remote port
+func (p *RFC2217Port) SetDTR(on bool) error {
+	p.lineMu.Lock()
+	p.dtr = on
+	p.lineMu.Unlock()
+	return p.command(comSetControl, boolControl(on, controlDTROn, controlDTROff))
+}
+
+// SetRTS raises or lowers RTS on the remote port
+func (p *RFC2217Port) SetRTS(on bool) error {
+	p.lineMu.Lock()
+	p.rts = on
+	p.lineMu.Unlock()
+	return p.command(comSetControl, boolControl(on, controlRTSOn, controlRTSOff))
+}
+
+// ModemStatus returns the modem lines from the server's last notification
+func (p *RFC2217Port) ModemStatus() (ModemStatus, error) {
+	p.lineMu.Lock()
+	defer p.lineMu.Unlock()
+
+	if !p.comPort {
+		return ModemStatus{}, fmt.Errorf("server has not enabled COM port control")
+	}
+	return ModemStatus{
+		CTS: p.modem&modemStateCTS != 0,
+		DSR: p.modem&modemStateDSR != 0,
+		RI:  p.modem&modemStateRI != 0,
+		DCD: p.modem&modemStateDCD != 0,
+	}, nil
+}
+
+// SendBreak holds the remote line in the break condition for d
+func (p *RFC2217Port) SendBreak(d time.Duration) error {
+	if err := p.command(comSetControl, controlBreakOn); err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return p.command(comSetControl, controlBreakOff)
+}
@@ -0,0 +1,90 @@
+package serial
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// RS485Config sets up half-duplex RS-485, where RTS switches the
+// transceiver between driving the bus and listening to it
+type RS485Config struct {
+	DelayBefore  time.Duration `json:"delay_before,omitempty"`   // RTS asserted this long before the first bit
+	DelayAfter   time.Duration `json:"delay_after,omitempty"`    // RTS held this long after the last bit
+	RTSActiveLow bool          `json:"rts_active_low,omitempty"` // Drive RTS low while sending, for inverted transceivers
+	RxDuringTx   bool          `json:"rx_during_tx,omitempty"`   // Keep receiving while sending, to see the echo (driver mode)
+	Software     bool          `json:"software,omitempty"`       // Toggle RTS from sterm even if the driver supports RS-485
+}
+
+// RS-485 direction control modes, as RS485Mode reports them
+const (
+	RS485Driver   = "driver"   // The kernel driver switches RTS on the UART
+	RS485Software = "software" // sterm raises RTS around each write
+)
+
+// RS485Reporter is implemented by ports that can run RS-485
+type RS485Reporter interface {
+	// RS485Mode returns RS485Driver, RS485Software, or "" when off
+	RS485Mode() string
+}
+
+// Validate checks the delays
+func (c RS485Config) Validate() error {
+	if c.DelayBefore < 0 || c.DelayAfter < 0 {
+		return fmt.Errorf("RS-485 delays cannot be negative")
+	}
+	return nil
+}
+
+// enableRS485 turns on RS-485 for a newly opened port, in the driver when
+// it supports it and otherwise by toggling RTS around writes
+func (sp *CrossPlatformSerialPort) enableRS485(port serial.Port, config SerialConfig) error {
+	rs485 := *config.RS485
+	if !rs485.Software {
+		restore, err := setDriverRS485(config.Port, rs485)
+		if err == nil {
+			sp.rs485Mode = RS485Driver
+			sp.rs485Restore = restore
+			return nil
+		}
+	}
+
+	// Listen until there is something to send
+	if err := port.SetRTS(rs485.RTSActiveLow); err != nil {
+		return fmt.Errorf("failed to set up RS-485: RTS can't be controlled: %w", err)
+	}
+	sp.rs485Mode = RS485Software
+	return nil
+}
+
+// RS485Mode reports how RS-485 direction is controlled, or "" when off
+func (sp *CrossPlatformSerialPort) RS485Mode() string {
+	if !sp.isOpen {
+		return ""
+	}
+	return sp.rs485Mode
+}
+
+// writeRS485 sends data with RTS driving the transceiver: asserted, a
+// pause for it to turn around, the data, then released once the data has
+// left. Drain returns when the driver has handed the data on, which for
+// USB adapters is before the last bit is on the wire; DelayAfter covers
+// the rest.
+func writeRS485(port serial.Port, config RS485Config, data []byte) (int, error) {
+	if err := port.SetRTS(!config.RTSActiveLow); err != nil {
+		return 0, fmt.Errorf("failed to assert RTS: %w", err)
+	}
+	time.Sleep(config.DelayBefore)
+
+	n, err := port.Write(data)
+	if err == nil {
+		err = port.Drain()
+	}
+	time.Sleep(config.DelayAfter)
+
+	if rtsErr := port.SetRTS(config.RTSActiveLow); err == nil && rtsErr != nil {
+		err = fmt.Errorf("failed to release RTS: %w", rtsErr)
+	}
+	return n, err
+}
@@ -0,0 +1,78 @@
+package serial
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flags of struct serial_rs485 (linux/serial.h)
+const (
+	serRS485Enabled      = 1 << 0
+	serRS485RTSOnSend    = 1 << 1
+	serRS485RTSAfterSend = 1 << 2
+	serRS485RxDuringTx   = 1 << 4
+)
+
+// serialRS485 mirrors struct serial_rs485; delays are in milliseconds
+type serialRS485 struct {
+	Flags              uint32
+	DelayRTSBeforeSend uint32
+	DelayRTSAfterSend  uint32
+	Padding            [5]uint32
+}
+
+// setDriverRS485 has the UART driver switch RTS itself (TIOCSRS485), which
+// times the turnaround to the last stop bit. It returns a function putting
+// back the previous settings, as they outlive the open device. Most USB
+// adapters and ptys don't support it.
+func setDriverRS485(device string, config RS485Config) (func(), error) {
+	fd, ok := deviceDescriptor(device)
+	if !ok {
+		return nil, fmt.Errorf("device descriptor not found")
+	}
+
+	var previous serialRS485
+	if err := ioctlRS485(fd, unix.TIOCGRS485, &previous); err != nil {
+		return nil, err
+	}
+
+	settings := serialRS485{
+		Flags:              serRS485Enabled,
+		DelayRTSBeforeSend: milliseconds(config.DelayBefore),
+		DelayRTSAfterSend:  milliseconds(config.DelayAfter),
+	}
+	if config.RTSActiveLow {
+		settings.Flags |= serRS485RTSAfterSend
+	} else {
+		settings.Flags |= serRS485RTSOnSend
+	}
+	if config.RxDuringTx {
+		settings.Flags |= serRS485RxDuringTx
+	}
+	if err := ioctlRS485(fd, unix.TIOCSRS485, &settings); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if fd, ok := deviceDescriptor(device); ok {
+			_ = ioctlRS485(fd, unix.TIOCSRS485, &previous)
+		}
+	}, nil
+}
+
+// ioctlRS485 gets or sets the RS-485 settings of a descriptor
+func ioctlRS485(fd int, request uint, settings *serialRS485) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(request), uintptr(unsafe.Pointer(settings)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// milliseconds rounds a delay up to whole milliseconds
+func milliseconds(d time.Duration) uint32 {
+	return uint32((d + time.Millisecond - 1) / time.Millisecond)
+}
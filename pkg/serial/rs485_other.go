@@ -0,0 +1,10 @@
+//go:build !linux
+
+package serial
+
+import "errors"
+
+// setDriverRS485 is Linux only; elsewhere RTS is switched from sterm
+func setDriverRS485(device string, config RS485Config) (func(), error) {
+	return nil, errors.New("RS-485 driver mode is only available on Linux")
+}
@@ -2,6 +2,7 @@
 package serial
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -20,11 +21,33 @@ type SerialConfig struct {
 	StopBits int           `json:"stop_bits"`
 	Parity   string        `json:"parity"`
 	Timeout  time.Duration `json:"timeout"`
+
+	// FlowControl is none (or empty), xonxoff or rtscts. Only applied through
+	// RFC 2217 servers; local ports are opened without flow control.
+	FlowControl string `json:"flow_control,omitempty"`
+
+	// USB picks the device by its USB IDs when it is opened, replacing Port
+	// with wherever the device is now. Port keeps the name last seen.
+	USB *USBMatch `json:"usb,omitempty"`
+
+	// RS485 runs the port half-duplex with RTS switching the transceiver
+	RS485 *RS485Config `json:"rs485,omitempty"`
+
+	// IgnoreLock opens a device even if another program holds its lock
+	// file, and takes no lock. Set per run; never saved.
+	IgnoreLock bool `json:"-"`
 }
 
+// Flow control modes
+const (
+	FlowNone    = "none"
+	FlowXonXoff = "xonxoff"
+	FlowRTSCTS  = "rtscts"
+)
+
 // Validate checks if the serial configuration is valid
 func (c SerialConfig) Validate() error {
-	if c.Port == "" {
+	if c.Port == "" && c.USB == nil {
 		return fmt.Errorf("port cannot be empty")
 	}
 
@@ -55,13 +78,28 @@ func (c SerialConfig) Validate() error {
 		}
 	}
 	if !validParityFound {
-		return fmt.Errorf("invalid parity: %s", c.Parity)
+		return fmt.Errorf("invalid parity: %q (must be one of %s)", c.Parity, strings.Join(validParity, ", "))
 	}
 
 	if c.Timeout < 0 {
 		return fmt.Errorf("timeout cannot be negative")
 	}
 
+	switch c.FlowControl {
+	case "", FlowNone, FlowXonXoff, FlowRTSCTS:
+	default:
+		return fmt.Errorf("invalid flow control: %q (must be one of %s, %s, %s)", c.FlowControl, FlowNone, FlowXonXoff, FlowRTSCTS)
+	}
+
+	if c.RS485 != nil {
+		if c.FlowControl == FlowRTSCTS {
+			return fmt.Errorf("RS-485 uses RTS for direction control and can't be combined with RTS/CTS flow control")
+		}
+		if err := c.RS485.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -118,6 +156,10 @@ type CrossPlatformSerialPort struct {
 	port   serial.Port
 	config SerialConfig
 	isOpen bool
+	lock   *portLock // Lock file held while open
+
+	rs485Mode    string // RS485Driver or RS485Software while RS-485 is on
+	rs485Restore func() // Puts back the driver's RS-485 settings on close
 }
 
 // NewCrossPlatformSerialPort creates a new cross-platform serial port instance
@@ -136,6 +178,10 @@ func (sp *CrossPlatformSerialPort) Open(config SerialConfig) error {
 	if err := config.Validate(); err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
+	config, err := ResolvePort(config)
+	if err != nil {
+		return err
+	}
 
 	// Convert our config to go.bug.st/serial config
 	mode := &serial.Mode{
@@ -145,22 +191,50 @@ func (sp *CrossPlatformSerialPort) Open(config SerialConfig) error {
 		Parity:   convertParity(config.Parity),
 	}
 
+	// Other programs that follow the lock file convention are told apart
+	// from a busy device; the device itself is opened exclusively (TIOCEXCL
+	// on Unix, no sharing on Windows)
+	lock, err := lockPort(config.Port, config.IgnoreLock)
+	if err != nil {
+		return err
+	}
+
 	port, err := serial.Open(config.Port, mode)
 	if err != nil {
-		return fmt.Errorf("failed to open serial port %s: %w", config.Port, err)
+		defer lock.release()
+		// Drivers refuse rates they can't generate with a bare EINVAL
+		var portErr *serial.PortError
+		if errors.As(err, &portErr) && portErr.Code() == serial.InvalidSerialPort {
+			if baudErr := baudRateError(config.Port, *mode); baudErr != nil {
+				return baudErr
+			}
+		}
+		return openError(config.Port, err)
 	}
 
 	// Set read timeout if specified
 	if config.Timeout > 0 {
 		if err := port.SetReadTimeout(config.Timeout); err != nil {
 			port.Close()
+			lock.release()
 			return fmt.Errorf("failed to set read timeout: %w", err)
 		}
 	}
 
+	sp.rs485Mode = ""
+	sp.rs485Restore = nil
+	if config.RS485 != nil {
+		if err := sp.enableRS485(port, config); err != nil {
+			port.Close()
+			lock.release()
+			return err
+		}
+	}
+
 	sp.port = port
 	sp.config = config
 	sp.isOpen = true
+	sp.lock = lock
 
 	return nil
 }
@@ -171,9 +245,15 @@ func (sp *CrossPlatformSerialPort) Close() error {
 		return fmt.Errorf("serial port is not open")
 	}
 
+	if sp.rs485Restore != nil {
+		sp.rs485Restore()
+		sp.rs485Restore = nil
+	}
 	err := sp.port.Close()
 	sp.port = nil
 	sp.isOpen = false
+	sp.lock.release()
+	sp.lock = nil
 
 	if err != nil {
 		return fmt.Errorf("failed to close serial port: %w", err)
@@ -202,7 +282,13 @@ func (sp *CrossPlatformSerialPort) Write(data []byte) (int, error) {
 		return 0, fmt.Errorf("serial port is not open")
 	}
 
-	n, err := sp.port.Write(data)
+	var n int
+	var err error
+	if sp.rs485Mode == RS485Software {
+		n, err = writeRS485(sp.port, *sp.config.RS485, data)
+	} else {
+		n, err = sp.port.Write(data)
+	}
 	if err != nil {
 		return n, fmt.Errorf("failed to write to serial port: %w", err)
 	}
@@ -210,6 +296,51 @@ func (sp *CrossPlatformSerialPort) Write(data []byte) (int, error) {
 	return n, nil
 }
 
+// SetDTR raises or lowers the DTR line
+func (sp *CrossPlatformSerialPort) SetDTR(on bool) error {
+	if !sp.isOpen {
+		return fmt.Errorf("serial port is not open")
+	}
+	if err := sp.port.SetDTR(on); err != nil {
+		return fmt.Errorf("failed to set DTR: %w", err)
+	}
+	return nil
+}
+
+// SetRTS raises or lowers the RTS line
+func (sp *CrossPlatformSerialPort) SetRTS(on bool) error {
+	if !sp.isOpen {
+		return fmt.Errorf("serial port is not open")
+	}
+	if err := sp.port.SetRTS(on); err != nil {
+		return fmt.Errorf("failed to set RTS: %w", err)
+	}
+	return nil
+}
+
+// ModemStatus reads the modem input lines
+func (sp *CrossPlatformSerialPort) ModemStatus() (ModemStatus, error) {
+	if !sp.isOpen {
+		return ModemStatus{}, fmt.Errorf("serial port is not open")
+	}
+	bits, err := sp.port.GetModemStatusBits()
+	if err != nil {
+		return ModemStatus{}, fmt.Errorf("failed to read modem status: %w", err)
+	}
+	return ModemStatus{CTS: bits.CTS, DSR: bits.DSR, RI: bits.RI, DCD: bits.DCD}, nil
+}
+
+// SendBreak holds the line in the break condition for d
+func (sp *CrossPlatformSerialPort) SendBreak(d time.Duration) error {
+	if !sp.isOpen {
+		return fmt.Errorf("serial port is not open")
+	}
+	if err := sp.port.Break(d); err != nil {
+		return fmt.Errorf("failed to send break: %w", err)
+	}
+	return nil
+}
+
 // IsOpen returns true if the serial port is open
 func (sp *CrossPlatformSerialPort) IsOpen() bool {
 	return sp.isOpen
@@ -282,6 +413,8 @@ type PortInfo struct {
 	PID          string `json:"pid,omitempty"`
 	SerialNumber string `json:"serial_number,omitempty"`
 	Product      string `json:"product,omitempty"`
+	ByID         string `json:"by_id,omitempty"`  // Stable /dev/serial/by-id name (Linux)
+	Driver       string `json:"driver,omitempty"` // Kernel driver, e.g. ftdi_sio (Linux)
 }
 
 // GetDetailedPortsList returns detailed information about available serial ports
@@ -302,6 +435,8 @@ func GetDetailedPortsList() ([]PortInfo, error) {
 			})
 		}
 
+		addPortDetails(portInfos)
+
 		// Sort ports by name
 		sortPorts(portInfos)
 		return portInfos, nil
@@ -321,6 +456,8 @@ func GetDetailedPortsList() ([]PortInfo, error) {
 		portInfos = append(portInfos, portInfo)
 	}
 
+	addPortDetails(portInfos)
+
 	// Sort ports by name
 	sortPorts(portInfos)
 
@@ -1,9 +1,26 @@
 package serial
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"sterm/pkg/history"
+
+	"github.com/creack/pty"
+	"go.bug.st/serial"
+	"golang.org/x/term"
 )
 
 func TestSerialConfig_Validate(t *testing.T) {
@@ -835,3 +852,845 @@ func TestHealthChecker_CheckHealthPortNotOpen(t *testing.T) {
 		t.Error("CheckHealth() should error when port is not open")
 	}
 }
+
+func TestMonitorPort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("monitor mode opens COM ports normally on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "capture")
+	if err := os.WriteFile(path, []byte("boot ok\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	port := NewMonitorPort()
+	if !IsReadOnly(port) {
+		t.Error("Monitor port should be read-only")
+	}
+	if IsReadOnly(NewSerialPort()) {
+		t.Error("Regular port should not be read-only")
+	}
+
+	config := DefaultConfig()
+	config.Port = path
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+
+	buffer := make([]byte, 64)
+	n, err := port.Read(buffer)
+	if err != nil || string(buffer[:n]) != "boot ok\r\n" {
+		t.Errorf("Read = %q, %v", buffer[:n], err)
+	}
+
+	// Nothing may be sent, including terminal query responses
+	if n, err := port.Write([]byte("\x1b[1;1R")); n != 0 || !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Write = %d, %v, want ErrReadOnly", n, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "boot ok\r\n" {
+		t.Errorf("Device was written to: %q", data)
+	}
+}
+
+func TestMonitorPortEcho(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads the line settings of a Linux pty")
+	}
+	master, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer master.Close()
+	defer tty.Close()
+
+	// A fresh tty echoes, which would send received data back
+	config := DefaultConfig()
+	config.Port = tty.Name()
+	port := NewMonitorPort()
+	if err := port.Open(config); err == nil || !strings.Contains(err.Error(), "echo") {
+		port.Close()
+		t.Fatalf("Open of an echoing tty = %v, want it refused", err)
+	}
+
+	if _, err := term.MakeRaw(int(tty.Fd())); err != nil {
+		t.Fatal(err)
+	}
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open of a raw tty failed: %v", err)
+	}
+	port.Close()
+}
+
+func TestNewPortFor(t *testing.T) {
+	if _, ok := NewPortFor("pty:").(*PTYPort); !ok {
+		t.Error("pty: should select the pty backend")
+	}
+	if _, ok := NewPortFor("/dev/ttyUSB0").(*CrossPlatformSerialPort); !ok {
+		t.Error("Device names should select a serial port")
+	}
+	if IsDevicePort("pty:bash") || !IsDevicePort("COM3") {
+		t.Error("IsDevicePort misclassified a port")
+	}
+}
+
+func TestPTYPort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no pseudo-terminals on Windows")
+	}
+	if _, err := os.Stat("/bin/cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	port := NewPTYPort()
+	config := DefaultConfig()
+	config.Port = "pty:/bin/cat"
+	config.Timeout = 100 * time.Millisecond
+	if err := port.Open(config); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer port.Close()
+
+	if err := port.Resize(100, 30); err != nil {
+		t.Errorf("Resize failed: %v", err)
+	}
+	if _, err := port.Write([]byte("ping\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// The tty echoes the line and cat prints it again
+	var got []byte
+	buffer := make([]byte, 256)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && strings.Count(string(got), "ping") < 2 {
+		n, err := port.Read(buffer)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		got = append(got, buffer[:n]...)
+	}
+	if strings.Count(string(got), "ping") < 2 {
+		t.Errorf("Read %q, want echo and cat output", got)
+	}
+
+	// Once the command exits reads report EOF
+	if _, err := port.Write([]byte{4}); err != nil { // Ctrl+D
+		t.Fatalf("Write failed: %v", err)
+	}
+	for time.Now().Before(deadline) {
+		if _, err := port.Read(buffer); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("Read error = %v, want EOF", err)
+			}
+			return
+		}
+	}
+	t.Error("No EOF after the command exited")
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"", nil},
+		{"bash -l", []string{"bash", "-l"}},
+		{`qemu-system-arm  -serial stdio -append "console=ttyAMA0 quiet"`, []string{"qemu-system-arm", "-serial", "stdio", "-append", "console=ttyAMA0 quiet"}},
+		{`sh -c 'echo hi' ""`, []string{"sh", "-c", "echo hi", ""}},
+	}
+
+	for _, tt := range tests {
+		got := splitCommand(tt.line)
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) || len(got) != len(tt.expected) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.line, got, tt.expected)
+		}
+	}
+}
+
+// readUntil reads from port until want has been received or time runs out
+func readUntil(t *testing.T, port SerialPort, want string) string {
+	t.Helper()
+	var got []byte
+	buffer := make([]byte, 256)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && !strings.Contains(string(got), want) {
+		n, err := port.Read(buffer)
+		if err != nil {
+			t.Fatalf("Read failed after %q: %v", got, err)
+		}
+		got = append(got, buffer[:n]...)
+	}
+	return string(got)
+}
+
+func TestStreamPortSocket(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+
+	// A device that greets, echoes one line and hangs up
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("login: "))
+		line := make([]byte, 64)
+		n, _ := conn.Read(line)
+		_, _ = conn.Write(line[:n])
+	}()
+
+	config := DefaultConfig()
+	config.Port = TCPPrefix + listener.Addr().String()
+	config.Timeout = 100 * time.Millisecond
+	port := NewPortFor(config.Port)
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+
+	if got := readUntil(t, port, "login: "); got != "login: " {
+		t.Errorf("Read %q", got)
+	}
+	if _, err := port.Write([]byte("root\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readUntil(t, port, "root\n"); got != "root\n" {
+		t.Errorf("Read %q", got)
+	}
+
+	// The server hanging up is reported as EOF
+	buffer := make([]byte, 16)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if _, err := port.Read(buffer); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("Read error = %v, want EOF", err)
+			}
+			return
+		}
+	}
+	t.Error("No EOF after the server hung up")
+}
+
+func TestStreamPortExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses cat")
+	}
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	config := DefaultConfig()
+	config.Port = ExecPrefix + "cat"
+	config.Timeout = 100 * time.Millisecond
+	port := NewPortFor(config.Port)
+	if _, ok := port.(*StreamPort); !ok {
+		t.Fatalf("exec: selected %T", port)
+	}
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if _, err := port.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readUntil(t, port, "hello\n"); got != "hello\n" {
+		t.Errorf("Read %q", got)
+	}
+	if err := port.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if port.IsOpen() {
+		t.Error("Port still open after Close")
+	}
+}
+
+func TestRFC2217Port(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+
+	// A server that agrees to COM port control, reports CTS and DCD, sends
+	// data with an escaped 0xFF and records everything the client sends
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte{
+			telnetIAC, telnetDO, telnetOptComPort,
+			telnetIAC, telnetSB, telnetOptComPort, comServerOffset + comNotifyModemState, modemStateCTS | modemStateDCD, telnetIAC, telnetSE,
+			'a', telnetIAC, telnetIAC, 'b',
+		})
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	config := DefaultConfig()
+	config.Port = RFC2217Prefix + listener.Addr().String()
+	config.BaudRate = 9600
+	config.FlowControl = FlowRTSCTS
+	config.Timeout = 100 * time.Millisecond
+	port, ok := NewPortFor(config.Port).(*RFC2217Port)
+	if !ok {
+		t.Fatalf("NewPortFor(%q) is not an RFC2217Port", config.Port)
+	}
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if got := readUntil(t, port, "b"); got != "a\xffb" {
+		t.Errorf("Read %q, want %q", got, "a\xffb")
+	}
+	status, err := port.ModemStatus()
+	if err != nil {
+		t.Fatalf("ModemStatus failed: %v", err)
+	}
+	if want := (ModemStatus{CTS: true, DCD: true}); status != want {
+		t.Errorf("ModemStatus = %+v, want %+v", status, want)
+	}
+	if _, err := port.Write([]byte{'x', 0xff}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	port.Close()
+
+	var data []byte
+	select {
+	case data = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server received nothing")
+	}
+	for _, want := range [][]byte{
+		{telnetIAC, telnetWILL, telnetOptComPort},
+		{telnetIAC, telnetSB, telnetOptComPort, comSetBaudRate, 0, 0, 0x25, 0x80, telnetIAC, telnetSE},
+		{telnetIAC, telnetSB, telnetOptComPort, comSetControl, controlHardware, telnetIAC, telnetSE},
+		{'x', telnetIAC, telnetIAC},
+	} {
+		if !bytes.Contains(data, want) {
+			t.Errorf("Server did not receive % x in % x", want, data)
+		}
+	}
+	// RTS belongs to the server with hardware flow control
+	if bytes.Contains(data, []byte{comSetControl, controlRTSOn}) {
+		t.Error("RTS was set despite hardware flow control")
+	}
+}
+
+// bridgeTestDevice records what a Bridge does to the port it shares
+type bridgeTestDevice struct {
+	written []byte
+	config  SerialConfig
+	mu      sync.Mutex
+}
+
+func (d *bridgeTestDevice) Write(data []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written = append(d.written, data...)
+	return len(data), nil
+}
+
+func (d *bridgeTestDevice) Config() SerialConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.config
+}
+
+func (d *bridgeTestDevice) Reconfigure(config SerialConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config = config
+	return nil
+}
+
+func (d *bridgeTestDevice) Modem() ModemController { return nil }
+
+func TestBridgeRFC2217(t *testing.T) {
+	device := &bridgeTestDevice{config: DefaultConfig()}
+	device.config.Port = "/dev/ttyUSB0"
+	device.config.BaudRate = 9600
+	bridge, err := NewBridge("127.0.0.1:0", true, device)
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer bridge.Close()
+
+	// Our own RFC 2217 client asks for its line settings on connect
+	config := DefaultConfig()
+	config.Port = RFC2217Prefix + bridge.Addr()
+	config.BaudRate = 115200
+	config.Timeout = 100 * time.Millisecond
+	port := NewRFC2217Port()
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && bridge.Clients() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bridge.Broadcast([]byte("boot\xff>"))
+	if got := readUntil(t, port, ">"); got != "boot\xff>" {
+		t.Errorf("Client read %q, want %q", got, "boot\xff>")
+	}
+
+	// Reading answers the bridge's DO COM-PORT-OPTION with the settings
+	buffer := make([]byte, 64)
+	for time.Now().Before(deadline) && device.Config().BaudRate != 115200 {
+		_, _ = port.Read(buffer)
+	}
+	if rate := device.Config().BaudRate; rate != 115200 {
+		t.Errorf("Device baud rate = %d, want the client's 115200", rate)
+	}
+
+	if _, err := port.Write([]byte("AT\r\xff")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for time.Now().Before(deadline) {
+		device.mu.Lock()
+		written := string(device.written)
+		device.mu.Unlock()
+		if written == "AT\r\xff" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	device.mu.Lock()
+	defer device.mu.Unlock()
+	if string(device.written) != "AT\r\xff" {
+		t.Errorf("Device received %q, want the client data without telnet commands", device.written)
+	}
+}
+
+func TestEscapeIAC(t *testing.T) {
+	if got := escapeIAC([]byte("plain")); string(got) != "plain" {
+		t.Errorf("escapeIAC(plain) = %q", got)
+	}
+	if got, want := escapeIAC([]byte{1, 0xff, 2}), []byte{1, 0xff, 0xff, 2}; !bytes.Equal(got, want) {
+		t.Errorf("escapeIAC = % x, want % x", got, want)
+	}
+}
+
+func TestParseSimOptions(t *testing.T) {
+	opts, err := ParseSimOptions("latency=5ms,chunk=4,drop=0.5,seed=7")
+	if err != nil {
+		t.Fatalf("ParseSimOptions failed: %v", err)
+	}
+	want := SimOptions{Echo: true, Latency: 5 * time.Millisecond, Chunk: 4, DropRate: 0.5, Seed: 7}
+	if fmt.Sprint(opts) != fmt.Sprint(want) {
+		t.Errorf("ParseSimOptions = %+v, want %+v", opts, want)
+	}
+
+	for _, spec := range []string{"bogus", "latency=fast", "corrupt=2", "chunk=-1", "script=/nonexistent"} {
+		if _, err := ParseSimOptions(spec); err == nil {
+			t.Errorf("ParseSimOptions(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestParseSimScript(t *testing.T) {
+	script := `# Boot banner, then a login prompt
+"" => "U-Boot 2024.01\r\n"
+"root\r" => "Password: "
+"\x03" => "^C\r\n=> "
+`
+	rules, err := parseSimScript(strings.NewReader(script), "test")
+	if err != nil {
+		t.Fatalf("parseSimScript failed: %v", err)
+	}
+	want := []SimRule{
+		{Trigger: "", Reply: "U-Boot 2024.01\r\n"},
+		{Trigger: "root\r", Reply: "Password: "},
+		{Trigger: "\x03", Reply: "^C\r\n=> "},
+	}
+	if fmt.Sprint(rules) != fmt.Sprint(want) {
+		t.Errorf("rules = %q, want %q", rules, want)
+	}
+
+	if _, err := parseSimScript(strings.NewReader(`"a" "b"`), "test"); err == nil {
+		t.Error("Rule without => accepted")
+	}
+}
+
+func TestSimulatorPort(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "login.sim")
+	if err := os.WriteFile(script, []byte("\"\" => \"login: \"\n\"root\\r\" => \"Password: \"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Port = SimPrefix + "echo,script=" + script + ",chunk=2"
+	config.Timeout = 100 * time.Millisecond
+	port := NewPortFor(config.Port)
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+
+	if got := readUntil(t, port, "login: "); got != "login: " {
+		t.Errorf("Read %q", got)
+	}
+	if _, err := port.Write([]byte("root\r")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// Typed data is echoed before the reply
+	if got := readUntil(t, port, "Password: "); got != "root\rPassword: " {
+		t.Errorf("Read %q", got)
+	}
+
+	// A bare prefix is an echoing device
+	config.Port = SimPrefix
+	bare := NewPortFor(config.Port)
+	if err := bare.Open(config); err != nil {
+		t.Fatalf("Open(%q) failed: %v", config.Port, err)
+	}
+	defer bare.Close()
+	if _, err := bare.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readUntil(t, bare, "ping"); got != "ping" {
+		t.Errorf("Bare simulator read %q", got)
+	}
+}
+
+func TestSimulatorHangupAndErrors(t *testing.T) {
+	dir := t.TempDir()
+	replay := filepath.Join(dir, "boot.log")
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	if err := os.WriteFile(replay, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same seed corrupts the same bytes every run
+	read := func(spec string) ([]byte, error) {
+		config := DefaultConfig()
+		config.Port = SimPrefix + spec
+		config.Timeout = 100 * time.Millisecond
+		port := NewPortFor(config.Port)
+		if err := port.Open(config); err != nil {
+			t.Fatalf("Open(%q) failed: %v", spec, err)
+		}
+		defer port.Close()
+
+		var got []byte
+		buffer := make([]byte, 64)
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			n, err := port.Read(buffer)
+			got = append(got, buffer[:n]...)
+			if err != nil {
+				return got, err
+			}
+		}
+		return got, nil
+	}
+
+	got, err := read("replay=" + replay + ",hangup=25")
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Read error = %v, want EOF after hangup", err)
+	}
+	if !bytes.Equal(got, data[:25]) {
+		t.Errorf("Read %q before hangup, want %q", got, data[:25])
+	}
+
+	first, _ := read("replay=" + replay + ",corrupt=0.2,hangup=100")
+	second, _ := read("replay=" + replay + ",corrupt=0.2,hangup=100")
+	if bytes.Equal(first, data) {
+		t.Error("No bytes corrupted")
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("Corruption differs between runs:\n%q\n%q", first, second)
+	}
+}
+
+func TestUSBMatch(t *testing.T) {
+	match, err := ParseUSBMatch("0403:6001:A50285BI")
+	if err != nil {
+		t.Fatalf("ParseUSBMatch failed: %v", err)
+	}
+	if match.VID != "0403" || match.PID != "6001" || match.SerialNumber != "A50285BI" || match.String() != "0403:6001:A50285BI" {
+		t.Errorf("ParseUSBMatch = %+v", match)
+	}
+	if match, err := ParseUSBMatch("10C4:EA60"); err != nil || match.String() != "10c4:ea60" {
+		t.Errorf("ParseUSBMatch without serial = %v, %v", match, err)
+	}
+	for _, bad := range []string{"", "0403", "403:6001", "xyz1:6001"} {
+		if _, err := ParseUSBMatch(bad); err == nil {
+			t.Errorf("ParseUSBMatch(%q) accepted", bad)
+		}
+	}
+
+	ports := []PortInfo{
+		{Name: "/dev/ttyS0"},
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "B1"},
+		{Name: "/dev/ttyUSB1", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A50285BI"},
+		{Name: "/dev/ttyUSB2", IsUSB: true, VID: "10C4", PID: "EA60"},
+	}
+	tests := []struct {
+		match string
+		port  string
+	}{
+		{"0403:6001:A50285BI", "/dev/ttyUSB1"},
+		{"10c4:ea60", "/dev/ttyUSB2"},
+		{"0403:6001", ""}, // Two adapters can't be told apart
+		{"0403:6001:C3", ""},
+		{"1a86:7523", ""},
+	}
+	for _, tt := range tests {
+		match, _ := ParseUSBMatch(tt.match)
+		port, err := selectUSBPort(ports, *match)
+		if port != tt.port || (err != nil) != (tt.port == "") {
+			t.Errorf("selectUSBPort(%s) = %q, %v; want %q", tt.match, port, err, tt.port)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Port = ""
+	if cfg.Validate() == nil {
+		t.Error("Config without a port or USB match is valid")
+	}
+	cfg.USB = match
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Config with only a USB match is invalid: %v", err)
+	}
+}
+
+func TestHotplug(t *testing.T) {
+	tests := []struct {
+		message string
+		event   HotplugEvent
+		ok      bool
+	}{
+		{"add@/devices/pci0000:00/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0\x00ACTION=add\x00DEVPATH=/devices/pci0000:00/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0\x00SUBSYSTEM=tty\x00MAJOR=188\x00MINOR=0\x00DEVNAME=ttyUSB0\x00SEQNUM=4021\x00",
+			HotplugEvent{Port: "/dev/ttyUSB0", Added: true}, true},
+		{"remove@/devices/.../tty/ttyACM1\x00ACTION=remove\x00SUBSYSTEM=tty\x00DEVNAME=ttyACM1\x00",
+			HotplugEvent{Port: "/dev/ttyACM1"}, true},
+		{"add@/devices/.../1-2:1.0\x00ACTION=add\x00SUBSYSTEM=usb\x00DEVNAME=bus/usb/001/007\x00", HotplugEvent{}, false},
+		{"change@/devices/.../tty/ttyUSB0\x00ACTION=change\x00SUBSYSTEM=tty\x00DEVNAME=ttyUSB0\x00", HotplugEvent{}, false},
+	}
+	for _, tt := range tests {
+		event, ok := parseUevent([]byte(tt.message))
+		if event != tt.event || ok != tt.ok {
+			t.Errorf("parseUevent(%q) = %+v, %v; want %+v, %v", tt.message, event, ok, tt.event, tt.ok)
+		}
+	}
+
+	device := filepath.Join(t.TempDir(), "ttyTEST0")
+	cfg := DefaultConfig()
+	cfg.Port = device
+	if DevicePresent(cfg) {
+		t.Error("Missing device reported present")
+	}
+	if err := os.WriteFile(device, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !DevicePresent(cfg) {
+		t.Error("Device not reported present")
+	}
+
+	watcher := WatchHotplug()
+	watcher.Close()
+	watcher.Close()
+}
+
+func TestActualBaudRate(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("baud rates are only read back on Linux")
+	}
+	master, slave, err := pty.Open()
+	if err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer master.Close()
+	name := slave.Name()
+	slave.Close()
+
+	// A rate without a Bxxx constant goes through termios2
+	port := NewCrossPlatformSerialPort()
+	config := DefaultConfig()
+	config.Port = name
+	config.BaudRate = 250000
+	config.IgnoreLock = true
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got := port.ActualBaudRate(); got != 250000 {
+		t.Errorf("ActualBaudRate = %d, want 250000", got)
+	}
+	port.Close()
+	if got := port.ActualBaudRate(); got != 0 {
+		t.Errorf("ActualBaudRate after Close = %d", got)
+	}
+
+	// A pty takes any rate, so the rate isn't what failed
+	if err := baudRateError(name, serial.Mode{BaudRate: 1843200, DataBits: 8}); err != nil {
+		t.Errorf("baudRateError = %v for a rate the driver accepts", err)
+	}
+}
+
+// rs485TestPort records the RTS changes and writes RS-485 makes
+type rs485TestPort struct {
+	serial.Port
+	events []string
+}
+
+func (p *rs485TestPort) SetRTS(rts bool) error {
+	p.events = append(p.events, fmt.Sprintf("rts=%v", rts))
+	return nil
+}
+
+func (p *rs485TestPort) Write(data []byte) (int, error) {
+	p.events = append(p.events, "write "+string(data))
+	return len(data), nil
+}
+
+func (p *rs485TestPort) Drain() error {
+	p.events = append(p.events, "drain")
+	return nil
+}
+
+func TestRS485(t *testing.T) {
+	port := &rs485TestPort{}
+	n, err := writeRS485(port, RS485Config{DelayAfter: time.Millisecond}, []byte("\x01\x03"))
+	if n != 2 || err != nil {
+		t.Errorf("writeRS485 = %d, %v", n, err)
+	}
+	want := "rts=true,write \x01\x03,drain,rts=false"
+	if got := strings.Join(port.events, ","); got != want {
+		t.Errorf("Events = %q, want %q", got, want)
+	}
+
+	port = &rs485TestPort{}
+	_, _ = writeRS485(port, RS485Config{RTSActiveLow: true}, []byte("x"))
+	if got := strings.Join(port.events, ","); got != "rts=false,write x,drain,rts=true" {
+		t.Errorf("Inverted events = %q", got)
+	}
+
+	cfg := DefaultConfig()
+	cfg.RS485 = &RS485Config{DelayBefore: time.Millisecond}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("RS-485 config invalid: %v", err)
+	}
+	cfg.FlowControl = FlowRTSCTS
+	if cfg.Validate() == nil {
+		t.Error("RS-485 with RTS/CTS flow control accepted")
+	}
+	cfg.FlowControl = FlowNone
+	cfg.RS485.DelayAfter = -time.Millisecond
+	if cfg.Validate() == nil {
+		t.Error("Negative RS-485 delay accepted")
+	}
+}
+
+func TestSimulatorTimedReplay(t *testing.T) {
+	// A history export replays read by read, with the recorded gaps
+	start := time.Now()
+	mgr := history.NewMemoryHistoryManager(1024)
+	_ = mgr.WriteTimed([]byte("bootOK"), history.DirectionOutput, []history.ChunkTime{
+		{Offset: 0, Time: start},
+		{Offset: 4, Time: start.Add(200 * time.Millisecond)},
+	})
+	_ = mgr.Write([]byte("typed"), history.DirectionInput)
+	replay := filepath.Join(t.TempDir(), "session.json")
+	if err := mgr.SaveToFile(replay, history.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Port = SimPrefix + "replay=" + replay
+	config.Timeout = 100 * time.Millisecond
+	port := NewPortFor(config.Port)
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+
+	opened := time.Now()
+	var got []byte
+	buffer := make([]byte, 64)
+	for deadline := time.Now().Add(5 * time.Second); len(got) < 6 && time.Now().Before(deadline); {
+		n, _ := port.Read(buffer)
+		got = append(got, buffer[:n]...)
+	}
+	if string(got) != "bootOK" {
+		t.Errorf("Replayed %q, want only the received data", got)
+	}
+	if elapsed := time.Since(opened); elapsed < 150*time.Millisecond {
+		t.Errorf("Replay took %v, want the recorded 200ms gap", elapsed)
+	}
+}
+
+func TestReplayPort(t *testing.T) {
+	// A timestamped export with a long gap, which a speed change cuts short
+	replay := filepath.Join(t.TempDir(), "session.log")
+	log := "[2024-05-01 10:00:00.000] >> boot\\n\n" +
+		"[2024-05-01 10:00:00.100] << help\r\n" +
+		"[2024-05-01 10:00:10.000] >> login: \n"
+	if err := os.WriteFile(replay, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.Port = ReplayPrefix + replay
+	config.Timeout = 50 * time.Millisecond
+	port := NewPortFor(config.Port)
+	if err := port.Open(config); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer port.Close()
+	controller, ok := port.(ReplayController)
+	if !ok {
+		t.Fatal("Replay port has no replay controls")
+	}
+
+	read := func(want int) string {
+		var got []byte
+		buffer := make([]byte, 64)
+		for deadline := time.Now().Add(2 * time.Second); len(got) < want && time.Now().Before(deadline); {
+			n, _ := port.Read(buffer)
+			got = append(got, buffer[:n]...)
+		}
+		return string(got)
+	}
+	if got := read(5); got != "boot\n" {
+		t.Errorf("Replayed %q first, want only the received data", got)
+	}
+	progress, ok := controller.ReplayProgress()
+	if !ok || progress.Speed != 1 || progress.Duration != 10*time.Second || progress.Done {
+		t.Errorf("Progress = %+v, %v", progress, ok)
+	}
+
+	// Typed data goes nowhere
+	if _, err := port.Write([]byte("ls\r")); err != nil {
+		t.Errorf("Write failed: %v", err)
+	}
+	if err := controller.SetReplaySpeed(math.Inf(1)); err != nil {
+		t.Fatalf("SetReplaySpeed failed: %v", err)
+	}
+	if got := read(7); got != "login: " {
+		t.Errorf("Replayed %q after speeding up, want the rest at once", got)
+	}
+	if progress, _ = controller.ReplayProgress(); !progress.Done || progress.Position != progress.Duration {
+		t.Errorf("Progress at the end = %+v", progress)
+	}
+
+	for _, value := range []string{"0", "-1", "fast", "NaN"} {
+		if _, err := ParseReplaySpeed(value); err == nil {
+			t.Errorf("ParseReplaySpeed(%q) should fail", value)
+		}
+	}
+	if speed, err := ParseReplaySpeed("2.5x"); err != nil || speed != 2.5 || FormatReplaySpeed(speed) != "2.5x" {
+		t.Errorf("ParseReplaySpeed(2.5x) = %v, %v", speed, err)
+	}
+}
@@ -0,0 +1,363 @@
+package serial
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sterm/pkg/history"
+)
+
+// simMaxInput bounds the input kept for matching script triggers
+const simMaxInput = 4096
+
+// SimOptions describe a simulated device. They are parsed from the part of
+// a port name after "sim:", a comma-separated list such as
+// "echo,script=device.sim,latency=20ms".
+type SimOptions struct {
+	Echo        bool                // Send typed data back
+	Script      []SimRule           // Replies to input, from script=FILE
+	Replay      []byte              // Sent once on connect, from replay=FILE
+	ReplayTimed []history.TimedData // Sent with the recorded timing, from replay= of a JSON or timestamped history file
+	Speed       float64             // Pace of ReplayTimed: 2 plays twice as fast, +Inf without gaps (0 = as recorded)
+	Latency     time.Duration       // Delay before each reply or chunk
+	Chunk       int                 // Largest piece output is sent in (0 for whole replies)
+	DropRate    float64             // Chance of each output byte being lost
+	CorruptRate float64             // Chance of each output byte having a bit flipped
+	HangupAfter int                 // Hang up after this many output bytes (0 never)
+	Seed        int64               // Random seed for error injection, so runs repeat
+}
+
+// SimRule is a scripted reply: when input contains Trigger, Reply is sent.
+// A rule with an empty trigger is sent on connect.
+type SimRule struct {
+	Trigger string
+	Reply   string
+}
+
+// ParseSimOptions parses a simulator description. With no script or replay
+// file the device echoes.
+func ParseSimOptions(spec string) (SimOptions, error) {
+	opts := SimOptions{Seed: 1}
+	for _, field := range strings.Split(spec, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		var err error
+		switch key {
+		case "":
+		case "echo":
+			opts.Echo = true
+		case "script":
+			opts.Script, err = LoadSimScript(value)
+		case "replay":
+			opts.Replay, opts.ReplayTimed, err = loadReplay(value)
+		case "latency":
+			opts.Latency, err = time.ParseDuration(value)
+		case "chunk":
+			opts.Chunk, err = strconv.Atoi(value)
+		case "drop":
+			opts.DropRate, err = parseRate(value)
+		case "corrupt":
+			opts.CorruptRate, err = parseRate(value)
+		case "hangup":
+			opts.HangupAfter, err = strconv.Atoi(value)
+		case "seed":
+			opts.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "speed":
+			opts.Speed, err = ParseReplaySpeed(value)
+		default:
+			return SimOptions{}, fmt.Errorf("unknown simulator option %q", key)
+		}
+		if err != nil {
+			return SimOptions{}, fmt.Errorf("invalid simulator option %s: %w", key, err)
+		}
+	}
+
+	if opts.Latency < 0 || opts.Chunk < 0 || opts.HangupAfter < 0 {
+		return SimOptions{}, fmt.Errorf("simulator latency, chunk and hangup cannot be negative")
+	}
+	if opts.Script == nil && opts.Replay == nil && opts.ReplayTimed == nil {
+		opts.Echo = true
+	}
+	return opts, nil
+}
+
+// loadReplay reads a replay file. A JSON or timestamped history export is
+// played back with the timing it was received with, read by read when it
+// was saved with read times; anything else is sent as it is. Gzipped files
+// are decompressed first.
+func loadReplay(path string) ([]byte, []history.TimedData, error) {
+	data, err := history.ReadHistoryFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("[")) {
+		return data, nil, nil
+	}
+	entries, err := history.ParseEntries(data)
+	if err != nil {
+		return data, nil, nil // Not history after all
+	}
+
+	var timed []history.TimedData
+	for _, entry := range entries {
+		if entry.Direction == history.DirectionOutput {
+			timed = append(timed, entry.Pieces()...)
+		}
+	}
+	if len(timed) == 0 {
+		return nil, nil, fmt.Errorf("%s has no received data to replay", path)
+	}
+	return nil, timed, nil
+}
+
+// parseRate parses a probability between 0 and 1
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("%v is not between 0 and 1", rate)
+	}
+	return rate, nil
+}
+
+// LoadSimScript reads a simulator script. Each line is a rule
+//
+//	"trigger" => "reply"
+//
+// with Go-quoted strings, so escapes such as \r\n and \x1b work. Blank
+// lines and lines starting with # are ignored.
+func LoadSimScript(path string) ([]SimRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script: %w", err)
+	}
+	defer file.Close()
+	return parseSimScript(file, path)
+}
+
+// parseSimScript parses script rules from r; name is used in errors
+func parseSimScript(r io.Reader, name string) ([]SimRule, error) {
+	rules := []SimRule{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		triggerText, replyText, found := strings.Cut(line, "=>")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected \"trigger\" => \"reply\"", name, lineNum)
+		}
+		trigger, err := strconv.Unquote(strings.TrimSpace(triggerText))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid trigger: %w", name, lineNum, err)
+		}
+		reply, err := strconv.Unquote(strings.TrimSpace(replyText))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid reply: %w", name, lineNum, err)
+		}
+		rules = append(rules, SimRule{Trigger: trigger, Reply: reply})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return rules, nil
+}
+
+// simConn is a simulated device. Replies are queued and sent by a goroutine
+// that applies latency, chunking and error injection, so a slow reader
+// doesn't block typing.
+type simConn struct {
+	opts    SimOptions
+	input   []byte // Received since the last trigger matched
+	replay  replayState
+	queue   chan []byte
+	reader  *io.PipeReader
+	writer  *io.PipeWriter
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+}
+
+// openSimulator starts a simulated device described by spec
+func openSimulator(spec string) (io.ReadWriteCloser, error) {
+	opts, err := ParseSimOptions(spec)
+	if err != nil {
+		return nil, err
+	}
+	return newSimConn(opts), nil
+}
+
+// newSimConn starts a simulated device, queueing the replay data and
+// connect replies
+func newSimConn(opts SimOptions) *simConn {
+	reader, writer := io.Pipe()
+	c := &simConn{
+		opts:    opts,
+		queue:   make(chan []byte, 64),
+		reader:  reader,
+		writer:  writer,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	c.replay.init(opts)
+
+	if len(opts.Replay) > 0 {
+		c.queue <- opts.Replay
+	}
+	for _, rule := range opts.Script {
+		if rule.Trigger == "" && len(c.queue) < cap(c.queue) {
+			c.queue <- []byte(rule.Reply)
+		}
+	}
+	go c.run()
+	return c
+}
+
+// run sends queued output until the device is closed or hangs up
+func (c *simConn) run() {
+	defer close(c.stopped)
+	rng := rand.New(rand.NewSource(c.opts.Seed))
+	sent := 0
+
+	// Recorded output keeps the gaps it arrived with, at the replay speed
+	for i, piece := range c.opts.ReplayTimed {
+		if i > 0 && !c.replay.wait(piece.Time.Sub(c.opts.ReplayTimed[i-1].Time), c.done) {
+			return
+		}
+		c.replay.advance(i + 1)
+		if _, err := c.writer.Write(c.inject(rng, piece.Data)); err != nil {
+			return
+		}
+	}
+
+	for {
+		var data []byte
+		select {
+		case data = <-c.queue:
+		case <-c.done:
+			return
+		}
+
+		for len(data) > 0 {
+			n := len(data)
+			if c.opts.Chunk > 0 {
+				n = min(n, c.opts.Chunk)
+			}
+			if c.opts.HangupAfter > 0 {
+				n = min(n, c.opts.HangupAfter-sent)
+			}
+			chunk := c.inject(rng, data[:n])
+			data = data[n:]
+			sent += n
+
+			if c.opts.Latency > 0 {
+				select {
+				case <-time.After(c.opts.Latency):
+				case <-c.done:
+					return
+				}
+			}
+			if _, err := c.writer.Write(chunk); err != nil {
+				return
+			}
+			if c.opts.HangupAfter > 0 && sent >= c.opts.HangupAfter {
+				c.writer.Close() // Reads see EOF, like a device going away
+				return
+			}
+		}
+	}
+}
+
+// inject drops and corrupts bytes at the configured rates
+func (c *simConn) inject(rng *rand.Rand, data []byte) []byte {
+	if c.opts.DropRate == 0 && c.opts.CorruptRate == 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	for _, b := range data {
+		if c.opts.DropRate > 0 && rng.Float64() < c.opts.DropRate {
+			continue
+		}
+		if c.opts.CorruptRate > 0 && rng.Float64() < c.opts.CorruptRate {
+			b ^= 1 << rng.Intn(8)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// Read returns simulated output
+func (c *simConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// Write takes typed data: it is echoed and matched against the script
+func (c *simConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.stopped:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	var replies [][]byte
+	if c.opts.Echo {
+		replies = append(replies, append([]byte(nil), p...))
+	}
+	if len(c.opts.Script) > 0 {
+		c.input = append(c.input, p...)
+		for matched := true; matched; {
+			matched = false
+			for _, rule := range c.opts.Script {
+				if rule.Trigger == "" {
+					continue
+				}
+				if i := bytes.Index(c.input, []byte(rule.Trigger)); i >= 0 {
+					c.input = c.input[i+len(rule.Trigger):]
+					replies = append(replies, []byte(rule.Reply))
+					matched = true
+					break
+				}
+			}
+		}
+		if len(c.input) > simMaxInput {
+			c.input = c.input[len(c.input)-simMaxInput:]
+		}
+	}
+
+	for _, reply := range replies {
+		select {
+		case c.queue <- reply:
+		case <-c.stopped:
+			return 0, io.ErrClosedPipe
+		}
+	}
+	return len(p), nil
+}
+
+// Close stops the device
+func (c *simConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		// Unblocks a write to the pipe nobody is reading any more
+		c.reader.Close()
+		<-c.stopped
+		c.writer.Close()
+	})
+	return nil
+}
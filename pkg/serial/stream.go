@@ -0,0 +1,217 @@
+package serial
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamOpener connects to the target named after a backend prefix
+type streamOpener func(target string) (io.ReadWriteCloser, error)
+
+// StreamPort connects the terminal to a byte stream that isn't a serial
+// device: a socket, a named pipe, a subprocess or a simulated device. Line
+// settings don't apply and are ignored.
+type StreamPort struct {
+	prefix  string
+	open    streamOpener
+	config  SerialConfig
+	conn    io.ReadWriteCloser
+	reader  *streamReader
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+// newStreamPort creates a port for names starting with prefix
+func newStreamPort(prefix string, open streamOpener) *StreamPort {
+	return &StreamPort{prefix: prefix, open: open}
+}
+
+// Open connects to the target in the port name
+func (p *StreamPort) Open(config SerialConfig) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		return fmt.Errorf("serial port is already open")
+	}
+	target := strings.TrimPrefix(config.Port, p.prefix)
+	// A bare "sim:" is the simulator's default, an echoing device
+	if target == "" && p.prefix != SimPrefix {
+		return fmt.Errorf("no target given after %q", p.prefix)
+	}
+
+	conn, err := p.open(target)
+	if err != nil {
+		return err
+	}
+	p.config = config
+	p.conn = conn
+	p.reader = newStreamReader(conn)
+	p.timeout = config.Timeout
+	return nil
+}
+
+// Close disconnects
+func (p *StreamPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return nil
+	}
+	p.reader.Close()
+	err := p.conn.Close()
+	p.conn = nil
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %w", p.config.Port, err)
+	}
+	return nil
+}
+
+// Read reads received data. io.EOF is returned once the other end is gone.
+func (p *StreamPort) Read(buffer []byte) (int, error) {
+	p.mu.Lock()
+	reader, timeout := p.reader, p.timeout
+	p.mu.Unlock()
+
+	if reader == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+	n, err := reader.Read(buffer, timeout)
+	if err != nil {
+		return n, closedError("connection closed", err)
+	}
+	return n, nil
+}
+
+// Write sends data
+func (p *StreamPort) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+
+	if conn == nil {
+		return 0, fmt.Errorf("serial port is not open")
+	}
+	n, err := conn.Write(data)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to %s: %w", p.config.Port, err)
+	}
+	return n, nil
+}
+
+// IsOpen returns true while connected
+func (p *StreamPort) IsOpen() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conn != nil
+}
+
+// GetConfig returns the configuration the port was opened with
+func (p *StreamPort) GetConfig() SerialConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config
+}
+
+// SetReadTimeout sets how long Read waits for data
+func (p *StreamPort) SetReadTimeout(timeout time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		return fmt.Errorf("serial port is not open")
+	}
+	p.timeout = timeout
+	p.config.Timeout = timeout
+	return nil
+}
+
+// GetAvailablePorts returns the serial devices
+func (p *StreamPort) GetAvailablePorts() ([]string, error) {
+	return ListPorts()
+}
+
+// dialSocket returns an opener that connects to a socket address
+func dialSocket(network string) streamOpener {
+	return func(address string) (io.ReadWriteCloser, error) {
+		conn, err := net.DialTimeout(network, address, 5*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+		return conn, nil
+	}
+}
+
+// processConn is the standard input and output of a running subprocess.
+// Standard error is merged into the output so startup errors are visible.
+type processConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output *os.File
+	exited chan struct{}
+}
+
+// startProcess runs a command line and connects to its standard streams
+func startProcess(command string) (io.ReadWriteCloser, error) {
+	args := splitCommand(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	output, outputWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+
+	if err := cmd.Start(); err != nil {
+		output.Close()
+		outputWriter.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+	// Only the child keeps the write end, so reads see EOF when it exits
+	outputWriter.Close()
+
+	conn := &processConn{cmd: cmd, stdin: stdin, output: output, exited: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		close(conn.exited)
+	}()
+	return conn, nil
+}
+
+// Read reads the subprocess output
+func (c *processConn) Read(p []byte) (int, error) {
+	return c.output.Read(p)
+}
+
+// Write writes to the subprocess input
+func (c *processConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+// Close closes the subprocess input, giving it a moment to exit before it
+// is killed
+func (c *processConn) Close() error {
+	_ = c.stdin.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(time.Second):
+		_ = c.cmd.Process.Kill()
+		<-c.exited
+	}
+	return c.output.Close()
+}
@@ -0,0 +1,111 @@
+package serial
+
+import (
+	"fmt"
+	"strings"
+)
+
+// USBMatch picks a USB serial adapter by its vendor and product IDs, and
+// optionally its serial number, instead of by a port name such as
+// /dev/ttyUSB0 that can change when devices are plugged in another order
+type USBMatch struct {
+	VID          string `json:"vid"`
+	PID          string `json:"pid"`
+	SerialNumber string `json:"serial_number,omitempty"`
+}
+
+// ParseUSBMatch parses "VID:PID" or "VID:PID:SERIAL", with the IDs in hex
+// as `sterm list -d` shows them, e.g. "0403:6001:A50285BI"
+func ParseUSBMatch(text string) (*USBMatch, error) {
+	parts := strings.SplitN(strings.TrimSpace(text), ":", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid USB match %q (expected VID:PID or VID:PID:SERIAL)", text)
+	}
+	match := &USBMatch{VID: strings.ToLower(parts[0]), PID: strings.ToLower(parts[1])}
+	for _, id := range []string{match.VID, match.PID} {
+		if len(id) != 4 || strings.Trim(id, "0123456789abcdef") != "" {
+			return nil, fmt.Errorf("invalid USB ID %q in %q (expected 4 hex digits)", id, text)
+		}
+	}
+	if len(parts) == 3 {
+		match.SerialNumber = parts[2]
+	}
+	return match, nil
+}
+
+// String formats the match the way ParseUSBMatch reads it
+func (m USBMatch) String() string {
+	if m.SerialNumber != "" {
+		return m.VID + ":" + m.PID + ":" + m.SerialNumber
+	}
+	return m.VID + ":" + m.PID
+}
+
+// Matches reports whether a port belongs to the device
+func (m USBMatch) Matches(info PortInfo) bool {
+	if !info.IsUSB || !strings.EqualFold(info.VID, m.VID) || !strings.EqualFold(info.PID, m.PID) {
+		return false
+	}
+	return m.SerialNumber == "" || info.SerialNumber == m.SerialNumber
+}
+
+// USBMatchFor returns the match for the USB device behind a port, for
+// saving with a profile
+func USBMatchFor(port string) (*USBMatch, error) {
+	ports, err := GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range ports {
+		if info.Name == port || info.ByID == port {
+			if !info.IsUSB || info.VID == "" {
+				return nil, fmt.Errorf("%s is not a USB device", port)
+			}
+			return &USBMatch{VID: strings.ToLower(info.VID), PID: strings.ToLower(info.PID), SerialNumber: info.SerialNumber}, nil
+		}
+	}
+	return nil, fmt.Errorf("port %s not found", port)
+}
+
+// FindUSBPort returns the port of the connected device a match picks
+func FindUSBPort(match USBMatch) (string, error) {
+	ports, err := GetDetailedPortsList()
+	if err != nil {
+		return "", err
+	}
+	return selectUSBPort(ports, match)
+}
+
+// selectUSBPort picks the one port a match selects. Several identical
+// adapters without serial numbers can't be told apart, so that is an error
+// rather than a guess.
+func selectUSBPort(ports []PortInfo, match USBMatch) (string, error) {
+	var found []string
+	for _, info := range ports {
+		if match.Matches(info) {
+			found = append(found, info.Name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no USB device %s is connected", match)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%d USB devices match %s (%s); add the serial number or use a /dev/serial/by-id path", len(found), match, strings.Join(found, ", "))
+	}
+}
+
+// ResolvePort fills in the port of a configuration that picks its device by
+// USB IDs, so the device is found whatever name it was given this time
+func ResolvePort(config SerialConfig) (SerialConfig, error) {
+	if config.USB == nil {
+		return config, nil
+	}
+	port, err := FindUSBPort(*config.USB)
+	if err != nil {
+		return config, fmt.Errorf("failed to find serial port: %w", err)
+	}
+	config.Port = port
+	return config, nil
+}
@@ -0,0 +1,33 @@
+package serial
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// byIDDir holds the stable names udev gives serial devices, built from the
+// USB vendor, product and serial number
+const byIDDir = "/dev/serial/by-id"
+
+// addPortDetails fills in what sysfs and udev know about each port beyond
+// the enumerator: its /dev/serial/by-id name and the kernel driver
+// (ftdi_sio, cp210x, cdc_acm, ...)
+func addPortDetails(ports []PortInfo) {
+	byID := make(map[string]string)
+	if entries, err := os.ReadDir(byIDDir); err == nil {
+		for _, entry := range entries {
+			link := filepath.Join(byIDDir, entry.Name())
+			if target, err := filepath.EvalSymlinks(link); err == nil {
+				byID[target] = link
+			}
+		}
+	}
+
+	for i := range ports {
+		ports[i].ByID = byID[ports[i].Name]
+		driver, err := os.Readlink(filepath.Join("/sys/class/tty", filepath.Base(ports[i].Name), "device", "driver"))
+		if err == nil {
+			ports[i].Driver = filepath.Base(driver)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package serial
+
+// addPortDetails adds nothing on systems without sysfs and udev; the
+// enumerator already reports the USB IDs
+func addPortDetails(ports []PortInfo) {}
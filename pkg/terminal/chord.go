@@ -0,0 +1,66 @@
+package terminal
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// DefaultChordTimeout is how long the command key of a chord is waited for
+// after the prefix key
+const DefaultChordTimeout = 2 * time.Second
+
+// ChordResult is what a key means to a prefix key chord
+type ChordResult int
+
+const (
+	ChordNone    ChordResult = iota // Not part of a chord
+	ChordPending                    // The prefix key; the command key comes next
+	ChordCommand                    // The command key of a chord
+	ChordRepeat                     // The prefix key pressed twice, which sends it to the device
+)
+
+// SetChordPrefix makes the local keys chords: the prefix key, then a command
+// key within timeout, like screen's Ctrl+A. KeyNUL turns chords off.
+func (sm *ShortcutManager) SetChordPrefix(prefix tcell.Key, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultChordTimeout
+	}
+	sm.chordPrefix = prefix
+	sm.chordTimeout = timeout
+	sm.chordStarted = time.Time{}
+}
+
+// ChordPrefix returns the prefix key, KeyNUL if chords are off
+func (sm *ShortcutManager) ChordPrefix() tcell.Key {
+	return sm.chordPrefix
+}
+
+// ProcessChord follows a key through a chord. A command key that comes
+// after the timeout is an ordinary key again.
+func (sm *ShortcutManager) ProcessChord(key tcell.Key, mods tcell.ModMask) ChordResult {
+	if sm.chordPrefix == tcell.KeyNUL {
+		return ChordNone
+	}
+
+	pending := sm.ChordWaiting()
+	sm.chordStarted = time.Time{}
+	isPrefix := key == sm.chordPrefix && mods&^tcell.ModCtrl == 0
+
+	switch {
+	case pending && isPrefix:
+		return ChordRepeat
+	case pending:
+		return ChordCommand
+	case isPrefix:
+		sm.chordStarted = time.Now()
+		return ChordPending
+	}
+	return ChordNone
+}
+
+// ChordWaiting reports whether the prefix key was pressed and the command
+// key is still awaited
+func (sm *ShortcutManager) ChordWaiting() bool {
+	return !sm.chordStarted.IsZero() && time.Since(sm.chordStarted) < sm.chordTimeout
+}
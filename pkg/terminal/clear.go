@@ -0,0 +1,61 @@
+package terminal
+
+import "fmt"
+
+// ClearPolicy controls what clearing the whole screen (ED 2) and erasing
+// the saved lines (ED 3) do to the scrollback
+type ClearPolicy int
+
+const (
+	ClearPush    ClearPolicy = iota // ED 2 moves the lines on screen into scrollback; ED 3 is ignored
+	ClearDiscard                    // ED 2 drops the lines on screen; ED 3 is ignored
+	ClearOnED3                      // ED 2 drops the lines on screen and ED 3 erases the scrollback, like xterm
+)
+
+// String returns the settings name of the policy
+func (p ClearPolicy) String() string {
+	switch p {
+	case ClearDiscard:
+		return "discard"
+	case ClearOnED3:
+		return "ed3"
+	default:
+		return "push"
+	}
+}
+
+// ParseClearPolicy parses a policy name as used in the settings file
+func ParseClearPolicy(name string) (ClearPolicy, error) {
+	switch name {
+	case "", "push":
+		return ClearPush, nil
+	case "discard":
+		return ClearDiscard, nil
+	case "ed3":
+		return ClearOnED3, nil
+	default:
+		return ClearPush, fmt.Errorf("unknown clear policy %q", name)
+	}
+}
+
+// SetClearPolicy sets what clearing the screen does to the scrollback.
+// Pushing the screen into scrollback keeps everything the device showed,
+// but a program redrawing with ED 2 leaves a copy of the screen in the
+// scrollback each time.
+func (te *TerminalEmulator) SetClearPolicy(policy ClearPolicy) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.clearPolicy = policy
+}
+
+// eraseSavedLines carries out ED 3, erasing the scrollback if the policy
+// lets the device do so. The screen is left as it is.
+func (te *TerminalEmulator) eraseSavedLines() {
+	if te.clearPolicy != ClearOnED3 {
+		if te.logger != nil {
+			te.logger.Debugf("[clearScreen] Mode 3 ignored with the %s clear policy", te.clearPolicy)
+		}
+		return
+	}
+	te.ClearScrollback()
+}
@@ -0,0 +1,37 @@
+package terminal
+
+import "unicode/utf16"
+
+// RuneComposer joins the UTF-16 surrogate halves that the Windows console
+// delivers as two key events for a character outside the Basic Multilingual
+// Plane, such as an emoji or a rare CJK ideograph committed by an IME, so
+// the device gets the character's complete UTF-8 sequence rather than two
+// replacement characters. Characters composed by the host, from dead keys
+// or an IME, otherwise arrive whole and pass straight through.
+type RuneComposer struct {
+	high rune // First half waiting for the second
+}
+
+// Add takes the next typed character and returns the complete one. Returns
+// false while waiting for the second half; a half without its partner is
+// dropped.
+func (c *RuneComposer) Add(r rune) (rune, bool) {
+	high := c.high
+	c.high = 0
+	switch {
+	case !utf16.IsSurrogate(r):
+		return r, true
+	case r < 0xDC00:
+		c.high = r
+		return 0, false
+	case high != 0:
+		return utf16.DecodeRune(high, r), true
+	}
+	return 0, false
+}
+
+// Reset drops a first half still waiting, when another kind of key comes
+// in between
+func (c *RuneComposer) Reset() {
+	c.high = 0
+}
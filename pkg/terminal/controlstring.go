@@ -0,0 +1,81 @@
+package terminal
+
+// maxOSCLength is the longest OSC string kept; longer ones are read to
+// their end and dropped. It leaves room for the longest hyperlink.
+const maxOSCLength = 4096
+
+// maxDCSLength is the longest DCS string kept, large enough for tmux
+// passthrough of a full screen redraw
+const maxDCSLength = 1 << 20
+
+// handleString processes the control strings: OSC, ended by BEL or ST
+// (ESC \), and DCS, SOS, PM and APC, ended by ST only. An ESC followed by
+// anything else cuts the string short and starts a new escape sequence,
+// except in tmux passthrough, where ESC ESC stands for one ESC of the
+// wrapped sequence. CAN and SUB abandon the string. A string longer than
+// its limit is read to its end like any other and then dropped, so
+// garbage on the line can't grow the buffer without bound.
+func (vt *VTParser) handleString(b byte, screen *Screen, state *TerminalState) []Action {
+	if vt.stringEscape {
+		vt.stringEscape = false
+		switch {
+		case b == '\\':
+			return vt.endString()
+		case b == 0x1B && vt.State == StateDCS && vt.tmuxPassthrough():
+			vt.appendString(b)
+			return nil
+		}
+		vt.Reset()
+		vt.stringCancelled = true
+		vt.State = StateEscape
+		return vt.handleEscape(b, screen, state)
+	}
+
+	switch b {
+	case 0x1B: // ESC, starting ST
+		vt.stringEscape = true
+		return nil
+	case 0x07: // BEL, the end of an OSC but part of the other strings
+		if vt.State == StateOSC {
+			return vt.endString()
+		}
+	case 0x18, 0x1A: // CAN, SUB
+		vt.Reset()
+		return nil
+	}
+	vt.appendString(b)
+	return nil
+}
+
+// appendString adds a byte to the control string, up to its limit. SOS,
+// PM and APC strings are skipped, so nothing of them is kept.
+func (vt *VTParser) appendString(b byte) {
+	limit := 0
+	switch vt.State {
+	case StateOSC:
+		limit = maxOSCLength
+	case StateDCS:
+		limit = maxDCSLength
+	}
+	if len(vt.Buffer) >= limit {
+		vt.stringOverflow = vt.State != StateString
+		return
+	}
+	vt.Buffer = append(vt.Buffer, b)
+}
+
+// endString carries out the complete control string, unless it was too
+// long, and returns to the ground state
+func (vt *VTParser) endString() []Action {
+	var actions []Action
+	if !vt.stringOverflow {
+		switch vt.State {
+		case StateOSC:
+			actions = vt.processOSC()
+		case StateDCS:
+			actions = vt.processDCS()
+		}
+	}
+	vt.Reset()
+	return actions
+}
@@ -0,0 +1,104 @@
+package terminal
+
+// maxSequenceRecord is how much of an escape sequence is kept; longer ones,
+// like OSC strings, are cut off
+const maxSequenceRecord = 64
+
+// sequenceRecord keeps the escape sequence being parsed and the last
+// complete one
+type sequenceRecord struct {
+	current []byte
+	last    string
+	count   uint64
+}
+
+// add records a byte parsed outside the ground state, or the one that
+// ended a sequence when done is set
+func (r *sequenceRecord) add(b byte, done bool) {
+	if len(r.current) < maxSequenceRecord {
+		r.current = append(r.current, b)
+	}
+	if done {
+		r.last = string(r.current)
+		r.current = r.current[:0]
+		r.count++
+	}
+}
+
+// cancel ends the sequence being recorded at its last byte, an ESC that
+// cut a string short, and starts the next sequence with that ESC
+func (r *sequenceRecord) cancel() {
+	if n := len(r.current); n > 0 && r.current[n-1] == 0x1B {
+		r.last = string(r.current[:n-1])
+	} else {
+		r.last = string(r.current)
+	}
+	r.current = append(r.current[:0], 0x1B)
+	r.count++
+}
+
+// String returns the name of a parser state
+func (s ParserState) String() string {
+	switch s {
+	case StateGround:
+		return "ground"
+	case StateEscape:
+		return "escape"
+	case StateCSI:
+		return "csi"
+	case StateOSC:
+		return "osc"
+	case StateDCS:
+		return "dcs"
+	case StateString:
+		return "string"
+	case StateVT52Cursor:
+		return "vt52-cursor"
+	default:
+		return "unknown"
+	}
+}
+
+// DebugInfo is a snapshot of the emulator's internals, for a debug overlay
+type DebugInfo struct {
+	ParserState  ParserState
+	Partial      string // Bytes of the sequence being parsed
+	LastSequence string // The last complete escape sequence, cut off after 64 bytes
+	Sequences    uint64 // Escape sequences parsed
+
+	CursorX, CursorY int
+	AltScreen        bool
+	ScrollbackLines  int
+
+	// Rows waiting to be drawn: the dirty lines and their bounds, and rows
+	// scrolled since the last render
+	DirtyLines           int
+	DirtyMinY, DirtyMaxY int
+	Scrolled             RowScroll
+}
+
+// DebugInfo returns the parser state, the last escape sequence and what
+// the renderer has yet to draw
+func (te *TerminalEmulator) DebugInfo() DebugInfo {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+
+	info := DebugInfo{
+		ParserState:     te.parser.State,
+		Partial:         string(te.sequences.current),
+		LastSequence:    te.sequences.last,
+		Sequences:       te.sequences.count,
+		CursorX:         te.state.CursorX,
+		CursorY:         te.state.CursorY,
+		AltScreen:       te.useAltScreen,
+		ScrollbackLines: len(te.scrollbackBuffer),
+	}
+
+	screen := te.GetScreen()
+	screen.mutex.RLock()
+	defer screen.mutex.RUnlock()
+	info.DirtyLines = len(screen.DirtyLines)
+	info.DirtyMinY, info.DirtyMaxY = screen.DirtyMinY, screen.DirtyMaxY
+	info.Scrolled = screen.Scrolled
+	return info
+}
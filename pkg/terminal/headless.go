@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// HeadlessTerminal is a terminal emulator with no serial port and no
+// display, for test automation such as hardware-in-the-loop rigs: device
+// output is written to it and the screen is read back as text, so tests can
+// assert on what the device displayed. The embedded TerminalEmulator gives
+// access to the full state.
+type HeadlessTerminal struct {
+	*TerminalEmulator
+
+	mu        sync.Mutex
+	responses []byte // Answers to device queries not yet read
+}
+
+// AttributeRun is a run of cells on one row with the same attributes
+type AttributeRun struct {
+	Row        int // Screen row, from 0
+	StartCol   int // First column
+	EndCol     int // Column after the last one
+	Attributes TextAttributes
+}
+
+// NewHeadlessTerminal creates a running emulator with a screen of width
+// columns and height rows
+func NewHeadlessTerminal(width, height int) *HeadlessTerminal {
+	h := &HeadlessTerminal{TerminalEmulator: NewTerminalEmulator(nil, nil, width, height)}
+	h.SetResponseCallback(func(response []byte) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.responses = append(h.responses, response...)
+	})
+	_ = h.Start()
+	return h
+}
+
+// Write feeds device output to the emulator, so a headless terminal can be
+// the destination of io.Copy
+func (h *HeadlessTerminal) Write(p []byte) (int, error) {
+	if err := h.ProcessOutput(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Responses returns the answers the emulator gave to device queries, like
+// cursor position reports, since the last call. A rig that talks to a real
+// device sends them back to it.
+func (h *HeadlessTerminal) Responses() []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	responses := h.responses
+	h.responses = nil
+	return responses
+}
+
+// Lines returns the text of each screen row with trailing blanks trimmed
+func (h *HeadlessTerminal) Lines() []string {
+	h.TerminalEmulator.mu.RLock()
+	defer h.TerminalEmulator.mu.RUnlock()
+
+	buffer := h.GetScreen().Buffer
+	lines := make([]string, len(buffer))
+	for y, line := range buffer {
+		var sb strings.Builder
+		for _, cell := range line {
+			sb.WriteString(cell.String())
+		}
+		lines[y] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+// Text returns the screen as plain text, one line per row, with trailing
+// blanks and blank rows at the bottom trimmed
+func (h *HeadlessTerminal) Text() string {
+	lines := h.Lines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Cursor returns the cursor position, from 0. After a character is written
+// in the last column x is the screen width until the next one wraps.
+func (h *HeadlessTerminal) Cursor() (x, y int) {
+	state := h.GetState()
+	return state.CursorX, state.CursorY
+}
+
+// AttributeRuns returns the runs of cells whose attributes differ from the
+// default, row by row
+func (h *HeadlessTerminal) AttributeRuns() []AttributeRun {
+	h.TerminalEmulator.mu.RLock()
+	defer h.TerminalEmulator.mu.RUnlock()
+
+	defaults := DefaultTextAttributes()
+	var runs []AttributeRun
+	for y, line := range h.GetScreen().Buffer {
+		for x := 0; x < len(line); x++ {
+			attrs := line[x].Attributes
+			if attrs == defaults {
+				continue
+			}
+			end := x + 1
+			for end < len(line) && line[end].Attributes == attrs {
+				end++
+			}
+			runs = append(runs, AttributeRun{Row: y, StartCol: x, EndCol: end, Attributes: attrs})
+			x = end - 1
+		}
+	}
+	return runs
+}
+
+// AttributeDump returns the attribute runs as text, one per line, as
+// "row,start-end attributes" with 1-based inclusive columns, e.g.
+// "1,1-4 bold fg=red"
+func (h *HeadlessTerminal) AttributeDump() string {
+	var sb strings.Builder
+	for _, run := range h.AttributeRuns() {
+		fmt.Fprintf(&sb, "%d,%d-%d %s\n", run.Row+1, run.StartCol+1, run.EndCol, run.Attributes)
+	}
+	return sb.String()
+}
+
+// String lists the attributes that differ from the default, e.g.
+// "bold underline=3 fg=red", with the underline style as in SGR 4:n.
+// Default attributes give an empty string.
+func (a TextAttributes) String() string {
+	var parts []string
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{a.Bold, "bold"},
+		{a.Italic, "italic"},
+		{a.Blink, "blink"},
+		{a.Reverse, "reverse"},
+	}
+	for _, f := range flags {
+		if f.set {
+			parts = append(parts, f.name)
+		}
+	}
+	if a.Underline {
+		parts = append(parts, fmt.Sprintf("underline=%d", int(a.UnderlineStyle)+1))
+	}
+	if a.Foreground != ColorDefault {
+		parts = append(parts, "fg="+a.Foreground.String())
+	}
+	if a.Background != ColorDefault {
+		parts = append(parts, "bg="+a.Background.String())
+	}
+	if a.UnderlineColor != ColorDefault {
+		parts = append(parts, "ulcolor="+a.UnderlineColor.String())
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,67 @@
+package terminal
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxHyperlinkURI is the longest OSC 8 URI accepted; longer ones are
+// ignored and the text is shown as plain text
+const maxHyperlinkURI = 2048
+
+// Hyperlink is an OSC 8 link. Characters printed while one is active carry
+// it, so the link moves with the text when the screen scrolls. Cells of the
+// same link share one Hyperlink.
+type Hyperlink struct {
+	ID  string `json:"id,omitempty"` // id= parameter; links with the same ID and URI are one link
+	URI string `json:"uri"`
+}
+
+// processOSC carries out a complete OSC sequence held in the buffer:
+// a number, a semicolon and the arguments
+func (vt *VTParser) processOSC() []Action {
+	command, args, _ := strings.Cut(string(vt.Buffer), ";")
+	switch command {
+	case "8":
+		return vt.hyperlinkAction(args)
+	}
+	return nil
+}
+
+// hyperlinkAction parses the arguments of OSC 8, "params;URI", into an
+// action starting a link, or ending it when the URI is empty. The params
+// are key=value pairs separated by colons; only id is used. URIs without a
+// scheme are ignored, so nothing but a URL is ever opened.
+func (vt *VTParser) hyperlinkAction(args string) []Action {
+	params, uri, ok := strings.Cut(args, ";")
+	if !ok {
+		return nil
+	}
+	if uri == "" {
+		return []Action{{Type: ActionSetHyperlink, Data: (*Hyperlink)(nil)}}
+	}
+	if len(uri) > maxHyperlinkURI {
+		return nil
+	}
+	if u, err := url.Parse(uri); err != nil || u.Scheme == "" {
+		return nil
+	}
+
+	link := &Hyperlink{URI: uri}
+	for _, param := range strings.Split(params, ":") {
+		if id, ok := strings.CutPrefix(param, "id="); ok {
+			link.ID = id
+		}
+	}
+	return []Action{{Type: ActionSetHyperlink, Data: link}}
+}
+
+// setHyperlink makes characters printed from now on part of link, or of
+// no link when it is nil. A link with the same ID and URI as the active
+// one continues it.
+func (te *TerminalEmulator) setHyperlink(link *Hyperlink) {
+	if current := te.state.Hyperlink; link != nil && current != nil && link.ID != "" && *link == *current {
+		return
+	}
+	te.state.Hyperlink = link
+}
@@ -0,0 +1,87 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxIgnoredKinds is how many different ignored sequences are counted
+// separately; the rest are counted together, so garbage on the line can't
+// grow the counts without bound
+const maxIgnoredKinds = 100
+
+// ignoredOther is the summary the ignored sequences past maxIgnoredKinds
+// are counted under
+const ignoredOther = "other sequences"
+
+// SequenceCount is how many times an escape sequence was ignored
+type SequenceCount struct {
+	Sequence string // The sequence as in SequenceTrace.Summary
+	Count    int
+}
+
+// ignoredSequences counts the escape sequences the emulator did nothing
+// with, by summary
+type ignoredSequences struct {
+	counts map[string]int
+	total  int
+}
+
+// add counts an ignored sequence
+func (s *ignoredSequences) add(summary string) {
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	if _, ok := s.counts[summary]; !ok && len(s.counts) >= maxIgnoredKinds {
+		summary = ignoredOther
+	}
+	s.counts[summary]++
+	s.total++
+}
+
+// Summary returns the sequence in readable form with the parts that vary
+// from use to use left out: the string of an OSC, which is summarized by
+// its command number, the data of a DCS, summarized by what comes before
+// it, and the string of an SOS, PM or APC, summarized by its kind. CSI and
+// other escape sequences are given whole, like "CSI ?2026h".
+func (t SequenceTrace) Summary() string {
+	switch t.Kind {
+	case "OSC", "SOS", "PM", "APC":
+		return t.Name()
+	case "DCS":
+		// Parameters, intermediates and the final byte start the string
+		end := strings.IndexFunc(t.Params, func(r rune) bool { return r >= 0x40 && r <= 0x7E })
+		if end < 0 {
+			return "DCS " + escapeControls(t.Params)
+		}
+		return "DCS " + escapeControls(t.Params[:end+1])
+	}
+	return t.Sequence()
+}
+
+// String gives the count and the sequence, like "17× CSI ?1004h ignored"
+func (c SequenceCount) String() string {
+	return fmt.Sprintf("%d× %s ignored", c.Count, c.Sequence)
+}
+
+// IgnoredSequences returns the escape sequences the emulator did nothing
+// with since it was created, because it doesn't know them or doesn't
+// support what they ask for, most frequent first, and how many were
+// ignored in all. A device whose display comes out wrong often sends one
+// of these.
+func (te *TerminalEmulator) IgnoredSequences() (counts []SequenceCount, total int) {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+
+	for sequence, count := range te.ignored.counts {
+		counts = append(counts, SequenceCount{Sequence: sequence, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Sequence < counts[j].Sequence
+	})
+	return counts, te.ignored.total
+}
@@ -0,0 +1,221 @@
+package terminal
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Kitty keyboard protocol flags. Only disambiguating escape codes is
+// supported; the other flags a device asks for are left off, which it sees
+// when it queries them.
+const (
+	kittyDisambiguate   = 1
+	kittySupportedFlags = kittyDisambiguate
+
+	// kittyStackLimit is how many pushed flag sets are kept; pushing more
+	// drops the oldest
+	kittyStackLimit = 16
+)
+
+// KeyProtocolChange is the Data of ActionSetKeyProtocol: a new
+// modifyOtherKeys level, or an operation on the kitty keyboard flags
+type KeyProtocolChange struct {
+	ModifyOtherKeys *int // XTMODKEYS level 0-2
+
+	// Kitty is the operation: '>' pushes Flags, '<' pops Count entries,
+	// '=' sets (Mode 1), adds (2) or removes (3) Flags. 0 for none.
+	Kitty byte
+	Flags int
+	Mode  int
+	Count int
+}
+
+// paramPrefix returns the private marker that starts the parameters of a
+// CSI sequence ('<', '=', '>' or '?'), 0 if there is none
+func (vt *VTParser) paramPrefix() byte {
+	if len(vt.Intermediate) > 0 && vt.Intermediate[0] == '?' {
+		return '?'
+	}
+	if len(vt.Buffer) > 0 && vt.Buffer[0] >= '<' && vt.Buffer[0] <= '?' {
+		return vt.Buffer[0]
+	}
+	return 0
+}
+
+// modifyOtherKeys handles XTMODKEYS (CSI > 4 ; level m) and its reset
+// (CSI > 4 n). Other key modifier resources aren't supported.
+func (vt *VTParser) modifyOtherKeys(disable bool) []Action {
+	if len(vt.Params) == 0 || vt.Params[0] != 4 {
+		return nil
+	}
+	level := 0
+	if !disable {
+		level = min(vt.getParam(1, 0), 2)
+	}
+	return []Action{{Type: ActionSetKeyProtocol, Data: KeyProtocolChange{ModifyOtherKeys: &level}}}
+}
+
+// kittyKeyboard handles the kitty keyboard protocol sequences ending in u:
+// push (CSI > flags u), pop (CSI < n u), set (CSI = flags ; mode u) and
+// query (CSI ? u), answered with the current flags
+func (vt *VTParser) kittyKeyboard(prefix byte, state *TerminalState) []Action {
+	change := KeyProtocolChange{Kitty: prefix}
+	switch prefix {
+	case '?':
+		return []Action{{Type: ActionSendResponse, Data: fmt.Sprintf("\x1b[?%du", state.KittyKeyboard)}}
+	case '>':
+		change.Flags = vt.getParam(0, 0)
+	case '<':
+		change.Count = max(vt.getParam(0, 1), 1)
+	case '=':
+		change.Flags = vt.getParam(0, 0)
+		change.Mode = vt.getParam(1, 1)
+	default:
+		return nil
+	}
+	return []Action{{Type: ActionSetKeyProtocol, Data: change}}
+}
+
+// setKeyProtocol applies a keyboard protocol change
+func (te *TerminalEmulator) setKeyProtocol(change KeyProtocolChange) {
+	if change.ModifyOtherKeys != nil {
+		te.state.ModifyOtherKeys = *change.ModifyOtherKeys
+		te.logDebug("modifyOtherKeys level %d", te.state.ModifyOtherKeys)
+	}
+
+	flags := change.Flags & kittySupportedFlags
+	switch change.Kitty {
+	case '>':
+		te.kittyStack = append(te.kittyStack, te.state.KittyKeyboard)
+		if len(te.kittyStack) > kittyStackLimit {
+			te.kittyStack = te.kittyStack[1:]
+		}
+		te.state.KittyKeyboard = flags
+	case '<':
+		for i := 0; i < change.Count && len(te.kittyStack) > 0; i++ {
+			te.state.KittyKeyboard = te.kittyStack[len(te.kittyStack)-1]
+			te.kittyStack = te.kittyStack[:len(te.kittyStack)-1]
+		}
+		if len(te.kittyStack) == 0 && change.Count > 0 {
+			te.state.KittyKeyboard = 0
+		}
+	case '=':
+		switch change.Mode {
+		case 1:
+			te.state.KittyKeyboard = flags
+		case 2:
+			te.state.KittyKeyboard |= flags
+		case 3:
+			te.state.KittyKeyboard &^= flags
+		}
+	default:
+		return
+	}
+	te.logDebug("Kitty keyboard flags %d", te.state.KittyKeyboard)
+}
+
+// SetKeyProtocol makes keys with modifiers use the modifyOtherKeys
+// encoding at the given level (0 off, 1 or 2), or the kitty keyboard
+// protocol when kittyFlags has its disambiguate flag, which wins
+func (kh *KeyHandler) SetKeyProtocol(modifyOtherKeys, kittyFlags int) {
+	kh.modifyOtherKeys = modifyOtherKeys
+	kh.kittyFlags = kittyFlags
+}
+
+// protocolKey encodes a key with the keyboard protocol the device asked
+// for. Returns false to use the legacy encoding: for keys either protocol
+// leaves alone, like cursor and function keys, and for keys whose legacy
+// encoding is already unambiguous.
+func (kh *KeyHandler) protocolKey(key tcell.Key, char rune, mods tcell.ModMask) ([]byte, bool) {
+	if kh.kittyFlags&kittyDisambiguate == 0 && kh.modifyOtherKeys == 0 {
+		return nil, false
+	}
+	code, mods, ok := keyCode(key, char, mods)
+	if !ok {
+		return nil, false
+	}
+	modParam := 1
+	if mods&tcell.ModShift != 0 {
+		modParam += 1
+	}
+	if mods&tcell.ModAlt != 0 {
+		modParam += 2
+	}
+	if mods&tcell.ModCtrl != 0 {
+		modParam += 4
+	}
+	special := code == '\r' || code == '\t' || code == 0x7F || code == 0x1B
+
+	if kh.kittyFlags&kittyDisambiguate != 0 {
+		switch {
+		case code == 0x1B && modParam == 1:
+			return []byte("\x1b[27u"), true
+		case modParam == 1, modParam == 2 && !special:
+			return nil, false // Text, shifted or not
+		}
+		// Keys are reported unshifted, with Shift in the modifiers
+		return []byte(fmt.Sprintf("\x1b[%d;%du", unicode.ToLower(code), modParam)), true
+	}
+
+	// modifyOtherKeys: level 2 encodes every modified key, level 1 only
+	// those without a legacy encoding of their own
+	switch {
+	case modParam == 1, modParam == 2 && !special:
+		return nil, false
+	case code == '\t' && modParam == 2:
+		return nil, false // Shift+Tab keeps CSI Z
+	case kh.modifyOtherKeys == 1 && hasLegacyEncoding(code, mods):
+		return nil, false
+	}
+	if mods&tcell.ModShift != 0 && !special {
+		code = unicode.ToUpper(code)
+	}
+	return []byte(fmt.Sprintf("\x1b[27;%d;%d~", modParam, code)), true
+}
+
+// keyCode returns the character a key event is for, with tcell's control
+// keys (KeyCtrlA and the like) turned back into Ctrl and the character.
+// Returns false for keys that aren't characters.
+func keyCode(key tcell.Key, char rune, mods tcell.ModMask) (rune, tcell.ModMask, bool) {
+	switch {
+	case key == tcell.KeyRune:
+		if unicode.IsUpper(char) && mods&(tcell.ModCtrl|tcell.ModAlt) != 0 {
+			mods |= tcell.ModShift
+		}
+		return char, mods, true
+	case key == tcell.KeyEnter:
+		return '\r', mods, true
+	case key == tcell.KeyTab:
+		return '\t', mods, true
+	case key == tcell.KeyBackspace || key == tcell.KeyBackspace2:
+		return 0x7F, mods, true
+	case key == tcell.KeyEscape:
+		return 0x1B, mods, true
+	case key == tcell.KeyCtrlSpace:
+		return ' ', mods | tcell.ModCtrl, true
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ:
+		return rune('a' + key - tcell.KeyCtrlA), mods | tcell.ModCtrl, true
+	case key >= tcell.KeyCtrlBackslash && key <= tcell.KeyCtrlUnderscore:
+		return rune(`\]^_`[key-tcell.KeyCtrlBackslash]), mods | tcell.ModCtrl, true
+	}
+	return 0, mods, false
+}
+
+// hasLegacyEncoding reports whether a modified character can be sent
+// without modifyOtherKeys: a printable character with Alt, which adds an
+// ESC prefix, or Ctrl without Shift with a letter or a character that has
+// a control code, optionally with Alt too
+func hasLegacyEncoding(code rune, mods tcell.ModMask) bool {
+	if code == '\r' || code == '\t' || code == 0x7F || code == 0x1B {
+		return false
+	}
+	if mods&tcell.ModCtrl == 0 {
+		return true
+	}
+	if mods&tcell.ModShift != 0 {
+		return false
+	}
+	return (code >= 'a' && code <= 'z') || code == ' ' || (code >= '[' && code <= '_') || code == '@'
+}
@@ -0,0 +1,78 @@
+package terminal
+
+// LineSize is how a line is drawn, set for the cursor line by ESC # 3-6
+// (DECDHL, DECSWL, DECDWL). Each cell of a line carries its size, so it
+// moves with the line when the screen scrolls.
+type LineSize uint8
+
+const (
+	LineSingle       LineSize = iota // Normal line
+	LineDoubleWidth                  // DECDWL: each character two columns wide
+	LineDoubleTop                    // DECDHL: top half of double-height characters
+	LineDoubleBottom                 // DECDHL: bottom half of double-height characters
+)
+
+// IsDouble reports whether characters on the line are drawn double width,
+// which double-height lines are too
+func (s LineSize) IsDouble() bool {
+	return s != LineSingle
+}
+
+// lineSizeAction returns the line size set by ESC # and a digit, false for
+// other ESC # sequences
+func lineSizeAction(b byte) (Action, bool) {
+	var size LineSize
+	switch b {
+	case '3':
+		size = LineDoubleTop
+	case '4':
+		size = LineDoubleBottom
+	case '5':
+		size = LineSingle
+	case '6':
+		size = LineDoubleWidth
+	default:
+		return Action{}, false
+	}
+	return Action{Type: ActionSetLineSize, Data: size}, true
+}
+
+// lineSize returns the size of a screen line
+func (te *TerminalEmulator) lineSize(y int) LineSize {
+	screen := te.GetScreen()
+	if y < 0 || y >= len(screen.Buffer) || len(screen.Buffer[y]) == 0 {
+		return LineSingle
+	}
+	return screen.Buffer[y][0].LineSize
+}
+
+// lineLimit returns the number of columns usable on the cursor line: half
+// the width on double-width and double-height lines
+func (te *TerminalEmulator) lineLimit() int {
+	if te.lineSize(te.state.CursorY).IsDouble() {
+		return max(1, te.state.Width/2)
+	}
+	return te.state.Width
+}
+
+// setLineSize changes the size of the cursor line. Characters past the
+// half of the screen stay in the line but aren't shown while it is double
+// width, and a cursor there moves to the last column shown.
+func (te *TerminalEmulator) setLineSize(size LineSize) {
+	y := te.state.CursorY
+	screen := te.GetScreen()
+	if y < 0 || y >= len(screen.Buffer) {
+		return
+	}
+	line := screen.Buffer[y]
+	for x := range line {
+		line[x].LineSize = size
+		line[x].Dirty = true
+	}
+	screen.MarkLineDirty(y)
+	screen.Dirty = true
+
+	if limit := te.lineLimit(); te.state.CursorX >= limit {
+		te.state.CursorX = limit - 1
+	}
+}
@@ -0,0 +1,34 @@
+package terminal
+
+// lineFeedActions returns what LF, VT and FF do: move the cursor down a
+// line, and back to the first column in newline mode or when every LF is
+// taken to imply a CR, for devices that end lines with a bare LF and would
+// otherwise print them as a staircase drifting right
+func lineFeedActions(state *TerminalState) []Action {
+	if state.NewlineMode || state.ImplicitCR {
+		return []Action{{Type: ActionNewline}, {Type: ActionCarriageReturn}}
+	}
+	return []Action{{Type: ActionNewline}}
+}
+
+// carriageReturnActions returns what CR does: move the cursor to the first
+// column, and down a line too when every CR is taken to imply an LF, for
+// devices that end lines with a bare CR and would otherwise overwrite one
+// line over and over. The LF of a CR LF then does nothing, so both line
+// endings show the same.
+func carriageReturnActions(state *TerminalState) []Action {
+	if state.ImplicitLF {
+		return []Action{{Type: ActionCarriageReturn}, {Type: ActionNewline}}
+	}
+	return []Action{{Type: ActionCarriageReturn}}
+}
+
+// SetNewlineHandling sets whether every LF received also returns the
+// cursor to the first column (implicitCR) and every CR also moves it down
+// a line (implicitLF)
+func (te *TerminalEmulator) SetNewlineHandling(implicitCR, implicitLF bool) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.state.ImplicitCR = implicitCR
+	te.state.ImplicitLF = implicitLF
+}
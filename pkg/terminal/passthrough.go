@@ -0,0 +1,56 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+)
+
+// tmuxPassthroughPrefix starts the DCS string tmux wraps sequences in to
+// pass them to the terminal it runs in (ESC P tmux; ... ESC \), with each
+// ESC of the wrapped sequences doubled
+const tmuxPassthroughPrefix = "tmux;"
+
+// maxPassthroughDepth is how deep passthrough payloads are unwrapped, for
+// tmux running inside tmux; deeper ones are dropped
+const maxPassthroughDepth = 4
+
+// tmuxPassthrough reports whether the DCS string being parsed is tmux
+// passthrough
+func (vt *VTParser) tmuxPassthrough() bool {
+	return bytes.HasPrefix(vt.Buffer, []byte(tmuxPassthroughPrefix))
+}
+
+// processDCS carries out a complete DCS string held in the buffer. Only
+// tmux passthrough is understood: its payload is processed as if it had
+// been received directly, since the application inside tmux sent it for
+// this terminal. screen's passthrough needs nothing: it wraps a sequence
+// in a DCS without doubling its ESC, which ends the DCS and starts the
+// sequence, and the ST after it is dropped.
+func (vt *VTParser) processDCS() []Action {
+	payload, ok := bytes.CutPrefix(vt.Buffer, []byte(tmuxPassthroughPrefix))
+	if !ok {
+		return nil
+	}
+	return []Action{{Type: ActionPassthrough, Data: bytes.Clone(payload)}}
+}
+
+// processPassthrough processes the sequences tmux passed through to the
+// terminal, with the emulator locked
+func (te *TerminalEmulator) processPassthrough(payload []byte) {
+	if te.passthroughDepth >= maxPassthroughDepth {
+		te.logDebug("Dropped tmux passthrough nested %d deep", te.passthroughDepth+1)
+		return
+	}
+	te.passthroughDepth++
+	defer func() { te.passthroughDepth-- }()
+
+	te.processBytes(payload)
+
+	// A sequence cut off at the end of the payload doesn't swallow what
+	// comes after the passthrough
+	if te.parser.State != StateGround {
+		te.logDebug("tmux passthrough ended inside %s: %q", te.parser.State, strings.ToValidUTF8(string(te.sequences.current), "?"))
+		te.parser.Reset()
+		te.sequences.current = te.sequences.current[:0]
+	}
+}
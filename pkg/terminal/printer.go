@@ -0,0 +1,131 @@
+package terminal
+
+import "strings"
+
+// printerOff ends printer controller mode; everything before it goes to
+// the printer
+const printerOff = "\x1b[4i"
+
+// Media copy requests, the Data of ActionMediaCopy
+const (
+	mediaCopyScreen        = "print_screen"
+	mediaCopyLine          = "print_line"
+	mediaCopyControllerOn  = "controller_on"
+	mediaCopyControllerOff = "controller_off"
+	mediaCopyAutoPrintOn   = "autoprint_on"
+	mediaCopyAutoPrintOff  = "autoprint_off"
+)
+
+// mediaCopy turns an MC sequence (CSI i) into an action: printing the
+// screen (0) or cursor line (?1), printer controller mode (5 and 4) and
+// auto print mode (?5 and ?4)
+func (vt *VTParser) mediaCopy() []Action {
+	private := len(vt.Intermediate) > 0 && vt.Intermediate[0] == '?'
+	var request string
+	switch param := vt.getParam(0, 0); {
+	case !private && param == 0:
+		request = mediaCopyScreen
+	case !private && param == 5:
+		request = mediaCopyControllerOn
+	case !private && param == 4:
+		request = mediaCopyControllerOff
+	case private && param == 1:
+		request = mediaCopyLine
+	case private && param == 5:
+		request = mediaCopyAutoPrintOn
+	case private && param == 4:
+		request = mediaCopyAutoPrintOff
+	default:
+		return nil
+	}
+	return []Action{{Type: ActionMediaCopy, Data: request}}
+}
+
+// SetPrinterCallback sets where printed data goes: everything received in
+// printer controller mode, and printed lines as text. Without it printed
+// data is dropped. It runs with the terminal locked, so it must not call
+// back into the emulator.
+func (te *TerminalEmulator) SetPrinterCallback(callback func(data []byte)) {
+	te.onPrint = callback
+}
+
+// print passes data to the printer
+func (te *TerminalEmulator) print(data []byte) {
+	if len(data) > 0 && te.onPrint != nil {
+		te.onPrint(data)
+	}
+}
+
+// executeMediaCopy carries out an MC request
+func (te *TerminalEmulator) executeMediaCopy(request string) {
+	switch request {
+	case mediaCopyScreen:
+		var text strings.Builder
+		for _, line := range te.GetScreen().Buffer {
+			text.WriteString(cellsText(line))
+			text.WriteByte('\n')
+		}
+		te.print([]byte(text.String()))
+	case mediaCopyLine:
+		te.printLine(te.state.CursorY)
+	case mediaCopyControllerOn:
+		te.state.PrinterController = true
+		te.printerHeld = 0
+		te.logDebug("Printer controller mode on")
+	case mediaCopyControllerOff:
+		// Only seen outside printer controller mode, where it does nothing
+	case mediaCopyAutoPrintOn:
+		te.state.AutoPrint = true
+	case mediaCopyAutoPrintOff:
+		te.state.AutoPrint = false
+	}
+}
+
+// printControllerData sends data received in printer controller mode to
+// the printer, up to the ESC[4i that ends the mode, which isn't printed. A
+// partial ESC[4i at the end is held back until the next read shows whether
+// it ends the mode. Returns the number of bytes used.
+func (te *TerminalEmulator) printControllerData(data []byte) int {
+	var printed []byte
+	for i, b := range data {
+		if b == printerOff[te.printerHeld] {
+			te.printerHeld++
+			if te.printerHeld == len(printerOff) {
+				te.printerHeld = 0
+				te.state.PrinterController = false
+				te.print(printed)
+				te.logDebug("Printer controller mode off")
+				return i + 1
+			}
+			continue
+		}
+		// Not the end after all; the held bytes were data
+		printed = append(printed, printerOff[:te.printerHeld]...)
+		te.printerHeld = 0
+		if b == printerOff[0] {
+			te.printerHeld = 1
+			continue
+		}
+		printed = append(printed, b)
+	}
+	te.print(printed)
+	return len(data)
+}
+
+// printLine prints a screen line as text
+func (te *TerminalEmulator) printLine(y int) {
+	screen := te.GetScreen()
+	if y < 0 || y >= len(screen.Buffer) {
+		return
+	}
+	te.print([]byte(cellsText(screen.Buffer[y]) + "\n"))
+}
+
+// cellsText returns the text of a line without trailing blanks
+func cellsText(line []Cell) string {
+	var text strings.Builder
+	for _, cell := range line {
+		text.WriteString(cell.String())
+	}
+	return strings.TrimRight(text.String(), " ")
+}
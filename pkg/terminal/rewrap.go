@@ -0,0 +1,157 @@
+package terminal
+
+// blankCell is the cell rows are padded with
+func blankCell() Cell {
+	return Cell{Char: ' ', Attributes: DefaultTextAttributes()}
+}
+
+// isBlankCell reports whether a cell holds nothing but padding: a space
+// with default attributes and no link
+func isBlankCell(c Cell) bool {
+	return c.Char == ' ' && c.Cluster == "" && c.Hyperlink == nil && c.Attributes == DefaultTextAttributes()
+}
+
+// rewrapRows wraps rows again at a new width, as if the lines had been
+// received at that width: the rows of each logical line are joined, the
+// padding after its text is dropped and the text is split into rows of the
+// new width. A wide character that would straddle the right edge starts
+// the next row. Lines drawn double width or double height are cut or
+// padded to the new width instead, like the screen. lineMap gives the new
+// index of the row holding the start of each old row's text.
+func rewrapRows(rows [][]Cell, width int) (wrapped [][]Cell, lineMap []int) {
+	wrapped = make([][]Cell, 0, len(rows))
+	lineMap = make([]int, len(rows))
+	for _, line := range LogicalLines(rows) {
+		if line.Rows[0][0].LineSize.IsDouble() {
+			for i, row := range line.Rows {
+				lineMap[line.Start+i] = len(wrapped)
+				wrapped = append(wrapped, fitRow(row, width))
+			}
+			continue
+		}
+
+		// The text of the line, and where each old row starts in it
+		var text []Cell
+		starts := make([]int, len(line.Rows))
+		for i, row := range line.Rows {
+			starts[i] = len(text)
+			text = append(text, row...)
+		}
+		// A line still going on the screen keeps its padding
+		continues := IsWrapped(line.Rows[len(line.Rows)-1])
+		if !continues {
+			for len(text) > 0 && isBlankCell(text[len(text)-1]) {
+				text = text[:len(text)-1]
+			}
+		}
+		for i := range text {
+			text[i].Wrapped = false
+		}
+
+		// Split it into rows of the new width
+		first := len(wrapped)
+		var rowStarts []int
+		for offset := 0; offset < len(text) || len(rowStarts) == 0; {
+			row := make([]Cell, width)
+			n := 0
+			for n < width && offset+n < len(text) {
+				// A wide character doesn't fit in the last column
+				if n == width-1 && width > 1 && offset+n+1 < len(text) && text[offset+n+1].Char == 0 && text[offset+n].Char != 0 {
+					break
+				}
+				row[n] = text[offset+n]
+				n++
+			}
+			for x := n; x < width; x++ {
+				row[x] = blankCell()
+			}
+			rowStarts = append(rowStarts, offset)
+			offset += max(n, 1)
+			if offset < len(text) || continues {
+				row[width-1].Wrapped = true
+			}
+			wrapped = append(wrapped, row)
+		}
+
+		for i, start := range starts {
+			j := len(rowStarts) - 1
+			for j > 0 && rowStarts[j] > start {
+				j--
+			}
+			lineMap[line.Start+i] = first + j
+		}
+	}
+	return wrapped, lineMap
+}
+
+// fitRow returns a row cut or padded to width. A row that wrapped still
+// wraps when it is cut, since text still runs past its end, but not when
+// it is padded, as it no longer reaches the right edge.
+func fitRow(row []Cell, width int) []Cell {
+	fitted := make([]Cell, width)
+	n := copy(fitted, row)
+	for x := n; x < width; x++ {
+		fitted[x] = blankCell()
+	}
+	if n > 0 {
+		fitted[n-1].Wrapped = false
+	}
+	if len(row) >= width {
+		fitted[width-1].Wrapped = IsWrapped(row)
+	}
+	return fitted
+}
+
+// rewrapScrollback wraps the scrollback again at a new width, so lines
+// received before a resize fill the new width rather than keeping the
+// old one. Row numbers in scroll mode follow the text they pointed at,
+// and the rewrap callback is given the mapping of row numbers as counted
+// from the start of the session (GetScrollbackDropped plus the index).
+func (te *TerminalEmulator) rewrapScrollback(width int) {
+	if len(te.scrollbackBuffer) == 0 {
+		return
+	}
+	oldLen := len(te.scrollbackBuffer)
+	oldDropped := te.scrollbackDropped
+	rows, lineMap := rewrapRows(te.scrollbackBuffer, width)
+	index := func(i int) int {
+		switch {
+		case i < 0:
+			return i
+		case i >= oldLen:
+			return i - oldLen + len(rows)
+		default:
+			return lineMap[i]
+		}
+	}
+	remap := func(line int) int {
+		if line < oldDropped {
+			return line
+		}
+		return oldDropped + index(line-oldDropped)
+	}
+
+	te.scrollbackBuffer = rows
+	if te.isScrolling {
+		te.scrollPosition = min(index(te.scrollPosition), len(rows))
+		te.scrollOffset = len(rows) - te.scrollPosition
+		te.scrollEntryLines = remap(te.scrollEntryLines)
+	}
+	// Narrower rows are more rows; the oldest go as usual past the limit
+	te.trimScrollback()
+
+	if te.onRewrap != nil {
+		te.onRewrap(remap)
+	}
+}
+
+// SetRewrapCallback sets the callback told how row numbers changed when the
+// scrollback was wrapped again after a resize. remap takes a row number
+// counted from the start of the session and returns the row the same text
+// is on now. It is called with the emulator locked, so it must not call
+// back into the emulator.
+func (te *TerminalEmulator) SetRewrapCallback(callback func(remap func(line int) int)) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.onRewrap = callback
+}
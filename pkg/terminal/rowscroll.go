@@ -0,0 +1,86 @@
+package terminal
+
+// RowScroll is a scroll of whole rows that hasn't been drawn yet. Rows
+// Top to Bottom moved up by Lines, or down when Lines is negative. The
+// screen's dirty lines are where rows are after the scroll: the rows that
+// scrolled in, and rows changed before or after it.
+//
+// A renderer shows it by moving the rows it drew last time, like the
+// scroll region of a hardware terminal, and then drawing the dirty lines,
+// instead of drawing every row of the region again.
+type RowScroll struct {
+	Top    int
+	Bottom int
+	Lines  int
+}
+
+// TakeScroll returns the rows scrolled since the last call or ClearDirty,
+// and forgets them. Returns false if there are none.
+func (s *Screen) TakeScroll() (RowScroll, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scroll := s.Scrolled
+	s.Scrolled = RowScroll{}
+	return scroll, scroll.Lines != 0
+}
+
+// recordScroll adds a scroll of lines rows between top and bottom to the
+// one not drawn yet. Scrolls of the same region in the same direction add
+// up; any other scroll can't be shown together with it, so its rows are
+// marked dirty instead.
+func (s *Screen) recordScroll(top, bottom, lines int) {
+	if top < 0 || bottom >= len(s.Buffer) || top > bottom {
+		return
+	}
+
+	s.mutex.Lock()
+	pending := s.Scrolled
+	combine := pending.Lines == 0 ||
+		(pending.Top == top && pending.Bottom == bottom && (pending.Lines > 0) == (lines > 0))
+	if combine {
+		s.Scrolled = RowScroll{Top: top, Bottom: bottom, Lines: pending.Lines + lines}
+		s.shiftDirtyLines(top, bottom, lines)
+		s.Dirty = true
+	}
+	s.mutex.Unlock()
+
+	if !combine {
+		for y := top; y <= bottom; y++ {
+			s.MarkLineDirty(y)
+		}
+	}
+}
+
+// shiftDirtyLines moves the dirty line marks between top and bottom with
+// their rows. The caller holds the mutex.
+func (s *Screen) shiftDirtyLines(top, bottom, lines int) {
+	if len(s.DirtyLines) == 0 {
+		return
+	}
+
+	shifted := make(map[int]bool, len(s.DirtyLines))
+	for y := range s.DirtyLines {
+		switch {
+		case y < top || y > bottom:
+			shifted[y] = true
+		case y-lines >= top && y-lines <= bottom:
+			shifted[y-lines] = true
+		}
+	}
+	s.DirtyLines = shifted
+
+	s.DirtyMinY, s.DirtyMaxY = s.Height, -1
+	for y := range shifted {
+		s.DirtyMinY = min(s.DirtyMinY, y)
+		s.DirtyMaxY = max(s.DirtyMaxY, y)
+	}
+}
+
+// discardScroll forgets a scroll not drawn yet, when the rows on screen
+// aren't this screen's
+func (s *Screen) discardScroll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Scrolled = RowScroll{}
+}
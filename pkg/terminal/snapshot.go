@@ -0,0 +1,209 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// snapshotPalette is the RGB palette used when rendering the 16 basic colors
+var snapshotPalette = [16]color.RGBA{
+	{0x00, 0x00, 0x00, 0xff}, // Black
+	{0xcd, 0x00, 0x00, 0xff}, // Red
+	{0x00, 0xcd, 0x00, 0xff}, // Green
+	{0xcd, 0xcd, 0x00, 0xff}, // Yellow
+	{0x00, 0x00, 0xee, 0xff}, // Blue
+	{0xcd, 0x00, 0xcd, 0xff}, // Magenta
+	{0x00, 0xcd, 0xcd, 0xff}, // Cyan
+	{0xe5, 0xe5, 0xe5, 0xff}, // White
+	{0x7f, 0x7f, 0x7f, 0xff}, // Bright black
+	{0xff, 0x00, 0x00, 0xff}, // Bright red
+	{0x00, 0xff, 0x00, 0xff}, // Bright green
+	{0xff, 0xff, 0x00, 0xff}, // Bright yellow
+	{0x5c, 0x5c, 0xff, 0xff}, // Bright blue
+	{0xff, 0x00, 0xff, 0xff}, // Bright magenta
+	{0x00, 0xff, 0xff, 0xff}, // Bright cyan
+	{0xff, 0xff, 0xff, 0xff}, // Bright white
+}
+
+// Default colors for cells using ColorDefault
+var (
+	snapshotDefaultFg = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	snapshotDefaultBg = color.RGBA{0x00, 0x00, 0x00, 0xff}
+)
+
+// WriteANSISnapshot writes lines of cells as text with SGR escape sequences,
+// so the result can be replayed with `cat` in any ANSI terminal
+func WriteANSISnapshot(w io.Writer, lines [][]Cell) error {
+	bw := bufio.NewWriter(w)
+	defaults := DefaultTextAttributes()
+
+	for _, line := range lines {
+		current := defaults
+
+		// Trailing default-styled spaces carry no information
+		end := len(line)
+		for end > 0 && (line[end-1].Char == ' ' || line[end-1].Char == 0) && line[end-1].Attributes == defaults {
+			end--
+		}
+
+		for _, cell := range line[:end] {
+			if cell.Char == 0 {
+				// Continuation cell of a wide character
+				continue
+			}
+			if cell.Attributes != current {
+				bw.WriteString(sgrSequence(cell.Attributes))
+				current = cell.Attributes
+			}
+			bw.WriteString(cell.String())
+		}
+
+		if current != defaults {
+			bw.WriteString("\x1b[0m")
+		}
+		bw.WriteString("\n")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write ANSI snapshot: %w", err)
+	}
+	return nil
+}
+
+// sgrSequence builds an SGR sequence that resets and then applies the given attributes
+func sgrSequence(attrs TextAttributes) string {
+	params := []string{"0"}
+	if attrs.Bold {
+		params = append(params, "1")
+	}
+	if attrs.Italic {
+		params = append(params, "3")
+	}
+	if attrs.Underline {
+		if attrs.UnderlineStyle == UnderlineSingle {
+			params = append(params, "4")
+		} else {
+			params = append(params, fmt.Sprintf("4:%d", int(attrs.UnderlineStyle)+1))
+		}
+		if attrs.UnderlineColor.IsRGB() {
+			r, g, b := attrs.UnderlineColor.RGB()
+			params = append(params, fmt.Sprintf("58:2::%d:%d:%d", r, g, b))
+		} else if attrs.UnderlineColor != ColorDefault {
+			params = append(params, fmt.Sprintf("58:5:%d", int(attrs.UnderlineColor)))
+		}
+	}
+	if attrs.Blink {
+		params = append(params, "5")
+	}
+	if attrs.Reverse {
+		params = append(params, "7")
+	}
+	if attrs.Foreground >= ColorBlack && attrs.Foreground <= ColorWhite {
+		params = append(params, fmt.Sprintf("%d", 30+int(attrs.Foreground)))
+	} else if attrs.Foreground >= ColorBrightBlack && attrs.Foreground <= ColorBrightWhite {
+		params = append(params, fmt.Sprintf("%d", 90+int(attrs.Foreground-ColorBrightBlack)))
+	}
+	if attrs.Background >= ColorBlack && attrs.Background <= ColorWhite {
+		params = append(params, fmt.Sprintf("%d", 40+int(attrs.Background)))
+	} else if attrs.Background >= ColorBrightBlack && attrs.Background <= ColorBrightWhite {
+		params = append(params, fmt.Sprintf("%d", 100+int(attrs.Background-ColorBrightBlack)))
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// WritePNGSnapshot renders lines of cells to a PNG image using the bundled
+// 7x13 bitmap font. Glyphs the font doesn't cover are drawn as blanks.
+func WritePNGSnapshot(w io.Writer, lines [][]Cell) error {
+	face := basicfont.Face7x13
+	cellWidth := face.Advance
+	cellHeight := face.Height
+
+	// Size the image to the widest line
+	cols := 0
+	for _, line := range lines {
+		if len(line) > cols {
+			cols = len(line)
+		}
+	}
+	if cols == 0 || len(lines) == 0 {
+		return fmt.Errorf("nothing to render")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, cols*cellWidth, len(lines)*cellHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{snapshotDefaultBg}, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{
+		Dst:  img,
+		Face: face,
+	}
+
+	for y, line := range lines {
+		for x, cell := range line {
+			if cell.Char == 0 {
+				continue
+			}
+
+			fg, bg := snapshotColors(cell.Attributes)
+
+			// Wide characters cover two cells
+			width := 1
+			if x+1 < len(line) && line[x+1].Char == 0 {
+				width = 2
+			}
+
+			rect := image.Rect(x*cellWidth, y*cellHeight, (x+width)*cellWidth, (y+1)*cellHeight)
+			if bg != snapshotDefaultBg {
+				draw.Draw(img, rect, &image.Uniform{bg}, image.Point{}, draw.Src)
+			}
+
+			if cell.Char != ' ' {
+				drawer.Src = &image.Uniform{fg}
+				drawer.Dot = fixed.P(x*cellWidth, y*cellHeight+face.Ascent)
+				drawer.DrawString(cell.String())
+			}
+
+			if cell.Attributes.Underline {
+				underline := image.Rect(rect.Min.X, rect.Max.Y-1, rect.Max.X, rect.Max.Y)
+				draw.Draw(img, underline, &image.Uniform{fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode PNG snapshot: %w", err)
+	}
+	return nil
+}
+
+// snapshotColors resolves the RGB foreground and background for cell attributes
+func snapshotColors(attrs TextAttributes) (fg, bg color.RGBA) {
+	fg = snapshotDefaultFg
+	bg = snapshotDefaultBg
+
+	fgColor := attrs.Foreground
+	// Bold renders basic colors in their bright variant, as most terminals do
+	if attrs.Bold && fgColor >= ColorBlack && fgColor <= ColorWhite {
+		fgColor += ColorBrightBlack
+	}
+	if fgColor >= ColorBlack && fgColor <= ColorBrightWhite {
+		fg = snapshotPalette[fgColor]
+	}
+	if attrs.Background >= ColorBlack && attrs.Background <= ColorBrightWhite {
+		bg = snapshotPalette[attrs.Background]
+	}
+
+	if attrs.Reverse {
+		fg, bg = bg, fg
+	}
+	return fg, bg
+}
@@ -0,0 +1,74 @@
+package terminal
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyncTimeout is the longest a synchronized update holds back the screen:
+// if the application never ends it, say because it was killed halfway
+// through a redraw, the screen is drawn anyway after this long
+const SyncTimeout = 150 * time.Millisecond
+
+// setSynchronizedOutput starts or ends a synchronized update (mode 2026).
+// The screen keeps being updated meanwhile; only drawing it waits.
+func (te *TerminalEmulator) setSynchronizedOutput(on bool) {
+	if on && !te.state.SynchronizedOutput {
+		te.syncStarted = time.Now()
+	}
+	te.state.SynchronizedOutput = on
+}
+
+// SynchronizedUpdate reports whether the device is halfway through a
+// synchronized update, and since when. A renderer leaves the screen as it
+// is until the update ends or SyncTimeout passes, so the application's
+// redraw shows all at once rather than torn.
+func (te *TerminalEmulator) SynchronizedUpdate() (active bool, since time.Time) {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	return te.state.SynchronizedOutput, te.syncStarted
+}
+
+// reportPrivateMode answers DECRQM for a DEC private mode (CSI ? Ps $ p)
+// with whether it is set (1) or reset (2), or 0 for modes the emulator
+// can't report. Applications ask before relying on a mode, like
+// synchronized output.
+func reportPrivateMode(mode int, state *TerminalState) string {
+	value := 0
+	set := func(on bool) {
+		value = 2
+		if on {
+			value = 1
+		}
+	}
+	switch mode {
+	case 9:
+		set(state.MouseMode == MouseModeX10)
+	case 1000:
+		set(state.MouseMode == MouseModeVT200)
+	case 1001:
+		set(state.MouseMode == MouseModeVT200Highlight)
+	case 1002:
+		set(state.MouseMode == MouseModeBtnEvent)
+	case 1003:
+		set(state.MouseMode == MouseModeAnyEvent)
+	case 2004:
+		set(state.BracketedPaste)
+	case 2026:
+		set(state.SynchronizedOutput)
+	}
+	return fmt.Sprintf("\x1b[?%d;%d$y", mode, value)
+}
+
+// reportMode answers DECRQM for an ANSI mode (CSI Ps $ p) like
+// reportPrivateMode. Only newline mode (LNM) is reported.
+func reportMode(mode int, state *TerminalState) string {
+	value := 0
+	if mode == 20 {
+		value = 2
+		if state.NewlineMode {
+			value = 1
+		}
+	}
+	return fmt.Sprintf("\x1b[%d;%d$y", mode, value)
+}
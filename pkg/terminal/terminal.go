@@ -3,12 +3,16 @@ package terminal
 
 import (
 	"fmt"
+	"runtime/debug"
+	"sort"
 	"sterm/pkg/history"
 	"sterm/pkg/serial"
 	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
 )
 
 // Terminal interface defines the contract for terminal operations
@@ -36,6 +40,47 @@ type TerminalState struct {
 	ScrollBottom int            `json:"scroll_bottom"`
 	IsRunning    bool           `json:"is_running"`
 	LineWrap     bool           `json:"line_wrap"`
+
+	// BracketedPaste is set while the remote application wants pastes
+	// wrapped in ESC[200~ and ESC[201~ (mode 2004)
+	BracketedPaste bool `json:"bracketed_paste"`
+
+	// VT52 is set while the terminal understands VT52 escape sequences
+	// instead of ANSI ones (ESC[?2l until ESC <), and VT52Graphics while
+	// VT52 graphics characters are shown (ESC F until ESC G)
+	VT52         bool `json:"vt52"`
+	VT52Graphics bool `json:"vt52_graphics"`
+
+	// PrinterController is set while received data goes to the printer
+	// instead of the screen (ESC[5i until ESC[4i), and AutoPrint while each
+	// line is printed as the cursor leaves it (ESC[?5i until ESC[?4i)
+	PrinterController bool `json:"printer_controller"`
+	AutoPrint         bool `json:"auto_print"`
+
+	// Hyperlink is the OSC 8 link characters are printed as part of, nil
+	// outside a link
+	Hyperlink *Hyperlink `json:"hyperlink,omitempty"`
+
+	// ModifyOtherKeys is the xterm modifyOtherKeys level (CSI > 4 ; n m)
+	// and KittyKeyboard the kitty keyboard protocol flags (CSI > n u); both
+	// make keys with modifiers send sequences that tell them apart
+	ModifyOtherKeys int `json:"modify_other_keys"`
+	KittyKeyboard   int `json:"kitty_keyboard"`
+
+	// SynchronizedOutput is set while the application is redrawing and
+	// wants the screen drawn only once it is done (mode 2026)
+	SynchronizedOutput bool `json:"synchronized_output"`
+
+	// NewlineMode is set while LF, VT and FF return the cursor to the
+	// first column too, and Enter sends CR LF (LNM, mode 20)
+	NewlineMode bool `json:"newline_mode"`
+
+	// ImplicitCR and ImplicitLF are the user's options for devices that end
+	// lines with a bare LF or a bare CR: every LF also returns the cursor
+	// to the first column, and every CR also moves it down a line. Unlike
+	// the modes the device sets, they are kept across resets.
+	ImplicitCR bool `json:"implicit_cr"`
+	ImplicitLF bool `json:"implicit_lf"`
 }
 
 // Validate checks if the terminal state is valid
@@ -96,6 +141,9 @@ type TextAttributes struct {
 	Underline  bool  `json:"underline"`
 	Reverse    bool  `json:"reverse"`
 	Blink      bool  `json:"blink"`
+
+	UnderlineStyle UnderlineStyle `json:"underline_style,omitempty"` // Shape of the underline
+	UnderlineColor Color          `json:"underline_color"`           // ColorDefault for the text color
 }
 
 // DefaultTextAttributes returns default text attributes
@@ -108,10 +156,13 @@ func DefaultTextAttributes() TextAttributes {
 		Underline:  false,
 		Reverse:    false,
 		Blink:      false,
+
+		UnderlineColor: ColorDefault,
 	}
 }
 
-// Color represents terminal colors
+// Color represents terminal colors: the 16 named colors below, 16-255 for
+// the rest of the xterm 256-color palette, and 24-bit colors from RGBColor
 type Color int
 
 const (
@@ -149,7 +200,7 @@ func (c Color) String() string {
 	if int(c) >= 0 && int(c) < len(colors) {
 		return colors[c]
 	}
-	return "unknown"
+	return colorName(c)
 }
 
 // MouseMode represents different mouse modes
@@ -164,21 +215,35 @@ const (
 	MouseModeAnyEvent
 )
 
+// mouseModeNames are the names of the mouse modes, in order
+var mouseModeNames = []string{
+	"off", "x10", "vt200", "vt200_highlight", "btn_event", "any_event",
+}
+
 // String returns the string representation of MouseMode
 func (m MouseMode) String() string {
-	modes := []string{
-		"off", "x10", "vt200", "vt200_highlight", "btn_event", "any_event",
+	if int(m) >= 0 && int(m) < len(mouseModeNames) {
+		return mouseModeNames[m]
 	}
+	return "unknown"
+}
 
-	if int(m) < len(modes) {
-		return modes[m]
+// MouseModeFromName returns the mouse mode with the given String name,
+// MouseModeOff for an unknown name
+func MouseModeFromName(name string) MouseMode {
+	for i, n := range mouseModeNames {
+		if n == name {
+			return MouseMode(i)
+		}
 	}
-	return "unknown"
+	return MouseModeOff
 }
 
-// Logger interface for debug logging
+// Logger receives diagnostic messages from the emulator at different levels
 type Logger interface {
 	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
 }
 
 // TerminalEmulator implements the Terminal interface
@@ -193,19 +258,60 @@ type TerminalEmulator struct {
 	isRunning      bool
 	useAltScreen   bool         // Whether using alternative screen
 	tabStops       map[int]bool // Custom tab stops
+	tabStopWidth   int          // Columns that have been given default tab stops
 	utf8Decoder    *UTF8Decoder // UTF-8 decoder for multi-byte characters
+	decoded        []rune       // Runes completed by the decoder for the current byte
 	logger         Logger       // Logger for debug output
 	mu             sync.RWMutex // Protect concurrent access
 
 	// Scrollback buffer for history
-	scrollbackBuffer [][]Cell // History lines
-	scrollbackSize   int      // Maximum scrollback lines
-	scrollOffset     int      // Current scroll position (0 = bottom/normal)
-	scrollPosition   int      // Absolute line position in scroll mode (fixed position)
-	isScrolling      bool     // Whether in scroll mode
+	scrollbackBuffer  [][]Cell // History lines
+	scrollbackSize    int      // Maximum scrollback lines
+	scrollOffset      int      // Current scroll position (0 = bottom/normal)
+	scrollPosition    int      // Absolute line position in scroll mode (fixed position)
+	isScrolling       bool     // Whether in scroll mode
+	scrollbackDropped int      // Total lines dropped from the head of scrollback
+	scrollEntryLines  int      // Total lines ever added to scrollback when scroll mode was entered
 
 	// Mouse mode change callback
 	onMouseModeChange func(mode MouseMode)
+
+	// Called when the remote sends BEL
+	onBell func()
+
+	// What ED 2 and ED 3 do to the scrollback
+	clearPolicy ClearPolicy
+
+	// Called with the new row numbers when the scrollback is rewrapped
+	onRewrap func(remap func(line int) int)
+
+	// Called with answers to the remote's queries; without it they are
+	// written to the serial port
+	onResponse func(response []byte)
+
+	// Called with data for the printer (media copy)
+	onPrint     func(data []byte)
+	printerHeld int // Bytes of the ESC[4i ending printer controller mode seen so far
+
+	// Kitty keyboard flags pushed with CSI > u, restored by CSI < u
+	kittyStack []int
+
+	// The escape sequence being parsed and the last complete one, for
+	// DebugInfo
+	sequences sequenceRecord
+
+	// Called with every finished escape sequence while tracing
+	tracer func(SequenceTrace)
+
+	// Escape sequences the emulator did nothing with, for the summary
+	ignored ignoredSequences
+
+	// When the synchronized update in progress started
+	syncStarted time.Time
+
+	// How many tmux passthrough payloads are being processed, one inside
+	// the other
+	passthroughDepth int
 }
 
 // NewTerminalEmulator creates a new terminal emulator
@@ -230,9 +336,7 @@ func NewTerminalEmulator(serialPort serial.SerialPort, historyManager history.Hi
 		isScrolling:      false,
 	}
 	// Initialize default tab stops every 8 columns
-	for i := 8; i < width; i += 8 {
-		te.tabStops[i] = true
-	}
+	te.addDefaultTabStops(width)
 	return te
 }
 
@@ -249,6 +353,19 @@ func (te *TerminalEmulator) SetMouseModeChangeCallback(callback func(mode MouseM
 	te.onMouseModeChange = callback
 }
 
+// SetResponseCallback sets a callback for answers to the remote's queries
+// (DSR, DA, DECREQTPARM, VT52 identify). It runs with the terminal locked,
+// so it must not call back into the emulator.
+func (te *TerminalEmulator) SetResponseCallback(callback func(response []byte)) {
+	te.onResponse = callback
+}
+
+// SetBellCallback sets a callback for BEL. It runs with the terminal locked,
+// so it must not call back into the emulator.
+func (te *TerminalEmulator) SetBellCallback(callback func()) {
+	te.onBell = callback
+}
+
 // Screen represents the terminal screen buffer
 type Screen struct {
 	Width  int
@@ -263,6 +380,10 @@ type Screen struct {
 	DirtyMinY  int          // Minimum dirty Y coordinate
 	DirtyMaxY  int          // Maximum dirty Y coordinate
 
+	// Rows scrolled since the last render, which the renderer can move
+	// instead of drawing again
+	Scrolled RowScroll
+
 	// Special flags
 	JustCleared bool // Flag to indicate screen was just cleared
 
@@ -273,8 +394,34 @@ type Screen struct {
 // Cell represents a single character cell in the terminal
 type Cell struct {
 	Char       rune           `json:"char"`
+	Cluster    string         `json:"cluster,omitempty"` // Full grapheme cluster when Char is followed by combining marks, ZWJ sequences etc.
 	Attributes TextAttributes `json:"attributes"`
-	Dirty      bool           `json:"-"` // Track if this cell is dirty
+	LineSize   LineSize       `json:"line_size,omitempty"` // Size of the line the cell is on
+	Hyperlink  *Hyperlink     `json:"hyperlink,omitempty"` // OSC 8 link the character is part of
+	Wrapped    bool           `json:"wrapped,omitempty"`   // Set on the last cell of a row whose text wrapped onto the next
+	Dirty      bool           `json:"-"`                   // Track if this cell is dirty
+}
+
+// String returns the text shown in the cell: the grapheme cluster if there
+// is one, otherwise the single character. Continuation cells are empty.
+func (c Cell) String() string {
+	if c.Cluster != "" {
+		return c.Cluster
+	}
+	if c.Char == 0 {
+		return ""
+	}
+	return string(c.Char)
+}
+
+// Combining returns the runes of the cluster after the base character, in
+// the form tcell's SetContent expects
+func (c Cell) Combining() []rune {
+	if c.Cluster == "" {
+		return nil
+	}
+	runes := []rune(c.Cluster)
+	return runes[1:]
 }
 
 // NewScreen creates a new screen buffer
@@ -421,6 +568,7 @@ func (s *Screen) ClearDirty() {
 	s.DirtyMaxX = -1
 	s.DirtyMinY = s.Height
 	s.DirtyMaxY = -1
+	s.Scrolled = RowScroll{}
 
 	// Note: We intentionally do NOT clear JustCleared flag here
 	// It needs to be handled by the display update
@@ -468,128 +616,25 @@ func (s *Screen) GetDirtyBounds() (minX, maxX, minY, maxY int, hasDirty bool) {
 	return s.DirtyMinX, s.DirtyMaxX, s.DirtyMinY, s.DirtyMaxY, true
 }
 
-// UTF8Decoder handles UTF-8 character decoding
-type UTF8Decoder struct {
-	bytes    []byte
-	expected int
-	logger   Logger
-}
-
-// NewUTF8Decoder creates a new UTF-8 decoder
-func NewUTF8Decoder() *UTF8Decoder {
-	return &UTF8Decoder{
-		bytes:    make([]byte, 0, 4),
-		expected: 0,
-	}
-}
-
-// Decode processes a byte and returns a rune if complete, or 0 if incomplete
-func (d *UTF8Decoder) Decode(b byte) (rune, bool) {
-	// If we're expecting continuation bytes
-	if d.expected > 0 {
-		// Check if this is a valid continuation byte
-		if b >= 0x80 && b < 0xC0 {
-			// Valid continuation byte
-			d.bytes = append(d.bytes, b)
-			d.expected--
-
-			if d.expected == 0 {
-				// Complete character
-				r, size := decodeUTF8(d.bytes)
-				if size > 0 {
-					// Successfully decoded
-					d.Reset()
-					return r, true
-				} else {
-					// Failed to decode
-					d.Reset()
-					return '�', true
-				}
-			}
-			// Still need more bytes
-			return 0, false
-		} else {
-			// Invalid continuation byte - either new start or ASCII
-			// This byte is NOT a valid continuation
-			// Check if it's ASCII - if so, we need to output replacement for incomplete sequence
-			if b < 0x80 {
-				// ASCII byte interrupting UTF-8 sequence
-				d.Reset()
-				// Process ASCII byte normally
-				return rune(b), true
-			}
-			// Reset and try to process this byte as new character
-			d.Reset()
-			// Fall through to process as new character
-		}
-	}
-
-	// Starting a new character
-	if b < 0x80 { // ASCII
-		return rune(b), true
-	} else if b < 0xC0 { // Orphaned continuation byte
-		// This shouldn't happen in valid UTF-8
-		// IMPORTANT: Never treat a continuation byte as a character!
-		// It should always return replacement character
-		return '�', true
-	} else if b < 0xE0 { // 2-byte sequence
-		d.bytes = append(d.bytes[:0], b)
-		d.expected = 1
-		return 0, false
-	} else if b < 0xF0 { // 3-byte sequence
-		d.bytes = append(d.bytes[:0], b)
-		d.expected = 2
-		return 0, false
-	} else if b < 0xF8 { // 4-byte sequence
-		d.bytes = append(d.bytes[:0], b)
-		d.expected = 3
-		return 0, false
-	} else { // Invalid UTF-8
-		return '�', true
-	}
-}
-
-// Reset resets the decoder state
-func (d *UTF8Decoder) Reset() {
-	d.bytes = d.bytes[:0]
-	d.expected = 0
-}
-
-// decodeUTF8 decodes a complete UTF-8 sequence
-func decodeUTF8(bytes []byte) (rune, int) {
-	if len(bytes) == 0 {
-		return 0, 0
-	}
-
-	switch len(bytes) {
-	case 1:
-		return rune(bytes[0]), 1
-	case 2:
-		r := rune((bytes[0]&0x1F)<<6 | (bytes[1] & 0x3F))
-		return r, 2
-	case 3:
-		// Debug the calculation
-		b0 := uint32(bytes[0]) & 0x0F
-		b1 := uint32(bytes[1]) & 0x3F
-		b2 := uint32(bytes[2]) & 0x3F
-		result := (b0 << 12) | (b1 << 6) | b2
-		r := rune(result)
-		// For E4 B8 AD: b0=4, b1=38, b2=2D -> 4000 + E00 + 2D = 4E2D (中)
-		return r, 3
-	case 4:
-		r := rune(int32(bytes[0]&0x07)<<18 | int32(bytes[1]&0x3F)<<12 | int32(bytes[2]&0x3F)<<6 | int32(bytes[3]&0x3F))
-		return r, 4
-	default:
-		return 0, 0
-	}
-}
-
 // VTParser handles VT100/ANSI escape sequence parsing
 type VTParser struct {
 	State        ParserState
 	Buffer       []byte
 	Params       []int
 	Intermediate []byte
+
+	// stringEscape is set when the last byte of a control string was
+	// ESC, which starts the ST that ends it, and stringCancelled when an
+	// ESC followed by another byte cut the string short instead.
+	// stringOverflow is set when the string grew past its length limit
+	// and is dropped.
+	stringEscape    bool
+	stringCancelled bool
+	stringOverflow  bool
+
+	// crLineFed is set after a CR that moved the cursor down a line too,
+	// so the LF of a CR LF doesn't move it down another
+	crLineFed bool
 }
 
 // ParserState represents the current state of the VT parser
@@ -601,6 +646,8 @@ const (
 	StateCSI
 	StateOSC
 	StateDCS
+	StateString     // SOS, PM and APC strings, skipped up to their ST
+	StateVT52Cursor // Between ESC Y and its row and column in VT52 mode
 )
 
 // NewVTParser creates a new VT parser
@@ -619,6 +666,8 @@ func (vt *VTParser) Reset() {
 	vt.Buffer = vt.Buffer[:0]
 	vt.Params = vt.Params[:0]
 	vt.Intermediate = vt.Intermediate[:0]
+	vt.stringEscape = false
+	vt.stringOverflow = false
 }
 
 // ParseByte processes a single byte through the VT parser state machine
@@ -632,10 +681,10 @@ func (vt *VTParser) ParseByte(b byte, screen *Screen, state *TerminalState, utf8
 		actions = vt.handleEscape(b, screen, state)
 	case StateCSI:
 		actions = vt.handleCSI(b, screen, state)
-	case StateOSC:
-		actions = vt.handleOSC(b, screen, state)
-	case StateDCS:
-		actions = vt.handleDCS(b, screen, state)
+	case StateOSC, StateDCS, StateString:
+		actions = vt.handleString(b, screen, state)
+	case StateVT52Cursor:
+		actions = vt.handleVT52Cursor(b)
 	}
 
 	return actions
@@ -660,6 +709,7 @@ const (
 	ActionSetMode
 	ActionBell
 	ActionTab
+	ActionBackTab
 	ActionNewline
 	ActionCarriageReturn
 	ActionBackspace
@@ -673,10 +723,18 @@ const (
 	ActionSetTabStop
 	ActionClearTabStop
 	ActionReset
+	ActionMediaCopy
+	ActionSetLineSize
+	ActionSetHyperlink
+	ActionSetKeyProtocol
+	ActionPassthrough
 )
 
 // handleGround processes characters in ground state
 func (vt *VTParser) handleGround(b byte, screen *Screen, state *TerminalState, utf8Decoder *UTF8Decoder) []Action {
+	crLineFed := vt.crLineFed
+	vt.crLineFed = false
+
 	switch b {
 	case 0x1B: // ESC
 		vt.State = StateEscape
@@ -689,12 +747,19 @@ func (vt *VTParser) handleGround(b byte, screen *Screen, state *TerminalState, u
 		return []Action{{Type: ActionBackspace}}
 	case 0x09: // HT
 		return []Action{{Type: ActionTab}}
-	case 0x0A: // LF
-		return []Action{{Type: ActionNewline}}
+	case 0x0A, 0x0B, 0x0C: // LF, VT, FF
+		if crLineFed && b == 0x0A {
+			return nil
+		}
+		return lineFeedActions(state)
 	case 0x0D: // CR
-		return []Action{{Type: ActionCarriageReturn}}
+		vt.crLineFed = state.ImplicitLF
+		return carriageReturnActions(state)
 	default:
 		if b >= 0x20 && b <= 0x7E { // Printable ASCII
+			if state.VT52Graphics {
+				return []Action{{Type: ActionPrint, Data: vt52GraphicsChar(b)}}
+			}
 			return []Action{{Type: ActionPrint, Data: rune(b)}}
 		}
 		// UTF-8 and other bytes are handled in ProcessOutput
@@ -705,7 +770,23 @@ func (vt *VTParser) handleGround(b byte, screen *Screen, state *TerminalState, u
 
 // handleEscape processes escape sequences
 func (vt *VTParser) handleEscape(b byte, screen *Screen, state *TerminalState) []Action {
+	if state.VT52 {
+		return vt.handleVT52Escape(b)
+	}
+
+	// ESC # and a digit sets the line size
+	if len(vt.Intermediate) > 0 && vt.Intermediate[0] == '#' {
+		vt.Reset()
+		if action, ok := lineSizeAction(b); ok {
+			return []Action{action}
+		}
+		return nil
+	}
+
 	switch b {
+	case '#': // Line attributes, completed by the next byte
+		vt.Intermediate = append(vt.Intermediate, b)
+		return nil
 	case '[': // CSI
 		vt.State = StateCSI
 		vt.Buffer = vt.Buffer[:0]
@@ -720,6 +801,10 @@ func (vt *VTParser) handleEscape(b byte, screen *Screen, state *TerminalState) [
 		vt.State = StateDCS
 		vt.Buffer = vt.Buffer[:0]
 		return nil
+	case 'X', '^', '_': // SOS, PM, APC
+		vt.State = StateString
+		vt.Buffer = vt.Buffer[:0]
+		return nil
 	case 'D': // IND - Index
 		vt.Reset()
 		return []Action{{Type: ActionScroll, Data: "down"}}
@@ -747,6 +832,9 @@ func (vt *VTParser) handleEscape(b byte, screen *Screen, state *TerminalState) [
 	case 'c': // RIS - Reset to Initial State
 		vt.Reset()
 		return []Action{{Type: ActionReset}}
+	case 'Z': // DECID - Identify, answered like primary DA
+		vt.Reset()
+		return []Action{{Type: ActionSendResponse, Data: "\x1b[?62;1;2;6;7;8;9c"}}
 	default:
 		vt.Reset()
 		return nil
@@ -803,12 +891,10 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "left", Count: count}}}
 	case 'E': // CNL - Cursor Next Line
 		count := vt.getParam(0, 1)
-		actions := []Action{}
-		for i := 0; i < count; i++ {
-			actions = append(actions, Action{Type: ActionNewline})
+		return []Action{
+			{Type: ActionMoveCursor, Data: CursorMove{Direction: "down", Count: count}},
+			{Type: ActionCarriageReturn},
 		}
-		actions = append(actions, Action{Type: ActionCarriageReturn})
-		return actions
 	case 'F': // CPL - Cursor Previous Line
 		count := vt.getParam(0, 1)
 		return []Action{
@@ -829,6 +915,9 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 		mode := vt.getParam(0, 0)
 		return []Action{{Type: ActionClearLine, Data: mode}}
 	case 'm': // SGR - Select Graphic Rendition
+		if vt.paramPrefix() == '>' { // XTMODKEYS - Set key modifier options
+			return vt.modifyOtherKeys(false)
+		}
 		return vt.handleSGR()
 	case 'r': // DECSTBM - Set Top and Bottom Margins
 		top := vt.getParam(0, 1) - 1
@@ -837,6 +926,9 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 	case 's': // SCOSC - Save Cursor Position
 		return []Action{{Type: ActionSaveCursor}}
 	case 'u': // SCORC - Restore Cursor Position
+		if prefix := vt.paramPrefix(); prefix != 0 { // Kitty keyboard protocol
+			return vt.kittyKeyboard(prefix, state)
+		}
 		return []Action{{Type: ActionRestoreCursor}}
 	case 'h': // SM - Set Mode
 		return vt.handleSetMode(true)
@@ -848,10 +940,19 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 	case '@': // ICH - Insert Character
 		count := vt.getParam(0, 1)
 		return []Action{{Type: ActionInsertChar, Data: count}}
+	case 'I': // CHT - Cursor Forward Tabulation
+		count := vt.getParam(0, 1)
+		return []Action{{Type: ActionTab, Data: count}}
+	case 'Z': // CBT - Cursor Backward Tabulation
+		count := vt.getParam(0, 1)
+		return []Action{{Type: ActionBackTab, Data: count}}
 	case 'g': // TBC - Tab Clear
 		mode := vt.getParam(0, 0)
 		return []Action{{Type: ActionClearTabStop, Data: mode}}
 	case 'n': // DSR - Device Status Report
+		if vt.paramPrefix() == '>' { // XTMODKEYS - Disable key modifier options
+			return vt.modifyOtherKeys(true)
+		}
 		mode := vt.getParam(0, 0)
 		switch mode {
 		case 5: // Status Report
@@ -901,6 +1002,21 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 			// This prevents garbage output when receiving partial sequences
 			return nil
 		}
+	case 'i': // MC - Media Copy
+		return vt.mediaCopy()
+	case 'p': // DECRQM - Request Mode
+		if len(vt.Intermediate) == 0 || vt.Intermediate[len(vt.Intermediate)-1] != '$' {
+			return nil
+		}
+		if vt.paramPrefix() == '?' {
+			return []Action{{Type: ActionSendResponse, Data: reportPrivateMode(vt.getParam(0, 0), state)}}
+		}
+		return []Action{{Type: ActionSendResponse, Data: reportMode(vt.getParam(0, 0), state)}}
+	case 'x': // DECREQTPARM - Request Terminal Parameters
+		if response := reportTerminalParameters(vt.getParam(0, 0)); response != "" {
+			return []Action{{Type: ActionSendResponse, Data: response}}
+		}
+		return nil
 	case 'c': // DA - Device Attributes
 		// Send appropriate response based on query type
 		if len(vt.Intermediate) > 0 && vt.Intermediate[0] == '>' {
@@ -924,6 +1040,10 @@ func (vt *VTParser) executeCSI(final byte, screen *Screen, state *TerminalState)
 	}
 }
 
+// maxParam is the largest value a CSI parameter takes, as in xterm. Larger
+// values are clamped, so counts stay bounded and parsing cannot overflow.
+const maxParam = 65535
+
 // parseParams parses parameter string into integer array
 func (vt *VTParser) parseParams() {
 	vt.Params = vt.Params[:0]
@@ -938,7 +1058,7 @@ func (vt *VTParser) parseParams() {
 
 	for _, ch := range paramStr {
 		if ch >= '0' && ch <= '9' {
-			current = current*10 + int(ch-'0')
+			current = min(current*10+int(ch-'0'), maxParam)
 			hasDigit = true
 		} else if ch == ';' {
 			if hasDigit {
@@ -973,9 +1093,35 @@ func (vt *VTParser) handleSGR() []Action {
 		return []Action{{Type: ActionSetAttribute, Data: AttributeChange{Reset: true}}}
 	}
 
+	// Parameters are grouped with their colon-separated subparameters, and
+	// the extended colors use up the parameters after them
 	var actions []Action
-	for _, param := range vt.Params {
-		action := vt.sgrParamToAction(param)
+	groups := vt.sgrGroups()
+	for i := 0; i < len(groups); i++ {
+		group := groups[i]
+		var action *Action
+		switch param := group[0]; param {
+		case 4:
+			action = sgrUnderline(group)
+		case 38, 48, 58:
+			color, used, ok := extendedColor(group, groups[i+1:])
+			i += used
+			if !ok {
+				continue
+			}
+			change := AttributeChange{Foreground: &color}
+			if param == 48 {
+				change = AttributeChange{Background: &color}
+			} else if param == 58 {
+				change = AttributeChange{UnderlineColor: &color}
+			}
+			action = &Action{Type: ActionSetAttribute, Data: change}
+		default:
+			if len(group) > 1 {
+				continue // Subparameters of anything else aren't supported
+			}
+			action = vt.sgrParamToAction(param)
+		}
 		if action != nil {
 			actions = append(actions, *action)
 		}
@@ -999,6 +1145,9 @@ func (vt *VTParser) sgrParamToAction(param int) *Action {
 		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Blink: &[]bool{true}[0]}}
 	case 7: // Reverse
 		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Reverse: &[]bool{true}[0]}}
+	case 21: // Double underline
+		style := UnderlineDouble
+		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Underline: &[]bool{true}[0], UnderlineStyle: &style}}
 	case 22: // Normal intensity (not bold)
 		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Bold: &[]bool{false}[0]}}
 	case 23: // Not italic
@@ -1009,6 +1158,9 @@ func (vt *VTParser) sgrParamToAction(param int) *Action {
 		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Blink: &[]bool{false}[0]}}
 	case 27: // Not reversed
 		return &Action{Type: ActionSetAttribute, Data: AttributeChange{Reverse: &[]bool{false}[0]}}
+	case 59: // Default underline color
+		color := ColorDefault
+		return &Action{Type: ActionSetAttribute, Data: AttributeChange{UnderlineColor: &color}}
 	default:
 		if param >= 30 && param <= 37 { // Foreground colors
 			color := Color(param - 30)
@@ -1049,6 +1201,11 @@ func (vt *VTParser) handleSetMode(set bool) []Action {
 				} else {
 					mode = "cursor_normal"
 				}
+			case 2: // DECANM - ANSI/VT52 Mode; only leaving ANSI mode is a sequence
+				if set {
+					continue
+				}
+				mode = "vt52"
 			case 3: // DECCOLM - 132 Column Mode (not fully supported)
 				continue
 			case 4: // DECSCLM - Smooth Scrolling (not supported)
@@ -1083,12 +1240,24 @@ func (vt *VTParser) handleSetMode(set bool) []Action {
 				} else {
 					return []Action{{Type: ActionSwitchAltScreen, Data: false}}
 				}
-			case 1000: // Mouse tracking
+			case 9: // X10 mouse reporting: button presses only
 				if set {
 					mode = "mouse_x10"
 				} else {
 					mode = "mouse_off"
 				}
+			case 1000: // VT200 normal mouse tracking: presses and releases
+				if set {
+					mode = "mouse_vt200"
+				} else {
+					mode = "mouse_off"
+				}
+			case 1001: // VT200 highlight mouse tracking
+				if set {
+					mode = "mouse_vt200_highlight"
+				} else {
+					mode = "mouse_off"
+				}
 			case 1002: // Cell motion mouse tracking
 				if set {
 					mode = "mouse_btn_event"
@@ -1136,6 +1305,12 @@ func (vt *VTParser) handleSetMode(set bool) []Action {
 				} else {
 					mode = "bracketed_paste_off"
 				}
+			case 2026: // Synchronized Output
+				if set {
+					mode = "sync_on"
+				} else {
+					mode = "sync_off"
+				}
 			default:
 				continue
 			}
@@ -1167,30 +1342,6 @@ func (vt *VTParser) handleSetMode(set bool) []Action {
 	return actions
 }
 
-// handleOSC processes Operating System Command sequences
-func (vt *VTParser) handleOSC(b byte, screen *Screen, state *TerminalState) []Action {
-	if b == 0x07 || b == 0x1B { // BEL or ESC (end of OSC)
-		// TODO: Process OSC command
-		vt.Reset()
-		return nil
-	}
-
-	vt.Buffer = append(vt.Buffer, b)
-	return nil
-}
-
-// handleDCS processes Device Control String sequences
-func (vt *VTParser) handleDCS(b byte, screen *Screen, state *TerminalState) []Action {
-	if b == 0x1B { // ESC (end of DCS)
-		// TODO: Process DCS command
-		vt.Reset()
-		return nil
-	}
-
-	vt.Buffer = append(vt.Buffer, b)
-	return nil
-}
-
 // Supporting data structures
 
 // CursorMove represents cursor movement data
@@ -1211,6 +1362,9 @@ type AttributeChange struct {
 	Reverse    *bool
 	Foreground *Color
 	Background *Color
+
+	UnderlineStyle *UnderlineStyle
+	UnderlineColor *Color
 }
 
 // ScrollRegion represents scroll region data
@@ -1223,6 +1377,7 @@ type ScrollRegion struct {
 type TerminalRenderer struct {
 	screen   tcell.Screen
 	terminal *TerminalEmulator
+	input    *InputProcessor // Turns mouse events into reports, keeping button state between them
 	mutex    sync.RWMutex
 	running  bool
 	events   chan tcell.Event
@@ -1238,6 +1393,7 @@ func NewTerminalRenderer(terminal *TerminalEmulator) (*TerminalRenderer, error)
 	return &TerminalRenderer{
 		screen:   screen,
 		terminal: terminal,
+		input:    NewInputProcessor(terminal),
 		events:   make(chan tcell.Event, 100),
 	}, nil
 }
@@ -1315,7 +1471,7 @@ func (tr *TerminalRenderer) Render() error {
 
 			// tcell's SetContent automatically handles wide characters
 			// It will occupy two cells for wide characters and handle cursor positioning
-			tr.screen.SetContent(x, y, cell.Char, nil, style)
+			tr.screen.SetContent(x, y, cell.Char, cell.Combining(), style)
 		}
 	}
 
@@ -1346,7 +1502,11 @@ func (tr *TerminalRenderer) attributesToStyle(attrs TextAttributes) tcell.Style
 		style = style.Italic(true)
 	}
 	if attrs.Underline {
-		style = style.Underline(true)
+		// The styles are in the same order as tcell's, which start with none
+		style = style.Underline(tcell.UnderlineStyleSolid + tcell.UnderlineStyle(attrs.UnderlineStyle))
+		if attrs.UnderlineColor != ColorDefault {
+			style = style.Underline(tr.colorToTcell(attrs.UnderlineColor))
+		}
 	}
 	if attrs.Reverse {
 		style = style.Reverse(true)
@@ -1360,6 +1520,13 @@ func (tr *TerminalRenderer) attributesToStyle(attrs TextAttributes) tcell.Style
 
 // colorToTcell converts Color to tcell.Color
 func (tr *TerminalRenderer) colorToTcell(color Color) tcell.Color {
+	if color.IsRGB() {
+		r, g, b := color.RGB()
+		return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+	}
+	if color >= 16 && color <= 255 {
+		return tcell.PaletteColor(int(color))
+	}
 	switch color {
 	case ColorBlack:
 		return tcell.ColorBlack
@@ -1498,7 +1665,7 @@ func (te *TerminalEmulator) ProcessOutput(output []byte) error {
 	// Add panic recovery to prevent crashes
 	defer func() {
 		if r := recover(); r != nil {
-			te.logDebug("PANIC in ProcessOutput: %v", r)
+			te.logError("PANIC in ProcessOutput: %v\n%s", r, debug.Stack())
 			// Reset parser state on panic
 			te.parser.Reset()
 			te.utf8Decoder.Reset()
@@ -1524,9 +1691,23 @@ func (te *TerminalEmulator) ProcessOutput(output []byte) error {
 	// 	hexBytes := fmt.Sprintf("%X", output)
 	// 	te.logDebug("UTF-8 Raw bytes received (%d bytes): %s", len(output), hexBytes)
 	// 	te.logDebug("Decoder state at start: buffered=%X, expected=%d, decoder_ptr=%p",
-	// 		te.utf8Decoder.bytes, te.utf8Decoder.expected, te.utf8Decoder)
+	// 		te.utf8Decoder.bytes, te.utf8Decoder.need, te.utf8Decoder)
+	// }
+
+	te.processBytes(output)
+
+	// Log decoder state at end (disabled for performance)
+	// if len(output) > 0 && te.utf8Decoder.expected > 0 {
+	// 	te.logDebug("Decoder state at end: buffered=%X, expected=%d, decoder_ptr=%p",
+	// 		te.utf8Decoder.bytes, te.utf8Decoder.need, te.utf8Decoder)
 	// }
 
+	return nil
+}
+
+// processBytes runs device output through the parser and carries out the
+// actions, with the emulator locked
+func (te *TerminalEmulator) processBytes(output []byte) {
 	// Process the output
 	i := 0
 	processedCount := 0
@@ -1536,7 +1717,7 @@ func (te *TerminalEmulator) ProcessOutput(output []byte) error {
 
 		// Safety check for infinite loops
 		if processedCount > len(output)*2 {
-			te.logDebug("ERROR: Possible infinite loop in ProcessOutput, breaking. i=%d, len=%d", i, len(output))
+			te.logError("Possible infinite loop in ProcessOutput, breaking. i=%d, len=%d", i, len(output))
 			break
 		}
 
@@ -1562,21 +1743,51 @@ func (te *TerminalEmulator) ProcessOutput(output []byte) error {
 		// Debug what byte we're processing (disabled for performance)
 		// if b >= 0x80 || b < 0x20 {
 		// 	te.logDebug("Processing byte[%d]: 0x%02X, parser state=%d, decoder: buffered=%X, expected=%d",
-		// 		i, b, te.parser.State, te.utf8Decoder.bytes, te.utf8Decoder.expected)
+		// 		i, b, te.parser.State, te.utf8Decoder.bytes, te.utf8Decoder.need)
 		// }
 
-		// If in ground state and this could be UTF-8, use custom decoder
+		// In printer controller mode data goes to the printer, not the screen
+		if te.state.PrinterController {
+			i += te.printControllerData(output[i:])
+			continue
+		}
+
+		// If in ground state and this could be UTF-8, use the streaming decoder
 		if te.parser.State == StateGround && b >= 0x80 {
-			// Always use custom decoder for UTF-8 to handle partial sequences
-			if r, complete := te.utf8Decoder.Decode(b); complete && r != 0 {
+			te.parser.crLineFed = false
+			// The decoder keeps partial sequences split across reads
+			te.decoded = te.utf8Decoder.Decode(b, te.decoded[:0])
+			for _, r := range te.decoded {
 				te.executeAction(Action{Type: ActionPrint, Data: r})
 			}
 			i++
 			continue
 		}
 
+		// A control character or ASCII byte cuts off an incomplete sequence
+		if te.utf8Decoder.Pending() {
+			te.decoded = te.utf8Decoder.Flush(te.decoded[:0])
+			for _, r := range te.decoded {
+				te.executeAction(Action{Type: ActionPrint, Data: r})
+			}
+		}
+
 		// Process through VT parser for everything else
+		wasGround := te.parser.State == StateGround
 		actions := te.parser.ParseByte(b, te.GetScreen(), &te.state, te.utf8Decoder)
+		if te.parser.stringCancelled {
+			// The string ended at its ESC, which starts this sequence
+			te.parser.stringCancelled = false
+			te.sequences.cancel()
+			te.sequenceDone(nil)
+		}
+		if !wasGround || te.parser.State != StateGround {
+			done := te.parser.State == StateGround
+			te.sequences.add(b, done)
+			if done {
+				te.sequenceDone(actions)
+			}
+		}
 
 		// Execute actions
 		for _, action := range actions {
@@ -1586,14 +1797,6 @@ func (te *TerminalEmulator) ProcessOutput(output []byte) error {
 
 		i++
 	}
-
-	// Log decoder state at end (disabled for performance)
-	// if len(output) > 0 && te.utf8Decoder.expected > 0 {
-	// 	te.logDebug("Decoder state at end: buffered=%X, expected=%d, decoder_ptr=%p",
-	// 		te.utf8Decoder.bytes, te.utf8Decoder.expected, te.utf8Decoder)
-	// }
-
-	return nil
 }
 
 // logDebug logs debug messages to the configured logger
@@ -1603,6 +1806,13 @@ func (te *TerminalEmulator) logDebug(format string, args ...interface{}) {
 	}
 }
 
+// logError logs errors to the configured logger
+func (te *TerminalEmulator) logError(format string, args ...interface{}) {
+	if te.logger != nil {
+		te.logger.Errorf(format, args...)
+	}
+}
+
 // executeAction executes a terminal action
 func (te *TerminalEmulator) executeAction(action Action) {
 	switch action.Type {
@@ -1621,11 +1831,19 @@ func (te *TerminalEmulator) executeAction(action Action) {
 	case ActionSetMode:
 		te.setMode(action.Data.(string))
 	case ActionBell:
-		// TODO: Implement bell
+		if te.onBell != nil {
+			te.onBell()
+		}
 	case ActionReset:
 		te.resetTerminal()
 	case ActionTab:
-		te.tab()
+		if count, ok := action.Data.(int); ok {
+			te.tabForward(count)
+		} else {
+			te.tab()
+		}
+	case ActionBackTab:
+		te.tabBackward(action.Data.(int))
 	case ActionNewline:
 		te.newline()
 	case ActionCarriageReturn:
@@ -1648,14 +1866,26 @@ func (te *TerminalEmulator) executeAction(action Action) {
 		te.switchAltScreen(action.Data.(bool))
 	case ActionSendResponse:
 		// Send response back to remote device
-		if te.serialPort != nil && te.serialPort.IsOpen() {
-			response := action.Data.(string)
+		response := action.Data.(string)
+		if te.onResponse != nil {
+			te.onResponse([]byte(response))
+		} else if te.serialPort != nil && te.serialPort.IsOpen() {
 			_, _ = te.serialPort.Write([]byte(response))
 		}
 	case ActionSetTabStop:
 		te.setTabStop()
 	case ActionClearTabStop:
 		te.clearTabStop(action.Data.(int))
+	case ActionMediaCopy:
+		te.executeMediaCopy(action.Data.(string))
+	case ActionSetLineSize:
+		te.setLineSize(action.Data.(LineSize))
+	case ActionSetHyperlink:
+		te.setHyperlink(action.Data.(*Hyperlink))
+	case ActionSetKeyProtocol:
+		te.setKeyProtocol(action.Data.(KeyProtocolChange))
+	case ActionPassthrough:
+		te.processPassthrough(action.Data.([]byte))
 	}
 }
 
@@ -1664,8 +1894,69 @@ func runeWidth(r rune) int {
 	return runewidth.RuneWidth(r)
 }
 
+// clusterCell returns the position of the cell a new rune could join:
+// the character just left of the cursor, skipping a wide character's
+// continuation cell
+func (te *TerminalEmulator) clusterCell(screen *Screen) (x, y int, ok bool) {
+	x, y = min(te.state.CursorX, te.state.Width)-1, te.state.CursorY
+	if x < 0 || y < 0 || y >= len(screen.Buffer) || x >= len(screen.Buffer[y]) {
+		return 0, 0, false
+	}
+	if screen.Buffer[y][x].Char == 0 && x > 0 {
+		x--
+	}
+	return x, y, screen.Buffer[y][x].Char != 0
+}
+
+// joinCluster appends ch to the grapheme cluster before the cursor if it
+// extends it (combining accents, Thai vowel marks, emoji ZWJ sequences,
+// variation selectors, flag pairs). Returns false if ch starts a new cluster.
+func (te *TerminalEmulator) joinCluster(ch rune) bool {
+	// Nothing below the combining diacritics block extends a cluster
+	if ch < 0x300 {
+		return false
+	}
+
+	screen := te.GetScreen()
+	x, y, ok := te.clusterCell(screen)
+	if !ok {
+		return false
+	}
+	cell := &screen.Buffer[y][x]
+
+	joined := cell.String() + string(ch)
+	_, rest, width, _ := uniseg.FirstGraphemeClusterInString(joined, -1)
+	if rest != "" {
+		return false
+	}
+
+	oldWidth := 1
+	if x+1 < len(screen.Buffer[y]) && screen.Buffer[y][x+1].Char == 0 {
+		oldWidth = 2
+	}
+	cell.Cluster = joined
+	cell.Dirty = true
+	screen.MarkDirty(x, y)
+
+	// An emoji presentation selector or ZWJ sequence can widen the cluster
+	if width == 2 && oldWidth == 1 && x+1 < te.state.Width && x+1 < len(screen.Buffer[y]) {
+		screen.Buffer[y][x+1] = Cell{Char: 0, Attributes: cell.Attributes, Dirty: true}
+		screen.MarkDirty(x+1, y)
+		if te.state.CursorX == x+1 {
+			te.state.CursorX++
+		}
+	}
+	screen.Dirty = true
+	return true
+}
+
 // printChar prints a character at the current cursor position
 func (te *TerminalEmulator) printChar(ch rune) {
+	// Combining characters attach to the previous cell instead of taking a new one
+	if te.joinCluster(ch) {
+		return
+	}
+
 	// Calculate character width
 	charWidth := runeWidth(ch)
 
@@ -1679,21 +1970,26 @@ func (te *TerminalEmulator) printChar(ch rune) {
 		return
 	}
 
+	// Double-width lines hold half as many characters
+	limit := te.lineLimit()
+
 	// Check if there's enough space for wide characters
-	if charWidth == 2 && te.state.CursorX >= te.state.Width-1 {
+	if charWidth == 2 && te.state.CursorX >= limit-1 {
 		// Not enough space for wide character
 		if te.state.LineWrap {
 			// Line wrap enabled: move to next line
+			te.markWrapped(te.state.CursorY)
 			te.newline()
 			te.carriageReturn()
 		} else {
 			// Line wrap disabled: stay at last column
-			te.state.CursorX = te.state.Width - 1
+			te.state.CursorX = limit - 1
 			return
 		}
-	} else if te.state.CursorX >= te.state.Width {
+	} else if te.state.CursorX >= limit {
 		if te.state.LineWrap {
 			// Line wrap enabled: move to next line
+			te.markWrapped(te.state.CursorY)
 			te.newline()
 			te.carriageReturn()
 		} else {
@@ -1717,6 +2013,8 @@ func (te *TerminalEmulator) printChar(ch rune) {
 		screen.Buffer[te.state.CursorY][te.state.CursorX] = Cell{
 			Char:       ch,
 			Attributes: te.state.Attributes,
+			LineSize:   te.lineSize(te.state.CursorY),
+			Hyperlink:  te.state.Hyperlink,
 			Dirty:      true,
 		}
 		screen.MarkDirty(te.state.CursorX, te.state.CursorY)
@@ -1733,6 +2031,8 @@ func (te *TerminalEmulator) printChar(ch rune) {
 			screen.Buffer[te.state.CursorY][te.state.CursorX+1] = Cell{
 				Char:       0, // Use null character to indicate this cell is part of previous character
 				Attributes: te.state.Attributes,
+				LineSize:   te.lineSize(te.state.CursorY),
+				Hyperlink:  te.state.Hyperlink,
 				Dirty:      true,
 			}
 			screen.MarkDirty(te.state.CursorX+1, te.state.CursorY)
@@ -1813,6 +2113,8 @@ func (te *TerminalEmulator) clearScreen(mode int) {
 			te.logger.Debugf("[clearScreen] Mode 2 - Cursor reset to (0,0) from (%d,%d)",
 				te.state.CursorX, te.state.CursorY)
 		}
+	case 3: // Erase saved lines
+		te.eraseSavedLines()
 	}
 
 	// Force entire screen to be redrawn
@@ -1845,21 +2147,22 @@ func (te *TerminalEmulator) clearScreen(mode int) {
 func (te *TerminalEmulator) clearLine(mode int) {
 	y := te.state.CursorY
 	screen := te.GetScreen()
+	size := te.lineSize(y) // Erasing doesn't change the line size
 
 	switch mode {
 	case 0: // Clear from cursor to end of line
 		for x := te.state.CursorX; x < te.state.Width; x++ {
-			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), Dirty: true}
+			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), LineSize: size, Dirty: true}
 			screen.MarkDirty(x, y)
 		}
-	case 1: // Clear from beginning of line to cursor
-		for x := 0; x <= te.state.CursorX; x++ {
-			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), Dirty: true}
+	case 1: // Clear from beginning of line to cursor, which is past the last column while a wrap is pending
+		for x := 0; x <= te.state.CursorX && x < te.state.Width; x++ {
+			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), LineSize: size, Dirty: true}
 			screen.MarkDirty(x, y)
 		}
 	case 2: // Clear entire line
 		for x := 0; x < te.state.Width; x++ {
-			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), Dirty: true}
+			screen.Buffer[y][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), LineSize: size, Dirty: true}
 			screen.MarkDirty(x, y)
 		}
 	}
@@ -1882,6 +2185,12 @@ func (te *TerminalEmulator) setAttribute(change AttributeChange) {
 	if change.Underline != nil {
 		te.state.Attributes.Underline = *change.Underline
 	}
+	if change.UnderlineStyle != nil {
+		te.state.Attributes.UnderlineStyle = *change.UnderlineStyle
+	}
+	if change.UnderlineColor != nil {
+		te.state.Attributes.UnderlineColor = *change.UnderlineColor
+	}
 	if change.Blink != nil {
 		te.state.Attributes.Blink = *change.Blink
 	}
@@ -1931,19 +2240,17 @@ func (te *TerminalEmulator) scrollUp() {
 		te.scrollbackBuffer = append(te.scrollbackBuffer, topLine)
 
 		// Trim scrollback if it exceeds maximum size
-		if len(te.scrollbackBuffer) > te.scrollbackSize {
-			te.scrollbackBuffer = te.scrollbackBuffer[1:]
-		}
+		te.trimScrollback()
 	}
 
-	// Move all lines up within scroll region
+	// Move all lines up within scroll region. The moved rows aren't marked
+	// dirty; the renderer moves them on screen too.
 	for y := te.state.ScrollTop; y < te.state.ScrollBottom && y < len(screen.Buffer)-1; y++ {
 		if y+1 < len(screen.Buffer) {
 			copy(screen.Buffer[y], screen.Buffer[y+1])
-			// Mark entire line as dirty after copying
-			screen.MarkLineDirty(y)
 		}
 	}
+	screen.recordScroll(te.state.ScrollTop, te.state.ScrollBottom, 1)
 
 	// Clear bottom line of scroll region
 	if te.state.ScrollBottom >= 0 && te.state.ScrollBottom < len(screen.Buffer) {
@@ -1975,19 +2282,14 @@ func (te *TerminalEmulator) scrollDown() {
 		te.state.ScrollBottom = len(screen.Buffer) - 1
 	}
 
-	// Move all lines down within scroll region
+	// Move all lines down within scroll region, recorded for the renderer
+	// like scrolling up
 	for y := te.state.ScrollBottom; y > te.state.ScrollTop; y-- {
 		if y > 0 && y < len(screen.Buffer) && y-1 >= 0 && y-1 < len(screen.Buffer) {
 			copy(screen.Buffer[y], screen.Buffer[y-1])
-			// Mark entire line as dirty after copying
-			// Use actual buffer width, not state width
-			lineWidth := len(screen.Buffer[y])
-			for x := 0; x < lineWidth; x++ {
-				screen.Buffer[y][x].Dirty = true
-				screen.MarkDirty(x, y)
-			}
 		}
 	}
+	screen.recordScroll(te.state.ScrollTop, te.state.ScrollBottom, -1)
 
 	// Clear top line of scroll region
 	if te.state.ScrollTop >= 0 && te.state.ScrollTop < len(screen.Buffer) {
@@ -2006,6 +2308,7 @@ func (te *TerminalEmulator) EnterScrollMode() {
 	// This fixes the view position even as new data arrives
 	te.scrollPosition = len(te.scrollbackBuffer)
 	te.scrollOffset = 0 // Start at current view
+	te.scrollEntryLines = te.scrollbackDropped + len(te.scrollbackBuffer)
 }
 
 // ExitScrollMode exits scrollback viewing mode
@@ -2094,7 +2397,17 @@ func (te *TerminalEmulator) GetScrollPosition() (current, total int) {
 	if !te.isScrolling {
 		return 0, len(te.scrollbackBuffer)
 	}
-	return te.scrollOffset, len(te.scrollbackBuffer)
+	// Measured from the live end so it grows as new lines arrive
+	return len(te.scrollbackBuffer) - te.scrollPosition, len(te.scrollbackBuffer)
+}
+
+// NewLinesSinceScroll returns how many lines have scrolled off the live
+// screen since scroll mode was entered
+func (te *TerminalEmulator) NewLinesSinceScroll() int {
+	if !te.isScrolling {
+		return 0
+	}
+	return max(0, te.scrollbackDropped+len(te.scrollbackBuffer)-te.scrollEntryLines)
 }
 
 // GetScrollbackBuffer returns a view of the screen including scrollback
@@ -2141,8 +2454,40 @@ func (te *TerminalEmulator) GetScrollbackView() [][]Cell {
 	return view
 }
 
+// GetScrollbackDropped returns the total number of lines dropped from the
+// head of the scrollback buffer. Adding it to an index into GetAllLines gives
+// a line number that stays stable as old lines are trimmed.
+func (te *TerminalEmulator) GetScrollbackDropped() int {
+	return te.scrollbackDropped
+}
+
+// GetTopLineIndex returns the GetAllLines index of the top visible line
+func (te *TerminalEmulator) GetTopLineIndex() int {
+	if te.isScrolling {
+		return te.scrollPosition
+	}
+	return len(te.scrollbackBuffer)
+}
+
+// ScrollToLine enters scroll mode with the given GetAllLines index at the top of the view
+func (te *TerminalEmulator) ScrollToLine(index int) {
+	if !te.isScrolling {
+		te.EnterScrollMode()
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index > len(te.scrollbackBuffer) {
+		index = len(te.scrollbackBuffer)
+	}
+	te.scrollPosition = index
+	te.scrollOffset = len(te.scrollbackBuffer) - te.scrollPosition
+	te.GetScreen().Dirty = true
+}
+
 // ClearScrollback clears the scrollback buffer
 func (te *TerminalEmulator) ClearScrollback() {
+	te.scrollbackDropped += len(te.scrollbackBuffer)
 	te.scrollbackBuffer = make([][]Cell, 0, te.scrollbackSize)
 	te.ExitScrollMode()
 }
@@ -2162,6 +2507,13 @@ func (te *TerminalEmulator) GetAllLines() [][]Cell {
 	return allLines
 }
 
+// SetInvalidUTF8Policy sets how bytes that aren't valid UTF-8 are shown
+func (te *TerminalEmulator) SetInvalidUTF8Policy(policy InvalidUTF8Policy) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.utf8Decoder.SetPolicy(policy)
+}
+
 // SetLineWrap enables or disables line wrapping
 func (te *TerminalEmulator) SetLineWrap(enabled bool) {
 	te.state.LineWrap = enabled
@@ -2178,8 +2530,23 @@ func (te *TerminalEmulator) SetScrollbackSize(size int) {
 	te.scrollbackSize = size
 
 	// Trim existing buffer if it exceeds new size
-	if len(te.scrollbackBuffer) > size {
-		te.scrollbackBuffer = te.scrollbackBuffer[len(te.scrollbackBuffer)-size:]
+	te.trimScrollback()
+}
+
+// trimScrollback drops the oldest lines beyond the scrollback size. In scroll
+// mode the view position is moved with the buffer so the same lines stay on
+// screen; if they were trimmed the view stops at the oldest remaining line.
+func (te *TerminalEmulator) trimScrollback() {
+	excess := len(te.scrollbackBuffer) - te.scrollbackSize
+	if excess <= 0 {
+		return
+	}
+	te.scrollbackBuffer = te.scrollbackBuffer[excess:]
+	te.scrollbackDropped += excess
+
+	if te.isScrolling {
+		te.scrollPosition = max(0, te.scrollPosition-excess)
+		te.scrollOffset = len(te.scrollbackBuffer) - te.scrollPosition
 	}
 }
 
@@ -2195,54 +2562,82 @@ func (te *TerminalEmulator) setMode(mode string) {
 		// TODO: Implement cursor visibility
 	case "cursor_hidden":
 		// TODO: Implement cursor visibility
-	case "mouse_x10":
-		oldMode := te.state.MouseMode
-		te.state.MouseMode = MouseModeX10
-		te.logDebug("Mouse mode changed: %v -> %v (X10)", oldMode, te.state.MouseMode)
-		if te.onMouseModeChange != nil {
-			te.onMouseModeChange(MouseModeX10)
-		}
-	case "mouse_btn_event":
-		oldMode := te.state.MouseMode
-		te.state.MouseMode = MouseModeBtnEvent
-		te.logDebug("Mouse mode changed: %v -> %v (Button Event)", oldMode, te.state.MouseMode)
-		if te.onMouseModeChange != nil {
-			te.onMouseModeChange(MouseModeBtnEvent)
-		}
-	case "mouse_any_event":
-		oldMode := te.state.MouseMode
-		te.state.MouseMode = MouseModeAnyEvent
-		te.logDebug("Mouse mode changed: %v -> %v (Any Event)", oldMode, te.state.MouseMode)
-		if te.onMouseModeChange != nil {
-			te.onMouseModeChange(MouseModeAnyEvent)
-		}
-	case "mouse_off":
-		oldMode := te.state.MouseMode
-		te.state.MouseMode = MouseModeOff
-		te.logDebug("Mouse mode changed: %v -> %v (Off)", oldMode, te.state.MouseMode)
-		if te.onMouseModeChange != nil {
-			te.onMouseModeChange(MouseModeOff)
-		}
+	case "mouse_x10", "mouse_vt200", "mouse_vt200_highlight", "mouse_btn_event", "mouse_any_event", "mouse_off":
+		te.setMouseMode(MouseModeFromName(mode[len("mouse_"):]))
+	case "vt52":
+		te.state.VT52 = true
+		te.state.VT52Graphics = false
+		te.logDebug("Entered VT52 mode")
+	case "ansi":
+		te.state.VT52 = false
+		te.state.VT52Graphics = false
+		te.logDebug("Left VT52 mode")
+	case "vt52_graphics_on":
+		te.state.VT52Graphics = true
+	case "vt52_graphics_off":
+		te.state.VT52Graphics = false
+	case "bracketed_paste_on":
+		te.state.BracketedPaste = true
+	case "bracketed_paste_off":
+		te.state.BracketedPaste = false
+	case "sync_on", "sync_off":
+		te.setSynchronizedOutput(mode == "sync_on")
+	case "newline", "linefeed":
+		te.state.NewlineMode = mode == "newline"
+	}
+}
+
+// setMouseMode changes the mouse tracking mode and tells the callback
+func (te *TerminalEmulator) setMouseMode(mode MouseMode) {
+	oldMode := te.state.MouseMode
+	te.state.MouseMode = mode
+	te.logDebug("Mouse mode changed: %v -> %v", oldMode, mode)
+	if te.onMouseModeChange != nil {
+		te.onMouseModeChange(mode)
 	}
 }
 
 // tab moves cursor to next tab stop
 func (te *TerminalEmulator) tab() {
-	// Find next tab stop after current position
-	nextTab := -1
-	for col := te.state.CursorX + 1; col < te.state.Width; col++ {
-		if te.tabStops[col] {
-			nextTab = col
-			break
+	te.tabForward(1)
+}
+
+// tabForward moves the cursor forward count tab stops (HT, CHT). With no
+// tab stop left on the line the cursor stops at the last column.
+func (te *TerminalEmulator) tabForward(count int) {
+	// After writing the last column the cursor may sit just past it
+	x := min(te.state.CursorX, te.state.Width-1)
+	for ; count > 0 && x < te.state.Width-1; count-- {
+		x++
+		for x < te.state.Width-1 && !te.tabStops[x] {
+			x++
 		}
 	}
+	te.state.CursorX = max(x, 0)
+}
 
-	if nextTab != -1 {
-		te.state.CursorX = nextTab
-	} else {
-		// No tab stop found, move to end of line
-		te.state.CursorX = te.state.Width - 1
+// tabBackward moves the cursor back count tab stops (CBT). With no tab stop
+// left before the cursor it stops at the first column.
+func (te *TerminalEmulator) tabBackward(count int) {
+	x := min(te.state.CursorX, te.state.Width-1)
+	for ; count > 0 && x > 0; count-- {
+		x--
+		for x > 0 && !te.tabStops[x] {
+			x--
+		}
+	}
+	te.state.CursorX = max(x, 0)
+}
+
+// addDefaultTabStops sets a tab stop every 8 columns on columns that haven't
+// had default tab stops before, up to width. Columns that were already
+// covered keep whatever HTS and TBC made of them, even across resizes.
+func (te *TerminalEmulator) addDefaultTabStops(width int) {
+	start := (te.tabStopWidth + 7) / 8 * 8
+	for i := max(start, 8); i < width; i += 8 {
+		te.tabStops[i] = true
 	}
+	te.tabStopWidth = max(te.tabStopWidth, width)
 }
 
 // newline moves cursor to next line
@@ -2261,6 +2656,11 @@ func (te *TerminalEmulator) newline() {
 		te.state.ScrollTop = 0
 	}
 
+	// Auto print mode prints each line as the cursor leaves it
+	if te.state.AutoPrint {
+		te.printLine(te.state.CursorY)
+	}
+
 	te.state.CursorY++
 	if te.state.CursorY >= te.state.Height {
 		te.scroll("up")
@@ -2288,8 +2688,13 @@ func (te *TerminalEmulator) backspace() {
 // deleteChar deletes characters at cursor position
 func (te *TerminalEmulator) deleteChar(count int) {
 	y := te.state.CursorY
-	x := te.state.CursorX
+	x := min(te.state.CursorX, te.state.Width-1)
 	screen := te.GetScreen()
+	if x < 0 {
+		return
+	}
+	// At most the characters from the cursor to the right margin go
+	count = min(count, te.state.Width-x)
 
 	// Shift characters left
 	for i := x; i < te.state.Width-count; i++ {
@@ -2369,8 +2774,9 @@ func (te *TerminalEmulator) clearToCursor() {
 		}
 	}
 
-	// Clear from beginning of current line to cursor
-	for x := 0; x <= te.state.CursorX; x++ {
+	// Clear from beginning of current line to cursor, which is past the
+	// last column while a wrap is pending
+	for x := 0; x <= te.state.CursorX && x < te.state.Width; x++ {
 		screen.Buffer[te.state.CursorY][x] = Cell{Char: ' ', Attributes: DefaultTextAttributes(), Dirty: true}
 		screen.MarkDirty(x, te.state.CursorY)
 	}
@@ -2393,8 +2799,9 @@ func (te *TerminalEmulator) clearEntireScreen() {
 	}
 
 	// Save current screen to scrollback before clearing
-	// This preserves history like most terminal emulators
-	if len(screen.Buffer) > 0 {
+	// This preserves history like most terminal emulators, unless the clear
+	// policy drops it
+	if len(screen.Buffer) > 0 && te.clearPolicy == ClearPush {
 		for y := 0; y < te.state.Height && y < len(screen.Buffer); y++ {
 			// Only save non-empty lines
 			hasContent := false
@@ -2410,9 +2817,7 @@ func (te *TerminalEmulator) clearEntireScreen() {
 				te.scrollbackBuffer = append(te.scrollbackBuffer, lineCopy)
 
 				// Trim scrollback if it exceeds maximum size
-				if len(te.scrollbackBuffer) > te.scrollbackSize {
-					te.scrollbackBuffer = te.scrollbackBuffer[1:]
-				}
+				te.trimScrollback()
 			}
 		}
 	}
@@ -2478,6 +2883,18 @@ func (te *TerminalEmulator) resetTerminal() {
 	te.state.ScrollBottom = te.state.Height - 1
 	te.state.LineWrap = true
 	te.state.MouseMode = MouseModeOff
+	te.state.BracketedPaste = false
+	te.state.VT52 = false
+	te.state.VT52Graphics = false
+	te.state.PrinterController = false
+	te.state.AutoPrint = false
+	te.printerHeld = 0
+	te.state.Hyperlink = nil
+	te.state.ModifyOtherKeys = 0
+	te.state.KittyKeyboard = 0
+	te.kittyStack = nil
+	te.state.SynchronizedOutput = false
+	te.state.NewlineMode = false
 
 	// Clear saved state
 	te.savedState = nil
@@ -2491,11 +2908,11 @@ func (te *TerminalEmulator) resetTerminal() {
 
 	// Clear tab stops and set defaults (every 8 columns)
 	te.tabStops = make(map[int]bool)
-	for i := 8; i < te.state.Width; i += 8 {
-		te.tabStops[i] = true
-	}
+	te.tabStopWidth = 0
+	te.addDefaultTabStops(te.state.Width)
 
 	// Clear the scrollback buffer
+	te.scrollbackDropped += len(te.scrollbackBuffer)
 	te.scrollbackBuffer = make([][]Cell, 0, te.scrollbackSize)
 	te.scrollOffset = 0
 	te.scrollPosition = 0
@@ -2526,6 +2943,14 @@ func (te *TerminalEmulator) Resize(width, height int) error {
 		return fmt.Errorf("invalid dimensions: %dx%d", width, height)
 	}
 
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	// Lines in the scrollback are wrapped again to fill the new width
+	if width != te.state.Width {
+		te.rewrapScrollback(width)
+	}
+
 	// Helper function to resize a screen buffer
 	resizeScreen := func(oldScreen *Screen) *Screen {
 		newScreen := NewScreen(width, height)
@@ -2538,6 +2963,12 @@ func (te *TerminalEmulator) Resize(width, height int) error {
 			for x := 0; x < copyWidth && x < len(oldScreen.Buffer[y]) && x < len(newScreen.Buffer[y]); x++ {
 				newScreen.Buffer[y][x] = oldScreen.Buffer[y][x]
 			}
+			// A cut row still wraps at its new end; a widened one no longer
+			// reaches the edge, so its line ends there
+			if y < len(newScreen.Buffer) && y < len(oldScreen.Buffer) && copyWidth > 0 {
+				wrapped := width <= oldScreen.Width && IsWrapped(oldScreen.Buffer[y])
+				newScreen.Buffer[y][copyWidth-1].Wrapped = wrapped
+			}
 		}
 
 		return newScreen
@@ -2564,23 +2995,9 @@ func (te *TerminalEmulator) Resize(width, height int) error {
 	}
 	te.state.ScrollTop = 0
 
-	// Rebuild tab stops for new width
-	oldTabStops := te.tabStops
-	te.tabStops = make(map[int]bool)
-
-	// Copy existing tab stops that are still within bounds
-	for col := range oldTabStops {
-		if col < width {
-			te.tabStops[col] = true
-		}
-	}
-
-	// Ensure default tab stops exist
-	for i := 8; i < width; i += 8 {
-		if _, exists := te.tabStops[i]; !exists {
-			te.tabStops[i] = true
-		}
-	}
+	// Tab stops beyond the new width are kept so they come back if the
+	// terminal grows again; only columns never seen before get defaults
+	te.addDefaultTabStops(width)
 
 	return nil
 }
@@ -2588,7 +3005,7 @@ func (te *TerminalEmulator) Resize(width, height int) error {
 // EnableMouse enables or disables mouse support
 func (te *TerminalEmulator) EnableMouse(enable bool) error {
 	if enable {
-		te.state.MouseMode = MouseModeX10
+		te.state.MouseMode = MouseModeVT200
 	} else {
 		te.state.MouseMode = MouseModeOff
 	}
@@ -2628,25 +3045,29 @@ func (te *TerminalEmulator) restoreCursor() {
 	}
 }
 
-// setTabStop sets a tab stop at the current cursor position
+// setTabStop sets a tab stop at the current cursor position (HTS)
 func (te *TerminalEmulator) setTabStop() {
-	te.tabStops[te.state.CursorX] = true
+	te.tabStops[min(te.state.CursorX, te.state.Width-1)] = true
 }
 
 // clearTabStop clears tab stops based on mode
 func (te *TerminalEmulator) clearTabStop(mode int) {
 	switch mode {
 	case 0: // Clear tab stop at current position
-		delete(te.tabStops, te.state.CursorX)
-	case 3: // Clear all tab stops
+		delete(te.tabStops, min(te.state.CursorX, te.state.Width-1))
+	case 3: // Clear all tab stops; tabs then go to the end of the line
 		te.tabStops = make(map[int]bool)
-		// Restore default tab stops every 8 columns
-		for i := 8; i < te.state.Width; i += 8 {
-			te.tabStops[i] = true
-		}
 	}
 }
 
+// IsAltScreen reports whether a full-screen program has switched to the
+// alternate screen buffer
+func (te *TerminalEmulator) IsAltScreen() bool {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	return te.useAltScreen
+}
+
 // switchAltScreen switches between main and alternative screen buffers
 func (te *TerminalEmulator) switchAltScreen(useAlt bool) {
 	if useAlt && !te.useAltScreen {
@@ -2669,6 +3090,7 @@ func (te *TerminalEmulator) switchAltScreen(useAlt bool) {
 			}
 		}
 		altScreen.Dirty = true
+		altScreen.discardScroll()
 
 		// Now switch to alt screen
 		te.useAltScreen = true
@@ -2690,6 +3112,7 @@ func (te *TerminalEmulator) switchAltScreen(useAlt bool) {
 		// Mark the main screen as needing full redraw
 		// This ensures the main screen content is properly restored
 		te.screen.Dirty = true
+		te.screen.discardScroll()
 		for y := 0; y < te.screen.Height && y < len(te.screen.Buffer); y++ {
 			te.screen.MarkLineDirty(y)
 			// Mark all cells as dirty to force redraw
@@ -2809,13 +3232,18 @@ func (ma MouseAction) String() string {
 	}
 }
 
-// MouseHandler handles mouse events and converts them to terminal sequences
+// MouseHandler handles mouse events and converts them to terminal sequences.
+// It keeps which button is held between events to report drags and
+// releases, so one handler must see all of them; it is safe to share.
 type MouseHandler struct {
+	mu          sync.Mutex
 	mode        MouseMode
 	lastX       int
 	lastY       int
 	buttonState map[MouseButton]bool
 	dragButton  MouseButton
+	pressX      int // Where dragButton was pressed, for highlight tracking
+	pressY      int
 }
 
 // NewMouseHandler creates a new mouse handler
@@ -2827,18 +3255,29 @@ func NewMouseHandler() *MouseHandler {
 	}
 }
 
-// SetMode sets the mouse mode
+// SetMode sets the mouse mode. Turning tracking off forgets the held
+// buttons, whose releases the device no longer expects.
 func (mh *MouseHandler) SetMode(mode MouseMode) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	if mode == MouseModeOff && mh.mode != MouseModeOff {
+		clear(mh.buttonState)
+		mh.dragButton = MouseButtonNone
+	}
 	mh.mode = mode
 }
 
 // GetMode returns the current mouse mode
 func (mh *MouseHandler) GetMode() MouseMode {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
 	return mh.mode
 }
 
 // ProcessTcellEvent processes a tcell mouse event and returns terminal sequences
 func (mh *MouseHandler) ProcessTcellEvent(event *tcell.EventMouse) []byte {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
 	if mh.mode == MouseModeOff {
 		return nil
 	}
@@ -2846,12 +3285,19 @@ func (mh *MouseHandler) ProcessTcellEvent(event *tcell.EventMouse) []byte {
 	x, y := event.Position()
 	buttons := event.Buttons()
 
-	mouseEvent := mh.tcellToMouseEvent(x, y, buttons)
+	mouseEvent, ok := mh.tcellToMouseEvent(x, y, buttons)
+	if !ok {
+		return nil
+	}
+	mouseEvent.Mods = event.Modifiers()
 	return mh.mouseEventToSequence(mouseEvent)
 }
 
-// tcellToMouseEvent converts tcell event to MouseEvent
-func (mh *MouseHandler) tcellToMouseEvent(x, y int, buttons tcell.ButtonMask) MouseEvent {
+// tcellToMouseEvent converts a tcell event, which carries the buttons held
+// rather than what changed, to a MouseEvent. Returns false if nothing
+// changed: no button was pressed or released and the pointer is still in
+// the same cell.
+func (mh *MouseHandler) tcellToMouseEvent(x, y int, buttons tcell.ButtonMask) (MouseEvent, bool) {
 	var button MouseButton
 	var action MouseAction
 
@@ -2869,46 +3315,40 @@ func (mh *MouseHandler) tcellToMouseEvent(x, y int, buttons tcell.ButtonMask) Mo
 	case buttons&tcell.WheelDown != 0:
 		currentButton = MouseButtonWheelDown
 	}
+	moved := x != mh.lastX || y != mh.lastY
 
-	// Handle button state changes
-	if currentButton == MouseButtonNone {
-		// No button pressed now - check if we had a button pressed before
-		if mh.dragButton != MouseButtonNone {
-			// Button was released
-			button = mh.dragButton
-			action = MouseActionRelease
-			mh.buttonState[mh.dragButton] = false
-			// Debug: log release detection
-			// fmt.Printf("Mouse: Release detected for button %v\n", button)
-			mh.dragButton = MouseButtonNone
-		} else if x != mh.lastX || y != mh.lastY {
-			// Mouse moved without button
-			button = MouseButtonNone
-			action = MouseActionMove
-		} else {
-			// No change
-			button = MouseButtonNone
-			action = MouseActionMove
+	switch {
+	case currentButton == MouseButtonWheelUp || currentButton == MouseButtonWheelDown:
+		// Wheel events are single presses, never held
+		button = currentButton
+		action = MouseActionPress
+	case currentButton == MouseButtonNone && mh.dragButton != MouseButtonNone:
+		// Button was released
+		button = mh.dragButton
+		action = MouseActionRelease
+		mh.buttonState[mh.dragButton] = false
+		mh.dragButton = MouseButtonNone
+	case currentButton == MouseButtonNone:
+		// Mouse moved without button
+		if !moved {
+			return MouseEvent{}, false
 		}
-	} else {
-		// Button is pressed
+		button = MouseButtonNone
+		action = MouseActionMove
+	case !mh.buttonState[currentButton]:
+		// Button just pressed
 		button = currentButton
-		wasPressed := mh.buttonState[button]
-
-		if !wasPressed {
-			// Button just pressed
-			action = MouseActionPress
-			mh.buttonState[button] = true
-			if button != MouseButtonWheelUp && button != MouseButtonWheelDown {
-				mh.dragButton = button
-			}
-		} else if x != mh.lastX || y != mh.lastY {
-			// Button held and mouse moved (drag)
-			action = MouseActionDrag
-		} else {
-			// Button held but no movement
-			action = MouseActionPress // Keep reporting as press
+		action = MouseActionPress
+		mh.buttonState[button] = true
+		mh.dragButton = button
+		mh.pressX, mh.pressY = x, y
+	default:
+		// Button held and mouse moved (drag)
+		if !moved {
+			return MouseEvent{}, false
 		}
+		button = currentButton
+		action = MouseActionDrag
 	}
 
 	mh.lastX = x
@@ -2919,7 +3359,7 @@ func (mh *MouseHandler) tcellToMouseEvent(x, y int, buttons tcell.ButtonMask) Mo
 		Y:      y,
 		Button: button,
 		Action: action,
-	}
+	}, true
 }
 
 // mouseEventToSequence converts MouseEvent to terminal escape sequence
@@ -2929,6 +3369,8 @@ func (mh *MouseHandler) mouseEventToSequence(event MouseEvent) []byte {
 		return mh.generateX10Sequence(event)
 	case MouseModeVT200:
 		return mh.generateVT200Sequence(event)
+	case MouseModeVT200Highlight:
+		return mh.generateHighlightSequence(event)
 	case MouseModeBtnEvent:
 		return mh.generateBtnEventSequence(event)
 	case MouseModeAnyEvent:
@@ -2938,9 +3380,37 @@ func (mh *MouseHandler) mouseEventToSequence(event MouseEvent) []byte {
 	}
 }
 
-// generateX10Sequence generates X10 mouse sequence
+// mouseSequence encodes a report as ESC [ M Cb Cx Cy, each offset by 32
+// and the position 1-based. Positions past what a byte holds are sent as
+// the largest one.
+func mouseSequence(cb, x, y int) []byte {
+	return []byte{
+		0x1B, '[', 'M',
+		byte(cb + 32),
+		byte(min(x+33, 255)),
+		byte(min(y+33, 255)),
+	}
+}
+
+// mouseModifierBits returns what the held modifiers add to a button code:
+// 4 for Shift, 8 for Meta (Alt) and 16 for Control
+func mouseModifierBits(mods tcell.ModMask) int {
+	bits := 0
+	if mods&tcell.ModShift != 0 {
+		bits |= 4
+	}
+	if mods&tcell.ModAlt != 0 || mods&tcell.ModMeta != 0 {
+		bits |= 8
+	}
+	if mods&tcell.ModCtrl != 0 {
+		bits |= 16
+	}
+	return bits
+}
+
+// generateX10Sequence generates X10 mouse sequence (mode 9)
 func (mh *MouseHandler) generateX10Sequence(event MouseEvent) []byte {
-	// X10 mode only reports button press
+	// X10 mode only reports presses of the three buttons, without modifiers
 	if event.Action != MouseActionPress {
 		return nil
 	}
@@ -2950,95 +3420,77 @@ func (mh *MouseHandler) generateX10Sequence(event MouseEvent) []byte {
 		return nil
 	}
 
-	// ESC[M + button + x + y (all offset by 32)
-	return []byte{
-		0x1B, '[', 'M',
-		byte(cb + 32),
-		byte(event.X + 33), // 1-based + 32 offset
-		byte(event.Y + 33), // 1-based + 32 offset
-	}
+	return mouseSequence(cb, event.X, event.Y)
 }
 
-// generateVT200Sequence generates VT200 mouse sequence
+// generateVT200Sequence generates VT200 normal tracking sequence (mode
+// 1000): presses and releases with modifiers, and the wheel
 func (mh *MouseHandler) generateVT200Sequence(event MouseEvent) []byte {
-	// VT200 mode reports press and release
 	if event.Action != MouseActionPress && event.Action != MouseActionRelease {
 		return nil
 	}
 
 	cb := mh.buttonToVT200Code(event.Button, event.Action)
+	if event.Button == MouseButtonWheelUp || event.Button == MouseButtonWheelDown {
+		cb = mh.buttonToBtnEventCode(event.Button, event.Action)
+	}
 	if cb == -1 {
 		return nil
 	}
 
-	// ESC[M + button + x + y (all offset by 32)
-	return []byte{
-		0x1B, '[', 'M',
-		byte(cb + 32),
-		byte(event.X + 33),
-		byte(event.Y + 33),
+	return mouseSequence(cb+mouseModifierBits(event.Mods), event.X, event.Y)
+}
+
+// generateHighlightSequence generates VT200 highlight tracking sequences
+// (mode 1001). Presses are reported as in mode 1000; the application may
+// answer with a highlight request, which isn't drawn. The release reports
+// the tracked region: ESC [ t and the end position when the pointer is
+// back where it was pressed, otherwise ESC [ T with the start, end and
+// pointer positions.
+func (mh *MouseHandler) generateHighlightSequence(event MouseEvent) []byte {
+	if event.Action != MouseActionRelease {
+		return mh.generateVT200Sequence(event)
+	}
+	pos := func(x, y int) []byte {
+		return []byte{byte(min(x+33, 255)), byte(min(y+33, 255))}
 	}
+	if event.X == mh.pressX && event.Y == mh.pressY {
+		return append([]byte("\x1b[t"), pos(event.X, event.Y)...)
+	}
+	sequence := []byte("\x1b[T")
+	startX, startY, endX, endY := mh.pressX, mh.pressY, event.X, event.Y
+	if endY < startY || (endY == startY && endX < startX) {
+		startX, startY, endX, endY = endX, endY, startX, startY
+	}
+	sequence = append(sequence, pos(startX, startY)...)
+	sequence = append(sequence, pos(endX, endY)...)
+	return append(sequence, pos(event.X, event.Y)...)
 }
 
-// generateBtnEventSequence generates button event sequence
+// generateBtnEventSequence generates button event sequence (mode 1002):
+// mode 1000 reports and motion while a button is held
 func (mh *MouseHandler) generateBtnEventSequence(event MouseEvent) []byte {
-	// Button event mode reports press, release, and drag
-	if event.Action == MouseActionMove && event.Button == MouseButtonNone {
+	if event.Action == MouseActionMove {
 		return nil // Don't report plain moves without button
 	}
-
-	var cb int
-	if event.Action == MouseActionRelease {
-		// Release always uses code 3
-		cb = 3
-	} else {
-		cb = mh.buttonToBtnEventCode(event.Button, event.Action)
+	if event.Action != MouseActionDrag {
+		return mh.generateVT200Sequence(event)
 	}
+
+	cb := mh.buttonToBtnEventCode(event.Button, event.Action)
 	if cb == -1 {
 		return nil
 	}
-
-	return []byte{
-		0x1B, '[', 'M',
-		byte(cb + 32),
-		byte(event.X + 33),
-		byte(event.Y + 33),
-	}
+	return mouseSequence(cb+mouseModifierBits(event.Mods), event.X, event.Y)
 }
 
-// generateAnyEventSequence generates any event sequence
+// generateAnyEventSequence generates any event sequence (mode 1003):
+// mode 1002 reports and motion with no button held, code 35
 func (mh *MouseHandler) generateAnyEventSequence(event MouseEvent) []byte {
-	// Any event mode reports all mouse events including moves
-	var cb int
-
-	switch event.Action {
-	case MouseActionRelease:
-		// Release always uses code 3
-		cb = 3
-	case MouseActionMove:
-		if event.Button == MouseButtonNone {
-			// Motion without button uses code 35
-			cb = 35
-		} else {
-			// Motion with button (shouldn't happen, but handle it)
-			cb = 32 + int(event.Button)
-		}
-	case MouseActionDrag:
-		// Drag uses button code + 32
-		cb = 32 + int(event.Button)
-	case MouseActionPress:
-		// Press uses button code
-		cb = int(event.Button)
-	default:
-		return nil
-	}
-
-	return []byte{
-		0x1B, '[', 'M',
-		byte(cb + 32),
-		byte(event.X + 33),
-		byte(event.Y + 33),
+	if event.Action == MouseActionMove {
+		return mouseSequence(35+mouseModifierBits(event.Mods), event.X, event.Y)
 	}
+	return mh.generateBtnEventSequence(event)
 }
 
 // buttonToX10Code converts button to X10 code
@@ -3150,16 +3602,15 @@ func (tr *TerminalRenderer) DisableMouseTracking() error {
 	return nil
 }
 
-// ProcessMouseEvent processes a mouse event and sends appropriate sequences
+// ProcessMouseEvent processes a mouse event and sends appropriate sequences.
+// It goes through the renderer's input processor, so a release or drag is
+// reported for the button an earlier event pressed.
 func (tr *TerminalRenderer) ProcessMouseEvent(event *tcell.EventMouse) error {
-	if tr.terminal.state.MouseMode == MouseModeOff {
-		return nil
-	}
-
-	mouseHandler := NewMouseHandler()
-	mouseHandler.SetMode(tr.terminal.state.MouseMode)
+	tr.mutex.RLock()
+	input := tr.input
+	tr.mutex.RUnlock()
 
-	sequence := mouseHandler.ProcessTcellEvent(event)
+	sequence := input.ProcessMouseEvent(event)
 	if len(sequence) > 0 {
 		// Send mouse sequence to serial port as if it was keyboard input
 		return tr.terminal.ProcessInput(sequence)
@@ -3211,6 +3662,12 @@ type KeyEvent struct {
 type KeyHandler struct {
 	applicationMode bool
 	cursorKeyMode   bool
+	vt52Mode        bool // Cursor keys send VT52 sequences (ESC A)
+	modifyOtherKeys int  // xterm modifyOtherKeys level for keys with modifiers
+	kittyFlags      int  // Kitty keyboard protocol flags
+	metaEightBit    bool // Alt sets the high bit of ASCII characters instead of sending ESC first
+	newlineMode     bool // Enter sends CR LF (LNM)
+	composer        RuneComposer
 }
 
 // NewKeyHandler creates a new keyboard handler
@@ -3231,12 +3688,37 @@ func (kh *KeyHandler) SetCursorKeyMode(enabled bool) {
 	kh.cursorKeyMode = enabled
 }
 
+// SetNewlineMode makes Enter send CR LF instead of CR, for LNM
+func (kh *KeyHandler) SetNewlineMode(enabled bool) {
+	kh.newlineMode = enabled
+}
+
+// SetVT52Mode makes cursor keys send VT52 sequences
+func (kh *KeyHandler) SetVT52Mode(enabled bool) {
+	kh.vt52Mode = enabled
+}
+
 // ProcessTcellEvent processes a tcell keyboard event and returns the appropriate sequence
 func (kh *KeyHandler) ProcessTcellEvent(event *tcell.EventKey) []byte {
 	key := event.Key()
 	char := event.Rune()
 	mods := event.Modifiers()
 
+	// Characters outside the BMP can arrive in two halves
+	if key == tcell.KeyRune {
+		var ok bool
+		if char, ok = kh.composer.Add(char); !ok {
+			return nil
+		}
+	} else {
+		kh.composer.Reset()
+	}
+
+	// Keys with modifiers the device asked to tell apart
+	if sequence, ok := kh.protocolKey(key, char, mods); ok {
+		return sequence
+	}
+
 	// Handle special keys first
 	if sequence := kh.handleSpecialKey(key, mods); sequence != nil {
 		return sequence
@@ -3274,6 +3756,9 @@ func (kh *KeyHandler) ProcessTcellEvent(event *tcell.EventKey) []byte {
 func (kh *KeyHandler) handleSpecialKey(key tcell.Key, mods tcell.ModMask) []byte {
 	switch key {
 	case tcell.KeyEnter:
+		if kh.newlineMode {
+			return []byte{0x0D, 0x0A} // CR LF
+		}
 		return []byte{0x0D} // CR
 	case tcell.KeyTab:
 		if mods&tcell.ModShift != 0 {
@@ -3392,6 +3877,21 @@ func (kh *KeyHandler) addFunctionKeyModifiers(base []byte, mods tcell.ModMask) [
 func (kh *KeyHandler) handleCursorKey(key tcell.Key, mods tcell.ModMask) []byte {
 	var sequence []byte
 
+	if kh.vt52Mode {
+		// VT52 has no modifiers or application cursor keys
+		switch key {
+		case tcell.KeyUp:
+			return []byte{0x1B, 'A'}
+		case tcell.KeyDown:
+			return []byte{0x1B, 'B'}
+		case tcell.KeyRight:
+			return []byte{0x1B, 'C'}
+		case tcell.KeyLeft:
+			return []byte{0x1B, 'D'}
+		}
+		return nil
+	}
+
 	if kh.cursorKeyMode {
 		// Application mode
 		switch key {
@@ -3483,8 +3983,7 @@ func (kh *KeyHandler) handleControlChar(key tcell.Key, char rune, mods tcell.Mod
 
 	// Handle Alt+key combinations
 	if mods&tcell.ModAlt != 0 && char != 0 {
-		// Alt+char sends ESC followed by char
-		return []byte{0x1B, byte(char)}
+		return kh.altChar(char)
 	}
 
 	return nil
@@ -3494,8 +3993,7 @@ func (kh *KeyHandler) handleControlChar(key tcell.Key, char rune, mods tcell.Mod
 func (kh *KeyHandler) handleRegularChar(char rune, mods tcell.ModMask) []byte {
 	// Handle Alt modifier
 	if mods&tcell.ModAlt != 0 {
-		// Alt+char sends ESC followed by char
-		return []byte{0x1B, byte(char)}
+		return kh.altChar(char)
 	}
 
 	// Regular character
@@ -3507,6 +4005,24 @@ func (kh *KeyHandler) handleRegularChar(char rune, mods tcell.ModMask) []byte {
 	return []byte(string(char))
 }
 
+// altChar encodes Alt with a character: ESC followed by the character's
+// UTF-8 encoding, or with 8-bit meta an ASCII character with its high bit
+// set. The 8-bit character is sent as UTF-8 like any other and converted
+// to the device's encoding, so Latin-1 devices get the single byte. Other
+// characters have no 8-bit form and keep the ESC prefix.
+func (kh *KeyHandler) altChar(char rune) []byte {
+	if kh.metaEightBit && char < 0x80 {
+		return []byte(string(char | 0x80))
+	}
+	return append([]byte{0x1B}, string(char)...)
+}
+
+// SetMetaEightBit makes Alt set the high bit of ASCII characters, the meta
+// encoding of legacy hosts, instead of sending ESC before them
+func (kh *KeyHandler) SetMetaEightBit(enabled bool) {
+	kh.metaEightBit = enabled
+}
+
 // addModifiers adds modifier information to escape sequences
 func (kh *KeyHandler) addModifiers(base []byte, mods tcell.ModMask) []byte {
 	// Calculate modifier parameter
@@ -3580,19 +4096,29 @@ func (ip *InputProcessor) processKeyEvent(event *tcell.EventKey) error {
 
 // ProcessKeyEvent processes keyboard events and returns the data to send
 func (ip *InputProcessor) ProcessKeyEvent(event *tcell.EventKey) []byte {
+	// Follow the terminal in and out of VT52 mode, newline mode and
+	// keyboard protocols
+	if ip.terminal != nil {
+		state := ip.terminal.GetState()
+		ip.keyHandler.SetVT52Mode(state.VT52)
+		ip.keyHandler.SetNewlineMode(state.NewlineMode)
+		ip.keyHandler.SetKeyProtocol(state.ModifyOtherKeys, state.KittyKeyboard)
+	}
 	return ip.keyHandler.ProcessTcellEvent(event)
 }
 
 // processMouseEvent processes mouse events
 func (ip *InputProcessor) processMouseEvent(event *tcell.EventMouse) error {
-	sequence := ip.mouseHandler.ProcessTcellEvent(event)
+	sequence := ip.ProcessMouseEvent(event)
 	if len(sequence) > 0 {
 		return ip.terminal.ProcessInput(sequence)
 	}
 	return nil
 }
 
-// ProcessMouseEvent processes mouse events and returns the data to send
+// ProcessMouseEvent processes mouse events and returns the data to send.
+// All mouse input should come through one InputProcessor, whose handler
+// remembers which buttons are held between events.
 func (ip *InputProcessor) ProcessMouseEvent(event *tcell.EventMouse) []byte {
 	// Set the mouse mode from terminal state before processing
 	if ip.terminal != nil {
@@ -3633,10 +4159,16 @@ func (ip *InputProcessor) GetMouseHandler() *MouseHandler {
 	return ip.mouseHandler
 }
 
-// Update TerminalRenderer to use InputProcessor
+// SetInputProcessor makes the renderer handle mouse events with processor,
+// so that they share button state with events the processor handles
+// elsewhere. The renderer has its own until this is called.
 func (tr *TerminalRenderer) SetInputProcessor(processor *InputProcessor) {
-	// This would be used to coordinate input processing with rendering
-	// Implementation would depend on the specific architecture
+	if processor == nil {
+		return
+	}
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	tr.input = processor
 }
 
 // KeySequence represents a key sequence mapping
@@ -3763,6 +4295,11 @@ func (s *Shortcut) Execute() error {
 type ShortcutManager struct {
 	shortcuts map[string]*Shortcut
 	enabled   bool
+
+	// Prefix key chords; chordStarted is when the prefix was pressed
+	chordPrefix  tcell.Key
+	chordTimeout time.Duration
+	chordStarted time.Time
 }
 
 // NewShortcutManager creates a new shortcut manager
@@ -3943,17 +4480,46 @@ func (sm *ShortcutManager) CustomShortcut(name, description string, key tcell.Ke
 	sm.AddShortcut(shortcut)
 }
 
-// GetShortcutHelp returns help text for all shortcuts
-func (sm *ShortcutManager) GetShortcutHelp() string {
-	help := "Available Shortcuts:\n\n"
+// ShortcutHelp describes one shortcut for help screens
+type ShortcutHelp struct {
+	Name        string
+	Keys        string // e.g. "Ctrl+Shift+S"
+	Description string
+	Action      ShortcutAction
+	Bound       bool // Whether a handler is set, i.e. the shortcut does something
+}
 
+// HelpEntries returns the enabled shortcuts sorted by key description
+func (sm *ShortcutManager) HelpEntries() []ShortcutHelp {
+	entries := make([]ShortcutHelp, 0, len(sm.shortcuts))
 	for _, shortcut := range sm.shortcuts {
 		if !shortcut.Enabled {
 			continue
 		}
+		entries = append(entries, ShortcutHelp{
+			Name:        shortcut.Name,
+			Keys:        sm.formatKeyDescription(shortcut),
+			Description: shortcut.Description,
+			Action:      shortcut.Action,
+			Bound:       shortcut.Handler != nil,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Keys != entries[j].Keys {
+			return entries[i].Keys < entries[j].Keys
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// GetShortcutHelp returns help text for all shortcuts
+func (sm *ShortcutManager) GetShortcutHelp() string {
+	help := "Available Shortcuts:\n\n"
 
-		keyDesc := sm.formatKeyDescription(shortcut)
-		help += fmt.Sprintf("  %-20s %s\n", keyDesc, shortcut.Description)
+	for _, entry := range sm.HelpEntries() {
+		help += fmt.Sprintf("  %-20s %s\n", entry.Keys, entry.Description)
 	}
 
 	return help
@@ -1,7 +1,21 @@
 package terminal
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -837,6 +851,8 @@ func TestVTParser_parseParams(t *testing.T) {
 		{"1;;3", []int{1, 0, 3}},
 		{"1;", []int{1, 0}},
 		{";2", []int{0, 2}},
+		{"999999999", []int{65535}},
+		{"99999999999999999999999;7", []int{65535, 7}},
 	}
 
 	for _, tt := range tests {
@@ -856,6 +872,36 @@ func TestVTParser_parseParams(t *testing.T) {
 	}
 }
 
+// TestCursorNextLine checks CNL moves within the screen without scrolling,
+// and that a huge count is a single clamped move
+func TestCursorNextLine(t *testing.T) {
+	te := NewTerminalEmulator(nil, nil, 20, 6)
+	_ = te.Start()
+
+	_ = te.ProcessOutput([]byte("top\x1b[2;5H\x1b[2E"))
+	if state := te.GetState(); state.CursorX != 0 || state.CursorY != 3 {
+		t.Errorf("Cursor after CNL 2 = %d,%d, want 0,3", state.CursorX, state.CursorY)
+	}
+
+	parser := NewVTParser()
+	state := DefaultTerminalState(20, 6)
+	var actions []Action
+	for _, b := range []byte("\x1b[999999999E") {
+		actions = append(actions, parser.ParseByte(b, NewScreen(20, 6), &state, NewUTF8Decoder())...)
+	}
+	if len(actions) != 2 {
+		t.Errorf("CNL with a huge count made %d actions, want 2", len(actions))
+	}
+
+	_ = te.ProcessOutput([]byte("\x1b[999999999E"))
+	if state := te.GetState(); state.CursorX != 0 || state.CursorY != 5 {
+		t.Errorf("Cursor after CNL 999999999 = %d,%d, want 0,5", state.CursorX, state.CursorY)
+	}
+	if te.screen.Buffer[0][0].Char != 't' {
+		t.Error("CNL past the bottom scrolled the screen")
+	}
+}
+
 func TestVTParser_getParam(t *testing.T) {
 	parser := NewVTParser()
 	parser.Params = []int{10, 20, 30}
@@ -1257,6 +1303,216 @@ func TestTerminalEmulator_Scroll(t *testing.T) {
 	}
 }
 
+func TestTerminalEmulator_ScrollbackDroppedAndScrollToLine(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 10, 5)
+	emulator.SetScrollbackSize(100)
+
+	// Push 150 lines into scrollback, 50 of which get trimmed
+	for i := 0; i < 150; i++ {
+		emulator.screen.Buffer[0][0] = Cell{Char: 'x', Attributes: DefaultTextAttributes()}
+		emulator.scroll("up")
+	}
+
+	if got := emulator.GetScrollbackDropped(); got != 50 {
+		t.Errorf("GetScrollbackDropped() = %d, want 50", got)
+	}
+	if got := emulator.GetTopLineIndex(); got != 100 {
+		t.Errorf("GetTopLineIndex() = %d, want 100", got)
+	}
+
+	emulator.ScrollToLine(42)
+	if !emulator.IsScrolling() {
+		t.Error("ScrollToLine should enter scroll mode")
+	}
+	if got := emulator.GetTopLineIndex(); got != 42 {
+		t.Errorf("GetTopLineIndex() after ScrollToLine = %d, want 42", got)
+	}
+
+	// Out of range indices are clamped
+	emulator.ScrollToLine(1000)
+	if got := emulator.GetTopLineIndex(); got != 100 {
+		t.Errorf("GetTopLineIndex() after clamped ScrollToLine = %d, want 100", got)
+	}
+
+	emulator.ClearScrollback()
+	if got := emulator.GetScrollbackDropped(); got != 150 {
+		t.Errorf("GetScrollbackDropped() after clear = %d, want 150", got)
+	}
+}
+
+func TestTerminalEmulator_NewLinesSinceScroll(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 10, 5)
+	emulator.SetScrollbackSize(100)
+	pushLines := func(n int) {
+		for i := 0; i < n; i++ {
+			emulator.screen.Buffer[0][0] = Cell{Char: 'x', Attributes: DefaultTextAttributes()}
+			emulator.scroll("up")
+		}
+	}
+
+	pushLines(20)
+	if got := emulator.NewLinesSinceScroll(); got != 0 {
+		t.Errorf("NewLinesSinceScroll() outside scroll mode = %d, want 0", got)
+	}
+
+	emulator.ScrollUp(5)
+	pushLines(7)
+	if got := emulator.NewLinesSinceScroll(); got != 7 {
+		t.Errorf("NewLinesSinceScroll() = %d, want 7", got)
+	}
+
+	// Lines keep counting once scrollback is full and trimming starts
+	pushLines(100)
+	if got := emulator.NewLinesSinceScroll(); got != 107 {
+		t.Errorf("NewLinesSinceScroll() with trimming = %d, want 107", got)
+	}
+
+	emulator.ExitScrollMode()
+	if got := emulator.NewLinesSinceScroll(); got != 0 {
+		t.Errorf("NewLinesSinceScroll() after exit = %d, want 0", got)
+	}
+}
+
+func TestTerminalEmulator_StableScrollView(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 10, 5)
+	emulator.SetScrollbackSize(100)
+	next := 0
+	pushLines := func(n int) {
+		for i := 0; i < n; i++ {
+			emulator.screen.Buffer[0][0] = Cell{Char: rune('A' + next%26), Attributes: DefaultTextAttributes()}
+			next++
+			emulator.scroll("up")
+		}
+	}
+
+	// Fill scrollback so every new line trims the oldest one
+	pushLines(100)
+	emulator.ScrollToLine(50)
+	top := emulator.GetScrollbackView()[0][0].Char
+
+	pushLines(30)
+	if got := emulator.GetScrollbackView()[0][0].Char; got != top {
+		t.Errorf("View moved while trimming: top line %q, want %q", got, top)
+	}
+	if got := emulator.GetTopLineIndex(); got != 20 {
+		t.Errorf("GetTopLineIndex() = %d, want 20", got)
+	}
+	if current, total := emulator.GetScrollPosition(); current != 80 || total != 100 {
+		t.Errorf("GetScrollPosition() = %d/%d, want 80/100", current, total)
+	}
+
+	// Once the viewed lines are trimmed the view stays at the oldest line
+	pushLines(40)
+	if got := emulator.GetTopLineIndex(); got != 0 {
+		t.Errorf("GetTopLineIndex() after trimming past the view = %d, want 0", got)
+	}
+}
+
+func TestTerminalEmulator_GraphemeClusters(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		cells   []string // Expected String() of each cell from column 0, "" for continuation cells
+		cursorX int
+	}{
+		{"combining accent", "e\u0301x", []string{"e\u0301", "x"}, 2},
+		{"thai vowel and tone marks", "ที่นี่", []string{"ที่", "นี่"}, 2},
+		{"thai sara am is a spacing vowel", "กำ", []string{"กำ", ""}, 2},
+		{"two byte combining after cyrillic", "й\u0306", []string{"й\u0306"}, 1},
+		{"devanagari vowel signs", "नमस्ते", []string{"न", "म", "स्", "ते"}, 4},
+		{"emoji zwj sequence", "👩\u200d💻!", []string{"👩\u200d💻", "", "!"}, 3},
+		{"emoji skin tone", "👍🏽", []string{"👍🏽", ""}, 2},
+		{"flag", "🇨🇳🇯🇵", []string{"🇨🇳", "", "🇯🇵", ""}, 4},
+		{"emoji presentation selector widens", "❤\ufe0f.", []string{"❤\ufe0f", "", "."}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emulator := NewTerminalEmulator(nil, nil, 20, 3)
+			_ = emulator.Start()
+			if err := emulator.ProcessOutput([]byte(tt.input)); err != nil {
+				t.Fatalf("ProcessOutput failed: %v", err)
+			}
+
+			line := emulator.GetScreen().Buffer[0]
+			for i, want := range tt.cells {
+				if got := line[i].String(); got != want {
+					t.Errorf("cell %d = %q, want %q", i, got, want)
+				}
+			}
+			if got := emulator.GetState().CursorX; got != tt.cursorX {
+				t.Errorf("CursorX = %d, want %d", got, tt.cursorX)
+			}
+		})
+	}
+}
+
+func TestTerminalEmulator_AmbiguousWidth(t *testing.T) {
+	defer SetAmbiguousWide(AmbiguousWide())
+
+	tests := []struct {
+		wide    bool
+		cells   []string
+		cursorX int
+	}{
+		{false, []string{"○", "a"}, 2},
+		{true, []string{"○", "", "a"}, 3},
+	}
+
+	for _, tt := range tests {
+		SetAmbiguousWide(tt.wide)
+		emulator := NewTerminalEmulator(nil, nil, 20, 3)
+		_ = emulator.Start()
+		if err := emulator.ProcessOutput([]byte("○a")); err != nil {
+			t.Fatalf("ProcessOutput failed: %v", err)
+		}
+
+		line := emulator.GetScreen().Buffer[0]
+		for i, want := range tt.cells {
+			if got := line[i].String(); got != want {
+				t.Errorf("wide=%v: cell %d = %q, want %q", tt.wide, i, got, want)
+			}
+		}
+		if got := emulator.GetState().CursorX; got != tt.cursorX {
+			t.Errorf("wide=%v: CursorX = %d, want %d", tt.wide, got, tt.cursorX)
+		}
+	}
+}
+
+func TestParseCursorReport(t *testing.T) {
+	tests := []struct {
+		input    string
+		row, col int
+		ok       bool
+	}{
+		{"\x1b[12;3R", 12, 3, true},
+		{"abc\x1b[A\x1b[1;2R", 1, 2, true}, // Typed keys before the reply
+		{"\x1b[5;", 0, 0, false},           // Incomplete
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		row, col, ok := parseCursorReport([]byte(tt.input))
+		if row != tt.row || col != tt.col || ok != tt.ok {
+			t.Errorf("parseCursorReport(%q) = %d, %d, %v, want %d, %d, %v",
+				tt.input, row, col, ok, tt.row, tt.col, tt.ok)
+		}
+	}
+}
+
+func TestCell_Combining(t *testing.T) {
+	cell := Cell{Char: 'e', Cluster: "e\u0301"}
+	if got := cell.Combining(); len(got) != 1 || got[0] != 0x301 {
+		t.Errorf("Combining() = %q, want [U+0301]", got)
+	}
+	if got := (Cell{Char: 'x'}).Combining(); got != nil {
+		t.Errorf("Combining() of plain cell = %q, want nil", got)
+	}
+	if got := (Cell{Char: 0}).String(); got != "" {
+		t.Errorf("String() of continuation cell = %q, want empty", got)
+	}
+}
+
 func TestTerminalEmulator_Tab(t *testing.T) {
 	emulator := NewTerminalEmulator(nil, nil, 80, 24)
 
@@ -1283,6 +1539,69 @@ func TestTerminalEmulator_Tab(t *testing.T) {
 	}
 }
 
+func TestTerminalEmulator_TabStops(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		cursorX int
+	}{
+		{"CHT moves several stops", "\x1b[3I", 24},
+		{"CHT stops at last column", "\x1b[20I", 39},
+		{"CBT moves back", "\x1b[30G\x1b[2Z", 16},
+		{"CBT stops at first column", "\x1b[12G\x1b[5Z", 0},
+		{"HTS adds a stop", "\x1b[4G\x1bH\r\t", 3},
+		{"TBC 0 clears the stop under the cursor", "\x1b[9G\x1b[g\r\t", 16},
+		{"TBC 3 clears all stops", "\x1b[3g\r\t", 39},
+		{"TBC 3 then HTS", "\x1b[3g\x1b[6G\x1bH\r\t\t", 39},
+		{"tab after writing the last column", "\x1b[40Gx\t", 39},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emulator := NewTerminalEmulator(nil, nil, 40, 5)
+			_ = emulator.Start()
+			if err := emulator.ProcessOutput([]byte(tt.input)); err != nil {
+				t.Fatalf("ProcessOutput failed: %v", err)
+			}
+			if got := emulator.GetState().CursorX; got != tt.cursorX {
+				t.Errorf("CursorX = %d, want %d", got, tt.cursorX)
+			}
+		})
+	}
+}
+
+func TestTerminalEmulator_TabStopsAcrossResize(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 40, 5)
+	_ = emulator.Start()
+
+	// Custom stop at column 30, default at 16 cleared
+	_ = emulator.ProcessOutput([]byte("\x1b[31G\x1bH\x1b[17G\x1b[g"))
+
+	// Shrinking hides the custom stop, growing brings it back
+	_ = emulator.Resize(20, 5)
+	_ = emulator.Resize(100, 5)
+
+	tabsFrom := func(x int) []int {
+		emulator.state.CursorX = x
+		var stops []int
+		for i := 0; i < 8; i++ {
+			emulator.tab()
+			stops = append(stops, emulator.state.CursorX)
+		}
+		return stops
+	}
+
+	// New columns beyond the original width get default stops; the cleared
+	// stop stays cleared
+	want := []int{8, 24, 30, 32, 40, 48, 56, 64}
+	got := tabsFrom(0)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Tab stops after resize = %v, want %v", got, want)
+		}
+	}
+}
+
 func TestTerminalEmulator_NewlineAndCarriageReturn(t *testing.T) {
 	emulator := NewTerminalEmulator(nil, nil, 80, 24)
 
@@ -1864,6 +2183,8 @@ func TestKeyHandler_handleRegularChar(t *testing.T) {
 		{'!', 0, []byte{'!'}},
 		{'a', tcell.ModAlt, []byte{0x1B, 'a'}}, // Alt+a
 		{'€', 0, []byte{0xE2, 0x82, 0xAC}},     // Euro symbol (UTF-8)
+		{'é', tcell.ModAlt, []byte{0x1B, 0xC3, 0xA9}},
+		{'中', tcell.ModAlt, []byte{0x1B, 0xE4, 0xB8, 0xAD}},
 	}
 
 	for _, tt := range tests {
@@ -1882,6 +2203,73 @@ func TestKeyHandler_handleRegularChar(t *testing.T) {
 	}
 }
 
+func TestKeyHandler_ComposedInput(t *testing.T) {
+	handler := NewKeyHandler()
+	key := func(r rune, mods tcell.ModMask) []byte {
+		return handler.ProcessTcellEvent(tcell.NewEventKey(tcell.KeyRune, r, mods))
+	}
+
+	// IME commits and dead-key accents arrive as whole characters
+	for _, s := range []string{"中", "あ", "한", "é", "ñ"} {
+		r := []rune(s)[0]
+		if got := key(r, tcell.ModNone); string(got) != s {
+			t.Errorf("%s sent % X, want its UTF-8", s, got)
+		}
+		if got := key(r, tcell.ModShift); string(got) != s {
+			t.Errorf("Shift+%s sent % X, want its UTF-8", s, got)
+		}
+	}
+
+	// The Windows console sends characters outside the BMP in two halves
+	for _, s := range []string{"😀", "𠮷"} {
+		r := []rune(s)[0]
+		high, low := utf16.EncodeRune(r)
+		if got := key(high, tcell.ModNone); got != nil {
+			t.Errorf("First half of %s sent % X", s, got)
+		}
+		if got := key(low, tcell.ModNone); string(got) != s {
+			t.Errorf("%s in halves sent % X, want its UTF-8", s, got)
+		}
+	}
+
+	// A half without its partner is dropped
+	high, low := utf16.EncodeRune('😀')
+	key(high, tcell.ModNone)
+	if got := key('a', tcell.ModNone); string(got) != "a" {
+		t.Errorf("Key after a lone half sent %q", got)
+	}
+	if got := key(low, tcell.ModNone); got != nil {
+		t.Errorf("Lone second half sent % X", got)
+	}
+
+	// Keyboard protocols leave text alone
+	handler.SetKeyProtocol(2, kittyDisambiguate)
+	if got := key('中', tcell.ModShift); string(got) != "中" {
+		t.Errorf("中 with the kitty protocol sent %q", got)
+	}
+}
+
+func TestKeyHandler_AltCharacters(t *testing.T) {
+	handler := NewKeyHandler()
+	key := func(r rune) []byte {
+		return handler.ProcessTcellEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt))
+	}
+
+	// ESC and the whole UTF-8 encoding, not its first byte
+	if got := key('é'); !bytes.Equal(got, []byte{0x1B, 0xC3, 0xA9}) {
+		t.Errorf("Alt+é sent % X", got)
+	}
+
+	// 8-bit meta sets the high bit: Alt+a is U+00E1, 0xE1 in Latin-1
+	handler.SetMetaEightBit(true)
+	if got := key('a'); string(got) != "á" {
+		t.Errorf("8-bit meta Alt+a sent % X", got)
+	}
+	if got := key('é'); !bytes.Equal(got, []byte{0x1B, 0xC3, 0xA9}) {
+		t.Errorf("8-bit meta Alt+é sent % X, want the ESC prefix", got)
+	}
+}
+
 func TestKeyHandler_addModifiers(t *testing.T) {
 	handler := NewKeyHandler()
 
@@ -2249,6 +2637,43 @@ func TestShortcutManager_SetEnabled(t *testing.T) {
 	}
 }
 
+func TestShortcutManager_Chords(t *testing.T) {
+	sm := NewShortcutManager()
+	if got := sm.ProcessChord(tcell.KeyCtrlA, tcell.ModCtrl); got != ChordNone {
+		t.Errorf("Without a prefix got %v, want ChordNone", got)
+	}
+
+	sm.SetChordPrefix(tcell.KeyCtrlA, 50*time.Millisecond)
+	steps := []struct {
+		key  tcell.Key
+		mods tcell.ModMask
+		want ChordResult
+	}{
+		{tcell.KeyRune, tcell.ModNone, ChordNone},
+		{tcell.KeyCtrlA, tcell.ModCtrl, ChordPending},
+		{tcell.KeyRune, tcell.ModNone, ChordCommand},
+		{tcell.KeyRune, tcell.ModNone, ChordNone},
+		{tcell.KeyCtrlA, tcell.ModCtrl, ChordPending},
+		{tcell.KeyCtrlA, tcell.ModCtrl, ChordRepeat},
+		{tcell.KeyCtrlA, tcell.ModCtrl | tcell.ModAlt, ChordNone},
+	}
+	for i, step := range steps {
+		if got := sm.ProcessChord(step.key, step.mods); got != step.want {
+			t.Errorf("Step %d: got %v, want %v", i, got, step.want)
+		}
+	}
+
+	// The command key has to come before the timeout
+	sm.ProcessChord(tcell.KeyCtrlA, tcell.ModCtrl)
+	if !sm.ChordWaiting() {
+		t.Error("Should be waiting for the command key")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if got := sm.ProcessChord(tcell.KeyRune, tcell.ModNone); got != ChordNone {
+		t.Errorf("After the timeout got %v, want ChordNone", got)
+	}
+}
+
 func TestShortcutManager_ProcessKeyEvent(t *testing.T) {
 	sm := NewShortcutManager()
 
@@ -2427,6 +2852,35 @@ func TestShortcutManager_GetShortcutHelp(t *testing.T) {
 	}
 }
 
+func TestShortcutManager_HelpEntries(t *testing.T) {
+	sm := NewShortcutManager()
+	sm.CustomShortcut("macro", "Send login", tcell.KeyF5, 0, 0, func() error { return nil })
+	sm.DisableShortcut("settings")
+
+	entries := sm.HelpEntries()
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Keys > entries[i].Keys {
+			t.Errorf("HelpEntries() not sorted: %q before %q", entries[i-1].Keys, entries[i].Keys)
+		}
+	}
+
+	var macro *ShortcutHelp
+	for i := range entries {
+		if entries[i].Name == "settings" {
+			t.Error("HelpEntries() should skip disabled shortcuts")
+		}
+		if entries[i].Name == "macro" {
+			macro = &entries[i]
+		}
+	}
+	if macro == nil {
+		t.Fatal("HelpEntries() should include custom shortcuts")
+	}
+	if macro.Keys != "F5" || !macro.Bound || macro.Action != ActionCustom {
+		t.Errorf("Unexpected macro entry: %+v", *macro)
+	}
+}
+
 func TestShortcut_ToConfig(t *testing.T) {
 	shortcut := &Shortcut{
 		Name:        "test",
@@ -2550,3 +3004,1241 @@ func indexOfSubstringHelper(s, substr string) int {
 	}
 	return -1
 }
+
+func TestWriteANSISnapshot(t *testing.T) {
+	red := DefaultTextAttributes()
+	red.Foreground = ColorRed
+	red.Bold = true
+
+	lines := [][]Cell{
+		{
+			{Char: 'o', Attributes: DefaultTextAttributes()},
+			{Char: 'k', Attributes: red},
+			{Char: ' ', Attributes: DefaultTextAttributes()},
+			{Char: ' ', Attributes: DefaultTextAttributes()},
+		},
+		{
+			{Char: ' ', Attributes: DefaultTextAttributes()},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteANSISnapshot(&buf, lines); err != nil {
+		t.Fatalf("WriteANSISnapshot failed: %v", err)
+	}
+
+	want := "o\x1b[0;1;31mk\x1b[0m\n\n"
+	if buf.String() != want {
+		t.Errorf("WriteANSISnapshot() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWritePNGSnapshot(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 10, 2)
+	_ = emulator.ProcessOutput([]byte("\x1b[32mHi\x1b[0m"))
+
+	var buf bytes.Buffer
+	if err := WritePNGSnapshot(&buf, emulator.GetScreen().Buffer); err != nil {
+		t.Fatalf("WritePNGSnapshot failed: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Output is not a valid PNG: %v", err)
+	}
+
+	// 7x13 font cells
+	bounds := img.Bounds()
+	if bounds.Dx() != 70 || bounds.Dy() != 26 {
+		t.Errorf("Image size = %dx%d, want 70x26", bounds.Dx(), bounds.Dy())
+	}
+
+	if err := WritePNGSnapshot(&buf, nil); err == nil {
+		t.Error("Expected error for empty snapshot")
+	}
+}
+
+// decodeAll feeds data to a decoder one byte at a time and flushes at the end
+func decodeAll(policy InvalidUTF8Policy, data []byte) []rune {
+	d := NewUTF8Decoder()
+	d.SetPolicy(policy)
+	var out []rune
+	for _, b := range data {
+		out = d.Decode(b, out)
+	}
+	return d.Flush(out)
+}
+
+// referenceDecode decodes data with unicode/utf8, treating each invalid byte
+// according to policy
+func referenceDecode(policy InvalidUTF8Policy, data []byte) []rune {
+	var out []rune
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			switch policy {
+			case InvalidUTF8Drop:
+			case InvalidUTF8Latin1:
+				out = append(out, rune(data[0]))
+			default:
+				out = append(out, utf8.RuneError)
+			}
+		} else {
+			out = append(out, r)
+		}
+		data = data[size:]
+	}
+	return out
+}
+
+func TestUTF8Decoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"ascii", []byte("abc"), "abc"},
+		{"two byte", []byte("é"), "é"},
+		{"three byte", []byte("中"), "中"},
+		{"four byte", []byte("😀"), "😀"},
+		{"overlong two byte", []byte{0xC0, 0xAF}, "\uFFFD\uFFFD"},
+		{"overlong three byte", []byte{0xE0, 0x80, 0xAF}, "\uFFFD\uFFFD\uFFFD"},
+		{"surrogate", []byte{0xED, 0xA0, 0x80}, "\uFFFD\uFFFD\uFFFD"},
+		{"above U+10FFFF", []byte{0xF4, 0x90, 0x80, 0x80}, "\uFFFD\uFFFD\uFFFD\uFFFD"},
+		{"truncated then ascii", []byte{0xE4, 0xB8, 'A'}, "\uFFFD\uFFFDA"},
+		{"truncated then lead byte", []byte{0xE4, 0xE4, 0xB8, 0xAD}, "\uFFFD中"},
+		{"orphan continuation", []byte{0x80, 'x'}, "\uFFFDx"},
+		{"incomplete at end", []byte{'a', 0xF0, 0x9F}, "a\uFFFD\uFFFD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(decodeAll(InvalidUTF8Replace, tt.input)); got != tt.want {
+				t.Errorf("decoded %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Other policies only change how invalid bytes come out
+	input := []byte{'a', 0xE9, 'b', 0xED, 0xA0, 0x80}
+	if got := string(decodeAll(InvalidUTF8Drop, input)); got != "ab" {
+		t.Errorf("drop policy decoded %q, want %q", got, "ab")
+	}
+	if got := string(decodeAll(InvalidUTF8Latin1, input)); got != "aébí\u00a0\u0080" {
+		t.Errorf("latin1 policy decoded %q", got)
+	}
+}
+
+func TestTerminalEmulator_InvalidUTF8(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 3)
+	_ = emulator.Start()
+
+	// A sequence split across reads still decodes
+	_ = emulator.ProcessOutput([]byte{0xE4, 0xB8})
+	_ = emulator.ProcessOutput([]byte{0xAD})
+	// A newline cuts off an incomplete sequence, which shows as a replacement
+	_ = emulator.ProcessOutput([]byte{0xE4, '\r', '\n'})
+
+	screen := emulator.GetScreen()
+	if got := screen.Buffer[0][0].Char; got != '中' {
+		t.Errorf("split sequence decoded as %q, want '中'", got)
+	}
+	if got := screen.Buffer[0][2].Char; got != utf8.RuneError {
+		t.Errorf("cut off sequence decoded as %q, want U+FFFD", got)
+	}
+}
+
+func FuzzUTF8Decoder(f *testing.F) {
+	for _, seed := range []string{"hello", "中文 émoji 😀", "\xC0\xAF", "\xED\xA0\x80", "\xF4\x90\x80\x80", "\xE4\xB8A", "\xF0\x9F\x98"} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, policy := range []InvalidUTF8Policy{InvalidUTF8Replace, InvalidUTF8Drop, InvalidUTF8Latin1} {
+			got, want := decodeAll(policy, data), referenceDecode(policy, data)
+			if string(got) != string(want) {
+				t.Fatalf("%s: decoding % X gave %q, unicode/utf8 gives %q", policy, data, string(got), string(want))
+			}
+		}
+	})
+}
+
+func TestTerminalEmulator_Bell(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 3)
+	_ = emulator.Start()
+
+	bells := 0
+	emulator.SetBellCallback(func() { bells++ })
+
+	// BEL terminating an OSC is not a bell
+	_ = emulator.ProcessOutput([]byte("done\a\x1b]0;title\a"))
+	if bells != 1 {
+		t.Errorf("Bell callback called %d times, want 1", bells)
+	}
+}
+
+func TestTerminalEmulator_BracketedPaste(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 3)
+	_ = emulator.Start()
+
+	_ = emulator.ProcessOutput([]byte("\x1b[?2004h"))
+	if !emulator.GetState().BracketedPaste {
+		t.Error("ESC[?2004h should enable bracketed paste")
+	}
+	_ = emulator.ProcessOutput([]byte("\x1b[?2004l"))
+	if emulator.GetState().BracketedPaste {
+		t.Error("ESC[?2004l should disable bracketed paste")
+	}
+
+	_ = emulator.ProcessOutput([]byte("\x1b[?2004h\x1bc"))
+	if emulator.GetState().BracketedPaste {
+		t.Error("Reset should disable bracketed paste")
+	}
+}
+
+func TestTerminalEmulator_VT52(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 5)
+	_ = emulator.Start()
+	var responses []string
+	emulator.SetResponseCallback(func(response []byte) {
+		responses = append(responses, string(response))
+	})
+
+	// DECREQTPARM is answered in ANSI mode, unsolicited reports allowed or not
+	_ = emulator.ProcessOutput([]byte("\x1b[x\x1b[1x\x1b[2x"))
+	want := []string{"\x1b[2;1;1;128;128;1;0x", "\x1b[3;1;1;128;128;1;0x"}
+	if !slices.Equal(responses, want) {
+		t.Errorf("DECREQTPARM responses = %q, want %q", responses, want)
+	}
+
+	responses = nil
+	_ = emulator.ProcessOutput([]byte("\x1b[?2l"))
+	if !emulator.GetState().VT52 {
+		t.Fatal("ESC[?2l should enter VT52 mode")
+	}
+
+	// Direct cursor address, cursor movement, identify and graphics
+	_ = emulator.ProcessOutput([]byte("\x1bY\x22\x25ab\x1bD\x1bZ\x1bFa\x1bGa"))
+	state := emulator.GetState()
+	if state.CursorY != 2 || state.CursorX != 8 {
+		t.Errorf("Cursor at %d,%d, want 2,8", state.CursorY, state.CursorX)
+	}
+	line := emulator.GetScreen().Buffer[2]
+	if got := string([]rune{line[5].Char, line[6].Char, line[7].Char}); got != "a█a" {
+		t.Errorf("Row 2 = %q, want %q", got, "a█a")
+	}
+	if !slices.Equal(responses, []string{"\x1b/Z"}) {
+		t.Errorf("VT52 identify responses = %q", responses)
+	}
+
+	// Cursor keys follow the mode
+	input := NewInputProcessor(emulator)
+	if got := input.ProcessKeyEvent(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)); string(got) != "\x1bA" {
+		t.Errorf("Up in VT52 mode sends %q, want ESC A", got)
+	}
+
+	_ = emulator.ProcessOutput([]byte("\x1bH\x1bJ\x1b<\x1b[3;4H"))
+	state = emulator.GetState()
+	if state.VT52 || state.CursorY != 2 || state.CursorX != 3 {
+		t.Errorf("After ESC < VT52=%v, cursor at %d,%d, want ANSI mode at 2,3", state.VT52, state.CursorY, state.CursorX)
+	}
+	if got := input.ProcessKeyEvent(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)); string(got) != "\x1b[A" {
+		t.Errorf("Up in ANSI mode sends %q, want ESC [ A", got)
+	}
+}
+
+func TestTerminalEmulator_MediaCopy(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 3)
+	_ = emulator.Start()
+	var printed bytes.Buffer
+	emulator.SetPrinterCallback(func(data []byte) { printed.Write(data) })
+
+	// Printer controller mode diverts everything, escapes included, until
+	// ESC[4i, even split across reads
+	_ = emulator.ProcessOutput([]byte("ab\x1b[5iLINE 1\r\n\x1b[1mX\x1b"))
+	if !emulator.GetState().PrinterController {
+		t.Fatal("ESC[5i should turn printer controller mode on")
+	}
+	_ = emulator.ProcessOutput([]byte("[4"))
+	_ = emulator.ProcessOutput([]byte("icd"))
+	if got, want := printed.String(), "LINE 1\r\n\x1b[1mX"; got != want {
+		t.Errorf("Printed %q, want %q", got, want)
+	}
+	if emulator.GetState().PrinterController {
+		t.Error("ESC[4i should turn printer controller mode off")
+	}
+	if got := cellsText(emulator.GetScreen().Buffer[0]); got != "abcd" {
+		t.Errorf("Screen shows %q, want only the data outside printer mode", got)
+	}
+
+	// Print screen, and auto print of each line the cursor leaves
+	printed.Reset()
+	_ = emulator.ProcessOutput([]byte("\x1b[i"))
+	if got := printed.String(); got != "abcd\n\n\n" {
+		t.Errorf("Print screen gave %q", got)
+	}
+	printed.Reset()
+	_ = emulator.ProcessOutput([]byte("\x1b[?5i\r\nnext\r\n\x1b[?4ilast\r\n"))
+	if got := printed.String(); got != "abcd\nnext\n" {
+		t.Errorf("Auto print gave %q", got)
+	}
+}
+
+func TestTerminalEmulator_LineSize(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 10, 4)
+	_ = emulator.Start()
+
+	// A double-width line holds half as many characters before wrapping
+	_ = emulator.ProcessOutput([]byte("\x1b#6ABCDEFG"))
+	screen := emulator.GetScreen()
+	if got := cellsText(screen.Buffer[0]); got != "ABCDE" {
+		t.Errorf("Double-width line = %q, want %q", got, "ABCDE")
+	}
+	if got := cellsText(screen.Buffer[1]); got != "FG" {
+		t.Errorf("Wrapped line = %q, want %q", got, "FG")
+	}
+	if size := screen.Buffer[0][3].LineSize; size != LineDoubleWidth {
+		t.Errorf("Line 0 size = %v, want double width", size)
+	}
+	if size := screen.Buffer[1][0].LineSize; size != LineSingle {
+		t.Errorf("Line 1 size = %v, want single", size)
+	}
+
+	// Erasing keeps the size, and it moves with the line when scrolling
+	_ = emulator.ProcessOutput([]byte("\r\n\x1b#3TOP\x1b[K\r\n\x1b#4TOP\r\n"))
+	if size := screen.Buffer[1][9].LineSize; size != LineDoubleTop {
+		t.Errorf("Scrolled line size after erase = %v, want double-height top", size)
+	}
+	if size := screen.Buffer[2][0].LineSize; size != LineDoubleBottom {
+		t.Errorf("Scrolled line size = %v, want double-height bottom", size)
+	}
+
+	// Back to single width, with a cursor past the half pulled in first
+	_ = emulator.ProcessOutput([]byte("\x1b[1;9H\x1b#6"))
+	if x := emulator.GetState().CursorX; x != 4 {
+		t.Errorf("Cursor at column %d on a double-width line, want 4", x)
+	}
+	_ = emulator.ProcessOutput([]byte("\x1b#5"))
+	if size := screen.Buffer[0][0].LineSize; size != LineSingle {
+		t.Errorf("Line size after ESC#5 = %v, want single", size)
+	}
+}
+
+func TestTerminalEmulator_UnderlineStyles(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 4)
+	_ = emulator.Start()
+
+	tests := []struct {
+		seq   string
+		on    bool
+		style UnderlineStyle
+		color Color
+	}{
+		{"\x1b[4m", true, UnderlineSingle, ColorDefault},
+		{"\x1b[4:3m", true, UnderlineCurly, ColorDefault},
+		{"\x1b[4:4;58;5;196m", true, UnderlineDotted, Color(196)},
+		{"\x1b[4:5;58:2::255:128:0m", true, UnderlineDashed, RGBColor(255, 128, 0)},
+		{"\x1b[21;58:2:1:2:3m", true, UnderlineDouble, RGBColor(1, 2, 3)},
+		{"\x1b[4:3;58;2;10;20;30m", true, UnderlineCurly, RGBColor(10, 20, 30)},
+		{"\x1b[59m", true, UnderlineCurly, ColorDefault},
+		{"\x1b[4:0m", false, UnderlineSingle, ColorDefault},
+		{"\x1b[4:9m", false, UnderlineSingle, ColorDefault},
+	}
+	for _, tt := range tests {
+		_ = emulator.ProcessOutput([]byte(tt.seq))
+		attrs := emulator.GetState().Attributes
+		if attrs.Underline != tt.on || attrs.UnderlineStyle != tt.style || attrs.UnderlineColor != tt.color {
+			t.Errorf("After %q: underline %v style %v color %v, want %v %v %v", tt.seq,
+				attrs.Underline, attrs.UnderlineStyle, attrs.UnderlineColor, tt.on, tt.style, tt.color)
+		}
+	}
+
+	// Subparameters are no longer read as parameters: 4:3 isn't background
+	// yellow, and the values of 38;5;n aren't blink and others
+	_ = emulator.ProcessOutput([]byte("\x1b[0;4:3;38;5;100mX"))
+	cell := emulator.GetScreen().Buffer[0][0]
+	if cell.Attributes.Background != ColorDefault || cell.Attributes.Blink || cell.Attributes.Foreground != Color(100) {
+		t.Errorf("Cell attributes = %+v", cell.Attributes)
+	}
+	if cell.Attributes.UnderlineStyle != UnderlineCurly {
+		t.Errorf("Cell underline style = %v, want curly", cell.Attributes.UnderlineStyle)
+	}
+
+	_ = emulator.ProcessOutput([]byte("\x1b[0m"))
+	if attrs := emulator.GetState().Attributes; attrs != DefaultTextAttributes() {
+		t.Errorf("Attributes after reset = %+v", attrs)
+	}
+	curly := DefaultTextAttributes()
+	curly.Underline = true
+	curly.UnderlineStyle = UnderlineCurly
+	curly.UnderlineColor = RGBColor(255, 0, 16)
+	if seq := sgrSequence(curly); seq != "\x1b[0;4:3;58:2::255:0:16m" {
+		t.Errorf("Snapshot SGR for a curly underline = %q", seq)
+	}
+	if s := RGBColor(255, 0, 16).String(); s != "#ff0010" {
+		t.Errorf("RGB color string = %q", s)
+	}
+}
+
+func TestTerminalEmulator_Hyperlink(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 20, 3)
+	_ = emulator.Start()
+
+	_ = emulator.ProcessOutput([]byte("a\x1b]8;id=x:foo=1;https://example.com/\x07link\x1b]8;;\x07b"))
+	line := emulator.GetScreen().Buffer[0]
+	if got := cellsText(line); got != "alinkb" {
+		t.Fatalf("Line = %q, want %q", got, "alinkb")
+	}
+	if line[0].Hyperlink != nil || line[5].Hyperlink != nil {
+		t.Error("Text outside the link has a hyperlink")
+	}
+	for x := 1; x <= 4; x++ {
+		link := line[x].Hyperlink
+		if link == nil || link.URI != "https://example.com/" || link.ID != "x" {
+			t.Fatalf("Cell %d hyperlink = %+v", x, link)
+		}
+	}
+	if emulator.GetState().Hyperlink != nil {
+		t.Error("Link still active after OSC 8 with an empty URI")
+	}
+
+	// URIs without a scheme are ignored, and the link moves when scrolling
+	_ = emulator.ProcessOutput([]byte("\r\n\x1b]8;;-rf\x07x\x1b]8;;\x07\r\n\r\n"))
+	if link := emulator.GetScreen().Buffer[1][0].Hyperlink; link != nil {
+		t.Errorf("Link without a scheme = %+v", link)
+	}
+	if link := emulator.GetScreen().Buffer[0][0].Hyperlink; link != nil {
+		t.Errorf("Scrolled line should be the plain one, got %+v", link)
+	}
+
+	// A reset ends the link
+	_ = emulator.ProcessOutput([]byte("\x1b]8;;file:///tmp/x\x07\x1bc"))
+	if emulator.GetState().Hyperlink != nil {
+		t.Error("Link still active after a reset")
+	}
+}
+
+func TestMouseTrackingModes(t *testing.T) {
+	// DECSET numbers map to the xterm modes
+	emulator := NewTerminalEmulator(nil, nil, 80, 24)
+	_ = emulator.Start()
+	modes := []struct {
+		seq  string
+		mode MouseMode
+	}{
+		{"\x1b[?9h", MouseModeX10},
+		{"\x1b[?1000h", MouseModeVT200},
+		{"\x1b[?1001h", MouseModeVT200Highlight},
+		{"\x1b[?1002h", MouseModeBtnEvent},
+		{"\x1b[?1003h", MouseModeAnyEvent},
+		{"\x1b[?1003l", MouseModeOff},
+	}
+	for _, tt := range modes {
+		_ = emulator.ProcessOutput([]byte(tt.seq))
+		if got := emulator.GetState().MouseMode; got != tt.mode {
+			t.Errorf("After %q mouse mode = %v, want %v", tt.seq, got, tt.mode)
+		}
+	}
+
+	// A press at (1,2), a drag to (3,2), a release there, a wheel step
+	// and a move to (4,4), with the reports xterm sends in each mode
+	type step struct {
+		x, y    int
+		buttons tcell.ButtonMask
+		mods    tcell.ModMask
+	}
+	steps := []step{
+		{1, 2, tcell.Button1, tcell.ModCtrl},
+		{3, 2, tcell.Button1, tcell.ModNone},
+		{3, 2, tcell.ButtonNone, tcell.ModNone},
+		{3, 2, tcell.WheelUp, tcell.ModShift},
+		{4, 4, tcell.ButtonNone, tcell.ModNone},
+	}
+	report := func(cb, x, y int) string {
+		return string([]byte{0x1b, '[', 'M', byte(32 + cb), byte(33 + x), byte(33 + y)})
+	}
+	tests := []struct {
+		mode MouseMode
+		want []string
+	}{
+		{MouseModeX10, []string{report(0, 1, 2), "", "", "", ""}},
+		{MouseModeVT200, []string{report(16, 1, 2), "", report(3, 3, 2), report(68, 3, 2), ""}},
+		{MouseModeVT200Highlight, []string{report(16, 1, 2), "", "\x1b[T" + "\"#$#$#", report(68, 3, 2), ""}},
+		{MouseModeBtnEvent, []string{report(16, 1, 2), report(32, 3, 2), report(3, 3, 2), report(68, 3, 2), ""}},
+		{MouseModeAnyEvent, []string{report(16, 1, 2), report(32, 3, 2), report(3, 3, 2), report(68, 3, 2), report(35, 4, 4)}},
+	}
+	for _, tt := range tests {
+		handler := NewMouseHandler()
+		handler.SetMode(tt.mode)
+		for i, s := range steps {
+			got := string(handler.ProcessTcellEvent(tcell.NewEventMouse(s.x, s.y, s.buttons, s.mods)))
+			if got != tt.want[i] {
+				t.Errorf("%v step %d: sent %q, want %q", tt.mode, i, got, tt.want[i])
+			}
+		}
+	}
+
+	// Highlight tracking ending where it started reports just the position
+	handler := NewMouseHandler()
+	handler.SetMode(MouseModeVT200Highlight)
+	handler.ProcessTcellEvent(tcell.NewEventMouse(5, 5, tcell.Button1, tcell.ModNone))
+	if got := string(handler.ProcessTcellEvent(tcell.NewEventMouse(5, 5, tcell.ButtonNone, tcell.ModNone))); got != "\x1b[t&&" {
+		t.Errorf("Highlight release in place = %q", got)
+	}
+}
+
+func TestTerminalRenderer_SharedMouseState(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 80, 24)
+	_ = emulator.Start()
+	_ = emulator.ProcessOutput([]byte("\x1b[?1002h"))
+
+	// The renderer and the app's input processor see one button state
+	processor := NewInputProcessor(emulator)
+	renderer := &TerminalRenderer{terminal: emulator, input: NewInputProcessor(emulator)}
+	renderer.SetInputProcessor(processor)
+
+	if err := renderer.ProcessMouseEvent(tcell.NewEventMouse(2, 3, tcell.Button1, tcell.ModNone)); err != nil {
+		t.Fatalf("ProcessMouseEvent failed: %v", err)
+	}
+	drag := processor.ProcessMouseEvent(tcell.NewEventMouse(4, 3, tcell.Button1, tcell.ModNone))
+	if want := []byte{0x1b, '[', 'M', 32 + 32, 33 + 4, 33 + 3}; !slices.Equal(drag, want) {
+		t.Errorf("Drag after a press seen by the renderer = %q, want %q", drag, want)
+	}
+	_ = renderer.ProcessMouseEvent(tcell.NewEventMouse(4, 3, tcell.ButtonNone, tcell.ModNone))
+	if processor.GetMouseHandler().dragButton != MouseButtonNone {
+		t.Error("Release seen by the renderer didn't reach the shared handler")
+	}
+
+	// Tracking turned off forgets held buttons
+	handler := NewMouseHandler()
+	handler.SetMode(MouseModeVT200)
+	handler.ProcessTcellEvent(tcell.NewEventMouse(1, 1, tcell.Button1, tcell.ModNone))
+	handler.SetMode(MouseModeOff)
+	handler.SetMode(MouseModeVT200)
+	if got := handler.ProcessTcellEvent(tcell.NewEventMouse(1, 1, tcell.ButtonNone, tcell.ModNone)); got != nil {
+		t.Errorf("Release reported for a press from before tracking was off: %q", got)
+	}
+}
+
+func TestKeyboardProtocols(t *testing.T) {
+	emulator := NewTerminalEmulator(nil, nil, 80, 24)
+	_ = emulator.Start()
+	var responses []string
+	emulator.SetResponseCallback(func(response []byte) { responses = append(responses, string(response)) })
+	input := NewInputProcessor(emulator)
+	key := func(k tcell.Key, ch rune, mods tcell.ModMask) string {
+		return string(input.ProcessKeyEvent(tcell.NewEventKey(k, ch, mods)))
+	}
+
+	// Without a protocol modifiers collapse as before
+	if got := key(tcell.KeyEnter, 0, tcell.ModCtrl); got != "\r" {
+		t.Errorf("Legacy Ctrl+Enter = %q", got)
+	}
+
+	// modifyOtherKeys level 2 encodes every modified key but Shift+text
+	_ = emulator.ProcessOutput([]byte("\x1b[>4;2m"))
+	if emulator.GetState().ModifyOtherKeys != 2 {
+		t.Fatalf("modifyOtherKeys = %d, want 2", emulator.GetState().ModifyOtherKeys)
+	}
+	if attrs := emulator.GetState().Attributes; attrs.Underline {
+		t.Error("XTMODKEYS was taken for SGR")
+	}
+	tests := []struct {
+		k    tcell.Key
+		ch   rune
+		mods tcell.ModMask
+		want string
+	}{
+		{tcell.KeyEnter, 0, tcell.ModCtrl, "\x1b[27;5;13~"},
+		{tcell.KeyCtrlA, 1, tcell.ModCtrl, "\x1b[27;5;97~"},
+		{tcell.KeyRune, 'a', tcell.ModCtrl | tcell.ModShift, "\x1b[27;6;65~"},
+		{tcell.KeyRune, 'A', tcell.ModShift, "A"},
+		{tcell.KeyTab, 0, tcell.ModShift, "\x1b[Z"},
+		{tcell.KeyUp, 0, tcell.ModCtrl, "\x1b[1;5A"},
+	}
+	for _, tt := range tests {
+		if got := key(tt.k, tt.ch, tt.mods); got != tt.want {
+			t.Errorf("modifyOtherKeys 2: key %v %q mods %v = %q, want %q", tt.k, tt.ch, tt.mods, got, tt.want)
+		}
+	}
+
+	// Level 1 leaves keys with a legacy encoding alone
+	_ = emulator.ProcessOutput([]byte("\x1b[>4;1m"))
+	if got := key(tcell.KeyCtrlA, 1, tcell.ModCtrl); got != "\x01" {
+		t.Errorf("modifyOtherKeys 1: Ctrl+A = %q", got)
+	}
+	if got := key(tcell.KeyRune, 'a', tcell.ModCtrl|tcell.ModShift); got != "\x1b[27;6;65~" {
+		t.Errorf("modifyOtherKeys 1: Ctrl+Shift+A = %q", got)
+	}
+	_ = emulator.ProcessOutput([]byte("\x1b[>4n"))
+	if emulator.GetState().ModifyOtherKeys != 0 {
+		t.Error("CSI > 4 n didn't turn modifyOtherKeys off")
+	}
+
+	// Kitty: push, query, set and pop
+	_ = emulator.ProcessOutput([]byte("\x1b[>31u\x1b[?u"))
+	if got := emulator.GetState().KittyKeyboard; got != 1 {
+		t.Errorf("Kitty flags after push = %d, want the supported 1", got)
+	}
+	if len(responses) != 1 || responses[0] != "\x1b[?1u" {
+		t.Errorf("Kitty query answered %q", responses)
+	}
+	tests = []struct {
+		k    tcell.Key
+		ch   rune
+		mods tcell.ModMask
+		want string
+	}{
+		{tcell.KeyEscape, 0, tcell.ModNone, "\x1b[27u"},
+		{tcell.KeyEnter, 0, tcell.ModNone, "\r"},
+		{tcell.KeyEnter, 0, tcell.ModCtrl, "\x1b[13;5u"},
+		{tcell.KeyTab, 0, tcell.ModShift, "\x1b[9;2u"},
+		{tcell.KeyRune, 'A', tcell.ModCtrl, "\x1b[97;6u"},
+		{tcell.KeyRune, 'x', tcell.ModAlt, "\x1b[120;3u"},
+		{tcell.KeyRune, 'x', tcell.ModNone, "x"},
+	}
+	for _, tt := range tests {
+		if got := key(tt.k, tt.ch, tt.mods); got != tt.want {
+			t.Errorf("Kitty: key %v %q mods %v = %q, want %q", tt.k, tt.ch, tt.mods, got, tt.want)
+		}
+	}
+	_ = emulator.ProcessOutput([]byte("\x1b[=1;3u"))
+	if got := emulator.GetState().KittyKeyboard; got != 0 {
+		t.Errorf("Kitty flags after removing 1 = %d", got)
+	}
+	_ = emulator.ProcessOutput([]byte("\x1b[=1u\x1b[<u"))
+	if got := emulator.GetState().KittyKeyboard; got != 0 {
+		t.Errorf("Kitty flags after pop = %d, want 0", got)
+	}
+
+	// Plain CSI u still restores the cursor
+	_ = emulator.ProcessOutput([]byte("\x1b[5;5H\x1b[s\x1b[1;1H\x1b[u"))
+	if state := emulator.GetState(); state.CursorX != 4 || state.CursorY != 4 {
+		t.Errorf("Cursor after CSI u = %d,%d, want 4,4", state.CursorX, state.CursorY)
+	}
+}
+
+// updateGoldens rewrites the conformance goldens from the emulator's
+// output: go test ./pkg/terminal -run TestVTConformance -update
+var updateGoldens = flag.Bool("update", false, "rewrite the VT conformance golden files")
+
+// vtCaseSize matches the screen size at the end of a case name, e.g.
+// "scroll_region_20x6"; cases without one use 80x24
+var vtCaseSize = regexp.MustCompile(`_(\d+)x(\d+)$`)
+
+// TestVTConformance feeds the recorded byte streams in testdata/vt/*.vt
+// into a TerminalEmulator and compares the final screen with the .golden
+// file next to each
+func TestVTConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "vt", "*.vt"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No conformance cases found: %v", err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".vt")
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read case: %v", err)
+			}
+			width, height := 80, 24
+			if m := vtCaseSize.FindStringSubmatch(name); m != nil {
+				width, _ = strconv.Atoi(m[1])
+				height, _ = strconv.Atoi(m[2])
+			}
+
+			h := NewHeadlessTerminal(width, height)
+			if _, err := h.Write(input); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			got := conformanceDump(h)
+
+			golden := strings.TrimSuffix(file, ".vt") + ".golden"
+			if *updateGoldens {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatalf("Failed to write golden: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("Screen differs from %s (run with -update to accept)\n--- got\n%s--- want\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+// conformanceDump describes the screen for a golden file: the cursor, each
+// row with trailing blanks trimmed, then the runs of cells with attributes
+// other than the default
+func conformanceDump(h *HeadlessTerminal) string {
+	var sb strings.Builder
+	x, y := h.Cursor()
+	fmt.Fprintf(&sb, "cursor %d,%d\n", y+1, x+1)
+	for _, line := range h.Lines() {
+		sb.WriteString("|" + line + "\n")
+	}
+	for _, run := range strings.SplitAfter(h.AttributeDump(), "\n") {
+		if run != "" {
+			sb.WriteString("attr " + run)
+		}
+	}
+	return sb.String()
+}
+
+func TestHeadlessTerminal(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	if _, err := io.WriteString(h, "login: \x1b[1;31mroot\x1b[0m\r\n中文\x1b[6n"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if got, want := h.Text(), "login: root\n中文"; got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+	if lines := h.Lines(); len(lines) != 5 || lines[4] != "" {
+		t.Errorf("Lines() = %q, want 5 rows", lines)
+	}
+	if x, y := h.Cursor(); x != 4 || y != 1 {
+		t.Errorf("Cursor() = %d,%d, want 4,1", x, y)
+	}
+
+	runs := h.AttributeRuns()
+	if len(runs) != 1 || runs[0].Row != 0 || runs[0].StartCol != 7 || runs[0].EndCol != 11 {
+		t.Fatalf("AttributeRuns() = %+v, want one run over root", runs)
+	}
+	if got, want := h.AttributeDump(), "1,8-11 bold fg=red\n"; got != want {
+		t.Errorf("AttributeDump() = %q, want %q", got, want)
+	}
+
+	if got := string(h.Responses()); got != "\x1b[2;5R" {
+		t.Errorf("Responses() = %q, want the cursor position report", got)
+	}
+	if got := h.Responses(); len(got) != 0 {
+		t.Errorf("Responses() again = %q, want nothing", got)
+	}
+}
+
+func TestDebugInfo(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	_, _ = io.WriteString(h, "ok \x1b[1;31mred\x1b[0m\r\n\x1b]0;tit")
+
+	info := h.DebugInfo()
+	if info.ParserState != StateOSC || info.ParserState.String() != "osc" {
+		t.Errorf("ParserState = %v, want osc", info.ParserState)
+	}
+	if info.Partial != "\x1b]0;tit" {
+		t.Errorf("Partial = %q, want the unfinished OSC", info.Partial)
+	}
+	if info.LastSequence != "\x1b[0m" || info.Sequences != 2 {
+		t.Errorf("LastSequence = %q after %d, want ESC [0m after 2", info.LastSequence, info.Sequences)
+	}
+	if info.CursorX != 0 || info.CursorY != 1 || info.AltScreen {
+		t.Errorf("Cursor = %d,%d alt=%v, want 0,1 on the main screen", info.CursorX, info.CursorY, info.AltScreen)
+	}
+	if info.DirtyLines == 0 || info.DirtyMinY != 0 {
+		t.Errorf("DirtyLines = %d from row %d, want the first row dirty", info.DirtyLines, info.DirtyMinY)
+	}
+
+	_, _ = io.WriteString(h, "le\x07")
+	if info := h.DebugInfo(); info.ParserState != StateGround || info.Partial != "" || info.LastSequence != "\x1b]0;title\x07" {
+		t.Errorf("After BEL: state %v, partial %q, last %q", info.ParserState, info.Partial, info.LastSequence)
+	}
+}
+
+func TestSequenceTrace(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	var traces []string
+	h.SetSequenceTracer(func(trace SequenceTrace) {
+		traces = append(traces, trace.String())
+	})
+	_, _ = io.WriteString(h, "a\x1b[1;31mb\x1b[?1004h\x1b[5;3H\x1b#8\x1b]8;;http://x\x07\x1b[c\x1b[?25l\x1b[9y")
+
+	want := []string{
+		"CSI 1;31m  SGR -> set-attribute(bold), set-attribute(fg=red)",
+		"CSI ?1004h  DECSET -> ignored",
+		"CSI 5;3H  CUP -> move-cursor(to 5,3)",
+		"ESC #8  DECALN -> ignored",
+		"OSC 8;;http://x  OSC 8 -> set-hyperlink(http://x)",
+		`CSI c  DA -> send-response(\x1b[?62;1;2;6;7;8;9c)`,
+		"CSI ?25l  DECRST -> set-mode(cursor_hidden)",
+		"CSI 9y  unknown -> ignored",
+	}
+	if strings.Join(traces, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Traces:\n%s\nwant:\n%s", strings.Join(traces, "\n"), strings.Join(want, "\n"))
+	}
+
+	h.SetSequenceTracer(nil)
+	_, _ = io.WriteString(h, "\x1b[H")
+	if len(traces) != len(want) {
+		t.Errorf("Traced %d sequences after stopping, want %d", len(traces), len(want))
+	}
+}
+
+func TestIgnoredSequences(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	for i := 0; i < 3; i++ {
+		_, _ = io.WriteString(h, "\x1b[?1004h\x1b[1mx\x1b[?1004l")
+	}
+	_, _ = io.WriteString(h, "\x1b]0;one\x07\x1b]0;two\x07\x1bP$qm\x1b\\\x1b[?1004h")
+
+	counts, total := h.IgnoredSequences()
+	want := []string{"4× CSI ?1004h ignored", "3× CSI ?1004l ignored", "2× OSC 0 ignored", "1× DCS $q ignored"}
+	var got []string
+	for _, count := range counts {
+		got = append(got, count.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") || total != 10 {
+		t.Errorf("IgnoredSequences() = %q, %d; want %q, 10", got, total, want)
+	}
+
+	// Garbage can't grow the counts without bound
+	for i := 0; i < 2*maxIgnoredKinds; i++ {
+		fmt.Fprintf(h, "\x1b[%dy", i)
+	}
+	counts, total = h.IgnoredSequences()
+	if len(counts) != maxIgnoredKinds+1 || total != 10+2*maxIgnoredKinds {
+		t.Errorf("%d kinds, %d in all after garbage; want %d, %d", len(counts), total, maxIgnoredKinds+1, 10+2*maxIgnoredKinds)
+	}
+}
+
+func TestSynchronizedOutput(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	if active, _ := h.SynchronizedUpdate(); active {
+		t.Fatal("Synchronized update active at start")
+	}
+
+	before := time.Now()
+	_, _ = io.WriteString(h, "\x1b[?2026$p\x1b[?2026hredraw\x1b[?2026$p")
+	active, since := h.SynchronizedUpdate()
+	if !active || since.Before(before) {
+		t.Errorf("SynchronizedUpdate() = %v, %v; want active since the write", active, since)
+	}
+	if got := h.Text(); got != "redraw" {
+		t.Errorf("Screen = %q, want it updated during the synchronized update", got)
+	}
+
+	// A second begin doesn't restart the timeout
+	_, _ = io.WriteString(h, "\x1b[?2026h")
+	if _, again := h.SynchronizedUpdate(); !again.Equal(since) {
+		t.Errorf("Start moved from %v to %v", since, again)
+	}
+
+	_, _ = io.WriteString(h, "\x1b[?2026l\x1b[?2004$p\x1b[?4242$p")
+	if active, _ := h.SynchronizedUpdate(); active {
+		t.Error("Synchronized update still active after ending it")
+	}
+	if got, want := string(h.Responses()), "\x1b[?2026;2$y\x1b[?2026;1$y\x1b[?2004;2$y\x1b[?4242;0$y"; got != want {
+		t.Errorf("DECRQM responses = %q, want %q", got, want)
+	}
+
+	_, _ = io.WriteString(h, "\x1b[?2026h\x1bc")
+	if active, _ := h.SynchronizedUpdate(); active {
+		t.Error("Synchronized update survived a reset")
+	}
+}
+
+func TestNewlineHandling(t *testing.T) {
+	tests := []struct {
+		name       string
+		implicitCR bool
+		implicitLF bool
+		input      string
+		want       []string
+	}{
+		{"bare LF", false, false, "ab\ncd\n", []string{"ab", "  cd", ""}},
+		{"LNM", false, false, "\x1b[20hab\ncd\x0bef\x0cgh", []string{"ab", "cd", "ef", "gh"}},
+		{"LNM reset", false, false, "\x1b[20h\x1b[20lab\ncd", []string{"ab", "  cd"}},
+		{"implicit CR", true, false, "ab\ncd\r\nef", []string{"ab", "cd", "ef"}},
+		{"bare CR", false, false, "ab\rcd\r", []string{"cd"}},
+		{"implicit LF", false, true, "ab\rcd\r\nef\r\r\ngh", []string{"ab", "cd", "ef", "", "gh"}},
+		{"implicit LF with UTF-8 after CR", false, true, "ab\ré\nx", []string{"ab", "é", " x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHeadlessTerminal(10, 5)
+			h.SetNewlineHandling(tt.implicitCR, tt.implicitLF)
+			_, _ = io.WriteString(h, tt.input)
+			lines := h.Lines()
+			if got := lines[:len(tt.want)]; strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// LNM is reported, makes Enter send CR LF, and is cleared by a reset
+	// while the user's options are kept
+	h := NewHeadlessTerminal(10, 5)
+	h.SetNewlineHandling(true, false)
+	processor := NewInputProcessor(h.TerminalEmulator)
+	enter := tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+	_, _ = io.WriteString(h, "\x1b[20$p\x1b[20h\x1b[20$p")
+	if got, want := string(h.Responses()), "\x1b[20;2$y\x1b[20;1$y"; got != want {
+		t.Errorf("DECRQM responses = %q, want %q", got, want)
+	}
+	if got := processor.ProcessKeyEvent(enter); string(got) != "\r\n" {
+		t.Errorf("Enter in newline mode sent %q, want CR LF", got)
+	}
+	_, _ = io.WriteString(h, "\x1bc")
+	if state := h.GetState(); state.NewlineMode || !state.ImplicitCR {
+		t.Errorf("After a reset NewlineMode = %v, ImplicitCR = %v; want false, true", state.NewlineMode, state.ImplicitCR)
+	}
+	if got := processor.ProcessKeyEvent(enter); string(got) != "\r" {
+		t.Errorf("Enter after a reset sent %q, want CR", got)
+	}
+}
+
+func TestWrapTracking(t *testing.T) {
+	h := NewHeadlessTerminal(5, 3)
+	_, _ = io.WriteString(h, "abcdefghijkl\r\nxy")
+	rows := h.GetAllLines()
+	var wrapped []bool
+	for _, row := range rows {
+		wrapped = append(wrapped, IsWrapped(row))
+	}
+	// The first row scrolled into scrollback with its flag
+	if want := []bool{true, true, false, false}; fmt.Sprint(wrapped) != fmt.Sprint(want) {
+		t.Errorf("Wrapped rows = %v, want %v", wrapped, want)
+	}
+	var texts []string
+	for _, line := range LogicalLines(rows) {
+		texts = append(texts, fmt.Sprintf("%d-%d %s", line.Start, line.End(), line.Text()))
+	}
+	if want := []string{"0-3 abcdefghijkl", "3-4 xy"}; strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("LogicalLines() = %q, want %q", texts, want)
+	}
+
+	// Writing over the end of a wrapped row, or erasing it, ends the line
+	// there; filling a row exactly without wrapping doesn't start one
+	_, _ = io.WriteString(h, "\x1b[1;5HZ\x1b[2;3H\x1b[K\x1b[3;1Hvwxyz")
+	screen := h.GetScreen()
+	for y := 0; y < 3; y++ {
+		if IsWrapped(screen.Buffer[y]) {
+			t.Errorf("Row %d still wrapped", y)
+		}
+	}
+}
+
+func TestRewrapScrollback(t *testing.T) {
+	h := NewHeadlessTerminal(10, 2)
+	_, _ = io.WriteString(h, "0123456789abcdef\r\nxy\r\nz")
+	var remap func(int) int
+	h.SetRewrapCallback(func(r func(int) int) { remap = r })
+
+	rows := func() string {
+		var out []string
+		for _, row := range h.GetAllLines() {
+			text := LogicalLine{Rows: [][]Cell{row}}.Text()
+			if IsWrapped(row) {
+				text += "+"
+			}
+			out = append(out, text)
+		}
+		return strings.Join(out, "|")
+	}
+
+	// Narrowing splits the scrollback line into more rows; the screen is cut
+	if err := h.Resize(4, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows(), "0123+|4567+|89ab+|cdef|xy|z"; got != want {
+		t.Errorf("Rows after narrowing = %q, want %q", got, want)
+	}
+	if remap == nil || remap(0) != 0 || remap(1) != 2 || remap(2) != 4 {
+		t.Errorf("Rows not remapped to their text after narrowing")
+	}
+
+	// Widening joins them again, and the view stays on the same text
+	h.ScrollToLine(2)
+	if err := h.Resize(12, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows(), "0123456789ab+|cdef|xy|z"; got != want {
+		t.Errorf("Rows after widening = %q, want %q", got, want)
+	}
+	if top := h.GetTopLineIndex(); top != 0 {
+		t.Errorf("GetTopLineIndex() = %d after widening, want 0", top)
+	}
+	if remap(3) != 1 || remap(4) != 2 {
+		t.Errorf("Rows not remapped to their text after widening")
+	}
+}
+
+func TestClearPolicy(t *testing.T) {
+	tests := []struct {
+		policy     string
+		afterED2   int // Rows of scrollback after clearing the screen
+		afterED3   int // and after erasing the saved lines
+		wantPolicy ClearPolicy
+	}{
+		{"", 3, 3, ClearPush},
+		{"discard", 1, 1, ClearDiscard},
+		{"ed3", 1, 0, ClearOnED3},
+	}
+	for _, tt := range tests {
+		policy, err := ParseClearPolicy(tt.policy)
+		if err != nil || policy != tt.wantPolicy {
+			t.Fatalf("ParseClearPolicy(%q) = %v, %v", tt.policy, policy, err)
+		}
+		h := NewHeadlessTerminal(10, 2)
+		h.SetClearPolicy(policy)
+		scrollback := func() int { return len(h.GetAllLines()) - 2 }
+
+		_, _ = io.WriteString(h, "a\r\nb\r\nc\x1b[2J")
+		if got := scrollback(); got != tt.afterED2 {
+			t.Errorf("%s: %d rows of scrollback after ED 2, want %d", policy, got, tt.afterED2)
+		}
+		// ED 3 leaves the screen alone
+		_, _ = io.WriteString(h, "d\x1b[3J")
+		if got := scrollback(); got != tt.afterED3 {
+			t.Errorf("%s: %d rows of scrollback after ED 3, want %d", policy, got, tt.afterED3)
+		}
+		if got := h.Text(); got != "d" {
+			t.Errorf("%s: Text() = %q after ED 3, want d", policy, got)
+		}
+	}
+	if _, err := ParseClearPolicy("keep"); err == nil {
+		t.Error("ParseClearPolicy should reject unknown names")
+	}
+}
+
+func TestTmuxPassthrough(t *testing.T) {
+	h := NewHeadlessTerminal(20, 5)
+	var traces []string
+	h.SetSequenceTracer(func(trace SequenceTrace) {
+		traces = append(traces, trace.Sequence()+" "+trace.Name())
+	})
+
+	// A payload cut off inside a sequence doesn't swallow what follows
+	_, _ = io.WriteString(h, "\x1bPtmux;\x1b\x1b[3\x1b\\after")
+	if got := h.Text(); got != "after" {
+		t.Errorf("Text() = %q after a cut off payload, want after", got)
+	}
+	if len(traces) != 1 || traces[0] != `DCS tmux;\x1b\x1b[3 tmux passthrough` {
+		t.Errorf("Traces = %q, want the passthrough only", traces)
+	}
+
+	// Passthrough nested deeper than tmux inside tmux a few times is dropped
+	wrap := func(s string) string {
+		return "\x1bPtmux;" + strings.ReplaceAll(s, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	h.Clear()
+	nested := "\x1b[Hok"
+	for i := 0; i < maxPassthroughDepth; i++ {
+		nested = wrap(nested)
+	}
+	_, _ = io.WriteString(h, "\x1b[2;1H"+nested+"\x1b[3;1H"+wrap(nested))
+	if lines := h.Lines(); lines[0] != "ok" || lines[2] != "" {
+		t.Errorf("Lines() = %q, want ok from %d levels and nothing from one more", lines, maxPassthroughDepth)
+	}
+}
+
+func TestControlStrings(t *testing.T) {
+	long := strings.Repeat("a", maxOSCLength)
+	tests := []struct {
+		name  string
+		input string
+		want  string // Summary of the string, from the trace
+	}{
+		{"OSC ended by BEL", "\x1b]0;title\x07", "OSC 0"},
+		{"OSC ended by ST", "\x1b]0;title\x1b\\", "OSC 0"},
+		{"OSC cut short by CSI", "\x1b]0;title\x1b[m", "OSC 0"},
+		{"OSC abandoned by CAN", "\x1b]0;ti\x18", "OSC 0"},
+		{"too long OSC", "\x1b]8;id=" + long + ";https://example.com/\x1b\\", "OSC 8"},
+		{"DCS with BEL inside", "\x1bP1$r0m\x07x\x1b\\", "DCS 1$r"},
+		{"APC", "\x1b_Gi=1,a=q;AAAA\x1b\\", "APC"},
+		{"PM", "\x1b^private\x07still\x1b\\", "PM"},
+		{"SOS abandoned by SUB", "\x1bXstart\x1a", "SOS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHeadlessTerminal(20, 5)
+			var summaries []string
+			h.SetSequenceTracer(func(trace SequenceTrace) {
+				summaries = append(summaries, trace.Summary())
+			})
+
+			// The string leaves nothing behind on the screen or in the
+			// parser, whatever ended it
+			_, _ = io.WriteString(h, tt.input+"ok")
+			if got := h.Text(); got != "ok" {
+				t.Errorf("Text() = %q, want ok", got)
+			}
+			if len(summaries) == 0 || summaries[0] != tt.want {
+				t.Errorf("Traced %q, want %s first", summaries, tt.want)
+			}
+			if h.parser.State != StateGround || cap(h.parser.Buffer) > 2*maxOSCLength {
+				t.Errorf("Parser in %s with a %d byte buffer, want ground and a bounded buffer", h.parser.State, cap(h.parser.Buffer))
+			}
+			if link := h.state.Hyperlink; link != nil {
+				t.Errorf("Hyperlink %q started by a dropped string", link.URI)
+			}
+		})
+	}
+}
+
+// benchmarkInputs are representative device output for the ProcessOutput
+// benchmarks, each about 64 KiB
+func benchmarkInputs() []struct {
+	name string
+	data []byte
+} {
+	const size = 64 << 10
+	repeat := func(s string) []byte {
+		return bytes.Repeat([]byte(s), size/len(s)+1)[:size]
+	}
+
+	// A log: plain lines that scroll the screen
+	plain := repeat("[  12.345678] usb 1-1: new high-speed USB device number 2 using ehci-platform\r\n")
+
+	// Colored output where almost every word changes the attributes, like
+	// ls --color or a test runner
+	sgr := repeat("\x1b[1;32mPASS\x1b[0m \x1b[38;5;244mtest_\x1b[4mcase\x1b[24m\x1b[0m \x1b[48;2;40;40;40m\x1b[33m0.01s\x1b[m\r\n")
+
+	// Text in a multi-byte encoding
+	utf8Text := repeat("温度 23.5°C 湿度 41% — état prêt ✓\r\n")
+
+	// A full-screen application redrawing with cursor addressing
+	var redraw strings.Builder
+	for redraw.Len() < size {
+		for y := 1; y <= 24; y++ {
+			fmt.Fprintf(&redraw, "\x1b[%d;1H\x1b[K\x1b[7m%3d\x1b[m row of the status display", y, y)
+		}
+	}
+
+	// Binary data, like cat of a firmware image, from a fixed seed
+	binary := make([]byte, size)
+	state := uint32(1)
+	for i := range binary {
+		state = state*1664525 + 1013904223
+		binary[i] = byte(state >> 24)
+	}
+
+	return []struct {
+		name string
+		data []byte
+	}{
+		{"plain", plain},
+		{"sgr", sgr},
+		{"utf8", utf8Text},
+		{"redraw", []byte(redraw.String())},
+		{"binary", binary},
+	}
+}
+
+// BenchmarkProcessOutput measures the throughput of the emulator in MB/s
+// and its allocations for each kind of input. The budget is at least
+// 100 MB/s on one core for plain text and colored output.
+func BenchmarkProcessOutput(b *testing.B) {
+	for _, input := range benchmarkInputs() {
+		b.Run(input.name, func(b *testing.B) {
+			te := NewTerminalEmulator(nil, nil, 80, 24)
+			_ = te.Start()
+			b.SetBytes(int64(len(input.data)))
+			b.ReportAllocs()
+			for b.Loop() {
+				_ = te.ProcessOutput(input.data)
+			}
+		})
+	}
+}
+
+// fuzzLogger fails a fuzz input on anything the emulator logs as an error,
+// which is a recovered panic or the infinite loop guard in ProcessOutput
+type fuzzLogger struct {
+	t *testing.T
+}
+
+func (l fuzzLogger) Debugf(format string, args ...interface{}) {}
+func (l fuzzLogger) Warnf(format string, args ...interface{})  {}
+func (l fuzzLogger) Errorf(format string, args ...interface{}) {
+	l.t.Errorf(format, args...)
+}
+
+// addFuzzSeeds adds sequences from each part of the parser and the VT
+// conformance cases to a fuzz corpus. Samples of device output, like boot
+// logs and shell sessions, are in testdata/fuzz/FuzzProcessOutput.
+func addFuzzSeeds(f *testing.F) {
+	seeds := []string{
+		"hello\r\nworld\b\b\t!",
+		"\x1b[1;31mred\x1b[0m \x1b[38;5;196;48;2;1;2;3;4:3;58:5:33mcolor\x1b[m",
+		"\x1b[2J\x1b[H\x1b[3;5H\x1b[K\x1b[1J\x1b[10A\x1b[10C",
+		"\x1b[2;5r\x1b[5;1H\n\n\x1bM\x1b[3S\x1b[2T\x1b[r",
+		"\x1b[4h\x1b[2@\x1b[3P\x1b[4l",
+		"\x1b7\x1b[?6h\x1b[H\x1b8\x1b[s\x1b[u",
+		"\x1b[?1049h\x1b[?25l\x1b[?1000;1006h\x1b[?1049l",
+		"\x1b]8;id=1;https://example.com\x1b\\link\x1b]8;;\x07",
+		"\x1b]0;title\x07\x1bP$q\"p\x1b\\",
+		"\x1b[6n\x1b[c\x1b[>c\x1b[5n\x1b[18t",
+		"\x1b[>4;2m\x1b[>1u\x1b[?u\x1b[<u",
+		"\x1bH\x1b[3g\x1b[0g\x1b[2Z",
+		"\x1b#3dbl\n\x1b#4dbl\n\x1b#6wide\x1b#5",
+		"\x1b[5iprinted\x1b[4i\x1b[i",
+		"\x1b[?2l\x1bA\x1bY&*\x1bJ\x1b<",
+		"\x1bc\x1b(0lqk\x1b(B",
+		"\x1b[999999999E\x1b[99999999999999999999F\x1b[4294967297;2H\x1b[999999999@",
+		"中文字́\xe4\xb8 \xf0\x9f\x98\x80\xed\xa0\x80\xc0\xaf",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	files, _ := filepath.Glob(filepath.Join("testdata", "vt", "*.vt"))
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			f.Add(data)
+		}
+	}
+}
+
+// FuzzProcessOutput feeds arbitrary device output to a small emulator in
+// two reads, so sequences are also split between them. ProcessOutput
+// recovers from panics, so the logger fails the input instead; the cursor
+// and screen must stay within bounds.
+func FuzzProcessOutput(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		te := NewTerminalEmulator(nil, nil, 20, 6)
+		te.SetLogger(fuzzLogger{t})
+		_ = te.Start()
+
+		half := len(data) / 2
+		_ = te.ProcessOutput(data[:half])
+		_ = te.ProcessOutput(data[half:])
+
+		state := te.GetState()
+		screen := te.GetScreen()
+		if state.CursorX < 0 || state.CursorX > screen.Width || state.CursorY < 0 || state.CursorY >= screen.Height {
+			t.Errorf("Cursor %d,%d is off the %dx%d screen", state.CursorX, state.CursorY, screen.Width, screen.Height)
+		}
+		if len(screen.Buffer) != screen.Height {
+			t.Fatalf("Screen has %d rows, want %d", len(screen.Buffer), screen.Height)
+		}
+		for y, line := range screen.Buffer {
+			if len(line) != screen.Width {
+				t.Errorf("Row %d has %d cells, want %d", y, len(line), screen.Width)
+			}
+		}
+	})
+}
+
+// FuzzParseByte runs the parser on its own, without the recovery of
+// ProcessOutput, and checks it ends in a known state
+func FuzzParseByte(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parser := NewVTParser()
+		screen := NewScreen(20, 6)
+		state := DefaultTerminalState(20, 6)
+		decoder := NewUTF8Decoder()
+		for _, b := range data {
+			parser.ParseByte(b, screen, &state, decoder)
+		}
+		if parser.State < StateGround || parser.State > StateVT52Cursor {
+			t.Errorf("Parser ended in unknown state %d", parser.State)
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SequenceTrace describes an escape sequence the parser finished and what
+// the emulator did with it, for tracing emulation bugs
+type SequenceTrace struct {
+	Raw string // The sequence as received, cut off after 64 bytes
+
+	// Kind is ESC, CSI, or the control string OSC, DCS, SOS, PM or APC,
+	// or VT52 for escape sequences in VT52 mode
+	Kind string
+
+	// Params holds the parameter bytes with any private marker, like
+	// "?2026", or the string of a control string
+	Params        string
+	Intermediates string
+	Final         byte // 0 for control strings, and for sequences cut short
+
+	Actions []Action // None if the sequence was ignored
+}
+
+// csiNames are the mnemonics of the CSI sequences, by private marker,
+// intermediates and final byte
+var csiNames = map[string]string{
+	"A": "CUU", "B": "CUD", "C": "CUF", "D": "CUB", "E": "CNL", "F": "CPL",
+	"G": "CHA", "H": "CUP", "f": "HVP", "J": "ED", "K": "EL", "m": "SGR",
+	"r": "DECSTBM", "s": "SCOSC", "u": "SCORC", "h": "SM", "l": "RM",
+	"?h": "DECSET", "?l": "DECRST", "P": "DCH", "@": "ICH", "I": "CHT",
+	"Z": "CBT", "g": "TBC", "n": "DSR", "?n": "DECDSR", "t": "XTWINOPS",
+	"i": "MC", "?i": "DECMC", "x": "DECREQTPARM", "c": "DA", "?c": "DA",
+	">c": "DA2", ">m": "XTMODKEYS", ">n": "XTMODKEYS", "?u": "KITTYKB",
+	">u": "KITTYKB", "<u": "KITTYKB", "=u": "KITTYKB",
+	"L": "IL", "M": "DL", "S": "SU", "T": "SD", "X": "ECH", "b": "REP",
+	"d": "VPA", "q": "DECLL", " q": "DECSCUSR", "$p": "DECRQM", "?$p": "DECRQM",
+}
+
+// escNames are the mnemonics of the other escape sequences, by
+// intermediates and final byte
+var escNames = map[string]string{
+	"D": "IND", "M": "RI", "E": "NEL", "H": "HTS", "7": "DECSC", "8": "DECRC",
+	"=": "DECKPAM", ">": "DECKPNM", "c": "RIS", "Z": "DECID",
+	"#3": "DECDHL", "#4": "DECDHL", "#5": "DECSWL", "#6": "DECDWL", "#8": "DECALN",
+	"(0": "SCS", "(B": "SCS", ")0": "SCS", ")B": "SCS", "\\": "ST",
+}
+
+// stringKinds are the control strings, by the byte after ESC that starts
+// them
+var stringKinds = map[byte]string{']': "OSC", 'P': "DCS", 'X': "SOS", '^': "PM", '_': "APC"}
+
+// actionNames are the names of the action types, for traces
+var actionNames = [...]string{
+	ActionPrint:           "print",
+	ActionMoveCursor:      "move-cursor",
+	ActionClearScreen:     "clear-screen",
+	ActionClearLine:       "clear-line",
+	ActionSetAttribute:    "set-attribute",
+	ActionScroll:          "scroll",
+	ActionSetMode:         "set-mode",
+	ActionBell:            "bell",
+	ActionTab:             "tab",
+	ActionBackTab:         "back-tab",
+	ActionNewline:         "newline",
+	ActionCarriageReturn:  "carriage-return",
+	ActionBackspace:       "backspace",
+	ActionDeleteChar:      "delete-char",
+	ActionInsertChar:      "insert-char",
+	ActionSetScrollRegion: "set-scroll-region",
+	ActionSaveCursor:      "save-cursor",
+	ActionRestoreCursor:   "restore-cursor",
+	ActionSwitchAltScreen: "switch-alt-screen",
+	ActionSendResponse:    "send-response",
+	ActionSetTabStop:      "set-tab-stop",
+	ActionClearTabStop:    "clear-tab-stop",
+	ActionReset:           "reset",
+	ActionMediaCopy:       "media-copy",
+	ActionSetLineSize:     "set-line-size",
+	ActionSetHyperlink:    "set-hyperlink",
+	ActionSetKeyProtocol:  "set-key-protocol",
+	ActionPassthrough:     "passthrough",
+}
+
+// SetSequenceTracer calls tracer with every escape sequence the parser
+// finishes, from the goroutine processing output and with the emulator
+// locked, so it must not call back into the emulator. Nil stops tracing.
+func (te *TerminalEmulator) SetSequenceTracer(tracer func(SequenceTrace)) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.tracer = tracer
+}
+
+// sequenceDone counts the sequence just recorded if it was ignored, and
+// traces it while tracing
+func (te *TerminalEmulator) sequenceDone(actions []Action) {
+	if te.tracer == nil && len(actions) > 0 {
+		return
+	}
+	trace := newSequenceTrace(te.sequences.last, actions, te.state.VT52)
+	if trace.Ignored() {
+		te.ignored.add(trace.Summary())
+	}
+	if te.tracer != nil {
+		te.tracer(trace)
+	}
+}
+
+// newSequenceTrace splits a finished sequence into its parts
+func newSequenceTrace(raw string, actions []Action, vt52 bool) SequenceTrace {
+	t := SequenceTrace{Raw: raw, Kind: "ESC", Actions: actions}
+	body := strings.TrimPrefix(raw, "\x1b")
+	if vt52 {
+		t.Kind = "VT52"
+	} else if len(body) > 0 {
+		switch body[0] {
+		case '[':
+			t.Kind = "CSI"
+			body = body[1:]
+			i := 0
+			for i < len(body) && body[i] >= 0x30 && body[i] <= 0x3F {
+				i++
+			}
+			j := i
+			for j < len(body) && body[j] >= 0x20 && body[j] <= 0x2F {
+				j++
+			}
+			t.Params, t.Intermediates = body[:i], body[i:j]
+			if j == len(body)-1 && body[j] >= 0x40 && body[j] <= 0x7E {
+				t.Final = body[j]
+			}
+			return t
+		case ']', 'P', 'X', '^', '_':
+			t.Kind = stringKinds[body[0]]
+			t.Params = strings.TrimRight(strings.TrimSuffix(body[1:], "\x1b\\"), "\x07\x1b")
+			return t
+		}
+	}
+
+	// ESC, intermediates and a final byte
+	if n := len(body); n > 0 && body[n-1] >= 0x30 && body[n-1] <= 0x7E {
+		t.Intermediates, t.Final = body[:n-1], body[n-1]
+	} else {
+		t.Intermediates = body
+	}
+	return t
+}
+
+// Name returns the mnemonic of the sequence, like CUP or DECSET, or the
+// command number of an OSC, like "OSC 8". Empty if unknown.
+func (t SequenceTrace) Name() string {
+	switch t.Kind {
+	case "CSI":
+		if t.Final == 0 {
+			return ""
+		}
+		marker := ""
+		if t.Params != "" && t.Params[0] >= '<' && t.Params[0] <= '?' {
+			marker = t.Params[:1]
+		}
+		return csiNames[marker+t.Intermediates+string(t.Final)]
+	case "ESC":
+		if t.Final == 0 {
+			return ""
+		}
+		return escNames[t.Intermediates+string(t.Final)]
+	case "OSC":
+		command, _, _ := strings.Cut(t.Params, ";")
+		return "OSC " + command
+	case "DCS":
+		if strings.HasPrefix(t.Params, tmuxPassthroughPrefix) {
+			return "tmux passthrough"
+		}
+	case "SOS", "PM", "APC":
+		return t.Kind
+	}
+	return ""
+}
+
+// Ignored reports whether the emulator did nothing with the sequence,
+// because it doesn't know it or doesn't support what it asks for
+func (t SequenceTrace) Ignored() bool {
+	return len(t.Actions) == 0
+}
+
+// Sequence returns the sequence in readable form, like "CSI ?2026h", with
+// control characters escaped
+func (t SequenceTrace) Sequence() string {
+	text := t.Params + t.Intermediates
+	if t.Final != 0 {
+		text += string(t.Final)
+	}
+	return t.Kind + " " + escapeControls(text)
+}
+
+// String describes the sequence and what was done with it on one line,
+// e.g. `CSI ?25l  DECRST -> set-mode(cursor_hidden)`
+func (t SequenceTrace) String() string {
+	name := t.Name()
+	if name == "" {
+		name = "unknown"
+	}
+	if t.Ignored() {
+		return fmt.Sprintf("%s  %s -> ignored", t.Sequence(), name)
+	}
+	actions := make([]string, len(t.Actions))
+	for i, action := range t.Actions {
+		actions[i] = action.String()
+	}
+	return fmt.Sprintf("%s  %s -> %s", t.Sequence(), name, strings.Join(actions, ", "))
+}
+
+// String returns the name of an action type
+func (t ActionType) String() string {
+	if int(t) < len(actionNames) && actionNames[t] != "" {
+		return actionNames[t]
+	}
+	return fmt.Sprintf("action-%d", int(t))
+}
+
+// String describes an action and its data, like "move-cursor(up 2)"
+func (a Action) String() string {
+	var data string
+	switch d := a.Data.(type) {
+	case nil:
+		return a.Type.String()
+	case CursorMove:
+		switch d.Direction {
+		case "absolute":
+			data = fmt.Sprintf("to %d,%d", d.Row+1, d.Col+1)
+		case "horizontal":
+			data = fmt.Sprintf("to column %d", d.Col+1)
+		default:
+			data = fmt.Sprintf("%s %d", d.Direction, d.Count)
+		}
+	case AttributeChange:
+		data = d.String()
+	case ScrollRegion:
+		data = fmt.Sprintf("rows %d-%d", d.Top+1, d.Bottom+1)
+	case *Hyperlink:
+		if d == nil {
+			data = "end"
+		} else {
+			data = d.URI
+		}
+	case KeyProtocolChange:
+		if d.ModifyOtherKeys != nil {
+			data = fmt.Sprintf("modifyOtherKeys %d", *d.ModifyOtherKeys)
+		} else {
+			data = fmt.Sprintf("kitty %c flags %d mode %d count %d", d.Kitty, d.Flags, d.Mode, d.Count)
+		}
+	case string:
+		data = escapeControls(d)
+	case []byte:
+		data = escapeControls(string(d))
+	case rune:
+		data = string(d)
+	default:
+		data = fmt.Sprint(d)
+	}
+	return a.Type.String() + "(" + data + ")"
+}
+
+// String lists what an SGR changes, like "reset bold fg=red"
+func (c AttributeChange) String() string {
+	var parts []string
+	if c.Reset {
+		parts = append(parts, "reset")
+	}
+	flags := []struct {
+		value *bool
+		name  string
+	}{
+		{c.Bold, "bold"},
+		{c.Italic, "italic"},
+		{c.Underline, "underline"},
+		{c.Blink, "blink"},
+		{c.Reverse, "reverse"},
+	}
+	for _, f := range flags {
+		switch {
+		case f.value == nil:
+		case *f.value:
+			parts = append(parts, f.name)
+		default:
+			parts = append(parts, "no-"+f.name)
+		}
+	}
+	if c.UnderlineStyle != nil {
+		parts = append(parts, fmt.Sprintf("underline=%d", int(*c.UnderlineStyle)+1))
+	}
+	colors := []struct {
+		value *Color
+		name  string
+	}{
+		{c.Foreground, "fg"},
+		{c.Background, "bg"},
+		{c.UnderlineColor, "ulcolor"},
+	}
+	for _, color := range colors {
+		if color.value != nil {
+			parts = append(parts, color.name+"="+color.value.String())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// escapeControls writes control characters as Go escapes, so a trace line
+// stays on one line and doesn't act on the terminal showing it
+func escapeControls(s string) string {
+	quoted := fmt.Sprintf("%q", s)
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+}
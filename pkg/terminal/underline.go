@@ -0,0 +1,124 @@
+package terminal
+
+import "fmt"
+
+// UnderlineStyle is the shape of an underline, set with SGR 4:x
+type UnderlineStyle uint8
+
+const (
+	UnderlineSingle UnderlineStyle = iota // SGR 4 or 4:1
+	UnderlineDouble                       // SGR 4:2 or 21
+	UnderlineCurly                        // SGR 4:3
+	UnderlineDotted                       // SGR 4:4
+	UnderlineDashed                       // SGR 4:5
+)
+
+// colorRGBFlag marks a Color holding a 24-bit RGB value in its low bits.
+// Colors 16-255 are the xterm 256-color palette.
+const colorRGBFlag Color = 1 << 24
+
+// RGBColor returns a 24-bit color
+func RGBColor(r, g, b uint8) Color {
+	return colorRGBFlag | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// IsRGB reports whether the color is a 24-bit color
+func (c Color) IsRGB() bool {
+	return c >= 0 && c&colorRGBFlag != 0
+}
+
+// RGB returns the components of a 24-bit color
+func (c Color) RGB() (r, g, b uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+// sgrGroups splits the parameters of an SGR sequence into groups: one per
+// parameter separated by semicolons, holding it and any colon-separated
+// subparameters (4:3, 58:2::255:0:0). Empty values are 0.
+func (vt *VTParser) sgrGroups() [][]int {
+	var groups [][]int
+	group := []int{0}
+	for _, ch := range vt.Buffer {
+		switch {
+		case ch >= '0' && ch <= '9':
+			group[len(group)-1] = group[len(group)-1]*10 + int(ch-'0')
+		case ch == ':':
+			group = append(group, 0)
+		case ch == ';':
+			groups = append(groups, group)
+			group = []int{0}
+		}
+	}
+	return append(groups, group)
+}
+
+// sgrUnderline returns the underline change of SGR 4 with an optional
+// style subparameter (4:0 off, 4:1 single, 4:2 double, 4:3 curly, 4:4
+// dotted, 4:5 dashed), nil for an unknown style
+func sgrUnderline(group []int) *Action {
+	on, style := true, UnderlineSingle
+	if len(group) > 1 {
+		switch {
+		case group[1] == 0:
+			on = false
+		case group[1] <= int(UnderlineDashed)+1:
+			style = UnderlineStyle(group[1] - 1)
+		default:
+			return nil
+		}
+	}
+	return &Action{Type: ActionSetAttribute, Data: AttributeChange{Underline: &on, UnderlineStyle: &style}}
+}
+
+// extendedColor parses the color of SGR 38, 48 and 58: 5 and a palette
+// index, or 2 and red, green and blue. The values are either subparameters
+// of the group (58:5:n, 58:2::r:g:b with the optional color space id) or
+// the groups in rest (58;5;n, 58;2;r;g;b). Returns the color, how many
+// groups of rest were used, and false if the color isn't valid.
+func extendedColor(group []int, rest [][]int) (Color, int, bool) {
+	values := group[1:]
+	if len(values) == 0 {
+		for _, g := range rest {
+			values = append(values, g[0])
+		}
+	}
+	if len(values) == 0 {
+		return ColorDefault, 0, false
+	}
+
+	used := func(n int) int {
+		if len(group) > 1 {
+			return 0
+		}
+		return min(n, len(rest))
+	}
+	switch values[0] {
+	case 5:
+		if len(values) < 2 || values[1] > 255 {
+			return ColorDefault, used(2), false
+		}
+		return Color(values[1]), used(2), true
+	case 2:
+		rgb := values[1:]
+		if len(group) > 1 && len(rgb) >= 4 {
+			rgb = rgb[1:] // Color space id
+		}
+		if len(rgb) < 3 || rgb[0] > 255 || rgb[1] > 255 || rgb[2] > 255 {
+			return ColorDefault, used(4), false
+		}
+		return RGBColor(uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2])), used(4), true
+	}
+	return ColorDefault, used(1), false
+}
+
+// colorName describes an extended color for Color.String
+func colorName(c Color) string {
+	if c.IsRGB() {
+		r, g, b := c.RGB()
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	if c >= 16 && c <= 255 {
+		return fmt.Sprintf("color%d", int(c))
+	}
+	return "unknown"
+}
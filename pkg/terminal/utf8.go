@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// InvalidUTF8Policy controls what the decoder produces for bytes that are not
+// part of a valid UTF-8 sequence
+type InvalidUTF8Policy int
+
+const (
+	InvalidUTF8Replace InvalidUTF8Policy = iota // U+FFFD for each invalid byte, like unicode/utf8
+	InvalidUTF8Drop                             // Skip invalid bytes
+	InvalidUTF8Latin1                           // Show invalid bytes as Latin-1, for devices mixing encodings
+)
+
+// String returns the settings name of the policy
+func (p InvalidUTF8Policy) String() string {
+	switch p {
+	case InvalidUTF8Drop:
+		return "drop"
+	case InvalidUTF8Latin1:
+		return "latin1"
+	default:
+		return "replace"
+	}
+}
+
+// ParseInvalidUTF8Policy parses a policy name as used in the settings file
+func ParseInvalidUTF8Policy(name string) (InvalidUTF8Policy, error) {
+	switch name {
+	case "", "replace":
+		return InvalidUTF8Replace, nil
+	case "drop":
+		return InvalidUTF8Drop, nil
+	case "latin1":
+		return InvalidUTF8Latin1, nil
+	default:
+		return InvalidUTF8Replace, fmt.Errorf("unknown invalid UTF-8 policy %q", name)
+	}
+}
+
+// UTF8Decoder is a streaming UTF-8 decoder. It is a small DFA: the lead byte
+// decides how many continuation bytes follow and the range the first one
+// must be in, which rules out overlong encodings, surrogates and code points
+// above U+10FFFF. Sequences may be split across reads.
+type UTF8Decoder struct {
+	bytes  []byte // Bytes of the sequence being decoded
+	need   int    // Continuation bytes still expected
+	lo, hi byte   // Valid range of the next continuation byte
+	r      rune   // Code point decoded so far
+	policy InvalidUTF8Policy
+	logger Logger
+}
+
+// NewUTF8Decoder creates a new UTF-8 decoder that replaces invalid bytes
+func NewUTF8Decoder() *UTF8Decoder {
+	return &UTF8Decoder{
+		bytes: make([]byte, 0, utf8.UTFMax),
+	}
+}
+
+// SetPolicy sets how invalid bytes are decoded
+func (d *UTF8Decoder) SetPolicy(policy InvalidUTF8Policy) {
+	d.policy = policy
+}
+
+// utf8Lead classifies a lead byte, returning the number of continuation bytes
+// and the valid range of the first one. ok is false for bytes that can't
+// start a sequence: continuation bytes, C0/C1 (overlong) and F5-FF.
+func utf8Lead(b byte) (need int, lo, hi byte, ok bool) {
+	switch {
+	case b >= 0xC2 && b <= 0xDF:
+		return 1, 0x80, 0xBF, true
+	case b == 0xE0:
+		return 2, 0xA0, 0xBF, true // No overlong 3-byte forms
+	case b == 0xED:
+		return 2, 0x80, 0x9F, true // No surrogates
+	case b >= 0xE1 && b <= 0xEF:
+		return 2, 0x80, 0xBF, true
+	case b == 0xF0:
+		return 3, 0x90, 0xBF, true // No overlong 4-byte forms
+	case b >= 0xF1 && b <= 0xF3:
+		return 3, 0x80, 0xBF, true
+	case b == 0xF4:
+		return 3, 0x80, 0x8F, true // Nothing above U+10FFFF
+	default:
+		return 0, 0, 0, false
+	}
+}
+
+// Decode feeds one byte to the decoder and appends any runes it completes to
+// out. A byte that breaks off a sequence makes every byte of the sequence
+// invalid and is then decoded on its own, so the output matches decoding the
+// whole stream with unicode/utf8.
+func (d *UTF8Decoder) Decode(b byte, out []rune) []rune {
+	if d.need > 0 {
+		if b >= d.lo && b <= d.hi {
+			d.bytes = append(d.bytes, b)
+			d.r = d.r<<6 | rune(b&0x3F)
+			d.need--
+			d.lo, d.hi = 0x80, 0xBF
+			if d.need > 0 {
+				return out
+			}
+			r := d.r
+			d.Reset()
+			return append(out, r)
+		}
+		out = d.Flush(out)
+	}
+
+	if b < utf8.RuneSelf {
+		return append(out, rune(b))
+	}
+	need, lo, hi, ok := utf8Lead(b)
+	if !ok {
+		return d.invalid(out, b)
+	}
+	d.bytes = append(d.bytes[:0], b)
+	d.need, d.lo, d.hi = need, lo, hi
+	d.r = rune(b) & (0x7F >> (need + 1)) // Payload bits of the lead byte
+	return out
+}
+
+// Pending reports whether the decoder is in the middle of a sequence
+func (d *UTF8Decoder) Pending() bool {
+	return d.need > 0
+}
+
+// Flush ends an incomplete sequence, appending its bytes to out as invalid
+func (d *UTF8Decoder) Flush(out []rune) []rune {
+	if d.need == 0 {
+		return out
+	}
+	out = d.invalid(out, d.bytes...)
+	d.Reset()
+	return out
+}
+
+// invalid appends invalid bytes to out according to the policy
+func (d *UTF8Decoder) invalid(out []rune, bytes ...byte) []rune {
+	if d.logger != nil {
+		d.logger.Debugf("Invalid UTF-8 bytes: % X", bytes)
+	}
+	for _, b := range bytes {
+		switch d.policy {
+		case InvalidUTF8Drop:
+		case InvalidUTF8Latin1:
+			out = append(out, rune(b))
+		default:
+			out = append(out, utf8.RuneError)
+		}
+	}
+	return out
+}
+
+// Reset discards any partial sequence
+func (d *UTF8Decoder) Reset() {
+	d.bytes = d.bytes[:0]
+	d.need = 0
+	d.r = 0
+}
@@ -0,0 +1,92 @@
+package terminal
+
+import "fmt"
+
+// vt52Graphics maps the characters 0x5F-0x7E to the VT52 graphics set shown
+// while graphics mode (ESC F) is on: fractions, arrows, horizontal bars at
+// the eight scan lines and subscript digits
+var vt52Graphics = map[byte]rune{
+	'_': ' ', '`': ' ', 'a': '█', 'b': '⅟', 'c': '³', 'd': '⁵', 'e': '⁷',
+	'f': '°', 'g': '±', 'h': '→', 'i': '…', 'j': '÷', 'k': '↓',
+	'l': '⎺', 'm': '⎺', 'n': '⎻', 'o': '⎻', 'p': '─', 'q': '─', 'r': '⎼', 's': '⎽',
+	't': '₀', 'u': '₁', 'v': '₂', 'w': '₃', 'x': '₄', 'y': '₅', 'z': '₆',
+	'{': '₇', '|': '₈', '}': '₉', '~': '¶',
+}
+
+// vt52Identify is the answer to ESC Z in VT52 mode: a VT52 without a copier
+const vt52Identify = "\x1b/Z"
+
+// handleVT52Escape processes the escape sequences of VT52 mode, entered with
+// ESC[?2l (DECANM) and left with ESC <
+func (vt *VTParser) handleVT52Escape(b byte) []Action {
+	vt.Reset()
+	switch b {
+	case 'A': // Cursor up
+		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "up", Count: 1}}}
+	case 'B': // Cursor down
+		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "down", Count: 1}}}
+	case 'C': // Cursor right
+		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "right", Count: 1}}}
+	case 'D': // Cursor left
+		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "left", Count: 1}}}
+	case 'F': // Enter graphics mode
+		return []Action{{Type: ActionSetMode, Data: "vt52_graphics_on"}}
+	case 'G': // Exit graphics mode
+		return []Action{{Type: ActionSetMode, Data: "vt52_graphics_off"}}
+	case 'H': // Cursor home
+		return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "absolute"}}}
+	case 'I': // Reverse line feed
+		return []Action{{Type: ActionScroll, Data: "up"}}
+	case 'J': // Erase to end of screen
+		return []Action{{Type: ActionClearScreen, Data: 0}}
+	case 'K': // Erase to end of line
+		return []Action{{Type: ActionClearLine, Data: 0}}
+	case 'Y': // Direct cursor address, followed by row and column
+		vt.State = StateVT52Cursor
+		return nil
+	case 'Z': // Identify
+		return []Action{{Type: ActionSendResponse, Data: vt52Identify}}
+	case '=': // Alternate keypad mode
+		return []Action{{Type: ActionSetMode, Data: "keypad_app"}}
+	case '>': // Exit alternate keypad mode
+		return []Action{{Type: ActionSetMode, Data: "keypad_num"}}
+	case '<': // Enter ANSI mode
+		return []Action{{Type: ActionSetMode, Data: "ansi"}}
+	}
+	return nil
+}
+
+// handleVT52Cursor collects the row and column of ESC Y, each sent as the
+// position plus 32
+func (vt *VTParser) handleVT52Cursor(b byte) []Action {
+	if b < 0x20 {
+		// A control character cancels the sequence
+		vt.Reset()
+		return nil
+	}
+	vt.Buffer = append(vt.Buffer, b)
+	if len(vt.Buffer) < 2 {
+		return nil
+	}
+	row, col := int(vt.Buffer[0])-32, int(vt.Buffer[1])-32
+	vt.Reset()
+	return []Action{{Type: ActionMoveCursor, Data: CursorMove{Direction: "absolute", Row: row, Col: col}}}
+}
+
+// vt52GraphicsChar returns the character shown for b in VT52 graphics mode
+func vt52GraphicsChar(b byte) rune {
+	if r, ok := vt52Graphics[b]; ok {
+		return r
+	}
+	return rune(b)
+}
+
+// reportTerminalParameters answers DECREQTPARM (CSI x). Asked with 0 the
+// terminal may also report unprompted, with 1 only when asked. The line
+// is reported as no parity, 8 bits, 38400 baud each way.
+func reportTerminalParameters(request int) string {
+	if request != 0 && request != 1 {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[%d;1;1;128;128;1;0x", request+2)
+}
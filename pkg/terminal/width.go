@@ -0,0 +1,114 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// ambiguousProbeChar is printed to find out how wide the host terminal draws
+// East Asian ambiguous characters. U+25CB WHITE CIRCLE is ambiguous and
+// present in common fonts.
+const ambiguousProbeChar = "○"
+
+// SetAmbiguousWide sets whether East Asian ambiguous characters take two
+// cells. The setting is shared with tcell, which uses the same runewidth
+// condition, so the emulator's layout and the renderer always agree.
+func SetAmbiguousWide(wide bool) {
+	runewidth.DefaultCondition.EastAsianWidth = wide
+}
+
+// AmbiguousWide reports whether East Asian ambiguous characters take two cells
+func AmbiguousWide() bool {
+	return runewidth.DefaultCondition.EastAsianWidth
+}
+
+// DetectAmbiguousWidth asks the host terminal how wide it draws ambiguous
+// characters: it prints one at the start of the line and reads back the
+// cursor position (DSR 6). Must be called before the screen is initialized,
+// since the reply is read directly from tty. Returns an error if tty is not
+// a terminal or doesn't answer within timeout.
+func DetectAmbiguousWidth(tty *os.File, timeout time.Duration) (bool, error) {
+	// Fd() would switch the file to blocking mode and disable read deadlines
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return false, fmt.Errorf("failed to access terminal: %w", err)
+	}
+
+	var state *term.State
+	err = conn.Control(func(fd uintptr) {
+		if !term.IsTerminal(int(fd)) {
+			err = fmt.Errorf("not a terminal")
+			return
+		}
+		state, err = term.MakeRaw(int(fd))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer func() {
+		_ = conn.Control(func(fd uintptr) { _ = term.Restore(int(fd), state) })
+	}()
+
+	if _, err := tty.WriteString("\r" + ambiguousProbeChar + "\x1b[6n"); err != nil {
+		return false, fmt.Errorf("failed to write probe: %w", err)
+	}
+	// Erase the probe character whatever happens
+	defer func() { _, _ = tty.WriteString("\r\x1b[K") }()
+
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+	defer func() { _ = tty.SetReadDeadline(time.Time{}) }()
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if _, col, ok := parseCursorReport(reply); ok {
+			switch col {
+			case 2:
+				return false, nil
+			case 3:
+				return true, nil
+			default:
+				return false, fmt.Errorf("unexpected cursor column %d", col)
+			}
+		}
+		if err != nil {
+			return false, fmt.Errorf("no cursor position reply: %w", err)
+		}
+	}
+}
+
+// parseCursorReport finds a cursor position report (ESC [ row ; col R) in
+// data. Anything before it, such as keys typed during startup, is skipped.
+func parseCursorReport(data []byte) (row, col int, ok bool) {
+	for {
+		start := bytes.Index(data, []byte("\x1b["))
+		if start < 0 {
+			return 0, 0, false
+		}
+		data = data[start+2:]
+
+		end := bytes.IndexByte(data, 'R')
+		if end < 0 {
+			return 0, 0, false
+		}
+		rowText, colText, found := bytes.Cut(data[:end], []byte(";"))
+		if !found {
+			continue
+		}
+		r, errRow := strconv.Atoi(string(rowText))
+		c, errCol := strconv.Atoi(string(colText))
+		if errRow == nil && errCol == nil {
+			return r, c, true
+		}
+	}
+}
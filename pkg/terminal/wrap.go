@@ -0,0 +1,85 @@
+package terminal
+
+import "strings"
+
+// A row whose text ran past the right margin and continued on the next row
+// has Wrapped set on its last cell, so the flag moves with the row when the
+// screen scrolls and goes into scrollback with it. Writing over that cell
+// or erasing it clears the flag: the row no longer ends where the text
+// wrapped.
+
+// markWrapped records that the text on row y continues on the next row
+func (te *TerminalEmulator) markWrapped(y int) {
+	screen := te.GetScreen()
+	if y < 0 || y >= len(screen.Buffer) || len(screen.Buffer[y]) == 0 {
+		return
+	}
+	row := screen.Buffer[y]
+	row[len(row)-1].Wrapped = true
+}
+
+// IsWrapped reports whether the text of a row continues on the next row,
+// because it ran past the right margin rather than ending with a newline
+func IsWrapped(row []Cell) bool {
+	return len(row) > 0 && row[len(row)-1].Wrapped
+}
+
+// LogicalLine is a line of text as the device sent it: a row and the rows
+// it wrapped onto
+type LogicalLine struct {
+	Start int      // Index of the first row
+	Rows  [][]Cell // The rows, all but the last wrapped
+}
+
+// LogicalLines groups rows into logical lines. A wrapped last row starts a
+// line that continues past the rows given.
+func LogicalLines(rows [][]Cell) []LogicalLine {
+	var lines []LogicalLine
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && IsWrapped(rows[end-1]) {
+			end++
+		}
+		lines = append(lines, LogicalLine{Start: start, Rows: rows[start:end]})
+		start = end
+	}
+	return lines
+}
+
+// VisualLines makes each row a line of its own, for text laid out as it is
+// on screen, with lines breaking where their rows do
+func VisualLines(rows [][]Cell) []LogicalLine {
+	lines := make([]LogicalLine, len(rows))
+	for i := range rows {
+		lines[i] = LogicalLine{Start: i, Rows: rows[i : i+1]}
+	}
+	return lines
+}
+
+// TrimBlankLines drops the lines without text at the end, such as the
+// empty rows of the screen below the last output
+func TrimBlankLines(lines []LogicalLine) []LogicalLine {
+	for len(lines) > 0 && lines[len(lines)-1].Text() == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// End returns the index of the row after the line
+func (l LogicalLine) End() int {
+	return l.Start + len(l.Rows)
+}
+
+// Text returns the text of the line, its rows joined without breaks and
+// trailing blanks trimmed. Wide character continuation cells are skipped.
+func (l LogicalLine) Text() string {
+	var sb strings.Builder
+	for _, row := range l.Rows {
+		for _, cell := range row {
+			if cell.Char != 0 {
+				sb.WriteString(cell.String())
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), " ")
+}